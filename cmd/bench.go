@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// runBenchmark drives synthetic logs through the given output pipelines and
+// prints the result. Inputs, the API and hot reload are not started. Without
+// outputs a single null output is used.
+func runBenchmark(outputs []core.PluginDefinition, engine *core.Engine, benchConfig core.BenchmarkConfig) {
+	benchmark, err := core.NewBenchmark(benchConfig)
+	if err != nil {
+		mainLog.Fatalf("Invalid benchmark configuration: %v", err)
+	}
+
+	if len(outputs) == 0 {
+		outputs = []core.PluginDefinition{{Type: "null", Name: "null"}}
+	}
+	for i, outputDef := range outputs {
+		outputName := outputDef.Name
+		if outputName == "" {
+			outputName = fmt.Sprintf("%s-%d", outputDef.Type, i+1)
+		}
+		// Resilient outputs connect in the background and would drop the first logs
+		outputDef.Config = maps.Clone(outputDef.Config)
+		if outputDef.Config == nil {
+			outputDef.Config = map[string]any{}
+		}
+		outputDef.Config["resilient"] = false
+		createOutputPipeline(outputName, outputDef, core.ResilienceConfig{}, engine, benchmark.Instrument)
+	}
+
+	mainLog.Printf("Running benchmark for %s (rate=%d/s, outputs=%d)", benchConfig.Duration, benchConfig.Rate, len(outputs))
+
+	// The engine logs every log it processes; that would measure the logger instead
+	log.SetOutput(io.Discard)
+	result := benchmark.Run(context.Background(), engine)
+	log.SetOutput(os.Stderr)
+
+	fmt.Print(result.String())
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+const inspectUsage = `Usage:
+  loganalyzer wal list   [-config file | -dir dir]
+  loganalyzer wal dump   [-config file | -dir dir] [-pending]
+  loganalyzer wal count  [-config file | -dir dir] [-pending] [-by level|source]
+  loganalyzer dlq list   [-config file | -dir dir]
+  loganalyzer dlq dump   [-config file | -dir dir] -output name
+  loganalyzer dlq count  [-config file | -dir dir] -output name [-by level|source|reason]
+  loganalyzer dlq export [-config file | -dir dir] -output name -to file
+
+The wal and dlq commands only read, so they can run next to an engine that owns the files.
+`
+
+// runInspect runs the wal and dlq subcommands, which inspect the persisted WAL
+// and DLQ files offline, and exits on error
+func runInspect(command string, args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		fmt.Fprint(os.Stderr, inspectUsage)
+		os.Exit(2)
+	}
+	action := args[0]
+
+	flags := flag.NewFlagSet(command+" "+action, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, inspectUsage) }
+	var configFiles configFileList
+	flags.Var(&configFiles, "config", "Configuration file(s) to take the WAL or DLQ directory from")
+	dir := flags.String("dir", "", "WAL or DLQ directory (default: from -config, else ./data/wal or ./data/dlq)")
+	output := flags.String("output", "", "dlq: output whose DLQ is read")
+	pending := flags.Bool("pending", false, "wal: only entries after the recovery checkpoint, which the next start replays")
+	by := flags.String("by", "level", "count: group entries by level, source or (dlq) reason")
+	to := flags.String("to", "", "dlq export: file the logs are written to as JSON lines; it must not exist")
+	if err := flags.Parse(args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	if *dir == "" {
+		*dir = inspectDir(command, configFiles)
+	}
+
+	var err error
+	switch command + " " + action {
+	case "wal list":
+		err = listWAL(*dir)
+	case "wal dump":
+		err = walEntries(*dir, *pending, func(entry core.WALEntry) error { return printJSON(entry) })
+	case "wal count":
+		counts := make(map[string]int)
+		err = walEntries(*dir, *pending, func(entry core.WALEntry) error {
+			key, keyErr := countKey(entry.Log, "", *by, false)
+			counts[key]++
+			return keyErr
+		})
+		if err == nil {
+			printCounts(counts)
+		}
+	case "dlq list":
+		err = listDLQ(*dir)
+	case "dlq dump", "dlq count", "dlq export":
+		err = inspectDLQ(action, *dir, *output, *by, *to)
+	default:
+		fmt.Fprint(os.Stderr, inspectUsage)
+		os.Exit(2)
+	}
+	if err != nil {
+		mainLog.Fatalf("%s %s: %v", command, action, err)
+	}
+}
+
+// inspectDir returns the WAL or DLQ directory of the configuration, or the default one
+func inspectDir(command string, configFiles configFileList) string {
+	persistence := core.DefaultPersistenceConfig()
+	buffer := core.DefaultOutputBufferConfig()
+	if len(configFiles) > 0 {
+		config, err := core.LoadConfigFiles(configFiles...)
+		if err != nil {
+			mainLog.Fatalf("Error loading config file: %v", err)
+		}
+		if config.Persistence.Dir != "" {
+			persistence = config.Persistence
+		}
+		if config.OutputBuffer.DLQPath != "" {
+			buffer = config.OutputBuffer
+		}
+	}
+	if command == "wal" {
+		return persistence.Dir
+	}
+	return buffer.DLQPath
+}
+
+// listWAL prints the WAL files with their entries and sequence range
+func listWAL(dir string) error {
+	files, checkpoint, err := core.ListWALFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSIZE\tMODIFIED\tENTRIES\tPENDING\tSEQUENCES\tMALFORMED")
+	for _, file := range files {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d-%d\t%d\n", filepath.Base(file.Path), file.Size,
+			file.ModTime.Format("2006-01-02T15:04:05"), file.Entries, file.Pending, file.FirstSeq, file.LastSeq, file.Malformed)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d WAL files in %s, recovery checkpoint at sequence %d\n", len(files), dir, checkpoint)
+	return nil
+}
+
+// walEntries calls fn with the entries of every WAL file in dir, oldest file
+// first; with pending, only those after the recovery checkpoint
+func walEntries(dir string, pending bool, fn func(core.WALEntry) error) error {
+	files, checkpoint, err := core.ListWALFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		_, err := core.ReadWALFile(file.Path, func(entry core.WALEntry) error {
+			if pending && entry.Sequence > 0 && entry.Sequence <= checkpoint {
+				return nil
+			}
+			return fn(entry)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listDLQ prints the outputs with DLQ files and how many entries each holds
+func listDLQ(dir string) error {
+	outputs, err := core.DLQOutputs(dir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OUTPUT\tFILES\tENTRIES")
+	for _, output := range outputs {
+		files, err := core.DLQFiles(dir, output)
+		if err != nil {
+			return err
+		}
+		entries, err := core.ReadDLQFiles(dir, output)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\n", output, len(files), len(entries))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d outputs with DLQ files in %s\n", len(outputs), dir)
+	return nil
+}
+
+// inspectDLQ dumps, counts or exports the DLQ entries of an output
+func inspectDLQ(action, dir, output, by, to string) error {
+	if output == "" {
+		return errors.New("-output is required (see 'loganalyzer dlq list')")
+	}
+	entries, err := core.ReadDLQFiles(dir, output)
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(entry *core.BufferedLog) bool { return entry.Log == nil })
+
+	switch action {
+	case "dump":
+		for _, entry := range entries {
+			if err := printJSON(entry); err != nil {
+				return err
+			}
+		}
+	case "count":
+		counts := make(map[string]int)
+		for _, entry := range entries {
+			key, err := countKey(entry.Log, entry.DLQReason, by, true)
+			if err != nil {
+				return err
+			}
+			counts[key]++
+		}
+		printCounts(counts)
+	case "export":
+		return exportDLQ(entries, to)
+	}
+	return nil
+}
+
+// exportDLQ writes the logs of DLQ entries to a new file as JSON lines, oldest first
+func exportDLQ(entries []*core.BufferedLog, to string) error {
+	if to == "" {
+		return errors.New("-to is required")
+	}
+	// O_EXCL: never overwrite a file, least of all one the engine owns
+	file, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) // #nosec G304 - path given by the operator
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry.Log); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d logs to %s\n", len(entries), to)
+	return nil
+}
+
+// countKey returns the value an entry is counted under
+func countKey(logEntry *core.Log, reason, by string, dlq bool) (string, error) {
+	switch {
+	case by == "level":
+		return logEntry.Level, nil
+	case by == "source":
+		return logEntry.Source, nil
+	case by == "reason" && dlq:
+		if reason == "" {
+			return "max_retries", nil // Dead-lettered after exhausting its retries
+		}
+		return reason, nil
+	case dlq:
+		return "", fmt.Errorf("invalid -by %q, must be level, source or reason", by)
+	default:
+		return "", fmt.Errorf("invalid -by %q, must be level or source", by)
+	}
+}
+
+// printCounts prints counts from the largest to the smallest
+func printCounts(counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		name := key
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "%s\t%d\n", name, counts[key])
+	}
+	_ = w.Flush()
+}
+
+// printJSON prints a value as one line of JSON
+func printJSON(value any) error {
+	return json.NewEncoder(os.Stdout).Encode(value)
+}
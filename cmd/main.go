@@ -3,13 +3,15 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/version"
 
 	// Import plugins for auto-registration
 	_ "github.com/mbiondo/logAnalyzer/plugins/filter"
@@ -17,30 +19,84 @@ import (
 	_ "github.com/mbiondo/logAnalyzer/plugins/output"
 )
 
+// mainLog writes startup, reload and shutdown logs of the command
+var mainLog = logging.New("main")
+
 func main() {
+	// Offline admin subcommands have their own flags
+	if len(os.Args) > 1 && (os.Args[1] == "wal" || os.Args[1] == "dlq") {
+		runInspect(os.Args[1], os.Args[2:])
+		return
+	}
+
 	// Command line flags
-	configFile := flag.String("config", "", "Path to configuration file (YAML)")
+	var configFiles configFileList
+	flag.Var(&configFiles, "config", "Path to configuration file (YAML); repeat to merge several files in order")
 	hotReload := flag.Bool("hot-reload", false, "Enable hot reload of configuration file")
+	validate := flag.Bool("validate", false, "Validate the configuration file, report likely misconfigurations and exit")
+	quickstart := flag.Bool("quickstart", false, "Run without a config file: read the given files (or stdin) and print logs to the console")
+	bench := flag.Bool("bench", false, "Run a throughput self-test through the configured outputs and exit")
+	benchRate := flag.Int("bench-rate", 0, "Benchmark: target logs per second (0 = as fast as possible)")
+	benchDuration := flag.Duration("bench-duration", core.DefaultBenchmarkDuration, "Benchmark: how long logs are generated")
+	benchGenerators := flag.Int("bench-generators", 0, "Benchmark: concurrent log generators (default: GOMAXPROCS)")
+	benchMessageSize := flag.Int("bench-message-size", core.DefaultBenchmarkMessageSize, "Benchmark: bytes per synthetic message")
+	showVersion := flag.Bool("version", false, "Print the version and build information and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Println(version.Get())
+		return
+	}
+
 	// Load configuration
 	var config *core.Config
 	var err error
 
-	if *configFile != "" {
-		config, err = core.LoadConfig(*configFile)
+	if *quickstart && len(configFiles) > 0 {
+		mainLog.Fatalf("-quickstart and -config cannot be combined: quickstart runs without a config file")
+	}
+
+	if *validate {
+		validateConfig(configFiles)
+		return
+	}
+
+	if *quickstart {
+		config = quickstartConfig(flag.Args())
+	} else if len(configFiles) > 0 {
+		config, err = core.LoadConfigFiles(configFiles...)
 		if err != nil {
-			log.Fatalf("Error loading config file: %v", err)
+			mainLog.Fatalf("Error loading config file: %v", err)
 		}
-		log.Printf("Loaded configuration from %s", *configFile)
 	} else {
 		config = core.DefaultConfig()
-		log.Println("Using default configuration")
+	}
+
+	// Switch internal logs to the configured format and rate limits before anything else logs
+	if err := config.Logging.Apply(); err != nil {
+		mainLog.Fatalf("Error configuring logging: %v", err)
+	}
+	mainLog.Printf("Starting %s", version.Get())
+	if len(configFiles) > 0 {
+		mainLog.Printf("Loaded configuration from %s", configFiles.String())
+	} else if !*quickstart {
+		mainLog.Println("Using default configuration")
+	}
+
+	// Apply the level vocabulary before any plugin is created
+	if err := core.SetLevels(config.Levels); err != nil {
+		mainLog.Fatalf("Error configuring levels: %v", err)
 	}
 
 	// Create engine
 	engine := core.NewEngine()
 
+	// Limit WAL, buffer and DLQ disk usage; must precede persistence so existing WAL files are counted
+	engine.SetDiskBudget(config.DiskBudget)
+	if config.DiskBudget > 0 {
+		mainLog.Printf("Disk budget enabled: %d bytes for WAL, buffers and DLQ", config.DiskBudget)
+	}
+
 	// Configure persistence if enabled
 	persistenceConfig := config.Persistence
 	if persistenceConfig.Dir == "" {
@@ -48,10 +104,10 @@ func main() {
 		persistenceConfig = core.DefaultPersistenceConfig()
 	}
 	if err := engine.SetPersistence(persistenceConfig); err != nil {
-		log.Fatalf("Error configuring persistence: %v", err)
+		mainLog.Fatalf("Error configuring persistence: %v", err)
 	}
 	if persistenceConfig.Enabled {
-		log.Printf("Persistence enabled: dir=%s, buffer=%d, flush=%ds",
+		mainLog.Printf("Persistence enabled: dir=%s, buffer=%d, flush=%ds",
 			persistenceConfig.Dir, persistenceConfig.BufferSize, persistenceConfig.FlushInterval)
 	}
 
@@ -63,10 +119,97 @@ func main() {
 	}
 	engine.SetOutputBufferConfig(bufferConfig)
 	if bufferConfig.Enabled {
-		log.Printf("Output buffering enabled: queue=%d, retries=%d, dlq=%v",
+		mainLog.Printf("Output buffering enabled: queue=%d, retries=%d, dlq=%v",
 			bufferConfig.MaxQueueSize, bufferConfig.MaxRetries, bufferConfig.DLQEnabled)
 	}
 
+	if *bench {
+		outputs := config.Outputs
+		if len(configFiles) == 0 {
+			outputs = nil // Benchmark the engine alone rather than the default outputs
+		}
+		runBenchmark(outputs, engine, core.BenchmarkConfig{
+			Rate:        *benchRate,
+			Duration:    *benchDuration,
+			Generators:  *benchGenerators,
+			MessageSize: *benchMessageSize,
+		})
+		return
+	}
+
+	// Configure how the engine behaves while paused via the API
+	if err := engine.SetPauseConfig(config.Pause); err != nil {
+		mainLog.Fatalf("Error configuring pause: %v", err)
+	}
+
+	// Record config reloads for GET /reloads and the optional audit file
+	if err := engine.SetReloadAudit(config.ReloadAudit); err != nil {
+		mainLog.Fatalf("Error configuring reload audit: %v", err)
+	}
+	engine.SetAppliedConfig(config)
+
+	// Configure periodic stats logging if enabled
+	if config.StatsInterval > 0 {
+		engine.SetStatsInterval(config.StatsInterval)
+		mainLog.Printf("Stats logging enabled every %s", config.StatsInterval)
+	}
+
+	// Share JSON encodings between outputs if enabled
+	if config.SerializationCache {
+		engine.SetSerializationCache(true)
+		mainLog.Println("Serialization cache enabled")
+	}
+
+	// Drop logs too old to be useful, e.g. replayed after a long outage
+	if config.MaxLogAge > 0 {
+		engine.SetMaxLogAge(config.MaxLogAge)
+		mainLog.Printf("Dropping logs older than %s", config.MaxLogAge)
+	}
+
+	// Bound end-to-end latency; set before outputs are added so their buffers apply it
+	engine.SetDeadline(config.Deadline)
+	if config.Deadline.Budget > 0 {
+		mainLog.Printf("Delivery deadline of %s per log", config.Deadline.Budget)
+	}
+
+	// Keep recent logs per source to show what led up to an error
+	engine.SetErrorContext(config.ErrorContext)
+	if config.ErrorContext.ContextBefore > 0 {
+		mainLog.Printf("Flushing the %d logs before each error to output '%s'", config.ErrorContext.ContextBefore, config.ErrorContext.Target)
+	}
+
+	// Keep compliance-critical logs whatever sampling and rate limiting decide; set before outputs are added
+	engine.SetPreserve(config.PreserveIf)
+	if len(config.PreserveIf) > 0 {
+		mainLog.Printf("Preserving logs matching %d preserve_if conditions from sampling and rate limiting", len(config.PreserveIf))
+	}
+
+	// Keep one noisy source from starving the others
+	engine.SetSourceQuotas(config.SourceQuotas)
+	if config.SourceQuotas.Default > 0 || len(config.SourceQuotas.Sources) > 0 {
+		mainLog.Printf("Source quotas enabled (default %g logs/s, %d per-source overrides)", config.SourceQuotas.Default, len(config.SourceQuotas.Sources))
+	}
+
+	// Let outputs that connect in the background get ready before inputs start
+	if config.StartupGrace > 0 {
+		engine.SetStartupGrace(config.StartupGrace)
+		mainLog.Printf("Waiting up to %s at startup for outputs to become healthy", config.StartupGrace)
+	}
+
+	// Share repeated metadata strings between logs if enabled
+	engine.SetMetadataStorage(config.MetadataStorage)
+	if config.MetadataStorage.Mode == core.MetadataStorageCompact {
+		mainLog.Println("Compact metadata storage enabled")
+	}
+
+	// Configure per-log stage timing if enabled
+	if err := engine.SetTraceConfig(config.Trace); err != nil {
+		mainLog.Fatalf("Error configuring trace: %v", err)
+	}
+	if config.Trace.SampleEvery > 0 {
+		mainLog.Printf("Stage timing enabled for 1 in %d logs", config.Trace.SampleEvery)
+	}
+
 	// Configure API if enabled
 	apiConfig := config.API
 	if apiConfig.Port == 0 {
@@ -74,9 +217,22 @@ func main() {
 	}
 	if apiConfig.Enabled {
 		if err := engine.EnableAPI(apiConfig); err != nil {
-			log.Fatalf("Failed to enable API: %v", err)
+			mainLog.Fatalf("Failed to enable API: %v", err)
 		}
-		log.Printf("API server enabled on port %d", apiConfig.Port)
+		mainLog.Printf("API server enabled on %s", apiConfig.ListenAddress())
+	}
+
+	// Plugins added through the API are built like the ones in the config file
+	engine.SetPluginBuilder(core.PluginBuilder{
+		Input: func(name string, def core.PluginDefinition, resilience core.ResilienceConfig) (func(*core.Engine), error) {
+			return buildInput(def.Type, name, def.Config, resilience)
+		},
+		Pipeline: func(name string, def core.PluginDefinition, resilience core.ResilienceConfig) (*core.OutputPipeline, error) {
+			return buildOutputPipeline(name, def, resilience, nil)
+		},
+	})
+	if apiConfig.Enabled && apiConfig.WriteBack != "" {
+		mainLog.Printf("Plugin changes made through the API are written back to %s", apiConfig.WriteBack)
 	}
 
 	// Configure input plugin(s)
@@ -85,7 +241,7 @@ func main() {
 		if inputName == "" {
 			inputName = fmt.Sprintf("%s-%d", inputDef.Type, i+1)
 		}
-		createInputPlugin(inputDef.Type, inputName, inputDef.Config, engine)
+		createInputPlugin(inputDef.Type, inputName, inputDef.Config, config.Resilience, engine)
 	}
 
 	// Configure filter plugin(s) - now handled per output pipeline
@@ -96,34 +252,52 @@ func main() {
 		if outputName == "" {
 			outputName = fmt.Sprintf("%s-%d", outputDef.Type, i+1)
 		}
-		createOutputPipeline(outputName, outputDef, engine)
+		createOutputPipeline(outputName, outputDef, config.Resilience, engine, nil)
 	}
 
 	// Start engine
 	engine.Start()
 
-	// Initialize hot reload if enabled and config file is specified
+	// Initialize hot reload if enabled and config files are specified
 	var configWatcher *core.ConfigWatcher
-	if *hotReload && *configFile != "" {
+	if *hotReload && len(configFiles) > 0 {
 		var err error
-		configWatcher, err = core.NewConfigWatcher(*configFile, func(newConfig *core.Config) {
+		configWatcher, err = core.NewConfigFilesWatcher(configFiles, func(newConfig *core.Config) {
 			// Reload engine with new configuration
-			if err := engine.ReloadConfig(newConfig, createInputPluginWrapper, createOutputPipelineWrapper); err != nil {
-				log.Printf("Error reloading configuration: %v", err)
+			if err := engine.ReloadConfigFrom(core.ReloadTriggerFile, newConfig, buildPlugins); err != nil {
+				mainLog.Printf("Error reloading configuration: %v", err)
 			}
 		})
 		if err != nil {
-			log.Printf("Warning: Failed to initialize config watcher: %v", err)
-			log.Println("Continuing without hot reload")
+			mainLog.Printf("Warning: Failed to initialize config watcher: %v", err)
+			mainLog.Println("Continuing without hot reload")
 		} else {
-			log.Println("Hot reload enabled for config file:", *configFile)
+			configWatcher.OnError(func(err error) {
+				engine.RecordReloadFailure(core.ReloadTriggerFile, err)
+			})
+			mainLog.Println("Hot reload enabled for config files:", configFiles.String())
 		}
 	}
 
+	// SIGHUP reloads the config files on demand
+	hupChan := make(chan os.Signal, 1)
+	if len(configFiles) > 0 {
+		signal.Notify(hupChan, syscall.SIGHUP)
+	}
+
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	for running := true; running; {
+		select {
+		case <-hupChan:
+			reloadFromSignal(configFiles, engine)
+		case <-sigChan:
+			running = false
+		case <-engine.ShutdownRequested():
+			running = false
+		}
+	}
 
 	// Stop config watcher if running
 	if configWatcher != nil {
@@ -132,22 +306,147 @@ func main() {
 
 	// Stop engine
 	engine.Stop()
-	log.Println("LogAnalyzer shutdown complete")
+	logging.SetRateLimits(nil, 0) // Report the lines suppressed since the last report
+	mainLog.Println("LogAnalyzer shutdown complete")
 }
 
-func createInputPlugin(pluginType string, name string, config map[string]any, engine *core.Engine) {
-	// Check if resilient mode is enabled in config (default: true)
-	resilientEnabled := true
-	if val, ok := config["resilient"]; ok {
-		if enabled, ok := val.(bool); ok {
-			resilientEnabled = enabled
+// quickstartConfig builds the --quickstart configuration and prints it as YAML
+// on stderr, so it can be saved and used with -config
+func quickstartConfig(paths []string) *core.Config {
+	files := core.DetectQuickstartFiles(paths, os.Stdin)
+	config := core.QuickstartConfig(files)
+	if err := config.Validate(); err != nil {
+		mainLog.Fatalf("Error building quickstart configuration: %v", err)
+	}
+
+	data, err := core.MarshalConfig(config)
+	if err != nil {
+		mainLog.Fatalf("Error rendering quickstart configuration: %v", err)
+	}
+
+	source := "stdin"
+	if len(files) > 0 {
+		source = strings.Join(files, ", ")
+	}
+	fmt.Fprintf(os.Stderr, "# Quickstart mode: no config file loaded, reading %s\n", source)
+	fmt.Fprintf(os.Stderr, "# Equivalent configuration (save it and run with -config <file>):\n%s\n", data)
+	return config
+}
+
+// configFileList collects repeated -config flags in the order they are given
+type configFileList []string
+
+func (l *configFileList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *configFileList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// validateConfig loads the config files, which validates the merged result and
+// logs lint warnings, and exits non-zero when it is invalid
+func validateConfig(configFiles configFileList) {
+	if len(configFiles) == 0 {
+		mainLog.Fatalf("-validate requires -config")
+	}
+	config, err := core.LoadConfigFiles(configFiles...)
+	if err != nil {
+		mainLog.Fatalf("Invalid configuration: %v", err)
+	}
+	fmt.Printf("Configuration %s is valid (%d warnings)\n", configFiles.String(), len(core.LintConfig(config)))
+}
+
+// reloadFromSignal reloads the config files after a SIGHUP
+func reloadFromSignal(configFiles configFileList, engine *core.Engine) {
+	mainLog.Println("SIGHUP received, reloading configuration...")
+	newConfig, err := core.LoadConfigFiles(configFiles...)
+	if err != nil {
+		mainLog.Printf("Error reloading configuration: %v", err)
+		engine.RecordReloadFailure(core.ReloadTriggerSignal, err)
+		return
+	}
+	if err := engine.ReloadConfigFrom(core.ReloadTriggerSignal, newConfig, buildPlugins); err != nil {
+		mainLog.Printf("Error reloading configuration: %v", err)
+	}
+}
+
+// createInputPlugin creates an input and adds it to the engine, exiting on error
+func createInputPlugin(pluginType string, name string, config map[string]any, resilience core.ResilienceConfig, engine *core.Engine) {
+	add, err := buildInput(pluginType, name, config, resilience)
+	if err != nil {
+		mainLog.Fatalf("Error creating input plugin %s (%s): %v", pluginType, name, err)
+	}
+	add(engine)
+}
+
+// createOutputPipeline creates an output with its filters and adds the pipeline to the engine, exiting on error.
+// wrap, when set, wraps the output before it is buffered (used by the benchmark).
+func createOutputPipeline(name string, outputDef core.PluginDefinition, resilience core.ResilienceConfig, engine *core.Engine, wrap func(core.OutputPlugin) core.OutputPlugin) {
+	pipeline, err := buildOutputPipeline(name, outputDef, resilience, wrap)
+	if err != nil {
+		mainLog.Fatalf("Error creating output pipeline '%s': %v", name, err)
+	}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		mainLog.Fatalf("Error adding output pipeline '%s': %v", name, err)
+	}
+}
+
+// buildPlugins is the core.ReloadBuilder used for hot reload: it builds every
+// input and output pipeline of a configuration without starting them
+func buildPlugins(config *core.Config) (func(*core.Engine), error) {
+	var inputs []func(*core.Engine)
+	for i, inputDef := range config.Inputs {
+		name := inputDef.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", inputDef.Type, i+1)
+		}
+		add, err := buildInput(inputDef.Type, name, inputDef.Config, config.Resilience)
+		if err != nil {
+			return nil, fmt.Errorf("input %s (%s): %w", inputDef.Type, name, err)
 		}
+		inputs = append(inputs, add)
 	}
 
-	if resilientEnabled {
-		// Use resilient plugin wrapper
-		log.Printf("Creating resilient %s input plugin as '%s'", pluginType, name)
+	var pipelines []*core.OutputPipeline
+	for i, outputDef := range config.Outputs {
+		name := outputDef.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", outputDef.Type, i+1)
+		}
+		pipeline, err := buildOutputPipeline(name, outputDef, config.Resilience, nil)
+		if err != nil {
+			// Release what was built so far; the running configuration is kept
+			for _, built := range pipelines {
+				if err := built.Output.Close(); err != nil {
+					mainLog.Printf("Error closing output %s: %v", built.Name, err)
+				}
+			}
+			return nil, fmt.Errorf("output pipeline '%s': %w", name, err)
+		}
+		pipelines = append(pipelines, pipeline)
+	}
 
+	return func(engine *core.Engine) {
+		for _, add := range inputs {
+			add(engine)
+		}
+		for _, pipeline := range pipelines {
+			if err := engine.AddOutputPipeline(pipeline); err != nil {
+				mainLog.Printf("Error adding output pipeline '%s': %v", pipeline.Name, err)
+			}
+		}
+	}, nil
+}
+
+// buildInput constructs an input without starting it and returns the function
+// that adds it to an engine. Resilient inputs connect and start in the
+// background as soon as they are created, so they are created by that function
+// instead, once the engine they send to is in place.
+func buildInput(pluginType string, name string, config map[string]any, resilience core.ResilienceConfig) (func(*core.Engine), error) {
+	// The plugin's "resilient" key overrides the global resilience default (true)
+	if resilience.Resilient(config) {
 		resilientConfig := core.DefaultResilientPluginConfig()
 		// Override from config if provided
 		if retryInterval, ok := config["retry_interval"].(int); ok {
@@ -159,47 +458,83 @@ func createInputPlugin(pluginType string, name string, config map[string]any, en
 		if healthCheck, ok := config["health_check_interval"].(int); ok {
 			resilientConfig.HealthCheck = time.Duration(healthCheck) * time.Second
 		}
+		if healthCheckTimeout, ok := config["health_check_timeout"].(int); ok {
+			resilientConfig.HealthCheckTimeout = time.Duration(healthCheckTimeout) * time.Second
+		}
+		if jitter, ok := config["retry_jitter"].(string); ok {
+			if err := core.ValidateJitter(jitter); err != nil {
+				return nil, err
+			}
+			resilientConfig.Jitter = jitter
+		}
 
 		// Get factory function
 		factory := func(cfg map[string]any) (any, error) {
 			return core.CreateInputPlugin(pluginType, cfg)
 		}
-
-		resilientInput := core.NewResilientInputPlugin(name, pluginType, factory, config, engine.InputChannel(), resilientConfig)
-		engine.AddInput(name, resilientInput)
-		log.Printf("Resilient %s input plugin '%s' will connect in background", pluginType, name)
-	} else {
-		// Use direct plugin (original behavior)
-		inputPlugin, err := core.CreateInputPlugin(pluginType, config)
-		if err != nil {
-			log.Fatalf("Error creating input plugin %s (%s): %v", pluginType, name, err)
+		if resilience.FailFast {
+			var err error
+			if factory, err = core.FailFastFactory(factory, config); err != nil {
+				return nil, err
+			}
 		}
 
-		// Set name if plugin supports it (duck typing)
-		if nameable, ok := inputPlugin.(interface{ SetName(string) }); ok {
-			nameable.SetName(name)
-		}
+		return func(engine *core.Engine) {
+			// Use resilient plugin wrapper
+			mainLog.Printf("Creating resilient %s input plugin as '%s'", pluginType, name)
+			resilientInput := core.NewResilientInputPlugin(name, pluginType, factory, config, engine.InputChannel(), resilientConfig)
+			engine.AddInputWithType(name, pluginType, resilientInput)
+			mainLog.Printf("Resilient %s input plugin '%s' will connect in background", pluginType, name)
+		}, nil
+	}
+
+	// Use direct plugin (original behavior)
+	inputPlugin, err := core.CreateInputPlugin(pluginType, config)
+	if err != nil {
+		return nil, err
+	}
 
-		engine.AddInput(name, inputPlugin)
-		log.Printf("Using %s input plugin as '%s'", pluginType, name)
+	// Set name if plugin supports it (duck typing)
+	if nameable, ok := inputPlugin.(interface{ SetName(string) }); ok {
+		nameable.SetName(name)
 	}
+
+	return func(engine *core.Engine) {
+		engine.AddInputWithType(name, pluginType, inputPlugin)
+		mainLog.Printf("Using %s input plugin as '%s'", pluginType, name)
+	}, nil
 }
 
-func createOutputPipeline(name string, outputDef core.PluginDefinition, engine *core.Engine) {
-	// Check if resilient mode is enabled in config (default: true)
-	resilientEnabled := true
-	if val, ok := outputDef.Config["resilient"]; ok {
-		if enabled, ok := val.(bool); ok {
-			resilientEnabled = enabled
+// buildOutputPipeline creates an output with its filters as a pipeline ready to be added to an engine.
+// wrap, when set, wraps the output before it is buffered (used by the benchmark).
+func buildOutputPipeline(name string, outputDef core.PluginDefinition, resilience core.ResilienceConfig, wrap func(core.OutputPlugin) core.OutputPlugin) (*core.OutputPipeline, error) {
+	// Create filters first: they are cheap and fail on bad configuration
+	var filters []core.FilterPlugin
+	var filterEnabled []bool
+	for i, filterDef := range outputDef.Filters {
+		filterPlugin, err := core.CreateFilterPlugin(filterDef.Type, filterDef.Config)
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", filterDef.Type, err)
+		}
+		filters = append(filters, filterPlugin)
+		filterEnabled = append(filterEnabled, filterDef.IsEnabled())
+		if filterDef.IsEnabled() {
+			mainLog.Printf("Added %s filter #%d to output '%s'", filterDef.Type, i+1, name)
+		} else {
+			mainLog.Printf("Added %s filter #%d to output '%s', disabled (enable at runtime via POST /pipelines/%s/filters/%d/enable)",
+				filterDef.Type, i+1, name, name, i+1)
 		}
 	}
 
+	// The output's "resilient" key overrides the global resilience default (true)
+	resilientEnabled := resilience.Resilient(outputDef.Config)
+
 	var outputPlugin core.OutputPlugin
 	var err error
 
 	if resilientEnabled {
 		// Use resilient plugin wrapper
-		log.Printf("Creating resilient %s output plugin as '%s'", outputDef.Type, name)
+		mainLog.Printf("Creating resilient %s output plugin as '%s'", outputDef.Type, name)
 
 		resilientConfig := core.DefaultResilientPluginConfig()
 		// Override from config if provided
@@ -212,54 +547,78 @@ func createOutputPipeline(name string, outputDef core.PluginDefinition, engine *
 		if healthCheck, ok := outputDef.Config["health_check_interval"].(int); ok {
 			resilientConfig.HealthCheck = time.Duration(healthCheck) * time.Second
 		}
+		if healthCheckTimeout, ok := outputDef.Config["health_check_timeout"].(int); ok {
+			resilientConfig.HealthCheckTimeout = time.Duration(healthCheckTimeout) * time.Second
+		}
+		if jitter, ok := outputDef.Config["retry_jitter"].(string); ok {
+			if err := core.ValidateJitter(jitter); err != nil {
+				return nil, err
+			}
+			resilientConfig.Jitter = jitter
+		}
 
 		// Get factory function
 		factory := func(cfg map[string]any) (any, error) {
 			return core.CreateOutputPlugin(outputDef.Type, cfg)
 		}
+		if resilience.FailFast {
+			if factory, err = core.FailFastFactory(factory, outputDef.Config); err != nil {
+				return nil, err
+			}
+		}
 
 		resilientOutput := core.NewResilientOutputPlugin(name, outputDef.Type, factory, outputDef.Config, resilientConfig)
 		outputPlugin = resilientOutput
-		log.Printf("Resilient %s output plugin '%s' will connect in background", outputDef.Type, name)
+		mainLog.Printf("Resilient %s output plugin '%s' will connect in background", outputDef.Type, name)
 	} else {
 		// Use direct plugin (original behavior)
 		outputPlugin, err = core.CreateOutputPlugin(outputDef.Type, outputDef.Config)
 		if err != nil {
-			log.Fatalf("Error creating output plugin %s (%s): %v", outputDef.Type, name, err)
+			return nil, err
 		}
-		log.Printf("Using %s output plugin as '%s'", outputDef.Type, name)
+		mainLog.Printf("Using %s output plugin as '%s'", outputDef.Type, name)
 	}
-
-	// Create filters for this output
-	var filters []core.FilterPlugin
-	for i, filterDef := range outputDef.Filters {
-		filterPlugin, err := core.CreateFilterPlugin(filterDef.Type, filterDef.Config)
-		if err != nil {
-			log.Fatalf("Error creating filter plugin %s for output %s: %v", filterDef.Type, name, err)
-		}
-		filters = append(filters, filterPlugin)
-		log.Printf("  Added %s filter #%d to output '%s'", filterDef.Type, i+1, name)
+	if wrap != nil {
+		outputPlugin = wrap(outputPlugin)
 	}
 
 	// Create pipeline
 	pipeline := &core.OutputPipeline{
-		Name:    name,
-		Output:  outputPlugin,
-		Filters: filters,
-		Sources: outputDef.Sources,
+		Name:          name,
+		Type:          outputDef.Type,
+		Output:        outputPlugin,
+		Filters:       filters,
+		FilterEnabled: filterEnabled,
+		Sources:       outputDef.Sources,
+		Tags:          outputDef.Tags,
+		TagMatch:      outputDef.TagMatch,
+		WriteTimeout:  outputDef.WriteTimeout,
+		AutoReorder:   outputDef.AutoReorder,
+
+		DeliveryConcurrency: outputDef.DeliveryConcurrency,
+
+		StampPipeline: outputDef.StampPipeline,
+		Shadow:        outputDef.Shadow,
+		SampleRate:    outputDef.SampleRate,
+		SampleKey:     outputDef.SampleKey,
+		RequireFields: outputDef.RequireFields,
+		AckMode:       outputDef.AckMode,
+		AckTimeout:    outputDef.AckTimeout,
+	}
+	pipeline.SetEnabled(outputDef.IsEnabled())
+	if !outputDef.IsEnabled() {
+		mainLog.Printf("Output pipeline '%s' is disabled (enable at runtime via POST /pipelines/%s/enable)", name, name)
 	}
 
-	if err := engine.AddOutputPipeline(pipeline); err != nil {
-		log.Fatalf("Error adding output pipeline '%s': %v", name, err)
+	if outputDef.AckMode == core.AckModeSync {
+		mainLog.Printf("Output pipeline '%s' has ack_mode sync: inputs acknowledge their source only once it delivered a log", name)
 	}
-	log.Printf("Using %s output plugin as '%s' (sources: %v, filters: %d)",
-		outputDef.Type, name, outputDef.Sources, len(filters))
-}
 
-func createInputPluginWrapper(pluginType string, name string, config map[string]any, engine *core.Engine) {
-	createInputPlugin(pluginType, name, config, engine)
-}
+	if outputDef.Shadow {
+		mainLog.Printf("Output pipeline '%s' is a shadow: it receives a copy of every log, unbuffered and isolated from the other outputs", name)
+	}
 
-func createOutputPipelineWrapper(name string, outputDef core.PluginDefinition, engine *core.Engine) {
-	createOutputPipeline(name, outputDef, engine)
+	mainLog.Printf("Using %s output plugin as '%s' (sources: %v, filters: %d)",
+		outputDef.Type, name, outputDef.Sources, len(filters))
+	return pipeline, nil
 }
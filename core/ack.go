@@ -0,0 +1,276 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline ack modes: whether the input that produced a log waits for the pipeline to deliver it
+const (
+	AckModeAsync = "async" // The input is done with a log once the engine received it (default)
+	AckModeSync  = "sync"  // The input acknowledges its source only once the output wrote the log
+)
+
+// DefaultAckTimeout bounds how long an input waits for a sync pipeline to deliver a log
+const DefaultAckTimeout = 30 * time.Second
+
+// ErrAckTimeout is returned by Ack.Wait when a sync pipeline did not deliver the log in time
+var ErrAckTimeout = errors.New("timed out waiting for delivery")
+
+// errAckSpilled fails the Ack of a buffered log written to disk: it is delivered
+// after a restart, too late for the input to wait for it
+var errAckSpilled = errors.New("buffer full, log queued on disk")
+
+// AckingInput is implemented by inputs that acknowledge their source once a log
+// is handled, such as Kafka committing an offset or SQS deleting a message. The
+// engine hands them a function reporting whether any pipeline has ack_mode: sync;
+// while it does, the input attaches an Ack to each log and acknowledges the
+// source only after Ack.Wait succeeds.
+type AckingInput interface {
+	SetAckRequired(required func() bool)
+}
+
+// Ack carries the delivery result of a log back to the input that sent it. The
+// engine settles it once the log has been dispatched and every sync pipeline
+// that took the log has delivered it, or on the first failure. Async pipelines
+// and pipelines that filtered the log out do not hold it up.
+type Ack struct {
+	mu        sync.Mutex
+	pending   int             // Sync deliveries not settled yet
+	routed    bool            // Dispatch is done, so no more deliveries can be added
+	err       error           // First failed delivery
+	deadline  time.Time       // Latest ack_timeout of the sync pipelines that took the log
+	delivered map[string]bool // Whether each sync pipeline that took the log delivered it
+
+	only map[string]bool // For a resend (see Retry): the pipelines to deliver to, nil for any
+
+	dispatchedCh chan struct{} // Closed once the log has been dispatched
+	doneCh       chan struct{} // Closed once the result is known
+}
+
+// NewAck creates an Ack to attach to a log with SetAck
+func NewAck() *Ack {
+	return &Ack{
+		dispatchedCh: make(chan struct{}),
+		doneCh:       make(chan struct{}),
+	}
+}
+
+// SetAck attaches an Ack to the log before it is sent to the engine
+func (l *Log) SetAck(ack *Ack) {
+	l.ack = ack
+}
+
+// Retry returns an Ack for resending the log after Wait failed. The resend goes
+// only to the sync pipelines that did not deliver the log, or whose delivery was
+// still pending when Wait gave up (such a pipeline may then get the log twice).
+// Other pipelines, the WAL and the engine's counters and quotas do not see it again.
+func (a *Ack) Retry() *Ack {
+	retry := NewAck()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	retry.only = make(map[string]bool, len(a.delivered))
+	for pipeline, delivered := range a.delivered {
+		if !delivered {
+			retry.only[pipeline] = true
+		}
+	}
+	if len(retry.only) == 0 {
+		// Nothing failed that can be named (e.g. the pipelines were removed since):
+		// aim where the previous attempt did, every pipeline for a first attempt
+		retry.only = a.only
+	}
+	return retry
+}
+
+// retry reports whether the Ack belongs to a resend made with Retry
+func (a *Ack) retry() bool {
+	return a != nil && a.only != nil
+}
+
+// includes reports whether the log may be dispatched to pipeline: always, except
+// for a resend, which only goes to the sync pipelines that did not deliver it
+func (a *Ack) includes(pipeline *OutputPipeline) bool {
+	return a == nil || a.only == nil || a.only[pipeline.Name]
+}
+
+// Wait blocks until the log was delivered by every sync pipeline that took it.
+// It returns the first delivery error, ErrAckTimeout when a pipeline's
+// ack_timeout passed first, or ctx's error. The timeout starts when the log is
+// dispatched, so time spent queued in the engine (e.g. while it is paused) only
+// ends with ctx. A nil Ack has nothing to wait for.
+func (a *Ack) Wait(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+
+	// A settled result wins over a cancelled ctx
+	select {
+	case <-a.doneCh:
+		return a.result()
+	default:
+	}
+
+	select {
+	case <-a.dispatchedCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	a.mu.Lock()
+	deadline := a.deadline
+	a.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-a.doneCh:
+		return a.result()
+	case <-timer.C:
+		// The last delivery may have settled right at the deadline
+		select {
+		case <-a.doneCh:
+			return a.result()
+		default:
+		}
+		a.mu.Lock()
+		pending := a.pending
+		a.mu.Unlock()
+		return fmt.Errorf("%w (%d outputs pending)", ErrAckTimeout, pending)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// result returns the first delivery error, once doneCh is closed
+func (a *Ack) result() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// expect registers a delivery to a sync pipeline, which must settle within timeout
+func (a *Ack) expect(pipeline string, timeout time.Duration) *ackDelivery {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending++
+	if a.delivered == nil {
+		a.delivered = make(map[string]bool, 1)
+	}
+	a.delivered[pipeline] = false
+	if deadline := time.Now().Add(timeout); deadline.After(a.deadline) {
+		a.deadline = deadline
+	}
+	return &ackDelivery{ack: a, pipeline: pipeline}
+}
+
+// dispatched marks the end of dispatch: the Ack settles as soon as no sync delivery is pending
+func (a *Ack) dispatched() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.routed {
+		return
+	}
+	a.routed = true
+	close(a.dispatchedCh)
+	a.settleLocked()
+}
+
+// settle records the result of one sync pipeline's delivery
+func (a *Ack) settle(pipeline string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending--
+	if err == nil {
+		a.delivered[pipeline] = true
+	} else if a.err == nil {
+		a.err = err
+	}
+	a.settleLocked()
+}
+
+// settleLocked closes doneCh once dispatch is done and either every delivery
+// succeeded or one failed, which already decides the result
+func (a *Ack) settleLocked() {
+	if !a.routed || (a.pending > 0 && a.err == nil) {
+		return
+	}
+	select {
+	case <-a.doneCh:
+	default:
+		close(a.doneCh)
+	}
+}
+
+// ackDelivery is the delivery of a log to one sync pipeline. It travels with the
+// pipeline's copy of the log, into the output buffer if there is one, and
+// settles the Ack exactly once however many copies of it are made.
+type ackDelivery struct {
+	ack      *Ack
+	pipeline string
+	settled  atomic.Bool
+}
+
+// settleAck reports whether the log was delivered to the sync pipeline holding
+// it. Logs of async pipelines have no delivery and are left alone.
+func (l *Log) settleAck(err error) {
+	d := l.delivery
+	if d == nil || !d.settled.CompareAndSwap(false, true) {
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("output '%s': %w", d.pipeline, err)
+	}
+	d.ack.settle(d.pipeline, err)
+}
+
+// ackMode returns the pipeline's ack mode, async when unset
+func (p *OutputPipeline) ackMode() string {
+	if p.AckMode == "" || p.Shadow {
+		return AckModeAsync
+	}
+	return p.AckMode
+}
+
+// ackTimeout returns how long an input waits for the pipeline to deliver a log
+func (p *OutputPipeline) ackTimeout() time.Duration {
+	if p.AckTimeout > 0 {
+		return p.AckTimeout
+	}
+	return DefaultAckTimeout
+}
+
+// expectAck returns the copy of a log to write to a sync pipeline, carrying the
+// delivery that settles the log's Ack. Other pipelines get the log unchanged.
+// shared reports whether entry is also seen by other pipelines.
+func (p *OutputPipeline) expectAck(ack *Ack, entry *Log, shared bool) *Log {
+	if ack == nil || p.ackMode() != AckModeSync {
+		return entry
+	}
+	if shared {
+		entry = entry.Clone()
+	}
+	entry.delivery = ack.expect(p.Name, p.ackTimeout())
+	return entry
+}
+
+// ackRequired reports whether any pipeline has ack_mode: sync, so inputs know
+// to attach an Ack to their logs
+func (e *Engine) ackRequired() bool {
+	for _, pipeline := range e.plugins.Load().pipelines {
+		if pipeline.ackMode() == AckModeSync {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ackOutput fails the logs whose message contains reject
+type ackOutput struct {
+	mockOutput
+	reject string
+}
+
+func (o *ackOutput) Write(logEntry *Log) error {
+	if o.reject != "" && strings.Contains(logEntry.Message, o.reject) {
+		return errors.New("rejected by backend")
+	}
+	return o.mockOutput.Write(logEntry)
+}
+
+func TestAck_Wait(t *testing.T) {
+	var none *Ack
+	if err := none.Wait(context.Background()); err != nil {
+		t.Errorf("Expected a nil Ack to have nothing to wait for, got %v", err)
+	}
+
+	// Only async pipelines took the log
+	ack := NewAck()
+	ack.dispatched()
+	if err := ack.Wait(context.Background()); err != nil {
+		t.Errorf("Expected no sync delivery to succeed at once, got %v", err)
+	}
+
+	ack = NewAck()
+	first, second := ack.expect("a", time.Minute), ack.expect("b", time.Minute)
+	ack.dispatched()
+	go (&Log{delivery: first}).settleAck(nil)
+	go (&Log{delivery: second}).settleAck(nil)
+	if err := ack.Wait(context.Background()); err != nil {
+		t.Errorf("Expected both deliveries to succeed, got %v", err)
+	}
+
+	// The first failure decides the result without waiting for the other pipelines
+	ack = NewAck()
+	failed := &Log{delivery: ack.expect("a", time.Minute)}
+	ack.expect("b", time.Minute)
+	ack.dispatched()
+	failed.settleAck(errors.New("boom"))
+	failed.Clone().settleAck(nil) // A copy of a settled delivery changes nothing
+	if err := ack.Wait(context.Background()); err == nil || err.Error() != "output 'a': boom" {
+		t.Errorf("Expected the failed output's error, got %v", err)
+	}
+}
+
+func TestAck_WaitTimeout(t *testing.T) {
+	ack := NewAck()
+	ack.expect("slow", 20*time.Millisecond)
+	ack.dispatched()
+	if err := ack.Wait(context.Background()); !errors.Is(err, ErrAckTimeout) {
+		t.Errorf("Expected ErrAckTimeout, got %v", err)
+	}
+
+	// Logs still queued in the engine are only waited for until ctx ends
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := NewAck().Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ctx's error before dispatch, got %v", err)
+	}
+}
+
+func TestEngine_AckMode(t *testing.T) {
+	engine := NewEngine()
+	syncOut := &ackOutput{reject: "bad"}
+	asyncOut := &ackOutput{reject: " "} // Fails both messages below
+	pipelines := []*OutputPipeline{
+		{Name: "durable", Output: syncOut, AckMode: AckModeSync},
+		{Name: "best-effort", Output: asyncOut, AckMode: AckModeAsync},
+		{Name: "errors", Output: newMockOutput(), AckMode: AckModeSync, Filters: []FilterPlugin{newMockFilter(false)}},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+	if !engine.ackRequired() {
+		t.Fatal("Expected a sync pipeline to require acks")
+	}
+
+	dispatch := func(message string) error {
+		logEntry := NewLog("info", message)
+		ack := NewAck()
+		logEntry.SetAck(ack)
+		engine.dispatchLog(logEntry)
+		return ack.Wait(context.Background())
+	}
+
+	// Failures of async pipelines and filtered out sync pipelines do not count
+	if err := dispatch("all good"); err != nil {
+		t.Errorf("Expected the sync delivery to succeed, got %v", err)
+	}
+	if err := dispatch("bad payload"); err == nil || !strings.Contains(err.Error(), "output 'durable'") {
+		t.Errorf("Expected the sync output's failure, got %v", err)
+	}
+
+	// The sync pipeline got its own copy, so the delivery never leaks to other outputs
+	if logs := syncOut.getLogs(); len(logs) != 1 || logs[0].delivery == nil {
+		t.Fatalf("Expected the delivered copy to carry the delivery, got %d logs", len(logs))
+	}
+	if logs := asyncOut.getLogs(); len(logs) != 0 {
+		t.Errorf("Expected the async output to reject everything, got %d logs", len(logs))
+	}
+}
+
+func TestEngine_AckModeBuffered(t *testing.T) {
+	engine := NewEngine()
+	output := &ackOutput{reject: "bad"}
+	buffer, err := NewOutputBuffer("durable", output, retryOverflowConfig(t.TempDir(), 0, ""))
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+
+	pipeline := &OutputPipeline{Name: "durable", Output: output, Buffer: buffer, AckMode: AckModeSync, AckTimeout: 200 * time.Millisecond}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	delivered := NewLog("info", "ok")
+	delivered.SetAck(NewAck())
+	engine.dispatchLog(delivered)
+	if err := delivered.ack.Wait(context.Background()); err != nil {
+		t.Errorf("Expected the buffered delivery to succeed, got %v", err)
+	}
+	if output.getCallCount() != 1 {
+		t.Errorf("Expected the log to be written before the ack, got %d writes", output.getCallCount())
+	}
+
+	// Retried logs settle once delivered or given up; a retry an hour away outlasts ack_timeout
+	retried := NewLog("info", "bad payload")
+	retried.SetAck(NewAck())
+	engine.dispatchLog(retried)
+	if err := retried.ack.Wait(context.Background()); !errors.Is(err, ErrAckTimeout) {
+		t.Errorf("Expected ErrAckTimeout while the log waits to be retried, got %v", err)
+	}
+}
+
+func TestEngine_AckRetry(t *testing.T) {
+	engine := NewEngine()
+	flaky := &ackOutput{reject: "order"}
+	archive := newMockOutput()
+	console := newMockOutput()
+	pipelines := []*OutputPipeline{
+		{Name: "durable", Output: flaky, AckMode: AckModeSync},
+		{Name: "archive", Output: archive, AckMode: AckModeSync},
+		{Name: "console", Output: console},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	send := func(ack *Ack) error {
+		logEntry := NewLog("info", "order placed")
+		logEntry.SetAck(ack)
+		if engine.receiveLog(logEntry) {
+			engine.dispatchLog(logEntry)
+		}
+		return ack.Wait(context.Background())
+	}
+
+	ack := NewAck()
+	if err := send(ack); err == nil {
+		t.Fatal("Expected the first attempt to fail")
+	}
+
+	// The resend reaches only the pipeline that failed and is not counted again
+	flaky.reject = ""
+	retry := ack.Retry()
+	if err := send(retry); err != nil {
+		t.Fatalf("Expected the resend to be delivered, got %v", err)
+	}
+	if len(flaky.getLogs()) != 1 || len(archive.getLogs()) != 1 || len(console.getLogs()) != 1 {
+		t.Errorf("Expected one copy per output, got durable=%d archive=%d console=%d",
+			len(flaky.getLogs()), len(archive.getLogs()), len(console.getLogs()))
+	}
+	if engine.totalLogsProcessed != 1 {
+		t.Errorf("Expected the resend not to be counted again, got %d logs processed", engine.totalLogsProcessed)
+	}
+
+	// A delivery still pending when Wait gave up is resent as well
+	slow := NewAck()
+	slow.expect("durable", time.Minute)
+	slow.expect("archive", time.Minute).settled.Store(true)
+	slow.settle("archive", nil)
+	slow.dispatched()
+	if only := slow.Retry().only; len(only) != 1 || !only["durable"] {
+		t.Errorf("Expected only the pending pipeline to be retried, got %v", only)
+	}
+}
+
+func TestEngine_AckRequired(t *testing.T) {
+	engine := NewEngine()
+	if engine.ackRequired() {
+		t.Error("Expected no ack required without a sync pipeline")
+	}
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "durable", Output: newMockOutput(), AckMode: AckModeSync, Shadow: true}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	if engine.ackRequired() {
+		t.Error("Expected a shadow pipeline never to require acks")
+	}
+}
+
+func TestPluginDefinition_AckModeValidate(t *testing.T) {
+	def := PluginDefinition{Type: "console", Config: map[string]any{"target": "stdout"}, AckMode: AckModeSync, AckTimeout: 5 * time.Second}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	def.AckMode = "eventually"
+	if err := def.Validate(); err == nil || !strings.Contains(err.Error(), "must be 'async' or 'sync'") {
+		t.Errorf("Expected an unknown ack_mode to be rejected, got %v", err)
+	}
+	def.AckMode = ""
+	if err := def.Validate(); err == nil || !strings.Contains(err.Error(), "requires ack_mode: sync") {
+		t.Errorf("Expected ack_timeout without ack_mode sync to be rejected, got %v", err)
+	}
+	def.AckMode, def.AckTimeout, def.Shadow = AckModeSync, 0, true
+	if err := def.Validate(); err == nil {
+		t.Error("Expected ack_mode on a shadow output to be rejected")
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Retry jitter modes for backoff delays
+const (
+	JitterNone  = "none"  // Exact exponential delays (default)
+	JitterFull  = "full"  // Uniform in [0, delay]
+	JitterEqual = "equal" // Half the delay plus uniform in [0, delay/2]
+)
+
+// JitterSource returns a random value in [0, 1). Buffers and resilient plugins
+// use rand.Float64 by default; tests replace it for deterministic delays.
+type JitterSource func() float64
+
+// defaultJitterSource is the JitterSource used unless one is set
+var defaultJitterSource JitterSource = rand.Float64
+
+// ValidateJitter returns an error for an unknown jitter mode ("" means none)
+func ValidateJitter(mode string) error {
+	switch mode {
+	case "", JitterNone, JitterFull, JitterEqual:
+		return nil
+	default:
+		return fmt.Errorf("invalid retry jitter '%s', must be '%s', '%s' or '%s'", mode, JitterNone, JitterFull, JitterEqual)
+	}
+}
+
+// ApplyJitter randomizes a backoff delay that is already capped at its maximum.
+// Jitter only ever shortens the delay, so the cap still holds afterwards.
+func ApplyJitter(delay time.Duration, mode string, random JitterSource) time.Duration {
+	if delay <= 0 {
+		return delay
+	}
+	if random == nil {
+		random = defaultJitterSource
+	}
+
+	switch mode {
+	case JitterFull:
+		return time.Duration(random() * float64(delay))
+	case JitterEqual:
+		half := delay / 2
+		return delay - half + time.Duration(random()*float64(half))
+	default:
+		return delay
+	}
+}
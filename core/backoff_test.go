@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		mode     string
+		random   float64
+		expected time.Duration
+	}{
+		{"none", time.Second, JitterNone, 0.5, time.Second},
+		{"unset", time.Second, "", 0.5, time.Second},
+		{"full low", time.Second, JitterFull, 0, 0},
+		{"full mid", time.Second, JitterFull, 0.25, 250 * time.Millisecond},
+		{"equal low", time.Second, JitterEqual, 0, 500 * time.Millisecond},
+		{"equal mid", time.Second, JitterEqual, 0.5, 750 * time.Millisecond},
+		{"zero delay", 0, JitterEqual, 0.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApplyJitter(tt.delay, tt.mode, func() float64 { return tt.random })
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyJitterRespectsCap(t *testing.T) {
+	// The largest value a source may return must not push the delay past the capped input
+	almostOne := func() float64 { return 0.999999999 }
+	for _, mode := range []string{JitterNone, JitterFull, JitterEqual} {
+		if got := ApplyJitter(time.Minute, mode, almostOne); got > time.Minute {
+			t.Errorf("Expected %s jitter to stay within 1m, got %v", mode, got)
+		}
+	}
+}
+
+func TestValidateJitter(t *testing.T) {
+	for _, mode := range []string{"", JitterNone, JitterFull, JitterEqual} {
+		if err := ValidateJitter(mode); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := ValidateJitter("decorrelated"); err == nil {
+		t.Error("Expected an error for an unknown jitter mode")
+	}
+}
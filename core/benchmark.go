@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBenchmarkDuration is how long logs are generated by default
+	DefaultBenchmarkDuration = 10 * time.Second
+	// DefaultBenchmarkMessageSize is the default size of a synthetic message in bytes
+	DefaultBenchmarkMessageSize = 128
+	// DefaultBenchmarkDrainTimeout bounds the wait for in-flight logs after generation stops
+	DefaultBenchmarkDrainTimeout = 10 * time.Second
+
+	// maxLatencySamples bounds the memory used for latency percentiles
+	maxLatencySamples = 100000
+)
+
+// BenchmarkConfig configures a throughput self-test
+type BenchmarkConfig struct {
+	Rate         int           // Target logs per second across all generators (0 = as fast as possible)
+	Duration     time.Duration // How long logs are generated (default: 10s)
+	Generators   int           // Concurrent generators (default: GOMAXPROCS)
+	MessageSize  int           // Bytes per synthetic message (default: 128)
+	DrainTimeout time.Duration // Wait for in-flight logs after generation stops (default: 10s)
+}
+
+// Validate validates the benchmark configuration
+func (c BenchmarkConfig) Validate() error {
+	if c.Rate < 0 || c.Generators < 0 || c.MessageSize < 0 {
+		return fmt.Errorf("rate, generators and message size must be non-negative")
+	}
+	if c.Duration < 0 || c.DrainTimeout < 0 {
+		return fmt.Errorf("duration and drain timeout must be non-negative")
+	}
+	return nil
+}
+
+// withDefaults returns the configuration with defaults applied
+func (c BenchmarkConfig) withDefaults() BenchmarkConfig {
+	if c.Duration == 0 {
+		c.Duration = DefaultBenchmarkDuration
+	}
+	if c.Generators == 0 {
+		c.Generators = runtime.GOMAXPROCS(0)
+	}
+	if c.MessageSize == 0 {
+		c.MessageSize = DefaultBenchmarkMessageSize
+	}
+	if c.DrainTimeout == 0 {
+		c.DrainTimeout = DefaultBenchmarkDrainTimeout
+	}
+	return c
+}
+
+// BenchmarkResult reports what a benchmark run achieved
+type BenchmarkResult struct {
+	Generated  int64            // Logs sent to the input channel
+	Processed  int64            // Logs read by the engine
+	Delivered  int64            // Writes that reached an instrumented output (one per output per log)
+	Dropped    map[string]int64 // Dropped logs by reason
+	Elapsed    time.Duration    // From the first generated log to the last delivery
+	Throughput float64          // Processed logs per second
+	LatencyP50 time.Duration    // Generation to output write, median
+	LatencyP99 time.Duration    // Generation to output write, 99th percentile
+	LatencyMax time.Duration    // Generation to output write, worst case
+}
+
+// String formats the result as a short report
+func (r BenchmarkResult) String() string {
+	var dropped int64
+	reasons := make([]string, 0, len(r.Dropped))
+	for reason, count := range r.Dropped {
+		dropped += count
+		reasons = append(reasons, fmt.Sprintf("%s:%d", reason, count))
+	}
+	sort.Strings(reasons)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "generated:  %d\n", r.Generated)
+	fmt.Fprintf(&b, "processed:  %d\n", r.Processed)
+	fmt.Fprintf(&b, "delivered:  %d\n", r.Delivered)
+	fmt.Fprintf(&b, "dropped:    %d [%s]\n", dropped, strings.Join(reasons, " "))
+	fmt.Fprintf(&b, "elapsed:    %s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "throughput: %.0f logs/s\n", r.Throughput)
+	fmt.Fprintf(&b, "latency:    p50=%s p99=%s max=%s\n", r.LatencyP50, r.LatencyP99, r.LatencyMax)
+	return b.String()
+}
+
+// Benchmark generates synthetic logs at a target rate through an engine and
+// measures throughput, latency and drops. Outputs are timed by wrapping them
+// with Instrument before their pipelines are added to the engine, so buffered
+// outputs are timed at delivery rather than at enqueue.
+type Benchmark struct {
+	config    BenchmarkConfig
+	latencies latencyRecorder
+}
+
+// NewBenchmark creates a benchmark with defaults applied
+func NewBenchmark(config BenchmarkConfig) (*Benchmark, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Benchmark{config: config.withDefaults()}, nil
+}
+
+// Instrument wraps an output so its writes are counted and timed
+func (b *Benchmark) Instrument(output OutputPlugin) OutputPlugin {
+	return &benchmarkOutput{OutputPlugin: output, latencies: &b.latencies}
+}
+
+// Run starts the engine, generates logs for the configured duration, waits for
+// in-flight logs to drain and stops the engine. The engine must not be started.
+// Engine logging is per log, so callers usually discard the log output first.
+func (b *Benchmark) Run(ctx context.Context, engine *Engine) BenchmarkResult {
+	engine.Start()
+
+	start := time.Now()
+	generated := b.generate(ctx, engine.inputCh)
+	b.drain(ctx, engine, generated)
+	engine.Stop()
+
+	stats := engine.Stats()
+	result := BenchmarkResult{
+		Generated: generated,
+		Processed: stats.TotalLogsProcessed,
+		Delivered: b.latencies.count.Load(),
+		Dropped:   stats.LogsDropped,
+		Elapsed:   time.Since(start),
+	}
+	if last := b.latencies.last.Load(); last > 0 {
+		result.Elapsed = time.Unix(0, last).Sub(start)
+	}
+	if result.Elapsed > 0 {
+		result.Throughput = float64(result.Processed) / result.Elapsed.Seconds()
+	}
+	result.LatencyP50, result.LatencyP99, result.LatencyMax = b.latencies.percentiles()
+	return result
+}
+
+// generate runs the generators until the duration elapses and returns the number of logs sent
+func (b *Benchmark) generate(ctx context.Context, inputCh chan<- *Log) int64 {
+	ctx, cancel := context.WithTimeout(ctx, b.config.Duration)
+	defer cancel()
+
+	var sent atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < b.config.Generators; i++ {
+		// Split the rate evenly, giving the remainder to the first generators
+		rate := 0
+		if b.config.Rate > 0 {
+			rate = b.config.Rate / b.config.Generators
+			if i < b.config.Rate%b.config.Generators {
+				rate++
+			}
+			if rate == 0 {
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func(id, rate int) {
+			defer wg.Done()
+			sent.Add(b.runGenerator(ctx, id, rate, inputCh))
+		}(i, rate)
+	}
+	wg.Wait()
+	return sent.Load()
+}
+
+// runGenerator sends logs at rate per second (0 = unpaced) until ctx is done.
+// Sends block while the engine is behind, like a real input under backpressure.
+func (b *Benchmark) runGenerator(ctx context.Context, id, rate int, inputCh chan<- *Log) int64 {
+	levels := []string{"debug", "info", "info", "info", "warn", "error"}
+	messages := syntheticMessages(id, b.config.MessageSize)
+	source := fmt.Sprintf("bench-%d", id)
+
+	start := time.Now()
+	var sent int64
+	for {
+		if rate > 0 {
+			due := int64(time.Since(start).Seconds() * float64(rate))
+			if sent >= due {
+				wait := time.Duration(float64(sent+1-due) / float64(rate) * float64(time.Second))
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return sent
+				}
+				continue
+			}
+		}
+
+		i := int(sent) % len(messages)
+		logEntry := NewLog(levels[i%len(levels)], messages[i])
+		logEntry.Source = source
+		select {
+		case inputCh <- logEntry:
+			sent++
+		case <-ctx.Done():
+			return sent
+		}
+	}
+}
+
+// syntheticMessages builds a small set of messages of about size bytes
+func syntheticMessages(id, size int) []string {
+	messages := make([]string, 64)
+	for i := range messages {
+		prefix := fmt.Sprintf("generator=%d seq=%d user=user%d action=request path=/api/v1/items/%d ", id, i, i%7, i)
+		if len(prefix) >= size {
+			messages[i] = prefix[:size]
+			continue
+		}
+		messages[i] = prefix + strings.Repeat("x", size-len(prefix))
+	}
+	return messages
+}
+
+// drain waits until the engine has read every generated log and deliveries have
+// settled, or the drain timeout expires
+func (b *Benchmark) drain(ctx context.Context, engine *Engine, generated int64) {
+	deadline := time.Now().Add(b.config.DrainTimeout)
+	lastDelivered := int64(-1)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		processed := engine.Stats().TotalLogsProcessed
+		delivered := b.latencies.count.Load()
+		if processed >= generated && delivered == lastDelivered {
+			return
+		}
+		lastDelivered = delivered
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// benchmarkOutput records the latency of each write to the wrapped output
+type benchmarkOutput struct {
+	OutputPlugin
+	latencies *latencyRecorder
+}
+
+// Write writes to the wrapped output and records the latency of successful writes
+func (o *benchmarkOutput) Write(logEntry *Log) error {
+	if err := o.OutputPlugin.Write(logEntry); err != nil {
+		return err
+	}
+	o.latencies.record(time.Since(logEntry.Timestamp))
+	return nil
+}
+
+// latencyRecorder keeps a uniform sample of latencies (reservoir sampling) so
+// percentiles stay accurate with bounded memory
+type latencyRecorder struct {
+	count atomic.Int64
+	last  atomic.Int64 // Unix nanoseconds of the last recorded write
+
+	mu      sync.Mutex
+	samples []time.Duration
+	max     time.Duration
+}
+
+func (r *latencyRecorder) record(latency time.Duration) {
+	n := r.count.Add(1)
+	r.last.Store(time.Now().UnixNano())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if latency > r.max {
+		r.max = latency
+	}
+	if len(r.samples) < maxLatencySamples {
+		r.samples = append(r.samples, latency)
+	} else if i := rand.Int64N(n); i < maxLatencySamples {
+		r.samples[i] = latency
+	}
+}
+
+// percentiles returns the median, 99th percentile and maximum latency
+func (r *latencyRecorder) percentiles() (p50, p99, maxLatency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.samples) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]time.Duration(nil), r.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	at := func(q float64) time.Duration {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	return at(0.50), at(0.99), r.max
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkRun(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	benchmark, err := NewBenchmark(BenchmarkConfig{Duration: 100 * time.Millisecond, Generators: 2})
+	if err != nil {
+		t.Fatalf("Failed to create benchmark: %v", err)
+	}
+
+	engine := NewEngine()
+	output := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "kept", Output: benchmark.Instrument(output)}); err != nil {
+		t.Fatalf("Failed to add pipeline: %v", err)
+	}
+	blocked := &OutputPipeline{Name: "blocked", Output: benchmark.Instrument(newMockOutput()), Filters: []FilterPlugin{newMockFilter(false)}}
+	if err := engine.AddOutputPipeline(blocked); err != nil {
+		t.Fatalf("Failed to add pipeline: %v", err)
+	}
+
+	result := benchmark.Run(context.Background(), engine)
+
+	if result.Generated == 0 {
+		t.Fatal("Expected the generators to send logs")
+	}
+	if result.Processed != result.Generated {
+		t.Errorf("Expected every generated log processed, generated %d, processed %d", result.Generated, result.Processed)
+	}
+	if result.Delivered != result.Generated || int64(output.getCallCount()) != result.Generated {
+		t.Errorf("Expected %d deliveries, got %d (output saw %d)", result.Generated, result.Delivered, output.getCallCount())
+	}
+	if result.Dropped[DropReasonFilter] != result.Generated {
+		t.Errorf("Expected %d filter drops, got %d", result.Generated, result.Dropped[DropReasonFilter])
+	}
+	if result.Throughput <= 0 || result.LatencyP99 <= 0 || result.LatencyP99 < result.LatencyP50 || result.LatencyMax < result.LatencyP99 {
+		t.Errorf("Expected positive throughput and ordered latencies, got %+v", result)
+	}
+	if report := result.String(); !strings.Contains(report, "throughput:") || !strings.Contains(report, "filter:") {
+		t.Errorf("Expected throughput and drop reasons in the report, got %q", report)
+	}
+}
+
+func TestBenchmarkRate(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	benchmark, err := NewBenchmark(BenchmarkConfig{Rate: 1000, Duration: 300 * time.Millisecond, Generators: 3})
+	if err != nil {
+		t.Fatalf("Failed to create benchmark: %v", err)
+	}
+	engine := NewEngine()
+	engine.AddOutput(benchmark.Instrument(newMockOutput()))
+
+	result := benchmark.Run(context.Background(), engine)
+
+	// 1000 logs/s for 300ms, with room for timer jitter
+	if result.Generated < 250 || result.Generated > 320 {
+		t.Errorf("Expected about 300 logs at 1000/s, got %d", result.Generated)
+	}
+}
+
+func TestBenchmarkConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BenchmarkConfig
+	}{
+		{name: "negative rate", config: BenchmarkConfig{Rate: -1}},
+		{name: "negative generators", config: BenchmarkConfig{Generators: -1}},
+		{name: "negative duration", config: BenchmarkConfig{Duration: -time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBenchmark(tt.config); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestLatencyRecorderPercentiles(t *testing.T) {
+	var recorder latencyRecorder
+	for i := 1; i <= 1000; i++ {
+		recorder.record(time.Duration(i) * time.Millisecond)
+	}
+
+	p50, p99, maxLatency := recorder.percentiles()
+	if p50 != 500*time.Millisecond || p99 != 990*time.Millisecond || maxLatency != time.Second {
+		t.Errorf("Expected p50=500ms p99=990ms max=1s, got p50=%s p99=%s max=%s", p50, p99, maxLatency)
+	}
+}
@@ -2,8 +2,10 @@ package core
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,13 +13,23 @@ import (
 	"github.com/fsnotify/fsnotify"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/mbiondo/logAnalyzer/pkg/auth"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 	"gopkg.in/yaml.v3"
 )
 
 // APIConfig defines API server configuration
 type APIConfig struct {
-	Enabled bool `yaml:"enabled"` // Enable/disable API server
-	Port    int  `yaml:"port"`    // Port for the API server
+	Enabled     bool   `yaml:"enabled"`                // Enable/disable API server
+	Port        int    `yaml:"port"`                   // Port for the API server
+	BindAddress string `yaml:"bind_address,omitempty"` // Interface to bind to (default: all interfaces)
+	UnixSocket  string `yaml:"unix_socket,omitempty"`  // Optional additional Unix socket listener path
+	WriteBack   string `yaml:"write_back,omitempty"`   // Config file rewritten after plugins change through the API (default: changes are lost on restart)
+
+	// TLS configuration for the API server
+	TLS      tlsconfig.Config `yaml:"tls,omitempty"`
+	CertFile string           `yaml:"cert_file,omitempty"` // Server certificate file (required when TLS is enabled)
+	KeyFile  string           `yaml:"key_file,omitempty"`  // Server key file (required when TLS is enabled)
 
 	// Authentication configuration
 	Auth APIAuthConfig `yaml:"auth,omitempty"`
@@ -56,6 +68,58 @@ func (a APIAuthConfig) Validate() error {
 	)
 }
 
+// LoggingConfig configures LogAnalyzer's own internal logs
+type LoggingConfig struct {
+	Format string `yaml:"format,omitempty"` // "text" (logfmt, default) or "json" (one object per line)
+
+	RateLimits     map[string]int `yaml:"rate_limits,omitempty"`     // Lines per second by component (e.g. buffer: 10); the rest are counted and reported
+	ReportInterval time.Duration  `yaml:"report_interval,omitempty"` // How often suppressed lines are reported (default: 10s)
+}
+
+// Validate validates the LoggingConfig
+func (c LoggingConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Format, validation.In(logging.FormatText, logging.FormatJSON).Error("must be 'text' or 'json'")),
+		validation.Field(&c.RateLimits, validation.By(func(any) error {
+			for component, limit := range c.RateLimits {
+				if limit < 1 {
+					return fmt.Errorf("%s must be at least 1 line per second", component)
+				}
+			}
+			return nil
+		})),
+		validation.Field(&c.ReportInterval, validation.Min(time.Duration(0)).Error("must be no less than 0")),
+	)
+}
+
+// Apply sets the format and rate limits of every internal logger
+func (c LoggingConfig) Apply() error {
+	if err := logging.SetFormat(c.Format); err != nil {
+		return err
+	}
+	logging.SetRateLimits(c.RateLimits, c.ReportInterval)
+	return nil
+}
+
+// ResilienceConfig sets the resilience defaults for every input and output
+type ResilienceConfig struct {
+	Enabled  *bool `yaml:"enabled,omitempty"`   // Wrap plugins so they connect and retry in the background (default: true)
+	FailFast bool  `yaml:"fail_fast,omitempty"` // Fail startup when a resilient plugin cannot be created on the first attempt
+}
+
+// IsEnabled reports whether plugins are resilient unless they set "resilient" themselves
+func (c ResilienceConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// Resilient reports whether a plugin is resilient: its own "resilient" key wins over the default
+func (c ResilienceConfig) Resilient(pluginConfig map[string]any) bool {
+	if enabled, ok := pluginConfig["resilient"].(bool); ok {
+		return enabled
+	}
+	return c.IsEnabled()
+}
+
 // APIKeyConfig defines an API key configuration
 type APIKeyConfig = auth.APIKeyConfig
 
@@ -80,6 +144,31 @@ type Config struct {
 	Persistence  PersistenceConfig  `yaml:"persistence,omitempty"`
 	OutputBuffer OutputBufferConfig `yaml:"output_buffer,omitempty"`
 	API          APIConfig          `yaml:"api,omitempty"`
+	Levels       LevelsConfig       `yaml:"levels,omitempty"`
+	Pause        PauseConfig        `yaml:"pause,omitempty"`
+	ReloadAudit  ReloadAuditConfig  `yaml:"reload_audit,omitempty"`
+	Logging      LoggingConfig      `yaml:"logging,omitempty"`
+	Resilience   ResilienceConfig   `yaml:"resilience,omitempty"`
+	Trace        TraceConfig        `yaml:"trace,omitempty"`
+	Lint         LinterConfig       `yaml:"lint,omitempty"`
+	Defaults     DefaultsConfig     `yaml:"defaults,omitempty"`
+
+	StatsInterval time.Duration `yaml:"stats_interval,omitempty"` // Log a stats summary at this interval (0 = disabled)
+	DiskBudget    int64         `yaml:"disk_budget,omitempty"`    // Max bytes of WAL, buffer and DLQ files together (0 = unlimited)
+
+	SerializationCache bool `yaml:"serialization_cache,omitempty"` // Encode each log once per JSON format across outputs
+
+	MaxLogAge time.Duration `yaml:"max_log_age,omitempty"` // Drop logs whose timestamp is older than this (0 = disabled)
+
+	StartupGrace time.Duration `yaml:"startup_grace,omitempty"` // Max wait at startup for outputs to become healthy before inputs start (0 = disabled)
+
+	MetadataStorage MetadataStorageConfig `yaml:"metadata_storage,omitempty"`
+	Deadline        DeadlineConfig        `yaml:"deadline,omitempty"`
+	ErrorContext    ErrorContextConfig    `yaml:"error_context,omitempty"`
+
+	PreserveIf []PreserveCondition `yaml:"preserve_if,omitempty"` // Logs that sampling, rate limiting and burst filters always pass
+
+	SourceQuotas SourceQuotaConfig `yaml:"source_quotas,omitempty"` // Max logs per second per source
 }
 
 // Validate validates the Config
@@ -88,8 +177,34 @@ func (c Config) Validate() error {
 		validation.Field(&c.Inputs, validation.Required.Error("cannot be blank"), validation.Length(1, 100), validation.Each(validation.Required)),
 		validation.Field(&c.Outputs, validation.Required.Error("cannot be blank"), validation.Length(1, 100), validation.Each(validation.Required)),
 		validation.Field(&c.API),
-		validation.Field(&c.Persistence),
+		validation.Field(&c.Persistence, validation.By(func(value interface{}) error {
+			return c.Persistence.validateMinLevel(c.Levels)
+		})),
 		validation.Field(&c.OutputBuffer),
+		validation.Field(&c.Levels),
+		validation.Field(&c.Pause, validation.By(func(value interface{}) error {
+			if c.Pause.Mode == PauseModePersist && !c.Persistence.Enabled {
+				return fmt.Errorf("mode 'persist' requires persistence to be enabled")
+			}
+			return nil
+		})),
+		validation.Field(&c.ReloadAudit),
+		validation.Field(&c.Logging),
+		validation.Field(&c.Trace),
+		validation.Field(&c.Lint),
+		validation.Field(&c.MetadataStorage),
+		validation.Field(&c.Deadline, validation.By(func(value interface{}) error {
+			return c.Deadline.validateLateOutput(c.Outputs)
+		})),
+		validation.Field(&c.ErrorContext, validation.By(func(value interface{}) error {
+			return c.ErrorContext.validateAgainst(c.Levels, c.Outputs)
+		})),
+		validation.Field(&c.PreserveIf),
+		validation.Field(&c.SourceQuotas),
+		validation.Field(&c.StatsInterval, validation.Min(time.Duration(0)).Error("must be no less than 0")),
+		validation.Field(&c.DiskBudget, validation.Min(int64(0)).Error("must be no less than 0")),
+		validation.Field(&c.MaxLogAge, validation.Min(time.Duration(0)).Error("must be no less than 0")),
+		validation.Field(&c.StartupGrace, validation.Min(time.Duration(0)).Error("must be no less than 0")),
 	)
 }
 
@@ -102,16 +217,54 @@ type PluginDefinition struct {
 	// Output-specific options
 	Sources []string           `yaml:"sources,omitempty"` // Input sources to accept logs from (empty = all)
 	Filters []PluginDefinition `yaml:"filters,omitempty"` // Filters to apply before this output
+	Enabled *bool              `yaml:"enabled,omitempty"` // Whether this output pipeline or filter starts enabled (default: true)
+
+	WriteTimeout time.Duration `yaml:"write_timeout,omitempty"` // Max time a single write/enqueue may block the engine (0 = no limit)
+	AutoReorder  bool          `yaml:"auto_reorder,omitempty"`  // Run cheap predicate filters before expensive ones
+
+	DeliveryConcurrency int `yaml:"delivery_concurrency,omitempty"` // Buffer delivery workers for this output (0 = output_buffer.delivery_concurrency)
+
+	StampPipeline bool `yaml:"stamp_pipeline,omitempty"` // Set metadata.pipeline to this output's name on the logs it delivers
+	Shadow        bool `yaml:"shadow,omitempty"`         // Receive a copy of every log for testing, isolated from the other outputs
+
+	SampleRate float64 `yaml:"sample_rate,omitempty"` // Forward only this fraction of the logs that pass filters and routing (0 = all)
+	SampleKey  string  `yaml:"sample_key,omitempty"`  // Field whose value decides sampling, so related logs are sampled together
+
+	Tags     []string `yaml:"tags,omitempty"`      // Only accept logs carrying these tags (empty = all)
+	TagMatch string   `yaml:"tag_match,omitempty"` // "any" (default) or "all" of the tags must be present
+
+	RequireFields []FieldRequirement `yaml:"require_fields,omitempty"` // Fields a log must have to be written; others go to the DLQ
+
+	AckMode    string        `yaml:"ack_mode,omitempty"`    // "async" (default) or "sync": inputs such as kafka and sqs acknowledge only after this output delivered
+	AckTimeout time.Duration `yaml:"ack_timeout,omitempty"` // Max time an input waits for a sync delivery (default: 30s)
+}
+
+// IsEnabled returns whether the plugin is enabled (defaults to true when unset)
+func (p PluginDefinition) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
 }
 
 // Validate validates the PluginDefinition
 func (p PluginDefinition) Validate() error {
 	return validation.ValidateStruct(&p,
-		validation.Field(&p.Type, validation.Required.Error("cannot be blank"), validation.In("file", "docker", "http", "kafka", "console", "elasticsearch", "file_output", "prometheus", "slack", "level", "json", "regex", "rate_limit").Error("must be a valid value")),
+		validation.Field(&p.Type, validation.Required.Error("cannot be blank"), validation.In("file", "docker", "http", "kafka", "sqs", "redis_stream", "stdin", "wineventlog", "multiplex", "aggregate", "console", "datadog", "elasticsearch", "email", "fallback", "file_output", "grpc", "null", "prometheus", "shard", "slack", "syslog", "level", "json", "regex", "rate_limit", "lookup", "sample", "burst", "sanitize", "accesslog", "trace_context").Error("must be a valid value")),
 		validation.Field(&p.Name, validation.Length(0, 100).Error("the length must be no more than 100")),
 		validation.Field(&p.Config, validation.Required.Error("cannot be blank")),
-		validation.Field(&p.Sources, validation.Each(validation.Required.Error("cannot be blank"))),
+		validation.Field(&p.Sources, validation.Each(validation.Required.Error("cannot be blank")), validation.When(p.Shadow, validation.Empty.Error("must be empty for a shadow output, which receives every log"))),
 		validation.Field(&p.Filters, validation.Each(validation.Required.Error("cannot be blank"))),
+		validation.Field(&p.WriteTimeout, validation.Min(time.Duration(0)).Error("must be no less than 0")),
+		validation.Field(&p.DeliveryConcurrency, validation.Min(0).Error("must be no less than 0"),
+			validation.Max(MaxDeliveryConcurrency).Error(fmt.Sprintf("must be no greater than %d", MaxDeliveryConcurrency))),
+		validation.Field(&p.Tags, validation.Each(validation.Required.Error("cannot be blank")), validation.When(p.Shadow, validation.Empty.Error("must be empty for a shadow output, which receives every log"))),
+		validation.Field(&p.TagMatch, validation.In(TagMatchAny, TagMatchAll).Error("must be 'any' or 'all'")),
+		validation.Field(&p.SampleRate, validation.Min(0.0).Error("must be no less than 0"), validation.Max(1.0).Error("must be no greater than 1"),
+			validation.When(p.Shadow, validation.Empty.Error("must be empty for a shadow output, which receives every log"))),
+		validation.Field(&p.SampleKey, validation.When(p.SampleRate == 0, validation.Empty.Error("requires sample_rate"))),
+		validation.Field(&p.RequireFields, validation.When(p.Shadow, validation.Empty.Error("must be empty for a shadow output"))),
+		validation.Field(&p.AckMode, validation.In(AckModeAsync, AckModeSync).Error("must be 'async' or 'sync'"),
+			validation.When(p.Shadow, validation.Empty.Error("must be empty for a shadow output"))),
+		validation.Field(&p.AckTimeout, validation.Min(time.Duration(0)).Error("must be no less than 0"),
+			validation.When(p.AckMode != AckModeSync, validation.Empty.Error("requires ack_mode: sync"))),
 	)
 }
 
@@ -131,16 +284,29 @@ func LoadConfig(filename string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
+	return prepareConfig(&config)
+}
+
+// prepareConfig applies defaults and environment overrides to a parsed
+// configuration, validates it and logs lint warnings
+func prepareConfig(config *Config) (*Config, error) {
+	// Merge shared plugin settings before validation, so they are checked like any other setting
+	config.Defaults.Apply(config)
 
 	// Load API keys from environment variables if available
-	loadAPIKeysFromEnv(&config)
+	loadAPIKeysFromEnv(config)
 
 	// Validate the configuration
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
 
-	return &config, nil
+	// Likely mistakes are reported but never fail loading
+	for _, warning := range LintConfig(config) {
+		configLog.Printf("Config warning: %s", warning)
+	}
+
+	return config, nil
 }
 
 // loadAPIKeysFromEnv loads API keys from environment variables
@@ -219,54 +385,60 @@ func DefaultConfig() *Config {
 	}
 }
 
-// ConfigWatcher monitors a config file for changes and triggers reloads
+// ConfigWatcher monitors config files for changes and triggers reloads
 type ConfigWatcher struct {
-	filename    string
-	watcher     *fsnotify.Watcher
-	onReload    func(*Config)
-	stopCh      chan struct{}
-	wg          sync.WaitGroup
-	lastModTime time.Time
-	mu          sync.Mutex
+	filenames    []string
+	watcher      *fsnotify.Watcher
+	onReload     func(*Config)
+	onError      func(error) // Called when a changed file fails to load or validate
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+	lastModTimes map[string]time.Time
+	mu           sync.Mutex
 }
 
 // NewConfigWatcher creates a new config file watcher
 func NewConfigWatcher(filename string, onReload func(*Config)) (*ConfigWatcher, error) {
+	return NewConfigFilesWatcher([]string{filename}, onReload)
+}
+
+// NewConfigFilesWatcher watches config files merged with LoadConfigFiles. A change
+// to any of them reloads the merged configuration.
+func NewConfigFilesWatcher(filenames []string, onReload func(*Config)) (*ConfigWatcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
-	// Get initial file modification time
-	info, err := os.Stat(filename)
-	if err != nil {
-		_ = watcher.Close()
-		return nil, fmt.Errorf("failed to stat config file: %w", err)
-	}
-
 	cw := &ConfigWatcher{
-		filename:    filename,
-		watcher:     watcher,
-		onReload:    onReload,
-		stopCh:      make(chan struct{}),
-		lastModTime: info.ModTime(),
+		filenames:    filenames,
+		watcher:      watcher,
+		onReload:     onReload,
+		stopCh:       make(chan struct{}),
+		lastModTimes: make(map[string]time.Time, len(filenames)),
 	}
 
-	// Watch the directory containing the config file
-	// This handles cases where the file is replaced atomically
-	dir := filename
-	if idx := len(filename) - 1; idx >= 0 {
-		for i := idx; i >= 0; i-- {
-			if filename[i] == '/' || filename[i] == '\\' {
-				dir = filename[:i]
-				break
-			}
+	watched := make(map[string]bool)
+	for _, filename := range filenames {
+		// Get initial file modification time
+		info, err := os.Stat(filename)
+		if err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("failed to stat config file: %w", err)
 		}
-	}
+		cw.lastModTimes[filename] = info.ModTime()
 
-	if err := watcher.Add(dir); err != nil {
-		_ = watcher.Close()
-		return nil, fmt.Errorf("failed to watch directory: %w", err)
+		// Watch the directory containing the config file
+		// This handles cases where the file is replaced atomically
+		dir := watchDir(filename)
+		if watched[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("failed to watch directory: %w", err)
+		}
+		watched[dir] = true
 	}
 
 	cw.wg.Add(1)
@@ -275,6 +447,24 @@ func NewConfigWatcher(filename string, onReload func(*Config)) (*ConfigWatcher,
 	return cw, nil
 }
 
+// watchDir returns the directory watched for a config file
+func watchDir(filename string) string {
+	for i := len(filename) - 1; i >= 0; i-- {
+		if filename[i] == '/' || filename[i] == '\\' {
+			return filename[:i]
+		}
+	}
+	return filename
+}
+
+// OnError sets a function called when a changed config file fails to load or
+// validate, so rejected reloads can be recorded
+func (cw *ConfigWatcher) OnError(fn func(error)) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.onError = fn
+}
+
 // Stop stops the config watcher
 func (cw *ConfigWatcher) Stop() {
 	close(cw.stopCh)
@@ -293,14 +483,14 @@ func (cw *ConfigWatcher) watchLoop() {
 				return
 			}
 
-			// Check if the event is for our config file
-			if event.Name != cw.filename {
+			// Check if the event is for one of our config files
+			if _, ok := cw.lastModTimes[event.Name]; !ok {
 				continue
 			}
 
 			// Only react to write events
 			if event.Op&fsnotify.Write == fsnotify.Write {
-				cw.handleFileChange()
+				cw.handleFileChange(event.Name)
 			}
 
 		case err, ok := <-cw.watcher.Errors:
@@ -316,30 +506,33 @@ func (cw *ConfigWatcher) watchLoop() {
 }
 
 // handleFileChange handles a config file change event
-func (cw *ConfigWatcher) handleFileChange() {
+func (cw *ConfigWatcher) handleFileChange(filename string) {
 	cw.mu.Lock()
 	defer cw.mu.Unlock()
 
 	// Check if file was actually modified (avoid duplicate events)
-	info, err := os.Stat(cw.filename)
+	info, err := os.Stat(filename)
 	if err != nil {
 		fmt.Printf("Error checking config file: %v\n", err)
 		return
 	}
 
-	if info.ModTime().Equal(cw.lastModTime) {
+	if info.ModTime().Equal(cw.lastModTimes[filename]) {
 		return // No actual change
 	}
 
-	cw.lastModTime = info.ModTime()
+	cw.lastModTimes[filename] = info.ModTime()
 
 	// Small delay to ensure file write is complete
 	time.Sleep(100 * time.Millisecond)
 
 	// Load new config
-	config, err := LoadConfig(cw.filename)
+	config, err := LoadConfigFiles(cw.filenames...)
 	if err != nil {
-		fmt.Printf("Error reloading config: %v\n", err)
+		configLog.Printf("Error reloading config: %v", err)
+		if cw.onError != nil {
+			cw.onError(err)
+		}
 		return
 	}
 
@@ -403,6 +596,33 @@ func validateFileInDirectory(filePath, baseDir string) error {
 func (a APIConfig) Validate() error {
 	return validation.ValidateStruct(&a,
 		validation.Field(&a.Port, validation.When(a.Enabled, validation.Required.Error("cannot be blank"), validation.Min(1).Error("must be no less than 1"), validation.Max(65535).Error("must be no greater than 65535")).Else(validation.Min(0))),
+		validation.Field(&a.BindAddress, validation.By(func(value interface{}) error {
+			addr := value.(string)
+			if addr == "" {
+				return nil
+			}
+			if strings.ContainsAny(addr, " /") || (strings.Contains(addr, ":") && net.ParseIP(addr) == nil) {
+				return fmt.Errorf("must be an IP address or hostname without a port")
+			}
+			return nil
+		})),
+		validation.Field(&a.WriteBack, validation.By(func(value interface{}) error {
+			if a.WriteBack == "" {
+				return nil
+			}
+			return validateFilePath(a.WriteBack)
+		})),
+		validation.Field(&a.CertFile, validation.When(a.TLS.Enabled, validation.Required.Error("is required when TLS is enabled"))),
+		validation.Field(&a.KeyFile, validation.When(a.TLS.Enabled, validation.Required.Error("is required when TLS is enabled"))),
+		validation.Field(&a.TLS, validation.By(func(value interface{}) error {
+			tlsCfg := value.(tlsconfig.Config)
+			return tlsCfg.Validate()
+		})),
 		validation.Field(&a.Auth),
 	)
 }
+
+// ListenAddress returns the TCP address the API server listens on
+func (a APIConfig) ListenAddress() string {
+	return net.JoinHostPort(a.BindAddress, strconv.Itoa(a.Port))
+}
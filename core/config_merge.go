@@ -0,0 +1,106 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergedPluginLists are the top-level lists merged by plugin name instead of replaced
+var mergedPluginLists = []string{"inputs", "outputs"}
+
+// LoadConfigFiles loads several YAML files and merges them in order into one
+// configuration, so environment overlays can be kept apart from a shared base.
+// Defaults, validation and linting run once, on the merged result, so a fragment
+// does not need to be a complete configuration on its own.
+//
+// A later file overrides earlier ones the way plugin settings override shared
+// defaults: maps are merged key by key, other lists and plain values replace the
+// earlier value, and "key: null" clears it. Entries of inputs and outputs are
+// matched by name: an entry whose name is already defined is merged into that
+// plugin, any other entry is appended.
+func LoadConfigFiles(filenames ...string) (*Config, error) {
+	switch len(filenames) {
+	case 0:
+		return nil, fmt.Errorf("no config file given")
+	case 1:
+		return LoadConfig(filenames[0])
+	}
+
+	var merged map[string]any
+	for _, filename := range filenames {
+		if err := validateFilePath(filename); err != nil {
+			return nil, fmt.Errorf("invalid config file path: %w", err)
+		}
+
+		data, err := os.ReadFile(filename) // #nosec G304 - path validated by validateFilePath above
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+
+		var document map[string]any
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", filename, err)
+		}
+		merged = mergeConfigDocuments(merged, document)
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("error merging config files: %w", err)
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing merged config files: %w", err)
+	}
+	return prepareConfig(&config)
+}
+
+// mergeConfigDocuments merges a decoded config file over the files before it
+func mergeConfigDocuments(base, overlay map[string]any) map[string]any {
+	merged := mergeSettings(base, overlay)
+	for _, key := range mergedPluginLists {
+		baseList, baseOK := base[key].([]any)
+		overlayList, overlayOK := overlay[key].([]any)
+		if baseOK && overlayOK {
+			merged[key] = mergePluginList(baseList, overlayList)
+		}
+	}
+	return merged
+}
+
+// mergePluginList merges overlay plugin definitions into base by name, keeping
+// the order of base and appending new plugins in overlay order
+func mergePluginList(base, overlay []any) []any {
+	merged := copySetting(base).([]any)
+	index := make(map[string]int, len(merged))
+	for i, item := range merged {
+		if name := definitionName(item); name != "" {
+			index[name] = i
+		}
+	}
+
+	for _, item := range overlay {
+		name := definitionName(item)
+		if i, ok := index[name]; ok && name != "" {
+			merged[i] = mergeSettings(merged[i].(map[string]any), item.(map[string]any))
+			continue
+		}
+		if name != "" {
+			index[name] = len(merged)
+		}
+		merged = append(merged, copySetting(item))
+	}
+	return merged
+}
+
+// definitionName returns the name of a decoded plugin definition, or "" when it has none
+func definitionName(item any) string {
+	definition, ok := item.(map[string]any)
+	if !ok {
+		return ""
+	}
+	name, _ := definition["name"].(string)
+	return name
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeConfigFiles writes each content to its own file and returns the paths in order
+func writeConfigFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, "config-"+string(rune('a'+i))+".yaml")
+		if err := os.WriteFile(paths[i], []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+	return paths
+}
+
+const mergeBaseConfig = `
+inputs:
+  - type: file
+    name: "app"
+    config:
+      path: "/var/log/app.log"
+
+outputs:
+  - type: console
+    name: "console"
+    config:
+      target: "stdout"
+  - type: elasticsearch
+    name: "search"
+    config:
+      addresses: ["http://localhost:9200"]
+      index: "logs"
+      tls:
+        enabled: true
+        ca_cert: "/certs/ca.pem"
+
+stats_interval: 1m
+api:
+  enabled: true
+  port: 9090
+`
+
+const mergeOverlayConfig = `
+outputs:
+  - type: elasticsearch
+    name: "search"
+    config:
+      addresses: ["https://es-prod:9200"]
+      tls:
+        insecure_skip_verify: true
+  - type: file
+    name: "archive"
+    config:
+      path: "/var/log/archive.log"
+
+stats_interval: 5m
+api:
+  port: 9191
+`
+
+func TestLoadConfigFilesMerge(t *testing.T) {
+	paths := writeConfigFiles(t, mergeBaseConfig, mergeOverlayConfig)
+
+	config, err := LoadConfigFiles(paths...)
+	if err != nil {
+		t.Fatalf("failed to load config files: %v", err)
+	}
+
+	// Plain values of the later file win, maps are merged key by key
+	if config.StatsInterval != 5*time.Minute {
+		t.Errorf("expected stats_interval from the overlay, got %s", config.StatsInterval)
+	}
+	if !config.API.Enabled || config.API.Port != 9191 {
+		t.Errorf("expected api.enabled from the base and api.port from the overlay, got %+v", config.API)
+	}
+
+	// Outputs with the same name are merged in place, new ones are appended
+	if len(config.Outputs) != 3 {
+		t.Fatalf("expected 3 outputs, got %d", len(config.Outputs))
+	}
+	names := []string{config.Outputs[0].Name, config.Outputs[1].Name, config.Outputs[2].Name}
+	if names[0] != "console" || names[1] != "search" || names[2] != "archive" {
+		t.Errorf("expected base order followed by new outputs, got %v", names)
+	}
+	search := config.Outputs[1].Config
+	addresses, _ := search["addresses"].([]any)
+	if len(addresses) != 1 || addresses[0] != "https://es-prod:9200" {
+		t.Errorf("expected the overlay's addresses to replace the list, got %v", search["addresses"])
+	}
+	if search["index"] != "logs" {
+		t.Errorf("expected index kept from the base, got %v", search["index"])
+	}
+	tls, _ := search["tls"].(map[string]any)
+	if tls["enabled"] != true || tls["ca_cert"] != "/certs/ca.pem" || tls["insecure_skip_verify"] != true {
+		t.Errorf("expected tls merged key by key, got %v", tls)
+	}
+	if len(config.Inputs) != 1 || config.Inputs[0].Name != "app" {
+		t.Errorf("expected the base inputs to be kept, got %+v", config.Inputs)
+	}
+}
+
+func TestLoadConfigFilesOrder(t *testing.T) {
+	first := "stats_interval: 1m\n"
+	second := "stats_interval: 2m\n"
+	full := `
+inputs:
+  - type: file
+    config:
+      path: "/var/log/app.log"
+outputs:
+  - type: console
+    config:
+      target: "stdout"
+`
+	paths := writeConfigFiles(t, full, first, second)
+
+	config, err := LoadConfigFiles(paths...)
+	if err != nil {
+		t.Fatalf("failed to load config files: %v", err)
+	}
+	if config.StatsInterval != 2*time.Minute {
+		t.Errorf("expected the last file to win, got %s", config.StatsInterval)
+	}
+
+	config, err = LoadConfigFiles(paths[0], paths[2], paths[1])
+	if err != nil {
+		t.Fatalf("failed to load config files: %v", err)
+	}
+	if config.StatsInterval != time.Minute {
+		t.Errorf("expected the last file to win, got %s", config.StatsInterval)
+	}
+
+	// Unnamed plugins cannot be matched and are always appended
+	paths = writeConfigFiles(t, full, full)
+	config, err = LoadConfigFiles(paths...)
+	if err != nil {
+		t.Fatalf("failed to load config files: %v", err)
+	}
+	if len(config.Inputs) != 2 || len(config.Outputs) != 2 {
+		t.Errorf("expected unnamed plugins to be appended, got %d inputs and %d outputs", len(config.Inputs), len(config.Outputs))
+	}
+}
+
+func TestLoadConfigFilesValidatesMergedResult(t *testing.T) {
+	inputs := `
+inputs:
+  - type: file
+    config:
+      path: "/var/log/app.log"
+`
+	outputs := `
+outputs:
+  - type: console
+    config:
+      target: "stdout"
+`
+	paths := writeConfigFiles(t, inputs, outputs)
+
+	// Neither fragment is valid alone
+	for _, path := range paths {
+		if _, err := LoadConfigFiles(path); err == nil {
+			t.Errorf("expected %s alone to fail validation", path)
+		}
+	}
+	if _, err := LoadConfigFiles(paths...); err != nil {
+		t.Errorf("expected the merged fragments to be valid, got %v", err)
+	}
+
+	// An overlay can make the merged result invalid
+	paths = writeConfigFiles(t, inputs, outputs, "stats_interval: -1s\n")
+	if _, err := LoadConfigFiles(paths...); err == nil {
+		t.Error("expected the merged config to fail validation")
+	}
+
+	// null clears an earlier value
+	paths = writeConfigFiles(t, inputs, outputs, "outputs: null\n")
+	if _, err := LoadConfigFiles(paths...); err == nil {
+		t.Error("expected outputs: null to clear the outputs")
+	}
+
+	paths = writeConfigFiles(t, inputs, "outputs: [unclosed\n")
+	if _, err := LoadConfigFiles(paths...); err == nil {
+		t.Error("expected a parse error")
+	}
+	if _, err := LoadConfigFiles(paths[0], filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if _, err := LoadConfigFiles(); err == nil {
+		t.Error("expected an error without files")
+	}
+}
@@ -3,11 +3,13 @@ package core
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/pkg/auth"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 )
 
 func TestLoadDynamicConfig(t *testing.T) {
@@ -521,6 +523,36 @@ func TestAPIConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "Port: must be no greater than 65535",
 		},
+		{
+			name: "valid bind address and unix socket",
+			config: APIConfig{
+				Enabled:     true,
+				Port:        9090,
+				BindAddress: "127.0.0.1",
+				UnixSocket:  "/run/loganalyzer/api.sock",
+			},
+			expectError: false,
+		},
+		{
+			name: "bind address with port",
+			config: APIConfig{
+				Enabled:     true,
+				Port:        9090,
+				BindAddress: "127.0.0.1:9090",
+			},
+			expectError: true,
+			errorMsg:    "BindAddress: must be an IP address or hostname without a port",
+		},
+		{
+			name: "TLS without certificate files",
+			config: APIConfig{
+				Enabled: true,
+				Port:    9090,
+				TLS:     tlsconfig.Config{Enabled: true},
+			},
+			expectError: true,
+			errorMsg:    "CertFile: is required when TLS is enabled",
+		},
 		{
 			name: "API key without ID",
 			config: APIConfig{
@@ -697,6 +729,30 @@ func TestPersistenceConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "RetentionHours: must be no greater than 8760",
 		},
+		{
+			name: "high water mark above buffer size",
+			config: PersistenceConfig{
+				BufferSize:     100,
+				FlushInterval:  5,
+				MaxFileSize:    1024,
+				RetentionHours: 24,
+				HighWaterMark:  101,
+			},
+			expectError: true,
+			errorMsg:    "HighWaterMark: must be no greater than buffer_size",
+		},
+		{
+			name: "max flush interval below flush interval",
+			config: PersistenceConfig{
+				BufferSize:       100,
+				FlushInterval:    5,
+				MaxFileSize:      1024,
+				RetentionHours:   24,
+				MaxFlushInterval: 2,
+			},
+			expectError: true,
+			errorMsg:    "MaxFlushInterval: must be no less than flush_interval",
+		},
 		{
 			name: "directory path too long",
 			config: PersistenceConfig{
@@ -1118,3 +1174,264 @@ func generateManyPlugins(count int, pluginType string) []PluginDefinition {
 	}
 	return plugins
 }
+
+func TestPluginDefinitionEnabled(t *testing.T) {
+	configContent := `
+inputs:
+  - type: file
+    config:
+      path: "/var/log/app.log"
+
+outputs:
+  - type: console
+    name: "default"
+    config:
+      target: "stdout"
+  - type: console
+    name: "muted"
+    enabled: false
+    config:
+      target: "stdout"
+`
+
+	tmpFile, err := os.CreateTemp("", "config-*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.Write([]byte(configContent)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if !config.Outputs[0].IsEnabled() {
+		t.Error("expected output without 'enabled' to default to enabled")
+	}
+	if config.Outputs[1].IsEnabled() {
+		t.Error("expected output with 'enabled: false' to be disabled")
+	}
+}
+
+func TestPluginDefinitionWriteTimeout(t *testing.T) {
+	configContent := `
+inputs:
+  - type: file
+    config:
+      path: "/var/log/app.log"
+
+outputs:
+  - type: console
+    name: "bounded"
+    write_timeout: 250ms
+    config:
+      target: "stdout"
+`
+
+	tmpFile, err := os.CreateTemp("", "config-*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.Write([]byte(configContent)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if config.Outputs[0].WriteTimeout != 250*time.Millisecond {
+		t.Errorf("expected write timeout 250ms, got %v", config.Outputs[0].WriteTimeout)
+	}
+
+	invalid := PluginDefinition{Type: "console", Config: map[string]any{"target": "stdout"}, WriteTimeout: -time.Second}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for negative write timeout")
+	}
+}
+
+func TestConfigLevels(t *testing.T) {
+	configContent := `
+inputs:
+  - type: file
+    config:
+      path: "/var/log/app.log"
+
+outputs:
+  - type: console
+    config:
+      target: "stdout"
+
+levels:
+  order: [trace, debug, info, warn, error, fatal]
+  aliases:
+    CRIT: fatal
+    warning: warn
+  default: info
+`
+
+	tmpFile, err := os.CreateTemp("", "config-*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.Write([]byte(configContent)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(config.Levels.Order) != 6 {
+		t.Errorf("expected 6 levels, got %d", len(config.Levels.Order))
+	}
+	if config.Levels.Aliases["CRIT"] != "fatal" {
+		t.Errorf("expected CRIT alias for fatal, got %q", config.Levels.Aliases["CRIT"])
+	}
+
+	config.Levels.Aliases["panic"] = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for alias to unknown level")
+	}
+}
+
+func TestConfigStatsInterval(t *testing.T) {
+	config := Config{
+		Inputs:        []PluginDefinition{{Type: "file", Config: map[string]any{"path": "/var/log/app.log"}}},
+		Outputs:       []PluginDefinition{{Type: "console", Config: map[string]any{"target": "stdout"}}},
+		StatsInterval: time.Minute,
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	config.StatsInterval = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for negative stats interval")
+	}
+}
+
+func TestConfigLoggingFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"", false},
+		{"text", false},
+		{"json", false},
+		{"xml", true},
+	}
+
+	for _, tt := range tests {
+		config := Config{
+			Inputs:  []PluginDefinition{{Type: "file", Config: map[string]any{"path": "/var/log/app.log"}}},
+			Outputs: []PluginDefinition{{Type: "console", Config: map[string]any{"target": "stdout"}}},
+			Logging: LoggingConfig{Format: tt.format},
+		}
+		if err := config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("format %q: expected error=%v, got %v", tt.format, tt.wantErr, err)
+		}
+	}
+}
+
+func TestConfigLoggingRateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		logging LoggingConfig
+		wantErr bool
+	}{
+		{"limits", LoggingConfig{RateLimits: map[string]int{"buffer": 10, "resilience": 1}, ReportInterval: time.Minute}, false},
+		{"zero limit", LoggingConfig{RateLimits: map[string]int{"buffer": 0}}, true},
+		{"negative report interval", LoggingConfig{ReportInterval: -time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.logging.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestResilienceConfigDefaults(t *testing.T) {
+	disabled := false
+	enabled := true
+
+	tests := []struct {
+		name       string
+		resilience ResilienceConfig
+		plugin     map[string]any
+		expected   bool
+	}{
+		{"default is resilient", ResilienceConfig{}, map[string]any{}, true},
+		{"global disable", ResilienceConfig{Enabled: &disabled}, map[string]any{}, false},
+		{"global enable", ResilienceConfig{Enabled: &enabled}, map[string]any{}, true},
+		{"plugin disables", ResilienceConfig{}, map[string]any{"resilient": false}, false},
+		{"plugin enables over global disable", ResilienceConfig{Enabled: &disabled}, map[string]any{"resilient": true}, true},
+		{"non-bool override is ignored", ResilienceConfig{Enabled: &disabled}, map[string]any{"resilient": "yes"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resilience.Resilient(tt.plugin); got != tt.expected {
+				t.Errorf("Expected resilient=%v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if (ResilienceConfig{}).FailFast {
+		t.Error("Expected fail_fast to be off by default")
+	}
+}
+
+func TestConfigResilienceFromYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+resilience:
+  enabled: false
+  fail_fast: true
+inputs:
+  - type: file
+    config:
+      path: /var/log/app.log
+outputs:
+  - type: console
+    config:
+      target: stdout
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Resilience.IsEnabled() || !config.Resilience.FailFast {
+		t.Errorf("Expected resilience disabled with fail_fast, got %+v", config.Resilience)
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DropReasonDeadline counts logs that could not be delivered within the deadline budget
+const DropReasonDeadline = "deadline"
+
+// DeadlineMissedKey is the metadata key naming the output a late log missed
+const DeadlineMissedKey = "deadline_missed"
+
+// DeadlineConfig bounds end-to-end latency: a log must reach each output within
+// Budget of being received by the engine. The deadline covers the whole pipeline
+// (input channel, pause holds, filters and slower outputs before this one), unlike
+// an output's write_timeout, which bounds a single write.
+type DeadlineConfig struct {
+	Budget     time.Duration `yaml:"budget,omitempty"`      // Time from receipt to delivery (0 = no deadline)
+	LateOutput string        `yaml:"late_output,omitempty"` // Output receiving logs that missed the deadline (default: drop them)
+}
+
+// Validate validates the DeadlineConfig
+func (c DeadlineConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Budget, validation.Min(time.Duration(0)).Error("must be no less than 0")),
+		validation.Field(&c.LateOutput, validation.When(c.Budget == 0, validation.Empty.Error("requires budget"))),
+	)
+}
+
+// validateLateOutput checks that late_output names a regular output
+func (c DeadlineConfig) validateLateOutput(outputs []PluginDefinition) error {
+	if c.LateOutput == "" {
+		return nil
+	}
+	for _, output := range outputs {
+		if output.Name != c.LateOutput {
+			continue
+		}
+		if output.Shadow {
+			return fmt.Errorf("late_output '%s' cannot be a shadow output", c.LateOutput)
+		}
+		if len(output.Sources) > 0 || len(output.Tags) > 0 {
+			return fmt.Errorf("late_output '%s' receives only late logs and cannot set sources or tags", c.LateOutput)
+		}
+		return nil
+	}
+	return fmt.Errorf("late_output '%s' is not a defined output", c.LateOutput)
+}
+
+// SetDeadline configures the delivery deadline; call it before output pipelines
+// are added so their buffers apply it to retries
+func (e *Engine) SetDeadline(config DeadlineConfig) {
+	e.deadline = config
+}
+
+// deadlineOf returns when a log must have been delivered, or the zero time when
+// there is no deadline or the log did not come through the input channel
+func deadlineOf(logEntry *Log, budget time.Duration) time.Time {
+	if budget <= 0 || logEntry.receivedAt.IsZero() {
+		return time.Time{}
+	}
+	return logEntry.receivedAt.Add(budget)
+}
+
+// isLateOutput reports whether the pipeline only receives logs that missed the deadline
+func (e *Engine) isLateOutput(pipeline *OutputPipeline) bool {
+	return e.deadline.LateOutput != "" && pipeline.Name == e.deadline.LateOutput
+}
+
+// missedDeadline reports whether a log is past its deadline before being written
+// to pipeline, counting it and handing it to the late output if so
+func (e *Engine) missedDeadline(pipeline *OutputPipeline, logEntry *Log) bool {
+	deadline := deadlineOf(logEntry, e.deadline.Budget)
+	if deadline.IsZero() || time.Now().Before(deadline) {
+		return false
+	}
+	engineLog.Printf("Log missed its deadline for output '%s' by %s", pipeline.Name, time.Since(deadline))
+	e.expireLog(pipeline, logEntry)
+	return true
+}
+
+// expireLog counts a log that missed its deadline for pipeline and, with a late
+// output, delivers a copy marked with the missed output's name
+func (e *Engine) expireLog(pipeline *OutputPipeline, logEntry *Log) {
+	pipeline.deadlineMissed.Add(1)
+	e.drops.Inc(DropReasonDeadline)
+
+	if e.deadline.LateOutput == "" {
+		return
+	}
+	late, err := e.findPipeline(e.deadline.LateOutput)
+	if err != nil || !late.Enabled() {
+		return
+	}
+
+	entry := logEntry.Clone()
+	if entry.Metadata == nil {
+		entry.Metadata = make(map[string]string, 1)
+	}
+	entry.Metadata[DeadlineMissedKey] = pipeline.Name
+	entry, passed, _ := late.applyFilters(entry)
+	if !passed {
+		return
+	}
+
+	// Buffers of several outputs can expire logs at once; the late output sees one write at a time
+	e.lateMu.Lock()
+	defer e.lateMu.Unlock()
+	if err := late.writeWithTimeout(e.ctx, entry); err != nil {
+		engineLog.Printf("Error writing late log to output '%s': %v", late.Name, err)
+	}
+}
+
+// DeadlineMissedCount returns the number of logs that missed their deadline for this pipeline
+func (p *OutputPipeline) DeadlineMissedCount() int64 {
+	return p.deadlineMissed.Load()
+}
+
+// dropExpired hands a buffered log that missed its deadline back to the engine.
+// Only retries are checked: waiting in the queue of a healthy output is expected
+// delivery time, while a failed attempt means the output cannot meet the deadline.
+func (ob *OutputBuffer) dropExpired(bufferedLog *BufferedLog) bool {
+	if ob.onExpired == nil || bufferedLog.Deadline.IsZero() || time.Now().Before(bufferedLog.Deadline) {
+		return false
+	}
+	bufferedLog.Log.settleAck(errors.New("missed its delivery deadline")) // Before the late output gets a copy
+	ob.onExpired(bufferedLog.Log)
+	ob.statsMu.Lock()
+	ob.stats.TotalExpired++
+	ob.statsMu.Unlock()
+	ob.logger().Printf("Dropping log that missed its deadline by %s (%d attempts)",
+		time.Since(bufferedLog.Deadline), bufferedLog.Attempts)
+	return true
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEngineDeadline(t *testing.T) {
+	engine := NewEngine()
+	engine.SetDeadline(DeadlineConfig{Budget: 50 * time.Millisecond, LateOutput: "late"})
+	prod := newMockOutput()
+	late := newMockOutput()
+
+	pipelines := []*OutputPipeline{
+		{Name: "prod", Output: prod},
+		{Name: "late", Output: late},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	// Held past the budget, e.g. behind a slow output or while paused
+	delayed := NewLog("error", "delayed")
+	engine.receiveLog(delayed)
+	time.Sleep(60 * time.Millisecond)
+	engine.dispatchLog(delayed)
+
+	fresh := NewLog("error", "fresh")
+	engine.receiveLog(fresh)
+	engine.dispatchLog(fresh)
+
+	// Logs that did not come through the input channel have no deadline
+	engine.dispatchLog(NewLog("error", "direct"))
+	engine.Stop()
+
+	logs := prod.getLogs()
+	if len(logs) != 2 || logs[0].Message != "fresh" || logs[1].Message != "direct" {
+		t.Errorf("Expected prod to receive only logs within the deadline, got %d logs", len(logs))
+	}
+
+	// The late output receives nothing but the late log, marked with the output it missed
+	lateLogs := late.getLogs()
+	if len(lateLogs) != 1 || lateLogs[0].Message != "delayed" || lateLogs[0].Metadata[DeadlineMissedKey] != "prod" {
+		t.Fatalf("Expected the late output to receive the delayed log, got %d logs", len(lateLogs))
+	}
+	if _, ok := delayed.Metadata[DeadlineMissedKey]; ok {
+		t.Error("Expected the late copy to leave the original log unchanged")
+	}
+
+	if got := pipelines[0].DeadlineMissedCount(); got != 1 {
+		t.Errorf("Expected 1 missed deadline for prod, got %d", got)
+	}
+	if got := engine.Drops().Count(DropReasonDeadline); got != 1 {
+		t.Errorf("Expected 1 deadline drop, got %d", got)
+	}
+}
+
+func TestOutputBufferDeadline(t *testing.T) {
+	tmpDir := t.TempDir()
+	output := &MockOutput{}
+	output.SetShouldFail(true, 1)
+
+	config := retryOverflowConfig(tmpDir, 0, "")
+	config.RetryInterval = 10 * time.Millisecond
+	config.MaxRetryDelay = 10 * time.Millisecond
+	buffer, err := NewOutputBuffer("test", output, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	var expired atomic.Int64
+	buffer.deadline = 200 * time.Millisecond
+	buffer.onExpired = func(*Log) { expired.Add(1) }
+	defer func() { _ = buffer.Close() }()
+
+	// Fails once, then misses its deadline before the retry worker's next pass (every second)
+	failing := NewLog("error", "failing")
+	failing.receivedAt = time.Now()
+	if err := buffer.Enqueue(failing); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for stats := buffer.GetStats(); stats.TotalExpired < 1 && time.Now().Before(deadline); stats = buffer.GetStats() {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if stats := buffer.GetStats(); stats.TotalExpired != 1 || stats.CurrentRetrying != 0 || expired.Load() != 1 {
+		t.Fatalf("Expected the retried log to expire, got %+v", stats)
+	}
+
+	// A log already past its deadline still gets its first attempt from a healthy queue
+	queued := NewLog("error", "queued")
+	queued.receivedAt = time.Now().Add(-time.Hour)
+	if err := buffer.Enqueue(queued); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for buffer.GetStats().TotalDelivered < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := buffer.GetStats(); stats.TotalDelivered != 1 || stats.TotalExpired != 1 {
+		t.Errorf("Expected the queued log to be delivered, got %+v", stats)
+	}
+}
+
+func TestDeadlineConfigValidation(t *testing.T) {
+	outputs := []PluginDefinition{
+		{Type: "console", Name: "prod", Config: map[string]any{"target": "stdout"}},
+		{Type: "file", Name: "late", Config: map[string]any{"path": "late.log"}},
+		{Type: "file", Name: "candidate", Config: map[string]any{"path": "shadow.log"}, Shadow: true},
+		{Type: "file", Name: "routed", Config: map[string]any{"path": "routed.log"}, Tags: []string{"audit"}},
+	}
+
+	tests := []struct {
+		name     string
+		config   DeadlineConfig
+		expected string
+	}{
+		{"disabled", DeadlineConfig{}, ""},
+		{"budget", DeadlineConfig{Budget: time.Second}, ""},
+		{"late output", DeadlineConfig{Budget: time.Second, LateOutput: "late"}, ""},
+		{"negative budget", DeadlineConfig{Budget: -time.Second}, "no less than 0"},
+		{"late output without budget", DeadlineConfig{LateOutput: "late"}, "requires budget"},
+		{"undefined late output", DeadlineConfig{Budget: time.Second, LateOutput: "missing"}, "not a defined output"},
+		{"shadow late output", DeadlineConfig{Budget: time.Second, LateOutput: "candidate"}, "shadow"},
+		{"routed late output", DeadlineConfig{Budget: time.Second, LateOutput: "routed"}, "sources or tags"},
+	}
+
+	for _, tt := range tests {
+		config := Config{
+			Inputs:   []PluginDefinition{{Type: "file", Config: map[string]any{"path": "app.log"}}},
+			Outputs:  outputs,
+			Deadline: tt.config,
+		}
+		err := config.Validate()
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import "sort"
+
+// DefaultsAll is the defaults key whose settings apply to every plugin of a kind
+const DefaultsAll = "all"
+
+// DefaultsConfig holds plugin settings written once and merged into the config of
+// every matching plugin, e.g. the TLS settings shared by all network outputs.
+// For each kind, "all" applies to every plugin and a type key ("elasticsearch")
+// to the plugins of that type, over "all". Filters cover every output filter.
+//
+// Precedence is deterministic: plugin config > type defaults > "all" defaults.
+// Nested maps are merged key by key, lists and scalars are replaced as a whole,
+// and a null value in a plugin's config drops the default for that key.
+type DefaultsConfig struct {
+	Inputs  map[string]map[string]any `yaml:"inputs,omitempty"`
+	Outputs map[string]map[string]any `yaml:"outputs,omitempty"`
+	Filters map[string]map[string]any `yaml:"filters,omitempty"`
+}
+
+// Apply merges the defaults into the plugin definitions of config. It runs on the
+// parsed configuration, before validation, so the merged settings are validated
+// and linted like settings written on each plugin.
+func (d DefaultsConfig) Apply(config *Config) {
+	for i := range config.Inputs {
+		applyDefaults(d.Inputs, &config.Inputs[i])
+	}
+	for i := range config.Outputs {
+		output := &config.Outputs[i]
+		applyDefaults(d.Outputs, output)
+		for j := range output.Filters {
+			applyDefaults(d.Filters, &output.Filters[j])
+		}
+	}
+}
+
+// unusedTypes returns the type keys of defaults that no plugin of the kind uses, sorted
+func unusedTypes(defaults map[string]map[string]any, defs []PluginDefinition) []string {
+	used := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		used[def.Type] = true
+	}
+
+	var unused []string
+	for key := range defaults {
+		if key != DefaultsAll && !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// applyDefaults merges the "all" and type defaults of one kind into a plugin's config
+func applyDefaults(defaults map[string]map[string]any, plugin *PluginDefinition) {
+	base := mergeSettings(defaults[DefaultsAll], defaults[plugin.Type])
+	if len(base) == 0 {
+		return
+	}
+	plugin.Config = mergeSettings(base, plugin.Config)
+}
+
+// mergeSettings returns a copy of base with override merged over it. The result
+// shares no maps or lists with its arguments, so plugins never share settings.
+func mergeSettings(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = copySetting(value)
+	}
+	for key, value := range override {
+		baseMap, baseIsMap := merged[key].(map[string]any)
+		overrideMap, overrideIsMap := value.(map[string]any)
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeSettings(baseMap, overrideMap)
+			continue
+		}
+		merged[key] = copySetting(value)
+	}
+	return merged
+}
+
+// copySetting deep-copies the maps and lists of a decoded YAML value
+func copySetting(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return mergeSettings(nil, v)
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = copySetting(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultsApply(t *testing.T) {
+	defaults := DefaultsConfig{
+		Outputs: map[string]map[string]any{
+			DefaultsAll: {
+				"timeout": 10,
+				"tls":     map[string]any{"enabled": true, "ca_cert": "/certs/ca.pem"},
+				"hosts":   []any{"a", "b"},
+			},
+			"elasticsearch": {
+				"timeout": 30,
+				"index":   "logs",
+			},
+		},
+		Filters: map[string]map[string]any{
+			"rate_limit": {"burst": 100},
+		},
+	}
+
+	config := &Config{
+		Inputs: []PluginDefinition{{Type: "file", Config: map[string]any{"path": "/var/log/app.log"}}},
+		Outputs: []PluginDefinition{
+			{
+				Type: "elasticsearch",
+				Config: map[string]any{
+					"index": "audit",
+					"tls":   map[string]any{"ca_cert": "/certs/other.pem"},
+					"hosts": []any{"c"},
+				},
+				Filters: []PluginDefinition{{Type: "rate_limit", Config: map[string]any{"rate": 5.0}}},
+			},
+			{Type: "slack", Config: map[string]any{"tls": nil}},
+			{Type: "console"},
+		},
+	}
+	defaults.Apply(config)
+
+	expected := []map[string]any{
+		{
+			"timeout": 30,                                                             // Type default over "all"
+			"index":   "audit",                                                        // Plugin over type default
+			"tls":     map[string]any{"enabled": true, "ca_cert": "/certs/other.pem"}, // Nested maps merged
+			"hosts":   []any{"c"},                                                     // Lists replaced
+		},
+		{"timeout": 10, "tls": nil, "hosts": []any{"a", "b"}}, // null drops the default
+		{"timeout": 10, "tls": map[string]any{"enabled": true, "ca_cert": "/certs/ca.pem"}, "hosts": []any{"a", "b"}},
+	}
+	for i, want := range expected {
+		if !reflect.DeepEqual(config.Outputs[i].Config, want) {
+			t.Errorf("Output %d: expected %v, got %v", i, want, config.Outputs[i].Config)
+		}
+	}
+
+	if filter := config.Outputs[0].Filters[0].Config; !reflect.DeepEqual(filter, map[string]any{"rate": 5.0, "burst": 100}) {
+		t.Errorf("Expected filter defaults merged, got %v", filter)
+	}
+	if input := config.Inputs[0].Config; !reflect.DeepEqual(input, map[string]any{"path": "/var/log/app.log"}) {
+		t.Errorf("Expected input without defaults to be unchanged, got %v", input)
+	}
+
+	// Plugins never share the default maps
+	config.Outputs[2].Config["tls"].(map[string]any)["enabled"] = false
+	if defaults.Outputs[DefaultsAll]["tls"].(map[string]any)["enabled"] != true {
+		t.Error("Expected changing a plugin's config to leave the defaults untouched")
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	configContent := `
+defaults:
+  outputs:
+    all:
+      target: "stderr"
+    file:
+      file_path: "/var/log/out.log"
+
+inputs:
+  - type: file
+    config:
+      path: "/var/log/app.log"
+
+outputs:
+  - type: console
+    name: "errors"
+  - type: file
+    name: "archive"
+    config:
+      format: "json"
+`
+
+	tmpFile, err := os.CreateTemp("", "config-*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.Write([]byte(configContent)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		t.Fatalf("failed to close temp file: %v", err)
+	}
+
+	// The console output has no config of its own and passes validation through the defaults
+	config, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if config.Outputs[0].Config["target"] != "stderr" {
+		t.Errorf("expected target from defaults, got %v", config.Outputs[0].Config)
+	}
+	archive := config.Outputs[1].Config
+	if archive["file_path"] != "/var/log/out.log" || archive["format"] != "json" || archive["target"] != "stderr" {
+		t.Errorf("expected all, type and plugin settings merged, got %v", archive)
+	}
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDiskBudgetExceeded is returned when a write would exceed the disk budget
+// and nothing could be pruned to make room
+var ErrDiskBudgetExceeded = errors.New("disk budget exceeded")
+
+// diskBudgetWarnInterval limits how often rejected writes are logged
+const diskBudgetWarnInterval = 10 * time.Second
+
+// DiskBudget caps the bytes the WAL, output buffers and DLQ keep on disk
+// together. Usage is counted once from the files present at startup and then
+// kept up to date by every write and removal, so directories are only listed
+// again when the budget is full. A write that does not fit first deletes the
+// oldest prunable files (rotated WAL files and DLQ segments); when nothing is
+// left to prune the write is rejected and counted.
+type DiskBudget struct {
+	limit int64
+
+	mu          sync.Mutex
+	used        int64
+	prunable    map[string]func() []string // Files that may be deleted under pressure, by owner
+	prunedFiles int64
+	prunedBytes int64
+	rejected    int64     // Writes rejected since startup
+	warned      int64     // Rejected writes already reported in the log
+	lastWarn    time.Time // When rejected writes were last logged
+}
+
+// DiskBudgetStats is a snapshot of a disk budget
+type DiskBudgetStats struct {
+	Limit       int64 `json:"limit"`
+	Used        int64 `json:"used"`
+	PrunedFiles int64 `json:"pruned_files"`
+	PrunedBytes int64 `json:"pruned_bytes"`
+	Rejected    int64 `json:"rejected_writes"`
+}
+
+// NewDiskBudget creates a budget of limit bytes (nil when limit is 0, meaning no budget)
+func NewDiskBudget(limit int64) *DiskBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &DiskBudget{limit: limit}
+}
+
+// track counts files that already exist against the budget
+func (b *DiskBudget) track(files ...string) {
+	if b == nil {
+		return
+	}
+	total := filesSize(files)
+
+	b.mu.Lock()
+	b.used += total
+	b.mu.Unlock()
+}
+
+// untrack stops counting an owner's files, e.g. when an output buffer is closed
+// on reload and its files will be tracked again by its replacement
+func (b *DiskBudget) untrack(owner string, files ...string) {
+	if b == nil {
+		return
+	}
+	total := filesSize(files)
+
+	b.mu.Lock()
+	b.used = max(b.used-total, 0)
+	delete(b.prunable, owner)
+	b.mu.Unlock()
+}
+
+// addPrunable registers a function listing an owner's files that may be deleted
+// to make room. It is called with the budget locked, so it must not wait on the
+// writers' locks.
+func (b *DiskBudget) addPrunable(owner string, list func() []string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if b.prunable == nil {
+		b.prunable = make(map[string]func() []string)
+	}
+	b.prunable[owner] = list
+	b.mu.Unlock()
+}
+
+// Reserve accounts n bytes about to be written by what (e.g. "wal"). It prunes
+// the oldest prunable files when needed and returns false if the write still
+// does not fit.
+func (b *DiskBudget) Reserve(n int64, what string) bool {
+	if b == nil || n <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.used+n > b.limit {
+		b.pruneLocked(b.used + n - b.limit)
+	}
+	if b.used+n > b.limit {
+		b.rejected++
+		if time.Since(b.lastWarn) >= diskBudgetWarnInterval {
+			engineLog.Printf("Disk budget full (%d/%d bytes used, nothing left to prune): rejected %d writes, latest from %s",
+				b.used, b.limit, b.rejected-b.warned, what)
+			b.warned = b.rejected
+			b.lastWarn = time.Now()
+		}
+		return false
+	}
+
+	b.used += n
+	return true
+}
+
+// Release returns n bytes of deleted or truncated files to the budget
+func (b *DiskBudget) Release(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used = max(b.used-n, 0)
+	b.mu.Unlock()
+}
+
+// pruneLocked deletes prunable files, oldest first, until need bytes are freed
+func (b *DiskBudget) pruneLocked(need int64) {
+	type candidate struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var candidates []candidate
+	for _, list := range b.prunable {
+		for _, path := range list() {
+			if info, err := os.Stat(path); err == nil {
+				candidates = append(candidates, candidate{path: path, size: info.Size(), modTime: info.ModTime()})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].modTime.Equal(candidates[j].modTime) {
+			return candidates[i].modTime.Before(candidates[j].modTime)
+		}
+		return candidates[i].path < candidates[j].path // Segment names embed their rotation time
+	})
+
+	var freed int64
+	for _, c := range candidates {
+		if freed >= need {
+			break
+		}
+		if err := os.Remove(c.path); err != nil {
+			if !os.IsNotExist(err) {
+				engineLog.Printf("Disk budget: error pruning %s: %v", c.path, err)
+			}
+			continue
+		}
+		freed += c.size
+		b.used = max(b.used-c.size, 0)
+		b.prunedFiles++
+		b.prunedBytes += c.size
+		engineLog.Printf("Disk budget exceeded: pruned %s (%d bytes, modified %s)", c.path, c.size, c.modTime.Format(time.RFC3339))
+	}
+}
+
+// Stats returns a snapshot of the budget
+func (b *DiskBudget) Stats() DiskBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return DiskBudgetStats{
+		Limit:       b.limit,
+		Used:        b.used,
+		PrunedFiles: b.prunedFiles,
+		PrunedBytes: b.prunedBytes,
+		Rejected:    b.rejected,
+	}
+}
+
+// filesSize returns the total size of the regular files among paths
+func filesSize(paths []string) int64 {
+	var total int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// fileSize returns the size of a file, or 0 when it cannot be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskBudgetReserve(t *testing.T) {
+	var unlimited *DiskBudget
+	if !unlimited.Reserve(1<<40, "wal") {
+		t.Error("Expected a nil budget to accept every write")
+	}
+	if NewDiskBudget(0) != nil {
+		t.Error("Expected no budget for a limit of 0")
+	}
+
+	budget := NewDiskBudget(100)
+	if !budget.Reserve(60, "wal") {
+		t.Error("Expected 60 bytes to fit in 100")
+	}
+	if budget.Reserve(50, "wal") {
+		t.Error("Expected 50 more bytes to be rejected")
+	}
+	budget.Release(30)
+	if !budget.Reserve(50, "wal") {
+		t.Error("Expected 50 bytes to fit after releasing 30")
+	}
+
+	stats := budget.Stats()
+	if stats.Used != 80 || stats.Rejected != 1 {
+		t.Errorf("Expected 80 bytes used and 1 rejected write, got %+v", stats)
+	}
+}
+
+func TestDiskBudgetPrunesOldestFirst(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	var files []string
+	for i, age := range []time.Duration{time.Minute, 3 * time.Minute, 2 * time.Minute} {
+		path := filepath.Join(dir, string(rune('a'+i))+".log")
+		if err := os.WriteFile(path, make([]byte, 40), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	budget := NewDiskBudget(130)
+	budget.track(files...)
+	budget.addPrunable("test", func() []string { return files })
+
+	// 120 used: 50 more needs 40 freed, which the oldest file (b.log) covers
+	if !budget.Reserve(50, "dlq") {
+		t.Fatal("Expected the write to fit after pruning")
+	}
+	for i, path := range files {
+		_, err := os.Stat(path)
+		if pruned := os.IsNotExist(err); pruned != (i == 1) {
+			t.Errorf("Expected only the oldest file to be pruned, %s pruned=%v", filepath.Base(path), pruned)
+		}
+	}
+
+	stats := budget.Stats()
+	if stats.Used != 130 || stats.PrunedFiles != 1 || stats.PrunedBytes != 40 {
+		t.Errorf("Expected 130 used after pruning 1 file of 40 bytes, got %+v", stats)
+	}
+
+	// Nothing prunable is left that could make room for a write larger than the budget
+	if budget.Reserve(200, "dlq") {
+		t.Error("Expected a write larger than the budget to be rejected")
+	}
+}
+
+func TestOutputBuffer_DiskBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := OutputBufferConfig{
+		Enabled:       true,
+		Dir:           tmpDir,
+		MaxQueueSize:  10,
+		MaxRetries:    1,
+		RetryInterval: time.Second,
+		MaxRetryDelay: time.Second,
+		FlushInterval: time.Hour,
+		DLQEnabled:    true,
+		DLQPath:       tmpDir,
+		DLQMaxSize:    200,
+	}
+
+	budget := NewDiskBudget(1000)
+	buffer, err := newOutputBuffer("test", &MockOutput{}, config, budget)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+
+	for i := 0; i < 30; i++ {
+		buffer.sendToDLQ(&BufferedLog{Log: NewLog("error", "failed"), Attempts: 1, OutputName: "test"})
+	}
+
+	// Old segments were pruned to keep writing, and the accounting matches the files
+	stats := budget.Stats()
+	if stats.PrunedFiles == 0 {
+		t.Error("Expected rotated DLQ segments to be pruned")
+	}
+	if stats.Used > 1000 {
+		t.Errorf("Expected usage within the budget, got %d", stats.Used)
+	}
+	files, _ := DLQFiles(tmpDir, "test")
+	if onDisk := filesSize(files); onDisk != stats.Used {
+		t.Errorf("Expected %d bytes accounted, got %d on disk", stats.Used, onDisk)
+	}
+	if buffer.GetStats().TotalDLQ != 30 {
+		t.Errorf("Expected 30 logs in DLQ, got %d", buffer.GetStats().TotalDLQ)
+	}
+
+}
+
+func TestOutputBuffer_DiskBudgetRejectsSpill(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := OutputBufferConfig{
+		Enabled:       true,
+		Dir:           tmpDir,
+		MaxQueueSize:  10,
+		MaxRetries:    1,
+		RetryInterval: time.Second,
+		MaxRetryDelay: time.Second,
+		FlushInterval: time.Hour,
+	}
+
+	budget := NewDiskBudget(10)
+	buffer, err := newOutputBuffer("test", &MockOutput{}, config, budget)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+
+	// Buffer files are never pruned, so a spill that does not fit is rejected
+	err = buffer.persistLog(&BufferedLog{Log: NewLog("info", "spill"), OutputName: "test"})
+	if !errors.Is(err, ErrDiskBudgetExceeded) {
+		t.Errorf("Expected ErrDiskBudgetExceeded, got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(tmpDir, "test", "*.jsonl")); len(files) != 0 {
+		t.Errorf("Expected no buffer file to be written, got %v", files)
+	}
+	if stats := budget.Stats(); stats.Rejected != 1 {
+		t.Errorf("Expected 1 rejected write, got %d", stats.Rejected)
+	}
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dlqSegment is a rotated DLQ file ({output}-dlq-{unix nanos}.jsonl)
+type dlqSegment struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// dlqActivePath returns the path of the DLQ file currently written by an output
+func dlqActivePath(dir, outputName string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-dlq.jsonl", outputName))
+}
+
+// listDLQSegments returns the rotated DLQ segments of an output, oldest first
+func listDLQSegments(dir, outputName string) ([]dlqSegment, error) {
+	prefix := outputName + "-dlq-"
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]dlqSegment, 0, len(matches))
+	for _, match := range matches {
+		// Skip files of other outputs whose name starts with this one (e.g. "app" and "app-dlq")
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), prefix), ".jsonl")
+		nanos, err := strconv.ParseInt(stamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, dlqSegment{path: match, rotatedAt: time.Unix(0, nanos)})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].rotatedAt.Before(segments[j].rotatedAt)
+	})
+	return segments, nil
+}
+
+// DLQFiles returns the DLQ files of an output in write order: rotated segments
+// from oldest to newest, followed by the active file when it exists
+func DLQFiles(dir, outputName string) ([]string, error) {
+	segments, err := listDLQSegments(dir, outputName)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(segments)+1)
+	for _, segment := range segments {
+		files = append(files, segment.path)
+	}
+	if _, err := os.Stat(dlqActivePath(dir, outputName)); err == nil {
+		files = append(files, dlqActivePath(dir, outputName))
+	}
+	return files, nil
+}
+
+// ReadDLQFiles reads every DLQ entry of an output across all segments, oldest first
+func ReadDLQFiles(dir, outputName string) ([]*BufferedLog, error) {
+	files, err := DLQFiles(dir, outputName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*BufferedLog
+	for _, path := range files {
+		fileEntries, err := readDLQFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// readDLQFile reads the entries of a single DLQ file, skipping malformed lines
+func readDLQFile(path string) ([]*BufferedLog, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Pruned or rotated between listing and reading
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open DLQ file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []*BufferedLog
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var entry BufferedLog
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			bufferLog.Printf("Skipping malformed entry in %s: %v", path, err)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read DLQ file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ReadDLQ returns every DLQ entry of the buffer across all segments, oldest first.
+// It holds the DLQ lock so no segment is rotated or pruned while reading.
+func (ob *OutputBuffer) ReadDLQ() ([]*BufferedLog, error) {
+	ob.dlqMu.Lock()
+	defer ob.dlqMu.Unlock()
+
+	return ReadDLQFiles(ob.config.DLQPath, ob.outputName)
+}
+
+// openDLQ opens the active DLQ file and records its size and age for rotation
+func (ob *OutputBuffer) openDLQ() error {
+	path := dlqActivePath(ob.config.DLQPath, ob.outputName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) // #nosec G304 - path constructed from controlled inputs
+	if err != nil {
+		return fmt.Errorf("failed to open DLQ file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat DLQ file: %w", err)
+	}
+
+	ob.dlqFile = file
+	ob.dlqSize = info.Size()
+	ob.dlqStarted = time.Now()
+	if info.Size() > 0 {
+		// Best estimate for a file left by a previous run
+		ob.dlqStarted = info.ModTime()
+	}
+	return nil
+}
+
+// shouldRotateDLQ reports whether writing n more bytes requires a new segment.
+// An empty segment is never rotated, so oversized entries are still written.
+func (ob *OutputBuffer) shouldRotateDLQ(n int, now time.Time) bool {
+	if ob.dlqSize == 0 {
+		return false
+	}
+	if ob.config.DLQMaxSize > 0 && ob.dlqSize+int64(n) > ob.config.DLQMaxSize {
+		return true
+	}
+	return ob.config.DLQMaxAge > 0 && now.Sub(ob.dlqStarted) >= ob.config.DLQMaxAge
+}
+
+// rotateDLQ renames the active DLQ file to a timestamped segment, opens a new
+// active file and prunes old segments. Callers must hold dlqMu.
+func (ob *OutputBuffer) rotateDLQ(now time.Time) error {
+	if err := ob.dlqFile.Close(); err != nil {
+		ob.logger().Printf("Error closing DLQ file: %v", err)
+	}
+
+	active := dlqActivePath(ob.config.DLQPath, ob.outputName)
+	segment := filepath.Join(ob.config.DLQPath, fmt.Sprintf("%s-dlq-%d.jsonl", ob.outputName, now.UnixNano()))
+	renameErr := os.Rename(active, segment)
+
+	// Reopen the active file even if the rename failed, so DLQ writes keep working
+	if err := ob.openDLQ(); err != nil {
+		ob.dlqFile = nil
+		return err
+	}
+	if renameErr != nil {
+		return fmt.Errorf("failed to rotate DLQ file: %w", renameErr)
+	}
+
+	ob.logger().Printf("DLQ rotated to %s", filepath.Base(segment))
+	ob.pruneDLQ(now)
+	return nil
+}
+
+// pruneDLQ deletes rotated segments older than dlq_max_age and the oldest segments
+// beyond dlq_max_segments. Segments younger than dlq_min_retention are always kept.
+// Callers must hold dlqMu.
+func (ob *OutputBuffer) pruneDLQ(now time.Time) {
+	if ob.config.DLQMaxAge <= 0 && ob.config.DLQMaxSegments <= 0 {
+		return
+	}
+
+	segments, err := listDLQSegments(ob.config.DLQPath, ob.outputName)
+	if err != nil {
+		ob.logger().Printf("Error listing DLQ segments: %v", err)
+		return
+	}
+
+	remaining := len(segments)
+	for _, segment := range segments {
+		age := now.Sub(segment.rotatedAt)
+		if ob.config.DLQMinRetention > 0 && age < ob.config.DLQMinRetention {
+			// Segments are sorted oldest first, so every later segment is retained too
+			break
+		}
+
+		expired := ob.config.DLQMaxAge > 0 && age > ob.config.DLQMaxAge
+		excess := ob.config.DLQMaxSegments > 0 && remaining > ob.config.DLQMaxSegments
+		if !expired && !excess {
+			continue
+		}
+
+		size := fileSize(segment.path)
+		if err := os.Remove(segment.path); err != nil {
+			if !os.IsNotExist(err) {
+				ob.logger().Printf("Error pruning DLQ segment %s: %v", filepath.Base(segment.path), err)
+				continue
+			}
+			size = 0 // Already pruned by the disk budget
+		}
+		ob.budget.Release(size)
+		remaining--
+		ob.logger().Printf("Pruned DLQ segment %s", filepath.Base(segment.path))
+	}
+}
+
+// maintainDLQ rotates an aged active segment and prunes old segments, so limits
+// are enforced even while no new logs reach the DLQ
+func (ob *OutputBuffer) maintainDLQ() {
+	ob.dlqMu.Lock()
+	defer ob.dlqMu.Unlock()
+
+	if ob.dlqFile == nil {
+		return
+	}
+
+	now := time.Now()
+	if ob.shouldRotateDLQ(0, now) {
+		if err := ob.rotateDLQ(now); err != nil {
+			ob.logger().Printf("%v", err)
+		}
+		return
+	}
+	ob.pruneDLQ(now)
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Drop reasons recorded by DropCounter. Pipeline-level reasons are counted once
+// per pipeline that discards the log, so a log skipped by one output but
+// delivered by another still shows up under the first output's reason.
+const (
+	DropReasonGlobalFilter     = "global_filter"     // Blocked by a global filter
+	DropReasonPipelineDisabled = "pipeline_disabled" // Output pipeline disabled at runtime
+	DropReasonSourceMismatch   = "source_mismatch"   // Input source not accepted by the pipeline
+	DropReasonFilter           = "filter"            // Blocked by a pipeline filter
+	DropReasonTagMismatch      = "tag_mismatch"      // Tags not accepted by the pipeline
+	DropReasonSampled          = "sampled"           // Left out by the pipeline's sample_rate or a sample filter
+	DropReasonWriteTimeout     = "write_timeout"     // Write exceeded the pipeline write timeout
+	DropReasonWriteError       = "write_error"       // Output (or buffer enqueue) returned an error
+	DropReasonDeliveryFailed   = "delivery_failed"   // Buffered log exhausted its retries and could not be dead-lettered
+	DropReasonDiskBudget       = "disk_budget"       // Buffer or DLQ write rejected because the disk budget is full
+)
+
+// DropReasoner is an optional interface for filters that report a more specific
+// drop reason than DropReasonFilter (e.g. "rate_limit")
+type DropReasoner interface {
+	DropReason() string
+}
+
+// DropCounter counts discarded logs by reason
+type DropCounter struct {
+	mu     sync.RWMutex
+	counts map[string]*atomic.Int64
+}
+
+// NewDropCounter creates an empty drop counter
+func NewDropCounter() *DropCounter {
+	return &DropCounter{
+		counts: make(map[string]*atomic.Int64),
+	}
+}
+
+// Inc records one dropped log for the given reason
+func (d *DropCounter) Inc(reason string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.RLock()
+	counter, ok := d.counts[reason]
+	d.mu.RUnlock()
+
+	if !ok {
+		d.mu.Lock()
+		if counter, ok = d.counts[reason]; !ok {
+			counter = &atomic.Int64{}
+			d.counts[reason] = counter
+		}
+		d.mu.Unlock()
+	}
+
+	counter.Add(1)
+}
+
+// Count returns the number of logs dropped for a reason
+func (d *DropCounter) Count(reason string) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if counter, ok := d.counts[reason]; ok {
+		return counter.Load()
+	}
+	return 0
+}
+
+// Snapshot returns the current counts keyed by reason
+func (d *DropCounter) Snapshot() map[string]int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	snapshot := make(map[string]int64, len(d.counts))
+	for reason, counter := range d.counts {
+		snapshot[reason] = counter.Load()
+	}
+	return snapshot
+}
+
+// Reset zeroes all counts
+func (d *DropCounter) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.counts = make(map[string]*atomic.Int64)
+}
+
+// filterDropReason returns the drop reason reported by a filter, or DropReasonFilter
+func filterDropReason(filter FilterPlugin) string {
+	if reasoner, ok := filter.(DropReasoner); ok {
+		if reason := reasoner.DropReason(); reason != "" {
+			return reason
+		}
+	}
+	return DropReasonFilter
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDropCounter(t *testing.T) {
+	drops := NewDropCounter()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			drops.Inc(DropReasonFilter)
+		}()
+	}
+	wg.Wait()
+	drops.Inc(DropReasonSourceMismatch)
+
+	if drops.Count(DropReasonFilter) != 10 {
+		t.Errorf("Expected 10 filter drops, got %d", drops.Count(DropReasonFilter))
+	}
+
+	snapshot := drops.Snapshot()
+	if len(snapshot) != 2 || snapshot[DropReasonSourceMismatch] != 1 {
+		t.Errorf("Unexpected snapshot: %v", snapshot)
+	}
+
+	drops.Reset()
+	if drops.Count(DropReasonFilter) != 0 {
+		t.Errorf("Expected counts to be reset, got %d", drops.Count(DropReasonFilter))
+	}
+
+	// A nil counter (e.g. standalone output buffer) ignores increments
+	var nilCounter *DropCounter
+	nilCounter.Inc(DropReasonDeliveryFailed)
+}
+
+// rateLimitedFilter drops every log with a specific reason
+type rateLimitedFilter struct{}
+
+func (f *rateLimitedFilter) Process(log *Log) bool { return false }
+func (f *rateLimitedFilter) DropReason() string    { return "rate_limit" }
+
+func TestEngineDropReasons(t *testing.T) {
+	engine := NewEngine()
+
+	logs := []*Log{NewLog("info", "one"), NewLog("error", "two")}
+	for _, logEntry := range logs {
+		logEntry.Source = "app"
+	}
+	engine.AddInput("app", newMockInput(logs))
+
+	pipelines := []*OutputPipeline{
+		{Name: "other-source", Output: newMockOutput(), Sources: []string{"db"}},
+		{Name: "filtered", Output: newMockOutput(), Filters: []FilterPlugin{newMockFilter(false)}},
+		{Name: "rate-limited", Output: newMockOutput(), Filters: []FilterPlugin{&rateLimitedFilter{}}},
+		{Name: "tagged", Output: newMockOutput(), Tags: []string{"audit"}},
+		{Name: "disabled", Output: newMockOutput()},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+	pipelines[4].SetEnabled(false)
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	expected := map[string]int64{
+		DropReasonSourceMismatch:   2,
+		DropReasonFilter:           2,
+		"rate_limit":               2,
+		DropReasonTagMismatch:      2,
+		DropReasonPipelineDisabled: 2,
+	}
+	for reason, count := range expected {
+		if got := engine.Drops().Count(reason); got != count {
+			t.Errorf("Expected %d drops for %s, got %d", count, reason, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	engine.handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	var metrics map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&metrics); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	dropped, ok := metrics["logs_dropped_total"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected logs_dropped_total in metrics, got %v", metrics["logs_dropped_total"])
+	}
+	if dropped[DropReasonSourceMismatch] != float64(2) {
+		t.Errorf("Expected 2 source mismatches in metrics, got %v", dropped[DropReasonSourceMismatch])
+	}
+}
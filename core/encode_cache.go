@@ -0,0 +1,73 @@
+package core
+
+import "sync"
+
+// maxEncodeCacheEntries bounds the encodings kept per log; fan-out rarely uses
+// more than a couple of distinct formats
+const maxEncodeCacheEntries = 4
+
+// encodeFormat identifies the output of a LogEncoder
+type encodeFormat struct {
+	style        FieldStyle
+	timestampKey string
+}
+
+// encodeCache holds the JSON encodings of one log while the engine dispatches it,
+// so JSON outputs sharing a format encode a fanned-out log once.
+//
+// An encoding is only reused for the exact log the cache was attached to: Clone
+// drops the cache, and value copies (e.g. an output batching core.Log values)
+// are a different log. Pipelines never mutate the shared log in place, they
+// clone it first (see applyFilters and stamp), so a cached encoding stays valid
+// for as long as the cache is attached. The cache is released once the log has
+// left every pipeline, so logs held by buffers or batches keep no encodings.
+type encodeCache struct {
+	mu       sync.Mutex
+	owner    *Log
+	entries  map[encodeFormat][]byte
+	released bool
+}
+
+// attachEncodeCache gives a log an encoding cache for the duration of its dispatch
+func attachEncodeCache(log *Log) *encodeCache {
+	cache := &encodeCache{owner: log}
+	log.encoded = cache
+	return cache
+}
+
+// get returns the cached encoding of log in format, if any
+func (c *encodeCache) get(log *Log, format encodeFormat) ([]byte, bool) {
+	if c == nil || c.owner != log {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.entries[format]
+	return data, ok
+}
+
+// put caches the encoding of log in format. The slice is stored with its capacity
+// clipped, so a caller appending to it (e.g. a newline) never writes into the
+// bytes other outputs receive.
+func (c *encodeCache) put(log *Log, format encodeFormat, data []byte) {
+	if c == nil || c.owner != log {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.released || len(c.entries) >= maxEncodeCacheEntries {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[encodeFormat][]byte, 1)
+	}
+	c.entries[format] = data[:len(data):len(data)]
+}
+
+// release drops the cached encodings and stops caching new ones
+func (c *encodeCache) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.released = true
+	c.entries = nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// sameBytes reports whether two slices share their backing array
+func sameBytes(a, b []byte) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
+
+func TestLogEncoderCache(t *testing.T) {
+	nested, _ := NewLogEncoder(FieldStyle{}, "timestamp")
+	nestedToo, _ := NewLogEncoder(FieldStyle{}, "timestamp")
+	flat, _ := NewLogEncoder(FieldStyle{Style: FieldStyleFlat}, "timestamp")
+
+	logEntry := NewLogWithMetadata("info", "cached", map[string]string{"service": "api"})
+	cache := attachEncodeCache(logEntry)
+
+	first, _ := nested.Marshal(logEntry)
+	second, _ := nestedToo.Marshal(logEntry)
+	if !sameBytes(first, second) {
+		t.Error("Expected encoders with the same settings to share the encoding")
+	}
+	if other, _ := flat.Marshal(logEntry); sameBytes(first, other) || !strings.Contains(string(other), `"metadata_service"`) {
+		t.Errorf("Expected a separate encoding per format, got %s", other)
+	}
+
+	// Appending to a shared encoding never writes into the cached bytes
+	_ = append(first, '\n')
+	_ = append(second, 'x')
+	if again, _ := nested.Marshal(logEntry); string(again) != string(first) || len(again) != len(first) {
+		t.Errorf("Expected the cached encoding to be unchanged, got %s", again)
+	}
+
+	// A per-pipeline copy is encoded from its own content
+	clone := logEntry.Clone()
+	clone.Metadata["pipeline"] = "alerts"
+	if data, _ := nested.Marshal(clone); !strings.Contains(string(data), `"pipeline":"alerts"`) {
+		t.Errorf("Expected the clone's change in its encoding, got %s", data)
+	}
+	copied := *logEntry
+	copied.Level = "error"
+	if data, _ := nested.Marshal(&copied); !strings.Contains(string(data), `"level":"error"`) {
+		t.Errorf("Expected a value copy to be encoded from its own content, got %s", data)
+	}
+
+	cache.release()
+	if data, _ := nested.Marshal(logEntry); sameBytes(data, first) {
+		t.Error("Expected no cached encoding after release")
+	}
+	if cache.entries != nil {
+		t.Errorf("Expected a released cache to keep nothing, got %d entries", len(cache.entries))
+	}
+}
+
+func TestLogEncoderCacheBounded(t *testing.T) {
+	logEntry := NewLog("info", "many formats")
+	cache := attachEncodeCache(logEntry)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		encoder, _ := NewLogEncoder(FieldStyle{}, key)
+		_, _ = encoder.Marshal(logEntry)
+	}
+	if len(cache.entries) != maxEncodeCacheEntries {
+		t.Errorf("Expected at most %d cached encodings, got %d", maxEncodeCacheEntries, len(cache.entries))
+	}
+}
+
+// encodingOutput records what a JSON output would write
+type encodingOutput struct {
+	encoder *LogEncoder
+	written [][]byte
+}
+
+func (o *encodingOutput) Write(log *Log) error {
+	data, err := o.encoder.Marshal(log)
+	o.written = append(o.written, data)
+	return err
+}
+
+func (o *encodingOutput) Close() error { return nil }
+
+func TestEngineSerializationCache(t *testing.T) {
+	engine := NewEngine()
+	engine.SetSerializationCache(true)
+
+	newOutput := func() *encodingOutput {
+		encoder, _ := NewLogEncoder(FieldStyle{}, "timestamp")
+		return &encodingOutput{encoder: encoder}
+	}
+	plain, plainToo, mutated, stamped := newOutput(), newOutput(), newOutput(), newOutput()
+	pipelines := []*OutputPipeline{
+		{Name: "plain", Output: plain},
+		{Name: "plain-too", Output: plainToo},
+		{Name: "mutated", Output: mutated, Filters: []FilterPlugin{&metadataFilter{key: "enriched", value: "true"}}},
+		{Name: "stamped", Output: stamped, StampPipeline: true},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	logEntry := NewLogWithMetadata("info", "fan-out", map[string]string{"service": "api"})
+	engine.dispatchLog(logEntry)
+
+	if !sameBytes(plain.written[0], plainToo.written[0]) {
+		t.Error("Expected unchanged pipelines to share one encoding")
+	}
+	if data := string(mutated.written[0]); !strings.Contains(data, `"enriched":"true"`) || sameBytes(mutated.written[0], plain.written[0]) {
+		t.Errorf("Expected the mutated pipeline to encode its own copy, got %s", data)
+	}
+	if data := string(stamped.written[0]); !strings.Contains(data, `"pipeline":"stamped"`) {
+		t.Errorf("Expected the stamped pipeline to encode its own copy, got %s", data)
+	}
+	if strings.Contains(string(plain.written[0]), "enriched") {
+		t.Errorf("Expected the shared encoding to be unaffected by other pipelines, got %s", plain.written[0])
+	}
+
+	// Nothing is kept once the log has left the pipelines
+	if logEntry.encoded == nil || !logEntry.encoded.released || logEntry.encoded.entries != nil {
+		t.Error("Expected the cache to be released after dispatch")
+	}
+}
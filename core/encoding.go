@@ -0,0 +1,181 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Metadata field styles for JSON-emitting outputs
+const (
+	FieldStyleNested = "nested" // Metadata as an object: {"metadata":{"service":"api"}}
+	FieldStyleFlat   = "flat"   // Metadata as top-level keys: {"metadata_service":"api"}
+)
+
+// FieldStyle controls how Log.Metadata is rendered by JSON-emitting outputs.
+// Outputs embed it inline, so the options sit next to the output's own settings.
+type FieldStyle struct {
+	Style     string `yaml:"field_style,omitempty"`     // "nested" (default) or "flat"
+	Prefix    string `yaml:"field_prefix,omitempty"`    // Nested object key, or flat key prefix (default: "metadata")
+	Separator string `yaml:"field_separator,omitempty"` // Flat: between prefix and metadata key (default: "_")
+}
+
+// Validate validates the field style and applies defaults
+func (f *FieldStyle) Validate() error {
+	switch f.Style {
+	case "":
+		f.Style = FieldStyleNested
+	case FieldStyleNested, FieldStyleFlat:
+	default:
+		return fmt.Errorf("invalid field_style '%s', must be 'nested' or 'flat'", f.Style)
+	}
+	if f.Prefix == "" {
+		f.Prefix = "metadata"
+	}
+	if f.Separator == "" {
+		f.Separator = "_"
+	}
+	return nil
+}
+
+// LogEncoder serializes logs as JSON objects with a stable key order: timestamp,
+// level, message, source, source_type, tags, the trace context, then metadata in
+// the configured style
+type LogEncoder struct {
+	style        FieldStyle
+	timestampKey string
+}
+
+// NewLogEncoder creates a log encoder. timestampKey names the timestamp field
+// (e.g. "timestamp", or "@timestamp" for Elasticsearch).
+func NewLogEncoder(style FieldStyle, timestampKey string) (*LogEncoder, error) {
+	if err := style.Validate(); err != nil {
+		return nil, err
+	}
+	return &LogEncoder{style: style, timestampKey: timestampKey}, nil
+}
+
+// jsonField is a key and value written in order
+type jsonField struct {
+	key   string
+	value any
+}
+
+// Marshal encodes a log as a single-line JSON object. While the engine
+// dispatches a log with the serialization cache enabled, encoders with the same
+// settings share one encoding, so callers must not modify the returned bytes.
+func (e *LogEncoder) Marshal(log *Log) ([]byte, error) {
+	format := encodeFormat{style: e.style, timestampKey: e.timestampKey}
+	if data, ok := log.encoded.get(log, format); ok {
+		return data, nil
+	}
+	data, err := e.marshal(log)
+	if err != nil {
+		return nil, err
+	}
+	log.encoded.put(log, format, data)
+	return data, nil
+}
+
+// marshal encodes a log without the serialization cache
+func (e *LogEncoder) marshal(log *Log) ([]byte, error) {
+	fields := []jsonField{
+		{e.timestampKey, log.Timestamp.Format(time.RFC3339)},
+		{"level", log.Level},
+		{"message", log.Message},
+	}
+	if log.Source != "" {
+		fields = append(fields, jsonField{"source", log.Source})
+	}
+	if log.SourceType != "" {
+		fields = append(fields, jsonField{"source_type", log.SourceType})
+	}
+	if len(log.Tags) > 0 {
+		fields = append(fields, jsonField{"tags", log.Tags})
+	}
+	if log.TraceID != "" {
+		fields = append(fields, jsonField{"trace_id", log.TraceID})
+		if log.SpanID != "" {
+			fields = append(fields, jsonField{"span_id", log.SpanID})
+		}
+		fields = append(fields, jsonField{"trace_flags", log.TraceFlags})
+	}
+
+	if len(log.Metadata) > 0 {
+		if e.style.Style == FieldStyleFlat {
+			fields = append(fields, e.flatten(log.Metadata, fields)...)
+		} else {
+			fields = append(fields, jsonField{e.style.Prefix, log.Metadata})
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", field.key, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// flatten returns metadata as prefixed top-level fields sorted by key. Log fields
+// always keep their names: a metadata key whose flat name is already taken gets
+// the lowest free numeric suffix (e.g. "source_type_2"), assigned in key order
+// after every non-colliding key, so the output is the same on every run.
+func (e *LogEncoder) flatten(metadata map[string]string, reserved []jsonField) []jsonField {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	used := make(map[string]bool, len(reserved)+len(keys))
+	for _, field := range reserved {
+		used[field.key] = true
+	}
+
+	names := make(map[string]string, len(keys))
+	var colliding []string
+	for _, key := range keys {
+		name := e.style.Prefix + e.style.Separator + key
+		if used[name] {
+			colliding = append(colliding, key)
+			continue
+		}
+		used[name] = true
+		names[key] = name
+	}
+	for _, key := range colliding {
+		base := e.style.Prefix + e.style.Separator + key
+		for n := 2; ; n++ {
+			name := base + e.style.Separator + strconv.Itoa(n)
+			if !used[name] {
+				used[name] = true
+				names[key] = name
+				break
+			}
+		}
+	}
+
+	fields := make([]jsonField, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, jsonField{names[key], metadata[key]})
+	}
+	return fields
+}
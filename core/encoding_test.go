@@ -0,0 +1,101 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogEncoderFieldStyles(t *testing.T) {
+	logEntry := &Log{
+		Timestamp:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:      "error",
+		Message:    `disk "data" full`,
+		Source:     "app",
+		SourceType: "file",
+		Tags:       []string{"alert"},
+		Metadata:   map[string]string{"service": "api", "host": "web-1"},
+	}
+
+	tests := []struct {
+		name     string
+		style    FieldStyle
+		key      string
+		expected string
+	}{
+		{
+			name:     "nested default",
+			key:      "timestamp",
+			expected: `{"timestamp":"2025-01-02T03:04:05Z","level":"error","message":"disk \"data\" full","source":"app","source_type":"file","tags":["alert"],"metadata":{"host":"web-1","service":"api"}}`,
+		},
+		{
+			name:     "nested with prefix",
+			style:    FieldStyle{Style: "nested", Prefix: "meta"},
+			key:      "@timestamp",
+			expected: `{"@timestamp":"2025-01-02T03:04:05Z","level":"error","message":"disk \"data\" full","source":"app","source_type":"file","tags":["alert"],"meta":{"host":"web-1","service":"api"}}`,
+		},
+		{
+			name:     "flat with prefix and separator",
+			style:    FieldStyle{Style: "flat", Prefix: "meta", Separator: "."},
+			key:      "timestamp",
+			expected: `{"timestamp":"2025-01-02T03:04:05Z","level":"error","message":"disk \"data\" full","source":"app","source_type":"file","tags":["alert"],"meta.host":"web-1","meta.service":"api"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := NewLogEncoder(tt.style, tt.key)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			data, err := encoder.Marshal(logEntry)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestLogEncoderFlatCollisions(t *testing.T) {
+	encoder, err := NewLogEncoder(FieldStyle{Style: "flat", Prefix: "source"}, "timestamp")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	logEntry := &Log{
+		Timestamp:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:      "info",
+		Message:    "ok",
+		SourceType: "docker",
+		// "type" collides with source_type; "type_2" takes its natural name first
+		Metadata: map[string]string{"type": "web", "type_2": "other", "zone": "a"},
+	}
+
+	expected := `{"timestamp":"2025-01-02T03:04:05Z","level":"info","message":"ok","source_type":"docker","source_type_3":"web","source_type_2":"other","source_zone":"a"}`
+	for i := 0; i < 5; i++ {
+		data, err := encoder.Marshal(logEntry)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(data) != expected {
+			t.Fatalf("Expected %s, got %s", expected, data)
+		}
+	}
+}
+
+func TestFieldStyleValidate(t *testing.T) {
+	style := FieldStyle{}
+	if err := style.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if style.Style != FieldStyleNested || style.Prefix != "metadata" || style.Separator != "_" {
+		t.Errorf("Expected defaults nested/metadata/_, got %+v", style)
+	}
+
+	invalid := FieldStyle{Style: "dotted"}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected error for invalid field_style")
+	}
+}
@@ -2,48 +2,317 @@ package core
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/pkg/auth"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/version"
+)
+
+// SourceTypePrefix marks pipeline source entries that match the input plugin type
+// instead of the input name (e.g. "type:docker")
+const SourceTypePrefix = "type:"
+
+// Internal loggers, one per engine component
+var (
+	engineLog      = logging.New("engine")
+	apiLog         = logging.New("api")
+	reloadLog      = logging.New("reload")
+	persistenceLog = logging.New("persistence")
+	bufferLog      = logging.New("buffer")
+	resilienceLog  = logging.New("resilience")
+	statsLog       = logging.New("stats")
+	traceLog       = logging.New("trace")
+	configLog      = logging.New("config")
+)
+
+// Tag matching modes for output pipelines
+const (
+	TagMatchAny = "any" // Accept logs carrying at least one of the pipeline tags
+	TagMatchAll = "all" // Accept logs carrying every pipeline tag
 )
 
 // OutputPipeline represents an output with its own filters and source restrictions
 type OutputPipeline struct {
 	Name    string         // Optional name for this output
+	Type    string         // Output plugin type, reported in lifecycle events
 	Output  OutputPlugin   // The output plugin
 	Buffer  *OutputBuffer  // Optional output buffer with retry logic
 	Filters []FilterPlugin // Filters specific to this output
 	Sources []string       // Input sources to accept (empty = all)
+
+	Tags     []string // Tags to accept (empty = all)
+	TagMatch string   // TagMatchAny (default) or TagMatchAll
+
+	// AutoReorder sorts reorderable filters by cost (cheap predicates first) when the pipeline is added
+	AutoReorder bool
+
+	// FilterEnabled is the initial state of each filter, aligned with Filters as
+	// given (nil = all enabled). Disabled filters pass every log through; see SetFilterEnabled.
+	FilterEnabled []bool
+
+	// DeliveryConcurrency overrides output_buffer.delivery_concurrency for this
+	// pipeline's buffer (0 = use the buffer config)
+	DeliveryConcurrency int
+
+	// WriteTimeout bounds how long a single Write (or buffer Enqueue) may block the
+	// engine loop. It is independent of the output buffer's retry timing. Zero disables it.
+	WriteTimeout time.Duration
+
+	// StampPipeline sets metadata.pipeline to Name on the logs this pipeline
+	// delivers. The stamp goes on a copy so other pipelines never see it.
+	StampPipeline bool
+
+	// Shadow pipelines receive a copy of every log regardless of Sources and Tags,
+	// for trying filters and outputs against live traffic. They run on their own
+	// goroutine and queue, are never buffered and keep their own counters, so they
+	// cannot slow down or skew the real pipelines.
+	Shadow bool
+
+	// SampleRate forwards only this fraction of the logs that pass the filters
+	// and routing (0 = all). With SampleKey, logs sharing the key's value are
+	// kept or left out together; see SampleKeyValue for the keys.
+	SampleRate float64
+	SampleKey  string
+
+	// RequireFields are checked right before a log is written; logs missing one
+	// go to the buffer's DLQ, or are dropped when there is none
+	RequireFields []FieldRequirement
+
+	// AckMode AckModeSync makes inputs that support it (see AckingInput) wait
+	// until this pipeline delivered a log before acknowledging their source;
+	// with a buffer, until a delivery attempt succeeded. AckTimeout bounds the
+	// wait (0 = DefaultAckTimeout). Empty or AckModeAsync never holds inputs up.
+	AckMode    string
+	AckTimeout time.Duration
+
+	disabled atomic.Bool  // Runtime toggle; pipelines are enabled by default
+	skipped  atomic.Int64 // Logs skipped while the pipeline was disabled
+	writing  atomic.Bool  // A timed write is still in flight
+	timeouts atomic.Int64 // Writes that timed out or were rejected while a previous write hung
+
+	sampleSeq  atomic.Uint64 // Position of the next sampled log without a sample key
+	sampledOut atomic.Int64  // Logs left out by SampleRate
+
+	deadlineMissed atomic.Int64 // Logs past their delivery deadline before reaching this pipeline
+	fieldsRejected atomic.Int64 // Logs missing a field in RequireFields
+
+	filterStats []*filterStats     // Per-filter statistics, aligned with Filters
+	preserve    *preservePredicate // The engine's preserve_if, set when the pipeline is added
+	shadow      *shadowRunner      // Queue and counters of a shadow pipeline
+
+	overrideMu    sync.Mutex  // Guards overrideTimer and level override changes
+	overrideTimer *time.Timer // Clears the level override when it expires
+}
+
+// errWriteInFlight is returned when a previous timed-out write has not finished yet
+var errWriteInFlight = fmt.Errorf("previous write still in progress")
+
+// errEngineUnavailable is returned when logs are injected into a stopped or stopping engine
+var errEngineUnavailable = fmt.Errorf("engine is not running")
+
+// Enabled reports whether the pipeline currently accepts logs
+func (p *OutputPipeline) Enabled() bool {
+	return !p.disabled.Load()
+}
+
+// SetEnabled enables or disables the pipeline at runtime
+func (p *OutputPipeline) SetEnabled(enabled bool) {
+	p.disabled.Store(!enabled)
+}
+
+// AcceptsSource reports whether the pipeline accepts a log based on its Sources.
+// Entries match the input name, or the input plugin type when prefixed with "type:".
+func (p *OutputPipeline) AcceptsSource(logEntry *Log) bool {
+	if len(p.Sources) == 0 {
+		return true
+	}
+
+	for _, source := range p.Sources {
+		if sourceType, ok := strings.CutPrefix(source, SourceTypePrefix); ok {
+			if logEntry.SourceType != "" && sourceType == logEntry.SourceType {
+				return true
+			}
+			continue
+		}
+		if source == logEntry.Source {
+			return true
+		}
+	}
+	return false
+}
+
+// stamp returns the log to deliver, carrying metadata.pipeline when StampPipeline
+// is set. owned reports whether logEntry is already this pipeline's private copy;
+// the shared log is copied before it is stamped.
+func (p *OutputPipeline) stamp(logEntry *Log, owned bool) *Log {
+	if !p.StampPipeline {
+		return logEntry
+	}
+	stamped := logEntry
+	if !owned {
+		stamped = logEntry.Clone()
+	}
+	if stamped.Metadata == nil {
+		stamped.Metadata = make(map[string]string, 1)
+	}
+	stamped.Metadata[PipelineMetadataKey] = p.Name
+	return stamped
+}
+
+// AcceptsTags reports whether the pipeline accepts a log based on its Tags
+func (p *OutputPipeline) AcceptsTags(logEntry *Log) bool {
+	if len(p.Tags) == 0 {
+		return true
+	}
+
+	if p.TagMatch == TagMatchAll {
+		for _, tag := range p.Tags {
+			if !logEntry.HasTag(tag) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, tag := range p.Tags {
+		if logEntry.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTimeoutCount returns the number of writes that exceeded the write timeout
+func (p *OutputPipeline) WriteTimeoutCount() int64 {
+	return p.timeouts.Load()
+}
+
+// write sends a log to the pipeline's buffer or output
+func (p *OutputPipeline) write(logEntry *Log) error {
+	if p.Buffer != nil {
+		return p.Buffer.Enqueue(logEntry)
+	}
+	return p.Output.Write(logEntry)
+}
+
+// buffered reports whether writes go through a buffer that delivers them later
+func (p *OutputPipeline) buffered() bool {
+	return p.Buffer != nil && p.Buffer.config.Enabled
+}
+
+// writeWithTimeout writes a log, giving up after WriteTimeout so a hanging output
+// cannot stall the other pipelines. At most one write per pipeline runs in the
+// background: while a timed-out write is still hanging, new writes fail fast
+// instead of piling up goroutines.
+func (p *OutputPipeline) writeWithTimeout(ctx context.Context, logEntry *Log) error {
+	if p.WriteTimeout <= 0 {
+		return p.write(logEntry)
+	}
+
+	if !p.writing.CompareAndSwap(false, true) {
+		p.timeouts.Add(1)
+		return errWriteInFlight
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.WriteTimeout)
+	defer cancel()
+
+	// The write may outlive this call, so it gets its own copy of the log that
+	// later pipelines' filters cannot mutate concurrently
+	entry := logEntry.Clone()
+	done := make(chan error, 1) // Buffered so a late write never blocks its goroutine
+	go func() {
+		defer p.writing.Store(false)
+		done <- p.write(entry)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		p.timeouts.Add(1)
+		return fmt.Errorf("write timed out after %s: %w", p.WriteTimeout, ctx.Err())
+	}
+}
+
+// SkippedCount returns the number of logs skipped while the pipeline was disabled
+func (p *OutputPipeline) SkippedCount() int64 {
+	return p.skipped.Load()
 }
 
 // Engine represents the core log processing engine
 type Engine struct {
 	inputCh      chan *Log
-	inputs       map[string]InputPlugin // Map of input name -> plugin
-	filters      []FilterPlugin         // Global filters (deprecated, but kept for backward compatibility)
-	pipelines    []*OutputPipeline      // Output pipelines with their own filters
-	persistence  *Persistence           // Persistence layer for WAL
-	bufferConfig OutputBufferConfig     // Output buffer configuration
+	plugins      atomic.Pointer[pluginSet] // Inputs, global filters and output pipelines; replaced, never changed in place
+	pluginsMu    sync.Mutex                // Serializes changes to plugins
+	dispatchMu   sync.RWMutex              // Held by dispatchLog, so a removed pipeline is only closed once no log is dispatched to it
+	persistence  *Persistence              // Persistence layer for WAL
+	bufferConfig OutputBufferConfig        // Output buffer configuration
+	diskBudget   *DiskBudget               // Shared limit for WAL, buffer and DLQ files (nil = unlimited)
 	wg           sync.WaitGroup
 	ctx          context.Context
 	cancel       context.CancelFunc
-	stopped      bool       // Flag to prevent multiple stops
-	mu           sync.Mutex // Protects stopped flag
-	nextInputID  int        // Monotonic counter for generating unique input names
+	stopped      bool         // Flag to prevent multiple stops
+	mu           sync.Mutex   // Protects stopped flag
+	injectMu     sync.RWMutex // Held for reading while InjectLogs sends, for writing while the input channel is closed
+	nextInputID  int          // Monotonic counter for generating unique input names
+
+	// Runtime plugin changes through the API
+	pluginBuilder *PluginBuilder // nil = POST /pipelines, DELETE /pipelines/<name> and PUT /inputs/<name> are disabled
+	started       atomic.Bool    // Set by Start; inputs added through the API afterwards are started when added
+
+	// Shutdown requests from inputs
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+
+	// Pause/resume
+	pauseConfig  PauseConfig
+	pauseMu      sync.Mutex
+	paused       bool
+	pausedAt     time.Time
+	pauseChanged chan struct{} // Closed and replaced whenever paused changes
+	heldLogs     atomic.Int64  // Logs held while paused (persist mode)
+	held         []*Log        // The held logs; owned by processLogs and kept across reloads
+
+	// Reload audit trail
+	reloadAudit   *ReloadAudit
+	appliedConfig *Config       // Configuration last applied, for reload change summaries
+	reloadMetrics ReloadMetrics // Reload attempts by result, for /metrics
 
 	// API server
 	apiServer      *http.Server
 	apiConfig      APIConfig
+	apiTLSConfig   *tls.Config
 	apiKeyManager  *auth.APIKeyManager
 	authMiddleware *auth.Middleware
 
 	// Metrics
 	totalLogsProcessed int64
+	totalLogsInjected  int64 // Synthetic logs from POST /inject, kept out of totalLogsProcessed
+	drops              *DropCounter
+	statsInterval      time.Duration // Periodic stats logging interval (0 = disabled)
+	trace              TraceConfig   // Per-log stage timing
+	serializationCache bool          // Share JSON encodings between outputs while a log is dispatched
+	maxLogAge          time.Duration // Drop logs whose timestamp is older than this (0 = disabled)
+	internPool         *internPool   // Shares repeated metadata strings in compact mode (nil = map mode)
+	deadline           DeadlineConfig
+	lateMu             sync.Mutex         // Serializes writes to the late output
+	errorContext       *errorContext      // Recent logs per source, flushed to a target on errors (nil = disabled)
+	preserve           *preservePredicate // Logs volume-reducing filters must always pass (nil = none)
+	sourceQuota        *sourceQuota       // Per-source log rate limits (nil = unlimited)
+	startupGrace       time.Duration      // Max wait in Start for outputs to become healthy (0 = disabled)
+	traceSeq           atomic.Uint64      // Logs considered for tracing
 	metricsMu          sync.RWMutex
 	startTime          time.Time
 }
@@ -55,12 +324,23 @@ type InputPlugin interface {
 	SetLogChannel(ch chan<- *Log)
 }
 
+// ShutdownRequester is an optional interface for inputs that can ask the engine
+// to shut down, e.g. a stdin input that reached EOF in batch mode
+type ShutdownRequester interface {
+	SetShutdownFunc(fn func())
+}
+
 // FilterPlugin interface for log filtering/processing
 type FilterPlugin interface {
 	Process(log *Log) bool // Returns true if log should be kept
 }
 
-// OutputPlugin interface for log output destinations
+// OutputPlugin interface for log output destinations.
+//
+// Write must be safe for concurrent use. With output buffering, the retry worker
+// writes alongside the delivery workers, and with delivery_concurrency above 1
+// several delivery workers call Write at once. Ordering across concurrent calls
+// is best-effort; sinks that need strict order should keep delivery_concurrency at 1.
 type OutputPlugin interface {
 	Write(log *Log) error
 	Close() error
@@ -69,20 +349,29 @@ type OutputPlugin interface {
 // NewEngine creates a new log processing engine
 func NewEngine() *Engine {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Engine{
-		inputCh:   make(chan *Log, 100), // Buffered channel for inputs
-		inputs:    make(map[string]InputPlugin),
-		filters:   []FilterPlugin{},
-		pipelines: []*OutputPipeline{},
-		ctx:       ctx,
-		cancel:    cancel,
-		startTime: time.Now(),
+	e := &Engine{
+		inputCh:    make(chan *Log, 100), // Buffered channel for inputs
+		ctx:        ctx,
+		cancel:     cancel,
+		startTime:  time.Now(),
+		shutdownCh: make(chan struct{}),
+		drops:      NewDropCounter(),
+		reloadAudit: &ReloadAudit{
+			maxEvents: DefaultReloadAuditMaxEvents,
+		},
 	}
+	e.plugins.Store(newPluginSet())
+	return e
+}
+
+// Drops returns the counter of discarded logs by reason
+func (e *Engine) Drops() *DropCounter {
+	return e.drops
 }
 
 // SetPersistence configures the persistence layer for the engine
 func (e *Engine) SetPersistence(config PersistenceConfig) error {
-	p, err := NewPersistence(config)
+	p, err := newPersistence(config, e.diskBudget)
 	if err != nil {
 		return fmt.Errorf("failed to initialize persistence: %w", err)
 	}
@@ -90,6 +379,13 @@ func (e *Engine) SetPersistence(config PersistenceConfig) error {
 	return nil
 }
 
+// SetDiskBudget limits the bytes the WAL, output buffers and DLQ keep on disk
+// together (0 = unlimited). Call it before SetPersistence and adding pipelines
+// so their existing files are counted.
+func (e *Engine) SetDiskBudget(limit int64) {
+	e.diskBudget = NewDiskBudget(limit)
+}
+
 // SetOutputBufferConfig configures output buffering for all outputs
 func (e *Engine) SetOutputBufferConfig(config OutputBufferConfig) {
 	e.bufferConfig = config
@@ -100,6 +396,18 @@ func (e *Engine) EnableAPI(config APIConfig) error {
 	if config.Port == 0 {
 		return fmt.Errorf("API port cannot be 0")
 	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid API config: %w", err)
+	}
+
+	// Build the TLS config up front so certificate errors surface at startup
+	if config.TLS.Enabled {
+		tlsCfg, err := config.TLS.NewTLSConfig()
+		if err != nil {
+			return fmt.Errorf("failed to create API TLS config: %w", err)
+		}
+		e.apiTLSConfig = tlsCfg
+	}
 	e.apiConfig = config
 
 	// Initialize API key manager if authentication is enabled
@@ -118,7 +426,7 @@ func (e *Engine) EnableAPI(config APIConfig) error {
 			config.Auth.HealthBypass,
 		)
 
-		log.Printf("API authentication enabled with %d API keys", len(config.Auth.APIKeys))
+		apiLog.Printf("API authentication enabled with %d API keys", len(config.Auth.APIKeys))
 	}
 
 	return nil
@@ -129,10 +437,45 @@ func (e *Engine) EnableAPIDefault() error {
 	return e.EnableAPI(DefaultAPIConfig())
 }
 
+// AddInputWithType adds a named input plugin and records its plugin type, which is
+// stamped on every log from that input as SourceType
+func (e *Engine) AddInputWithType(name, pluginType string, input InputPlugin) {
+	e.AddInput(name, input)
+	e.updatePlugins(func(plugins *pluginSet) {
+		plugins.inputTypes[name] = pluginType
+	})
+}
+
 // AddInput adds an input plugin to the engine with a name
 func (e *Engine) AddInput(name string, input InputPlugin) {
 	input.SetLogChannel(e.inputCh)
-	e.inputs[name] = input
+	if requester, ok := input.(ShutdownRequester); ok {
+		requester.SetShutdownFunc(e.RequestShutdown)
+	}
+	if acking, ok := input.(AckingInput); ok {
+		acking.SetAckRequired(e.ackRequired)
+	}
+	e.updatePlugins(func(plugins *pluginSet) {
+		plugins.inputs[name] = input
+	})
+}
+
+// RequestShutdown asks the owner of the engine to shut it down. Logs already queued
+// on the input channel are given time to be processed before the request is signaled.
+func (e *Engine) RequestShutdown() {
+	go e.shutdownOnce.Do(func() {
+		deadline := time.Now().Add(30 * time.Second)
+		for len(e.inputCh) > 0 && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		engineLog.Println("Shutdown requested by input")
+		close(e.shutdownCh)
+	})
+}
+
+// ShutdownRequested returns a channel that is closed when an input requests shutdown
+func (e *Engine) ShutdownRequested() <-chan struct{} {
+	return e.shutdownCh
 }
 
 // AddInputAnonymous adds an input plugin without a specific name (for backward compatibility)
@@ -146,32 +489,67 @@ func (e *Engine) AddInputAnonymous(input InputPlugin) {
 
 // AddFilter adds a global filter plugin to the engine (deprecated)
 func (e *Engine) AddFilter(filter FilterPlugin) {
-	e.filters = append(e.filters, filter)
+	e.updatePlugins(func(plugins *pluginSet) {
+		plugins.filters = append(plugins.filters, filter)
+	})
 }
 
 // AddOutput adds an output plugin to the engine (deprecated - use AddOutputPipeline)
 func (e *Engine) AddOutput(output OutputPlugin) {
-	pipeline := &OutputPipeline{
-		Name:    fmt.Sprintf("output-%d", len(e.pipelines)),
-		Output:  output,
-		Filters: []FilterPlugin{},
-		Sources: []string{}, // Accept from all sources
-	}
-	e.pipelines = append(e.pipelines, pipeline)
+	e.updatePlugins(func(plugins *pluginSet) {
+		plugins.pipelines = append(plugins.pipelines, &OutputPipeline{
+			Name:    fmt.Sprintf("output-%d", len(plugins.pipelines)),
+			Output:  output,
+			Filters: []FilterPlugin{},
+			Sources: []string{}, // Accept from all sources
+		})
+	})
 }
 
 // AddOutputPipeline adds an output pipeline with filters and source restrictions
 func (e *Engine) AddOutputPipeline(pipeline *OutputPipeline) error {
+	pipeline.prepareFilters()
+	pipeline.preserve = e.preserve
+
+	if pipeline.Shadow {
+		pipeline.startShadow()
+		e.updatePlugins(func(plugins *pluginSet) {
+			plugins.pipelines = append(plugins.pipelines, pipeline)
+		})
+		return nil
+	}
+
 	// Wrap output with buffer if configured
 	if e.bufferConfig.Enabled {
-		buffer, err := NewOutputBuffer(pipeline.Name, pipeline.Output, e.bufferConfig)
+		bufferConfig := e.bufferConfig
+		if pipeline.DeliveryConcurrency > 0 {
+			bufferConfig.DeliveryConcurrency = pipeline.DeliveryConcurrency
+		}
+		buffer, err := newOutputBuffer(pipeline.Name, pipeline.Output, bufferConfig, e.diskBudget)
 		if err != nil {
 			return fmt.Errorf("failed to create output buffer for %s: %w", pipeline.Name, err)
 		}
+		buffer.drops = e.drops
+		buffer.maxLogAge = e.maxLogAge
+		buffer.deadline = e.deadline.Budget
+		buffer.onExpired = func(logEntry *Log) { e.expireLog(pipeline, logEntry) }
 		pipeline.Buffer = buffer
 	}
 
-	e.pipelines = append(e.pipelines, pipeline)
+	e.updatePlugins(func(plugins *pluginSet) {
+		plugins.pipelines = append(plugins.pipelines, pipeline)
+	})
+	return nil
+}
+
+// SetPipelineEnabled enables or disables the named output pipeline at runtime
+func (e *Engine) SetPipelineEnabled(name string, enabled bool) error {
+	pipeline, err := e.findPipeline(name)
+	if err != nil {
+		return err
+	}
+	pipeline.SetEnabled(enabled)
+	engineLog.Printf("Output pipeline '%s' enabled=%t", name, enabled)
 	return nil
 }
 
@@ -182,65 +560,170 @@ func (e *Engine) InputChannel() chan<- *Log {
 
 // Start begins the log processing
 func (e *Engine) Start() {
-	// Recover persisted logs if persistence is enabled
+	// Start API server if enabled. It keeps serving across reloads, which only
+	// restart processing and inputs.
+	if e.apiConfig.Enabled {
+		e.startAPIServer()
+	}
+
+	e.startProcessing()
+
+	// Give outputs that connect in the background a chance to be ready for the first logs
+	e.waitForOutputs(e.ctx, e.startupGrace)
+
+	// Recover persisted logs if persistence is enabled. The WAL outlives reloads,
+	// so this happens once per run rather than in startInputs.
 	if e.persistence != nil {
 		recoveryCh, err := e.persistence.Recover()
 		if err != nil {
-			log.Printf("Error starting recovery: %v", err)
+			engineLog.Printf("Error starting recovery: %v", err)
 		} else {
 			e.wg.Add(1)
 			go e.processRecoveredLogs(recoveryCh)
 		}
 	}
 
-	// Start all input plugins
-	for name, input := range e.inputs {
-		if err := input.Start(); err != nil {
-			log.Printf("Error starting input plugin %s: %v", name, err)
-		}
-	}
+	e.startInputs()
+}
 
-	// Start API server if enabled
-	if e.apiConfig.Enabled {
-		e.startAPIServer()
+// startProcessing starts the goroutines that process logs, ahead of the inputs that feed them
+func (e *Engine) startProcessing() {
+	// Start periodic stats logging if configured
+	if e.statsInterval > 0 {
+		e.wg.Add(1)
+		go e.emitStats(e.ctx, e.statsInterval)
 	}
 
 	e.wg.Add(1)
 	go e.processLogs()
-	log.Println("LogAnalyzer engine started")
+}
+
+// startInputs starts the input plugins
+func (e *Engine) startInputs() {
+	// Start all input plugins
+	for name, input := range e.plugins.Load().inputs {
+		if err := input.Start(); err != nil {
+			engineLog.Printf("Error starting input plugin %s: %v", name, err)
+			e.publishInputEvent(name, input, EventPluginUnhealthy, err)
+			continue
+		}
+		e.publishInputEvent(name, input, EventPluginStarted, nil)
+	}
+
+	e.started.Store(true)
+	engineLog.Println("LogAnalyzer engine started")
+	LifecycleEvents().Publish(LifecycleEvent{Type: EventEngineStarted})
 }
 
 // startAPIServer starts the metrics API server
 func (e *Engine) startAPIServer() {
 	mux := http.NewServeMux()
 
+	// Closed on shutdown so event streams end instead of holding the server open
+	eventsDone := make(chan struct{})
+
 	// Apply authentication middleware if enabled
 	if e.authMiddleware != nil {
 		mux.HandleFunc("/health", e.authMiddleware.WrapHandlerFunc(e.handleHealth))
 		mux.HandleFunc("/metrics", e.authMiddleware.WrapHandlerFunc(e.handleMetrics))
+		mux.HandleFunc("/metrics/reset", e.authMiddleware.WrapHandlerFunc(e.handleMetricsReset))
 		mux.HandleFunc("/status", e.authMiddleware.WrapHandlerFunc(e.handleStatus))
+		mux.HandleFunc("/pipelines", e.authMiddleware.WrapHandlerFunc(e.handlePipelineCreate))
+		mux.HandleFunc("/pipelines/", e.authMiddleware.WrapHandlerFunc(e.handlePipelineToggle))
+		mux.HandleFunc("/inputs/", e.authMiddleware.WrapHandlerFunc(e.handleInputReplace))
+		mux.HandleFunc("/inject", e.authMiddleware.WrapHandlerFunc(e.handleInject))
+		mux.HandleFunc("/ready", e.authMiddleware.WrapHandlerFunc(e.handleReady))
+		mux.HandleFunc("/pause", e.authMiddleware.WrapHandlerFunc(e.handlePause))
+		mux.HandleFunc("/resume", e.authMiddleware.WrapHandlerFunc(e.handlePause))
+		mux.HandleFunc("/reloads", e.authMiddleware.WrapHandlerFunc(e.handleReloads))
+		mux.HandleFunc("/events", e.authMiddleware.WrapHandlerFunc(e.handleEvents(eventsDone)))
 	} else {
 		mux.HandleFunc("/health", e.handleHealth)
 		mux.HandleFunc("/metrics", e.handleMetrics)
+		mux.HandleFunc("/metrics/reset", e.handleMetricsReset)
 		mux.HandleFunc("/status", e.handleStatus)
+		mux.HandleFunc("/pipelines", e.handlePipelineCreate)
+		mux.HandleFunc("/pipelines/", e.handlePipelineToggle)
+		mux.HandleFunc("/inputs/", e.handleInputReplace)
+		mux.HandleFunc("/inject", e.handleInject)
+		mux.HandleFunc("/ready", e.handleReady)
+		mux.HandleFunc("/pause", e.handlePause)
+		mux.HandleFunc("/resume", e.handlePause)
+		mux.HandleFunc("/reloads", e.handleReloads)
+		mux.HandleFunc("/events", e.handleEvents(eventsDone))
 	}
 
-	e.apiServer = &http.Server{
-		Addr:              fmt.Sprintf(":%d", e.apiConfig.Port),
+	server := &http.Server{
+		Addr:              e.apiConfig.ListenAddress(),
 		Handler:           mux,
 		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         e.apiTLSConfig,
 	}
+	server.RegisterOnShutdown(func() { close(eventsDone) })
+	e.apiServer = server
 
-	log.Printf("Starting API server on port %d", e.apiConfig.Port)
 	if e.authMiddleware != nil {
-		log.Printf("API authentication is enabled")
+		apiLog.Printf("API authentication is enabled")
 	}
 
-	go func() {
-		if err := e.apiServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("API server error: %v", err)
+	// TCP listener (HTTPS when TLS is configured)
+	listener, err := net.Listen("tcp", e.apiConfig.ListenAddress())
+	if err != nil {
+		apiLog.Printf("API server error: %v", err)
+	} else {
+		if e.apiTLSConfig != nil {
+			apiLog.Printf("Starting API server on %s (TLS enabled)", e.apiConfig.ListenAddress())
+		} else {
+			apiLog.Printf("Starting API server on %s", e.apiConfig.ListenAddress())
 		}
-	}()
+		go e.serveAPI(server, listener, e.apiTLSConfig != nil)
+	}
+
+	// Optional Unix socket listener for local sidecar access (plain HTTP, protected by file permissions)
+	if e.apiConfig.UnixSocket != "" {
+		unixListener, err := listenUnixSocket(e.apiConfig.UnixSocket)
+		if err != nil {
+			apiLog.Printf("API server error: %v", err)
+			return
+		}
+		apiLog.Printf("Starting API server on unix socket %s", e.apiConfig.UnixSocket)
+		go e.serveAPI(server, unixListener, false)
+	}
+}
+
+// serveAPI serves the API on a listener until the server is shut down
+func (e *Engine) serveAPI(server *http.Server, listener net.Listener, useTLS bool) {
+	var err error
+	if useTLS {
+		err = server.ServeTLS(listener, e.apiConfig.CertFile, e.apiConfig.KeyFile)
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		apiLog.Printf("API server error: %v", err)
+	}
+}
+
+// listenUnixSocket listens on a Unix socket, replacing a stale socket file if present
+func listenUnixSocket(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket path %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale unix socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0660); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set unix socket permissions: %w", err)
+	}
+	return listener, nil
 }
 
 // handleHealth returns a simple health check
@@ -261,31 +744,41 @@ func (e *Engine) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding health response: %v", err)
+		apiLog.Printf("Error encoding health response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
 // handleMetrics returns detailed metrics in JSON format
 func (e *Engine) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	// Hold the metrics lock for the whole snapshot so a concurrent reset is never observed half-applied
 	e.metricsMu.RLock()
 	totalLogs := e.totalLogsProcessed
-	e.metricsMu.RUnlock()
+	injectedLogs := e.totalLogsInjected
 
 	uptime := time.Since(e.startTime)
+	plugins := e.plugins.Load()
 
 	metrics := map[string]interface{}{
 		"total_logs_processed": totalLogs,
+		"total_logs_injected":  injectedLogs,
+		"logs_dropped_total":   e.drops.Snapshot(),
 		"uptime_seconds":       uptime.Seconds(),
-		"inputs_count":         len(e.inputs),
-		"pipelines_count":      len(e.pipelines),
+		"inputs_count":         len(plugins.inputs),
+		"pipelines_count":      len(plugins.pipelines),
 		"buffer_enabled":       e.bufferConfig.Enabled,
 	}
+	if e.errorContext != nil {
+		metrics["error_context_flushed"] = e.ErrorContextFlushed()
+	}
+	if e.sourceQuota != nil {
+		metrics["source_quota_dropped"] = e.sourceQuota.droppedBySource()
+	}
 
 	// Add buffer stats if enabled
 	if e.bufferConfig.Enabled {
 		bufferStats := make(map[string]interface{})
-		for _, pipeline := range e.pipelines {
+		for _, pipeline := range plugins.pipelines {
 			if pipeline.Buffer != nil {
 				stats := pipeline.Buffer.GetStats()
 				bufferStats[pipeline.Name] = map[string]interface{}{
@@ -294,17 +787,29 @@ func (e *Engine) handleMetrics(w http.ResponseWriter, r *http.Request) {
 					"total_retried":    stats.TotalRetried,
 					"total_failed":     stats.TotalFailed,
 					"total_dlq":        stats.TotalDLQ,
+					"total_spilled":    stats.TotalSpilled,
+					"total_stale":      stats.TotalStale,
+					"total_expired":    stats.TotalExpired,
 					"current_queued":   stats.CurrentQueued,
 					"current_retrying": stats.CurrentRetrying,
+					"delivery_workers": pipeline.Buffer.DeliveryWorkers(),
 				}
 			}
 		}
 		metrics["buffer_stats"] = bufferStats
 	}
+	metrics["resilience"] = e.resilienceStatus()
+	if e.diskBudget != nil {
+		metrics["disk_budget"] = e.diskBudgetStatus()
+	}
+	for key, value := range e.reloadMetrics.Snapshot() {
+		metrics[key] = value
+	}
+	e.metricsMu.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(metrics); err != nil {
-		log.Printf("Error encoding metrics response: %v", err)
+		apiLog.Printf("Error encoding metrics response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -317,37 +822,94 @@ func (e *Engine) handleStatus(w http.ResponseWriter, r *http.Request) {
 
 	e.metricsMu.RLock()
 	totalLogs := e.totalLogsProcessed
-	e.metricsMu.RUnlock()
+	injectedLogs := e.totalLogsInjected
 
 	uptime := time.Since(e.startTime)
+	plugins := e.plugins.Load()
 
 	status := map[string]interface{}{
 		"engine": map[string]interface{}{
-			"status":               map[bool]string{true: "stopped", false: "running"}[stopped],
+			"status":               engineStatus(stopped, e.Paused()),
 			"uptime_seconds":       uptime.Seconds(),
 			"start_time":           e.startTime.Format(time.RFC3339),
 			"total_logs_processed": totalLogs,
+			"total_logs_injected":  injectedLogs,
 		},
+		"build": version.Get(),
 		"inputs": map[string]interface{}{
-			"count": len(e.inputs),
+			"count": len(plugins.inputs),
 			"names": func() []string {
-				names := make([]string, 0, len(e.inputs))
-				for name := range e.inputs {
+				names := make([]string, 0, len(plugins.inputs))
+				for name := range plugins.inputs {
 					names = append(names, name)
 				}
 				return names
 			}(),
+			"stats": func() map[string]map[string]any {
+				stats := make(map[string]map[string]any)
+				for name, input := range plugins.inputs {
+					if counters := inputStats(input); counters != nil {
+						stats[name] = counters
+					}
+				}
+				return stats
+			}(),
+			"resilience": func() map[string]map[string]any {
+				stats := make(map[string]map[string]any)
+				for name, input := range plugins.inputs {
+					if health := resilienceStats(input); health != nil {
+						stats[name] = health
+					}
+				}
+				return stats
+			}(),
 		},
 		"outputs": map[string]interface{}{
-			"count": len(e.pipelines),
+			"count": len(plugins.pipelines),
 			"pipelines": func() []map[string]interface{} {
-				pipelines := make([]map[string]interface{}, 0, len(e.pipelines))
-				for _, p := range e.pipelines {
+				pipelines := make([]map[string]interface{}, 0, len(plugins.pipelines))
+				for _, p := range plugins.pipelines {
 					pipeline := map[string]interface{}{
-						"name":       p.Name,
-						"has_buffer": p.Buffer != nil,
-						"filters":    len(p.Filters),
-						"sources":    p.Sources,
+						"name":           p.Name,
+						"enabled":        p.Enabled(),
+						"skipped_logs":   p.SkippedCount(),
+						"write_timeouts": p.WriteTimeoutCount(),
+						"has_buffer":     p.Buffer != nil,
+						"filters":        len(p.Filters),
+						"filter_stats":   p.FilterStats(),
+						"auto_reorder":   p.AutoReorder,
+						"sources":        p.Sources,
+						"shadow":         p.Shadow,
+						"ack_mode":       p.ackMode(),
+					}
+					if missed := p.DeadlineMissedCount(); missed > 0 {
+						pipeline["deadline_missed"] = missed
+					}
+					if p.SampleRate > 0 {
+						pipeline["sample_rate"] = p.SampleRate
+						pipeline["sampled_out"] = p.SampledOutCount()
+					}
+					if len(p.RequireFields) > 0 {
+						pipeline["fields_rejected"] = p.FieldsRejectedCount()
+					}
+					if stats := p.ShadowStats(); stats != nil {
+						pipeline["shadow_stats"] = map[string]interface{}{
+							"received":     stats.Received,
+							"filtered":     stats.Filtered,
+							"written":      stats.Written,
+							"write_errors": stats.WriteErrors,
+							"queue_full":   stats.QueueFull,
+							"queued":       stats.Queued,
+						}
+					}
+					if stats := outputStats(p.Output); stats != nil {
+						pipeline["output_stats"] = stats
+					}
+					if stats := resilienceStats(p.Output); stats != nil {
+						pipeline["resilience"] = stats
+					}
+					if override := levelOverrideStatusEntry(p); override != nil {
+						pipeline["level_override"] = override
 					}
 					if p.Buffer != nil {
 						stats := p.Buffer.GetStats()
@@ -357,6 +919,9 @@ func (e *Engine) handleStatus(w http.ResponseWriter, r *http.Request) {
 							"total_retried":    stats.TotalRetried,
 							"total_failed":     stats.TotalFailed,
 							"total_dlq":        stats.TotalDLQ,
+							"total_spilled":    stats.TotalSpilled,
+							"total_stale":      stats.TotalStale,
+							"total_expired":    stats.TotalExpired,
 							"current_queued":   stats.CurrentQueued,
 							"current_retrying": stats.CurrentRetrying,
 						}
@@ -366,35 +931,310 @@ func (e *Engine) handleStatus(w http.ResponseWriter, r *http.Request) {
 				return pipelines
 			}(),
 		},
+		"pause": e.pauseStatus(),
 		"persistence": map[string]interface{}{
 			"enabled": e.persistence != nil,
 		},
+		"disk_budget":      e.diskBudgetStatus(),
+		"metadata_storage": e.metadataStorageStatus(),
 		"api": map[string]interface{}{
 			"enabled": e.apiConfig.Enabled,
 			"port":    e.apiConfig.Port,
 		},
 	}
+	e.metricsMu.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(status); err != nil {
-		log.Printf("Error encoding status response: %v", err)
+		apiLog.Printf("Error encoding status response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
+// diskBudgetStatus returns the disk budget usage, or nil when there is no budget
+func (e *Engine) diskBudgetStatus() *DiskBudgetStats {
+	if e.diskBudget == nil {
+		return nil
+	}
+	stats := e.diskBudget.Stats()
+	return &stats
+}
+
+// resilienceStatus returns the health, retries and last error of every resilient
+// input and output by name; plugins without the resilient wrapper are omitted
+func (e *Engine) resilienceStatus() map[string]map[string]map[string]any {
+	plugins := e.plugins.Load()
+	inputs := make(map[string]map[string]any)
+	for name, input := range plugins.inputs {
+		if stats := resilienceStats(input); stats != nil {
+			inputs[name] = stats
+		}
+	}
+	outputs := make(map[string]map[string]any)
+	for _, pipeline := range plugins.pipelines {
+		if stats := resilienceStats(pipeline.Output); stats != nil {
+			outputs[pipeline.Name] = stats
+		}
+	}
+	return map[string]map[string]map[string]any{"inputs": inputs, "outputs": outputs}
+}
+
+// engineStatus describes the engine state reported by /status
+func engineStatus(stopped, paused bool) string {
+	switch {
+	case stopped:
+		return "stopped"
+	case paused:
+		return "paused"
+	default:
+		return "running"
+	}
+}
+
+// ResetMetrics zeroes the processed, injected and dropped log counters, per-pipeline counters and buffer statistics.
+// All counters are reset under the metrics lock so readers never observe a partial reset.
+func (e *Engine) ResetMetrics() {
+	e.metricsMu.Lock()
+	defer e.metricsMu.Unlock()
+
+	e.totalLogsProcessed = 0
+	e.totalLogsInjected = 0
+	e.drops.Reset()
+	if e.sourceQuota != nil {
+		e.sourceQuota.resetDropped()
+	}
+	for _, pipeline := range e.plugins.Load().pipelines {
+		pipeline.skipped.Store(0)
+		pipeline.timeouts.Store(0)
+		pipeline.sampledOut.Store(0)
+		pipeline.deadlineMissed.Store(0)
+		pipeline.fieldsRejected.Store(0)
+		pipeline.resetFilterStats()
+		if pipeline.Buffer != nil {
+			pipeline.Buffer.ResetStats()
+		}
+	}
+
+	engineLog.Println("Metrics reset")
+}
+
+// handleMetricsReset resets all engine counters via POST /metrics/reset
+func (e *Engine) handleMetricsReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	e.ResetMetrics()
+
+	response := map[string]string{
+		"status": "reset",
+		"time":   time.Now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding metrics reset response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handlePipelineToggle enables or disables an output pipeline
+// via POST /pipelines/<name>/enable or POST /pipelines/<name>/disable.
+// /pipelines/<name>/level is handed to handlePipelineLevel,
+// /pipelines/<name>/filters/... to handleFilterToggle and
+// DELETE /pipelines/<name> to handlePipelineDelete.
+func (e *Engine) handlePipelineToggle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/pipelines/")
+	if r.Method == http.MethodDelete && path != "" && !strings.Contains(path, "/") {
+		e.handlePipelineDelete(w, r, path)
+		return
+	}
+	if strings.Contains(path, "/filters/") {
+		e.handleFilterToggle(w, r, path)
+		return
+	}
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 {
+		http.Error(w, "Expected /pipelines/<name>/enable, /pipelines/<name>/disable or /pipelines/<name>/level", http.StatusNotFound)
+		return
+	}
+	name, action := path[:idx], path[idx+1:]
+
+	if action == "level" {
+		e.handlePipelineLevel(w, r, name)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var enabled bool
+	switch action {
+	case "enable":
+		enabled = true
+	case "disable":
+		enabled = false
+	default:
+		http.Error(w, fmt.Sprintf("Unknown pipeline action: %s", action), http.StatusNotFound)
+		return
+	}
+
+	if err := e.SetPipelineEnabled(name, enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"name":    name,
+		"enabled": enabled,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding pipeline response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// InjectedLog is a synthetic log accepted by POST /inject
+type InjectedLog struct {
+	Level    string            `json:"level"`
+	Message  string            `json:"message"`
+	Source   string            `json:"source,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+}
+
+const (
+	// InjectedMetadataKey marks logs injected through the API (value "true")
+	InjectedMetadataKey = "injected"
+	// InjectedDefaultSource is the source used for injected logs that do not set one
+	InjectedDefaultSource = "inject"
+	// PipelineMetadataKey holds the name of the pipeline that delivered a log (see OutputPipeline.StampPipeline)
+	PipelineMetadataKey = "pipeline"
+	// maxInjectBatch bounds the number of logs accepted by a single inject request
+	maxInjectBatch = 1000
+	// maxInjectBodyBytes bounds the size of an inject request body
+	maxInjectBodyBytes = 10 << 20
+)
+
+// InjectLogs feeds synthetic logs into the processing pipeline as if they came from an input.
+// Each log is marked with injected=true metadata and routed by its source like any other log.
+func (e *Engine) InjectLogs(entries []InjectedLog) (int, error) {
+	logs := make([]*Log, 0, len(entries))
+	for i, entry := range entries {
+		if strings.TrimSpace(entry.Message) == "" {
+			return 0, fmt.Errorf("log %d: message is required", i)
+		}
+
+		level := Levels().Default()
+		if entry.Level != "" {
+			level = Levels().Normalize(entry.Level)
+		}
+
+		metadata := make(map[string]string, len(entry.Metadata)+1)
+		for k, v := range entry.Metadata {
+			metadata[k] = v
+		}
+		metadata[InjectedMetadataKey] = "true"
+
+		logEntry := NewLogWithMetadata(level, entry.Message, metadata)
+		logEntry.Source = entry.Source
+		if logEntry.Source == "" {
+			logEntry.Source = InjectedDefaultSource
+		}
+		logEntry.AddTags(entry.Tags...)
+		logEntry.injected = true
+		logs = append(logs, logEntry)
+	}
+
+	e.mu.Lock()
+	stopped, inputCh, ctx := e.stopped, e.inputCh, e.ctx
+	e.mu.Unlock()
+	if stopped {
+		return 0, errEngineUnavailable
+	}
+
+	// Stop and ReloadConfig cancel ctx before they close the input channel under
+	// injectMu, so a send blocked on a full channel gives way instead of holding them up
+	e.injectMu.RLock()
+	defer e.injectMu.RUnlock()
+	if ctx.Err() != nil {
+		return 0, errEngineUnavailable
+	}
+
+	for i, logEntry := range logs {
+		select {
+		case inputCh <- logEntry:
+		case <-ctx.Done():
+			return i, errEngineUnavailable
+		}
+	}
+
+	return len(logs), nil
+}
+
+// handleInject accepts a JSON array of synthetic logs via POST /inject
+func (e *Engine) handleInject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var entries []InjectedLog
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInjectBodyBytes)).Decode(&entries); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON array of logs: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, "At least one log is required", http.StatusBadRequest)
+		return
+	}
+	if len(entries) > maxInjectBatch {
+		http.Error(w, fmt.Sprintf("Too many logs: %d (max %d)", len(entries), maxInjectBatch), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	injected, err := e.InjectLogs(entries)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errEngineUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, fmt.Sprintf("%v (injected %d)", err, injected), status)
+		return
+	}
+
+	response := map[string]interface{}{
+		"injected": injected,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding inject response: %v", err)
+	}
+}
+
 // processRecoveredLogs handles logs recovered from persistence
 func (e *Engine) processRecoveredLogs(recoveryCh <-chan *Log) {
 	defer e.wg.Done()
+	// Checkpoint whatever was handed over, including when the engine stops mid-recovery
+	defer e.persistence.finishRecovery()
+
 	for logEntry := range recoveryCh {
-		log.Printf("[ENGINE] Recovered log from WAL: %s - %s", logEntry.Level, logEntry.Message)
+		engineLog.Printf("Recovered log from WAL: %s - %s", logEntry.Level, logEntry.Message)
 		// Send recovered logs directly to the processing pipeline
 		select {
 		case e.inputCh <- logEntry:
+			e.persistence.markRecovered(logEntry.walSeq)
 		case <-e.ctx.Done():
 			return
 		}
 	}
-	log.Println("[ENGINE] Log recovery complete")
+	engineLog.Println("Log recovery complete")
 }
 
 // Stop gracefully shuts down the engine
@@ -409,16 +1249,20 @@ func (e *Engine) Stop() {
 
 	// Signal context cancellation first
 	e.cancel()
+	plugins := e.plugins.Load()
 
 	// Stop all inputs first to stop new logs from coming
-	for name, input := range e.inputs {
+	for name, input := range plugins.inputs {
 		if err := input.Stop(); err != nil {
-			log.Printf("Error stopping input plugin %s: %v", name, err)
+			engineLog.Printf("Error stopping input plugin %s: %v", name, err)
 		}
+		e.publishInputEvent(name, input, EventPluginStopped, nil)
 	}
 
 	// Close the input channel after inputs are stopped
+	e.injectMu.Lock()
 	close(e.inputCh)
+	e.injectMu.Unlock()
 	// Don't set to nil to avoid potential races
 	// e.inputCh = nil
 
@@ -428,68 +1272,143 @@ func (e *Engine) Stop() {
 	// Close persistence layer
 	if e.persistence != nil {
 		if err := e.persistence.Close(); err != nil {
-			log.Printf("Error closing persistence: %v", err)
+			engineLog.Printf("Error closing persistence: %v", err)
 		}
 	}
 
 	// Close API server
 	if e.apiServer != nil {
-		log.Println("Shutting down API server")
+		apiLog.Println("Shutting down API server")
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := e.apiServer.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down API server: %v", err)
+			apiLog.Printf("Error shutting down API server: %v", err)
 		}
 	}
 
+	// Close the reload audit file
+	if err := e.audit().Close(); err != nil {
+		engineLog.Printf("Error closing reload audit: %v", err)
+	}
+
 	// Close all outputs
-	for _, pipeline := range e.pipelines {
+	for _, pipeline := range plugins.pipelines {
+		pipeline.stopShadow()
+
 		// Close buffer if exists
 		if pipeline.Buffer != nil {
 			if err := pipeline.Buffer.Close(); err != nil {
-				log.Printf("Error closing buffer for %s: %v", pipeline.Name, err)
+				engineLog.Printf("Error closing buffer for %s: %v", pipeline.Name, err)
 			}
 		} else {
 			// Close output directly if no buffer
 			if err := pipeline.Output.Close(); err != nil {
-				log.Printf("Error closing output %s: %v", pipeline.Name, err)
+				engineLog.Printf("Error closing output %s: %v", pipeline.Name, err)
 			}
 		}
+		e.publishOutputEvent(pipeline, EventPluginStopped)
 	}
-	log.Println("LogAnalyzer engine stopped")
+	engineLog.Println("LogAnalyzer engine stopped")
+	LifecycleEvents().Publish(LifecycleEvent{Type: EventEngineStopped})
 }
 
+// ReloadBuilder constructs the plugins of a new configuration while the running
+// one keeps processing logs. It must not start plugins or bind ports, so the old
+// and new plugins never hold the same resources; an error rejects the reload and
+// leaves the running configuration untouched. The returned install function adds
+// the built plugins to the engine once the old ones are stopped and closed.
+type ReloadBuilder func(config *Config) (install func(*Engine), err error)
+
 // ReloadConfig reloads the engine with new configuration
-// This method stops the current engine and recreates it with new config
+// This method stops the current engine and recreates it with new config.
+// Plugins are created after the old ones are stopped, so a plugin that fails
+// to construct leaves the engine without it; ReloadConfigFrom with a
+// ReloadBuilder constructs them first.
 func (e *Engine) ReloadConfig(newConfig *Config, createInputFunc func(string, string, map[string]any, *Engine), createOutputFunc func(string, PluginDefinition, *Engine)) error {
+	return e.ReloadConfigFrom(ReloadTriggerManual, newConfig, func(config *Config) (func(*Engine), error) {
+		return func(e *Engine) {
+			for i, inputDef := range config.Inputs {
+				createInputFunc(inputDef.Type, pluginName(inputDef, i), inputDef.Config, e)
+			}
+			for i, outputDef := range config.Outputs {
+				createOutputFunc(pluginName(outputDef, i), outputDef, e)
+			}
+		}, nil
+	})
+}
+
+// ReloadConfigFrom reloads the engine transactionally and records the attempt,
+// with what triggered it, in the reload audit trail. The new configuration is
+// validated and its plugins are built before anything is stopped; if either
+// fails, the running configuration keeps going and the error is returned.
+func (e *Engine) ReloadConfigFrom(trigger string, newConfig *Config, build ReloadBuilder) error {
+	if err := newConfig.Validate(); err != nil {
+		err = fmt.Errorf("invalid configuration: %w", err)
+		e.RecordReloadFailure(trigger, err)
+		return err
+	}
+
+	if err := e.checkPauseConfig(newConfig.Pause); err != nil {
+		e.RecordReloadFailure(trigger, err)
+		return err
+	}
+
+	// Apply the level vocabulary before plugins are built so they resolve levels
+	// against it; the previous one is restored if the reload is rejected
+	previousLevels := levels.Load()
+	if err := SetLevels(newConfig.Levels); err != nil {
+		err = fmt.Errorf("invalid levels configuration: %w", err)
+		e.RecordReloadFailure(trigger, err)
+		return err
+	}
+
+	install, err := build(newConfig)
+	if err != nil {
+		levels.Store(previousLevels)
+		err = fmt.Errorf("failed to build plugins, keeping the running configuration: %w", err)
+		e.recordReloadFailure(trigger, ReloadStageApply, err)
+		return err
+	}
+
 	e.mu.Lock()
-	defer e.mu.Unlock()
 
-	log.Println("Reloading engine configuration...")
+	changes := DiffConfigs(e.appliedConfig, newConfig)
+	e.reloadMetrics.record(ReloadResultSuccess, "")
+	defer e.reloadAudit.Record(ReloadEvent{Trigger: trigger, Result: ReloadResultSuccess, Changes: changes})
+	e.appliedConfig = newConfig
+
+	reloadLog.Println("Reloading engine configuration...")
 
 	// Stop current engine
 	e.cancel()
+	plugins := e.plugins.Load()
 
 	// Stop all inputs first to stop new logs from coming
-	for name, input := range e.inputs {
+	for name, input := range plugins.inputs {
 		if err := input.Stop(); err != nil {
-			log.Printf("Error stopping input plugin %s: %v", name, err)
+			engineLog.Printf("Error stopping input plugin %s: %v", name, err)
 		}
+		e.publishInputEvent(name, input, EventPluginStopped, nil)
 	}
 
 	// Close the input channel after inputs are stopped
 	if e.inputCh != nil {
+		e.injectMu.Lock()
 		close(e.inputCh)
+		e.injectMu.Unlock()
 	}
 
 	// Wait for processing goroutine to finish
 	e.wg.Wait()
 
 	// Close all outputs
-	for _, pipeline := range e.pipelines {
+	for _, pipeline := range plugins.pipelines {
+		pipeline.stopLevelOverrideTimer()
+		pipeline.stopShadow()
 		if err := pipeline.Output.Close(); err != nil {
-			log.Printf("Error closing output %s: %v", pipeline.Name, err)
+			engineLog.Printf("Error closing output %s: %v", pipeline.Name, err)
 		}
+		e.publishOutputEvent(pipeline, EventPluginStopped)
 	}
 
 	// Recreate engine with new context
@@ -497,128 +1416,297 @@ func (e *Engine) ReloadConfig(newConfig *Config, createInputFunc func(string, st
 	e.ctx = ctx
 	e.cancel = cancel
 	e.inputCh = make(chan *Log, 100)
-	e.inputs = make(map[string]InputPlugin)
-	e.filters = []FilterPlugin{}
-	e.pipelines = []*OutputPipeline{}
+	e.updatePlugins(func(plugins *pluginSet) {
+		*plugins = *newPluginSet()
+	})
 	e.stopped = false
+	e.statsInterval = newConfig.StatsInterval
+	e.trace = newConfig.Trace
+	e.serializationCache = newConfig.SerializationCache
+	e.maxLogAge = newConfig.MaxLogAge
+	e.internPool = newInternPool(newConfig.MetadataStorage)
+	e.deadline = newConfig.Deadline
+	e.errorContext = newErrorContext(newConfig.ErrorContext)
+	e.preserve = newPreservePredicate(newConfig.PreserveIf)
+	e.sourceQuota = newSourceQuota(newConfig.SourceQuotas)
+	e.startupGrace = newConfig.StartupGrace
+	_ = newConfig.Logging.Apply()         // Validated above
+	_ = e.SetPauseConfig(newConfig.Pause) // Checked above
+
+	// Add the plugins built for the new configuration
+	install(e)
+
+	// Start the reloaded engine. The wait for outputs happens without e.mu, so
+	// health checks, status and injects keep answering during startup_grace;
+	// inputs added through the API meanwhile are started along with the others.
+	e.startProcessing()
+	e.started.Store(false)
+	ctx, grace := e.ctx, e.startupGrace
+	e.mu.Unlock()
 
-	// Reconfigure with new config
-	// Configure input plugin(s)
-	for i, inputDef := range newConfig.Inputs {
-		inputName := inputDef.Name
-		if inputName == "" {
-			inputName = fmt.Sprintf("%s-%d", inputDef.Type, i+1)
-		}
-		createInputFunc(inputDef.Type, inputName, inputDef.Config, e)
-	}
+	e.waitForOutputs(ctx, grace)
 
-	// Configure output plugin(s)
-	for i, outputDef := range newConfig.Outputs {
-		outputName := outputDef.Name
-		if outputName == "" {
-			outputName = fmt.Sprintf("%s-%d", outputDef.Type, i+1)
-		}
-		createOutputFunc(outputName, outputDef, e)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped || e.ctx != ctx {
+		// Stopped or reloaded again while waiting: the engine is no longer ours to start
+		return nil
 	}
+	e.startInputs()
 
-	// Start the reloaded engine
-	e.Start()
-
-	log.Println("Engine configuration reloaded successfully")
+	reloadLog.Println("Engine configuration reloaded successfully")
 	return nil
 }
 
-// processLogs handles incoming logs, applies filters, and sends to outputs
+// processLogs handles incoming logs, applies filters, and sends to outputs.
+// While the engine is paused it either stops reading the input channel
+// (backpressure) or persists and holds incoming logs until resume (persist).
+// Held logs stay on the engine, so the loop started by a reload delivers them.
 func (e *Engine) processLogs() {
 	defer e.wg.Done()
+
 	for {
-		select {
-		case logEntry, ok := <-e.inputCh:
-			if !ok {
+		paused, changed := e.pauseState()
+
+		switch {
+		case paused && (e.pauseMode() != PauseModePersist || len(e.held) >= e.pauseConfig.maxHeld()):
+			// Leave logs in the input channel; inputs block once it is full
+			select {
+			case <-changed:
+			case <-e.ctx.Done():
 				return
 			}
 
-			// Increment total logs processed counter
-			e.metricsMu.Lock()
-			e.totalLogsProcessed++
-			e.metricsMu.Unlock()
-
-			log.Printf("[ENGINE] Received log from '%s': %s - %s", logEntry.Source, logEntry.Level, logEntry.Message)
-
-			// Persist log before processing (Write-Ahead Log)
-			if e.persistence != nil {
-				if err := e.persistence.Persist(logEntry); err != nil {
-					log.Printf("[ENGINE] Error persisting log: %v", err)
-					// Continue processing even if persistence fails
+		case paused:
+			select {
+			case logEntry, ok := <-e.inputCh:
+				if !ok {
+					return
+				}
+				if e.receiveLog(logEntry) {
+					e.held = append(e.held, logEntry)
+					e.heldLogs.Store(int64(len(e.held)))
 				}
+			case <-changed:
+			case <-e.ctx.Done():
+				return
 			}
 
-			// Apply global filters (deprecated, but kept for backward compatibility)
-			passedGlobalFilters := true
-			if len(e.filters) > 0 {
-				for i, filter := range e.filters {
-					result := filter.Process(logEntry)
-					log.Printf("[ENGINE] Global Filter #%d result: %t", i+1, result)
-					if !result {
-						passedGlobalFilters = false
-						log.Printf("[ENGINE] Log BLOCKED by global filter #%d", i+1)
-						break
-					}
+		case len(e.held) > 0:
+			// Drain logs held while paused before reading new ones
+			logEntry := e.held[0]
+			e.held[0] = nil
+			e.held = e.held[1:]
+			e.heldLogs.Store(int64(len(e.held)))
+			e.dispatchLog(logEntry)
+
+		default:
+			select {
+			case logEntry, ok := <-e.inputCh:
+				if !ok {
+					return
+				}
+				if e.receiveLog(logEntry) {
+					e.dispatchLog(logEntry)
 				}
+			case <-changed:
+			case <-e.ctx.Done():
+				return
 			}
+		}
+	}
+}
+
+// receiveLog counts a log, stamps its source type and writes it to the WAL. It
+// returns false when the log is over its source's quota and must not be dispatched.
+func (e *Engine) receiveLog(logEntry *Log) bool {
+	e.startTrace(logEntry)
+	logEntry.receivedAt = time.Now()
+
+	// A resend for the sync pipelines that failed was counted, checked against its
+	// quota and written to the WAL the first time around
+	if logEntry.ack.retry() {
+		e.internMetadata(logEntry)
+		return true
+	}
+
+	// Increment total logs processed counter (synthetic logs are counted separately)
+	e.metricsMu.Lock()
+	if logEntry.injected {
+		e.totalLogsInjected++
+	} else {
+		e.totalLogsProcessed++
+	}
+	e.metricsMu.Unlock()
+
+	// Stamp the input plugin type unless the input already set one
+	if logEntry.SourceType == "" {
+		logEntry.SourceType = e.plugins.Load().inputTypes[logEntry.Source]
+	}
+
+	// Over-quota logs are dropped before they take WAL space or reach a pipeline
+	if e.overQuota(logEntry) {
+		logEntry.ack.dispatched()
+		return false
+	}
+	e.internMetadata(logEntry)
+
+	engineLog.Printf("Received log from '%s': %s - %s", logEntry.Source, logEntry.Level, logEntry.Message)
+
+	// Persist log before processing (Write-Ahead Log); recovered logs are already in it
+	if e.persistence != nil && logEntry.walSeq == 0 {
+		if err := e.persistence.Persist(logEntry); err != nil {
+			engineLog.Printf("Error persisting log: %v", err)
+			// Continue processing even if persistence fails
+		}
+		if logEntry.trace != nil {
+			logEntry.trace.persisted = time.Now()
+		}
+	}
+	return true
+}
 
-			if !passedGlobalFilters {
-				continue // Skip this log
+// dispatchLog applies the global filters and sends a log to every output pipeline
+func (e *Engine) dispatchLog(logEntry *Log) {
+	// Sync pipelines have registered their deliveries by the time dispatch returns
+	defer logEntry.ack.dispatched()
+
+	// Logs replayed or delayed past max_log_age would only mislead dashboards
+	if e.dropStale(logEntry) {
+		return
+	}
+
+	// Pipelines removed through the API are closed only once this dispatch is done
+	e.dispatchMu.RLock()
+	defer e.dispatchMu.RUnlock()
+	plugins := e.plugins.Load()
+
+	// Apply global filters (deprecated, but kept for backward compatibility)
+	for i, filter := range plugins.filters {
+		if e.preserve.skips(filter, logEntry) {
+			continue
+		}
+		result := filter.Process(logEntry)
+		engineLog.Printf("Global Filter #%d result: %t", i+1, result)
+		if !result {
+			e.drops.Inc(DropReasonGlobalFilter)
+			engineLog.Printf("Log BLOCKED by global filter #%d", i+1)
+			return // Skip this log
+		}
+	}
+
+	// Outputs sharing a JSON format encode the log once; encodings are dropped
+	// once the log has left every pipeline
+	if e.serializationCache && len(plugins.pipelines) > 1 {
+		defer attachEncodeCache(logEntry).release()
+	}
+
+	// An error brings along the logs its source sent just before it; other logs
+	// are kept as context once dispatched, noting whether the target already has them.
+	// Resends skip it, as the first dispatch already did this.
+	contextTarget := false
+	if e.errorContext != nil && !logEntry.ack.retry() {
+		if e.errorContext.isTrigger(logEntry) {
+			e.flushErrorContext(logEntry)
+		} else {
+			defer func() { e.errorContext.remember(logEntry, contextTarget) }()
+		}
+	}
+
+	// Send to each output pipeline
+	for _, pipeline := range plugins.pipelines {
+		// A resend goes only to the sync pipelines that did not deliver the log
+		if !logEntry.ack.includes(pipeline) {
+			continue
+		}
+
+		if pipeline.Shadow {
+			pipeline.offerShadow(logEntry)
+			continue
+		}
+
+		// The late output receives only logs that missed their deadline elsewhere
+		if e.isLateOutput(pipeline) {
+			continue
+		}
+
+		// Skip pipelines that have been disabled at runtime
+		if !pipeline.Enabled() {
+			pipeline.skipped.Add(1)
+			e.drops.Inc(DropReasonPipelineDisabled)
+			continue
+		}
+
+		// Check if this pipeline accepts logs from this source
+		if !pipeline.AcceptsSource(logEntry) {
+			e.drops.Inc(DropReasonSourceMismatch)
+			engineLog.Printf("Output '%s' rejected log from source '%s'", pipeline.Name, logEntry.Source)
+			continue
+		}
+
+		// Apply pipeline-specific filters
+		entry, passedPipelineFilters, blockedBy := pipeline.applyFilters(logEntry)
+		filtered := traceTime(logEntry)
+		if !passedPipelineFilters {
+			e.drops.Inc(filterDropReason(pipeline.Filters[blockedBy]))
+			engineLog.Printf("Log BLOCKED by output '%s' filter #%d", pipeline.Name, pipeline.filterNumber(blockedBy))
+		}
+
+		// Tags are checked after the pipeline filters so they can tag logs for this pipeline
+		if passedPipelineFilters && !pipeline.AcceptsTags(entry) {
+			e.drops.Inc(DropReasonTagMismatch)
+			engineLog.Printf("Output '%s' rejected log with tags %v", pipeline.Name, entry.Tags)
+			continue
+		}
+
+		if passedPipelineFilters {
+			// Sampling comes last, so the rate applies to the logs the pipeline would deliver
+			if !pipeline.sampled(entry) {
+				e.drops.Inc(DropReasonSampled)
+				continue
 			}
 
-			// Send to each output pipeline
-			for _, pipeline := range e.pipelines {
-				// Check if this pipeline accepts logs from this source
-				if len(pipeline.Sources) > 0 {
-					accepted := false
-					for _, source := range pipeline.Sources {
-						if source == logEntry.Source {
-							accepted = true
-							break
-						}
-					}
-					if !accepted {
-						log.Printf("[ENGINE] Output '%s' rejected log from source '%s'", pipeline.Name, logEntry.Source)
-						continue
-					}
-				}
+			// A log that can no longer arrive in time is not worth writing
+			if e.missedDeadline(pipeline, entry) {
+				continue
+			}
 
-				// Apply pipeline-specific filters
-				passedPipelineFilters := true
-				for i, filter := range pipeline.Filters {
-					result := filter.Process(logEntry)
-					log.Printf("[ENGINE] Output '%s' Filter #%d result: %t", pipeline.Name, i+1, result)
-					if !result {
-						passedPipelineFilters = false
-						log.Printf("[ENGINE] Log BLOCKED by output '%s' filter #%d", pipeline.Name, i+1)
-						break
-					}
-				}
+			// Logs the backend would refuse are set aside before they are written
+			if e.rejectsFields(pipeline, entry) {
+				continue
+			}
 
-				if passedPipelineFilters {
-					log.Printf("[ENGINE] Log PASSED filters for output '%s', sending to output", pipeline.Name)
+			engineLog.Printf("Log PASSED filters for output '%s', sending to output", pipeline.Name)
 
-					// Use buffer if available, otherwise direct write
-					var err error
-					if pipeline.Buffer != nil {
-						err = pipeline.Buffer.Enqueue(logEntry)
-					} else {
-						err = pipeline.Output.Write(logEntry)
-					}
+			// A copy made by the pipeline filters is owned by this pipeline, so
+			// metadata the filters added can be interned before it is queued
+			if entry != logEntry {
+				e.internMetadata(entry)
+			}
 
-					if err != nil {
-						log.Printf("[ENGINE] Error writing to output '%s': %v", pipeline.Name, err)
-					}
+			// Use buffer if available, otherwise direct write (bounded by the write timeout)
+			out := pipeline.stamp(entry, entry != logEntry)
+			out = e.annotateTrace(out, out != logEntry, filtered)
+			out = pipeline.expectAck(logEntry.ack, out, out == logEntry)
+			err := pipeline.writeWithTimeout(e.ctx, out)
+			if err != nil || !pipeline.buffered() {
+				out.settleAck(err) // Otherwise the buffer settles it once delivered
+			}
+			if logEntry.trace != nil {
+				logTraceStages(pipeline.Name, logEntry, filtered, time.Now())
+			}
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errWriteInFlight) {
+					e.drops.Inc(DropReasonWriteTimeout)
+				} else if errors.Is(err, ErrDiskBudgetExceeded) {
+					e.drops.Inc(DropReasonDiskBudget)
+				} else {
+					e.drops.Inc(DropReasonWriteError)
 				}
+				engineLog.Printf("Error writing to output '%s': %v", pipeline.Name, err)
+			} else if e.errorContext != nil && pipeline.Name == e.errorContext.config.Target {
+				contextTarget = true
 			}
-
-		case <-e.ctx.Done():
-			return
 		}
 	}
 }
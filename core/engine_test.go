@@ -1,12 +1,31 @@
 package core
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 )
 
 // Mock input plugin for testing
@@ -126,15 +145,16 @@ func TestNewEngine(t *testing.T) {
 		t.Error("inputCh should be initialized")
 	}
 
-	if engine.inputs == nil {
+	plugins := engine.plugins.Load()
+	if plugins.inputs == nil {
 		t.Error("inputs map should be initialized")
 	}
 
-	if engine.filters == nil {
+	if plugins.filters == nil {
 		t.Error("filters slice should be initialized")
 	}
 
-	if engine.pipelines == nil {
+	if plugins.pipelines == nil {
 		t.Error("pipelines slice should be initialized")
 	}
 
@@ -153,11 +173,11 @@ func TestEngineAddInput(t *testing.T) {
 
 	engine.AddInput("test-input", input)
 
-	if len(engine.inputs) != 1 {
-		t.Errorf("Expected 1 input, got %d", len(engine.inputs))
+	if len(engine.plugins.Load().inputs) != 1 {
+		t.Errorf("Expected 1 input, got %d", len(engine.plugins.Load().inputs))
 	}
 
-	if engine.inputs["test-input"] != input {
+	if engine.plugins.Load().inputs["test-input"] != input {
 		t.Error("Input not added correctly")
 	}
 }
@@ -168,11 +188,11 @@ func TestEngineAddFilter(t *testing.T) {
 
 	engine.AddFilter(filter)
 
-	if len(engine.filters) != 1 {
-		t.Errorf("Expected 1 filter, got %d", len(engine.filters))
+	if len(engine.plugins.Load().filters) != 1 {
+		t.Errorf("Expected 1 filter, got %d", len(engine.plugins.Load().filters))
 	}
 
-	if engine.filters[0] != filter {
+	if engine.plugins.Load().filters[0] != filter {
 		t.Error("Filter not added correctly")
 	}
 }
@@ -183,11 +203,11 @@ func TestEngineAddOutput(t *testing.T) {
 
 	engine.AddOutput(output)
 
-	if len(engine.pipelines) != 1 {
-		t.Errorf("Expected 1 pipeline, got %d", len(engine.pipelines))
+	if len(engine.plugins.Load().pipelines) != 1 {
+		t.Errorf("Expected 1 pipeline, got %d", len(engine.plugins.Load().pipelines))
 	}
 
-	if engine.pipelines[0].Output != output {
+	if engine.plugins.Load().pipelines[0].Output != output {
 		t.Error("Output not added correctly")
 	}
 }
@@ -208,12 +228,12 @@ func TestEngineAddOutputPipeline(t *testing.T) {
 		t.Fatalf("Failed to add output pipeline: %v", err)
 	}
 
-	if len(engine.pipelines) != 1 {
-		t.Errorf("Expected 1 pipeline, got %d", len(engine.pipelines))
+	if len(engine.plugins.Load().pipelines) != 1 {
+		t.Errorf("Expected 1 pipeline, got %d", len(engine.plugins.Load().pipelines))
 	}
 
-	if engine.pipelines[0].Name != "test-pipeline" {
-		t.Errorf("Expected pipeline name 'test-pipeline', got '%s'", engine.pipelines[0].Name)
+	if engine.plugins.Load().pipelines[0].Name != "test-pipeline" {
+		t.Errorf("Expected pipeline name 'test-pipeline', got '%s'", engine.plugins.Load().pipelines[0].Name)
 	}
 }
 
@@ -718,6 +738,12 @@ func TestEngineHandleStatus(t *testing.T) {
 		}
 	}
 
+	// Check build section
+	build, ok := statusResp["build"].(map[string]interface{})
+	if !ok || build["version"] != "dev" || build["go_version"] == "" {
+		t.Errorf("Expected the build info with the dev version, got %v", statusResp["build"])
+	}
+
 	// Check inputs section
 	if inputsSection, exists := statusResp["inputs"]; exists {
 		inputsMap := inputsSection.(map[string]interface{})
@@ -791,3 +817,833 @@ func TestEngineHandleStatusWithAPIEnabled(t *testing.T) {
 		}
 	}
 }
+
+func TestEnginePipelineDisabledSkipsLogs(t *testing.T) {
+	engine := NewEngine()
+
+	logs := []*Log{
+		NewLog("info", "first"),
+		NewLog("info", "second"),
+	}
+	input := newMockInput(logs)
+	engine.AddInput("test-input", input)
+
+	enabledOutput := newMockOutput()
+	disabledOutput := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "enabled", Output: enabledOutput}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	disabled := &OutputPipeline{Name: "disabled", Output: disabledOutput}
+	disabled.SetEnabled(false)
+	if err := engine.AddOutputPipeline(disabled); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	if enabledOutput.getCallCount() != len(logs) {
+		t.Errorf("Expected enabled output to receive %d logs, got %d", len(logs), enabledOutput.getCallCount())
+	}
+	if disabledOutput.getCallCount() != 0 {
+		t.Errorf("Expected disabled output to receive no logs, got %d", disabledOutput.getCallCount())
+	}
+	if disabled.SkippedCount() != int64(len(logs)) {
+		t.Errorf("Expected %d skipped logs, got %d", len(logs), disabled.SkippedCount())
+	}
+}
+
+func TestEngineHandlePipelineToggle(t *testing.T) {
+	engine := NewEngine()
+	pipeline := &OutputPipeline{Name: "alerts", Output: newMockOutput()}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	if !pipeline.Enabled() {
+		t.Fatal("Pipelines should be enabled by default")
+	}
+
+	w := httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("POST", "/pipelines/alerts/disable", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if pipeline.Enabled() {
+		t.Error("Expected pipeline to be disabled")
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("POST", "/pipelines/alerts/enable", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !pipeline.Enabled() {
+		t.Error("Expected pipeline to be enabled")
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "unknown pipeline", method: "POST", path: "/pipelines/missing/enable", status: http.StatusNotFound},
+		{name: "unknown action", method: "POST", path: "/pipelines/alerts/restart", status: http.StatusNotFound},
+		{name: "missing action", method: "POST", path: "/pipelines/alerts", status: http.StatusNotFound},
+		{name: "wrong method", method: "GET", path: "/pipelines/alerts/enable", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.handlePipelineToggle(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestEngineHandleMetricsReset(t *testing.T) {
+	engine := NewEngine()
+	engine.SetOutputBufferConfig(OutputBufferConfig{
+		Enabled:       true,
+		Dir:           t.TempDir(),
+		MaxQueueSize:  10,
+		MaxRetries:    3,
+		RetryInterval: time.Second,
+		MaxRetryDelay: time.Minute,
+		FlushInterval: time.Minute,
+	})
+
+	output := newMockOutput()
+	pipeline := &OutputPipeline{Name: "buffered", Output: output}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	defer func() { _ = pipeline.Buffer.Close() }()
+
+	engine.totalLogsProcessed = 42
+	pipeline.skipped.Store(7)
+	if err := pipeline.Buffer.Enqueue(NewLog("info", "buffered")); err != nil {
+		t.Fatalf("Failed to enqueue log: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	engine.handleMetricsReset(w, httptest.NewRequest("POST", "/metrics/reset", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	if engine.totalLogsProcessed != 0 {
+		t.Errorf("Expected total logs to be reset, got %d", engine.totalLogsProcessed)
+	}
+	if pipeline.SkippedCount() != 0 {
+		t.Errorf("Expected skipped logs to be reset, got %d", pipeline.SkippedCount())
+	}
+	if stats := pipeline.Buffer.GetStats(); stats.TotalEnqueued != 0 {
+		t.Errorf("Expected buffer enqueued count to be reset, got %d", stats.TotalEnqueued)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleMetricsReset(w, httptest.NewRequest("GET", "/metrics/reset", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+type shutdownRequestingInput struct {
+	*mockInput
+	shutdown func()
+}
+
+func (s *shutdownRequestingInput) SetShutdownFunc(fn func()) {
+	s.shutdown = fn
+}
+
+func TestEngineRequestShutdown(t *testing.T) {
+	engine := NewEngine()
+	input := &shutdownRequestingInput{mockInput: newMockInput([]*Log{})}
+
+	engine.AddInput("stdin", input)
+	if input.shutdown == nil {
+		t.Fatal("Expected engine to provide a shutdown func to the input")
+	}
+
+	select {
+	case <-engine.ShutdownRequested():
+		t.Fatal("Shutdown should not be requested yet")
+	default:
+	}
+
+	// Multiple requests must be safe
+	input.shutdown()
+	input.shutdown()
+
+	select {
+	case <-engine.ShutdownRequested():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected shutdown to be requested")
+	}
+}
+
+func TestEngineSourceTypeRouting(t *testing.T) {
+	engine := NewEngine()
+
+	dockerLogs := []*Log{NewLog("info", "From docker")}
+	dockerLogs[0].Source = "docker-web-1"
+
+	httpLogs := []*Log{NewLog("info", "From http")}
+	httpLogs[0].Source = "api"
+
+	legacyLogs := []*Log{NewLog("info", "From untyped input")}
+	legacyLogs[0].Source = "legacy"
+
+	engine.AddInputWithType("docker-web-1", "docker", newMockInput(dockerLogs))
+	engine.AddInputWithType("api", "http", newMockInput(httpLogs))
+	engine.AddInput("legacy", newMockInput(legacyLogs))
+
+	// Output that accepts every docker input regardless of instance name
+	output := newMockOutput()
+	pipeline := &OutputPipeline{
+		Name:    "docker-only",
+		Output:  output,
+		Filters: []FilterPlugin{},
+		Sources: []string{"type:docker"},
+	}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	outputLogs := output.getLogs()
+	if len(outputLogs) != 1 {
+		t.Fatalf("Expected 1 output log, got %d", len(outputLogs))
+	}
+	if outputLogs[0].SourceType != "docker" {
+		t.Errorf("Expected source type 'docker', got '%s'", outputLogs[0].SourceType)
+	}
+	if outputLogs[0].Source != "docker-web-1" {
+		t.Errorf("Expected source 'docker-web-1', got '%s'", outputLogs[0].Source)
+	}
+	if legacyLogs[0].SourceType != "" {
+		t.Errorf("Expected empty source type for untyped input, got '%s'", legacyLogs[0].SourceType)
+	}
+}
+
+func TestOutputPipelineAcceptsSource(t *testing.T) {
+	logEntry := NewLog("info", "test")
+	logEntry.Source = "docker-web-1"
+	logEntry.SourceType = "docker"
+
+	tests := []struct {
+		name     string
+		sources  []string
+		expected bool
+	}{
+		{name: "no sources accepts all", sources: nil, expected: true},
+		{name: "matching name", sources: []string{"docker-web-1"}, expected: true},
+		{name: "matching type", sources: []string{"type:docker"}, expected: true},
+		{name: "other type", sources: []string{"type:http"}, expected: false},
+		{name: "type name is not an instance name", sources: []string{"docker"}, expected: false},
+		{name: "mixed list", sources: []string{"api", "type:docker"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &OutputPipeline{Sources: tt.sources}
+			if got := pipeline.AcceptsSource(logEntry); got != tt.expected {
+				t.Errorf("AcceptsSource() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// freeTCPPort returns a currently unused local TCP port
+func freeTCPPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestEngineAPIBindAddressAndUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+	port := freeTCPPort(t)
+
+	engine := NewEngine()
+	if err := engine.EnableAPI(APIConfig{
+		Enabled:     true,
+		Port:        port,
+		BindAddress: "127.0.0.1",
+		UnixSocket:  socketPath,
+	}); err != nil {
+		t.Fatalf("EnableAPI failed: %v", err)
+	}
+	engine.Start()
+	defer engine.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	checkHealth := func() {
+		t.Helper()
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
+		if err != nil {
+			t.Fatalf("Health request over TCP failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 over TCP, got %d", resp.StatusCode)
+		}
+
+		resp, err = unixClient.Get("http://unix/health")
+		if err != nil {
+			t.Fatalf("Health request over unix socket failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 over unix socket, got %d", resp.StatusCode)
+		}
+	}
+	checkHealth()
+
+	// A reload keeps the running API server and its listeners
+	server := engine.apiServer
+	inputs := []PluginDefinition{{Type: "stdin", Config: map[string]any{"resilient": false}}}
+	outputs := []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}}
+	noInput := func(string, string, map[string]any, *Engine) {}
+	noOutput := func(string, PluginDefinition, *Engine) {}
+	if err := engine.ReloadConfig(&Config{Inputs: inputs, Outputs: outputs}, noInput, noOutput); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if engine.apiServer != server {
+		t.Error("Expected the reload not to start another API server")
+	}
+	checkHealth()
+
+	// Stop shuts down the server that owns the listeners
+	engine.Stop()
+	if _, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port)); err == nil {
+		t.Error("Expected the TCP listener to be closed after Stop")
+	}
+	if _, err := unixClient.Get("http://unix/health"); err == nil {
+		t.Error("Expected the unix socket to be closed after Stop")
+	}
+}
+
+func TestEngineAPITLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	port := freeTCPPort(t)
+	engine := NewEngine()
+	if err := engine.EnableAPI(APIConfig{
+		Enabled:     true,
+		Port:        port,
+		BindAddress: "127.0.0.1",
+		TLS:         tlsconfig.Config{Enabled: true},
+		CertFile:    certFile,
+		KeyFile:     keyFile,
+	}); err != nil {
+		t.Fatalf("EnableAPI failed: %v", err)
+	}
+	engine.Start()
+	defer engine.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	pool := x509.NewCertPool()
+	cert, _ := x509.ParseCertificate(certDER)
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/health", port))
+	if err != nil {
+		t.Fatalf("Health request over TLS failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 over TLS, got %d", resp.StatusCode)
+	}
+
+	// Plain HTTP must not be served when TLS is enabled
+	if resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port)); err == nil {
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("Expected plain HTTP request to be rejected")
+		}
+	}
+}
+
+// blockingOutput blocks every Write until released
+type blockingOutput struct {
+	release   chan struct{}
+	callCount atomic.Int64
+}
+
+func (b *blockingOutput) Write(log *Log) error {
+	b.callCount.Add(1)
+	<-b.release
+	return nil
+}
+
+func (b *blockingOutput) Close() error {
+	return nil
+}
+
+func TestEnginePipelineWriteTimeoutIsolatesOutputs(t *testing.T) {
+	engine := NewEngine()
+
+	logs := make([]*Log, 5)
+	for i := range logs {
+		logs[i] = NewLog("info", fmt.Sprintf("message %d", i))
+	}
+	engine.AddInput("test-input", newMockInput(logs))
+
+	hanging := &blockingOutput{release: make(chan struct{})}
+	defer close(hanging.release)
+	slowPipeline := &OutputPipeline{
+		Name:         "hanging",
+		Output:       hanging,
+		WriteTimeout: 20 * time.Millisecond,
+	}
+	healthy := newMockOutput()
+	fastPipeline := &OutputPipeline{
+		Name:   "healthy",
+		Output: healthy,
+	}
+	if err := engine.AddOutputPipeline(slowPipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	if err := engine.AddOutputPipeline(fastPipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	if got := healthy.getCallCount(); got != len(logs) {
+		t.Errorf("Expected healthy output to receive %d logs, got %d", len(logs), got)
+	}
+
+	// Only the first write may hang; later writes fail fast instead of spawning goroutines
+	if got := hanging.callCount.Load(); got != 1 {
+		t.Errorf("Expected exactly 1 in-flight write to the hanging output, got %d", got)
+	}
+	if got := slowPipeline.WriteTimeoutCount(); got != int64(len(logs)) {
+		t.Errorf("Expected %d write timeouts, got %d", len(logs), got)
+	}
+
+	hanging.release <- struct{}{}
+	engine.Stop()
+}
+
+func TestOutputPipelineAcceptsTags(t *testing.T) {
+	logEntry := NewLog("error", "test")
+	logEntry.AddTags("alert", "security")
+
+	tests := []struct {
+		name     string
+		tags     []string
+		tagMatch string
+		expected bool
+	}{
+		{name: "no tags accepts all", tags: nil, expected: true},
+		{name: "any matches one", tags: []string{"audit", "alert"}, expected: true},
+		{name: "any matches none", tags: []string{"audit"}, expected: false},
+		{name: "all present", tags: []string{"alert", "security"}, tagMatch: TagMatchAll, expected: true},
+		{name: "all missing one", tags: []string{"alert", "audit"}, tagMatch: TagMatchAll, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &OutputPipeline{Tags: tt.tags, TagMatch: tt.tagMatch}
+			if got := pipeline.AcceptsTags(logEntry); got != tt.expected {
+				t.Errorf("AcceptsTags() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// tagFilter tags every log it sees
+type tagFilter struct {
+	tag string
+}
+
+func (f *tagFilter) Process(log *Log) bool {
+	log.AddTags(f.tag)
+	return true
+}
+
+func TestEngineTagRouting(t *testing.T) {
+	engine := NewEngine()
+
+	tagged := NewLog("error", "tagged at ingest")
+	tagged.AddTags("alert")
+	plain := NewLog("info", "untagged")
+	engine.AddInput("test-input", newMockInput([]*Log{tagged, plain}))
+
+	alerts := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "alerts", Output: alerts, Tags: []string{"alert"}}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	// A pipeline filter can tag logs for its own pipeline
+	audited := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{
+		Name:    "audit",
+		Output:  audited,
+		Filters: []FilterPlugin{&tagFilter{tag: "audit"}},
+		Tags:    []string{"audit"},
+	}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	if got := alerts.getLogs(); len(got) != 1 || got[0].Message != "tagged at ingest" {
+		t.Errorf("Expected only the alert-tagged log, got %d logs", len(got))
+	}
+	if got := audited.getCallCount(); got != 2 {
+		t.Errorf("Expected both logs to be tagged and routed to audit, got %d", got)
+	}
+}
+
+func TestEngineStampPipeline(t *testing.T) {
+	engine := NewEngine()
+
+	original := NewLogWithMetadata("error", "shared", map[string]string{"trace_id": "abc"})
+	engine.AddInput("test-input", newMockInput([]*Log{original}))
+
+	alerts := newMockOutput()
+	archive := newMockOutput()
+	plain := newMockOutput()
+	for _, pipeline := range []*OutputPipeline{
+		{Name: "alerts", Output: alerts, StampPipeline: true},
+		{Name: "archive", Output: archive, StampPipeline: true},
+		{Name: "plain", Output: plain},
+	} {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	for name, output := range map[string]*mockOutput{"alerts": alerts, "archive": archive} {
+		logs := output.getLogs()
+		if len(logs) != 1 {
+			t.Fatalf("Expected 1 log for %s, got %d", name, len(logs))
+		}
+		if got := logs[0].Metadata[PipelineMetadataKey]; got != name {
+			t.Errorf("Expected pipeline %q, got %q", name, got)
+		}
+		if got := logs[0].Metadata["trace_id"]; got != "abc" {
+			t.Errorf("Expected existing metadata to be kept for %s, got %q", name, got)
+		}
+		if logs[0] == original {
+			t.Errorf("Expected %s to receive a copy of the log", name)
+		}
+	}
+
+	logs := plain.getLogs()
+	if len(logs) != 1 || logs[0] != original {
+		t.Fatalf("Expected the unstamped pipeline to receive the shared log")
+	}
+	if _, ok := original.Metadata[PipelineMetadataKey]; ok {
+		t.Errorf("Expected the shared log not to be stamped, got %v", original.Metadata)
+	}
+}
+
+func TestEnginePipelineFiltersAreIsolated(t *testing.T) {
+	engine := NewEngine()
+
+	shared := NewLog("error", "shared")
+	engine.AddInput("test-input", newMockInput([]*Log{shared}))
+
+	// Both pipelines set the same key; neither may see the other's value
+	first := newMockOutput()
+	second := newMockOutput()
+	untouched := newMockOutput()
+	for _, pipeline := range []*OutputPipeline{
+		{Name: "first", Output: first, Filters: []FilterPlugin{&metadataFilter{key: "team", value: "first"}}},
+		{Name: "second", Output: second, Filters: []FilterPlugin{&metadataFilter{key: "team", value: "second"}, &tagFilter{tag: "second"}}},
+		{Name: "untouched", Output: untouched, Filters: []FilterPlugin{&costFilter{cost: 1, keep: true}}},
+	} {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	for name, output := range map[string]*mockOutput{"first": first, "second": second} {
+		logs := output.getLogs()
+		if len(logs) != 1 {
+			t.Fatalf("Expected 1 log for %s, got %d", name, len(logs))
+		}
+		if got := logs[0].Metadata["team"]; got != name {
+			t.Errorf("Expected %s to see its own metadata, got %q", name, got)
+		}
+	}
+	if tags := first.getLogs()[0].Tags; len(tags) != 0 {
+		t.Errorf("Expected tags added by another pipeline not to leak, got %v", tags)
+	}
+
+	logs := untouched.getLogs()
+	if len(logs) != 1 || logs[0] != shared {
+		t.Fatalf("Expected the predicate-only pipeline to receive the shared log without copying")
+	}
+	if len(shared.Metadata) != 0 || len(shared.Tags) != 0 {
+		t.Errorf("Expected the shared log to be untouched, got metadata %v and tags %v", shared.Metadata, shared.Tags)
+	}
+}
+
+func TestEngineHandleInject(t *testing.T) {
+	engine := NewEngine()
+	engine.AddInputWithType("docker-web-1", "docker", newMockInput(nil))
+
+	output := newMockOutput()
+	pipeline := &OutputPipeline{
+		Name:    "docker-only",
+		Output:  output,
+		Filters: []FilterPlugin{},
+		Sources: []string{"type:docker"},
+	}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+
+	body := `[
+		{"level": "ERROR", "message": "routed", "source": "docker-web-1", "metadata": {"trace_id": "abc"}},
+		{"message": "not routed"}
+	]`
+	w := httptest.NewRecorder()
+	engine.handleInject(w, httptest.NewRequest("POST", "/inject", strings.NewReader(body)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Inputs can carry an injected=true metadata value of their own; only InjectLogs counts as injected
+	engine.inputCh <- NewLogWithMetadata("info", "from an input", map[string]string{InjectedMetadataKey: "true"})
+
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	outputLogs := output.getLogs()
+	if len(outputLogs) != 1 {
+		t.Fatalf("Expected 1 output log, got %d", len(outputLogs))
+	}
+	logEntry := outputLogs[0]
+	if logEntry.Level != "error" {
+		t.Errorf("Expected level 'error', got '%s'", logEntry.Level)
+	}
+	if logEntry.SourceType != "docker" {
+		t.Errorf("Expected source type 'docker', got '%s'", logEntry.SourceType)
+	}
+	if logEntry.Metadata[InjectedMetadataKey] != "true" {
+		t.Errorf("Expected injected=true metadata, got %v", logEntry.Metadata)
+	}
+	if logEntry.Metadata["trace_id"] != "abc" {
+		t.Errorf("Expected trace_id metadata to be kept, got %v", logEntry.Metadata)
+	}
+
+	engine.metricsMu.RLock()
+	defer engine.metricsMu.RUnlock()
+	if engine.totalLogsInjected != 2 {
+		t.Errorf("Expected 2 injected logs, got %d", engine.totalLogsInjected)
+	}
+	if engine.totalLogsProcessed != 1 {
+		t.Errorf("Expected only the input's log in the processed count, got %d", engine.totalLogsProcessed)
+	}
+}
+
+func TestEngineHandleInjectErrors(t *testing.T) {
+	engine := NewEngine()
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{name: "wrong method", method: "GET", body: "", status: http.StatusMethodNotAllowed},
+		{name: "invalid json", method: "POST", body: `{"message": "not an array"}`, status: http.StatusBadRequest},
+		{name: "empty array", method: "POST", body: `[]`, status: http.StatusBadRequest},
+		{name: "missing message", method: "POST", body: `[{"level": "info"}]`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.handleInject(w, httptest.NewRequest(tt.method, "/inject", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+
+	engine.Start()
+	engine.Stop()
+
+	w := httptest.NewRecorder()
+	engine.handleInject(w, httptest.NewRequest("POST", "/inject", strings.NewReader(`[{"message": "late"}]`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 after stop, got %d", w.Code)
+	}
+}
+
+func TestEngineInjectLogsDoesNotBlockStop(t *testing.T) {
+	engine := NewEngine() // Not started, so nothing drains the input channel
+
+	entries := make([]InjectedLog, cap(engine.inputCh)+10)
+	for i := range entries {
+		entries[i] = InjectedLog{Message: fmt.Sprintf("synthetic %d", i)}
+	}
+
+	type result struct {
+		sent int
+		err  error
+	}
+	injected := make(chan result, 1)
+	go func() {
+		sent, err := engine.InjectLogs(entries)
+		injected <- result{sent, err}
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(engine.inputCh) < cap(engine.inputCh) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		engine.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop deadlocked behind InjectLogs blocked on a full input channel")
+	}
+
+	res := <-injected
+	if res.sent != cap(engine.inputCh) || !errors.Is(res.err, errEngineUnavailable) {
+		t.Errorf("Expected %d logs sent and errEngineUnavailable, got %d and %v", cap(engine.inputCh), res.sent, res.err)
+	}
+}
+
+func TestReloadConfigFromKeepsRunningConfigOnBuildFailure(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	defer func() { _ = SetLevels(LevelsConfig{}) }()
+
+	engine := NewEngine()
+	oldOutput := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "old", Output: oldOutput}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	engine.Start()
+	defer engine.Stop()
+
+	newConfig := &Config{
+		Inputs:  []PluginDefinition{{Type: "stdin", Config: map[string]any{"resilient": false}}},
+		Outputs: []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}},
+		Levels:  LevelsConfig{Order: []string{"low", "high"}, Default: "low"},
+	}
+
+	built := false
+	err := engine.ReloadConfigFrom(ReloadTriggerFile, newConfig, func(config *Config) (func(*Engine), error) {
+		built = true
+		if !Levels().Known("high") {
+			t.Error("Expected the new level vocabulary while building plugins")
+		}
+		return nil, fmt.Errorf("unknown field 'hots' in elasticsearch config")
+	})
+	if err == nil || !built {
+		t.Fatalf("Expected the build failure to reject the reload, got %v", err)
+	}
+	if Levels().Known("high") {
+		t.Error("Expected the previous level vocabulary to be restored")
+	}
+
+	// The old pipeline is still in place and processing
+	engine.InputChannel() <- NewLog("info", "still running")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(oldOutput.getLogs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(oldOutput.getLogs()) != 1 {
+		t.Fatalf("Expected the old pipeline to keep processing, got %d logs", len(oldOutput.getLogs()))
+	}
+
+	// A successful build is installed after the old pipeline is closed
+	newOutput := newMockOutput()
+	err = engine.ReloadConfigFrom(ReloadTriggerFile, &Config{Inputs: newConfig.Inputs, Outputs: newConfig.Outputs}, func(config *Config) (func(*Engine), error) {
+		return func(e *Engine) {
+			_ = e.AddOutputPipeline(&OutputPipeline{Name: "new", Output: newOutput})
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	engine.InputChannel() <- NewLog("info", "after reload")
+	deadline = time.Now().Add(2 * time.Second)
+	for len(newOutput.getLogs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(newOutput.getLogs()) != 1 || len(oldOutput.getLogs()) != 1 {
+		t.Errorf("Expected the log on the new pipeline only, got old=%d new=%d", len(oldOutput.getLogs()), len(newOutput.getLogs()))
+	}
+
+	events := engine.ReloadEvents()
+	if len(events) != 2 || events[0].Result != ReloadResultRejected || events[1].Result != ReloadResultSuccess {
+		t.Errorf("Expected a rejected then a successful reload event, got %+v", events)
+	}
+}
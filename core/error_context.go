@@ -0,0 +1,196 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ErrorContextKey is the metadata key marking a log delivered as error context;
+// its value is the timestamp of the log that triggered the flush
+const ErrorContextKey = "error_context"
+
+const (
+	// DefaultErrorContextTrigger is the level that flushes the context when trigger_level is unset
+	DefaultErrorContextTrigger = "error"
+	// MaxErrorContextBefore bounds the logs kept per source
+	MaxErrorContextBefore = 10000
+	// maxErrorContextSources bounds the sources with a ring; logs from further sources are not kept
+	maxErrorContextSources = 1000
+)
+
+// ErrorContextConfig keeps the most recent logs of each source and, when a log at
+// or above TriggerLevel is dispatched, writes them to Target so the output shows
+// what happened right before the error, even logs its own filters would drop.
+type ErrorContextConfig struct {
+	ContextBefore int    `yaml:"context_before,omitempty"` // Logs kept per source and flushed before a trigger (0 = disabled)
+	TriggerLevel  string `yaml:"trigger_level,omitempty"`  // Lowest level that flushes the context (default: error)
+	Target        string `yaml:"target,omitempty"`         // Output receiving the context logs
+}
+
+// Validate validates the ErrorContextConfig
+func (c ErrorContextConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.ContextBefore, validation.Min(0).Error("must be no less than 0"),
+			validation.Max(MaxErrorContextBefore).Error(fmt.Sprintf("must be no greater than %d", MaxErrorContextBefore))),
+		validation.Field(&c.Target, validation.When(c.ContextBefore > 0, validation.Required.Error("cannot be blank"))),
+	)
+}
+
+// validateAgainst checks that trigger_level is part of the configured level
+// vocabulary and that target names a regular output
+func (c ErrorContextConfig) validateAgainst(levels LevelsConfig, outputs []PluginDefinition) error {
+	if c.ContextBefore <= 0 || c.Target == "" {
+		return nil // Reported by Validate
+	}
+	if vocabulary, err := NewLevelVocabulary(levels); err == nil && !vocabulary.Known(c.triggerLevel()) {
+		return fmt.Errorf("trigger_level %q is not a known level (known: %s)", c.triggerLevel(), strings.Join(vocabulary.Levels(), ", "))
+	}
+	for _, output := range outputs {
+		if output.Name != c.Target {
+			continue
+		}
+		if output.Shadow {
+			return fmt.Errorf("target '%s' cannot be a shadow output", c.Target)
+		}
+		return nil
+	}
+	return fmt.Errorf("target '%s' is not a defined output", c.Target)
+}
+
+// triggerLevel returns the level that flushes the context
+func (c ErrorContextConfig) triggerLevel() string {
+	if c.TriggerLevel == "" {
+		return DefaultErrorContextTrigger
+	}
+	return c.TriggerLevel
+}
+
+// contextEntry is a log kept for error context
+type contextEntry struct {
+	log       *Log
+	delivered bool // The target already received the log through its own pipeline
+}
+
+// contextRing holds the most recent logs of one source, oldest first from next
+type contextRing struct {
+	entries []contextEntry
+	next    int
+}
+
+// errorContext keeps a bounded ring of recent logs per source
+type errorContext struct {
+	config  ErrorContextConfig
+	mu      sync.Mutex
+	rings   map[string]*contextRing
+	flushed int64 // Context logs written to the target
+}
+
+// newErrorContext returns the error context capture, or nil when it is disabled
+func newErrorContext(config ErrorContextConfig) *errorContext {
+	if config.ContextBefore <= 0 {
+		return nil
+	}
+	return &errorContext{config: config, rings: make(map[string]*contextRing)}
+}
+
+// SetErrorContext configures the capture of leading context for error logs
+func (e *Engine) SetErrorContext(config ErrorContextConfig) {
+	e.errorContext = newErrorContext(config)
+}
+
+// isTrigger reports whether a log flushes the context of its source
+func (c *errorContext) isTrigger(logEntry *Log) bool {
+	return Levels().AtLeast(logEntry.Level, c.config.triggerLevel())
+}
+
+// remember adds a log to its source's ring, overwriting the oldest once the ring is full
+func (c *errorContext) remember(logEntry *Log, delivered bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.rings[logEntry.Source]
+	if !ok {
+		if len(c.rings) >= maxErrorContextSources {
+			return
+		}
+		ring = &contextRing{entries: make([]contextEntry, 0, c.config.ContextBefore)}
+		c.rings[logEntry.Source] = ring
+	}
+
+	entry := contextEntry{log: logEntry, delivered: delivered}
+	if len(ring.entries) < c.config.ContextBefore {
+		ring.entries = append(ring.entries, entry)
+		return
+	}
+	ring.entries[ring.next] = entry
+	ring.next = (ring.next + 1) % len(ring.entries)
+}
+
+// take empties the ring of a source and returns the logs the target has not
+// received yet, oldest first
+func (c *errorContext) take(source string) []*Log {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.rings[source]
+	if !ok {
+		return nil
+	}
+
+	var logs []*Log
+	for i := range ring.entries {
+		entry := ring.entries[(ring.next+i)%len(ring.entries)]
+		if !entry.delivered {
+			logs = append(logs, entry.log)
+		}
+	}
+	clear(ring.entries)
+	ring.entries = ring.entries[:0]
+	ring.next = 0
+	return logs
+}
+
+// flushErrorContext writes the context kept for the source of a trigger log to
+// the target output, before the trigger itself is dispatched. The context
+// bypasses the target's filters and routing: it is what they would otherwise drop.
+func (e *Engine) flushErrorContext(trigger *Log) {
+	logs := e.errorContext.take(trigger.Source)
+	if len(logs) == 0 {
+		return
+	}
+	target, err := e.findPipeline(e.errorContext.config.Target)
+	if err != nil || !target.Enabled() {
+		return
+	}
+
+	marker := trigger.Timestamp.Format(time.RFC3339Nano)
+	for _, logEntry := range logs {
+		entry := logEntry.Clone()
+		if entry.Metadata == nil {
+			entry.Metadata = make(map[string]string, 1)
+		}
+		entry.Metadata[ErrorContextKey] = marker
+		if err := target.writeWithTimeout(e.ctx, entry); err != nil {
+			engineLog.Printf("Error writing error context to output '%s': %v", target.Name, err)
+		}
+	}
+
+	e.errorContext.mu.Lock()
+	e.errorContext.flushed += int64(len(logs))
+	e.errorContext.mu.Unlock()
+	engineLog.Printf("Flushed %d context logs from '%s' to output '%s'", len(logs), trigger.Source, target.Name)
+}
+
+// ErrorContextFlushed returns the number of context logs written to the target
+func (e *Engine) ErrorContextFlushed() int64 {
+	if e.errorContext == nil {
+		return 0
+	}
+	e.errorContext.mu.Lock()
+	defer e.errorContext.mu.Unlock()
+	return e.errorContext.flushed
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// minLevelFilter keeps logs at or above a level
+type minLevelFilter struct {
+	level string
+}
+
+func (f *minLevelFilter) Process(log *Log) bool {
+	return Levels().AtLeast(log.Level, f.level)
+}
+
+func TestEngineErrorContext(t *testing.T) {
+	engine := NewEngine()
+	engine.SetErrorContext(ErrorContextConfig{ContextBefore: 3, Target: "debug"})
+	prod := newMockOutput()
+	debug := newMockOutput()
+
+	pipelines := []*OutputPipeline{
+		{Name: "prod", Output: prod},
+		{Name: "debug", Output: debug, Filters: []FilterPlugin{&minLevelFilter{level: "warn"}}},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	send := func(source, level, message string) *Log {
+		logEntry := NewLog(level, message)
+		logEntry.Source = source
+		engine.dispatchLog(logEntry)
+		return logEntry
+	}
+
+	send("api", "debug", "d1")
+	send("api", "info", "i1")
+	send("api", "warn", "w1") // Reaches debug through its own filters
+	send("api", "info", "i2")
+	send("worker", "info", "x1")
+	send("api", "info", "i3")
+	trigger := send("api", "error", "e1")
+	send("api", "error", "e2") // Nothing left to flush
+
+	var messages []string
+	for _, logEntry := range debug.getLogs() {
+		messages = append(messages, logEntry.Message)
+	}
+	// The ring keeps the last 3 api logs; w1 is not sent twice
+	if got := strings.Join(messages, " "); got != "w1 i2 i3 e1 e2" {
+		t.Fatalf("Expected debug to receive w1 i2 i3 e1 e2, got %s", got)
+	}
+
+	marker := trigger.Timestamp.Format(time.RFC3339Nano)
+	for _, logEntry := range debug.getLogs() {
+		_, marked := logEntry.Metadata[ErrorContextKey]
+		if context := logEntry.Message == "i2" || logEntry.Message == "i3"; context != marked {
+			t.Errorf("Expected only context logs to be marked, got %s marked=%v", logEntry.Message, marked)
+		}
+		if marked && logEntry.Metadata[ErrorContextKey] != marker {
+			t.Errorf("Expected the trigger timestamp, got %s", logEntry.Metadata[ErrorContextKey])
+		}
+	}
+	if len(prod.getLogs()) != 8 {
+		t.Errorf("Expected prod to receive every log, got %d", len(prod.getLogs()))
+	}
+
+	// Sources keep separate rings
+	send("worker", "error", "x2")
+	if logs := debug.getLogs(); len(logs) != 7 || logs[5].Message != "x1" || logs[6].Message != "x2" {
+		t.Errorf("Expected the worker context before its error, got %d logs", len(logs))
+	}
+	if got := engine.ErrorContextFlushed(); got != 3 {
+		t.Errorf("Expected 3 flushed context logs, got %d", got)
+	}
+}
+
+func TestErrorContextRingBounded(t *testing.T) {
+	capture := newErrorContext(ErrorContextConfig{ContextBefore: 2, Target: "debug"})
+	for i := range 100 {
+		logEntry := NewLog("info", strings.Repeat("x", i))
+		logEntry.Source = "api"
+		capture.remember(logEntry, false)
+	}
+	if ring := capture.rings["api"]; len(ring.entries) != 2 || cap(ring.entries) != 2 {
+		t.Fatalf("Expected a ring of 2 entries, got %d (cap %d)", len(ring.entries), cap(ring.entries))
+	}
+	if logs := capture.take("api"); len(logs) != 2 || len(logs[0].Message) != 98 || len(logs[1].Message) != 99 {
+		t.Errorf("Expected the 2 most recent logs oldest first, got %d", len(logs))
+	}
+
+	for i := range maxErrorContextSources + 10 {
+		logEntry := NewLog("info", "log")
+		logEntry.Source = strings.Repeat("s", i+1)
+		capture.remember(logEntry, false)
+	}
+	if len(capture.rings) != maxErrorContextSources {
+		t.Errorf("Expected at most %d sources, got %d", maxErrorContextSources, len(capture.rings))
+	}
+
+	if newErrorContext(ErrorContextConfig{}) != nil {
+		t.Error("Expected error context to be disabled without context_before")
+	}
+}
+
+func TestErrorContextConfigValidation(t *testing.T) {
+	outputs := []PluginDefinition{
+		{Type: "console", Name: "prod", Config: map[string]any{"target": "stdout"}},
+		{Type: "file", Name: "debug", Config: map[string]any{"path": "debug.log"}},
+		{Type: "file", Name: "candidate", Config: map[string]any{"path": "shadow.log"}, Shadow: true},
+	}
+
+	tests := []struct {
+		name     string
+		config   ErrorContextConfig
+		expected string
+	}{
+		{"disabled", ErrorContextConfig{}, ""},
+		{"default trigger", ErrorContextConfig{ContextBefore: 50, Target: "debug"}, ""},
+		{"warn trigger", ErrorContextConfig{ContextBefore: 50, TriggerLevel: "WARNING", Target: "debug"}, ""},
+		{"negative context", ErrorContextConfig{ContextBefore: -1}, "no less than 0"},
+		{"too much context", ErrorContextConfig{ContextBefore: MaxErrorContextBefore + 1, Target: "debug"}, "no greater than"},
+		{"missing target", ErrorContextConfig{ContextBefore: 50}, "cannot be blank"},
+		{"undefined target", ErrorContextConfig{ContextBefore: 50, Target: "missing"}, "not a defined output"},
+		{"shadow target", ErrorContextConfig{ContextBefore: 50, Target: "candidate"}, "shadow"},
+		{"unknown trigger", ErrorContextConfig{ContextBefore: 50, TriggerLevel: "fatal", Target: "debug"}, "not a known level"},
+	}
+
+	for _, tt := range tests {
+		config := Config{
+			Inputs:       []PluginDefinition{{Type: "file", Config: map[string]any{"path": "app.log"}}},
+			Outputs:      outputs,
+			ErrorContext: tt.config,
+		}
+		err := config.Validate()
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
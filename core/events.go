@@ -0,0 +1,209 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lifecycle event types published on the lifecycle event stream
+const (
+	EventEngineStarted     = "engine_started"     // The engine started, or restarted after a reload
+	EventEngineStopped     = "engine_stopped"     // The engine stopped
+	EventPluginStarted     = "plugin_started"     // A plugin was created (and, for inputs, started) on its first attempt
+	EventPluginReconnected = "plugin_reconnected" // A plugin was created after failed attempts
+	EventPluginHealthy     = "plugin_healthy"     // A health check passed again after failing
+	EventPluginUnhealthy   = "plugin_unhealthy"   // A plugin failed to connect, start or pass a health check
+	EventPluginStopped     = "plugin_stopped"     // A plugin was stopped or closed
+)
+
+// Plugin kinds carried by lifecycle events
+const (
+	PluginKindInput  = "input"
+	PluginKindOutput = "output"
+)
+
+// DefaultEventBuffer is the number of events a subscriber can fall behind before
+// events are dropped for it
+const DefaultEventBuffer = 64
+
+// LifecycleEvent is a change in the state of the engine or one of its plugins
+type LifecycleEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`                  // One of the Event* constants
+	Plugin     string    `json:"plugin,omitempty"`      // Plugin instance name
+	PluginType string    `json:"plugin_type,omitempty"` // Registered plugin type, e.g. "kafka"
+	Kind       string    `json:"kind,omitempty"`        // PluginKindInput or PluginKindOutput
+	OldState   string    `json:"old_state,omitempty"`   // Health before the event
+	NewState   string    `json:"new_state,omitempty"`   // Health after the event
+	Attempts   int       `json:"attempts,omitempty"`    // Failed attempts before a reconnect
+	Error      string    `json:"error,omitempty"`       // Why the plugin became unhealthy
+}
+
+// EventBus fans lifecycle events out to subscribers. Publishing never blocks:
+// a subscriber whose buffer is full misses the event and has it counted as dropped.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[*EventSubscription]struct{}
+}
+
+// EventSubscription receives lifecycle events from an EventBus
+type EventSubscription struct {
+	C <-chan LifecycleEvent
+
+	ch      chan LifecycleEvent
+	bus     *EventBus
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*EventSubscription]struct{})}
+}
+
+// lifecycleEvents is the process-wide bus, shared by the engine and the resilient
+// plugin wrappers so plugins built before the engine is known still report to it
+var lifecycleEvents = NewEventBus()
+
+// LifecycleEvents returns the process-wide lifecycle event bus
+func LifecycleEvents() *EventBus {
+	return lifecycleEvents
+}
+
+// Subscribe returns a subscription buffering up to buffer events (DefaultEventBuffer if not positive)
+func (b *EventBus) Subscribe(buffer int) *EventSubscription {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	ch := make(chan LifecycleEvent, buffer)
+	sub := &EventSubscription{C: ch, ch: ch, bus: b}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Publish sends an event to every subscriber without waiting for slow ones
+func (b *EventBus) Publish(event LifecycleEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions
+func (b *EventBus) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// Dropped returns the number of events this subscriber missed because its buffer was full
+func (s *EventSubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes C. It is safe to call more than once.
+func (s *EventSubscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscribers, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// handleEvents streams lifecycle events as server-sent events until the client
+// disconnects or done is closed. Each event is sent as "event: <type>" with the
+// JSON encoded event as data; missed events are reported as a "dropped" event
+// with the subscriber's total.
+func (e *Engine) handleEvents(done <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		sub := LifecycleEvents().Subscribe(DefaultEventBuffer)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		var reported int64
+		for {
+			select {
+			case event := <-sub.C:
+				if dropped := sub.Dropped(); dropped > reported {
+					reported = dropped
+					if err := writeSSE(w, "dropped", map[string]int64{"dropped": dropped}); err != nil {
+						return
+					}
+				}
+				if err := writeSSE(w, event.Type, event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// writeSSE writes one server-sent event with a JSON payload
+func writeSSE(w http.ResponseWriter, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		apiLog.Printf("Error encoding event: %v", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// publishInputEvent publishes a lifecycle event for an input the engine starts or
+// stops itself; resilient inputs publish their own events
+func (e *Engine) publishInputEvent(name string, input InputPlugin, eventType string, err error) {
+	if _, ok := input.(*ResilientInputPlugin); ok {
+		return
+	}
+	event := LifecycleEvent{Type: eventType, Plugin: name, PluginType: e.plugins.Load().inputTypes[name], Kind: PluginKindInput}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	LifecycleEvents().Publish(event)
+}
+
+// publishOutputEvent publishes a lifecycle event for an output the engine closes
+// itself; resilient outputs publish their own events
+func (e *Engine) publishOutputEvent(pipeline *OutputPipeline, eventType string) {
+	if _, ok := pipeline.Output.(*ResilientOutputPlugin); ok {
+		return
+	}
+	LifecycleEvents().Publish(LifecycleEvent{Type: eventType, Plugin: pipeline.Name, PluginType: pipeline.Type, Kind: PluginKindOutput})
+}
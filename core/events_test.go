@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventBusDropsForSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe(2)
+	fast := bus.Subscribe(10)
+
+	for range 5 {
+		bus.Publish(LifecycleEvent{Type: EventPluginHealthy, Plugin: "p"})
+	}
+
+	if len(slow.C) != 2 || slow.Dropped() != 3 {
+		t.Errorf("Expected 2 buffered and 3 dropped events, got %d and %d", len(slow.C), slow.Dropped())
+	}
+	if len(fast.C) != 5 || fast.Dropped() != 0 {
+		t.Errorf("Expected a slow subscriber not to affect others, got %d and %d dropped", len(fast.C), fast.Dropped())
+	}
+	if event := <-fast.C; event.Time.IsZero() {
+		t.Error("Expected Publish to set the event time")
+	}
+
+	slow.Close()
+	slow.Close()
+	if bus.Subscribers() != 1 {
+		t.Errorf("Expected 1 subscriber after Close, got %d", bus.Subscribers())
+	}
+	bus.Publish(LifecycleEvent{Type: EventPluginStopped}) // Must not send on the closed channel
+}
+
+// nextEvent returns the next event of the named plugin
+func nextEvent(t *testing.T, sub *EventSubscription, plugin string) LifecycleEvent {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case event := <-sub.C:
+			if event.Plugin == plugin {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for an event of %s", plugin)
+		}
+	}
+}
+
+func TestResilientPluginLifecycleEvents(t *testing.T) {
+	sub := LifecycleEvents().Subscribe(100)
+	defer sub.Close()
+
+	mock := &mockPlugin{healthCheckOK: true}
+	var mu sync.Mutex
+	attempts := 0
+	factory := func(map[string]any) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts++; attempts <= 2 {
+			return nil, errors.New("connection refused")
+		}
+		return mock, nil
+	}
+
+	output := NewResilientOutputPlugin("events-output", "mock", factory, nil, ResilientPluginConfig{
+		RetryInterval: 10 * time.Millisecond,
+		HealthCheck:   20 * time.Millisecond,
+	})
+
+	check := func(expected LifecycleEvent) {
+		t.Helper()
+		event := nextEvent(t, sub, "events-output")
+		if event.Type != expected.Type || event.OldState != expected.OldState || event.NewState != expected.NewState ||
+			event.Attempts != expected.Attempts || event.Error != expected.Error {
+			t.Errorf("Expected %+v, got %+v", expected, event)
+		}
+		if event.PluginType != "mock" || event.Kind != PluginKindOutput {
+			t.Errorf("Expected the plugin type and kind, got %+v", event)
+		}
+	}
+
+	// Only the first failed attempt is a transition
+	check(LifecycleEvent{Type: EventPluginUnhealthy, OldState: "unknown", NewState: "unhealthy", Error: "connection refused"})
+	check(LifecycleEvent{Type: EventPluginReconnected, OldState: "unhealthy", NewState: "healthy", Attempts: 2})
+
+	mock.mu.Lock()
+	mock.healthCheckOK = false
+	mock.mu.Unlock()
+	check(LifecycleEvent{Type: EventPluginUnhealthy, OldState: "healthy", NewState: "unhealthy", Error: "health check failed"})
+
+	mock.mu.Lock()
+	mock.healthCheckOK = true
+	mock.mu.Unlock()
+	check(LifecycleEvent{Type: EventPluginHealthy, OldState: "unhealthy", NewState: "healthy"})
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	check(LifecycleEvent{Type: EventPluginStopped, OldState: "healthy", NewState: "stopped"})
+}
+
+func TestEngineLifecycleEvents(t *testing.T) {
+	sub := LifecycleEvents().Subscribe(100)
+	defer sub.Close()
+
+	engine := NewEngine()
+	engine.AddInputWithType("events-input", "mock", newMockInput(nil))
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "events-console", Type: "console", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine.Start()
+	engine.Stop()
+
+	// Events are published synchronously, so all of them are buffered by now
+	var got []string
+	for len(sub.C) > 0 {
+		event := <-sub.C
+		switch event.Plugin {
+		case "events-input":
+			if event.PluginType != "mock" || event.Kind != PluginKindInput {
+				t.Errorf("Expected the input's type and kind, got %+v", event)
+			}
+		case "events-console":
+			if event.PluginType != "console" || event.Kind != PluginKindOutput {
+				t.Errorf("Expected the output's type and kind, got %+v", event)
+			}
+		case "":
+		default:
+			continue
+		}
+		got = append(got, event.Plugin+":"+event.Type)
+	}
+
+	expected := []string{
+		"events-input:plugin_started",
+		":engine_started",
+		"events-input:plugin_stopped",
+		"events-console:plugin_stopped",
+		":engine_stopped",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestHandleEventsStream(t *testing.T) {
+	engine := NewEngine()
+	done := make(chan struct{})
+	server := httptest.NewServer(engine.handleEvents(done))
+	defer server.Close()
+
+	subscribers := LifecycleEvents().Subscribers()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	// The handler subscribes before it sends the headers
+	if LifecycleEvents().Subscribers() != subscribers+1 {
+		t.Fatalf("Expected the stream to subscribe")
+	}
+	LifecycleEvents().Publish(LifecycleEvent{Type: EventPluginUnhealthy, Plugin: "sse-output", OldState: "healthy", NewState: "unhealthy"})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if lines[0] != "event: plugin_unhealthy" {
+		t.Errorf("Expected the event type line, got %q", lines[0])
+	}
+	var event LifecycleEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &event); err != nil {
+		t.Fatalf("Failed to decode event data %q: %v", lines[1], err)
+	}
+	if event.Plugin != "sse-output" || event.OldState != "healthy" || event.NewState != "unhealthy" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// Closing done (API shutdown) ends the stream
+	close(done)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for LifecycleEvents().Subscribers() != subscribers {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Expected the stream to unsubscribe after shutdown")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	resp, err = http.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to post: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", resp.StatusCode)
+	}
+}
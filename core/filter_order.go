@@ -0,0 +1,222 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Relative filter costs reported through CostedFilter
+const (
+	FilterCostCheap     = 1   // Map lookups and simple comparisons (e.g. level)
+	FilterCostModerate  = 10  // String scans and light parsing
+	FilterCostExpensive = 100 // Regular expressions and full parsers
+)
+
+// CostedFilter is an optional interface for filters that can be safely reordered.
+// Implementations must be pure predicates: no changes to the log and no state that
+// depends on which logs reached them. Filters without it keep their position.
+type CostedFilter interface {
+	Cost() int // Relative evaluation cost, lower runs first
+}
+
+// MutatingFilter is an optional interface for filters that modify logs (e.g. parsers
+// that add metadata or tags). A mutating filter is never reordered, and predicates
+// are never moved across it, so predicates that depend on its output still see it.
+// Filters that return false here (or implement CostedFilter) are trusted not to
+// modify logs; any other filter is treated as mutating.
+type MutatingFilter interface {
+	Mutates() bool
+}
+
+// mayMutate reports whether a filter may modify the logs it processes, in which
+// case the pipeline gives it a private copy instead of the log shared by all pipelines
+func mayMutate(filter FilterPlugin) bool {
+	if mutating, ok := filter.(MutatingFilter); ok {
+		return mutating.Mutates()
+	}
+	_, costed := filter.(CostedFilter)
+	return !costed
+}
+
+// reorderable reports whether a filter may be moved by ReorderFilters
+func reorderable(filter FilterPlugin) (int, bool) {
+	if mutating, ok := filter.(MutatingFilter); ok && mutating.Mutates() {
+		return 0, false
+	}
+	costed, ok := filter.(CostedFilter)
+	if !ok {
+		return 0, false
+	}
+	return costed.Cost(), true
+}
+
+// ReorderFilters returns the filters sorted so cheap predicates run first.
+// Mutating filters and filters with unknown cost act as barriers: each run of
+// reorderable filters between two barriers is sorted by cost (stable), and no
+// filter ever crosses a barrier.
+func ReorderFilters(filters []FilterPlugin) []FilterPlugin {
+	reordered := make([]FilterPlugin, len(filters))
+	for i, index := range reorderIndexes(filters) {
+		reordered[i] = filters[index]
+	}
+	return reordered
+}
+
+// reorderIndexes returns the indexes of the filters in the order ReorderFilters runs them
+func reorderIndexes(filters []FilterPlugin) []int {
+	order := make([]int, len(filters))
+	for i := range order {
+		order[i] = i
+	}
+	cost := func(index int) (int, bool) { return reorderable(filters[index]) }
+
+	start := 0
+	for start < len(order) {
+		if _, ok := cost(order[start]); !ok {
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(order) {
+			if _, ok := cost(order[end]); !ok {
+				break
+			}
+			end++
+		}
+
+		segment := order[start:end]
+		sort.SliceStable(segment, func(i, j int) bool {
+			costI, _ := cost(segment[i])
+			costJ, _ := cost(segment[j])
+			return costI < costJ
+		})
+		start = end
+	}
+
+	return order
+}
+
+// filterStats tracks how often a pipeline filter ran, how long it took and how
+// many logs it dropped, and holds its runtime toggle
+type filterStats struct {
+	name     string
+	number   int         // Position in the configured filter list starting at 1, unaffected by auto_reorder
+	disabled atomic.Bool // A disabled filter passes every log through
+	calls    atomic.Int64
+	dropped  atomic.Int64
+	nanos    atomic.Int64
+}
+
+// FilterStat is a snapshot of a pipeline filter's statistics
+type FilterStat struct {
+	Position         int     `json:"position"` // Execution order, starting at 1
+	Number           int     `json:"number"`   // Configured order, starting at 1, as used by the toggle API
+	Filter           string  `json:"filter"`
+	Enabled          bool    `json:"enabled"`
+	Calls            int64   `json:"calls"`
+	Dropped          int64   `json:"dropped"`
+	AvgLatencyMicros float64 `json:"avg_latency_us"`
+}
+
+// filterName returns a readable name for a filter plugin (e.g. "level.LevelFilter")
+func filterName(filter FilterPlugin) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", filter), "*")
+}
+
+// prepareFilters applies auto_reorder and sets up per-filter statistics and toggles
+func (p *OutputPipeline) prepareFilters() {
+	order := make([]int, len(p.Filters))
+	for i := range order {
+		order[i] = i
+	}
+	if p.AutoReorder {
+		order = reorderIndexes(p.Filters)
+		p.Filters = ReorderFilters(p.Filters)
+	}
+
+	p.filterStats = make([]*filterStats, len(p.Filters))
+	names := make([]string, len(p.Filters))
+	for i, filter := range p.Filters {
+		names[i] = filterName(filter)
+		p.filterStats[i] = &filterStats{name: names[i], number: order[i] + 1}
+		if order[i] < len(p.FilterEnabled) && !p.FilterEnabled[order[i]] {
+			p.filterStats[i].disabled.Store(true)
+		}
+	}
+
+	if p.AutoReorder && len(p.Filters) > 1 {
+		engineLog.Printf("Output '%s' filter order after auto_reorder: %v", p.Name, names)
+	}
+}
+
+// applyFilters runs the enabled pipeline filters in order and records their statistics.
+// The log is shared by every pipeline, so it is copied before the first filter
+// that may mutate it; the returned log is the one the pipeline should deliver.
+// It returns false and the index of the dropping filter as soon as a filter drops the log.
+func (p *OutputPipeline) applyFilters(logEntry *Log) (*Log, bool, int) {
+	tracked := len(p.filterStats) == len(p.Filters)
+	entry := logEntry
+
+	for i, filter := range p.Filters {
+		if tracked && p.filterStats[i].disabled.Load() {
+			continue
+		}
+		if p.preserve.skips(filter, entry) {
+			continue
+		}
+		if entry == logEntry && mayMutate(filter) {
+			entry = logEntry.Clone()
+		}
+
+		start := time.Now()
+		result := filter.Process(entry)
+
+		if tracked {
+			stats := p.filterStats[i]
+			stats.calls.Add(1)
+			stats.nanos.Add(int64(time.Since(start)))
+			if !result {
+				stats.dropped.Add(1)
+			}
+		}
+
+		if !result {
+			return entry, false, i
+		}
+	}
+	return entry, true, -1
+}
+
+// FilterStats returns a snapshot of per-filter statistics in execution order
+func (p *OutputPipeline) FilterStats() []FilterStat {
+	stats := make([]FilterStat, 0, len(p.filterStats))
+	for i, s := range p.filterStats {
+		calls := s.calls.Load()
+		stat := FilterStat{
+			Position: i + 1,
+			Number:   s.number,
+			Filter:   s.name,
+			Enabled:  !s.disabled.Load(),
+			Calls:    calls,
+			Dropped:  s.dropped.Load(),
+		}
+		if calls > 0 {
+			stat.AvgLatencyMicros = float64(s.nanos.Load()) / float64(calls) / float64(time.Microsecond)
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// resetFilterStats zeroes the per-filter statistics
+func (p *OutputPipeline) resetFilterStats() {
+	for _, s := range p.filterStats {
+		s.calls.Store(0)
+		s.dropped.Store(0)
+		s.nanos.Store(0)
+	}
+}
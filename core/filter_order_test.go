@@ -0,0 +1,194 @@
+package core
+
+import (
+	"testing"
+)
+
+// costFilter is a pure predicate with a fixed cost
+type costFilter struct {
+	name string
+	cost int
+	keep bool
+}
+
+func (f *costFilter) Process(log *Log) bool { return f.keep }
+func (f *costFilter) Cost() int             { return f.cost }
+
+// mutatingCostFilter reports a cost but also modifies logs
+type mutatingCostFilter struct {
+	costFilter
+}
+
+func (f *mutatingCostFilter) Mutates() bool { return true }
+
+// plainFilter has no cost information
+type plainFilter struct {
+	name string
+}
+
+func (f *plainFilter) Process(log *Log) bool { return true }
+
+func filterNames(filters []FilterPlugin) []string {
+	names := make([]string, len(filters))
+	for i, filter := range filters {
+		switch f := filter.(type) {
+		case *costFilter:
+			names[i] = f.name
+		case *mutatingCostFilter:
+			names[i] = f.name
+		case *plainFilter:
+			names[i] = f.name
+		}
+	}
+	return names
+}
+
+func TestReorderFilters(t *testing.T) {
+	regex := &costFilter{name: "regex", cost: FilterCostExpensive, keep: true}
+	level := &costFilter{name: "level", cost: FilterCostCheap, keep: true}
+	scan := &costFilter{name: "scan", cost: FilterCostModerate, keep: true}
+	parser := &mutatingCostFilter{costFilter{name: "parser", cost: FilterCostExpensive, keep: true}}
+	unknown := &plainFilter{name: "unknown"}
+
+	tests := []struct {
+		name     string
+		filters  []FilterPlugin
+		expected []string
+	}{
+		{
+			name:     "cheap predicates first",
+			filters:  []FilterPlugin{regex, scan, level},
+			expected: []string{"level", "scan", "regex"},
+		},
+		{
+			name:     "mutating filter is a barrier",
+			filters:  []FilterPlugin{regex, level, parser, regex, level},
+			expected: []string{"level", "regex", "parser", "level", "regex"},
+		},
+		{
+			name:     "predicates never move before a mutating filter",
+			filters:  []FilterPlugin{parser, regex, level},
+			expected: []string{"parser", "level", "regex"},
+		},
+		{
+			name:     "filters without cost keep their position",
+			filters:  []FilterPlugin{regex, unknown, level},
+			expected: []string{"regex", "unknown", "level"},
+		},
+		{
+			name:     "empty",
+			filters:  nil,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterNames(ReorderFilters(tt.filters))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestReorderFiltersDoesNotModifyInput(t *testing.T) {
+	regex := &costFilter{name: "regex", cost: FilterCostExpensive, keep: true}
+	level := &costFilter{name: "level", cost: FilterCostCheap, keep: true}
+	filters := []FilterPlugin{regex, level}
+
+	ReorderFilters(filters)
+
+	if filters[0] != regex {
+		t.Error("Expected input slice to be left untouched")
+	}
+}
+
+func TestOutputPipelineFilterStats(t *testing.T) {
+	engine := NewEngine()
+	regex := &costFilter{name: "regex", cost: FilterCostExpensive, keep: true}
+	level := &costFilter{name: "level", cost: FilterCostCheap, keep: false}
+
+	pipeline := &OutputPipeline{
+		Name:        "reordered",
+		Output:      newMockOutput(),
+		Filters:     []FilterPlugin{regex, level},
+		AutoReorder: true,
+	}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, passed, blockedBy := pipeline.applyFilters(NewLog("info", "test")); passed || blockedBy != 0 {
+			t.Fatalf("Expected log to be dropped by the first (level) filter, got passed=%t blockedBy=%d", passed, blockedBy)
+		}
+	}
+
+	stats := pipeline.FilterStats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 filter stats, got %d", len(stats))
+	}
+	if stats[0].Calls != 3 || stats[0].Dropped != 3 {
+		t.Errorf("Expected 3 calls and 3 drops for first filter, got %d and %d", stats[0].Calls, stats[0].Dropped)
+	}
+	if stats[1].Calls != 0 {
+		t.Errorf("Expected expensive filter to be skipped, got %d calls", stats[1].Calls)
+	}
+	if stats[0].Filter != "core.costFilter" {
+		t.Errorf("Expected filter name 'core.costFilter', got '%s'", stats[0].Filter)
+	}
+
+	engine.ResetMetrics()
+	if stats := pipeline.FilterStats(); stats[0].Calls != 0 {
+		t.Errorf("Expected filter stats to be reset, got %d calls", stats[0].Calls)
+	}
+}
+
+// metadataFilter sets a metadata key and declares that it mutates logs
+type metadataFilter struct {
+	key, value string
+}
+
+func (f *metadataFilter) Process(log *Log) bool {
+	log.Metadata[f.key] = f.value
+	return true
+}
+func (f *metadataFilter) Mutates() bool { return true }
+
+func TestOutputPipelineApplyFiltersCopyOnMutate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []FilterPlugin
+		copied  bool
+	}{
+		{"no filters", nil, false},
+		{"costed predicates", []FilterPlugin{&costFilter{cost: 1, keep: true}}, false},
+		{"mutating filter", []FilterPlugin{&costFilter{cost: 1, keep: true}, &metadataFilter{key: "k", value: "v"}}, true},
+		{"unknown filter", []FilterPlugin{&plainFilter{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := &OutputPipeline{Name: "test", Filters: tt.filters}
+			shared := NewLog("info", "test")
+
+			entry, passed, _ := pipeline.applyFilters(shared)
+			if !passed {
+				t.Fatalf("Expected the log to pass")
+			}
+			if copied := entry != shared; copied != tt.copied {
+				t.Errorf("Expected copied=%t, got %t", tt.copied, copied)
+			}
+			if len(shared.Metadata) != 0 {
+				t.Errorf("Expected the shared log to be untouched, got %v", shared.Metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errFilterNotFound is returned when a pipeline has no filter with the given number
+var errFilterNotFound = errors.New("filter not found")
+
+// SetFilterEnabled enables or disables filter number n, its position in the
+// pipeline's configured filter list starting at 1 (as in "filter #n" log lines).
+// Numbers do not change with auto_reorder. A disabled filter passes every log through.
+func (p *OutputPipeline) SetFilterEnabled(n int, enabled bool) error {
+	for _, stats := range p.filterStats {
+		if stats.number == n {
+			stats.disabled.Store(!enabled)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: output '%s' has no filter #%d", errFilterNotFound, p.Name, n)
+}
+
+// filterNumber returns the configured number of the filter at execution position i
+func (p *OutputPipeline) filterNumber(i int) int {
+	if i < len(p.filterStats) {
+		return p.filterStats[i].number
+	}
+	return i + 1
+}
+
+// SetFilterEnabled enables or disables a filter of the named output pipeline at runtime
+func (e *Engine) SetFilterEnabled(name string, n int, enabled bool) error {
+	pipeline, err := e.findPipeline(name)
+	if err != nil {
+		return err
+	}
+	if err := pipeline.SetFilterEnabled(n, enabled); err != nil {
+		return err
+	}
+	engineLog.Printf("Output pipeline '%s' filter #%d enabled=%t", name, n, enabled)
+	return nil
+}
+
+// handleFilterToggle enables or disables a pipeline filter by number via
+// POST /pipelines/<name>/filters/<n>/enable or .../disable. path is the
+// request path without the /pipelines/ prefix.
+func (e *Engine) handleFilterToggle(w http.ResponseWriter, r *http.Request, path string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	marker := strings.LastIndex(path, "/filters/")
+	var rest []string
+	if marker > 0 {
+		rest = strings.Split(path[marker+len("/filters/"):], "/")
+	}
+	if len(rest) != 2 {
+		http.Error(w, "Expected /pipelines/<name>/filters/<n>/enable or /pipelines/<name>/filters/<n>/disable", http.StatusNotFound)
+		return
+	}
+	name := path[:marker]
+
+	n, err := strconv.Atoi(rest[0])
+	if err != nil || n < 1 {
+		http.Error(w, fmt.Sprintf("Invalid filter number: %s", rest[0]), http.StatusBadRequest)
+		return
+	}
+
+	var enabled bool
+	switch rest[1] {
+	case "enable":
+		enabled = true
+	case "disable":
+		enabled = false
+	default:
+		http.Error(w, fmt.Sprintf("Unknown filter action: %s", rest[1]), http.StatusNotFound)
+		return
+	}
+
+	if err := e.SetFilterEnabled(name, n, enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"name":    name,
+		"filter":  n,
+		"enabled": enabled,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding filter response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPipelineFilterToggle(t *testing.T) {
+	engine := NewEngine()
+	output := newMockOutput()
+
+	// auto_reorder runs "level" first, but filters keep their configured numbers
+	regex := &costFilter{name: "regex", cost: FilterCostExpensive, keep: false}
+	level := &costFilter{name: "level", cost: FilterCostCheap, keep: true}
+	pipeline := &OutputPipeline{
+		Name:          "app",
+		Output:        output,
+		Filters:       []FilterPlugin{regex, level},
+		FilterEnabled: []bool{false, true},
+		AutoReorder:   true,
+	}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	stats := pipeline.FilterStats()
+	if stats[0].Number != 2 || !stats[0].Enabled || stats[1].Number != 1 || stats[1].Enabled {
+		t.Fatalf("Expected filter #2 enabled first and filter #1 disabled, got %+v", stats)
+	}
+
+	// The dropping filter starts disabled
+	engine.dispatchLog(NewLog("info", "first"))
+	if got := len(output.getLogs()); got != 1 {
+		t.Fatalf("Expected the disabled filter to pass the log, got %d logs", got)
+	}
+
+	w := httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("POST", "/pipelines/app/filters/1/enable", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	engine.dispatchLog(NewLog("info", "second"))
+	if got := len(output.getLogs()); got != 1 {
+		t.Errorf("Expected the enabled filter to drop the log, got %d logs", got)
+	}
+	if stats := pipeline.FilterStats(); !stats[1].Enabled || stats[1].Dropped != 1 {
+		t.Errorf("Expected filter #1 enabled with 1 drop, got %+v", stats[1])
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("POST", "/pipelines/app/filters/1/disable", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	engine.dispatchLog(NewLog("info", "third"))
+	if got := len(output.getLogs()); got != 2 {
+		t.Errorf("Expected the disabled filter to pass the log, got %d logs", got)
+	}
+}
+
+func TestPipelineFilterToggleErrors(t *testing.T) {
+	engine := NewEngine()
+	pipeline := &OutputPipeline{Name: "app", Output: newMockOutput(), Filters: []FilterPlugin{&plainFilter{}}}
+	if err := engine.AddOutputPipeline(pipeline); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "unknown pipeline", method: "POST", path: "/pipelines/missing/filters/1/disable", status: http.StatusNotFound},
+		{name: "unknown filter", method: "POST", path: "/pipelines/app/filters/2/disable", status: http.StatusNotFound},
+		{name: "zero is not a filter number", method: "POST", path: "/pipelines/app/filters/0/disable", status: http.StatusBadRequest},
+		{name: "invalid number", method: "POST", path: "/pipelines/app/filters/first/disable", status: http.StatusBadRequest},
+		{name: "unknown action", method: "POST", path: "/pipelines/app/filters/1/restart", status: http.StatusNotFound},
+		{name: "missing action", method: "POST", path: "/pipelines/app/filters/1", status: http.StatusNotFound},
+		{name: "missing pipeline", method: "POST", path: "/pipelines//filters/1/disable", status: http.StatusNotFound},
+		{name: "wrong method", method: "GET", path: "/pipelines/app/filters/1/disable", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.handlePipelineToggle(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Offline inspection of the WAL and DLQ
+//
+// These functions only read: they never write the recovery checkpoint, rotate,
+// prune or delete files, so they are safe to run against directories that a
+// running engine owns. The file the engine is writing may end with a partial
+// line; it is skipped like any other malformed line.
+
+// WALFileInfo describes a WAL file
+type WALFileInfo struct {
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	Entries   int    // Readable entries
+	Malformed int    // Lines that could not be decoded
+	FirstSeq  uint64 // Sequence of the first entry (0 when the file has none)
+	LastSeq   uint64 // Sequence of the last entry
+	Pending   int    // Entries after the recovery checkpoint, replayed by the next start
+}
+
+// ListWALFiles reads every WAL file in dir, oldest first, and returns them with
+// the sequence stored in the recovery checkpoint
+func ListWALFiles(dir string) ([]WALFileInfo, uint64, error) {
+	files, err := scanWALFiles(dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list WAL files: %w", err)
+	}
+	checkpoint := readCheckpoint(dir)
+
+	infos := make([]WALFileInfo, 0, len(files))
+	for _, file := range files {
+		stat, err := os.Stat(file.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // Removed by the cleanup of a running engine
+			}
+			return nil, 0, err
+		}
+		info := WALFileInfo{Path: file.path, Size: stat.Size(), ModTime: stat.ModTime()}
+		info.Malformed, err = ReadWALFile(file.path, func(entry WALEntry) error {
+			if info.Entries == 0 {
+				info.FirstSeq = entry.Sequence
+			}
+			info.Entries++
+			info.LastSeq = entry.Sequence
+			if entry.Sequence == 0 || entry.Sequence > checkpoint {
+				info.Pending++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, checkpoint, nil
+}
+
+// ReadWALFile calls fn with each entry of a WAL file in order and returns the
+// number of lines that could not be decoded. An error from fn stops the read.
+func ReadWALFile(path string, fn func(WALEntry) error) (int, error) {
+	reader, err := openLogFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open WAL file: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	malformed := 0
+	lines := bufio.NewReader(reader)
+	for {
+		line, readErr := lines.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var entry WALEntry
+			if err := json.Unmarshal(line, &entry); err != nil || entry.Log == nil {
+				malformed++
+			} else if err := fn(entry); err != nil {
+				return malformed, err
+			}
+		}
+		if readErr == io.EOF {
+			return malformed, nil
+		}
+		if readErr != nil {
+			return malformed, fmt.Errorf("error reading WAL file %s: %w", path, readErr)
+		}
+	}
+}
+
+// DLQOutputs returns the names of the outputs with DLQ files in dir, sorted
+func DLQOutputs(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*-dlq*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		name := strings.TrimSuffix(filepath.Base(match), ".jsonl")
+		if output, ok := strings.CutSuffix(name, "-dlq"); ok {
+			seen[output] = true
+			continue
+		}
+		// Rotated segment: {output}-dlq-{unix nanos}
+		i := strings.LastIndex(name, "-dlq-")
+		if i <= 0 || strings.Trim(name[i+len("-dlq-"):], "0123456789") != "" {
+			continue
+		}
+		seen[name[:i]] = true
+	}
+
+	outputs := make([]string, 0, len(seen))
+	for output := range seen {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+	return outputs, nil
+}
+
+// gzipReader closes both the gzip stream and the file under it
+type gzipReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r gzipReader) Close() error {
+	_ = r.Reader.Close()
+	return r.file.Close()
+}
+
+// openLogFile opens a WAL or DLQ file read-only. Gzip-compressed files are
+// recognized by their header and decompressed transparently.
+func openLogFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path) // #nosec G304 - WAL and DLQ files listed from the configured directories
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 2)
+	n, _ := io.ReadFull(file, header)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if n < 2 || header[0] != 0x1f || header[1] != 0x8b {
+		return file, nil
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read compressed file %s: %w", path, err)
+	}
+	return gzipReader{Reader: reader, file: file}, nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListWALFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writeFile("wal-2.log", `{"seq":3,"ts":"2026-10-16T10:00:02Z","log":{"level":"error","message":"c"}}
+{"seq":4,"ts":"2026-10-16T10:00:03Z","log":{"level":"info","mess`)
+	writeFile("wal-1.log", `{"seq":1,"ts":"2026-10-16T10:00:00Z","log":{"level":"info","message":"a"}}
+{"seq":2,"ts":"2026-10-16T10:00:01Z","log":{"level":"warn","message":"b"}}
+`)
+	if err := writeCheckpoint(dir, 2); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+	before, _ := os.ReadFile(filepath.Join(dir, checkpointFile))
+
+	files, checkpoint, err := ListWALFiles(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if checkpoint != 2 || len(files) != 2 {
+		t.Fatalf("Expected 2 files and checkpoint 2, got %d files and checkpoint %d", len(files), checkpoint)
+	}
+	first, second := files[0], files[1]
+	if filepath.Base(first.Path) != "wal-1.log" || first.Entries != 2 || first.Pending != 0 || first.FirstSeq != 1 || first.LastSeq != 2 {
+		t.Errorf("Unexpected first file: %+v", first)
+	}
+	// The partial line of a file being written is counted as malformed
+	if second.Entries != 1 || second.Pending != 1 || second.Malformed != 1 || second.LastSeq != 3 {
+		t.Errorf("Unexpected second file: %+v", second)
+	}
+
+	if after, _ := os.ReadFile(filepath.Join(dir, checkpointFile)); string(after) != string(before) {
+		t.Error("Expected inspecting the WAL to leave the checkpoint untouched")
+	}
+}
+
+func TestReadWALFile_Compressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal-1.log")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	writer := gzip.NewWriter(file)
+	_, _ = writer.Write([]byte(`{"seq":7,"ts":"2026-10-16T10:00:00Z","log":{"level":"error","message":"zipped"}}` + "\n"))
+	_ = writer.Close()
+	_ = file.Close()
+
+	var messages []string
+	malformed, err := ReadWALFile(path, func(entry WALEntry) error {
+		messages = append(messages, entry.Log.Message)
+		return nil
+	})
+	if err != nil || malformed != 0 {
+		t.Fatalf("Unexpected result: %d malformed, %v", malformed, err)
+	}
+	if !reflect.DeepEqual(messages, []string{"zipped"}) {
+		t.Errorf("Expected the compressed entry, got %v", messages)
+	}
+}
+
+func TestDLQOutputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app-dlq.jsonl", "app-dlq-1700000000000000000.jsonl", "es-dlq-1700000000000000000.jsonl",
+		"app-dlq-dlq.jsonl", "notes-dlq-old.jsonl", "buffer-1.jsonl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	outputs, err := DLQOutputs(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"app", "app-dlq", "es"}; !reflect.DeepEqual(outputs, expected) {
+		t.Errorf("Expected outputs %v, got %v", expected, outputs)
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultFlushMinInterval is the least time between two flushes triggered by flush_on_level
+const DefaultFlushMinInterval = time.Second
+
+// LevelFlush lets batching outputs send their batch as soon as an important log
+// is added, instead of waiting for the batch to fill or for the flush interval.
+// Outputs embed it inline, so the options sit next to the output's own settings.
+type LevelFlush struct {
+	FlushOnLevel     string        `yaml:"flush_on_level,omitempty"`     // Flush when a log at or above this level is added (default: disabled)
+	FlushMinInterval time.Duration `yaml:"flush_min_interval,omitempty"` // Least time between level-triggered flushes (default: 1s)
+}
+
+// Validate validates the level flush settings and applies defaults
+func (f *LevelFlush) Validate() error {
+	if f.FlushMinInterval < 0 {
+		return fmt.Errorf("flush_min_interval must be non-negative")
+	}
+	if f.FlushOnLevel == "" {
+		return nil
+	}
+	if !Levels().Known(f.FlushOnLevel) {
+		return fmt.Errorf("unknown flush_on_level %q", f.FlushOnLevel)
+	}
+	f.FlushOnLevel = Levels().Normalize(f.FlushOnLevel)
+	if f.FlushMinInterval == 0 {
+		f.FlushMinInterval = DefaultFlushMinInterval
+	}
+	return nil
+}
+
+// LevelFlusher decides when a batch holding an important log is sent early.
+// At most one level-triggered flush happens per flush_min_interval, so an
+// error storm does not turn every log into its own request: an important log
+// added sooner is sent by a single deferred flush once the interval has passed,
+// unless the batch is sent before for another reason. It is safe for concurrent use.
+type LevelFlusher struct {
+	config LevelFlush
+	flush  func() // Deferred flush; must take the output's own lock
+	now    func() time.Time
+
+	mu      sync.Mutex
+	last    time.Time   // Last level-triggered flush
+	timer   *time.Timer // Deferred flush, nil when none is scheduled
+	stopped bool
+}
+
+// NewLevelFlusher creates a level flusher that calls flush for deferred flushes.
+// It returns nil when flush_on_level is not set; a nil flusher never triggers.
+func NewLevelFlusher(config LevelFlush, flush func()) *LevelFlusher {
+	if config.FlushOnLevel == "" {
+		return nil
+	}
+	return &LevelFlusher{config: config, flush: flush, now: time.Now}
+}
+
+// Added is called with each log added to the batch and reports whether the
+// caller should send the batch now. An important log within flush_min_interval
+// of the previous early flush schedules a deferred flush instead.
+func (f *LevelFlusher) Added(logEntry *Log) bool {
+	if f == nil || !Levels().AtLeast(logEntry.Level, f.config.FlushOnLevel) {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped || f.timer != nil {
+		return false
+	}
+	now := f.now()
+	if wait := f.config.FlushMinInterval - now.Sub(f.last); wait > 0 {
+		f.timer = time.AfterFunc(wait, f.deferredFlush)
+		return false
+	}
+	f.last = now
+	return true
+}
+
+// deferredFlush sends the batch once flush_min_interval has passed
+func (f *LevelFlusher) deferredFlush() {
+	f.mu.Lock()
+	f.timer = nil
+	if f.stopped {
+		f.mu.Unlock()
+		return
+	}
+	f.last = f.now()
+	f.mu.Unlock()
+
+	f.flush()
+}
+
+// Stop cancels a deferred flush; the output sends what is left when it closes
+func (f *LevelFlusher) Stop() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLevelFlush_Validate(t *testing.T) {
+	config := LevelFlush{FlushOnLevel: "ERR"}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.FlushOnLevel != "error" || config.FlushMinInterval != DefaultFlushMinInterval {
+		t.Errorf("Expected the normalized level and the default interval, got %+v", config)
+	}
+
+	for name, invalid := range map[string]LevelFlush{
+		"unknown level":     {FlushOnLevel: "loud"},
+		"negative interval": {FlushOnLevel: "error", FlushMinInterval: -time.Second},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	if NewLevelFlusher(LevelFlush{}, nil).Added(NewLog("error", "x")) {
+		t.Error("Expected a disabled level flusher never to trigger")
+	}
+}
+
+func TestLevelFlusher_MinInterval(t *testing.T) {
+	var deferred atomic.Int32
+	flusher := NewLevelFlusher(LevelFlush{FlushOnLevel: "error", FlushMinInterval: 200 * time.Millisecond}, func() {
+		deferred.Add(1)
+	})
+	defer flusher.Stop()
+
+	if flusher.Added(NewLog("warn", "routine")) {
+		t.Error("Expected a log below flush_on_level not to trigger")
+	}
+	if !flusher.Added(NewLog("error", "first")) {
+		t.Fatal("Expected the first error to trigger a flush")
+	}
+
+	// An error storm within the interval is sent by a single deferred flush
+	for range 100 {
+		if flusher.Added(NewLog("error", "storm")) {
+			t.Fatal("Expected errors within flush_min_interval not to trigger")
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for deferred.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The next error is deferred again, until the output stops
+	if flusher.Added(NewLog("error", "deferred")) {
+		t.Error("Expected an error right after the deferred flush not to trigger")
+	}
+	flusher.Stop()
+	time.Sleep(300 * time.Millisecond)
+	if got := deferred.Load(); got != 1 {
+		t.Errorf("Expected 1 deferred flush, got %d", got)
+	}
+}
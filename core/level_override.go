@@ -0,0 +1,229 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLevelOverrideTTL is how long a level override lasts when no ttl is given
+	DefaultLevelOverrideTTL = 15 * time.Minute
+	// MaxLevelOverrideTTL bounds level overrides so a forgotten one cannot flood an output for days
+	MaxLevelOverrideTTL = 24 * time.Hour
+)
+
+var (
+	errPipelineNotFound = errors.New("pipeline not found")
+	errNoLevelFilter    = errors.New("pipeline has no level filter")
+)
+
+// LevelOverrider is an optional interface for filters whose level threshold can be
+// relaxed at runtime (POST /pipelines/<name>/level). An override only adds logs:
+// until it expires the filter also keeps logs at or above its minimum level.
+// Implementations must stop applying an override once its deadline has passed,
+// even if ClearLevelOverride is never called.
+type LevelOverrider interface {
+	SetLevelOverride(minLevel string, until time.Time)
+	ClearLevelOverride()
+	LevelOverride() (minLevel string, until time.Time, active bool)
+}
+
+// levelOverriders returns the pipeline filters that support level overrides
+func (p *OutputPipeline) levelOverriders() []LevelOverrider {
+	var overriders []LevelOverrider
+	for _, filter := range p.Filters {
+		if overrider, ok := filter.(LevelOverrider); ok {
+			overriders = append(overriders, overrider)
+		}
+	}
+	return overriders
+}
+
+// LevelOverride returns the active level override of the pipeline, if any
+func (p *OutputPipeline) LevelOverride() (string, time.Time, bool) {
+	for _, overrider := range p.levelOverriders() {
+		if minLevel, until, active := overrider.LevelOverride(); active {
+			return minLevel, until, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// findPipeline returns the named output pipeline
+func (e *Engine) findPipeline(name string) (*OutputPipeline, error) {
+	for _, pipeline := range e.plugins.Load().pipelines {
+		if pipeline.Name == name {
+			return pipeline, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errPipelineNotFound, name)
+}
+
+// SetLevelOverride relaxes the level filters of the named pipeline to keep logs at
+// or above minLevel for ttl, and returns when the override expires. Setting a new
+// override replaces the previous one.
+func (e *Engine) SetLevelOverride(name, minLevel string, ttl time.Duration) (time.Time, error) {
+	levels := Levels()
+	if !levels.Known(minLevel) {
+		return time.Time{}, fmt.Errorf("min %q is not a known level (known: %s)", minLevel, strings.Join(levels.Levels(), ", "))
+	}
+	if ttl <= 0 || ttl > MaxLevelOverrideTTL {
+		return time.Time{}, fmt.Errorf("ttl must be between 0 and %s, got %s", MaxLevelOverrideTTL, ttl)
+	}
+
+	pipeline, err := e.findPipeline(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	overriders := pipeline.levelOverriders()
+	if len(overriders) == 0 {
+		return time.Time{}, fmt.Errorf("%w: %s", errNoLevelFilter, name)
+	}
+
+	minLevel = levels.Normalize(minLevel)
+	until := time.Now().Add(ttl)
+
+	pipeline.overrideMu.Lock()
+	defer pipeline.overrideMu.Unlock()
+
+	for _, overrider := range overriders {
+		overrider.SetLevelOverride(minLevel, until)
+	}
+
+	// The filters ignore the override after the deadline on their own; the timer
+	// only clears it so the revert is logged
+	if pipeline.overrideTimer != nil {
+		pipeline.overrideTimer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		pipeline.overrideMu.Lock()
+		defer pipeline.overrideMu.Unlock()
+		if pipeline.overrideTimer != timer {
+			return // Replaced or cleared in the meantime
+		}
+		pipeline.clearLevelOverrideLocked()
+		engineLog.Printf("Output pipeline '%s' level override expired", name)
+	})
+	pipeline.overrideTimer = timer
+
+	engineLog.Printf("Output pipeline '%s' level override min=%s until %s", name, minLevel, until.Format(time.RFC3339))
+	return until, nil
+}
+
+// ClearLevelOverride removes the level override of the named pipeline before it expires
+func (e *Engine) ClearLevelOverride(name string) error {
+	pipeline, err := e.findPipeline(name)
+	if err != nil {
+		return err
+	}
+	if len(pipeline.levelOverriders()) == 0 {
+		return fmt.Errorf("%w: %s", errNoLevelFilter, name)
+	}
+
+	pipeline.overrideMu.Lock()
+	defer pipeline.overrideMu.Unlock()
+	pipeline.clearLevelOverrideLocked()
+	engineLog.Printf("Output pipeline '%s' level override cleared", name)
+	return nil
+}
+
+// clearLevelOverrideLocked removes the override from every filter; overrideMu must be held
+func (p *OutputPipeline) clearLevelOverrideLocked() {
+	if p.overrideTimer != nil {
+		p.overrideTimer.Stop()
+		p.overrideTimer = nil
+	}
+	for _, overrider := range p.levelOverriders() {
+		overrider.ClearLevelOverride()
+	}
+}
+
+// stopLevelOverrideTimer stops the expiry timer of a pipeline that is being discarded
+func (p *OutputPipeline) stopLevelOverrideTimer() {
+	p.overrideMu.Lock()
+	defer p.overrideMu.Unlock()
+	if p.overrideTimer != nil {
+		p.overrideTimer.Stop()
+		p.overrideTimer = nil
+	}
+}
+
+// handlePipelineLevel sets (POST ?min=<level>&ttl=<duration>) or clears (DELETE)
+// the level override of an output pipeline
+func (e *Engine) handlePipelineLevel(w http.ResponseWriter, r *http.Request, name string) {
+	response := map[string]interface{}{"name": name}
+
+	switch r.Method {
+	case http.MethodPost:
+		query := r.URL.Query()
+		minLevel := query.Get("min")
+		if minLevel == "" {
+			http.Error(w, "Missing min parameter", http.StatusBadRequest)
+			return
+		}
+		ttl := DefaultLevelOverrideTTL
+		if raw := query.Get("ttl"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid ttl: %v", err), http.StatusBadRequest)
+				return
+			}
+			ttl = parsed
+		}
+
+		until, err := e.SetLevelOverride(name, minLevel, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), levelOverrideStatus(err))
+			return
+		}
+		response["min_level"] = Levels().Normalize(minLevel)
+		response["expires_at"] = until.UTC().Format(time.RFC3339)
+
+	case http.MethodDelete:
+		if err := e.ClearLevelOverride(name); err != nil {
+			http.Error(w, err.Error(), levelOverrideStatus(err))
+			return
+		}
+		response["cleared"] = true
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding level override response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// levelOverrideStatus maps a level override error to an HTTP status
+func levelOverrideStatus(err error) int {
+	switch {
+	case errors.Is(err, errPipelineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errNoLevelFilter):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// levelOverrideStatusEntry describes an active level override for /status
+func levelOverrideStatusEntry(p *OutputPipeline) map[string]interface{} {
+	minLevel, until, active := p.LevelOverride()
+	if !active {
+		return nil
+	}
+	return map[string]interface{}{
+		"min_level":         minLevel,
+		"expires_at":        until.UTC().Format(time.RFC3339),
+		"remaining_seconds": int64(time.Until(until).Seconds()),
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// overridableFilter keeps logs at or above an override level, like the level filter
+type overridableFilter struct {
+	mu       sync.Mutex
+	minLevel string
+	until    time.Time
+	cleared  int
+}
+
+func (f *overridableFilter) Process(log *Log) bool {
+	minLevel, _, active := f.LevelOverride()
+	return active && Levels().AtLeast(log.Level, minLevel)
+}
+
+func (f *overridableFilter) SetLevelOverride(minLevel string, until time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.minLevel, f.until = minLevel, until
+}
+
+func (f *overridableFilter) ClearLevelOverride() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.minLevel, f.until = "", time.Time{}
+	f.cleared++
+}
+
+func (f *overridableFilter) LevelOverride() (string, time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.minLevel == "" || !time.Now().Before(f.until) {
+		return "", time.Time{}, false
+	}
+	return f.minLevel, f.until, true
+}
+
+func (f *overridableFilter) clearCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cleared
+}
+
+func newLevelOverrideEngine(t *testing.T) (*Engine, *overridableFilter) {
+	t.Helper()
+	engine := NewEngine()
+	filter := &overridableFilter{}
+	pipelines := []*OutputPipeline{
+		{Name: "app", Output: newMockOutput(), Filters: []FilterPlugin{filter}},
+		{Name: "plain", Output: newMockOutput()},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+	return engine, filter
+}
+
+func TestPipelineLevelOverrideAPI(t *testing.T) {
+	engine, filter := newLevelOverrideEngine(t)
+
+	w := httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("POST", "/pipelines/app/level?min=DEBUG&ttl=10m", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"min_level":"debug"`) {
+		t.Errorf("Expected the normalized level in the response, got %s", w.Body.String())
+	}
+	if !filter.Process(NewLog("debug", "test")) {
+		t.Error("Expected the filter to keep debug logs during the override")
+	}
+	if _, until, _ := filter.LevelOverride(); time.Until(until) < 9*time.Minute {
+		t.Errorf("Expected the override to last about 10m, expires at %v", until)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	if !strings.Contains(w.Body.String(), `"level_override":{"expires_at":`) || !strings.Contains(w.Body.String(), `"min_level":"debug"`) {
+		t.Errorf("Expected the override in /status, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("DELETE", "/pipelines/app/level", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if filter.Process(NewLog("debug", "test")) {
+		t.Error("Expected debug logs to be dropped after clearing the override")
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	if strings.Contains(w.Body.String(), "level_override") {
+		t.Errorf("Expected no override in /status after clearing, got %s", w.Body.String())
+	}
+}
+
+func TestPipelineLevelOverrideErrors(t *testing.T) {
+	engine, _ := newLevelOverrideEngine(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "unknown pipeline", method: "POST", path: "/pipelines/missing/level?min=debug", status: http.StatusNotFound},
+		{name: "no level filter", method: "POST", path: "/pipelines/plain/level?min=debug", status: http.StatusConflict},
+		{name: "clear without level filter", method: "DELETE", path: "/pipelines/plain/level", status: http.StatusConflict},
+		{name: "missing min", method: "POST", path: "/pipelines/app/level?ttl=5m", status: http.StatusBadRequest},
+		{name: "unknown level", method: "POST", path: "/pipelines/app/level?min=verbose", status: http.StatusBadRequest},
+		{name: "invalid ttl", method: "POST", path: "/pipelines/app/level?min=debug&ttl=soon", status: http.StatusBadRequest},
+		{name: "negative ttl", method: "POST", path: "/pipelines/app/level?min=debug&ttl=-1m", status: http.StatusBadRequest},
+		{name: "ttl above maximum", method: "POST", path: "/pipelines/app/level?min=debug&ttl=48h", status: http.StatusBadRequest},
+		{name: "wrong method", method: "GET", path: "/pipelines/app/level", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.handlePipelineToggle(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPipelineLevelOverrideExpires(t *testing.T) {
+	engine, filter := newLevelOverrideEngine(t)
+
+	if _, err := engine.SetLevelOverride("app", "debug", time.Hour); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	// Replacing the override must cancel the first timer
+	if _, err := engine.SetLevelOverride("app", "info", 20*time.Millisecond); err != nil {
+		t.Fatalf("Failed to replace override: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for filter.clearCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if filter.clearCount() != 1 {
+		t.Fatalf("Expected the override to be cleared once on expiry, got %d", filter.clearCount())
+	}
+	if _, _, active := engine.plugins.Load().pipelines[0].LevelOverride(); active {
+		t.Error("Expected no active override after expiry")
+	}
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelsConfig defines the log level vocabulary shared by all level-aware components
+type LevelsConfig struct {
+	Order   []string          `yaml:"order,omitempty"`   // Levels from least to most severe (default: debug, info, warn, error)
+	Aliases map[string]string `yaml:"aliases,omitempty"` // Alternative names mapped to a level in order (e.g. CRIT: error, "3": error)
+	Default string            `yaml:"default,omitempty"` // Level used when none is detected (default: info)
+}
+
+// DefaultLevelsConfig returns the built-in level vocabulary
+func DefaultLevelsConfig() LevelsConfig {
+	return LevelsConfig{
+		Order: []string{"debug", "info", "warn", "error"},
+		Aliases: map[string]string{
+			"warning": "warn",
+			"err":     "error",
+		},
+		Default: "info",
+	}
+}
+
+// Validate validates the LevelsConfig
+func (c LevelsConfig) Validate() error {
+	_, err := NewLevelVocabulary(c)
+	return err
+}
+
+// LevelVocabulary resolves level names and aliases to canonical levels and their severity
+type LevelVocabulary struct {
+	order        []string          // Canonical levels, least to most severe
+	severity     map[string]int    // Canonical level -> rank in order
+	aliases      map[string]string // Lowercase alias -> canonical level
+	defaultLevel string
+	keywords     [][]string // Detection keywords per level, most severe first
+}
+
+// NewLevelVocabulary builds a vocabulary from configuration. Unset fields fall back to the defaults.
+func NewLevelVocabulary(config LevelsConfig) (*LevelVocabulary, error) {
+	defaults := DefaultLevelsConfig()
+	if len(config.Order) == 0 {
+		config.Order = defaults.Order
+		if config.Aliases == nil {
+			config.Aliases = defaults.Aliases
+		}
+	}
+	if config.Default == "" {
+		config.Default = defaults.Default
+	}
+
+	v := &LevelVocabulary{
+		severity: make(map[string]int, len(config.Order)),
+		aliases:  make(map[string]string, len(config.Aliases)),
+	}
+
+	for _, level := range config.Order {
+		level = strings.ToLower(strings.TrimSpace(level))
+		if level == "" {
+			return nil, fmt.Errorf("levels order cannot contain blank levels")
+		}
+		if _, exists := v.severity[level]; exists {
+			return nil, fmt.Errorf("level %q appears more than once in levels order", level)
+		}
+		v.severity[level] = len(v.order)
+		v.order = append(v.order, level)
+	}
+
+	for alias, target := range config.Aliases {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		target = strings.ToLower(strings.TrimSpace(target))
+		if alias == "" {
+			return nil, fmt.Errorf("level aliases cannot contain blank names")
+		}
+		if _, ok := v.severity[target]; !ok {
+			return nil, fmt.Errorf("level alias %q maps to unknown level %q", alias, target)
+		}
+		if _, ok := v.severity[alias]; ok {
+			return nil, fmt.Errorf("level alias %q conflicts with a level in order", alias)
+		}
+		v.aliases[alias] = target
+	}
+
+	v.defaultLevel = strings.ToLower(config.Default)
+	if _, ok := v.severity[v.defaultLevel]; !ok {
+		return nil, fmt.Errorf("default level %q is not in levels order", config.Default)
+	}
+
+	v.buildKeywords()
+	return v, nil
+}
+
+// buildKeywords prepares the keywords used to detect levels in raw lines.
+// The default level is never detected by keyword and numeric aliases are skipped.
+func (v *LevelVocabulary) buildKeywords() {
+	for i := len(v.order) - 1; i >= 0; i-- {
+		level := v.order[i]
+		if level == v.defaultLevel {
+			v.keywords = append(v.keywords, nil)
+			continue
+		}
+
+		words := []string{level}
+		for alias, target := range v.aliases {
+			if target == level && !isNumeric(alias) {
+				words = append(words, alias)
+			}
+		}
+		v.keywords = append(v.keywords, words)
+	}
+}
+
+// isNumeric reports whether s only contains digits
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Normalize returns the canonical name of a level. Unknown levels are returned lowercased.
+func (v *LevelVocabulary) Normalize(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if target, ok := v.aliases[level]; ok {
+		return target
+	}
+	return level
+}
+
+// Known reports whether the level or alias is part of the vocabulary
+func (v *LevelVocabulary) Known(level string) bool {
+	_, ok := v.severity[v.Normalize(level)]
+	return ok
+}
+
+// Severity returns the rank of a level (higher is more severe) and whether it is known
+func (v *LevelVocabulary) Severity(level string) (int, bool) {
+	rank, ok := v.severity[v.Normalize(level)]
+	return rank, ok
+}
+
+// AtLeast reports whether level is at least as severe as threshold.
+// Unknown levels or thresholds never match.
+func (v *LevelVocabulary) AtLeast(level, threshold string) bool {
+	rank, ok := v.Severity(level)
+	if !ok {
+		return false
+	}
+	minRank, ok := v.Severity(threshold)
+	return ok && rank >= minRank
+}
+
+// Default returns the level used when none is detected
+func (v *LevelVocabulary) Default() string {
+	return v.defaultLevel
+}
+
+// Levels returns the canonical levels from least to most severe
+func (v *LevelVocabulary) Levels() []string {
+	return append([]string(nil), v.order...)
+}
+
+// Detect infers a level from keywords in a raw log line, checking the most severe
+// levels first. It returns the default level when no keyword is present.
+func (v *LevelVocabulary) Detect(line string) string {
+	level, _ := v.DetectKnown(line)
+	return level
+}
+
+// DetectKnown is like Detect but also reports whether the line names a level.
+// Lines naming the default level (e.g. "INFO") count as detected.
+func (v *LevelVocabulary) DetectKnown(line string) (string, bool) {
+	lowerLine := strings.ToLower(line)
+	for i, words := range v.keywords {
+		for _, word := range words {
+			if strings.Contains(lowerLine, word) {
+				return v.order[len(v.order)-1-i], true
+			}
+		}
+	}
+
+	if strings.Contains(lowerLine, v.defaultLevel) {
+		return v.defaultLevel, true
+	}
+	for alias, target := range v.aliases {
+		if target == v.defaultLevel && !isNumeric(alias) && strings.Contains(lowerLine, alias) {
+			return v.defaultLevel, true
+		}
+	}
+	return v.defaultLevel, false
+}
+
+var levels atomic.Pointer[LevelVocabulary]
+
+func init() {
+	vocabulary, _ := NewLevelVocabulary(DefaultLevelsConfig())
+	levels.Store(vocabulary)
+}
+
+// Levels returns the active level vocabulary
+func Levels() *LevelVocabulary {
+	return levels.Load()
+}
+
+// SetLevels replaces the active level vocabulary used by inputs, filters and outputs
+func SetLevels(config LevelsConfig) error {
+	vocabulary, err := NewLevelVocabulary(config)
+	if err != nil {
+		return err
+	}
+	levels.Store(vocabulary)
+	return nil
+}
@@ -0,0 +1,124 @@
+package core
+
+import "testing"
+
+func TestDefaultLevelVocabulary(t *testing.T) {
+	levels := Levels()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ERROR", "error"},
+		{"warning", "warn"},
+		{"Err", "error"},
+		{" info ", "info"},
+		{"verbose", "verbose"},
+	}
+
+	for _, tt := range tests {
+		if got := levels.Normalize(tt.input); got != tt.expected {
+			t.Errorf("Expected Normalize(%q) = %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	if !levels.AtLeast("error", "warn") {
+		t.Error("Expected error to be at least warn")
+	}
+	if levels.AtLeast("info", "warning") {
+		t.Error("Expected info to be below warning")
+	}
+	if levels.AtLeast("verbose", "debug") {
+		t.Error("Expected unknown level to never match")
+	}
+	if levels.Default() != "info" {
+		t.Errorf("Expected default level info, got %s", levels.Default())
+	}
+}
+
+func TestLevelVocabularyDetect(t *testing.T) {
+	vocabulary, err := NewLevelVocabulary(LevelsConfig{
+		Order:   []string{"debug", "info", "warn", "error", "critical"},
+		Aliases: map[string]string{"crit": "critical", "fatal": "critical", "2": "critical"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"FATAL: disk gone", "critical"},
+		{"CRIT error in module", "critical"},
+		{"an error occurred", "error"},
+		{"warn: slow query", "warn"},
+		{"debug details", "debug"},
+		{"user 2 logged in", "info"},
+		{"all good", "info"},
+	}
+
+	for _, tt := range tests {
+		if got := vocabulary.Detect(tt.line); got != tt.expected {
+			t.Errorf("Expected Detect(%q) = %q, got %q", tt.line, tt.expected, got)
+		}
+	}
+
+	if vocabulary.Normalize("2") != "critical" {
+		t.Error("Expected numeric alias to normalize")
+	}
+
+	if _, ok := vocabulary.DetectKnown("INFO service started"); !ok {
+		t.Error("Expected a line naming the default level to be detected")
+	}
+	if level, ok := vocabulary.DetectKnown("all good"); ok || level != "info" {
+		t.Errorf("Expected no level detected and default level, got %q %v", level, ok)
+	}
+}
+
+func TestLevelsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  LevelsConfig
+		wantErr bool
+	}{
+		{"empty uses defaults", LevelsConfig{}, false},
+		{"custom order", LevelsConfig{Order: []string{"low", "high"}, Default: "low"}, false},
+		{"duplicate level", LevelsConfig{Order: []string{"info", "INFO"}}, true},
+		{"blank level", LevelsConfig{Order: []string{"info", " "}}, true},
+		{"alias to unknown level", LevelsConfig{Aliases: map[string]string{"crit": "fatal"}}, true},
+		{"alias shadows level", LevelsConfig{Aliases: map[string]string{"info": "error"}}, true},
+		{"default not in order", LevelsConfig{Order: []string{"low", "high"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSetLevels(t *testing.T) {
+	defer func() { _ = SetLevels(DefaultLevelsConfig()) }()
+
+	if err := SetLevels(LevelsConfig{Order: []string{"low", "high"}}); err == nil {
+		t.Error("Expected error for invalid vocabulary")
+	}
+	if Levels().Default() != "info" {
+		t.Error("Expected invalid vocabulary to leave active levels untouched")
+	}
+
+	err := SetLevels(LevelsConfig{
+		Order:   []string{"debug", "info", "warn", "error"},
+		Aliases: map[string]string{"crit": "error"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if DetectLevel("CRIT something broke") != "error" {
+		t.Error("Expected DetectLevel to use the active vocabulary")
+	}
+}
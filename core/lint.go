@@ -0,0 +1,342 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Lint warning codes. Codes are stable so they can be listed in lint.ignore.
+const (
+	LintDuplicateName        = "duplicate_name"         // Two inputs or two outputs share a name
+	LintUnknownSource        = "unknown_source"         // An output lists a source no input is named
+	LintDuplicateDestination = "duplicate_destination"  // A catch-all output writes where another output writes
+	LintDropsAllLogs         = "drops_all_logs"         // A filter keeps no log, so later filters and the output never run
+	LintRateLimitBelowInput  = "rate_limit_below_input" // A rate_limit filter allows fewer logs than an input accepts requests
+	LintNoOutputBuffer       = "no_output_buffer"       // A network output runs without output buffering
+	LintNoDLQ                = "no_dlq"                 // Output buffering is enabled without a DLQ
+	LintUnusedDefaults       = "unused_defaults"        // Defaults are set for a plugin type no plugin uses
+)
+
+// lintCodes lists every warning code, for validating lint.ignore
+var lintCodes = []string{
+	LintDuplicateName, LintUnknownSource, LintDuplicateDestination, LintDropsAllLogs,
+	LintRateLimitBelowInput, LintNoOutputBuffer, LintNoDLQ, LintUnusedDefaults,
+}
+
+// networkOutputs are output types that write to a remote service and fail when it is unreachable
+var networkOutputs = map[string]bool{"elasticsearch": true, "slack": true, "redis_stream": true, "email": true, "syslog": true}
+
+// LinterConfig configures the configuration linter
+type LinterConfig struct {
+	// Warnings to suppress: a code ("no_dlq") suppresses it everywhere, and
+	// "code:subject" ("no_output_buffer:outputs.alerts") only for that subject
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// Validate validates the LinterConfig
+func (c LinterConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Ignore, validation.Each(validation.By(func(value interface{}) error {
+			code, _, _ := strings.Cut(value.(string), ":")
+			if !slices.Contains(lintCodes, code) {
+				return fmt.Errorf("unknown warning code %q (known: %s)", code, strings.Join(lintCodes, ", "))
+			}
+			return nil
+		}))),
+	)
+}
+
+// ignores reports whether a warning is suppressed
+func (c LinterConfig) ignores(w Warning) bool {
+	return slices.Contains(c.Ignore, w.Code) || slices.Contains(c.Ignore, w.ID())
+}
+
+// Warning is a likely misconfiguration found by LintConfig. Warnings never stop
+// LogAnalyzer from starting.
+type Warning struct {
+	Code    string // Kind of problem, one of the Lint* codes
+	Subject string // What the warning is about: "inputs.<name>", "outputs.<name>" or a section key
+	Message string // What is wrong and how to fix it
+}
+
+// ID returns the lint.ignore entry that suppresses only this warning
+func (w Warning) ID() string {
+	return w.Code + ":" + w.Subject
+}
+
+// String formats the warning with the entry that suppresses it
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s (suppress with lint.ignore: %s)", w.Subject, w.Message, w.ID())
+}
+
+// LintConfig flags valid but likely wrong configuration: names that collide,
+// sources that match no input, filters that drop everything, rate limits below
+// what inputs accept, network outputs without buffering or a DLQ, and defaults
+// for plugin types that are not used. Warnings listed in lint.ignore are left out.
+func LintConfig(config *Config) []Warning {
+	l := &linter{config: config}
+	l.inputNames = l.names("inputs", config.Inputs)
+	l.outputNames = l.names("outputs", config.Outputs)
+
+	for i, output := range config.Outputs {
+		if !output.IsEnabled() {
+			continue
+		}
+		subject := "outputs." + l.outputNames[i]
+		l.checkSources(subject, output)
+		l.checkDestination(i, subject, output)
+		l.checkFilters(subject, output)
+	}
+	l.checkBuffering()
+	l.checkDefaults()
+
+	var warnings []Warning
+	for _, w := range l.warnings {
+		if !config.Lint.ignores(w) {
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+// linter collects the warnings of one configuration
+type linter struct {
+	config      *Config
+	inputNames  []string // Effective input names, as the engine names them
+	outputNames []string // Effective output names, as the engine names them
+	warnings    []Warning
+}
+
+func (l *linter) warn(code, subject, format string, args ...any) {
+	l.warnings = append(l.warnings, Warning{Code: code, Subject: subject, Message: fmt.Sprintf(format, args...)})
+}
+
+// names returns the effective plugin names ("<type>-<n>" when unnamed) and flags duplicates
+func (l *linter) names(kind string, defs []PluginDefinition) []string {
+	names := make([]string, len(defs))
+	seen := make(map[string]bool, len(defs))
+	for i, def := range defs {
+		names[i] = def.Name
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("%s-%d", def.Type, i+1)
+		}
+		if seen[names[i]] {
+			l.warn(LintDuplicateName, kind+"."+names[i], "%s %d reuses the name %q; the API, metrics and reload diffs cannot tell them apart, give each a unique name", kind, i+1, names[i])
+		}
+		seen[names[i]] = true
+	}
+	return names
+}
+
+// checkSources flags sources that match no input, which never deliver logs
+func (l *linter) checkSources(subject string, output PluginDefinition) {
+	for _, source := range output.Sources {
+		matched := false
+		for i := range l.config.Inputs {
+			matched = matched || l.sourceMatches(source, i)
+		}
+		if matched {
+			continue
+		}
+		if _, ok := strings.CutPrefix(source, SourceTypePrefix); ok {
+			l.warn(LintUnknownSource, subject, "source %q matches no input type, so it delivers no logs to this output", source)
+		} else {
+			l.warn(LintUnknownSource, subject, "source %q matches no input (inputs: %s), so it delivers no logs to this output", source, strings.Join(l.inputNames, ", "))
+		}
+	}
+}
+
+// sourceMatches reports whether an output source entry matches the input at index i
+func (l *linter) sourceMatches(source string, i int) bool {
+	if sourceType, ok := strings.CutPrefix(source, SourceTypePrefix); ok {
+		return l.config.Inputs[i].Type == sourceType
+	}
+	return l.inputNames[i] == source
+}
+
+// feeds reports whether the input at index i can deliver logs to an output
+func (l *linter) feeds(i int, output PluginDefinition) bool {
+	if len(output.Sources) == 0 {
+		return true
+	}
+	for _, source := range output.Sources {
+		if l.sourceMatches(source, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDestination flags an output that writes where an earlier output writes while
+// one of them accepts every log: the logs both accept are written twice
+func (l *linter) checkDestination(index int, subject string, output PluginDefinition) {
+	for i, other := range l.config.Outputs[:index] {
+		if output.Type == "null" || !other.IsEnabled() || other.Type != output.Type || !reflect.DeepEqual(other.Config, output.Config) {
+			continue
+		}
+		if catchAll(output) || catchAll(other) {
+			l.warn(LintDuplicateDestination, subject, "has the same %s configuration as output %q and one of them has no sources, tags or filters, so logs they both accept are written twice; merge them or restrict one", output.Type, l.outputNames[i])
+			return
+		}
+	}
+}
+
+// catchAll reports whether an output pipeline accepts every log
+func catchAll(output PluginDefinition) bool {
+	return len(output.Sources) == 0 && len(output.Tags) == 0 && len(output.Filters) == 0
+}
+
+// checkFilters flags filters that keep no log, which makes the output (and any
+// later filter) unreachable, and rate limits below what a feeding input accepts
+func (l *linter) checkFilters(subject string, output PluginDefinition) {
+	vocabulary, err := NewLevelVocabulary(l.config.Levels)
+	if err != nil {
+		vocabulary = Levels()
+	}
+
+	var passing map[string]bool // Levels every level filter so far keeps (nil = all)
+	for i, filter := range output.Filters {
+		where := fmt.Sprintf("filters[%d] (%s)", i, filter.Type)
+		reason := ""
+		switch filter.Type {
+		case "level":
+			passing = intersectLevels(passing, levelFilterPasses(vocabulary, filter.Config))
+			if len(passing) == 0 {
+				reason = "keeps no level"
+				if i > 0 {
+					reason = "keeps no level that the level filters before it keep"
+				}
+			}
+		case "rate_limit":
+			var cfg struct {
+				Rate  float64 `yaml:"rate"`
+				Burst int     `yaml:"burst"`
+			}
+			if GetPluginConfig(filter.Config, &cfg) != nil {
+				continue
+			}
+			if cfg.Burst < 1 {
+				reason = "has burst below 1, so no token is ever available"
+				break
+			}
+			l.checkRateLimit(subject, where, output, cfg.Rate)
+		}
+		if reason == "" {
+			continue
+		}
+
+		message := fmt.Sprintf("%s %s, so this output never receives logs", where, reason)
+		if i < len(output.Filters)-1 {
+			message += fmt.Sprintf(" and filters[%d:] never run", i+1)
+		}
+		l.warn(LintDropsAllLogs, subject, "%s", message)
+		return
+	}
+}
+
+// levelFilterPasses returns the levels a level filter keeps
+func levelFilterPasses(vocabulary *LevelVocabulary, config map[string]any) map[string]bool {
+	var cfg struct {
+		Levels   []string `yaml:"levels"`
+		MinLevel string   `yaml:"min_level"`
+	}
+	passes := make(map[string]bool)
+	if GetPluginConfig(config, &cfg) != nil {
+		return passes
+	}
+	for _, level := range cfg.Levels {
+		passes[vocabulary.Normalize(level)] = true
+	}
+	if cfg.MinLevel != "" {
+		for _, level := range vocabulary.Levels() {
+			if vocabulary.AtLeast(level, cfg.MinLevel) {
+				passes[level] = true
+			}
+		}
+	}
+	return passes
+}
+
+// intersectLevels returns the levels in both sets; a nil set means every level
+func intersectLevels(a, b map[string]bool) map[string]bool {
+	if a == nil {
+		return b
+	}
+	result := make(map[string]bool)
+	for level := range a {
+		if b[level] {
+			result[level] = true
+		}
+	}
+	return result
+}
+
+// checkRateLimit flags a rate_limit filter below the rate limit of an HTTP input
+// feeding the output: sustained traffic the input accepts is partly dropped
+func (l *linter) checkRateLimit(subject, where string, output PluginDefinition, rate float64) {
+	for i, input := range l.config.Inputs {
+		if input.Type != "http" || !l.feeds(i, output) {
+			continue
+		}
+		var cfg struct {
+			RateLimit struct {
+				Enabled bool    `yaml:"enabled"`
+				Rate    float64 `yaml:"rate"`
+			} `yaml:"rate_limit"`
+		}
+		if GetPluginConfig(input.Config, &cfg) != nil || !cfg.RateLimit.Enabled || cfg.RateLimit.Rate <= 0 {
+			continue
+		}
+		if rate < cfg.RateLimit.Rate {
+			l.warn(LintRateLimitBelowInput, subject, "%s allows %g logs/s but input %q accepts %g requests/s, so sustained traffic the input accepts is dropped here; raise the filter rate or lower the input's", where, rate, l.inputNames[i], cfg.RateLimit.Rate)
+		}
+	}
+}
+
+// checkBuffering flags network outputs without buffering and buffering without a DLQ
+func (l *linter) checkBuffering() {
+	// Mirrors main: an output_buffer section without a dir uses the defaults
+	buffer := l.config.OutputBuffer
+	if buffer.Dir == "" {
+		buffer = DefaultOutputBufferConfig()
+	}
+
+	if !buffer.Enabled {
+		for i, output := range l.config.Outputs {
+			if output.IsEnabled() && !output.Shadow && networkOutputs[output.Type] {
+				l.warn(LintNoOutputBuffer, "outputs."+l.outputNames[i], "%s output writes over the network but output_buffer is disabled, so logs are dropped while it is unreachable; enable output_buffer", output.Type)
+			}
+		}
+		return
+	}
+	if !buffer.DLQEnabled {
+		l.warn(LintNoDLQ, "output_buffer", "buffering is enabled without a DLQ, so logs that exhaust max_retries are dropped; set dlq_enabled: true to keep them")
+	}
+}
+
+// checkDefaults flags type defaults that match no plugin, usually a misspelled type
+func (l *linter) checkDefaults() {
+	var filters []PluginDefinition
+	for _, output := range l.config.Outputs {
+		filters = append(filters, output.Filters...)
+	}
+
+	kinds := []struct {
+		kind     string
+		defaults map[string]map[string]any
+		defs     []PluginDefinition
+	}{
+		{"inputs", l.config.Defaults.Inputs, l.config.Inputs},
+		{"outputs", l.config.Defaults.Outputs, l.config.Outputs},
+		{"filters", l.config.Defaults.Filters, filters},
+	}
+	for _, k := range kinds {
+		for _, pluginType := range unusedTypes(k.defaults, k.defs) {
+			l.warn(LintUnusedDefaults, "defaults."+k.kind+"."+pluginType, "no %s plugin has type %q, so these defaults are never applied; check the type name or use %q for every plugin", strings.TrimSuffix(k.kind, "s"), pluginType, DefaultsAll)
+		}
+	}
+}
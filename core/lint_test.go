@@ -0,0 +1,201 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func lintTestConfig() *Config {
+	return &Config{
+		Inputs: []PluginDefinition{
+			{Type: "http", Name: "api", Config: map[string]any{"rate_limit": map[string]any{"enabled": true, "rate": 10.0}}},
+			{Type: "docker", Config: map[string]any{}},
+		},
+		Outputs: []PluginDefinition{
+			{Type: "console", Name: "debug", Config: map[string]any{"target": "stdout"}},
+		},
+		OutputBuffer: OutputBufferConfig{Enabled: true, Dir: "./data/buffers", DLQEnabled: true},
+	}
+}
+
+func lintCodesOf(warnings []Warning) []string {
+	var codes []string
+	for _, w := range warnings {
+		codes = append(codes, w.ID())
+	}
+	return codes
+}
+
+func TestLintConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*Config)
+		expected []string
+	}{
+		{
+			name:   "clean config",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "duplicate names",
+			modify: func(c *Config) {
+				c.Inputs = append(c.Inputs, PluginDefinition{Type: "file", Name: "docker-2"})
+				c.Outputs = append(c.Outputs, PluginDefinition{Type: "file", Name: "debug", Config: map[string]any{"file_path": "x"}})
+			},
+			expected: []string{"duplicate_name:inputs.docker-2", "duplicate_name:outputs.debug"},
+		},
+		{
+			name: "unknown sources",
+			modify: func(c *Config) {
+				c.Outputs[0].Sources = []string{"api", "docker-2", "type:docker", "ap1", "type:kafka"}
+			},
+			expected: []string{"unknown_source:outputs.debug", "unknown_source:outputs.debug"},
+		},
+		{
+			name: "catch-all output duplicating another",
+			modify: func(c *Config) {
+				c.Outputs = append(c.Outputs, PluginDefinition{Type: "console", Name: "errors", Config: map[string]any{"target": "stdout"}, Sources: []string{"api"}})
+			},
+			expected: []string{"duplicate_destination:outputs.errors"},
+		},
+		{
+			name: "same destination with restricted outputs",
+			modify: func(c *Config) {
+				c.Outputs[0].Sources = []string{"docker-2"}
+				c.Outputs = append(c.Outputs, PluginDefinition{Type: "console", Name: "errors", Config: map[string]any{"target": "stdout"}, Sources: []string{"api"}})
+			},
+		},
+		{
+			name: "empty level filter",
+			modify: func(c *Config) {
+				c.Outputs[0].Filters = []PluginDefinition{{Type: "level", Config: map[string]any{"levels": []any{}}}}
+			},
+			expected: []string{"drops_all_logs:outputs.debug"},
+		},
+		{
+			name: "contradicting level filters",
+			modify: func(c *Config) {
+				c.Outputs[0].Filters = []PluginDefinition{
+					{Type: "level", Config: map[string]any{"min_level": "error"}},
+					{Type: "level", Config: map[string]any{"levels": []any{"info", "WARNING"}}},
+					{Type: "json", Config: map[string]any{}},
+				}
+			},
+			expected: []string{"drops_all_logs:outputs.debug"},
+		},
+		{
+			name: "narrowing level filters",
+			modify: func(c *Config) {
+				c.Outputs[0].Filters = []PluginDefinition{
+					{Type: "level", Config: map[string]any{"min_level": "warn"}},
+					{Type: "level", Config: map[string]any{"levels": []any{"error"}}},
+				}
+			},
+		},
+		{
+			name: "rate limit without burst",
+			modify: func(c *Config) {
+				c.Outputs[0].Filters = []PluginDefinition{{Type: "rate_limit", Config: map[string]any{"rate": 100.0, "burst": 0}}}
+			},
+			expected: []string{"drops_all_logs:outputs.debug"},
+		},
+		{
+			name: "rate limit below http input",
+			modify: func(c *Config) {
+				c.Outputs[0].Filters = []PluginDefinition{{Type: "rate_limit", Config: map[string]any{"rate": 2.0, "burst": 5}}}
+			},
+			expected: []string{"rate_limit_below_input:outputs.debug"},
+		},
+		{
+			name: "rate limit on an output the http input does not feed",
+			modify: func(c *Config) {
+				c.Outputs[0].Sources = []string{"type:docker"}
+				c.Outputs[0].Filters = []PluginDefinition{{Type: "rate_limit", Config: map[string]any{"rate": 2.0, "burst": 5}}}
+			},
+		},
+		{
+			name: "network output without buffering",
+			modify: func(c *Config) {
+				c.OutputBuffer = OutputBufferConfig{}
+				c.Outputs = append(c.Outputs, PluginDefinition{Type: "slack", Name: "alerts", Config: map[string]any{"webhook_url": "x"}})
+			},
+			expected: []string{"no_output_buffer:outputs.alerts"},
+		},
+		{
+			name: "disabled outputs are skipped",
+			modify: func(c *Config) {
+				disabled := false
+				c.OutputBuffer = OutputBufferConfig{}
+				c.Outputs = append(c.Outputs, PluginDefinition{Type: "slack", Name: "alerts", Config: map[string]any{"webhook_url": "x"}, Enabled: &disabled})
+			},
+		},
+		{
+			name:     "buffering without DLQ",
+			modify:   func(c *Config) { c.OutputBuffer.DLQEnabled = false },
+			expected: []string{"no_dlq:output_buffer"},
+		},
+		{
+			name: "defaults for unused types",
+			modify: func(c *Config) {
+				c.Defaults = DefaultsConfig{
+					Inputs:  map[string]map[string]any{DefaultsAll: {"x": 1}, "docker": {"x": 1}},
+					Outputs: map[string]map[string]any{"elasticserch": {"x": 1}},
+					Filters: map[string]map[string]any{"level": {"x": 1}},
+				}
+			},
+			expected: []string{"unused_defaults:defaults.outputs.elasticserch", "unused_defaults:defaults.filters.level"},
+		},
+		{
+			name: "suppressed by code and by subject",
+			modify: func(c *Config) {
+				c.OutputBuffer.DLQEnabled = false
+				c.Outputs[0].Sources = []string{"missing"}
+				c.Outputs = append(c.Outputs, PluginDefinition{Type: "file", Name: "archive", Config: map[string]any{"file_path": "x"}, Sources: []string{"missing"}})
+				c.Lint.Ignore = []string{LintNoDLQ, "unknown_source:outputs.debug"}
+			},
+			expected: []string{"unknown_source:outputs.archive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := lintTestConfig()
+			tt.modify(config)
+			warnings := LintConfig(config)
+			if codes := lintCodesOf(warnings); !reflect.DeepEqual(codes, tt.expected) {
+				t.Errorf("Expected warnings %v, got %v", tt.expected, warnings)
+			}
+		})
+	}
+}
+
+func TestLintConfigMessages(t *testing.T) {
+	config := lintTestConfig()
+	config.Outputs[0].Filters = []PluginDefinition{
+		{Type: "level", Config: map[string]any{"levels": []any{}}},
+		{Type: "json", Config: map[string]any{}},
+	}
+
+	warnings := LintConfig(config)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %v", warnings)
+	}
+	expected := "outputs.debug: filters[0] (level) keeps no level, so this output never receives logs and filters[1:] never run (suppress with lint.ignore: drops_all_logs:outputs.debug)"
+	if warnings[0].String() != expected {
+		t.Errorf("Expected %q, got %q", expected, warnings[0].String())
+	}
+}
+
+func TestLinterConfigValidate(t *testing.T) {
+	valid := LinterConfig{Ignore: []string{LintNoDLQ, "unknown_source:outputs.debug"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid ignore list, got %v", err)
+	}
+
+	invalid := LinterConfig{Ignore: []string{"no_dql"}}
+	err := invalid.Validate()
+	if err == nil || !strings.Contains(err.Error(), "unknown warning code") {
+		t.Errorf("Expected an unknown code error, got %v", err)
+	}
+}
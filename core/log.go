@@ -6,11 +6,29 @@ import (
 
 // Log represents a standardized log entry
 type Log struct {
-	Timestamp time.Time         `json:"timestamp"`
-	Level     string            `json:"level"`
-	Message   string            `json:"message"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
-	Source    string            `json:"source,omitempty"` // Input plugin identifier
+	Timestamp  time.Time         `json:"timestamp"`
+	Level      string            `json:"level"`
+	Message    string            `json:"message"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Source     string            `json:"source,omitempty"`      // Input plugin identifier
+	SourceType string            `json:"source_type,omitempty"` // Input plugin type (e.g. "docker")
+	Tags       []string          `json:"tags,omitempty"`        // Classification tags (e.g. "alert", "audit")
+
+	// Distributed tracing context (see ParseTraceparent): lowercase hex, empty when unknown
+	TraceID    string `json:"trace_id,omitempty"`    // 32 hex digits
+	SpanID     string `json:"span_id,omitempty"`     // 16 hex digits
+	TraceFlags uint8  `json:"trace_flags,omitempty"` // W3C trace flags (bit 0: sampled)
+
+	trace    *logTrace // Stage times when the log is traced (see TraceConfig); never delivered
+	walSeq   uint64    // WAL sequence of a log replayed by recovery (0 = not replayed); such logs are not persisted again
+	injected bool      // Set by InjectLogs; counted as injected rather than processed, whatever the metadata says
+
+	receivedAt time.Time // When the engine received the log, the start of its delivery deadline (zero = no deadline)
+
+	encoded *encodeCache // JSON encodings shared by outputs while the log is dispatched (see SerializationCache)
+
+	ack      *Ack         // Set by inputs that acknowledge their source after delivery (see AckingInput)
+	delivery *ackDelivery // On a sync pipeline's copy: settles ack once the copy is delivered (see Log.settleAck)
 }
 
 // NewLog creates a new Log entry
@@ -29,3 +47,46 @@ func NewLogWithMetadata(level, message string, metadata map[string]string) *Log
 	log.Metadata = metadata
 	return log
 }
+
+// Clone returns a copy of the log entry with its own metadata map. The copy
+// does not share the original's encodings, since it is usually cloned to be changed.
+func (l *Log) Clone() *Log {
+	clone := *l
+	clone.encoded = nil
+	if l.Tags != nil {
+		clone.Tags = append([]string(nil), l.Tags...)
+	}
+	if l.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(l.Metadata))
+		for k, v := range l.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+	return &clone
+}
+
+// AddTags adds tags to the log entry, skipping empty and duplicate tags
+func (l *Log) AddTags(tags ...string) {
+	for _, tag := range tags {
+		if tag != "" && !l.HasTag(tag) {
+			l.Tags = append(l.Tags, tag)
+		}
+	}
+}
+
+// HasTag reports whether the log entry carries the given tag
+func (l *Log) HasTag(tag string) bool {
+	for _, t := range l.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectLevel infers a log level from keywords in a raw log line using the
+// active level vocabulary. It returns the vocabulary's default level when no
+// known keyword is present.
+func DetectLevel(line string) string {
+	return Levels().Detect(line)
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// DropReasonStale counts logs older than max_log_age, dropped before dispatch or buffered delivery
+const DropReasonStale = "stale"
+
+// isStale reports whether the log's event timestamp is older than maxAge at now.
+// A zero maxAge disables the check, and logs without a timestamp are never stale.
+func isStale(logEntry *Log, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 || logEntry.Timestamp.IsZero() {
+		return false
+	}
+	return now.Sub(logEntry.Timestamp) > maxAge
+}
+
+// SetMaxLogAge drops logs whose timestamp is older than maxAge instead of
+// delivering them (0 disables it); call it before output pipelines are added so
+// their buffers drop stale retries too
+func (e *Engine) SetMaxLogAge(maxAge time.Duration) {
+	e.maxLogAge = maxAge
+}
+
+// dropStale counts and reports a log that is too old to dispatch
+func (e *Engine) dropStale(logEntry *Log) bool {
+	if !isStale(logEntry, e.maxLogAge, time.Now()) {
+		return false
+	}
+	e.drops.Inc(DropReasonStale)
+	engineLog.Printf("Log DROPPED as older than max_log_age %s (timestamp %s)", e.maxLogAge, logEntry.Timestamp.Format(time.RFC3339))
+	return true
+}
+
+// dropStale counts a buffered log that became too old while it waited for delivery
+func (ob *OutputBuffer) dropStale(bufferedLog *BufferedLog) bool {
+	if !isStale(bufferedLog.Log, ob.maxLogAge, time.Now()) {
+		return false
+	}
+	bufferedLog.Log.settleAck(errors.New("dropped as older than max_log_age"))
+	ob.drops.Inc(DropReasonStale)
+	ob.statsMu.Lock()
+	ob.stats.TotalStale++
+	ob.statsMu.Unlock()
+	ob.logger().Printf("Dropping log older than max_log_age %s (timestamp %s, %d attempts)",
+		ob.maxLogAge, bufferedLog.Log.Timestamp.Format(time.RFC3339), bufferedLog.Attempts)
+	return true
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsStale(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		maxAge    time.Duration
+		expected  bool
+	}{
+		{"disabled", now.Add(-48 * time.Hour), 0, false},
+		{"within max age", now.Add(-59 * time.Minute), time.Hour, false},
+		{"exactly max age", now.Add(-time.Hour), time.Hour, false},
+		{"older than max age", now.Add(-61 * time.Minute), time.Hour, true},
+		{"in the future", now.Add(time.Hour), time.Hour, false},
+		{"no timestamp", time.Time{}, time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		logEntry := &Log{Timestamp: tt.timestamp, Level: "info"}
+		if got := isStale(logEntry, tt.maxAge, now); got != tt.expected {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestEngineMaxLogAge(t *testing.T) {
+	engine := NewEngine()
+	engine.SetMaxLogAge(time.Hour)
+
+	stale := NewLog("error", "replayed after an outage")
+	stale.Timestamp = time.Now().Add(-2 * time.Hour)
+	fresh := NewLog("error", "fresh")
+	engine.AddInput("app", newMockInput([]*Log{stale, fresh}))
+
+	output := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "out", Output: output}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	logs := output.getLogs()
+	if len(logs) != 1 || logs[0].Message != "fresh" {
+		t.Errorf("Expected only the fresh log to be delivered, got %d logs", len(logs))
+	}
+	if got := engine.Drops().Count(DropReasonStale); got != 1 {
+		t.Errorf("Expected 1 stale drop, got %d", got)
+	}
+}
+
+func TestOutputBufferDropsStaleLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	output := &MockOutput{}
+	output.SetShouldFail(true, 1)
+
+	config := retryOverflowConfig(tmpDir, 0, "")
+	config.RetryInterval = 10 * time.Millisecond
+	config.MaxRetryDelay = 10 * time.Millisecond
+	buffer, err := NewOutputBuffer("test", output, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	drops := NewDropCounter()
+	buffer.drops = drops
+	buffer.maxLogAge = 300 * time.Millisecond
+	defer func() { _ = buffer.Close() }()
+
+	// Already stale when it reaches the delivery worker
+	stale := NewLog("error", "stale")
+	stale.Timestamp = time.Now().Add(-time.Hour)
+	if err := buffer.Enqueue(stale); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	// Fails once, then ages out before the retry worker's next pass (every second)
+	if err := buffer.Enqueue(NewLog("error", "aging")); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for stats := buffer.GetStats(); (stats.TotalStale < 2 || stats.CurrentRetrying > 0) && time.Now().Before(deadline); stats = buffer.GetStats() {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	stats := buffer.GetStats()
+	if stats.TotalStale != 2 || stats.TotalDelivered != 0 || stats.CurrentRetrying != 0 {
+		t.Errorf("Expected both logs dropped as stale, got %+v", stats)
+	}
+	if got := drops.Count(DropReasonStale); got != 2 {
+		t.Errorf("Expected 2 stale drops, got %d", got)
+	}
+	if output.GetWriteCount() != 1 {
+		t.Errorf("Expected only the failed attempt to reach the output, got %d writes", output.GetWriteCount())
+	}
+}
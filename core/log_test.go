@@ -72,3 +72,47 @@ func TestLogTimestamp(t *testing.T) {
 		t.Error("Timestamp should be recent")
 	}
 }
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"ERROR: connection refused", "error"},
+		{"request failed: err=timeout", "error"},
+		{"Warning: high memory usage", "warn"},
+		{"WARN disk usage at 90%", "warn"},
+		{"DEBUG cache lookup", "debug"},
+		{"user logged in", "info"},
+		{"", "info"},
+	}
+
+	for _, tt := range tests {
+		if got := DetectLevel(tt.line); got != tt.expected {
+			t.Errorf("DetectLevel(%q) = %s, expected %s", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestLogTags(t *testing.T) {
+	log := NewLog("info", "test")
+
+	log.AddTags("audit", "", "alert", "audit")
+	log.AddTags("alert")
+
+	if len(log.Tags) != 2 {
+		t.Fatalf("Expected 2 de-duplicated tags, got %v", log.Tags)
+	}
+	if !log.HasTag("audit") || !log.HasTag("alert") {
+		t.Errorf("Expected tags audit and alert, got %v", log.Tags)
+	}
+	if log.HasTag("security") {
+		t.Error("Did not expect tag security")
+	}
+
+	clone := log.Clone()
+	clone.AddTags("security")
+	if log.HasTag("security") {
+		t.Error("Tags added to a clone must not affect the original")
+	}
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"strings"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Metadata storage modes
+const (
+	MetadataStorageMap     = "map"     // Every log keeps the strings it was created with (default)
+	MetadataStorageCompact = "compact" // Repeated metadata keys and values share one copy
+
+	DefaultMaxInterned       = 10000 // Strings kept by the intern pool
+	DefaultMaxInternedLength = 64    // Longer strings are rarely repeated and are never interned
+)
+
+// MetadataStorageConfig configures how log metadata is held in memory. In compact
+// mode the engine interns metadata keys and values: a log whose metadata repeats
+// strings seen before (hosts, levels, status codes, field names) points to one
+// shared copy instead of holding its own, which cuts the memory of queued, held
+// and buffered logs. Metadata stays a map[string]string, so filters and outputs
+// are unaffected.
+type MetadataStorageConfig struct {
+	Mode        string `yaml:"mode,omitempty"`         // "map" (default) or "compact"
+	MaxInterned int    `yaml:"max_interned,omitempty"` // Bound of the intern pool (default: 10000)
+	MaxLength   int    `yaml:"max_length,omitempty"`   // Longest string interned, in bytes (default: 64)
+}
+
+// Validate validates the MetadataStorageConfig
+func (c MetadataStorageConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Mode, validation.In(MetadataStorageMap, MetadataStorageCompact).Error("must be 'map' or 'compact'")),
+		validation.Field(&c.MaxInterned, validation.Min(0).Error("must be no less than 0")),
+		validation.Field(&c.MaxLength, validation.Min(0).Error("must be no less than 0")),
+	)
+}
+
+// MetadataStorageStats reports the intern pool of the compact metadata storage
+type MetadataStorageStats struct {
+	Mode      string `json:"mode"`
+	Interned  int    `json:"interned"`  // Strings currently in the pool
+	Hits      int64  `json:"hits"`      // Strings replaced by a pooled copy
+	Misses    int64  `json:"misses"`    // Strings added to the pool
+	Skipped   int64  `json:"skipped"`   // Strings longer than max_length
+	Rotations int64  `json:"rotations"` // Times the pool retired its older half
+}
+
+// internPool is a bounded string pool. It holds two generations of at most half
+// the bound each: when the current one is full it becomes the previous one and
+// the old previous generation is released. Strings still in use move back into
+// the current generation on their next lookup, while high-cardinality values
+// (request IDs, timestamps) age out instead of growing the pool forever.
+type internPool struct {
+	mu        sync.Mutex
+	current   map[string]string
+	previous  map[string]string
+	limit     int // Strings per generation
+	maxLength int
+
+	hits, misses, skipped, rotations int64
+}
+
+// newInternPool returns the pool for a configuration, or nil unless the mode is compact
+func newInternPool(config MetadataStorageConfig) *internPool {
+	if config.Mode != MetadataStorageCompact {
+		return nil
+	}
+	maxInterned := config.MaxInterned
+	if maxInterned <= 0 {
+		maxInterned = DefaultMaxInterned
+	}
+	maxLength := config.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxInternedLength
+	}
+	limit := max(maxInterned/2, 1)
+	return &internPool{
+		current:   make(map[string]string, limit),
+		limit:     limit,
+		maxLength: maxLength,
+	}
+}
+
+// internMetadata replaces the log's metadata keys and values with pooled copies
+func (p *internPool) internMetadata(logEntry *Log) {
+	if len(logEntry.Metadata) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, value := range logEntry.Metadata {
+		// Assigning to an existing key also replaces the stored key string
+		logEntry.Metadata[p.intern(key)] = p.intern(value)
+	}
+}
+
+// intern returns the pooled copy of s, adding it when missing; p.mu must be held
+func (p *internPool) intern(s string) string {
+	if s == "" {
+		return s
+	}
+	if len(s) > p.maxLength {
+		p.skipped++
+		return s
+	}
+	if pooled, ok := p.current[s]; ok {
+		p.hits++
+		return pooled
+	}
+	if pooled, ok := p.previous[s]; ok {
+		p.hits++
+		p.add(pooled)
+		return pooled
+	}
+
+	// Copy the string: values parsed out of a line are substrings of it, and
+	// pooling the substring would keep the whole line alive
+	p.misses++
+	pooled := strings.Clone(s)
+	p.add(pooled)
+	return pooled
+}
+
+// add stores s in the current generation, retiring the previous one when full
+func (p *internPool) add(s string) {
+	if len(p.current) >= p.limit {
+		p.previous = p.current
+		p.current = make(map[string]string, p.limit)
+		p.rotations++
+	}
+	p.current[s] = s
+}
+
+// stats returns a snapshot of the pool counters
+func (p *internPool) stats() MetadataStorageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return MetadataStorageStats{
+		Mode:      MetadataStorageCompact,
+		Interned:  len(p.current) + len(p.previous),
+		Hits:      p.hits,
+		Misses:    p.misses,
+		Skipped:   p.skipped,
+		Rotations: p.rotations,
+	}
+}
+
+// SetMetadataStorage configures how log metadata is held in memory; call it before Start
+func (e *Engine) SetMetadataStorage(config MetadataStorageConfig) {
+	e.internPool = newInternPool(config)
+}
+
+// internMetadata interns a log's metadata in compact mode
+func (e *Engine) internMetadata(logEntry *Log) {
+	if e.internPool != nil {
+		e.internPool.internMetadata(logEntry)
+	}
+}
+
+// metadataStorageStatus returns the intern pool counters, or nil outside compact mode
+func (e *Engine) metadataStorageStatus() *MetadataStorageStats {
+	if e.internPool == nil {
+		return nil
+	}
+	stats := e.internPool.stats()
+	return &stats
+}
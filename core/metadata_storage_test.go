@@ -0,0 +1,194 @@
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// sameString reports whether two strings share their bytes
+func sameString(a, b string) bool {
+	return unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func TestInternPoolSharesStrings(t *testing.T) {
+	pool := newInternPool(MetadataStorageConfig{Mode: MetadataStorageCompact})
+
+	// Build equal strings with separate backing arrays, as parsing lines would
+	first := NewLogWithMetadata("info", "a", map[string]string{fmt.Sprint("ho", "st"): fmt.Sprint("web-", 1)})
+	second := NewLogWithMetadata("info", "b", map[string]string{fmt.Sprint("ho", "st"): fmt.Sprint("web-", 1)})
+	pool.internMetadata(first)
+	pool.internMetadata(second)
+
+	var firstKey, secondKey string
+	for key := range first.Metadata {
+		firstKey = key
+	}
+	for key := range second.Metadata {
+		secondKey = key
+	}
+	if !sameString(firstKey, secondKey) || !sameString(first.Metadata["host"], second.Metadata["host"]) {
+		t.Error("Expected both logs to share the interned key and value")
+	}
+	if first.Metadata["host"] != "web-1" || len(first.Metadata) != 1 {
+		t.Errorf("Expected metadata to be unchanged, got %v", first.Metadata)
+	}
+
+	stats := pool.stats()
+	if stats.Interned != 2 || stats.Misses != 2 || stats.Hits != 2 {
+		t.Errorf("Unexpected pool stats %+v", stats)
+	}
+}
+
+func TestInternPoolCopiesSubstrings(t *testing.T) {
+	pool := newInternPool(MetadataStorageConfig{Mode: MetadataStorageCompact})
+
+	line := "user=alice " + strings.Repeat("x", 4096)
+	logEntry := NewLogWithMetadata("info", line, map[string]string{"user": line[5:10]})
+	pool.internMetadata(logEntry)
+
+	// The pooled value must not point into the line, or the pool would keep it alive
+	value := logEntry.Metadata["user"]
+	if value != "alice" || sameString(value, line[5:10]) {
+		t.Errorf("Expected a detached copy of the substring, got %q", value)
+	}
+}
+
+func TestInternPoolBounded(t *testing.T) {
+	pool := newInternPool(MetadataStorageConfig{Mode: MetadataStorageCompact, MaxInterned: 100, MaxLength: 16})
+
+	first := NewLogWithMetadata("info", "", map[string]string{"method": fmt.Sprint("GE", "T")})
+	pool.internMetadata(first)
+	pooled := first.Metadata["method"]
+
+	for i := range 10000 {
+		logEntry := NewLogWithMetadata("info", "", map[string]string{
+			"method":     fmt.Sprint("GE", "T"),
+			"request_id": fmt.Sprintf("req-%d", i),
+			"payload":    strings.Repeat("p", 17),
+		})
+		pool.internMetadata(logEntry)
+		if !sameString(logEntry.Metadata["method"], pooled) {
+			t.Fatalf("Expected a value in use to survive rotation at log %d", i)
+		}
+	}
+
+	// High-cardinality values age out instead of growing the pool
+	stats := pool.stats()
+	if stats.Interned > 100 || stats.Rotations == 0 {
+		t.Errorf("Expected the pool to stay within 100 strings, got %+v", stats)
+	}
+	if stats.Skipped != 10000 {
+		t.Errorf("Expected values over max_length to be skipped, got %d", stats.Skipped)
+	}
+}
+
+// userFilter copies the second word of the message into metadata, like parsing filters do
+type userFilter struct{}
+
+func (f *userFilter) Process(log *Log) bool {
+	log.Metadata["user"] = strings.Fields(log.Message)[1]
+	return true
+}
+func (f *userFilter) Mutates() bool { return true }
+
+func TestEngineCompactMetadataStorage(t *testing.T) {
+	engine := NewEngine()
+	engine.SetMetadataStorage(MetadataStorageConfig{Mode: MetadataStorageCompact})
+	output := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "users", Output: output, Filters: []FilterPlugin{&userFilter{}}}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	for i := range 3 {
+		logEntry := NewLogWithMetadata("info", fmt.Sprintf("login alice %d", i), map[string]string{"host": fmt.Sprint("web-", 1)})
+		engine.receiveLog(logEntry)
+		engine.dispatchLog(logEntry)
+	}
+	engine.Stop()
+
+	logs := output.getLogs()
+	if len(logs) != 3 {
+		t.Fatalf("Expected 3 logs, got %d", len(logs))
+	}
+	for _, logEntry := range logs[1:] {
+		if !sameString(logEntry.Metadata["host"], logs[0].Metadata["host"]) {
+			t.Error("Expected input metadata to be interned")
+		}
+		if logEntry.Metadata["user"] != "alice" || !sameString(logEntry.Metadata["user"], logs[0].Metadata["user"]) {
+			t.Errorf("Expected metadata added by filters to be interned, got %v", logEntry.Metadata)
+		}
+	}
+
+	stats := engine.Stats().MetadataStorage
+	if stats == nil || stats.Hits == 0 {
+		t.Errorf("Expected pool stats in compact mode, got %+v", stats)
+	}
+	if NewEngine().Stats().MetadataStorage != nil {
+		t.Error("Expected no pool stats in map mode")
+	}
+}
+
+func TestMetadataStorageConfigValidate(t *testing.T) {
+	tests := []struct {
+		config  MetadataStorageConfig
+		wantErr bool
+	}{
+		{MetadataStorageConfig{}, false},
+		{MetadataStorageConfig{Mode: MetadataStorageMap}, false},
+		{MetadataStorageConfig{Mode: MetadataStorageCompact, MaxInterned: 50000, MaxLength: 128}, false},
+		{MetadataStorageConfig{Mode: "slice"}, true},
+		{MetadataStorageConfig{Mode: MetadataStorageCompact, MaxInterned: -1}, true},
+		{MetadataStorageConfig{Mode: MetadataStorageCompact, MaxLength: -1}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+		}
+	}
+}
+
+// BenchmarkMetadataStorage compares the memory retained by queued logs with and
+// without interning. Metadata is parsed out of each line, so in map mode every
+// log holds its own copies.
+func BenchmarkMetadataStorage(b *testing.B) {
+	const queued = 10000
+
+	for _, mode := range []string{MetadataStorageMap, MetadataStorageCompact} {
+		b.Run(mode, func(b *testing.B) {
+			b.ReportAllocs()
+			var retained uint64
+			for range b.N {
+				pool := newInternPool(MetadataStorageConfig{Mode: mode})
+				logs := make([]*Log, queued)
+
+				var before runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				for i := range logs {
+					line := fmt.Sprintf("host=web-%d method=GET status=200 user=user-%d path=/api/orders", i%8, i%50)
+					metadata := make(map[string]string, 5)
+					for _, field := range strings.Fields(line) {
+						key, value, _ := strings.Cut(field, "=")
+						metadata[strings.Clone(key)] = strings.Clone(value)
+					}
+					logs[i] = NewLogWithMetadata("info", "request served", metadata)
+					if pool != nil {
+						pool.internMetadata(logs[i])
+					}
+				}
+
+				var after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained += after.HeapAlloc - min(before.HeapAlloc, after.HeapAlloc)
+				runtime.KeepAlive(logs)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N*queued), "retained-B/log")
+		})
+	}
+}
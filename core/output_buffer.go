@@ -1,15 +1,17 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
 )
 
 // OutputBufferConfig defines output buffer configuration
@@ -20,15 +22,34 @@ type OutputBufferConfig struct {
 	MaxRetries    int           `yaml:"max_retries"`     // Max retry attempts
 	RetryInterval time.Duration `yaml:"retry_interval"`  // Initial retry interval
 	MaxRetryDelay time.Duration `yaml:"max_retry_delay"` // Max backoff delay
+	RetryJitter   string        `yaml:"retry_jitter"`    // Randomize backoff delays: none (default), full or equal
 	FlushInterval time.Duration `yaml:"flush_interval"`  // How often to flush to disk
-	DLQEnabled    bool          `yaml:"dlq_enabled"`     // Enable Dead Letter Queue
-	DLQPath       string        `yaml:"dlq_path"`        // Path for DLQ file
+	// How often the delivery and retry queues are snapshotted to disk, bounding what a
+	// hard kill loses (0 = only the retry queue, every flush_interval)
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"`
+	DLQEnabled       bool          `yaml:"dlq_enabled"` // Enable Dead Letter Queue
+	DLQPath          string        `yaml:"dlq_path"`    // Path for DLQ file
+
+	// Delivery workers draining the queue of each output (0 = 1). More than one
+	// requires an output that is safe for concurrent Write and makes ordering best-effort.
+	DeliveryConcurrency int `yaml:"delivery_concurrency"`
+
+	// Retry queue bound (zero = unbounded)
+	MaxRetryQueueSize  int    `yaml:"max_retry_queue_size"` // Max logs in the in-memory retry queue per output
+	RetryQueueOverflow string `yaml:"retry_queue_overflow"` // What happens to the oldest logs beyond it: spill (default) or dlq
+
+	// DLQ rotation and pruning (zero disables each limit)
+	DLQMaxSize      int64         `yaml:"dlq_max_size"`      // Rotate the DLQ file when it reaches this many bytes
+	DLQMaxAge       time.Duration `yaml:"dlq_max_age"`       // Rotate the DLQ file after this long and prune older segments
+	DLQMaxSegments  int           `yaml:"dlq_max_segments"`  // Max rotated segments kept per output
+	DLQMinRetention time.Duration `yaml:"dlq_min_retention"` // Segments younger than this are never pruned
 }
 
 // Validate validates the OutputBufferConfig
 func (o OutputBufferConfig) Validate() error {
 	// If output buffering is not enabled and all fields are zero/default, skip validation
-	if !o.Enabled && o.Dir == "" && o.MaxQueueSize == 0 && o.MaxRetries == 0 && o.RetryInterval == 0 && o.MaxRetryDelay == 0 && o.FlushInterval == 0 && !o.DLQEnabled && o.DLQPath == "" {
+	if !o.Enabled && o.Dir == "" && o.MaxQueueSize == 0 && o.MaxRetries == 0 && o.RetryInterval == 0 && o.MaxRetryDelay == 0 && o.FlushInterval == 0 && o.SnapshotInterval == 0 && !o.DLQEnabled && o.DLQPath == "" && o.RetryJitter == "" &&
+		o.DeliveryConcurrency == 0 && o.MaxRetryQueueSize == 0 && o.RetryQueueOverflow == "" && o.DLQMaxSize == 0 && o.DLQMaxAge == 0 && o.DLQMaxSegments == 0 && o.DLQMinRetention == 0 {
 		return nil
 	}
 	return validation.ValidateStruct(&o,
@@ -47,10 +68,35 @@ func (o OutputBufferConfig) Validate() error {
 		validation.Field(&o.RetryInterval, validation.Min(time.Millisecond).Error("must be no less than 1ms"), validation.Max(time.Hour).Error("must be no greater than 1h0m0s")),
 		validation.Field(&o.MaxRetryDelay, validation.Min(time.Millisecond).Error("must be no less than 1ms"), validation.Max(24*time.Hour).Error("must be no greater than 24h0m0s")),
 		validation.Field(&o.FlushInterval, validation.Min(time.Millisecond).Error("must be no less than 1ms"), validation.Max(time.Hour).Error("must be no greater than 1h0m0s")),
+		validation.Field(&o.SnapshotInterval, validation.When(o.SnapshotInterval != 0,
+			validation.Min(MinSnapshotInterval).Error(fmt.Sprintf("must be no less than %v", MinSnapshotInterval)),
+			validation.Max(time.Hour).Error("must be no greater than 1h0m0s"))),
+		validation.Field(&o.RetryJitter, validation.By(func(value interface{}) error {
+			return ValidateJitter(value.(string))
+		})),
 		validation.Field(&o.DLQPath, validation.Length(0, 500).Error("the length must be no more than 500")),
+		validation.Field(&o.DeliveryConcurrency, validation.Min(0).Error("must be no less than 0"),
+			validation.Max(MaxDeliveryConcurrency).Error(fmt.Sprintf("must be no greater than %d", MaxDeliveryConcurrency))),
+		validation.Field(&o.MaxRetryQueueSize, validation.Min(0).Error("must be no less than 0")),
+		validation.Field(&o.RetryQueueOverflow, validation.In(RetryOverflowSpill, RetryOverflowDLQ).Error("must be spill or dlq")),
+		validation.Field(&o.DLQMaxSize, validation.Min(int64(0)).Error("must be no less than 0")),
+		validation.Field(&o.DLQMaxAge, validation.Min(time.Duration(0)).Error("must be no less than 0")),
+		validation.Field(&o.DLQMaxSegments, validation.Min(0).Error("must be no less than 0")),
+		validation.Field(&o.DLQMinRetention, validation.Min(time.Duration(0)).Error("must be no less than 0")),
 	)
 }
 
+// MaxDeliveryConcurrency bounds the delivery workers of a single output
+const MaxDeliveryConcurrency = 64
+
+// deliveryWorkers returns the number of delivery workers to start
+func (o OutputBufferConfig) deliveryWorkers() int {
+	if o.DeliveryConcurrency < 1 {
+		return 1
+	}
+	return o.DeliveryConcurrency
+}
+
 // DefaultOutputBufferConfig returns default output buffer configuration
 func DefaultOutputBufferConfig() OutputBufferConfig {
 	return OutputBufferConfig{
@@ -63,16 +109,21 @@ func DefaultOutputBufferConfig() OutputBufferConfig {
 		FlushInterval: 10 * time.Second,
 		DLQEnabled:    true,
 		DLQPath:       "./data/dlq",
+
+		DeliveryConcurrency: 1,
 	}
 }
 
 // BufferedLog represents a log with retry metadata
 type BufferedLog struct {
-	Log         *Log      `json:"log"`
-	Attempts    int       `json:"attempts"`
-	LastAttempt time.Time `json:"last_attempt"`
-	OutputName  string    `json:"output_name"`
-	EnqueuedAt  time.Time `json:"enqueued_at"`
+	Log         *Log          `json:"log"`
+	Attempts    int           `json:"attempts"`
+	LastAttempt time.Time     `json:"last_attempt"`
+	RetryDelay  time.Duration `json:"retry_delay,omitempty"` // Backoff (with jitter) chosen after the last failed attempt
+	OutputName  string        `json:"output_name"`
+	EnqueuedAt  time.Time     `json:"enqueued_at"`
+	DLQReason   string        `json:"dlq_reason,omitempty"` // Why the log was dead-lettered before exhausting its retries
+	Deadline    time.Time     `json:"deadline,omitempty"`   // Retries stop once this passes (zero = no deadline)
 }
 
 // OutputBuffer manages output buffering with persistence and retry logic
@@ -86,10 +137,30 @@ type OutputBuffer struct {
 	stopCh      chan struct{}
 	wg          sync.WaitGroup
 	dlqFile     *os.File
+	dlqSize     int64         // Bytes in the active DLQ file
+	dlqStarted  time.Time     // When the active DLQ file was started
+	drops       *DropCounter  // Engine drop counter (nil when used standalone)
+	random      JitterSource  // Randomness for retry jitter (nil = default source)
+	budget      *DiskBudget   // Shared disk budget (nil = unlimited)
+	maxLogAge   time.Duration // Drop logs older than this instead of delivering them (0 = disabled)
+	deadline    time.Duration // Delivery deadline budget from the log's receipt (0 = disabled)
+	onExpired   func(*Log)    // Counts and reroutes retried logs that missed their deadline (nil = never expire)
+	retrySize   int64         // Bytes in the persisted retry queue file, guarded by retryMu
 	dlqMu       sync.Mutex
 	flushTicker *time.Ticker
-	stats       BufferStats
-	statsMu     sync.RWMutex
+
+	// Logs in the delivery queue or being delivered, with their snapshot line once
+	// encoded (nil without snapshot_interval)
+	inflight       map[*BufferedLog][]byte
+	inflightMu     sync.Mutex
+	snapshotTicker *time.Ticker
+	stats          BufferStats
+	statsMu        sync.RWMutex
+}
+
+// logger returns the internal logger for this buffer, named after its output
+func (ob *OutputBuffer) logger() *logging.Logger {
+	return bufferLog.Named(ob.outputName)
 }
 
 // BufferStats tracks buffer statistics
@@ -99,12 +170,20 @@ type BufferStats struct {
 	TotalRetried    int64
 	TotalFailed     int64
 	TotalDLQ        int64
+	TotalSpilled    int64 // Logs moved from a full retry queue to disk
+	TotalStale      int64 // Logs dropped as older than max_log_age before delivery
+	TotalExpired    int64 // Retried logs dropped after missing their delivery deadline
 	CurrentQueued   int
 	CurrentRetrying int
 }
 
 // NewOutputBuffer creates a new output buffer
 func NewOutputBuffer(outputName string, output OutputPlugin, config OutputBufferConfig) (*OutputBuffer, error) {
+	return newOutputBuffer(outputName, output, config, nil)
+}
+
+// newOutputBuffer creates an output buffer whose buffer and DLQ files count against a disk budget
+func newOutputBuffer(outputName string, output OutputPlugin, config OutputBufferConfig, budget *DiskBudget) (*OutputBuffer, error) {
 	if !config.Enabled {
 		return &OutputBuffer{
 			config:     config,
@@ -135,30 +214,37 @@ func NewOutputBuffer(outputName string, output OutputPlugin, config OutputBuffer
 		retryQueue:  make([]*BufferedLog, 0),
 		stopCh:      make(chan struct{}),
 		flushTicker: time.NewTicker(config.FlushInterval),
+		budget:      budget,
+	}
+	if config.SnapshotInterval > 0 {
+		ob.inflight = make(map[*BufferedLog][]byte)
+		ob.snapshotTicker = time.NewTicker(config.SnapshotInterval)
 	}
+	ob.trackDiskUsage()
 
 	// Open DLQ file if enabled
 	if config.DLQEnabled {
-		dlqPath := filepath.Join(config.DLQPath, fmt.Sprintf("%s-dlq.jsonl", outputName))
-		file, err := os.OpenFile(dlqPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) // #nosec G304 - path constructed from controlled inputs
-		if err != nil {
-			return nil, fmt.Errorf("failed to open DLQ file: %w", err)
+		if err := ob.openDLQ(); err != nil {
+			return nil, err
 		}
-		ob.dlqFile = file
+		ob.maintainDLQ()
 	}
 
 	// Load persisted logs from disk
 	if err := ob.loadPersistedLogs(); err != nil {
-		log.Printf("[BUFFER:%s] Error loading persisted logs: %v", outputName, err)
+		ob.logger().Printf("Error loading persisted logs: %v", err)
 	}
 
 	// Start worker goroutines
-	ob.wg.Add(2)
-	go ob.deliveryWorker()
+	workers := config.deliveryWorkers()
+	ob.wg.Add(workers + 1)
+	for range workers {
+		go ob.deliveryWorker()
+	}
 	go ob.retryWorker()
 
-	log.Printf("[BUFFER:%s] Output buffer initialized: queue=%d, retries=%d, dlq=%v",
-		outputName, config.MaxQueueSize, config.MaxRetries, config.DLQEnabled)
+	ob.logger().Printf("Output buffer initialized: queue=%d, retries=%d, dlq=%v, workers=%d",
+		config.MaxQueueSize, config.MaxRetries, config.DLQEnabled, workers)
 
 	return ob, nil
 }
@@ -176,6 +262,7 @@ func (ob *OutputBuffer) Enqueue(logEntry *Log) error {
 		LastAttempt: time.Time{},
 		OutputName:  ob.outputName,
 		EnqueuedAt:  time.Now(),
+		Deadline:    deadlineOf(logEntry, ob.deadline),
 	}
 
 	ob.statsMu.Lock()
@@ -183,23 +270,32 @@ func (ob *OutputBuffer) Enqueue(logEntry *Log) error {
 	ob.stats.CurrentQueued++
 	ob.statsMu.Unlock()
 
+	// Tracked before it can be dequeued, so every queued log is in the next snapshot
+	ob.trackInflight(bufferedLog)
 	select {
 	case ob.queue <- bufferedLog:
 		return nil
 	case <-time.After(100 * time.Millisecond):
 		// Queue is full or blocked, persist to disk
+		ob.settleInflight(bufferedLog)
 		ob.statsMu.Lock()
 		ob.stats.CurrentQueued--
 		ob.statsMu.Unlock()
-		return ob.persistLog(bufferedLog)
+		if err := ob.persistLog(bufferedLog); err != nil {
+			return err
+		}
+		logEntry.settleAck(errAckSpilled)
+		return nil
 	}
 }
 
-// deliveryWorker processes logs from the main queue
+// deliveryWorker processes logs from the main queue. With delivery_concurrency
+// above 1 several workers drain the queue at once, so logs can reach the output
+// out of order.
 func (ob *OutputBuffer) deliveryWorker() {
 	defer ob.wg.Done()
 
-	log.Printf("[BUFFER:%s] Delivery worker started", ob.outputName)
+	ob.logger().Printf("Delivery worker started")
 
 	for {
 		select {
@@ -208,21 +304,28 @@ func (ob *OutputBuffer) deliveryWorker() {
 			ob.stats.CurrentQueued--
 			ob.statsMu.Unlock()
 
-			log.Printf("[BUFFER:%s] Attempting delivery (attempt %d)", ob.outputName, bufferedLog.Attempts+1)
+			if ob.dropStale(bufferedLog) {
+				ob.settleInflight(bufferedLog)
+				continue
+			}
+
+			ob.logger().Printf("Attempting delivery (attempt %d)", bufferedLog.Attempts+1)
 
 			if err := ob.deliverLog(bufferedLog); err != nil {
-				log.Printf("[BUFFER:%s] Delivery failed: %v (attempt %d/%d)",
-					ob.outputName, err, bufferedLog.Attempts, ob.config.MaxRetries)
+				ob.logger().Printf("Delivery failed: %v (attempt %d/%d)",
+					err, bufferedLog.Attempts, ob.config.MaxRetries)
 				ob.requeueForRetry(bufferedLog)
 			} else {
 				ob.statsMu.Lock()
 				ob.stats.TotalDelivered++
 				ob.statsMu.Unlock()
-				log.Printf("[BUFFER:%s] Delivery successful", ob.outputName)
+				bufferedLog.Log.settleAck(nil)
+				ob.logger().Printf("Delivery successful")
 			}
+			ob.settleInflight(bufferedLog)
 
 		case <-ob.stopCh:
-			log.Printf("[BUFFER:%s] Delivery worker stopping", ob.outputName)
+			ob.logger().Printf("Delivery worker stopping")
 			return
 		}
 	}
@@ -232,7 +335,7 @@ func (ob *OutputBuffer) deliveryWorker() {
 func (ob *OutputBuffer) retryWorker() {
 	defer ob.wg.Done()
 
-	log.Printf("[BUFFER:%s] Retry worker started", ob.outputName)
+	ob.logger().Printf("Retry worker started")
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -244,9 +347,13 @@ func (ob *OutputBuffer) retryWorker() {
 
 		case <-ob.flushTicker.C:
 			ob.persistRetryQueue()
+			ob.maintainDLQ()
+
+		case <-ob.snapshotC():
+			ob.persistRetryQueue()
 
 		case <-ob.stopCh:
-			log.Printf("[BUFFER:%s] Retry worker stopping", ob.outputName)
+			ob.logger().Printf("Retry worker stopping")
 			return
 		}
 	}
@@ -259,7 +366,7 @@ func (ob *OutputBuffer) processRetries() {
 	ob.retryMu.Unlock()
 
 	if queueSize > 0 {
-		log.Printf("[BUFFER:%s] Processing %d logs in retry queue", ob.outputName, queueSize)
+		ob.logger().Printf("Processing %d logs in retry queue", queueSize)
 	}
 
 	ob.retryMu.Lock()
@@ -269,8 +376,11 @@ func (ob *OutputBuffer) processRetries() {
 	remaining := make([]*BufferedLog, 0)
 
 	for _, bufferedLog := range ob.retryQueue {
-		// Calculate backoff delay
-		backoff := ob.calculateBackoff(bufferedLog.Attempts)
+		// Use the delay chosen when the attempt failed (logs persisted by older versions have none)
+		backoff := bufferedLog.RetryDelay
+		if backoff == 0 {
+			backoff = ob.calculateBackoff(bufferedLog.Attempts)
+		}
 		nextAttempt := bufferedLog.LastAttempt.Add(backoff)
 
 		if now.Before(nextAttempt) {
@@ -279,27 +389,33 @@ func (ob *OutputBuffer) processRetries() {
 			continue
 		}
 
-		log.Printf("[BUFFER:%s] Retrying log (attempt %d/%d, backoff: %v)",
-			ob.outputName, bufferedLog.Attempts, ob.config.MaxRetries, backoff)
+		// A log that aged out or missed its deadline while waiting is dropped rather than retried
+		if ob.dropStale(bufferedLog) || ob.dropExpired(bufferedLog) {
+			continue
+		}
+
+		ob.logger().Printf("Retrying log (attempt %d/%d, backoff: %v)",
+			bufferedLog.Attempts, ob.config.MaxRetries, backoff)
 
 		// Try delivery
 		if err := ob.deliverLog(bufferedLog); err != nil {
-			log.Printf("[BUFFER:%s] Retry failed: %v (attempt %d/%d)",
-				ob.outputName, err, bufferedLog.Attempts, ob.config.MaxRetries)
+			ob.logger().Printf("Retry failed: %v (attempt %d/%d)",
+				err, bufferedLog.Attempts, ob.config.MaxRetries)
 
 			if bufferedLog.Attempts >= ob.config.MaxRetries {
 				// Max retries reached, send to DLQ
-				log.Printf("[BUFFER:%s] Max retries reached, sending to DLQ", ob.outputName)
+				ob.logger().Printf("Max retries reached, sending to DLQ")
 				ob.sendToDLQ(bufferedLog)
 			} else {
 				// Requeue for another retry
 				remaining = append(remaining, bufferedLog)
 			}
 		} else {
-			log.Printf("[BUFFER:%s] Retry successful!", ob.outputName)
+			ob.logger().Printf("Retry successful!")
 			ob.statsMu.Lock()
 			ob.stats.TotalDelivered++
 			ob.statsMu.Unlock()
+			bufferedLog.Log.settleAck(nil)
 		}
 	}
 
@@ -314,7 +430,12 @@ func (ob *OutputBuffer) deliverLog(bufferedLog *BufferedLog) error {
 	bufferedLog.Attempts++
 	bufferedLog.LastAttempt = time.Now()
 
-	return ob.output.Write(bufferedLog.Log)
+	err := ob.output.Write(bufferedLog.Log)
+	if err != nil {
+		// Pick the delay once per attempt so the retry loop does not re-roll the jitter on every pass
+		bufferedLog.RetryDelay = ApplyJitter(ob.calculateBackoff(bufferedLog.Attempts), ob.config.RetryJitter, ob.random)
+	}
+	return err
 }
 
 // requeueForRetry adds a log to the retry queue
@@ -328,6 +449,8 @@ func (ob *OutputBuffer) requeueForRetry(bufferedLog *BufferedLog) {
 	ob.stats.TotalRetried++
 	ob.stats.CurrentRetrying++
 	ob.statsMu.Unlock()
+
+	ob.enforceRetryLimit()
 }
 
 // calculateBackoff calculates exponential backoff delay
@@ -366,26 +489,54 @@ func (ob *OutputBuffer) calculateBackoff(attempts int) time.Duration {
 }
 
 // sendToDLQ writes a log to the Dead Letter Queue
+// Rotation happens under dlqMu, so concurrent writers never write to a renamed segment.
 func (ob *OutputBuffer) sendToDLQ(bufferedLog *BufferedLog) {
+	bufferedLog.Log.settleAck(fmt.Errorf("not delivered after %d attempts", bufferedLog.Attempts))
+
+	ob.dlqMu.Lock()
+	defer ob.dlqMu.Unlock()
+
 	if !ob.config.DLQEnabled || ob.dlqFile == nil {
 		ob.statsMu.Lock()
 		ob.stats.TotalFailed++
 		ob.statsMu.Unlock()
-		log.Printf("[BUFFER:%s] Log failed permanently (DLQ disabled)", ob.outputName)
+		ob.drops.Inc(DropReasonDeliveryFailed)
+		ob.logger().Printf("Log failed permanently (DLQ disabled)")
 		return
 	}
 
-	ob.dlqMu.Lock()
-	defer ob.dlqMu.Unlock()
-
 	data, err := json.Marshal(bufferedLog)
 	if err != nil {
-		log.Printf("[BUFFER:%s] Error marshaling DLQ entry: %v", ob.outputName, err)
+		ob.drops.Inc(DropReasonDeliveryFailed)
+		ob.logger().Printf("Error marshaling DLQ entry: %v", err)
+		return
+	}
+
+	data = append(data, '\n')
+	if now := time.Now(); ob.shouldRotateDLQ(len(data), now) {
+		if err := ob.rotateDLQ(now); err != nil {
+			ob.logger().Printf("%v", err)
+		}
+		if ob.dlqFile == nil {
+			ob.drops.Inc(DropReasonDeliveryFailed)
+			return
+		}
+	}
+
+	if !ob.budget.Reserve(int64(len(data)), "dlq "+ob.outputName) {
+		ob.statsMu.Lock()
+		ob.stats.TotalFailed++
+		ob.statsMu.Unlock()
+		ob.drops.Inc(DropReasonDiskBudget)
 		return
 	}
 
-	if _, err := ob.dlqFile.Write(append(data, '\n')); err != nil {
-		log.Printf("[BUFFER:%s] Error writing to DLQ: %v", ob.outputName, err)
+	n, err := ob.dlqFile.Write(data)
+	ob.dlqSize += int64(n)
+	ob.budget.Release(int64(len(data) - n))
+	if err != nil {
+		ob.drops.Inc(DropReasonDeliveryFailed)
+		ob.logger().Printf("Error writing to DLQ: %v", err)
 		return
 	}
 
@@ -393,54 +544,86 @@ func (ob *OutputBuffer) sendToDLQ(bufferedLog *BufferedLog) {
 	ob.stats.TotalDLQ++
 	ob.statsMu.Unlock()
 
-	log.Printf("[BUFFER:%s] Log sent to DLQ after %d failed attempts", ob.outputName, bufferedLog.Attempts)
+	if bufferedLog.DLQReason != "" {
+		ob.logger().Printf("Log sent to DLQ: %s (%d failed attempts)", bufferedLog.DLQReason, bufferedLog.Attempts)
+	} else {
+		ob.logger().Printf("Log sent to DLQ after %d failed attempts", bufferedLog.Attempts)
+	}
 }
 
 // persistLog saves a log to disk when the queue is full
 func (ob *OutputBuffer) persistLog(bufferedLog *BufferedLog) error {
-	filename := filepath.Join(ob.config.Dir, ob.outputName, fmt.Sprintf("buffer-%d.jsonl", time.Now().UnixNano()))
+	return ob.writeBufferFile("buffer", bufferedLog)
+}
 
-	data, err := json.Marshal(bufferedLog)
-	if err != nil {
-		return fmt.Errorf("failed to marshal log: %w", err)
+// writeBufferFile saves logs as JSON lines to a new file in the buffer directory,
+// where loadPersistedLogs picks them up on the next start
+func (ob *OutputBuffer) writeBufferFile(prefix string, logs ...*BufferedLog) error {
+	filename := filepath.Join(ob.config.Dir, ob.outputName, fmt.Sprintf("%s-%d.jsonl", prefix, time.Now().UnixNano()))
+
+	var data []byte
+	for _, bufferedLog := range logs {
+		line, err := json.Marshal(bufferedLog)
+		if err != nil {
+			return fmt.Errorf("failed to marshal log: %w", err)
+		}
+		data = append(append(data, line...), '\n')
 	}
 
-	if err := os.WriteFile(filename, append(data, '\n'), 0600); err != nil {
+	if !ob.budget.Reserve(int64(len(data)), "buffer "+ob.outputName) {
+		return ErrDiskBudgetExceeded
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		ob.budget.Release(int64(len(data)))
 		return fmt.Errorf("failed to write buffer file: %w", err)
 	}
 
 	return nil
 }
 
-// persistRetryQueue saves the retry queue to disk
+// persistRetryQueue saves the retry queue to disk and, with snapshot_interval,
+// the logs waiting in the delivery queue or being delivered. The file always
+// reflects the queues at the time of the call: logs delivered since the
+// previous call are no longer in it, and an empty file is removed.
 func (ob *OutputBuffer) persistRetryQueue() {
 	ob.retryMu.Lock()
 	defer ob.retryMu.Unlock()
 
-	if len(ob.retryQueue) == 0 {
-		return
+	var retrying map[*BufferedLog]bool
+	if ob.inflight != nil {
+		retrying = make(map[*BufferedLog]bool, len(ob.retryQueue))
 	}
-
-	filename := filepath.Join(ob.config.Dir, ob.outputName, "retry-queue.jsonl")
-	file, err := os.Create(filename) // #nosec G304 - path constructed from controlled inputs
-	if err != nil {
-		log.Printf("[BUFFER:%s] Error creating retry queue file: %v", ob.outputName, err)
-		return
-	}
-	defer func() {
-		_ = file.Close()
-	}()
-
+	var data []byte
 	for _, bufferedLog := range ob.retryQueue {
-		data, err := json.Marshal(bufferedLog)
+		line, err := json.Marshal(bufferedLog)
 		if err != nil {
-			log.Printf("[BUFFER:%s] Error marshaling retry log: %v", ob.outputName, err)
+			ob.logger().Printf("Error marshaling retry log: %v", err)
 			continue
 		}
-		if _, err := file.Write(append(data, '\n')); err != nil {
-			log.Printf("[BUFFER:%s] Error writing retry log to disk: %v", ob.outputName, err)
+		data = append(append(data, line...), '\n')
+		if retrying != nil {
+			retrying[bufferedLog] = true
+		}
+	}
+	if ob.inflight != nil {
+		for _, line := range ob.inflightLines(retrying) {
+			data = append(append(data, line...), '\n')
 		}
 	}
+	if len(data) == 0 && ob.retrySize == 0 {
+		return
+	}
+
+	// The file is rewritten in place, so only growth needs room in the budget
+	size := int64(len(data))
+	if size > ob.retrySize && !ob.budget.Reserve(size-ob.retrySize, "retry queue "+ob.outputName) {
+		return // Keep the previous snapshot; the queue itself is still in memory
+	}
+
+	reserved := max(size, ob.retrySize)
+	size = ob.writeQueueFile(data)
+	ob.budget.Release(reserved - size)
+	ob.retrySize = size
 }
 
 // loadPersistedLogs loads logs from disk on startup
@@ -456,48 +639,74 @@ func (ob *OutputBuffer) loadPersistedLogs() error {
 		return nil
 	}
 
-	log.Printf("[BUFFER:%s] Loading %d persisted buffer files", ob.outputName, len(files))
+	ob.logger().Printf("Loading %d persisted buffer files", len(files))
 
 	loadedCount := 0
 	for _, filename := range files {
 		// Validate that the file is within our configured directory
 		if err := validateFileInDirectory(filename, bufferDir); err != nil {
-			log.Printf("[BUFFER:%s] Skipping invalid buffer file path %s: %v", ob.outputName, filename, err)
+			ob.logger().Printf("Skipping invalid buffer file path %s: %v", filename, err)
 			continue
 		}
 
 		data, err := os.ReadFile(filename) // #nosec G304 - path validated by validateFileInDirectory above
 		if err != nil {
-			log.Printf("[BUFFER:%s] Error reading buffer file %s: %v", ob.outputName, filename, err)
+			ob.logger().Printf("Error reading buffer file %s: %v", filename, err)
 			continue
 		}
 
-		var bufferedLog BufferedLog
-		if err := json.Unmarshal(data, &bufferedLog); err != nil {
-			log.Printf("[BUFFER:%s] Error unmarshaling buffer file %s: %v", ob.outputName, filename, err)
+		// Files hold one log per line: one for a full queue, several for the retry queue and spills
+		var logs []*BufferedLog
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for err == nil && decoder.More() {
+			var bufferedLog BufferedLog
+			if err = decoder.Decode(&bufferedLog); err == nil {
+				logs = append(logs, &bufferedLog)
+			}
+		}
+		if err != nil {
+			ob.logger().Printf("Error unmarshaling buffer file %s: %v", filename, err)
 			continue
 		}
 
-		// Reset attempts for persisted logs
-		bufferedLog.Attempts = 0
-		bufferedLog.LastAttempt = time.Time{}
+		for _, bufferedLog := range logs {
+			// Reset attempts for persisted logs
+			bufferedLog.Attempts = 0
+			bufferedLog.LastAttempt = time.Time{}
+		}
 
 		// Add to retry queue
-		ob.retryQueue = append(ob.retryQueue, &bufferedLog)
-		loadedCount++
+		ob.retryQueue = append(ob.retryQueue, logs...)
+		loadedCount += len(logs)
 
 		// Remove the file after loading
-		_ = os.Remove(filename)
+		if err := os.Remove(filename); err == nil {
+			ob.budget.Release(int64(len(data)))
+		}
 	}
 
 	ob.statsMu.Lock()
 	ob.stats.CurrentRetrying = len(ob.retryQueue)
 	ob.statsMu.Unlock()
 
-	log.Printf("[BUFFER:%s] Loaded %d logs from disk", ob.outputName, loadedCount)
+	ob.logger().Printf("Loaded %d logs from disk", loadedCount)
+
+	// Spill what was loaded beyond the bound again; workers are not running yet
+	ob.retryMu.Lock()
+	ob.enforceRetryLimit()
+	ob.retryMu.Unlock()
 	return nil
 }
 
+// DeliveryWorkers returns the number of delivery workers draining the queue
+// (zero when buffering is disabled)
+func (ob *OutputBuffer) DeliveryWorkers() int {
+	if !ob.config.Enabled {
+		return 0
+	}
+	return ob.config.deliveryWorkers()
+}
+
 // GetStats returns current buffer statistics
 func (ob *OutputBuffer) GetStats() BufferStats {
 	ob.statsMu.RLock()
@@ -505,19 +714,37 @@ func (ob *OutputBuffer) GetStats() BufferStats {
 	return ob.stats
 }
 
+// ResetStats zeroes the cumulative counters. Current queue gauges are left untouched
+// because they reflect logs that are still in flight.
+func (ob *OutputBuffer) ResetStats() {
+	ob.statsMu.Lock()
+	defer ob.statsMu.Unlock()
+	ob.stats.TotalEnqueued = 0
+	ob.stats.TotalDelivered = 0
+	ob.stats.TotalRetried = 0
+	ob.stats.TotalFailed = 0
+	ob.stats.TotalDLQ = 0
+	ob.stats.TotalSpilled = 0
+	ob.stats.TotalStale = 0
+	ob.stats.TotalExpired = 0
+}
+
 // Close shuts down the output buffer
 func (ob *OutputBuffer) Close() error {
 	if !ob.config.Enabled {
 		return ob.output.Close()
 	}
 
-	log.Printf("[BUFFER:%s] Shutting down output buffer...", ob.outputName)
+	ob.logger().Printf("Shutting down output buffer...")
 
 	// Stop workers
 	close(ob.stopCh)
 	if ob.flushTicker != nil {
 		ob.flushTicker.Stop()
 	}
+	if ob.snapshotTicker != nil {
+		ob.snapshotTicker.Stop()
+	}
 
 	// Drain the queue with timeout
 	timeout := time.After(10 * time.Second)
@@ -528,23 +755,30 @@ drainLoop:
 			if err := ob.deliverLog(bufferedLog); err != nil {
 				ob.requeueForRetry(bufferedLog)
 			}
+			ob.settleInflight(bufferedLog)
 		case <-timeout:
-			log.Printf("[BUFFER:%s] Drain timeout reached", ob.outputName)
+			ob.logger().Printf("Drain timeout reached")
 			break drainLoop
 		default:
 			break drainLoop
 		}
 	}
 
-	// Persist remaining logs
-	ob.persistRetryQueue()
-
-	// Wait for workers
+	// Wait for workers, then persist what they left undelivered
 	ob.wg.Wait()
+	ob.persistRetryQueue()
 
 	// Close DLQ file
+	ob.dlqMu.Lock()
 	if ob.dlqFile != nil {
 		_ = ob.dlqFile.Close()
+		ob.dlqFile = nil
+	}
+	ob.dlqMu.Unlock()
+
+	// A buffer created for the same output on reload tracks these files again
+	if ob.budget != nil {
+		ob.budget.untrack("dlq "+ob.outputName, ob.diskFiles()...)
 	}
 
 	// Close underlying output
@@ -554,8 +788,41 @@ drainLoop:
 
 	// Log final stats
 	stats := ob.GetStats()
-	log.Printf("[BUFFER:%s] Final stats - Enqueued: %d, Delivered: %d, Retried: %d, DLQ: %d, Failed: %d",
-		ob.outputName, stats.TotalEnqueued, stats.TotalDelivered, stats.TotalRetried, stats.TotalDLQ, stats.TotalFailed)
+	ob.logger().Printf("Final stats - Enqueued: %d, Delivered: %d, Retried: %d, DLQ: %d, Failed: %d",
+		stats.TotalEnqueued, stats.TotalDelivered, stats.TotalRetried, stats.TotalDLQ, stats.TotalFailed)
 
 	return nil
 }
+
+// diskFiles lists this output's buffer and DLQ files
+func (ob *OutputBuffer) diskFiles() []string {
+	files, _ := filepath.Glob(filepath.Join(ob.config.Dir, ob.outputName, "*.jsonl"))
+	if ob.config.DLQEnabled {
+		dlqFiles, _ := DLQFiles(ob.config.DLQPath, ob.outputName)
+		files = append(files, dlqFiles...)
+	}
+	return files
+}
+
+// trackDiskUsage counts this output's buffer and DLQ files against the disk budget
+// and lets the budget prune rotated DLQ segments, oldest first
+func (ob *OutputBuffer) trackDiskUsage() {
+	if ob.budget == nil {
+		return
+	}
+	ob.budget.track(ob.diskFiles()...)
+	if !ob.config.DLQEnabled {
+		return
+	}
+	ob.budget.addPrunable("dlq "+ob.outputName, func() []string {
+		segments, err := listDLQSegments(ob.config.DLQPath, ob.outputName)
+		if err != nil {
+			return nil
+		}
+		paths := make([]string, 0, len(segments))
+		for _, segment := range segments {
+			paths = append(paths, segment.path)
+		}
+		return paths
+	})
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -549,3 +550,326 @@ func TestDefaultOutputBufferConfig(t *testing.T) {
 		t.Error("Default retry interval should be positive")
 	}
 }
+
+func TestOutputBuffer_DLQRotation(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := OutputBufferConfig{
+		Enabled:       true,
+		Dir:           tmpDir,
+		MaxQueueSize:  10,
+		MaxRetries:    1,
+		RetryInterval: time.Second,
+		MaxRetryDelay: time.Second,
+		FlushInterval: time.Hour,
+		DLQEnabled:    true,
+		DLQPath:       tmpDir,
+		DLQMaxSize:    200,
+	}
+
+	buffer, err := NewOutputBuffer("test", &MockOutput{}, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+
+	for i := 0; i < 5; i++ {
+		buffer.sendToDLQ(&BufferedLog{Log: NewLog("error", fmt.Sprintf("failed %d", i)), Attempts: 1, OutputName: "test"})
+	}
+
+	files, err := DLQFiles(tmpDir, "test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("Expected the DLQ to rotate into several files, got %v", files)
+	}
+	if filepath.Base(files[len(files)-1]) != "test-dlq.jsonl" {
+		t.Errorf("Expected the active file last, got %v", files)
+	}
+
+	entries, err := buffer.ReadDLQ()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 DLQ entries across segments, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if expected := fmt.Sprintf("failed %d", i); entry.Log.Message != expected {
+			t.Errorf("Expected entry %d to be %q, got %q", i, expected, entry.Log.Message)
+		}
+	}
+	if stats := buffer.GetStats(); stats.TotalDLQ != 5 {
+		t.Errorf("Expected 5 logs in DLQ, got %d", stats.TotalDLQ)
+	}
+}
+
+func TestOutputBuffer_DLQPruning(t *testing.T) {
+	now := time.Now()
+	writeSegment := func(t *testing.T, dir string, age time.Duration) string {
+		path := filepath.Join(dir, fmt.Sprintf("test-dlq-%d.jsonl", now.Add(-age).UnixNano()))
+		if err := os.WriteFile(path, []byte("{}\n"), 0600); err != nil {
+			t.Fatalf("Failed to write segment: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		config   OutputBufferConfig
+		ages     []time.Duration
+		expected []bool // Whether each segment survives
+	}{
+		{
+			name:     "max segments keeps newest",
+			config:   OutputBufferConfig{DLQMaxSegments: 1},
+			ages:     []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour},
+			expected: []bool{false, false, true},
+		},
+		{
+			name:     "max age removes expired",
+			config:   OutputBufferConfig{DLQMaxAge: 90 * time.Minute},
+			ages:     []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour},
+			expected: []bool{false, false, true},
+		},
+		{
+			name:     "min retention protects recent segments",
+			config:   OutputBufferConfig{DLQMaxSegments: 1, DLQMinRetention: 150 * time.Minute},
+			ages:     []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour},
+			expected: []bool{false, true, true},
+		},
+		{
+			name:     "no limits keeps everything",
+			config:   OutputBufferConfig{},
+			ages:     []time.Duration{3 * time.Hour, time.Hour},
+			expected: []bool{true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			paths := make([]string, len(tt.ages))
+			for i, age := range tt.ages {
+				paths[i] = writeSegment(t, tmpDir, age)
+			}
+			// Another output whose name shares the prefix must never be pruned
+			other := filepath.Join(tmpDir, "test-dlq-dlq.jsonl")
+			if err := os.WriteFile(other, []byte("{}\n"), 0600); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+
+			tt.config.DLQPath = tmpDir
+			buffer := &OutputBuffer{config: tt.config, outputName: "test"}
+			buffer.pruneDLQ(now)
+
+			for i, path := range paths {
+				_, err := os.Stat(path)
+				if exists := err == nil; exists != tt.expected[i] {
+					t.Errorf("Expected segment %d exists=%v, got %v", i, tt.expected[i], exists)
+				}
+			}
+			if _, err := os.Stat(other); err != nil {
+				t.Errorf("Expected other output's file to be kept: %v", err)
+			}
+		})
+	}
+}
+
+func TestOutputBufferConfigDLQLimitsValidation(t *testing.T) {
+	valid := DefaultOutputBufferConfig()
+	valid.DLQMaxSize = 10 * 1024 * 1024
+	valid.DLQMaxAge = 24 * time.Hour
+	valid.DLQMaxSegments = 5
+	valid.DLQMinRetention = time.Hour
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+
+	invalid := []func(*OutputBufferConfig){
+		func(c *OutputBufferConfig) { c.DLQMaxSize = -1 },
+		func(c *OutputBufferConfig) { c.DLQMaxAge = -time.Second },
+		func(c *OutputBufferConfig) { c.DLQMaxSegments = -1 },
+		func(c *OutputBufferConfig) { c.DLQMinRetention = -time.Second },
+	}
+	for i, mutate := range invalid {
+		config := DefaultOutputBufferConfig()
+		mutate(&config)
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for case %d", i)
+		}
+	}
+}
+
+func TestOutputBuffer_RetryJitter(t *testing.T) {
+	output := &MockOutput{shouldFail: true, failCount: 10}
+
+	config := DefaultOutputBufferConfig()
+	config.Dir = t.TempDir()
+	config.RetryInterval = 100 * time.Millisecond
+	config.MaxRetryDelay = time.Second
+	config.Enabled = true
+	config.RetryJitter = JitterFull
+
+	buffer, err := NewOutputBuffer("test", output, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+	buffer.random = func() float64 { return 0.5 }
+
+	bufferedLog := &BufferedLog{Log: NewLog("error", "jitter"), OutputName: "test"}
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, 50 * time.Millisecond},
+		{2, 100 * time.Millisecond},
+		{5, 500 * time.Millisecond}, // Half of the capped 1s
+		{6, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for bufferedLog.Attempts < tt.attempt {
+			if err := buffer.deliverLog(bufferedLog); err == nil {
+				t.Fatal("Expected delivery to fail")
+			}
+		}
+		if bufferedLog.RetryDelay != tt.expected {
+			t.Errorf("For attempt %d, expected retry delay %v, got %v", tt.attempt, tt.expected, bufferedLog.RetryDelay)
+		}
+	}
+
+	config.RetryJitter = "random"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected validation error for an unknown retry jitter")
+	}
+}
+
+// gatedOutput holds every Write until release is closed and tracks how many run at once
+type gatedOutput struct {
+	release     chan struct{}
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	written     int
+}
+
+func (b *gatedOutput) Write(log *Log) error {
+	b.mu.Lock()
+	b.inFlight++
+	b.maxInFlight = max(b.maxInFlight, b.inFlight)
+	b.mu.Unlock()
+
+	<-b.release
+
+	b.mu.Lock()
+	b.inFlight--
+	b.written++
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *gatedOutput) Close() error { return nil }
+
+func (b *gatedOutput) counts() (inFlight, maxInFlight, written int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inFlight, b.maxInFlight, b.written
+}
+
+func TestOutputBuffer_DeliveryConcurrency(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		expected    int
+	}{
+		{0, 1},
+		{1, 1},
+		{4, 4},
+	}
+
+	for _, tt := range tests {
+		output := &gatedOutput{release: make(chan struct{})}
+
+		config := DefaultOutputBufferConfig()
+		config.Dir = t.TempDir()
+		config.Enabled = true
+		config.DLQEnabled = false
+		config.DeliveryConcurrency = tt.concurrency
+
+		buffer, err := NewOutputBuffer("test", output, config)
+		if err != nil {
+			t.Fatalf("Failed to create buffer: %v", err)
+		}
+		if buffer.DeliveryWorkers() != tt.expected {
+			t.Errorf("delivery_concurrency %d: expected %d workers, got %d", tt.concurrency, tt.expected, buffer.DeliveryWorkers())
+		}
+
+		for i := range 8 {
+			if err := buffer.Enqueue(NewLog("info", fmt.Sprintf("log %d", i))); err != nil {
+				t.Fatalf("Failed to enqueue: %v", err)
+			}
+		}
+
+		// Every worker picks up a log and blocks in Write
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if inFlight, _, _ := output.counts(); inFlight >= tt.expected || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(output.release)
+
+		deadline = time.Now().Add(2 * time.Second)
+		for {
+			if _, _, written := output.counts(); written == 8 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		_ = buffer.Close()
+
+		_, maxInFlight, written := output.counts()
+		if maxInFlight != tt.expected {
+			t.Errorf("delivery_concurrency %d: expected %d concurrent writes, got %d", tt.concurrency, tt.expected, maxInFlight)
+		}
+		if written != 8 {
+			t.Errorf("delivery_concurrency %d: expected 8 logs written, got %d", tt.concurrency, written)
+		}
+	}
+
+	config := DefaultOutputBufferConfig()
+	for _, concurrency := range []int{-1, MaxDeliveryConcurrency + 1} {
+		config.DeliveryConcurrency = concurrency
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for delivery_concurrency %d", concurrency)
+		}
+	}
+}
+
+func TestPipelineDeliveryConcurrencyOverride(t *testing.T) {
+	config := DefaultOutputBufferConfig()
+	config.Dir = t.TempDir()
+	config.Enabled = true
+	config.DeliveryConcurrency = 4
+
+	engine := NewEngine()
+	engine.SetOutputBufferConfig(config)
+
+	ordered := &OutputPipeline{Name: "ordered", Output: &MockOutput{}, DeliveryConcurrency: 1}
+	parallel := &OutputPipeline{Name: "parallel", Output: &MockOutput{}}
+	for _, pipeline := range []*OutputPipeline{ordered, parallel} {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+		defer func() { _ = pipeline.Buffer.Close() }()
+	}
+
+	if got := ordered.Buffer.DeliveryWorkers(); got != 1 {
+		t.Errorf("Expected the pipeline override of 1 worker, got %d", got)
+	}
+	if got := parallel.Buffer.DeliveryWorkers(); got != 4 {
+		t.Errorf("Expected the buffer config's 4 workers, got %d", got)
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import "fmt"
+
+// Input parse error modes (on_parse_error). An empty mode keeps each input's
+// historical behavior: lines are coerced to the default level, or dropped when
+// they cannot be turned into a log at all.
+const (
+	ParseErrorPassRaw = "pass_raw" // Forward the raw line with parse_error metadata
+	ParseErrorTag     = "tag"      // Like pass_raw, and add the parse_error tag for tag routing
+	ParseErrorDrop    = "drop"     // Discard the line
+
+	ParseErrorKey       = "parse_error"        // Metadata key ("true") and tag marking unparseable lines
+	ParseErrorReasonKey = "parse_error_reason" // Metadata key describing what failed
+)
+
+// ValidateParseErrorMode validates an input's on_parse_error setting
+func ValidateParseErrorMode(mode string) error {
+	switch mode {
+	case "", ParseErrorPassRaw, ParseErrorTag, ParseErrorDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid on_parse_error '%s', must be 'pass_raw', 'tag' or 'drop'", mode)
+	}
+}
+
+// HandleParseError applies an on_parse_error mode to a log built from a line the
+// input could not parse. The log should carry the raw line as its message. It
+// returns nil when the line must be dropped.
+func HandleParseError(mode string, logEntry *Log, reason string) *Log {
+	switch mode {
+	case ParseErrorDrop:
+		return nil
+	case ParseErrorPassRaw, ParseErrorTag:
+		if logEntry.Metadata == nil {
+			logEntry.Metadata = make(map[string]string)
+		}
+		logEntry.Metadata[ParseErrorKey] = "true"
+		logEntry.Metadata[ParseErrorReasonKey] = reason
+		if mode == ParseErrorTag {
+			logEntry.AddTags(ParseErrorKey)
+		}
+	}
+	return logEntry
+}
@@ -0,0 +1,55 @@
+package core
+
+import "testing"
+
+func TestHandleParseError(t *testing.T) {
+	tests := []struct {
+		mode         string
+		expectNil    bool
+		expectMarker bool
+		expectTag    bool
+	}{
+		{mode: "", expectMarker: false},
+		{mode: ParseErrorPassRaw, expectMarker: true},
+		{mode: ParseErrorTag, expectMarker: true, expectTag: true},
+		{mode: ParseErrorDrop, expectNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			logEntry := HandleParseError(tt.mode, NewLog("info", "garbled line"), "no level detected")
+			if tt.expectNil {
+				if logEntry != nil {
+					t.Error("Expected line to be dropped")
+				}
+				return
+			}
+			if logEntry == nil {
+				t.Fatal("Expected a log")
+			}
+			if logEntry.Message != "garbled line" {
+				t.Errorf("Expected raw message, got %q", logEntry.Message)
+			}
+			if marked := logEntry.Metadata[ParseErrorKey] == "true"; marked != tt.expectMarker {
+				t.Errorf("Expected parse_error metadata %v, got %v", tt.expectMarker, logEntry.Metadata)
+			}
+			if tt.expectMarker && logEntry.Metadata[ParseErrorReasonKey] != "no level detected" {
+				t.Errorf("Expected reason metadata, got %v", logEntry.Metadata)
+			}
+			if logEntry.HasTag(ParseErrorKey) != tt.expectTag {
+				t.Errorf("Expected parse_error tag %v, got %v", tt.expectTag, logEntry.Tags)
+			}
+		})
+	}
+}
+
+func TestValidateParseErrorMode(t *testing.T) {
+	for _, mode := range []string{"", "pass_raw", "tag", "drop"} {
+		if err := ValidateParseErrorMode(mode); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := ValidateParseErrorMode("ignore"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Pause modes define what happens to incoming logs while the engine is paused
+const (
+	// PauseModeBackpressure stops reading the input channel. Once it is full,
+	// inputs block on send and push back on their sources.
+	PauseModeBackpressure = "backpressure"
+	// PauseModePersist keeps reading the input channel, writes each log to the
+	// WAL and holds it until resume. When max_held logs are held it falls back
+	// to backpressure.
+	PauseModePersist = "persist"
+)
+
+// DefaultPauseMaxHeld is the default number of logs held in persist mode
+const DefaultPauseMaxHeld = 10000
+
+// PauseConfig defines how the engine behaves while paused
+type PauseConfig struct {
+	Mode    string `yaml:"mode,omitempty"`     // "backpressure" (default) or "persist"
+	MaxHeld int    `yaml:"max_held,omitempty"` // Logs held in persist mode before backpressure (default: 10000)
+}
+
+// Validate validates the PauseConfig
+func (p PauseConfig) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Mode, validation.In(PauseModeBackpressure, PauseModePersist).Error("must be 'backpressure' or 'persist'")),
+		validation.Field(&p.MaxHeld, validation.Min(0).Error("must be no less than 0")),
+	)
+}
+
+// maxHeld returns the number of logs held in persist mode
+func (p PauseConfig) maxHeld() int {
+	if p.MaxHeld > 0 {
+		return p.MaxHeld
+	}
+	return DefaultPauseMaxHeld
+}
+
+// SetPauseConfig configures how the engine behaves while paused. Persist mode
+// requires persistence so held logs survive a restart.
+func (e *Engine) SetPauseConfig(config PauseConfig) error {
+	if err := e.checkPauseConfig(config); err != nil {
+		return err
+	}
+	e.pauseMu.Lock()
+	e.pauseConfig = config
+	e.pauseMu.Unlock()
+	return nil
+}
+
+// checkPauseConfig reports whether SetPauseConfig would accept config
+func (e *Engine) checkPauseConfig(config PauseConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid pause config: %w", err)
+	}
+	if config.Mode == PauseModePersist && (e.persistence == nil || !e.persistence.config.Enabled) {
+		return fmt.Errorf("pause mode %q requires persistence to be enabled", PauseModePersist)
+	}
+	return nil
+}
+
+// Pause stops forwarding logs to outputs until Resume is called. Inputs keep
+// running; see PauseConfig for what happens to logs that arrive meanwhile.
+func (e *Engine) Pause() {
+	e.setPaused(true)
+}
+
+// Resume forwards logs again. Logs held while paused are delivered first, in
+// the order they arrived.
+func (e *Engine) Resume() {
+	e.setPaused(false)
+}
+
+// Paused reports whether the engine is paused
+func (e *Engine) Paused() bool {
+	paused, _ := e.pauseState()
+	return paused
+}
+
+// setPaused updates the paused flag and wakes up the processing loop
+func (e *Engine) setPaused(paused bool) {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+
+	if e.paused == paused {
+		return
+	}
+	e.paused = paused
+	if paused {
+		e.pausedAt = time.Now()
+	}
+	if e.pauseChanged != nil {
+		close(e.pauseChanged)
+	}
+	e.pauseChanged = make(chan struct{})
+
+	if paused {
+		engineLog.Printf("Paused (mode=%s)", e.pauseMode())
+	} else {
+		engineLog.Println("Resumed")
+	}
+}
+
+// pauseState returns the paused flag and a channel closed on the next change
+func (e *Engine) pauseState() (bool, <-chan struct{}) {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+
+	if e.pauseChanged == nil {
+		e.pauseChanged = make(chan struct{})
+	}
+	return e.paused, e.pauseChanged
+}
+
+// pauseMode returns the configured pause mode
+func (e *Engine) pauseMode() string {
+	if e.pauseConfig.Mode == "" {
+		return PauseModeBackpressure
+	}
+	return e.pauseConfig.Mode
+}
+
+// pauseStatus returns the pause section reported by /status
+func (e *Engine) pauseStatus() map[string]interface{} {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+
+	status := map[string]interface{}{
+		"paused":      e.paused,
+		"mode":        e.pauseMode(),
+		"held_logs":   e.heldLogs.Load(),
+		"queued_logs": len(e.inputCh),
+	}
+	if e.paused {
+		status["paused_since"] = e.pausedAt.Format(time.RFC3339)
+	}
+	return status
+}
+
+// handlePause pauses or resumes the engine via POST /pause or POST /resume
+func (e *Engine) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.URL.Path == "/pause" {
+		e.Pause()
+	} else {
+		e.Resume()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(e.pauseStatus()); err != nil {
+		apiLog.Printf("Error encoding pause response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handleReady reports whether the engine is forwarding logs. It returns 503
+// while the engine is paused or stopped so load balancers can route around it.
+func (e *Engine) handleReady(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	stopped := e.stopped
+	e.mu.Unlock()
+
+	status := "ready"
+	code := http.StatusOK
+	switch {
+	case stopped:
+		status, code = "stopped", http.StatusServiceUnavailable
+	case e.Paused():
+		status, code = "paused", http.StatusServiceUnavailable
+	}
+
+	response := map[string]string{
+		"status": status,
+		"time":   time.Now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding ready response: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForLogs waits until the output has received n logs
+func waitForLogs(t *testing.T, output *mockOutput, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for output.getCallCount() < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := output.getCallCount(); got != n {
+		t.Fatalf("Expected %d logs, got %d", n, got)
+	}
+}
+
+func TestEnginePauseBackpressure(t *testing.T) {
+	engine := NewEngine()
+	output := newMockOutput()
+	engine.AddOutput(output)
+	engine.Start()
+	defer engine.Stop()
+
+	engine.Pause()
+	if !engine.Paused() {
+		t.Fatal("Expected engine to be paused")
+	}
+
+	// Fill the input channel: the next send must block
+	for i := 0; i < cap(engine.inputCh); i++ {
+		engine.inputCh <- NewLog("info", fmt.Sprintf("log %d", i))
+	}
+	select {
+	case engine.inputCh <- NewLog("info", "overflow"):
+		t.Fatal("Expected a full input channel to block inputs while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if output.getCallCount() != 0 {
+		t.Fatalf("Expected no logs forwarded while paused, got %d", output.getCallCount())
+	}
+
+	engine.Resume()
+	waitForLogs(t, output, cap(engine.inputCh))
+	logs := output.getLogs()
+	for i, logEntry := range logs {
+		if logEntry.Message != fmt.Sprintf("log %d", i) {
+			t.Fatalf("Expected logs in arrival order, got %q at %d", logEntry.Message, i)
+		}
+	}
+}
+
+func TestEnginePausePersist(t *testing.T) {
+	engine := NewEngine()
+	persistence := DefaultPersistenceConfig()
+	persistence.Enabled = true
+	persistence.Dir = t.TempDir()
+	if err := engine.SetPersistence(persistence); err != nil {
+		t.Fatalf("Failed to set persistence: %v", err)
+	}
+	if err := engine.SetPauseConfig(PauseConfig{Mode: PauseModePersist, MaxHeld: 150}); err != nil {
+		t.Fatalf("Failed to set pause config: %v", err)
+	}
+	output := newMockOutput()
+	engine.AddOutput(output)
+	engine.Start()
+	defer engine.Stop()
+
+	// Let startup recovery of the empty WAL finish before logs are written to it
+	time.Sleep(100 * time.Millisecond)
+	engine.Pause()
+
+	// Held logs do not count against the input channel
+	total := cap(engine.inputCh) + 150
+	sent := 0
+	for ; sent < total; sent++ {
+		select {
+		case engine.inputCh <- NewLog("info", fmt.Sprintf("log %d", sent)):
+		case <-time.After(time.Second):
+			t.Fatalf("Input blocked after %d logs", sent)
+		}
+	}
+
+	// Once max_held logs are held the engine falls back to backpressure
+	deadline := time.Now().Add(2 * time.Second)
+	for engine.heldLogs.Load() < 150 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if held := engine.heldLogs.Load(); held != 150 {
+		t.Fatalf("Expected 150 held logs, got %d", held)
+	}
+	select {
+	case engine.inputCh <- NewLog("info", "overflow"):
+		t.Fatal("Expected backpressure once max_held is reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if output.getCallCount() != 0 {
+		t.Fatalf("Expected no logs forwarded while paused, got %d", output.getCallCount())
+	}
+
+	engine.Resume()
+	waitForLogs(t, output, total)
+	for i, logEntry := range output.getLogs() {
+		if logEntry.Message != fmt.Sprintf("log %d", i) {
+			t.Fatalf("Expected held logs drained first, got %q at %d", logEntry.Message, i)
+		}
+	}
+	if held := engine.heldLogs.Load(); held != 0 {
+		t.Errorf("Expected no held logs after resume, got %d", held)
+	}
+}
+
+func TestEngineSetPauseConfig(t *testing.T) {
+	engine := NewEngine()
+	if err := engine.SetPauseConfig(PauseConfig{Mode: PauseModePersist}); err == nil {
+		t.Error("Expected error for persist mode without persistence")
+	}
+	if err := engine.SetPauseConfig(PauseConfig{Mode: "spool"}); err == nil {
+		t.Error("Expected error for unknown pause mode")
+	}
+	if err := engine.SetPauseConfig(PauseConfig{MaxHeld: -1}); err == nil {
+		t.Error("Expected error for negative max_held")
+	}
+	if err := engine.SetPauseConfig(PauseConfig{Mode: PauseModeBackpressure}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestEnginePauseHandlers(t *testing.T) {
+	engine := NewEngine()
+
+	ready := func() int {
+		w := httptest.NewRecorder()
+		engine.handleReady(w, httptest.NewRequest("GET", "/ready", nil))
+		return w.Code
+	}
+
+	if code := ready(); code != http.StatusOK {
+		t.Errorf("Expected ready status 200, got %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	engine.handlePause(w, httptest.NewRequest("GET", "/pause", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePause(w, httptest.NewRequest("POST", "/pause", nil))
+	if w.Code != http.StatusOK || !engine.Paused() {
+		t.Fatalf("Expected engine paused, got status %d paused=%t", w.Code, engine.Paused())
+	}
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected ready status 503 while paused, got %d", code)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	if !strings.Contains(w.Body.String(), `"paused":true`) || !strings.Contains(w.Body.String(), `"status":"paused"`) {
+		t.Errorf("Expected paused state in /status, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePause(w, httptest.NewRequest("POST", "/resume", nil))
+	if w.Code != http.StatusOK || engine.Paused() {
+		t.Fatalf("Expected engine resumed, got status %d paused=%t", w.Code, engine.Paused())
+	}
+	if code := ready(); code != http.StatusOK {
+		t.Errorf("Expected ready status 200 after resume, got %d", code)
+	}
+}
+
+func TestEnginePauseReload(t *testing.T) {
+	engine := NewEngine()
+	persistence := DefaultPersistenceConfig()
+	persistence.Enabled = true
+	persistence.Dir = t.TempDir()
+	if err := engine.SetPersistence(persistence); err != nil {
+		t.Fatalf("Failed to set persistence: %v", err)
+	}
+	if err := engine.SetPauseConfig(PauseConfig{Mode: PauseModePersist}); err != nil {
+		t.Fatalf("Failed to set pause config: %v", err)
+	}
+	engine.AddOutput(newMockOutput())
+	engine.Start()
+	defer engine.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	engine.Pause()
+	for i := 0; i < 5; i++ {
+		engine.inputCh <- NewLog("info", fmt.Sprintf("log %d", i))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for engine.heldLogs.Load() < 5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	inputs := []PluginDefinition{{Type: "stdin", Config: map[string]any{"resilient": false}}}
+	outputs := []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}}
+	output := newMockOutput()
+	createInput := func(string, string, map[string]any, *Engine) {}
+	createOutput := func(_ string, _ PluginDefinition, e *Engine) { e.AddOutput(output) }
+
+	// A pause config the engine cannot honour rejects the reload
+	noPersistence := NewEngine()
+	invalid := &Config{Inputs: inputs, Outputs: outputs, Persistence: persistence, Pause: PauseConfig{Mode: PauseModePersist}}
+	if err := noPersistence.ReloadConfig(invalid, createInput, createOutput); err == nil {
+		t.Error("Expected persist mode without engine persistence to reject the reload")
+	}
+
+	newConfig := &Config{Inputs: inputs, Outputs: outputs, Persistence: persistence, Pause: PauseConfig{Mode: PauseModePersist, MaxHeld: 20}}
+	if err := engine.ReloadConfig(newConfig, createInput, createOutput); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if engine.pauseConfig.MaxHeld != 20 {
+		t.Errorf("Expected the reload to apply the pause config, got %+v", engine.pauseConfig)
+	}
+
+	// Logs held before the reload are delivered on resume
+	if held := engine.heldLogs.Load(); held != 5 {
+		t.Fatalf("Expected 5 held logs across the reload, got %d", held)
+	}
+	engine.Resume()
+	waitForLogs(t, output, 5)
+}
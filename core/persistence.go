@@ -4,11 +4,11 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -23,12 +23,26 @@ type PersistenceConfig struct {
 	FlushInterval  int    `yaml:"flush_interval"`  // Flush interval in seconds (default: 5)
 	RetentionHours int    `yaml:"retention_hours"` // How long to keep WAL files (default: 24)
 	SyncWrites     bool   `yaml:"sync_writes"`     // fsync after each write (slower but safer)
+
+	// Adaptive flushing
+	HighWaterMark    int `yaml:"high_water_mark,omitempty"`    // Buffered logs that trigger an early flush (default: 75% of buffer_size)
+	MaxFlushInterval int `yaml:"max_flush_interval,omitempty"` // Max flush interval in seconds when idle (default: 6x flush_interval)
+
+	// Recovered logs handed to the engine between recovery checkpoint writes (default: 1000)
+	RecoveryCheckpointEvery int `yaml:"recovery_checkpoint_every,omitempty"`
+
+	// WAL files decoded in parallel during recovery (default: 4); entries are merged by sequence either way
+	RecoveryConcurrency int `yaml:"recovery_concurrency,omitempty"`
+
+	// Lowest level written to the WAL (default: all logs); other logs are processed but not recoverable
+	MinLevel string `yaml:"min_level,omitempty"`
 }
 
 // Validate validates the PersistenceConfig
 func (p PersistenceConfig) Validate() error {
 	// If persistence is not enabled and all fields are zero, skip validation
-	if !p.Enabled && p.Dir == "" && p.MaxFileSize == 0 && p.BufferSize == 0 && p.FlushInterval == 0 && p.RetentionHours == 0 && !p.SyncWrites {
+	if !p.Enabled && p.Dir == "" && p.MaxFileSize == 0 && p.BufferSize == 0 && p.FlushInterval == 0 && p.RetentionHours == 0 && !p.SyncWrites &&
+		p.HighWaterMark == 0 && p.MaxFlushInterval == 0 && p.RecoveryCheckpointEvery == 0 && p.RecoveryConcurrency == 0 && p.MinLevel == "" {
 		return nil
 	}
 	return validation.ValidateStruct(&p,
@@ -64,9 +78,45 @@ func (p PersistenceConfig) Validate() error {
 			}
 			return nil
 		})),
+		validation.Field(&p.HighWaterMark, validation.By(func(value interface{}) error {
+			v := value.(int)
+			if v < 0 {
+				return fmt.Errorf("must be no less than 0")
+			}
+			if v > p.BufferSize {
+				return fmt.Errorf("must be no greater than buffer_size")
+			}
+			return nil
+		})),
+		validation.Field(&p.MaxFlushInterval, validation.By(func(value interface{}) error {
+			v := value.(int)
+			if v == 0 {
+				return nil
+			}
+			if v < p.FlushInterval {
+				return fmt.Errorf("must be no less than flush_interval")
+			}
+			if v > 3600 {
+				return fmt.Errorf("must be no greater than 3600")
+			}
+			return nil
+		})),
+		validation.Field(&p.RecoveryCheckpointEvery, validation.Min(0).Error("must be no less than 0")),
+		validation.Field(&p.RecoveryConcurrency, validation.Min(0).Error("must be no less than 0"), validation.Max(64).Error("must be no greater than 64")),
 	)
 }
 
+// validateMinLevel checks that min_level is part of the configured level vocabulary
+func (p PersistenceConfig) validateMinLevel(levels LevelsConfig) error {
+	if p.MinLevel == "" {
+		return nil
+	}
+	if vocabulary, err := NewLevelVocabulary(levels); err == nil && !vocabulary.Known(p.MinLevel) {
+		return fmt.Errorf("min_level %q is not a known level (known: %s)", p.MinLevel, strings.Join(vocabulary.Levels(), ", "))
+	}
+	return nil
+}
+
 // DefaultPersistenceConfig returns default persistence configuration
 func DefaultPersistenceConfig() PersistenceConfig {
 	return PersistenceConfig{
@@ -80,20 +130,52 @@ func DefaultPersistenceConfig() PersistenceConfig {
 	}
 }
 
+// highWaterMark returns the buffer size that triggers an early flush
+func (p PersistenceConfig) highWaterMark() int {
+	if p.HighWaterMark > 0 {
+		return p.HighWaterMark
+	}
+	if mark := p.BufferSize * 3 / 4; mark > 0 {
+		return mark
+	}
+	return 1
+}
+
+// flushIntervals returns the base flush interval and the max interval used when idle
+func (p PersistenceConfig) flushIntervals() (time.Duration, time.Duration) {
+	base := time.Duration(p.FlushInterval) * time.Second
+	maxSeconds := p.MaxFlushInterval
+	if maxSeconds == 0 {
+		maxSeconds = min(p.FlushInterval*6, 3600)
+	}
+	return base, max(time.Duration(maxSeconds)*time.Second, base)
+}
+
 // Persistence handles Write-Ahead Logging for log entries
 type Persistence struct {
 	config        PersistenceConfig
 	currentFile   *os.File
+	activePath    atomic.Pointer[string] // Path of currentFile, readable without bufferMu
+	budget        *DiskBudget            // Shared disk budget (nil = unlimited)
 	writer        *bufio.Writer
 	currentSize   int64
 	buffer        []*Log
 	bufferMu      sync.Mutex
-	flushTicker   *time.Ticker
+	idle          bool          // Flush timer is backed off (guarded by bufferMu)
+	flushCh       chan struct{} // High-water mark reached, flush early
+	wakeCh        chan struct{} // First log after idle, restore base interval
 	stopCh        chan struct{}
 	wg            sync.WaitGroup
 	sequenceNum   uint64
 	sequenceMu    sync.Mutex
 	recoveryQueue chan *Log
+
+	// Recovery checkpoint (see wal_checkpoint.go)
+	recoveryFiles  []walFile     // WAL files present at startup, oldest first (read-only)
+	checkpoint     atomic.Uint64 // Sequence stored in the checkpoint file
+	checkpointMu   sync.Mutex
+	recoveredSeq   uint64 // Highest sequence handed to the engine (guarded by checkpointMu)
+	uncheckpointed int    // Recovered logs since the last checkpoint write (guarded by checkpointMu)
 }
 
 // WALEntry represents a Write-Ahead Log entry
@@ -105,6 +187,11 @@ type WALEntry struct {
 
 // NewPersistence creates a new persistence handler
 func NewPersistence(config PersistenceConfig) (*Persistence, error) {
+	return newPersistence(config, nil)
+}
+
+// newPersistence creates a persistence handler whose WAL files count against a disk budget
+func newPersistence(config PersistenceConfig, budget *DiskBudget) (*Persistence, error) {
 	if !config.Enabled {
 		return &Persistence{
 			config:        config,
@@ -119,36 +206,59 @@ func NewPersistence(config PersistenceConfig) (*Persistence, error) {
 
 	p := &Persistence{
 		config:        config,
+		budget:        budget,
 		buffer:        make([]*Log, 0, config.BufferSize),
+		flushCh:       make(chan struct{}, 1),
+		wakeCh:        make(chan struct{}, 1),
 		stopCh:        make(chan struct{}),
 		recoveryQueue: make(chan *Log, 1000),
 	}
 
+	// Find the WAL files left by previous runs and continue their sequence, so entries
+	// written by this run always sort after them and after the recovery checkpoint
+	files, err := scanWALFiles(config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WAL files: %w", err)
+	}
+	checkpoint := readCheckpoint(config.Dir)
+	p.checkpoint.Store(checkpoint)
+	p.recoveredSeq = checkpoint
+	p.sequenceNum = checkpoint
+	p.recoveryFiles = files
+	for _, file := range files {
+		p.sequenceNum = max(p.sequenceNum, file.lastSeq)
+		budget.track(file.path) // Rotated files may be pruned when the budget is full
+	}
+	budget.addPrunable("wal", p.rotatedFiles)
+
 	// Open initial WAL file
 	if err := p.rotateFile(); err != nil {
 		return nil, fmt.Errorf("failed to create initial WAL file: %w", err)
 	}
 
-	// Start flush ticker
-	p.flushTicker = time.NewTicker(time.Duration(config.FlushInterval) * time.Second)
+	// Start adaptive flush loop, with its intervals read before it runs
+	baseInterval, maxInterval := config.flushIntervals()
 	p.wg.Add(1)
-	go p.flushLoop()
+	go p.flushLoop(baseInterval, maxInterval)
 
 	// Start cleanup routine
 	p.wg.Add(1)
 	go p.cleanupLoop()
 
-	log.Printf("Persistence initialized: dir=%s, buffer=%d, flush=%ds",
-		config.Dir, config.BufferSize, config.FlushInterval)
+	persistenceLog.Printf("Persistence initialized: dir=%s, buffer=%d, flush=%ds (max %s when idle), high_water_mark=%d",
+		config.Dir, config.BufferSize, config.FlushInterval, maxInterval, config.highWaterMark())
 
 	return p, nil
 }
 
-// Persist saves a log entry to the WAL
+// Persist saves a log entry to the WAL unless it is below min_level
 func (p *Persistence) Persist(logEntry *Log) error {
 	if !p.config.Enabled {
 		return nil
 	}
+	if p.config.MinLevel != "" && !Levels().AtLeast(logEntry.Level, p.config.MinLevel) {
+		return nil
+	}
 
 	p.bufferMu.Lock()
 	defer p.bufferMu.Unlock()
@@ -161,28 +271,97 @@ func (p *Persistence) Persist(logEntry *Log) error {
 		return p.flushBufferLocked()
 	}
 
+	// First log after an idle period: restore the base flush interval
+	if p.idle {
+		p.idle = false
+		notify(p.wakeCh)
+	}
+
+	// Past the high-water mark: let the flush loop write the buffer early.
+	// Signals coalesce, so repeated crossings before the flush only trigger one.
+	if len(p.buffer) >= p.config.highWaterMark() {
+		notify(p.flushCh)
+	}
+
 	return nil
 }
 
-// flushLoop periodically flushes the buffer
-func (p *Persistence) flushLoop() {
+// notify performs a non-blocking send on a signal channel
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// flushLoop flushes the buffer on the flush interval or when the high-water mark is
+// reached. The interval doubles while no logs arrive, up to maxInterval.
+func (p *Persistence) flushLoop(baseInterval, maxInterval time.Duration) {
 	defer p.wg.Done()
+
+	interval := baseInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
 	for {
 		select {
-		case <-p.flushTicker.C:
+		case <-timer.C:
 			p.bufferMu.Lock()
-			if len(p.buffer) > 0 {
-				if err := p.flushBufferLocked(); err != nil {
-					log.Printf("Error flushing persistence buffer: %v", err)
-				}
-			}
+			flushed := p.flushPendingLocked()
+			interval = nextFlushInterval(interval, baseInterval, maxInterval, flushed)
+			p.idle = !flushed
+			p.bufferMu.Unlock()
+			timer.Reset(interval)
+		case <-p.flushCh:
+			// All flushes run under bufferMu and the buffer is cleared afterwards,
+			// so a watermark flush racing the timer never writes the same logs twice
+			p.bufferMu.Lock()
+			p.flushPendingLocked()
 			p.bufferMu.Unlock()
+			interval = baseInterval
+			resetTimer(timer, interval)
+		case <-p.wakeCh:
+			if interval != baseInterval {
+				interval = baseInterval
+				resetTimer(timer, interval)
+			}
 		case <-p.stopCh:
 			return
 		}
 	}
 }
 
+// flushPendingLocked flushes buffered logs if any and reports whether anything was written
+// (must be called with bufferMu locked)
+func (p *Persistence) flushPendingLocked() bool {
+	if len(p.buffer) == 0 {
+		return false
+	}
+	if err := p.flushBufferLocked(); err != nil {
+		persistenceLog.Printf("Error flushing persistence buffer: %v", err)
+	}
+	return true
+}
+
+// nextFlushInterval backs off the flush interval while idle and restores it on activity
+func nextFlushInterval(current, base, maxInterval time.Duration, flushed bool) time.Duration {
+	if flushed {
+		return base
+	}
+	return min(current*2, maxInterval)
+}
+
+// resetTimer stops and drains the timer before resetting it to d
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
 // flushBufferLocked flushes the buffer to disk (must be called with bufferMu locked)
 func (p *Persistence) flushBufferLocked() error {
 	if len(p.buffer) == 0 {
@@ -211,9 +390,12 @@ func (p *Persistence) flushBufferLocked() error {
 
 		data, err := json.Marshal(entry)
 		if err != nil {
-			log.Printf("Error marshaling WAL entry: %v", err)
+			persistenceLog.Printf("Error marshaling WAL entry: %v", err)
 			continue
 		}
+		if !p.budget.Reserve(int64(len(data))+1, "wal") {
+			continue // The log is still processed, it just can't be recovered after a crash
+		}
 
 		// Write to file
 		n, err := p.writer.Write(append(data, '\n'))
@@ -247,10 +429,10 @@ func (p *Persistence) rotateFile() error {
 	// Close current file if open
 	if p.currentFile != nil {
 		if err := p.writer.Flush(); err != nil {
-			log.Printf("Error flushing before rotation: %v", err)
+			persistenceLog.Printf("Error flushing before rotation: %v", err)
 		}
 		if err := p.currentFile.Close(); err != nil {
-			log.Printf("Error closing WAL file: %v", err)
+			persistenceLog.Printf("Error closing WAL file: %v", err)
 		}
 	}
 
@@ -271,10 +453,11 @@ func (p *Persistence) rotateFile() error {
 	}
 
 	p.currentFile = file
+	p.activePath.Store(&filename)
 	p.writer = bufio.NewWriter(file)
 	p.currentSize = 0
 
-	log.Printf("Created new WAL file: %s", filename)
+	persistenceLog.Printf("Created new WAL file: %s", filename)
 	return nil
 }
 
@@ -297,80 +480,29 @@ func (p *Persistence) recoverAsync() {
 	defer p.wg.Done()
 	defer close(p.recoveryQueue)
 
-	files, err := filepath.Glob(filepath.Join(p.config.Dir, "wal-*.log"))
-	if err != nil {
-		log.Printf("Error listing WAL files: %v", err)
-		return
+	// Files written by previous runs only; entries up to the checkpoint were already replayed
+	checkpoint := p.checkpoint.Load()
+	var files []string
+	for _, file := range p.recoveryFiles {
+		if file.lastSeq > checkpoint {
+			files = append(files, file.path)
+		}
 	}
 
 	if len(files) == 0 {
-		log.Println("No WAL files found for recovery")
-		return
-	}
-
-	log.Printf("Found %d WAL files for recovery", len(files))
-
-	recoveredCount := 0
-	for _, filename := range files {
-		count, err := p.recoverFile(filename)
-		if err != nil {
-			log.Printf("Error recovering from %s: %v", filename, err)
-			continue
+		if len(p.recoveryFiles) > 0 {
+			persistenceLog.Printf("No WAL entries to recover after checkpoint %d", checkpoint)
+		} else {
+			persistenceLog.Println("No WAL files found for recovery")
 		}
-		recoveredCount += count
-	}
-
-	log.Printf("Recovery complete: %d logs recovered from %d files", recoveredCount, len(files))
-}
-
-// recoverFile recovers logs from a single WAL file
-func (p *Persistence) recoverFile(filename string) (int, error) {
-	// Validate that the file is within our configured directory
-	if err := validateFileInDirectory(filename, p.config.Dir); err != nil {
-		return 0, fmt.Errorf("invalid WAL file path: %w", err)
-	}
-
-	file, err := os.Open(filename) // #nosec G304 - path validated by validateFileInDirectory above
-	if err != nil {
-		return 0, fmt.Errorf("failed to open WAL file: %w", err)
+		return
 	}
-	defer func() { _ = file.Close() }()
-
-	reader := bufio.NewReader(file)
-	count := 0
-
-	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return count, fmt.Errorf("error reading WAL file: %w", err)
-		}
 
-		var entry WALEntry
-		if err := json.Unmarshal(line, &entry); err != nil {
-			log.Printf("Error unmarshaling WAL entry: %v", err)
-			continue
-		}
+	persistenceLog.Printf("Found %d WAL files for recovery (resuming after sequence %d)", len(files), checkpoint)
 
-		// Update sequence number
-		p.sequenceMu.Lock()
-		if entry.Sequence > p.sequenceNum {
-			p.sequenceNum = entry.Sequence
-		}
-		p.sequenceMu.Unlock()
-
-		// Send to recovery queue
-		select {
-		case p.recoveryQueue <- entry.Log:
-			count++
-		case <-p.stopCh:
-			return count, nil
-		}
-	}
+	recoveredCount := p.recoverFiles(files, checkpoint)
 
-	return count, nil
+	persistenceLog.Printf("Recovery complete: %d logs recovered from %d files", recoveredCount, len(files))
 }
 
 // cleanupLoop periodically removes old WAL files
@@ -398,7 +530,7 @@ func (p *Persistence) cleanup() {
 
 	files, err := filepath.Glob(filepath.Join(p.config.Dir, "wal-*.log"))
 	if err != nil {
-		log.Printf("Error listing WAL files for cleanup: %v", err)
+		persistenceLog.Printf("Error listing WAL files for cleanup: %v", err)
 		return
 	}
 
@@ -416,18 +548,39 @@ func (p *Persistence) cleanup() {
 			if p.currentFile != nil && filename == p.currentFile.Name() {
 				continue
 			}
+			// Nor a file whose entries recovery has not replayed yet
+			if p.pendingRecovery(filename) {
+				continue
+			}
 
 			if err := os.Remove(filename); err != nil {
-				log.Printf("Error removing old WAL file %s: %v", filename, err)
+				persistenceLog.Printf("Error removing old WAL file %s: %v", filename, err)
 			} else {
+				p.budget.Release(info.Size())
 				removedCount++
 			}
 		}
 	}
 
 	if removedCount > 0 {
-		log.Printf("Cleaned up %d old WAL files", removedCount)
+		persistenceLog.Printf("Cleaned up %d old WAL files", removedCount)
+	}
+}
+
+// rotatedFiles lists WAL files other than the one being written, for disk budget pruning
+func (p *Persistence) rotatedFiles() []string {
+	files, err := filepath.Glob(filepath.Join(p.config.Dir, "wal-*.log"))
+	if err != nil {
+		return nil
 	}
+	active := p.activePath.Load()
+	rotated := files[:0]
+	for _, file := range files {
+		if active == nil || file != *active {
+			rotated = append(rotated, file)
+		}
+	}
+	return rotated
 }
 
 // Close shuts down the persistence handler
@@ -436,25 +589,22 @@ func (p *Persistence) Close() error {
 		return nil
 	}
 
-	log.Println("Shutting down persistence...")
+	persistenceLog.Println("Shutting down persistence...")
 
 	// Stop background goroutines
 	close(p.stopCh)
-	if p.flushTicker != nil {
-		p.flushTicker.Stop()
-	}
 
 	// Final flush
 	p.bufferMu.Lock()
 	if err := p.flushBufferLocked(); err != nil {
-		log.Printf("Error during final flush: %v", err)
+		persistenceLog.Printf("Error during final flush: %v", err)
 	}
 	p.bufferMu.Unlock()
 
 	// Close file
 	if p.currentFile != nil {
 		if err := p.writer.Flush(); err != nil {
-			log.Printf("Error flushing writer: %v", err)
+			persistenceLog.Printf("Error flushing writer: %v", err)
 		}
 		if err := p.currentFile.Close(); err != nil {
 			return fmt.Errorf("failed to close WAL file: %w", err)
@@ -464,6 +614,6 @@ func (p *Persistence) Close() error {
 	// Wait for goroutines
 	p.wg.Wait()
 
-	log.Println("Persistence shut down complete")
+	persistenceLog.Println("Persistence shut down complete")
 	return nil
 }
@@ -2,8 +2,10 @@ package core
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -93,6 +95,59 @@ func TestPersistence_BasicWriteAndRecover(t *testing.T) {
 	}
 }
 
+func TestPersistence_MinLevel(t *testing.T) {
+	config := checkpointTestConfig(t.TempDir())
+	config.MinLevel = "warning" // Aliases resolve through the shared vocabulary
+
+	p, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	for _, log := range []*Log{
+		NewLog("debug", "debug message"),
+		NewLog("INFO", "info message"),
+		NewLog("WARN", "warn message"),
+		NewLog("err", "error message"),
+		NewLog("trace", "unknown level message"),
+	} {
+		if err := p.Persist(log); err != nil {
+			t.Fatalf("Failed to persist log: %v", err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Failed to close persistence: %v", err)
+	}
+
+	p2, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence for recovery: %v", err)
+	}
+	defer func() { _ = p2.Close() }()
+
+	messages := recoverMessages(t, p2, 0)
+	want := []string{"warn message", "error message"}
+	if fmt.Sprint(messages) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+}
+
+func TestPersistenceConfig_MinLevelValidation(t *testing.T) {
+	config := DefaultPersistenceConfig()
+	config.MinLevel = "error"
+	if err := config.validateMinLevel(LevelsConfig{}); err != nil {
+		t.Errorf("Expected error to be a known level, got %v", err)
+	}
+
+	config.MinLevel = "fatal"
+	if err := config.validateMinLevel(LevelsConfig{}); err == nil || !strings.Contains(err.Error(), "not a known level") {
+		t.Errorf("Expected unknown level error, got %v", err)
+	}
+	levels := LevelsConfig{Order: []string{"debug", "info", "warn", "error", "fatal"}}
+	if err := config.validateMinLevel(levels); err != nil {
+		t.Errorf("Expected fatal to be known with custom levels, got %v", err)
+	}
+}
+
 func TestPersistence_BufferFlush(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -144,6 +199,95 @@ func TestPersistence_BufferFlush(t *testing.T) {
 	}
 }
 
+func TestPersistence_HighWaterMarkFlush(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	config := PersistenceConfig{
+		Enabled:        true,
+		Dir:            tmpDir,
+		MaxFileSize:    1024 * 1024,
+		BufferSize:     10,
+		FlushInterval:  60, // Long interval so only the watermark can trigger a flush
+		RetentionHours: 24,
+		HighWaterMark:  2,
+	}
+
+	p, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	countLines := func() int {
+		files, err := filepath.Glob(filepath.Join(tmpDir, "wal-*.log"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("Failed to list WAL files: %v", err)
+		}
+		data, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatalf("Failed to read WAL file: %v", err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	// Crossing the high-water mark flushes well before the interval
+	for i := 0; i < 2; i++ {
+		if err := p.Persist(NewLog("INFO", "burst message")); err != nil {
+			t.Fatalf("Failed to persist log: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if lines := countLines(); lines != 2 {
+		t.Fatalf("Expected 2 WAL entries after watermark flush, got %d", lines)
+	}
+
+	// Below the watermark the log stays buffered
+	if err := p.Persist(NewLog("INFO", "trailing message")); err != nil {
+		t.Fatalf("Failed to persist log: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if lines := countLines(); lines != 2 {
+		t.Errorf("Expected buffered log not to be flushed yet, got %d WAL entries", lines)
+	}
+}
+
+func TestNextFlushInterval(t *testing.T) {
+	base := 5 * time.Second
+	maxInterval := 30 * time.Second
+
+	if got := nextFlushInterval(base, base, maxInterval, false); got != 10*time.Second {
+		t.Errorf("Expected idle interval to double to 10s, got %v", got)
+	}
+	if got := nextFlushInterval(20*time.Second, base, maxInterval, false); got != maxInterval {
+		t.Errorf("Expected idle interval to be capped at %v, got %v", maxInterval, got)
+	}
+	if got := nextFlushInterval(maxInterval, base, maxInterval, true); got != base {
+		t.Errorf("Expected interval to reset to %v after a flush, got %v", base, got)
+	}
+}
+
+func TestPersistenceConfig_AdaptiveDefaults(t *testing.T) {
+	config := DefaultPersistenceConfig()
+
+	if mark := config.highWaterMark(); mark != 75 {
+		t.Errorf("Expected default high-water mark 75, got %d", mark)
+	}
+
+	base, maxInterval := config.flushIntervals()
+	if base != 5*time.Second || maxInterval != 30*time.Second {
+		t.Errorf("Expected intervals 5s/30s, got %v/%v", base, maxInterval)
+	}
+
+	config.HighWaterMark = 10
+	config.MaxFlushInterval = 60
+	base, maxInterval = config.flushIntervals()
+	if config.highWaterMark() != 10 || maxInterval != time.Minute || base != 5*time.Second {
+		t.Errorf("Expected configured values, got mark=%d max=%v", config.highWaterMark(), maxInterval)
+	}
+}
+
 func TestPersistence_FileRotation(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -0,0 +1,412 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxPluginBodyBytes bounds the size of a plugin definition sent to the API
+const maxPluginBodyBytes = 1 << 20
+
+var (
+	errPluginAPIDisabled = errors.New("runtime plugin changes are not available")
+	errWriteBack         = errors.New("change applied but not written back")
+)
+
+// PluginBuilder constructs the plugins added through the API (POST /pipelines,
+// PUT /inputs/<name>) from their definition, like a ReloadBuilder does for a
+// whole configuration. It must not start them: Input returns the function that
+// adds the input to the engine, which starts it once it is added.
+type PluginBuilder struct {
+	Input    func(name string, def PluginDefinition, resilience ResilienceConfig) (install func(*Engine), err error)
+	Pipeline func(name string, def PluginDefinition, resilience ResilienceConfig) (*OutputPipeline, error)
+}
+
+// SetPluginBuilder enables adding, replacing and removing plugins at runtime
+func (e *Engine) SetPluginBuilder(builder PluginBuilder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pluginBuilder = &builder
+}
+
+// CreatePipeline builds an output pipeline from its definition and adds it to
+// the running engine. The definition must be named, and the name must be unused.
+func (e *Engine) CreatePipeline(def PluginDefinition) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.checkPluginChange(); err != nil {
+		return err
+	}
+	if def.Name == "" {
+		return fmt.Errorf("output pipeline name is required")
+	}
+	if _, err := e.findPipeline(def.Name); err == nil {
+		return fmt.Errorf("output pipeline '%s' already exists", def.Name)
+	}
+
+	config, err := e.changedConfig(&def, func(config *Config) {
+		applyDefaults(config.Defaults.Outputs, &def)
+		for i := range def.Filters {
+			applyDefaults(config.Defaults.Filters, &def.Filters[i])
+		}
+		config.Outputs = append(config.Outputs, def)
+	})
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := e.pluginBuilder.Pipeline(def.Name, def, e.resilience())
+	if err != nil {
+		return fmt.Errorf("failed to build output pipeline '%s': %w", def.Name, err)
+	}
+	if err := e.AddOutputPipeline(pipeline); err != nil {
+		if closeErr := pipeline.Output.Close(); closeErr != nil {
+			engineLog.Printf("Error closing output %s: %v", pipeline.Name, closeErr)
+		}
+		return err
+	}
+	e.publishOutputEvent(pipeline, EventPluginStarted)
+	engineLog.Printf("Output pipeline '%s' (%s) added through the API", def.Name, def.Type)
+
+	return e.applyPluginChange(config)
+}
+
+// DeletePipeline removes the named output pipeline from the running engine and
+// closes its output once no log is being dispatched to it.
+func (e *Engine) DeletePipeline(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.checkPluginChange(); err != nil {
+		return err
+	}
+	if _, err := e.findPipeline(name); err != nil {
+		return err
+	}
+
+	config, err := e.changedConfig(nil, func(config *Config) {
+		config.Outputs = withoutPlugin(config.Outputs, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Holding dispatchMu waits out a dispatch still writing to the pipeline from
+	// the previous plugin set, so nothing uses it once it is closed below
+	var pipeline *OutputPipeline
+	e.dispatchMu.Lock()
+	e.updatePlugins(func(plugins *pluginSet) {
+		kept := plugins.pipelines[:0]
+		for _, p := range plugins.pipelines {
+			if p.Name == name {
+				pipeline = p
+				continue
+			}
+			kept = append(kept, p)
+		}
+		plugins.pipelines = kept
+	})
+	e.dispatchMu.Unlock()
+
+	pipeline.stopLevelOverrideTimer()
+	pipeline.stopShadow()
+	if pipeline.Buffer != nil {
+		if err := pipeline.Buffer.Close(); err != nil {
+			engineLog.Printf("Error closing buffer for %s: %v", pipeline.Name, err)
+		}
+	} else if err := pipeline.Output.Close(); err != nil {
+		engineLog.Printf("Error closing output %s: %v", pipeline.Name, err)
+	}
+	e.publishOutputEvent(pipeline, EventPluginStopped)
+	engineLog.Printf("Output pipeline '%s' removed through the API", name)
+
+	return e.applyPluginChange(config)
+}
+
+// ReplaceInput builds an input from its definition and adds it to the running
+// engine under name, stopping the input it replaces, if any. The old input is
+// stopped before the new one starts, so both can use the same port.
+func (e *Engine) ReplaceInput(name string, def PluginDefinition) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.checkPluginChange(); err != nil {
+		return err
+	}
+	if def.Name != "" && def.Name != name {
+		return fmt.Errorf("input name '%s' does not match '%s'", def.Name, name)
+	}
+	def.Name = name
+
+	config, err := e.changedConfig(&def, func(config *Config) {
+		applyDefaults(config.Defaults.Inputs, &def)
+		for i, input := range config.Inputs {
+			if pluginName(input, i) == name {
+				config.Inputs[i] = def
+				return
+			}
+		}
+		config.Inputs = append(config.Inputs, def)
+	})
+	if err != nil {
+		return err
+	}
+
+	install, err := e.pluginBuilder.Input(name, def, e.resilience())
+	if err != nil {
+		return fmt.Errorf("failed to build input '%s': %w", name, err)
+	}
+
+	old, replaced := e.plugins.Load().inputs[name]
+	if replaced {
+		if err := old.Stop(); err != nil {
+			engineLog.Printf("Error stopping input plugin %s: %v", name, err)
+		}
+		e.publishInputEvent(name, old, EventPluginStopped, nil)
+		e.updatePlugins(func(plugins *pluginSet) {
+			delete(plugins.inputs, name)
+			delete(plugins.inputTypes, name)
+		})
+	}
+
+	install(e)
+	if e.started.Load() {
+		input := e.plugins.Load().inputs[name]
+		if err := input.Start(); err != nil {
+			e.publishInputEvent(name, input, EventPluginUnhealthy, err)
+			return fmt.Errorf("input '%s' added but failed to start: %w", name, err)
+		}
+		e.publishInputEvent(name, input, EventPluginStarted, nil)
+	}
+	if replaced {
+		engineLog.Printf("Input '%s' (%s) replaced through the API", name, def.Type)
+	} else {
+		engineLog.Printf("Input '%s' (%s) added through the API", name, def.Type)
+	}
+
+	return e.applyPluginChange(config)
+}
+
+// checkPluginChange reports whether plugins can be changed at runtime. The
+// caller holds e.mu, which keeps reloads and shutdown out until it is done.
+func (e *Engine) checkPluginChange() error {
+	if e.pluginBuilder == nil {
+		return errPluginAPIDisabled
+	}
+	if e.stopped {
+		return errEngineUnavailable
+	}
+	return nil
+}
+
+// resilience returns the resilience defaults of the applied configuration
+func (e *Engine) resilience() ResilienceConfig {
+	if e.appliedConfig == nil {
+		return ResilienceConfig{}
+	}
+	return e.appliedConfig.Resilience
+}
+
+// changedConfig returns the applied configuration with a change made to its
+// plugin lists, validated as a whole so references such as error_context.target
+// stay valid. Without an applied configuration only def is validated and nil is
+// returned. def is validated after the change, which may merge defaults into it.
+func (e *Engine) changedConfig(def *PluginDefinition, change func(*Config)) (*Config, error) {
+	if e.appliedConfig == nil {
+		if def != nil {
+			if err := def.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid plugin definition: %w", err)
+			}
+		}
+		return nil, nil
+	}
+
+	config := *e.appliedConfig
+	config.Inputs = append([]PluginDefinition(nil), config.Inputs...)
+	config.Outputs = append([]PluginDefinition(nil), config.Outputs...)
+	change(&config)
+	if def != nil {
+		if err := def.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid plugin definition: %w", err)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &config, nil
+}
+
+// withoutPlugin returns defs without the named plugin
+func withoutPlugin(defs []PluginDefinition, name string) []PluginDefinition {
+	kept := make([]PluginDefinition, 0, len(defs))
+	for i, def := range defs {
+		if pluginName(def, i) != name {
+			kept = append(kept, def)
+		}
+	}
+	return kept
+}
+
+// applyPluginChange records a configuration changed through the API as the
+// applied one, in the reload audit trail, and in the api.write_back file
+func (e *Engine) applyPluginChange(config *Config) error {
+	if config == nil {
+		return nil
+	}
+	changes := DiffConfigs(e.appliedConfig, config)
+	e.appliedConfig = config
+	e.reloadAudit.Record(ReloadEvent{Trigger: ReloadTriggerAPI, Result: ReloadResultSuccess, Changes: changes})
+
+	if e.apiConfig.WriteBack == "" {
+		return nil
+	}
+	if err := writeConfigFile(e.apiConfig.WriteBack, config); err != nil {
+		apiLog.Printf("Error writing configuration to %s: %v", e.apiConfig.WriteBack, err)
+		return fmt.Errorf("%w to %s: %v", errWriteBack, e.apiConfig.WriteBack, err)
+	}
+	apiLog.Printf("Configuration written to %s", e.apiConfig.WriteBack)
+	return nil
+}
+
+// writeConfigFile replaces a config file with config. The file is written next
+// to its destination and renamed over it, so a crash never leaves half a file.
+func writeConfigFile(path string, config *Config) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// decodePluginDefinition reads a plugin definition from a request body, in JSON
+// or YAML; both use the field names of the config file
+func decodePluginDefinition(w http.ResponseWriter, r *http.Request) (PluginDefinition, error) {
+	var def PluginDefinition
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPluginBodyBytes))
+	if err != nil {
+		return def, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&def); err != nil {
+		if errors.Is(err, io.EOF) {
+			return def, fmt.Errorf("empty body")
+		}
+		return def, err
+	}
+	return def, nil
+}
+
+// pluginChangeStatus returns the HTTP status for an error from a runtime plugin change
+func pluginChangeStatus(err error) int {
+	switch {
+	case errors.Is(err, errPipelineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errPluginAPIDisabled):
+		return http.StatusNotImplemented
+	case errors.Is(err, errEngineUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, errWriteBack):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// writePluginChange responds to a runtime plugin change
+func writePluginChange(w http.ResponseWriter, status int, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding plugin response: %v", err)
+	}
+}
+
+// handlePipelineCreate adds an output pipeline via POST /pipelines
+func (e *Engine) handlePipelineCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	def, err := decodePluginDefinition(w, r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid output definition: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := e.CreatePipeline(def); err != nil {
+		http.Error(w, err.Error(), pluginChangeStatus(err))
+		return
+	}
+
+	writePluginChange(w, http.StatusCreated, map[string]interface{}{
+		"name":    def.Name,
+		"type":    def.Type,
+		"created": true,
+	})
+}
+
+// handlePipelineDelete removes an output pipeline via DELETE /pipelines/<name>
+func (e *Engine) handlePipelineDelete(w http.ResponseWriter, r *http.Request, name string) {
+	if err := e.DeletePipeline(name); err != nil {
+		http.Error(w, err.Error(), pluginChangeStatus(err))
+		return
+	}
+
+	writePluginChange(w, http.StatusOK, map[string]interface{}{
+		"name":    name,
+		"deleted": true,
+	})
+}
+
+// handleInputReplace adds or replaces an input via PUT /inputs/<name>
+func (e *Engine) handleInputReplace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/inputs/")
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "Expected /inputs/<name>", http.StatusNotFound)
+		return
+	}
+
+	def, err := decodePluginDefinition(w, r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid input definition: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := e.ReplaceInput(name, def); err != nil {
+		http.Error(w, err.Error(), pluginChangeStatus(err))
+		return
+	}
+
+	writePluginChange(w, http.StatusOK, map[string]interface{}{
+		"name": name,
+		"type": def.Type,
+	})
+}
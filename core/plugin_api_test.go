@@ -0,0 +1,195 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testPluginBuilder builds mock plugins and remembers them by name
+type testPluginBuilder struct {
+	mu      sync.Mutex
+	inputs  map[string]*mockInput
+	outputs map[string]*mockOutput
+}
+
+func (b *testPluginBuilder) builder() PluginBuilder {
+	return PluginBuilder{
+		Input: func(name string, def PluginDefinition, resilience ResilienceConfig) (func(*Engine), error) {
+			input := newMockInput(nil)
+			b.mu.Lock()
+			b.inputs[name] = input
+			b.mu.Unlock()
+			return func(e *Engine) { e.AddInputWithType(name, def.Type, input) }, nil
+		},
+		Pipeline: func(name string, def PluginDefinition, resilience ResilienceConfig) (*OutputPipeline, error) {
+			output := newMockOutput()
+			b.mu.Lock()
+			b.outputs[name] = output
+			b.mu.Unlock()
+			return &OutputPipeline{Name: name, Type: def.Type, Output: output, Sources: def.Sources}, nil
+		},
+	}
+}
+
+// newPluginAPIEngine returns a started engine with one input and one output
+// that writes its configuration back to a file
+func newPluginAPIEngine(t *testing.T) (*Engine, *testPluginBuilder, string) {
+	t.Helper()
+	writeBack := filepath.Join(t.TempDir(), "config.yaml")
+
+	engine := NewEngine()
+	engine.apiConfig.WriteBack = writeBack
+	engine.SetAppliedConfig(&Config{
+		Inputs:  []PluginDefinition{{Type: "http", Name: "web", Config: map[string]any{"port": 8080}}},
+		Outputs: []PluginDefinition{{Type: "console", Name: "out", Config: map[string]any{"target": "stdout"}}},
+	})
+	builder := &testPluginBuilder{inputs: map[string]*mockInput{}, outputs: map[string]*mockOutput{}}
+	engine.SetPluginBuilder(builder.builder())
+
+	engine.AddInputWithType("web", "http", newMockInput(nil))
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "out", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	engine.Start()
+	t.Cleanup(engine.Stop)
+	return engine, builder, writeBack
+}
+
+func TestPluginAPI_Pipelines(t *testing.T) {
+	engine, builder, writeBack := newPluginAPIEngine(t)
+
+	w := httptest.NewRecorder()
+	engine.handlePipelineCreate(w, httptest.NewRequest("POST", "/pipelines",
+		strings.NewReader(`{"type": "console", "name": "audit", "config": {"target": "stderr"}, "sources": ["web"]}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	logEntry := NewLog("info", "hello")
+	logEntry.Source = "web"
+	engine.dispatchLog(logEntry)
+	if got := len(builder.outputs["audit"].getLogs()); got != 1 {
+		t.Errorf("Expected the new pipeline to receive the log, got %d logs", got)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePipelineCreate(w, httptest.NewRequest("POST", "/pipelines",
+		strings.NewReader(`{"type": "console", "name": "audit", "config": {"target": "stderr"}}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "already exists") {
+		t.Errorf("Expected a duplicate name to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	config, err := LoadConfig(writeBack)
+	if err != nil {
+		t.Fatalf("Failed to load the written back config: %v", err)
+	}
+	if len(config.Outputs) != 2 || config.Outputs[1].Name != "audit" || config.Outputs[1].Sources[0] != "web" {
+		t.Errorf("Expected the new pipeline in the written back config, got %+v", config.Outputs)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("DELETE", "/pipelines/audit", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := engine.findPipeline("audit"); err == nil {
+		t.Error("Expected the pipeline to be removed")
+	}
+
+	w = httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("DELETE", "/pipelines/audit", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a removed pipeline, got %d", w.Code)
+	}
+
+	// The last output cannot be removed: the configuration would not validate
+	w = httptest.NewRecorder()
+	engine.handlePipelineToggle(w, httptest.NewRequest("DELETE", "/pipelines/out", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected removing the last output to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	config, err = LoadConfig(writeBack)
+	if err != nil {
+		t.Fatalf("Failed to load the written back config: %v", err)
+	}
+	if len(config.Outputs) != 1 || config.Outputs[0].Name != "out" {
+		t.Errorf("Expected the removal in the written back config, got %+v", config.Outputs)
+	}
+
+	var apiEvents int
+	for _, event := range engine.ReloadEvents() {
+		if event.Trigger == ReloadTriggerAPI {
+			apiEvents++
+		}
+	}
+	if apiEvents != 2 {
+		t.Errorf("Expected 2 API changes in the reload audit trail, got %d", apiEvents)
+	}
+}
+
+func TestPluginAPI_ReplaceInput(t *testing.T) {
+	engine, builder, writeBack := newPluginAPIEngine(t)
+
+	w := httptest.NewRecorder()
+	engine.handleInputReplace(w, httptest.NewRequest("PUT", "/inputs/web",
+		strings.NewReader("type: http\nconfig:\n  port: 9000\n")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if engine.plugins.Load().inputs["web"] != builder.inputs["web"] {
+		t.Error("Expected the input to be replaced")
+	}
+
+	config, err := LoadConfig(writeBack)
+	if err != nil {
+		t.Fatalf("Failed to load the written back config: %v", err)
+	}
+	if len(config.Inputs) != 1 || config.Inputs[0].Config["port"] != 9000 {
+		t.Errorf("Expected the replaced input in the written back config, got %+v", config.Inputs)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleInputReplace(w, httptest.NewRequest("PUT", "/inputs/web",
+		strings.NewReader(`{"type": "http", "name": "other", "config": {"port": 9000}}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a mismatched name to be rejected, got %d", w.Code)
+	}
+}
+
+func TestPluginAPI_Rejected(t *testing.T) {
+	engine, builder, _ := newPluginAPIEngine(t)
+
+	bodies := map[string]string{
+		"unknown type":  `{"type": "bogus", "name": "x", "config": {"a": 1}}`,
+		"no config":     `{"type": "console", "name": "x"}`,
+		"no name":       `{"type": "console", "config": {"a": 1}}`,
+		"unknown field": `{"type": "console", "name": "x", "config": {"a": 1}, "sorces": ["web"]}`,
+		"empty":         ``,
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.handlePipelineCreate(w, httptest.NewRequest("POST", "/pipelines", strings.NewReader(body)))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+	if len(builder.outputs) != 0 {
+		t.Errorf("Expected nothing to be built for rejected definitions, got %d outputs", len(builder.outputs))
+	}
+
+	disabled := NewEngine()
+	w := httptest.NewRecorder()
+	disabled.handlePipelineCreate(w, httptest.NewRequest("POST", "/pipelines",
+		strings.NewReader(`{"type": "console", "name": "x", "config": {"a": 1}}`)))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without a plugin builder, got %d", w.Code)
+	}
+}
@@ -2,10 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
 )
 
 // PluginHealth represents the health status of a plugin
@@ -41,6 +44,7 @@ type HealthChecker interface {
 type ResilientPlugin struct {
 	name           string
 	pluginType     string
+	kind           string // PluginKindInput or PluginKindOutput, reported in lifecycle events
 	factory        PluginFactory
 	config         map[string]any
 	plugin         any
@@ -49,7 +53,11 @@ type ResilientPlugin struct {
 	lastHealthy    time.Time
 	retryInterval  time.Duration
 	maxRetries     int
+	jitter         string       // Retry jitter mode (see ApplyJitter)
+	random         JitterSource // Randomness for retry jitter (nil = default source)
 	currentRetries int
+	checkTimeout   time.Duration // Bound of a single health check
+	checking       atomic.Bool   // A health check is running, possibly past its timeout
 	mu             sync.RWMutex
 	ctx            context.Context
 	cancel         context.CancelFunc
@@ -58,11 +66,22 @@ type ResilientPlugin struct {
 
 // ResilientPluginConfig configures resilient plugin behavior
 type ResilientPluginConfig struct {
-	RetryInterval time.Duration // Time between retry attempts
-	MaxRetries    int           // Maximum retries before giving up (0 = infinite)
-	HealthCheck   time.Duration // Health check interval (0 = disabled)
+	RetryInterval      time.Duration // Time between retry attempts
+	MaxRetries         int           // Maximum retries before giving up (0 = infinite)
+	HealthCheck        time.Duration // Health check interval (0 = disabled)
+	HealthCheckTimeout time.Duration // Max duration of one health check (0 = DefaultHealthCheckTimeout, or half the interval if shorter)
+	Jitter             string        // Retry jitter: none (default), full or equal
+	Random             JitterSource  // Randomness for retry jitter (nil = default source)
+
+	kind string // Set by the input and output wrappers
 }
 
+// maxResilientBackoff caps the delay between initialization attempts
+const maxResilientBackoff = 2 * time.Minute
+
+// DefaultHealthCheckTimeout bounds a health check when no timeout is configured
+const DefaultHealthCheckTimeout = 10 * time.Second
+
 // DefaultResilientPluginConfig returns default configuration
 func DefaultResilientPluginConfig() ResilientPluginConfig {
 	return ResilientPluginConfig{
@@ -72,6 +91,31 @@ func DefaultResilientPluginConfig() ResilientPluginConfig {
 	}
 }
 
+// FailFastFactory makes the first attempt to create a plugin right away, so a
+// misconfigured plugin fails startup instead of retrying in the background. On
+// success the returned factory hands out that plugin on its first call and
+// delegates to factory afterwards, so later reconnects still follow the
+// resilient plugin's retry interval and max retries.
+func FailFastFactory(factory PluginFactory, config map[string]any) (PluginFactory, error) {
+	plugin, err := factory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	return func(cfg map[string]any) (any, error) {
+		mu.Lock()
+		first := plugin
+		plugin = nil
+		mu.Unlock()
+
+		if first != nil {
+			return first, nil
+		}
+		return factory(cfg)
+	}, nil
+}
+
 // NewResilientPlugin creates a new resilient plugin wrapper
 func NewResilientPlugin(name, pluginType string, factory PluginFactory, config map[string]any, resilientConfig ResilientPluginConfig) *ResilientPlugin {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -79,11 +123,15 @@ func NewResilientPlugin(name, pluginType string, factory PluginFactory, config m
 	rp := &ResilientPlugin{
 		name:          name,
 		pluginType:    pluginType,
+		kind:          resilientConfig.kind,
 		factory:       factory,
 		config:        config,
 		health:        HealthUnknown,
 		retryInterval: resilientConfig.RetryInterval,
 		maxRetries:    resilientConfig.MaxRetries,
+		jitter:        resilientConfig.Jitter,
+		random:        resilientConfig.Random,
+		checkTimeout:  resilientConfig.HealthCheckTimeout,
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -92,6 +140,14 @@ func NewResilientPlugin(name, pluginType string, factory PluginFactory, config m
 	rp.wg.Add(1)
 	go rp.initialize()
 
+	// A check that times out should have returned before the next one is due
+	if rp.checkTimeout <= 0 {
+		rp.checkTimeout = DefaultHealthCheckTimeout
+		if half := resilientConfig.HealthCheck / 2; half > 0 && half < rp.checkTimeout {
+			rp.checkTimeout = half
+		}
+	}
+
 	// Start health checker if configured
 	if resilientConfig.HealthCheck > 0 {
 		rp.wg.Add(1)
@@ -101,6 +157,11 @@ func NewResilientPlugin(name, pluginType string, factory PluginFactory, config m
 	return rp
 }
 
+// logger returns the internal logger for this plugin, named after its instance
+func (rp *ResilientPlugin) logger() *logging.Logger {
+	return resilienceLog.Named(rp.name)
+}
+
 // initialize attempts to create the plugin with retries
 func (rp *ResilientPlugin) initialize() {
 	defer rp.wg.Done()
@@ -114,33 +175,39 @@ func (rp *ResilientPlugin) initialize() {
 		default:
 		}
 
-		log.Printf("[RESILIENCE:%s] Attempting to initialize %s plugin (attempt %d)",
-			rp.name, rp.pluginType, rp.currentRetries+1)
+		rp.logger().Printf("Attempting to initialize %s plugin (attempt %d)",
+			rp.pluginType, rp.currentRetries+1)
 
 		plugin, err := rp.factory(rp.config)
 		if err != nil {
 			rp.mu.Lock()
+			previous := rp.health
 			rp.health = HealthUnhealthy
 			rp.lastError = err
 			rp.currentRetries++
 			rp.mu.Unlock()
 
-			log.Printf("[RESILIENCE:%s] Failed to initialize: %v", rp.name, err)
+			if previous != HealthUnhealthy {
+				rp.publish(EventPluginUnhealthy, previous, HealthUnhealthy, err)
+			}
+
+			rp.logger().Printf("Failed to initialize: %v", err)
 
 			// Check if max retries reached
 			if rp.maxRetries > 0 && rp.currentRetries >= rp.maxRetries {
-				log.Printf("[RESILIENCE:%s] Max retries (%d) reached, giving up", rp.name, rp.maxRetries)
+				rp.logger().Printf("Max retries (%d) reached, giving up", rp.maxRetries)
 				return
 			}
 
-			// Wait before retry with exponential backoff (capped at 2 minutes)
-			log.Printf("[RESILIENCE:%s] Retrying in %v...", rp.name, backoff)
+			// Wait before retry with exponential backoff (capped at 2 minutes, then jittered)
+			delay := ApplyJitter(backoff, rp.jitter, rp.random)
+			rp.logger().Printf("Retrying in %v...", delay)
 			select {
-			case <-time.After(backoff):
+			case <-time.After(delay):
 				// Exponential backoff with cap
 				backoff = backoff * 2
-				if backoff > 2*time.Minute {
-					backoff = 2 * time.Minute
+				if backoff > maxResilientBackoff {
+					backoff = maxResilientBackoff
 				}
 			case <-rp.ctx.Done():
 				return
@@ -150,6 +217,8 @@ func (rp *ResilientPlugin) initialize() {
 
 		// Success!
 		rp.mu.Lock()
+		previous := rp.health
+		attempts := rp.currentRetries
 		rp.plugin = plugin
 		rp.health = HealthHealthy
 		rp.lastError = nil
@@ -157,22 +226,31 @@ func (rp *ResilientPlugin) initialize() {
 		rp.currentRetries = 0
 		rp.mu.Unlock()
 
-		log.Printf("[RESILIENCE:%s] Successfully initialized %s plugin", rp.name, rp.pluginType)
+		rp.logger().Printf("Successfully initialized %s plugin", rp.pluginType)
 
 		// If it's an input plugin, start it
 		if inputPlugin, ok := plugin.(InputPlugin); ok {
 			if err := inputPlugin.Start(); err != nil {
-				log.Printf("[RESILIENCE:%s] Failed to start input plugin: %v", rp.name, err)
+				rp.logger().Printf("Failed to start input plugin: %v", err)
 				rp.mu.Lock()
 				rp.health = HealthUnhealthy
 				rp.lastError = err
 				rp.plugin = nil
 				rp.mu.Unlock()
+				if previous != HealthUnhealthy {
+					rp.publish(EventPluginUnhealthy, previous, HealthUnhealthy, err)
+				}
 				continue // Retry
 			}
-			log.Printf("[RESILIENCE:%s] Input plugin started", rp.name)
+			rp.logger().Printf("Input plugin started")
 		}
 
+		event := LifecycleEvent{Type: EventPluginStarted}
+		if attempts > 0 {
+			event = LifecycleEvent{Type: EventPluginReconnected, Attempts: attempts}
+		}
+		event.OldState, event.NewState = previous.String(), HealthHealthy.String()
+		rp.publishEvent(event)
 		return
 	}
 }
@@ -207,29 +285,68 @@ func (rp *ResilientPlugin) performHealthCheck() {
 
 	// If plugin implements HealthChecker, use it
 	if checker, ok := plugin.(HealthChecker); ok {
-		ctx, cancel := context.WithTimeout(rp.ctx, 10*time.Second)
-		defer cancel()
+		err := rp.checkHealth(checker)
+		if rp.ctx.Err() != nil {
+			return // Closing: an interrupted check says nothing about the plugin
+		}
 
-		err := checker.CheckHealth(ctx)
 		rp.mu.Lock()
 		if err != nil {
 			if currentHealth == HealthHealthy {
-				log.Printf("[RESILIENCE:%s] Health check failed: %v", rp.name, err)
+				rp.logger().Printf("Health check failed: %v", err)
 			}
 			rp.health = HealthUnhealthy
 			rp.lastError = err
 		} else {
 			if currentHealth != HealthHealthy {
-				log.Printf("[RESILIENCE:%s] Health check passed, plugin recovered", rp.name)
+				rp.logger().Printf("Health check passed, plugin recovered")
 			}
 			rp.health = HealthHealthy
 			rp.lastHealthy = time.Now()
 			rp.lastError = nil
 		}
 		rp.mu.Unlock()
+
+		switch {
+		case err != nil && currentHealth != HealthUnhealthy:
+			rp.publish(EventPluginUnhealthy, currentHealth, HealthUnhealthy, err)
+		case err == nil && currentHealth != HealthHealthy:
+			rp.publish(EventPluginHealthy, currentHealth, HealthHealthy, nil)
+		}
 	}
 }
 
+// checkHealth runs one health check bounded by the health check timeout. The
+// check runs on its own goroutine, so a CheckHealth that ignores its context
+// still fails at the timeout instead of stalling the health loop. Until such a
+// check returns, later checks fail without starting another one.
+func (rp *ResilientPlugin) checkHealth(checker HealthChecker) error {
+	if !rp.checking.CompareAndSwap(false, true) {
+		return fmt.Errorf("previous health check still running after %s timeout", rp.checkTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(rp.ctx, rp.checkTimeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		err := checker.CheckHealth(ctx)
+		rp.checking.Store(false)
+		result <- err
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("health check timed out after %s: %w", rp.checkTimeout, err)
+	}
+	return err
+}
+
 // GetPlugin returns the underlying plugin if healthy
 func (rp *ResilientPlugin) GetPlugin() (any, error) {
 	rp.mu.RLock()
@@ -294,22 +411,41 @@ func (rp *ResilientPlugin) Close() error {
 
 	rp.mu.Lock()
 	plugin := rp.plugin
+	health := rp.health
 	rp.plugin = nil
 	rp.mu.Unlock()
 
-	if plugin == nil {
-		return nil
+	var err error
+	switch p := plugin.(type) {
+	case InputPlugin:
+		err = p.Stop()
+	case OutputPlugin:
+		err = p.Close()
 	}
 
-	// Close based on plugin type
-	if inputPlugin, ok := plugin.(InputPlugin); ok {
-		return inputPlugin.Stop()
+	event := LifecycleEvent{Type: EventPluginStopped, OldState: health.String(), NewState: "stopped"}
+	if err != nil {
+		event.Error = err.Error()
 	}
-	if outputPlugin, ok := plugin.(OutputPlugin); ok {
-		return outputPlugin.Close()
+	rp.publishEvent(event)
+	return err
+}
+
+// publish publishes a lifecycle event for a health transition of this plugin
+func (rp *ResilientPlugin) publish(eventType string, oldState, newState PluginHealth, err error) {
+	event := LifecycleEvent{Type: eventType, OldState: oldState.String(), NewState: newState.String()}
+	if err != nil {
+		event.Error = err.Error()
 	}
+	rp.publishEvent(event)
+}
 
-	return nil
+// publishEvent fills in this plugin's identity and publishes the event
+func (rp *ResilientPlugin) publishEvent(event LifecycleEvent) {
+	event.Plugin = rp.name
+	event.PluginType = rp.pluginType
+	event.Kind = rp.kind
+	LifecycleEvents().Publish(event)
 }
 
 // GetStats returns statistics about the resilient plugin
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -456,3 +458,198 @@ func BenchmarkResilientPlugin_ConcurrentAccess(b *testing.B) {
 		}
 	})
 }
+
+func TestResilientPlugin_RetryJitter(t *testing.T) {
+	fpf := &failingPluginFactory{failUntil: 3}
+
+	// A source returning 0 makes full jitter retry immediately instead of waiting 1s, then 2s
+	config := ResilientPluginConfig{
+		RetryInterval: time.Second,
+		Jitter:        JitterFull,
+		Random:        func() float64 { return 0 },
+	}
+
+	rp := NewResilientPlugin("test-plugin", "test", fpf.create, map[string]any{}, config)
+	defer func() { _ = rp.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := rp.WaitForHealthy(ctx); err != nil {
+		t.Errorf("Expected jittered retries to succeed quickly, got %v", err)
+	}
+}
+
+func TestFailFastFactory(t *testing.T) {
+	failing := &failingPluginFactory{failUntil: 1}
+	if _, err := FailFastFactory(failing.create, map[string]any{}); err == nil {
+		t.Error("Expected the first failure to be returned")
+	}
+	if failing.attemptCount != 1 {
+		t.Errorf("Expected exactly one attempt, got %d", failing.attemptCount)
+	}
+
+	fpf := &failingPluginFactory{}
+	factory, err := FailFastFactory(fpf.create, map[string]any{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The plugin built up front is reused by the resilient plugin instead of creating another
+	config := ResilientPluginConfig{RetryInterval: 10 * time.Millisecond, MaxRetries: 1}
+	rp := NewResilientPlugin("test-plugin", "test", factory, map[string]any{}, config)
+	defer func() { _ = rp.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := rp.WaitForHealthy(ctx); err != nil {
+		t.Fatalf("Expected plugin to be healthy, got %v", err)
+	}
+
+	fpf.mu.Lock()
+	attempts := fpf.attemptCount
+	fpf.mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("Expected the prebuilt plugin to be used, got %d factory calls", attempts)
+	}
+
+	// Later calls (reconnects) go to the real factory
+	if _, err := factory(map[string]any{}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if fpf.attemptCount != 2 {
+		t.Errorf("Expected a reconnect to call the factory, got %d calls", fpf.attemptCount)
+	}
+}
+
+// hangingPlugin's health check blocks until released, ignoring its context
+type hangingPlugin struct {
+	mockPlugin
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (h *hangingPlugin) CheckHealth(ctx context.Context) error {
+	h.calls.Add(1)
+	<-h.release
+	return nil
+}
+
+// waitForHealth polls until the plugin reaches the wanted health
+func waitForHealth(t *testing.T, rp *ResilientPlugin, want PluginHealth) error {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if health, err := rp.GetHealth(); health == want {
+			return err
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	health, _ := rp.GetHealth()
+	t.Fatalf("Expected plugin to become %s, still %s", want, health)
+	return nil
+}
+
+func TestResilientPlugin_HealthCheckTimeout(t *testing.T) {
+	sub := LifecycleEvents().Subscribe(100)
+	defer sub.Close()
+
+	plugin := &hangingPlugin{mockPlugin: mockPlugin{healthCheckOK: true}, release: make(chan struct{})}
+	factory := func(config map[string]any) (any, error) {
+		return plugin, nil
+	}
+
+	rp := NewResilientPlugin("hanging-plugin", "test", factory, map[string]any{}, ResilientPluginConfig{
+		RetryInterval:      10 * time.Millisecond,
+		HealthCheck:        20 * time.Millisecond,
+		HealthCheckTimeout: 30 * time.Millisecond,
+	})
+	defer func() { _ = rp.Close() }()
+
+	// A check that ignores its context still fails at the timeout
+	event := nextEvent(t, sub, "hanging-plugin")
+	if event.Type != EventPluginStarted {
+		t.Fatalf("Expected the plugin to start first, got %+v", event)
+	}
+	event = nextEvent(t, sub, "hanging-plugin")
+	if event.Type != EventPluginUnhealthy || !strings.Contains(event.Error, "timed out after 30ms") {
+		t.Errorf("Expected a timeout error, got %+v", event)
+	}
+
+	// While the hung check runs, later checks fail without piling up
+	time.Sleep(100 * time.Millisecond)
+	if calls := plugin.calls.Load(); calls != 1 {
+		t.Errorf("Expected a single running check, got %d calls", calls)
+	}
+	if _, err := rp.GetHealth(); err == nil || !strings.Contains(err.Error(), "still running") {
+		t.Errorf("Expected later checks to report the running check, got %v", err)
+	}
+
+	close(plugin.release)
+	if event := nextEvent(t, sub, "hanging-plugin"); event.Type != EventPluginHealthy {
+		t.Errorf("Expected the plugin to recover, got %+v", event)
+	}
+}
+
+// deadlinePlugin's health check waits for its context and records its deadline
+type deadlinePlugin struct {
+	mockPlugin
+	mu       sync.Mutex
+	deadline time.Duration
+}
+
+func (d *deadlinePlugin) CheckHealth(ctx context.Context) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		d.mu.Lock()
+		d.deadline = time.Until(deadline)
+		d.mu.Unlock()
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestResilientPlugin_HealthCheckContext(t *testing.T) {
+	plugin := &deadlinePlugin{}
+	factory := func(config map[string]any) (any, error) {
+		return plugin, nil
+	}
+
+	// Without a timeout, a check gets half the interval when that is below the default
+	rp := NewResilientPlugin("test-plugin", "test", factory, map[string]any{}, ResilientPluginConfig{
+		RetryInterval: 10 * time.Millisecond,
+		HealthCheck:   100 * time.Millisecond,
+	})
+	defer func() { _ = rp.Close() }()
+
+	err := waitForHealth(t, rp, HealthUnhealthy)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("Expected the plugin's context error to be wrapped, got %v", err)
+	}
+	plugin.mu.Lock()
+	deadline := plugin.deadline
+	plugin.mu.Unlock()
+	if deadline <= 0 || deadline > 50*time.Millisecond {
+		t.Errorf("Expected the check's context to expire within half the interval, got %s", deadline)
+	}
+
+	tests := []struct {
+		interval time.Duration
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{30 * time.Second, 0, DefaultHealthCheckTimeout},
+		{2 * time.Second, 0, time.Second},
+		{30 * time.Second, 3 * time.Second, 3 * time.Second},
+		{0, 0, DefaultHealthCheckTimeout},
+	}
+	for _, tt := range tests {
+		rp := NewResilientPlugin("test-plugin", "test", factory, map[string]any{}, ResilientPluginConfig{
+			RetryInterval:      time.Second,
+			HealthCheck:        tt.interval,
+			HealthCheckTimeout: tt.timeout,
+		})
+		if rp.checkTimeout != tt.expected {
+			t.Errorf("Interval %s, timeout %s: expected %s, got %s", tt.interval, tt.timeout, tt.expected, rp.checkTimeout)
+		}
+		_ = rp.Close()
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+// pluginSet holds the inputs, global filters and output pipelines of the engine.
+// A set is never changed once it is published: changes are made to a copy that
+// replaces it (see Engine.updatePlugins), so processLogs and the API handlers
+// read the plugins without taking a lock, and a dispatch keeps working on the set
+// it started with while an API request adds or removes a pipeline.
+type pluginSet struct {
+	inputs     map[string]InputPlugin // Map of input name -> plugin
+	inputTypes map[string]string      // Map of input name -> plugin type
+	filters    []FilterPlugin         // Global filters (deprecated, but kept for backward compatibility)
+	pipelines  []*OutputPipeline      // Output pipelines with their own filters
+}
+
+// newPluginSet returns an empty plugin set
+func newPluginSet() *pluginSet {
+	return &pluginSet{
+		inputs:     make(map[string]InputPlugin),
+		inputTypes: make(map[string]string),
+		filters:    []FilterPlugin{},
+		pipelines:  []*OutputPipeline{},
+	}
+}
+
+// clone returns a copy of the set that can be changed without affecting readers of s
+func (s *pluginSet) clone() *pluginSet {
+	c := &pluginSet{
+		inputs:     make(map[string]InputPlugin, len(s.inputs)),
+		inputTypes: make(map[string]string, len(s.inputTypes)),
+		filters:    append([]FilterPlugin{}, s.filters...),
+		pipelines:  append([]*OutputPipeline{}, s.pipelines...),
+	}
+	for name, input := range s.inputs {
+		c.inputs[name] = input
+	}
+	for name, pluginType := range s.inputTypes {
+		c.inputTypes[name] = pluginType
+	}
+	return c
+}
+
+// updatePlugins applies change to a copy of the current plugin set and publishes
+// it. Changes are serialized; readers see either the old or the new set.
+func (e *Engine) updatePlugins(change func(plugins *pluginSet)) {
+	e.pluginsMu.Lock()
+	defer e.pluginsMu.Unlock()
+	next := e.plugins.Load().clone()
+	change(next)
+	e.plugins.Store(next)
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPluginSet_CloneIsIndependent(t *testing.T) {
+	set := newPluginSet()
+	set.inputs["a"] = newMockInput(nil)
+	set.inputTypes["a"] = "http"
+	set.pipelines = append(set.pipelines, &OutputPipeline{Name: "out"})
+
+	clone := set.clone()
+	clone.inputs["b"] = newMockInput(nil)
+	clone.inputTypes["b"] = "file"
+	clone.filters = append(clone.filters, newMockFilter(true))
+	clone.pipelines[0] = &OutputPipeline{Name: "replaced"}
+
+	if len(set.inputs) != 1 || len(set.inputTypes) != 1 || len(set.filters) != 0 {
+		t.Errorf("Expected the original set to be unchanged, got %d inputs, %d types, %d filters",
+			len(set.inputs), len(set.inputTypes), len(set.filters))
+	}
+	if set.pipelines[0].Name != "out" {
+		t.Errorf("Expected the original pipelines to be unchanged, got %s", set.pipelines[0].Name)
+	}
+}
+
+// TestEngine_ConcurrentPluginChanges adds plugins while logs are processed and the
+// API reads them; run with -race to check that the plugin set is safely shared
+func TestEngine_ConcurrentPluginChanges(t *testing.T) {
+	engine := NewEngine()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "out", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	engine.Start()
+	defer engine.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			engine.AddInputWithType(fmt.Sprintf("input-%d", i), "http", newMockInput(nil))
+			engine.AddFilter(newMockFilter(true))
+			if err := engine.AddOutputPipeline(&OutputPipeline{Name: fmt.Sprintf("out-%d", i), Output: newMockOutput()}); err != nil {
+				t.Errorf("Failed to add output pipeline: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range 200 {
+			logEntry := NewLog("info", "concurrent")
+			logEntry.Source = fmt.Sprintf("input-%d", i%50)
+			engine.InputChannel() <- logEntry
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			engine.handleStatus(httptest.NewRecorder(), httptest.NewRequest("GET", "/status", nil))
+			engine.handleMetrics(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+			engine.Stats()
+		}
+	}()
+	wg.Wait()
+
+	plugins := engine.plugins.Load()
+	if len(plugins.inputs) != 50 || len(plugins.filters) != 50 || len(plugins.pipelines) != 51 {
+		t.Errorf("Expected every plugin to be added, got %d inputs, %d filters, %d pipelines",
+			len(plugins.inputs), len(plugins.filters), len(plugins.pipelines))
+	}
+}
@@ -1,22 +1,55 @@
 package core
 
 import (
-	"log"
 	"sync"
 )
 
 // ResilientInputPlugin wraps an input plugin with resilience
 type ResilientInputPlugin struct {
-	resilient *ResilientPlugin
-	logCh     chan<- *Log
-	mu        sync.RWMutex
+	resilient   *ResilientPlugin
+	logCh       chan<- *Log
+	shutdownFn  func()
+	ackRequired func() bool
+	mu          sync.RWMutex
 }
 
 // NewResilientInputPlugin creates a resilient input plugin
 func NewResilientInputPlugin(name, pluginType string, factory PluginFactory, config map[string]any, logCh chan<- *Log, resilientConfig ResilientPluginConfig) *ResilientInputPlugin {
-	return &ResilientInputPlugin{
-		resilient: NewResilientPlugin(name, pluginType, factory, config, resilientConfig),
-		logCh:     logCh,
+	r := &ResilientInputPlugin{
+		logCh: logCh,
+	}
+	resilientConfig.kind = PluginKindInput
+	r.resilient = NewResilientPlugin(name, pluginType, r.wrapFactory(name, factory), config, resilientConfig)
+	return r
+}
+
+// wrapFactory wires the log channel, name and shutdown hook into each created
+// input before the resilient plugin starts it
+func (r *ResilientInputPlugin) wrapFactory(name string, factory PluginFactory) PluginFactory {
+	return func(config map[string]any) (any, error) {
+		plugin, err := factory(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if inputPlugin, ok := plugin.(InputPlugin); ok {
+			r.mu.RLock()
+			logCh, shutdownFn, ackRequired := r.logCh, r.shutdownFn, r.ackRequired
+			r.mu.RUnlock()
+
+			inputPlugin.SetLogChannel(logCh)
+			if nameable, ok := plugin.(interface{ SetName(string) }); ok {
+				nameable.SetName(name)
+			}
+			if requester, ok := plugin.(ShutdownRequester); ok && shutdownFn != nil {
+				requester.SetShutdownFunc(shutdownFn)
+			}
+			if acking, ok := plugin.(AckingInput); ok && ackRequired != nil {
+				acking.SetAckRequired(ackRequired)
+			}
+		}
+
+		return plugin, nil
 	}
 }
 
@@ -45,6 +78,34 @@ func (r *ResilientInputPlugin) SetLogChannel(ch chan<- *Log) {
 	}
 }
 
+// SetShutdownFunc sets the engine shutdown hook for inputs that support it
+func (r *ResilientInputPlugin) SetShutdownFunc(fn func()) {
+	r.mu.Lock()
+	r.shutdownFn = fn
+	r.mu.Unlock()
+
+	// If plugin is already healthy, update its hook
+	if plugin, err := r.resilient.GetPlugin(); err == nil {
+		if requester, ok := plugin.(ShutdownRequester); ok {
+			requester.SetShutdownFunc(fn)
+		}
+	}
+}
+
+// SetAckRequired passes the engine's ack_mode check to inputs that acknowledge their source
+func (r *ResilientInputPlugin) SetAckRequired(required func() bool) {
+	r.mu.Lock()
+	r.ackRequired = required
+	r.mu.Unlock()
+
+	// If plugin is already healthy, update its check
+	if plugin, err := r.resilient.GetPlugin(); err == nil {
+		if acking, ok := plugin.(AckingInput); ok {
+			acking.SetAckRequired(required)
+		}
+	}
+}
+
 // SetName sets the plugin name (for compatibility)
 func (r *ResilientInputPlugin) SetName(name string) {
 	// Name is already set in resilient plugin
@@ -60,6 +121,18 @@ func (r *ResilientInputPlugin) GetStats() map[string]any {
 	return r.resilient.GetStats()
 }
 
+// InputStats forwards the counters of the underlying input (nil until it is connected)
+func (r *ResilientInputPlugin) InputStats() map[string]any {
+	plugin, err := r.resilient.GetPlugin()
+	if err != nil {
+		return nil
+	}
+	if input, ok := plugin.(InputPlugin); ok {
+		return inputStats(input)
+	}
+	return nil
+}
+
 // ResilientOutputPlugin wraps an output plugin with resilience
 type ResilientOutputPlugin struct {
 	resilient *ResilientPlugin
@@ -67,6 +140,7 @@ type ResilientOutputPlugin struct {
 
 // NewResilientOutputPlugin creates a resilient output plugin
 func NewResilientOutputPlugin(name, pluginType string, factory PluginFactory, config map[string]any, resilientConfig ResilientPluginConfig) *ResilientOutputPlugin {
+	resilientConfig.kind = PluginKindOutput
 	return &ResilientOutputPlugin{
 		resilient: NewResilientPlugin(name, pluginType, factory, config, resilientConfig),
 	}
@@ -78,14 +152,13 @@ func (r *ResilientOutputPlugin) Write(logEntry *Log) error {
 	if err != nil {
 		// Plugin not healthy, log warning but don't fail
 		// The output buffer will handle retries
-		log.Printf("[RESILIENT-OUTPUT:%s] Plugin not available, buffering will handle retry: %v",
-			r.resilient.name, err)
+		r.resilient.logger().Printf("Plugin not available, buffering will handle retry: %v", err)
 		return err
 	}
 
 	outputPlugin, ok := plugin.(OutputPlugin)
 	if !ok {
-		log.Printf("[RESILIENT-OUTPUT:%s] Invalid plugin type", r.resilient.name)
+		r.resilient.logger().Printf("Invalid plugin type")
 		return ErrPluginNotAvailable
 	}
 
@@ -107,6 +180,18 @@ func (r *ResilientOutputPlugin) GetStats() map[string]any {
 	return r.resilient.GetStats()
 }
 
+// OutputStats forwards the counters of the underlying output (nil until it is connected)
+func (r *ResilientOutputPlugin) OutputStats() map[string]any {
+	plugin, err := r.resilient.GetPlugin()
+	if err != nil {
+		return nil
+	}
+	if output, ok := plugin.(OutputPlugin); ok {
+		return outputStats(output)
+	}
+	return nil
+}
+
 // ErrPluginNotAvailable is returned when plugin is not available
 var ErrPluginNotAvailable = NewError("plugin not available")
 
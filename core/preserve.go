@@ -0,0 +1,100 @@
+package core
+
+import (
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// PreserveCondition matches logs that volume-reducing filters must never drop.
+// It names either a tag or a field; a field matches when it equals Equals, or
+// when it is present and not empty if Equals is not set.
+type PreserveCondition struct {
+	Tag    string `yaml:"tag,omitempty"`    // Tag the log must carry (e.g. audit)
+	Field  string `yaml:"field,omitempty"`  // message, level, source, source_type or a metadata key
+	Equals string `yaml:"equals,omitempty"` // Value the field must have (default: any non-empty value)
+}
+
+// Validate validates the PreserveCondition
+func (c PreserveCondition) Validate() error {
+	if (c.Tag == "") == (c.Field == "") {
+		return fmt.Errorf("exactly one of tag or field must be set")
+	}
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Equals, validation.When(c.Field == "", validation.Empty.Error("requires field"))),
+	)
+}
+
+// PreservableFilter is an optional interface for filters that drop logs to reduce
+// volume (sampling, rate limiting, burst thinning) rather than because of their
+// content. Logs matching preserve_if skip these filters, so they always pass
+// whatever the filter would have decided and never use up its budget.
+type PreservableFilter interface {
+	Preservable() bool
+}
+
+// preservable reports whether logs matching preserve_if skip a filter
+func preservable(filter FilterPlugin) bool {
+	p, ok := filter.(PreservableFilter)
+	return ok && p.Preservable()
+}
+
+// preserveField is a compiled field condition
+type preserveField struct {
+	field  string
+	equals string
+}
+
+// preservePredicate is the compiled preserve_if: a log is preserved when any
+// condition matches. Matching only does slice scans and map lookups, and it runs
+// only when a log reaches a preservable filter or a pipeline's sample_rate.
+type preservePredicate struct {
+	tags   []string
+	fields []preserveField
+}
+
+// newPreservePredicate compiles preserve_if, or returns nil when it is empty
+func newPreservePredicate(conditions []PreserveCondition) *preservePredicate {
+	if len(conditions) == 0 {
+		return nil
+	}
+	p := &preservePredicate{}
+	for _, condition := range conditions {
+		if condition.Tag != "" {
+			p.tags = append(p.tags, condition.Tag)
+			continue
+		}
+		p.fields = append(p.fields, preserveField{field: condition.Field, equals: condition.Equals})
+	}
+	return p
+}
+
+// matches reports whether a log must be preserved (always false for a nil predicate)
+func (p *preservePredicate) matches(logEntry *Log) bool {
+	if p == nil {
+		return false
+	}
+	for _, tag := range p.tags {
+		if logEntry.HasTag(tag) {
+			return true
+		}
+	}
+	for _, f := range p.fields {
+		value, ok := SampleKeyValue(logEntry, f.field)
+		if ok && value != "" && (f.equals == "" || value == f.equals) {
+			return true
+		}
+	}
+	return false
+}
+
+// skips reports whether a log bypasses a filter because it must be preserved
+func (p *preservePredicate) skips(filter FilterPlugin, logEntry *Log) bool {
+	return p != nil && preservable(filter) && p.matches(logEntry)
+}
+
+// SetPreserve configures preserve_if, the logs that sampling, rate limiting and
+// other volume-reducing filters must always pass. Call it before adding pipelines.
+func (e *Engine) SetPreserve(conditions []PreserveCondition) {
+	e.preserve = newPreservePredicate(conditions)
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// dropAllFilter drops every log, standing in for an aggressive sampler
+type dropAllFilter struct {
+	preservable bool
+	calls       int
+}
+
+func (f *dropAllFilter) Process(log *Log) bool {
+	f.calls++
+	return false
+}
+
+func (f *dropAllFilter) Mutates() bool {
+	return false
+}
+
+func (f *dropAllFilter) Preservable() bool {
+	return f.preservable
+}
+
+func TestPreserveCondition_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition PreserveCondition
+		wantErr   string
+	}{
+		{"tag", PreserveCondition{Tag: "audit"}, ""},
+		{"field present", PreserveCondition{Field: "force_keep"}, ""},
+		{"field equals", PreserveCondition{Field: "force_keep", Equals: "true"}, ""},
+		{"empty", PreserveCondition{}, "exactly one of tag or field"},
+		{"tag and field", PreserveCondition{Tag: "audit", Field: "force_keep"}, "exactly one of tag or field"},
+		{"equals without field", PreserveCondition{Tag: "audit", Equals: "true"}, "requires field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.condition.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPreservePredicate_Matches(t *testing.T) {
+	predicate := newPreservePredicate([]PreserveCondition{
+		{Tag: "audit"},
+		{Field: "force_keep", Equals: "true"},
+		{Field: "compliance"},
+		{Field: "source", Equals: "billing"},
+	})
+
+	tests := []struct {
+		name string
+		log  func() *Log
+		want bool
+	}{
+		{"plain", func() *Log { return NewLog("info", "m") }, false},
+		{"tagged", func() *Log { l := NewLog("info", "m"); l.AddTags("audit"); return l }, true},
+		{"other tag", func() *Log { l := NewLog("info", "m"); l.AddTags("alert"); return l }, false},
+		{"force_keep", func() *Log { l := NewLog("info", "m"); l.Metadata["force_keep"] = "true"; return l }, true},
+		{"force_keep false", func() *Log { l := NewLog("info", "m"); l.Metadata["force_keep"] = "false"; return l }, false},
+		{"present", func() *Log { l := NewLog("info", "m"); l.Metadata["compliance"] = "sox"; return l }, true},
+		{"present but empty", func() *Log { l := NewLog("info", "m"); l.Metadata["compliance"] = ""; return l }, false},
+		{"log field", func() *Log { l := NewLog("info", "m"); l.Source = "billing"; return l }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := predicate.matches(tt.log()); got != tt.want {
+				t.Errorf("Expected matches=%t, got %t", tt.want, got)
+			}
+		})
+	}
+
+	var disabled *preservePredicate
+	if disabled.matches(NewLog("info", "m")) || newPreservePredicate(nil) != nil {
+		t.Error("Expected no preserve_if to preserve nothing")
+	}
+}
+
+func TestEnginePreserveBypassesVolumeFilters(t *testing.T) {
+	engine := NewEngine()
+	engine.SetPreserve([]PreserveCondition{{Tag: "audit"}})
+
+	sampler := &dropAllFilter{preservable: true}
+	content := &dropAllFilter{}
+	sampledOutput := newMockOutput()
+	contentOutput := newMockOutput()
+	rateOutput := newMockOutput()
+
+	pipelines := []*OutputPipeline{
+		{Name: "sampled", Output: sampledOutput, Filters: []FilterPlugin{sampler}},
+		{Name: "content", Output: contentOutput, Filters: []FilterPlugin{content}},
+		{Name: "rate", Output: rateOutput, SampleRate: 0.0001},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	audit := NewLog("info", "audit")
+	audit.AddTags("audit")
+	engine.dispatchLog(audit)
+	engine.dispatchLog(NewLog("info", "regular"))
+	engine.Stop()
+
+	for name, output := range map[string]*mockOutput{"sampled": sampledOutput, "rate": rateOutput} {
+		logs := output.getLogs()
+		if len(logs) != 1 || logs[0].Message != "audit" {
+			t.Errorf("Expected %s to receive only the audit log, got %d logs", name, len(logs))
+		}
+	}
+	if sampler.calls != 1 {
+		t.Errorf("Expected the preserved log to skip the sampler, got %d calls", sampler.calls)
+	}
+	// Filters that drop by content still apply to preserved logs
+	if got := len(contentOutput.getLogs()); got != 0 {
+		t.Errorf("Expected the content filter to drop both logs, got %d", got)
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// retryQueueFile is the file the retry queue, and with snapshot_interval the
+// delivery queue, is saved to; loadPersistedLogs reloads it on the next start
+const retryQueueFile = "retry-queue.jsonl"
+
+// MinSnapshotInterval bounds how often the queues are snapshotted
+const MinSnapshotInterval = 100 * time.Millisecond
+
+// trackInflight records a log entering the delivery queue, so snapshots include
+// it until delivery settles its fate. It is a no-op without snapshot_interval.
+func (ob *OutputBuffer) trackInflight(bufferedLog *BufferedLog) {
+	if ob.inflight == nil {
+		return
+	}
+	ob.inflightMu.Lock()
+	ob.inflight[bufferedLog] = nil
+	ob.inflightMu.Unlock()
+}
+
+// settleInflight forgets a log that was delivered, dropped or moved to the
+// retry queue; from then on the retry queue (or nothing) accounts for it
+func (ob *OutputBuffer) settleInflight(bufferedLog *BufferedLog) {
+	if ob.inflight == nil {
+		return
+	}
+	ob.inflightMu.Lock()
+	delete(ob.inflight, bufferedLog)
+	ob.inflightMu.Unlock()
+}
+
+// inflightLines returns the JSON lines of the logs in the delivery queue or
+// being delivered, skipping those already in the retry queue. The caller holds
+// retryMu, which requeueForRetry takes before a log is settled, so a log in both
+// places is written once.
+//
+// A line is encoded the first time a log is snapshotted and reused while it stays
+// queued, so each snapshot only encodes the logs enqueued since the previous
+// one. Lines are built from the fields a delivery never changes: attempts are
+// reset on reload anyway, and delivery workers update them concurrently.
+func (ob *OutputBuffer) inflightLines(retrying map[*BufferedLog]bool) [][]byte {
+	ob.inflightMu.Lock()
+	defer ob.inflightMu.Unlock()
+
+	lines := make([][]byte, 0, len(ob.inflight))
+	for bufferedLog, line := range ob.inflight {
+		if retrying[bufferedLog] {
+			continue
+		}
+		if line == nil {
+			snapshot := BufferedLog{
+				Log:        bufferedLog.Log,
+				OutputName: bufferedLog.OutputName,
+				EnqueuedAt: bufferedLog.EnqueuedAt,
+				Deadline:   bufferedLog.Deadline,
+			}
+			var err error
+			if line, err = json.Marshal(&snapshot); err != nil {
+				ob.logger().Printf("Error marshaling queued log: %v", err)
+				continue
+			}
+			ob.inflight[bufferedLog] = line
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// snapshotC returns the channel of the snapshot ticker, or nil (never ready)
+// without snapshot_interval
+func (ob *OutputBuffer) snapshotC() <-chan time.Time {
+	if ob.snapshotTicker == nil {
+		return nil
+	}
+	return ob.snapshotTicker.C
+}
+
+// writeQueueFile replaces the queue file with data, writing a temporary file
+// first so a kill during the write leaves the previous snapshot intact. Empty
+// data removes the file, so logs delivered since the last snapshot are not
+// reloaded. It returns the size of the file now on disk.
+func (ob *OutputBuffer) writeQueueFile(data []byte) int64 {
+	filename := filepath.Join(ob.config.Dir, ob.outputName, retryQueueFile)
+	if len(data) == 0 {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			ob.logger().Printf("Error removing queue snapshot: %v", err)
+			return fileSize(filename)
+		}
+		return 0
+	}
+
+	temp := filename + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		ob.logger().Printf("Error writing queue snapshot to disk: %v", err)
+		_ = os.Remove(temp)
+		return fileSize(filename)
+	}
+	if err := os.Rename(temp, filename); err != nil {
+		ob.logger().Printf("Error replacing queue snapshot: %v", err)
+		_ = os.Remove(temp)
+		return fileSize(filename)
+	}
+	return int64(len(data))
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snapshotLines returns the messages in an output's queue snapshot, or nil when there is none
+func snapshotLines(t *testing.T, dir string) []string {
+	file, err := os.Open(filepath.Join(dir, "test", retryQueueFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// waitUntil polls condition for up to 3 seconds
+func waitUntil(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(3 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOutputBuffer_QueueSnapshot(t *testing.T) {
+	output := &gatedOutput{release: make(chan struct{})}
+
+	config := DefaultOutputBufferConfig()
+	config.Dir = t.TempDir()
+	config.Enabled = true
+	config.DLQEnabled = false
+	config.SnapshotInterval = time.Hour // Snapshots are taken explicitly below
+
+	buffer, err := NewOutputBuffer("test", output, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+
+	for _, message := range []string{"one", "two", "three"} {
+		if err := buffer.Enqueue(NewLog("info", message)); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+	waitUntil(t, func() bool { inFlight, _, _ := output.counts(); return inFlight == 1 })
+
+	// The log being written and the queued ones are all in the snapshot
+	buffer.persistRetryQueue()
+	lines := snapshotLines(t, config.Dir)
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 logs in the snapshot, got %v", lines)
+	}
+
+	// A new buffer over the same directory, as after a hard kill, recovers them
+	recoveredDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(recoveredDir, "test"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(config.Dir, "test", retryQueueFile))
+	if err := os.WriteFile(filepath.Join(recoveredDir, "test", retryQueueFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	recoveredConfig := config
+	recoveredConfig.Dir = recoveredDir
+	recoveredConfig.RetryInterval = time.Hour
+	recovered, err := NewOutputBuffer("test", &gatedOutput{release: make(chan struct{})}, recoveredConfig)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	recovered.retryMu.Lock()
+	var messages []string
+	for _, bufferedLog := range recovered.retryQueue {
+		messages = append(messages, bufferedLog.Log.Message)
+	}
+	recovered.retryMu.Unlock()
+	if len(messages) != 3 || !strings.Contains(strings.Join(messages, ","), "two") {
+		t.Errorf("Expected the 3 snapshotted logs to be recovered, got %v", messages)
+	}
+	close(recovered.output.(*gatedOutput).release)
+	_ = recovered.Close()
+
+	// Once delivered, logs leave the snapshot instead of being recovered twice
+	close(output.release)
+	waitUntil(t, func() bool { _, _, written := output.counts(); return written == 3 })
+	waitUntil(t, func() bool {
+		buffer.inflightMu.Lock()
+		defer buffer.inflightMu.Unlock()
+		return len(buffer.inflight) == 0
+	})
+	buffer.persistRetryQueue()
+	if lines := snapshotLines(t, config.Dir); lines != nil {
+		t.Errorf("Expected the snapshot to be removed once every log was delivered, got %v", lines)
+	}
+	if err := buffer.Close(); err != nil {
+		t.Fatalf("Failed to close buffer: %v", err)
+	}
+}
+
+func TestOutputBuffer_QueueSnapshotRetried(t *testing.T) {
+	output := &MockOutput{}
+	output.SetShouldFail(true, 100)
+
+	config := DefaultOutputBufferConfig()
+	config.Dir = t.TempDir()
+	config.Enabled = true
+	config.DLQEnabled = false
+	config.MaxRetries = 10
+	config.RetryInterval = time.Hour
+	config.SnapshotInterval = 100 * time.Millisecond
+
+	buffer, err := NewOutputBuffer("test", output, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+
+	if err := buffer.Enqueue(NewLog("error", "failing")); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	// The periodic snapshot holds the failed log once, from the retry queue
+	waitUntil(t, func() bool { return len(snapshotLines(t, config.Dir)) > 0 })
+	time.Sleep(250 * time.Millisecond)
+	if lines := snapshotLines(t, config.Dir); len(lines) != 1 || !strings.Contains(lines[0], `"attempts":1`) {
+		t.Errorf("Expected the retried log once in the snapshot, got %v", lines)
+	}
+}
+
+func TestOutputBufferConfigSnapshotIntervalValidation(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		valid    bool
+	}{
+		{0, true},
+		{100 * time.Millisecond, true},
+		{time.Second, true},
+		{10 * time.Millisecond, false},
+		{-time.Second, false},
+		{2 * time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		config := DefaultOutputBufferConfig()
+		config.SnapshotInterval = tt.interval
+		err := config.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%v: unexpected error %v", tt.interval, err)
+		}
+		if !tt.valid && (err == nil || !strings.Contains(err.Error(), "SnapshotInterval")) {
+			t.Errorf("%v: expected a SnapshotInterval error, got %v", tt.interval, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// QuickstartLogFiles are the log files --quickstart looks for when it is given
+// no files and stdin is a terminal, in order of preference
+var QuickstartLogFiles = []string{
+	"app.log",
+	"/var/log/syslog",
+	"/var/log/messages",
+}
+
+// DetectQuickstartFiles decides what --quickstart reads. Explicit paths win;
+// otherwise piped stdin is used (nil result); otherwise the first readable
+// file from QuickstartLogFiles. A nil result means "read stdin".
+func DetectQuickstartFiles(paths []string, stdin *os.File) []string {
+	if len(paths) > 0 {
+		return paths
+	}
+	if stdin != nil && !isTerminal(stdin) {
+		return nil
+	}
+	for _, candidate := range QuickstartLogFiles {
+		if file, err := os.Open(candidate); err == nil { // #nosec G304 - fixed list of well-known log files
+			_ = file.Close()
+			return []string{candidate}
+		}
+	}
+	return nil
+}
+
+// isTerminal reports whether f is an interactive character device rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// QuickstartConfig returns the configuration used by --quickstart: one file
+// input per path (stdin when there are none) printing to the console as text.
+// Unlike DefaultConfig it is meant for interactive exploration, so every log
+// is visible immediately.
+func QuickstartConfig(files []string) *Config {
+	config := &Config{
+		Outputs: []PluginDefinition{
+			{
+				Name: "console",
+				Type: "console",
+				Config: map[string]any{
+					"format": "text",
+				},
+			},
+		},
+	}
+
+	if len(files) == 0 {
+		config.Inputs = []PluginDefinition{
+			{
+				Name: "stdin",
+				Type: "stdin",
+				Config: map[string]any{
+					"stop_on_eof": true,
+				},
+			},
+		}
+		return config
+	}
+
+	for _, path := range files {
+		config.Inputs = append(config.Inputs, PluginDefinition{
+			Type: "file",
+			Config: map[string]any{
+				"path": path,
+			},
+		})
+	}
+	return config
+}
+
+// MarshalConfig renders a configuration as YAML that LoadConfig accepts
+func MarshalConfig(config *Config) ([]byte, error) {
+	return yaml.Marshal(config)
+}
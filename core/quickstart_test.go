@@ -0,0 +1,100 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestQuickstartConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []string
+		inputTypes []string
+	}{
+		{"stdin", nil, []string{"stdin"}},
+		{"one file", []string{"app.log"}, []string{"file"}},
+		{"several files", []string{"a.log", "b.log"}, []string{"file", "file"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := QuickstartConfig(tt.files)
+
+			var inputTypes []string
+			for _, input := range config.Inputs {
+				inputTypes = append(inputTypes, input.Type)
+			}
+			if !reflect.DeepEqual(inputTypes, tt.inputTypes) {
+				t.Errorf("Expected inputs %v, got %v", tt.inputTypes, inputTypes)
+			}
+			if len(config.Outputs) != 1 || config.Outputs[0].Type != "console" {
+				t.Errorf("Expected a single console output, got %+v", config.Outputs)
+			}
+			if err := config.Validate(); err != nil {
+				t.Errorf("Expected a valid config, got %v", err)
+			}
+		})
+	}
+}
+
+func TestQuickstartConfigRoundTrip(t *testing.T) {
+	config := QuickstartConfig([]string{"app.log"})
+
+	data, err := MarshalConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected the printed config to load, got %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(loaded.Inputs, config.Inputs) {
+		t.Errorf("Expected inputs %+v, got %+v", config.Inputs, loaded.Inputs)
+	}
+	if !reflect.DeepEqual(loaded.Outputs, config.Outputs) {
+		t.Errorf("Expected outputs %+v, got %+v", config.Outputs, loaded.Outputs)
+	}
+}
+
+func TestDetectQuickstartFiles(t *testing.T) {
+	pipe, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer func() { _ = pipe.Close() }()
+	defer func() { _ = writer.Close() }()
+
+	if files := DetectQuickstartFiles([]string{"x.log"}, pipe); !reflect.DeepEqual(files, []string{"x.log"}) {
+		t.Errorf("Expected explicit paths to win, got %v", files)
+	}
+	if files := DetectQuickstartFiles(nil, pipe); files != nil {
+		t.Errorf("Expected piped stdin to be used, got %v", files)
+	}
+
+	// Without piped stdin, the first existing well-known file is used
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(existing, []byte("[INFO] hello\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	original := QuickstartLogFiles
+	defer func() { QuickstartLogFiles = original }()
+
+	QuickstartLogFiles = []string{filepath.Join(dir, "missing.log"), existing}
+	if files := DetectQuickstartFiles(nil, nil); !reflect.DeepEqual(files, []string{existing}) {
+		t.Errorf("Expected %s to be detected, got %v", existing, files)
+	}
+
+	QuickstartLogFiles = []string{filepath.Join(dir, "missing.log")}
+	if files := DetectQuickstartFiles(nil, nil); files != nil {
+		t.Errorf("Expected stdin when nothing is found, got %v", files)
+	}
+}
@@ -0,0 +1,352 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Reload triggers recorded in the reload audit trail
+const (
+	ReloadTriggerFile   = "file"   // The config watcher saw the file change (hot reload)
+	ReloadTriggerSignal = "signal" // SIGHUP
+	ReloadTriggerManual = "manual" // ReloadConfig called directly
+	ReloadTriggerAPI    = "api"    // A plugin was added, replaced or removed through the API
+)
+
+// Reload results recorded in the reload audit trail
+const (
+	ReloadResultSuccess  = "success"  // The new configuration was applied
+	ReloadResultRejected = "rejected" // The configuration could not be loaded or validated; nothing changed
+)
+
+// Stages a reload can fail at, recorded with rejected events and counted in /metrics
+const (
+	ReloadStageValidation = "validation" // The file could not be read, parsed or validated
+	ReloadStageApply      = "apply"      // The configuration is valid but its plugins could not be built
+)
+
+// DefaultReloadAuditMaxEvents is the default number of reload events kept for GET /reloads
+const DefaultReloadAuditMaxEvents = 100
+
+// ReloadAuditConfig defines where reload events are recorded
+type ReloadAuditConfig struct {
+	Path      string `yaml:"path,omitempty"`       // Append each event as a JSON line to this file (default: memory only)
+	MaxEvents int    `yaml:"max_events,omitempty"` // Recent events kept for GET /reloads (default: 100)
+}
+
+// Validate validates the ReloadAuditConfig
+func (c ReloadAuditConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.MaxEvents, validation.Min(0).Error("must be no less than 0")),
+	)
+}
+
+// ReloadEvent records one reload attempt
+type ReloadEvent struct {
+	Time    time.Time `json:"time"`
+	Trigger string    `json:"trigger"`           // ReloadTriggerFile, ReloadTriggerSignal, ReloadTriggerManual or ReloadTriggerAPI
+	Result  string    `json:"result"`            // ReloadResultSuccess or ReloadResultRejected
+	Error   string    `json:"error,omitempty"`   // Why the reload was rejected
+	Stage   string    `json:"stage,omitempty"`   // ReloadStageValidation or ReloadStageApply when rejected
+	Changes []string  `json:"changes,omitempty"` // Summary of what the new configuration changes, see DiffConfigs
+}
+
+// ReloadAudit keeps the most recent reload events in memory and optionally
+// appends every event to a file, so behavior changes can be correlated with
+// config changes after the fact
+type ReloadAudit struct {
+	mu        sync.Mutex
+	events    []ReloadEvent // Ring buffer of recent events
+	next      int           // Index the next event is written to once the ring is full
+	maxEvents int
+	file      *os.File
+}
+
+// NewReloadAudit creates a reload audit trail, opening the audit file if configured
+func NewReloadAudit(config ReloadAuditConfig) (*ReloadAudit, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid reload audit config: %w", err)
+	}
+
+	a := &ReloadAudit{maxEvents: config.MaxEvents}
+	if a.maxEvents == 0 {
+		a.maxEvents = DefaultReloadAuditMaxEvents
+	}
+	if config.Path != "" {
+		file, err := os.OpenFile(config.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 - path comes from configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to open reload audit file: %w", err)
+		}
+		a.file = file
+	}
+	return a, nil
+}
+
+// Record adds an event to the trail and the audit file
+func (a *ReloadAudit) Record(event ReloadEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.events) < a.maxEvents {
+		a.events = append(a.events, event)
+	} else {
+		a.events[a.next] = event
+		a.next = (a.next + 1) % a.maxEvents
+	}
+
+	if a.file != nil {
+		data, err := json.Marshal(event)
+		if err == nil {
+			_, err = a.file.Write(append(data, '\n'))
+		}
+		if err != nil {
+			reloadLog.Printf("Error writing reload audit event: %v", err)
+		}
+	}
+}
+
+// Events returns the recent events, oldest first
+func (a *ReloadAudit) Events() []ReloadEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	events := make([]ReloadEvent, 0, len(a.events))
+	events = append(events, a.events[a.next:]...)
+	return append(events, a.events[:a.next]...)
+}
+
+// Close closes the audit file
+func (a *ReloadAudit) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// DiffConfigs summarizes how newConfig differs from oldConfig. Inputs and
+// outputs are matched by name (or their generated type-index name) and reported
+// as "+input:<name>", "-input:<name>" or "~input:<name>" (likewise for outputs);
+// other changed sections are reported by their YAML key, e.g. "~levels".
+func DiffConfigs(oldConfig, newConfig *Config) []string {
+	if oldConfig == nil || newConfig == nil {
+		return nil
+	}
+
+	changes := diffPlugins("input", oldConfig.Inputs, newConfig.Inputs)
+	changes = append(changes, diffPlugins("output", oldConfig.Outputs, newConfig.Outputs)...)
+
+	sections := []struct {
+		key      string
+		old, new any
+	}{
+		{"persistence", oldConfig.Persistence, newConfig.Persistence},
+		{"output_buffer", oldConfig.OutputBuffer, newConfig.OutputBuffer},
+		{"api", oldConfig.API, newConfig.API},
+		{"levels", oldConfig.Levels, newConfig.Levels},
+		{"pause", oldConfig.Pause, newConfig.Pause},
+		{"stats_interval", oldConfig.StatsInterval, newConfig.StatsInterval},
+		{"serialization_cache", oldConfig.SerializationCache, newConfig.SerializationCache},
+		{"max_log_age", oldConfig.MaxLogAge, newConfig.MaxLogAge},
+		{"metadata_storage", oldConfig.MetadataStorage, newConfig.MetadataStorage},
+		{"deadline", oldConfig.Deadline, newConfig.Deadline},
+		{"error_context", oldConfig.ErrorContext, newConfig.ErrorContext},
+		{"preserve_if", oldConfig.PreserveIf, newConfig.PreserveIf},
+		{"source_quotas", oldConfig.SourceQuotas, newConfig.SourceQuotas},
+		{"startup_grace", oldConfig.StartupGrace, newConfig.StartupGrace},
+		{"reload_audit", oldConfig.ReloadAudit, newConfig.ReloadAudit},
+		{"logging", oldConfig.Logging, newConfig.Logging},
+		{"resilience", oldConfig.Resilience, newConfig.Resilience},
+		{"trace", oldConfig.Trace, newConfig.Trace},
+		{"disk_budget", oldConfig.DiskBudget, newConfig.DiskBudget},
+		{"lint", oldConfig.Lint, newConfig.Lint},
+		{"defaults", oldConfig.Defaults, newConfig.Defaults},
+	}
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.old, section.new) {
+			changes = append(changes, "~"+section.key)
+		}
+	}
+	return changes
+}
+
+// diffPlugins compares plugin definitions by name, in the order of the new and then the old list
+func diffPlugins(kind string, oldDefs, newDefs []PluginDefinition) []string {
+	oldByName := pluginsByName(oldDefs)
+	newByName := pluginsByName(newDefs)
+
+	var changes []string
+	for i, def := range newDefs {
+		name := pluginName(def, i)
+		oldDef, ok := oldByName[name]
+		switch {
+		case !ok:
+			changes = append(changes, "+"+kind+":"+name)
+		case !reflect.DeepEqual(*oldDef, def):
+			changes = append(changes, "~"+kind+":"+name)
+		}
+	}
+	for i, def := range oldDefs {
+		if name := pluginName(def, i); newByName[name] == nil {
+			changes = append(changes, "-"+kind+":"+name)
+		}
+	}
+	return changes
+}
+
+// pluginsByName indexes plugin definitions by their resolved name
+func pluginsByName(defs []PluginDefinition) map[string]*PluginDefinition {
+	byName := make(map[string]*PluginDefinition, len(defs))
+	for i := range defs {
+		byName[pluginName(defs[i], i)] = &defs[i]
+	}
+	return byName
+}
+
+// pluginName returns the configured name, or the "<type>-<n>" name the engine generates
+func pluginName(def PluginDefinition, index int) string {
+	if def.Name != "" {
+		return def.Name
+	}
+	return fmt.Sprintf("%s-%d", def.Type, index+1)
+}
+
+// SetReloadAudit replaces the reload audit trail. The engine keeps an in-memory
+// trail by default; this adds the audit file and sets the number of events kept.
+func (e *Engine) SetReloadAudit(config ReloadAuditConfig) error {
+	audit, err := NewReloadAudit(config)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	old := e.reloadAudit
+	e.reloadAudit = audit
+	e.mu.Unlock()
+
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// SetAppliedConfig records the configuration the engine was built from, so the
+// first reload can report what it changes
+func (e *Engine) SetAppliedConfig(config *Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.appliedConfig = config
+}
+
+// ReloadEvents returns the recent reload events, oldest first
+func (e *Engine) ReloadEvents() []ReloadEvent {
+	return e.audit().Events()
+}
+
+// RecordReloadFailure records a reload that was rejected before reaching the
+// engine, e.g. because the new config file does not parse or validate
+func (e *Engine) RecordReloadFailure(trigger string, err error) {
+	e.recordReloadFailure(trigger, ReloadStageValidation, err)
+}
+
+// recordReloadFailure records a rejected reload in the audit trail and the reload metrics
+func (e *Engine) recordReloadFailure(trigger, stage string, err error) {
+	e.reloadMetrics.record(ReloadResultRejected, stage)
+	e.audit().Record(ReloadEvent{Trigger: trigger, Result: ReloadResultRejected, Error: err.Error(), Stage: stage})
+}
+
+// ReloadMetrics counts reload attempts by result, for alerting on a config that
+// keeps failing to reload. It outlives reloads, unlike the audit trail settings.
+type ReloadMetrics struct {
+	mu          sync.Mutex
+	successes   uint64
+	failures    map[string]uint64 // By stage
+	lastReload  time.Time         // Last attempt, whatever its result
+	lastSuccess time.Time
+}
+
+// record counts one reload attempt
+func (m *ReloadMetrics) record(result, stage string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.lastReload = now
+	if result == ReloadResultSuccess {
+		m.successes++
+		m.lastSuccess = now
+		return
+	}
+	if m.failures == nil {
+		m.failures = make(map[string]uint64)
+	}
+	m.failures[stage]++
+}
+
+// Snapshot returns the counters in the /metrics format. Timestamps are Unix
+// seconds, 0 until the first reload.
+func (m *ReloadMetrics) Snapshot() map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var failures uint64
+	byStage := map[string]uint64{ReloadStageValidation: 0, ReloadStageApply: 0}
+	for stage, count := range m.failures {
+		byStage[stage] = count
+		failures += count
+	}
+	return map[string]any{
+		"config_reloads_total":                    map[string]uint64{"success": m.successes, "failure": failures},
+		"config_reload_failures_total":            byStage,
+		"config_last_reload_timestamp":            unixSeconds(m.lastReload),
+		"config_last_successful_reload_timestamp": unixSeconds(m.lastSuccess),
+	}
+}
+
+// unixSeconds returns t as Unix seconds, or 0 for the zero time
+func unixSeconds(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// audit returns the current reload audit trail
+func (e *Engine) audit() *ReloadAudit {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reloadAudit
+}
+
+// handleReloads returns the recent reload events via GET /reloads
+func (e *Engine) handleReloads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := map[string]any{
+		"events": e.ReloadEvents(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apiLog.Printf("Error encoding reloads response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadAuditKeepsRecentEvents(t *testing.T) {
+	audit, err := NewReloadAudit(ReloadAuditConfig{MaxEvents: 2})
+	if err != nil {
+		t.Fatalf("Failed to create audit: %v", err)
+	}
+
+	for _, trigger := range []string{"a", "b", "c"} {
+		audit.Record(ReloadEvent{Trigger: trigger, Result: ReloadResultSuccess})
+	}
+
+	events := audit.Events()
+	if len(events) != 2 || events[0].Trigger != "b" || events[1].Trigger != "c" {
+		t.Fatalf("Expected the last two events oldest first, got %+v", events)
+	}
+	if events[0].Time.IsZero() {
+		t.Error("Expected the event time to be set")
+	}
+}
+
+func TestReloadAuditFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reloads.jsonl")
+	audit, err := NewReloadAudit(ReloadAuditConfig{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to create audit: %v", err)
+	}
+
+	audit.Record(ReloadEvent{Trigger: ReloadTriggerFile, Result: ReloadResultSuccess, Changes: []string{"~levels"}})
+	audit.Record(ReloadEvent{Trigger: ReloadTriggerSignal, Result: ReloadResultRejected, Error: "bad yaml"})
+	if err := audit.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 audit lines, got %d", len(lines))
+	}
+	var event ReloadEvent
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("Invalid audit line: %v", err)
+	}
+	if event.Trigger != ReloadTriggerSignal || event.Result != ReloadResultRejected || event.Error != "bad yaml" {
+		t.Errorf("Unexpected audit event: %+v", event)
+	}
+}
+
+func TestReloadAuditConfigValidation(t *testing.T) {
+	if _, err := NewReloadAudit(ReloadAuditConfig{MaxEvents: -1}); err == nil {
+		t.Error("Expected error for negative max_events")
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	oldConfig := &Config{
+		Inputs: []PluginDefinition{
+			{Type: "file", Name: "app", Config: map[string]any{"path": "app.log"}},
+			{Type: "http"},
+		},
+		Outputs: []PluginDefinition{{Type: "console"}},
+	}
+
+	tests := []struct {
+		name      string
+		newConfig *Config
+		expected  []string
+	}{
+		{
+			name:      "unchanged",
+			newConfig: oldConfig,
+			expected:  nil,
+		},
+		{
+			name: "plugins added, removed and changed",
+			newConfig: &Config{
+				Inputs: []PluginDefinition{
+					{Type: "file", Name: "app", Config: map[string]any{"path": "other.log"}},
+					{Type: "docker", Name: "containers"},
+				},
+				Outputs: []PluginDefinition{{Type: "console"}},
+			},
+			expected: []string{"~input:app", "+input:containers", "-input:http-2"},
+		},
+		{
+			name: "other sections",
+			newConfig: &Config{
+				Inputs:        oldConfig.Inputs,
+				Outputs:       oldConfig.Outputs,
+				Levels:        LevelsConfig{Default: "warn"},
+				StatsInterval: time.Minute,
+			},
+			expected: []string{"~levels", "~stats_interval"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffConfigs(oldConfig, tt.newConfig); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEngineReloadAudit(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	inputs := []PluginDefinition{{Type: "stdin", Config: map[string]any{"resilient": false}}}
+	engine := NewEngine()
+	engine.SetAppliedConfig(&Config{Inputs: inputs, Outputs: []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}}})
+	engine.Start()
+	defer engine.Stop()
+
+	noop := func(*Config) (func(*Engine), error) {
+		return func(*Engine) {}, nil
+	}
+
+	newConfig := &Config{Inputs: inputs, Outputs: []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}, {Type: "null", Name: "sink", Config: map[string]any{"count": true}}}}
+	if err := engine.ReloadConfigFrom(ReloadTriggerSignal, newConfig, noop); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	invalid := &Config{Inputs: inputs, Outputs: newConfig.Outputs, Levels: LevelsConfig{Order: []string{"info"}, Default: "missing"}}
+	if err := engine.ReloadConfigFrom(ReloadTriggerFile, invalid, noop); err == nil {
+		t.Fatal("Expected reload with invalid levels to be rejected")
+	}
+	engine.RecordReloadFailure(ReloadTriggerFile, errors.New("configuration validation failed"))
+
+	events := engine.ReloadEvents()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 reload events, got %d", len(events))
+	}
+	if events[0].Result != ReloadResultSuccess || events[0].Trigger != ReloadTriggerSignal {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+	if !reflect.DeepEqual(events[0].Changes, []string{"+output:sink"}) {
+		t.Errorf("Expected +output:sink change, got %v", events[0].Changes)
+	}
+	if events[1].Result != ReloadResultRejected || events[1].Error == "" {
+		t.Errorf("Expected rejected event with error, got %+v", events[1])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/reloads", nil)
+	w := httptest.NewRecorder()
+	engine.handleReloads(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Events []ReloadEvent `json:"events"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(response.Events) != 3 || response.Events[2].Error != "configuration validation failed" {
+		t.Errorf("Unexpected /reloads events: %+v", response.Events)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleReloads(w, httptest.NewRequest(http.MethodPost, "/reloads", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", w.Code)
+	}
+}
+
+func TestEngineReloadMetrics(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	inputs := []PluginDefinition{{Type: "stdin", Config: map[string]any{"resilient": false}}}
+	outputs := []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}}
+	engine := NewEngine()
+	engine.Start()
+	defer engine.Stop()
+
+	noop := func(*Config) (func(*Engine), error) {
+		return func(*Engine) {}, nil
+	}
+	failing := func(*Config) (func(*Engine), error) {
+		return nil, errors.New("connection refused")
+	}
+
+	before := time.Now().Unix()
+	if err := engine.ReloadConfigFrom(ReloadTriggerSignal, &Config{Inputs: inputs, Outputs: outputs}, noop); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if err := engine.ReloadConfigFrom(ReloadTriggerFile, &Config{Inputs: inputs, Outputs: outputs}, failing); err == nil {
+		t.Fatal("Expected reload with failing plugins to be rejected")
+	}
+	if err := engine.ReloadConfigFrom(ReloadTriggerFile, &Config{Inputs: inputs}, noop); err == nil {
+		t.Fatal("Expected reload without outputs to be rejected")
+	}
+	engine.RecordReloadFailure(ReloadTriggerFile, errors.New("error parsing config file"))
+
+	w := httptest.NewRecorder()
+	engine.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	var metrics struct {
+		Reloads     map[string]uint64 `json:"config_reloads_total"`
+		Failures    map[string]uint64 `json:"config_reload_failures_total"`
+		LastReload  int64             `json:"config_last_reload_timestamp"`
+		LastSuccess int64             `json:"config_last_successful_reload_timestamp"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+
+	if !reflect.DeepEqual(metrics.Reloads, map[string]uint64{"success": 1, "failure": 3}) {
+		t.Errorf("Unexpected reload counts %v", metrics.Reloads)
+	}
+	if !reflect.DeepEqual(metrics.Failures, map[string]uint64{ReloadStageValidation: 2, ReloadStageApply: 1}) {
+		t.Errorf("Unexpected failures by stage %v", metrics.Failures)
+	}
+	if metrics.LastReload < before || metrics.LastSuccess < before {
+		t.Errorf("Expected reload timestamps after %d, got %d and %d", before, metrics.LastReload, metrics.LastSuccess)
+	}
+
+	events := engine.ReloadEvents()
+	if events[1].Stage != ReloadStageApply || events[2].Stage != ReloadStageValidation || events[0].Stage != "" {
+		t.Errorf("Unexpected event stages: %+v", events)
+	}
+}
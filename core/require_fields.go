@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DropReasonRequiredFields counts logs missing a required field of an output without a DLQ
+const DropReasonRequiredFields = "required_fields"
+
+// DLQReasonRequiredFields marks logs dead-lettered because they miss a required field
+const DLQReasonRequiredFields = "required_fields"
+
+// Types a required field can be checked against. Metadata values are strings,
+// so a typed field must parse as that type.
+const (
+	FieldTypeString    = "string"    // Any non-empty value (default)
+	FieldTypeInt       = "int"       // A base 10 integer
+	FieldTypeNumber    = "number"    // An integer or decimal number
+	FieldTypeBool      = "bool"      // true/false, 1/0 and the other values strconv.ParseBool accepts
+	FieldTypeTimestamp = "timestamp" // An RFC 3339 time
+)
+
+// FieldRequirement is a field an output requires before a log is written to it
+type FieldRequirement struct {
+	Field string `yaml:"field"`          // "message", "level", "source", "source_type" or a metadata key
+	Type  string `yaml:"type,omitempty"` // string (default), int, number, bool or timestamp
+}
+
+// Validate validates the FieldRequirement
+func (r FieldRequirement) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Field, validation.Required.Error("cannot be blank")),
+		validation.Field(&r.Type, validation.In(FieldTypeString, FieldTypeInt, FieldTypeNumber, FieldTypeBool, FieldTypeTimestamp).
+			Error("must be string, int, number, bool or timestamp")),
+	)
+}
+
+// check returns why a log does not meet the requirement, or "" when it does
+func (r FieldRequirement) check(logEntry *Log) string {
+	value, ok := SampleKeyValue(logEntry, r.Field)
+	if !ok || value == "" {
+		return fmt.Sprintf("missing %s", r.Field)
+	}
+
+	var err error
+	switch r.Type {
+	case FieldTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case FieldTypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case FieldTypeBool:
+		_, err = strconv.ParseBool(value)
+	case FieldTypeTimestamp:
+		_, err = time.Parse(time.RFC3339Nano, value)
+	}
+	if err != nil {
+		return fmt.Sprintf("%s is not a valid %s: %q", r.Field, r.Type, value)
+	}
+	return ""
+}
+
+// invalidFields returns why a log does not meet the pipeline's RequireFields, or "" when it does
+func (p *OutputPipeline) invalidFields(logEntry *Log) string {
+	for _, requirement := range p.RequireFields {
+		if problem := requirement.check(logEntry); problem != "" {
+			return problem
+		}
+	}
+	return ""
+}
+
+// rejectsFields reports whether a log misses a field the pipeline requires. The
+// log is dead-lettered when the pipeline's buffer has a DLQ, so it can be
+// replayed once the parsing upstream is fixed, and dropped otherwise.
+func (e *Engine) rejectsFields(pipeline *OutputPipeline, logEntry *Log) bool {
+	problem := pipeline.invalidFields(logEntry)
+	if problem == "" {
+		return false
+	}
+	pipeline.fieldsRejected.Add(1)
+	engineLog.Printf("Output '%s' rejected log: %s", pipeline.Name, problem)
+
+	if pipeline.Buffer == nil || !pipeline.Buffer.config.DLQEnabled {
+		e.drops.Inc(DropReasonRequiredFields)
+		return true
+	}
+	now := time.Now()
+	pipeline.Buffer.sendToDLQ(&BufferedLog{
+		Log:         logEntry,
+		LastAttempt: now,
+		OutputName:  pipeline.Name,
+		EnqueuedAt:  now,
+		DLQReason:   DLQReasonRequiredFields,
+	})
+	return true
+}
+
+// FieldsRejectedCount returns the number of logs rejected by the pipeline's require_fields
+func (p *OutputPipeline) FieldsRejectedCount() int64 {
+	return p.fieldsRejected.Load()
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldRequirement_Check(t *testing.T) {
+	logEntry := NewLogWithMetadata("error", "payment failed", map[string]string{
+		"service": "checkout",
+		"status":  "502",
+		"latency": "0.25",
+		"retried": "true",
+		"time":    "2026-10-16T09:30:00Z",
+		"empty":   "",
+	})
+
+	valid := []FieldRequirement{
+		{Field: "message"},
+		{Field: "level"},
+		{Field: "service", Type: FieldTypeString},
+		{Field: "status", Type: FieldTypeInt},
+		{Field: "latency", Type: FieldTypeNumber},
+		{Field: "retried", Type: FieldTypeBool},
+		{Field: "time", Type: FieldTypeTimestamp},
+	}
+	for _, requirement := range valid {
+		if problem := requirement.check(logEntry); problem != "" {
+			t.Errorf("Expected %+v to be met, got %q", requirement, problem)
+		}
+	}
+
+	invalid := []FieldRequirement{
+		{Field: "source"},
+		{Field: "trace_id"},
+		{Field: "empty"},
+		{Field: "latency", Type: FieldTypeInt},
+		{Field: "service", Type: FieldTypeNumber},
+		{Field: "status", Type: FieldTypeBool},
+		{Field: "status", Type: FieldTypeTimestamp},
+	}
+	for _, requirement := range invalid {
+		if problem := requirement.check(logEntry); problem == "" {
+			t.Errorf("Expected %+v not to be met", requirement)
+		}
+	}
+}
+
+func TestFieldRequirement_Validate(t *testing.T) {
+	def := PluginDefinition{Type: "console", Config: map[string]any{"target": "stdout"},
+		RequireFields: []FieldRequirement{{Field: "service"}, {Field: "status", Type: "int"}}}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	def.RequireFields = []FieldRequirement{{Field: "status", Type: "integer"}}
+	if err := def.Validate(); err == nil || !strings.Contains(err.Error(), "must be string") {
+		t.Errorf("Expected an unknown type to be rejected, got %v", err)
+	}
+	def.RequireFields = []FieldRequirement{{Type: "int"}}
+	if err := def.Validate(); err == nil {
+		t.Error("Expected a requirement without a field to be rejected")
+	}
+}
+
+func TestEngine_RequireFields(t *testing.T) {
+	dir := t.TempDir()
+	output := newMockOutput()
+	buffer, err := NewOutputBuffer("strict", output, retryOverflowConfig(dir, 0, ""))
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+
+	engine := NewEngine()
+	strict := &OutputPipeline{Name: "strict", Output: output, Buffer: buffer,
+		RequireFields: []FieldRequirement{{Field: "service"}, {Field: "status", Type: FieldTypeInt}}}
+	lenient := &OutputPipeline{Name: "lenient", Output: newMockOutput(),
+		RequireFields: []FieldRequirement{{Field: "service"}}}
+	for _, pipeline := range []*OutputPipeline{strict, lenient} {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+	defer func() { _ = buffer.Close() }()
+
+	engine.dispatchLog(NewLogWithMetadata("info", "complete", map[string]string{"service": "api", "status": "200"}))
+	engine.dispatchLog(NewLogWithMetadata("info", "bad status", map[string]string{"service": "api", "status": "OK"}))
+	engine.dispatchLog(NewLog("info", "unparsed"))
+
+	if got := strict.FieldsRejectedCount(); got != 2 {
+		t.Errorf("Expected 2 logs rejected by the strict output, got %d", got)
+	}
+	if got := lenient.FieldsRejectedCount(); got != 1 {
+		t.Errorf("Expected 1 log rejected by the lenient output, got %d", got)
+	}
+
+	// Rejected logs of a buffered output are recoverable from its DLQ; the others are dropped
+	entries, err := buffer.ReadDLQ()
+	if err != nil {
+		t.Fatalf("Failed to read DLQ: %v", err)
+	}
+	if len(entries) != 2 || entries[0].DLQReason != DLQReasonRequiredFields || entries[1].Log.Message != "unparsed" {
+		t.Errorf("Expected the 2 rejected logs in the DLQ, got %d entries", len(entries))
+	}
+	if got := engine.Drops().Count(DropReasonRequiredFields); got != 1 {
+		t.Errorf("Expected 1 required_fields drop, got %d", got)
+	}
+}
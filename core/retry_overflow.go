@@ -0,0 +1,79 @@
+package core
+
+import "sort"
+
+// Retry queue overflow policies, applied to the oldest logs once the retry queue
+// holds more than max_retry_queue_size
+const (
+	RetryOverflowSpill = "spill" // Write them to the buffer directory; they are retried after a restart
+	RetryOverflowDLQ   = "dlq"   // Dead-letter them early with DLQReasonRetryQueueFull
+)
+
+// DLQReasonRetryQueueFull marks logs dead-lettered because the retry queue was full
+const DLQReasonRetryQueueFull = "retry_queue_full"
+
+// enforceRetryLimit evicts the oldest logs from a retry queue over max_retry_queue_size.
+// A tenth of the limit is evicted beyond the excess, so a persistently failing output
+// evicts in batches instead of on every failed delivery. The caller holds retryMu.
+func (ob *OutputBuffer) enforceRetryLimit() {
+	limit := ob.config.MaxRetryQueueSize
+	if limit <= 0 || len(ob.retryQueue) <= limit {
+		return
+	}
+
+	evict := min(len(ob.retryQueue)-limit+max(limit/10, 1), len(ob.retryQueue))
+	evicted, kept := splitOldest(ob.retryQueue, evict)
+	ob.retryQueue = kept
+
+	ob.statsMu.Lock()
+	ob.stats.CurrentRetrying = len(kept)
+	ob.statsMu.Unlock()
+
+	if ob.config.RetryQueueOverflow != RetryOverflowDLQ {
+		err := ob.writeBufferFile("spill", evicted...)
+		if err == nil {
+			for _, bufferedLog := range evicted {
+				bufferedLog.Log.settleAck(errAckSpilled)
+			}
+			ob.statsMu.Lock()
+			ob.stats.TotalSpilled += int64(len(evicted))
+			ob.statsMu.Unlock()
+			ob.logger().Printf("Retry queue full, spilled the %d oldest logs to disk", len(evicted))
+			return
+		}
+		ob.logger().Printf("Retry queue full and spilling failed, sending the %d oldest logs to the DLQ: %v", len(evicted), err)
+	}
+
+	for _, bufferedLog := range evicted {
+		bufferedLog.DLQReason = DLQReasonRetryQueueFull
+		ob.sendToDLQ(bufferedLog)
+	}
+}
+
+// splitOldest returns the n logs enqueued first, oldest first, and the others in
+// queue order. Age is taken from EnqueuedAt rather than the queue position or the
+// next attempt: retries keep their queue position whatever their backoff, but logs
+// reloaded from disk are appended after newer ones.
+func splitOldest(logs []*BufferedLog, n int) (oldest, rest []*BufferedLog) {
+	order := make([]int, len(logs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return logs[order[a]].EnqueuedAt.Before(logs[order[b]].EnqueuedAt)
+	})
+
+	evicted := make([]bool, len(logs))
+	oldest = make([]*BufferedLog, 0, n)
+	for _, i := range order[:n] {
+		evicted[i] = true
+		oldest = append(oldest, logs[i])
+	}
+	rest = make([]*BufferedLog, 0, len(logs)-n)
+	for i, bufferedLog := range logs {
+		if !evicted[i] {
+			rest = append(rest, bufferedLog)
+		}
+	}
+	return oldest, rest
+}
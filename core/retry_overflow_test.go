@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitOldest(t *testing.T) {
+	base := time.Now()
+	// Queue order differs from age, as after logs are reloaded from disk
+	logs := []*BufferedLog{}
+	for _, offset := range []int{3, 4, 0, 5, 1, 2} {
+		logs = append(logs, &BufferedLog{Log: NewLog("info", fmt.Sprintf("log %d", offset)), EnqueuedAt: base.Add(time.Duration(offset) * time.Second)})
+	}
+
+	messages := func(logs []*BufferedLog) []string {
+		var result []string
+		for _, bufferedLog := range logs {
+			result = append(result, bufferedLog.Log.Message)
+		}
+		return result
+	}
+
+	oldest, rest := splitOldest(logs, 3)
+	if got := messages(oldest); !reflect.DeepEqual(got, []string{"log 0", "log 1", "log 2"}) {
+		t.Errorf("Expected the three oldest logs, oldest first, got %v", got)
+	}
+	if got := messages(rest); !reflect.DeepEqual(got, []string{"log 3", "log 4", "log 5"}) {
+		t.Errorf("Expected the others in queue order, got %v", got)
+	}
+}
+
+// retryOverflowConfig returns a buffer config whose retries never come due during a test
+func retryOverflowConfig(dir string, limit int, overflow string) OutputBufferConfig {
+	return OutputBufferConfig{
+		Enabled:            true,
+		Dir:                dir,
+		MaxQueueSize:       10,
+		MaxRetries:         3,
+		RetryInterval:      time.Hour,
+		MaxRetryDelay:      time.Hour,
+		FlushInterval:      time.Hour,
+		DLQEnabled:         true,
+		DLQPath:            filepath.Join(dir, "dlq"),
+		MaxRetryQueueSize:  limit,
+		RetryQueueOverflow: overflow,
+	}
+}
+
+// requeueFailed adds logs to the retry queue as if their first delivery had just failed
+func requeueFailed(buffer *OutputBuffer, count int) {
+	base := time.Now()
+	for i := range count {
+		buffer.requeueForRetry(&BufferedLog{
+			Log:         NewLog("error", fmt.Sprintf("log %d", i)),
+			Attempts:    1,
+			LastAttempt: base,
+			OutputName:  "test",
+			EnqueuedAt:  base.Add(time.Duration(i) * time.Millisecond),
+		})
+	}
+}
+
+func TestOutputBuffer_RetryQueueSpill(t *testing.T) {
+	tmpDir := t.TempDir()
+	output := &MockOutput{}
+	output.SetShouldFail(true, 1000)
+
+	buffer, err := NewOutputBuffer("test", output, retryOverflowConfig(tmpDir, 10, ""))
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+
+	// 11 and 13 logs exceed the limit, each time evicting the excess plus a tenth of the limit
+	requeueFailed(buffer, 15)
+	stats := buffer.GetStats()
+	if stats.TotalSpilled != 6 || stats.CurrentRetrying != 9 {
+		t.Errorf("Expected 6 spilled and 9 retrying logs, got %d and %d", stats.TotalSpilled, stats.CurrentRetrying)
+	}
+	if stats.TotalDLQ != 0 {
+		t.Errorf("Expected no logs in the DLQ, got %d", stats.TotalDLQ)
+	}
+	if first := buffer.retryQueue[0].Log.Message; first != "log 6" {
+		t.Errorf("Expected the oldest logs to be spilled, retry queue starts with %q", first)
+	}
+
+	spills, _ := filepath.Glob(filepath.Join(tmpDir, "test", "spill-*.jsonl"))
+	if len(spills) != 3 {
+		t.Errorf("Expected one spill file per eviction, got %v", spills)
+	}
+	if err := buffer.Close(); err != nil {
+		t.Fatalf("Failed to close buffer: %v", err)
+	}
+
+	// On restart, the spilled logs are loaded along with the persisted retry queue
+	recovered := &MockOutput{}
+	config := retryOverflowConfig(tmpDir, 0, "")
+	config.RetryInterval = 10 * time.Millisecond
+	restarted, err := NewOutputBuffer("test", recovered, config)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = restarted.Close() }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recovered.GetLogs()) < 15 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := len(recovered.GetLogs()); got != 15 {
+		t.Errorf("Expected all 15 logs delivered after restart, got %d", got)
+	}
+}
+
+func TestOutputBuffer_RetryQueueDLQ(t *testing.T) {
+	tmpDir := t.TempDir()
+	buffer, err := NewOutputBuffer("test", &MockOutput{}, retryOverflowConfig(tmpDir, 4, RetryOverflowDLQ))
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+	defer func() { _ = buffer.Close() }()
+
+	requeueFailed(buffer, 5)
+
+	entries, err := buffer.ReadDLQ()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 logs dead-lettered early, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.DLQReason != DLQReasonRetryQueueFull || entry.Log.Message != fmt.Sprintf("log %d", i) {
+			t.Errorf("Unexpected DLQ entry %d: %s (%q)", i, entry.Log.Message, entry.DLQReason)
+		}
+	}
+	if stats := buffer.GetStats(); stats.TotalDLQ != 2 || stats.TotalSpilled != 0 || stats.CurrentRetrying != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestOutputBufferConfigRetryQueueValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		overflow string
+		valid    bool
+	}{
+		{"unbounded", 0, "", true},
+		{"spill", 1000, RetryOverflowSpill, true},
+		{"dlq", 1000, RetryOverflowDLQ, true},
+		{"negative limit", -1, "", false},
+		{"unknown policy", 1000, "drop", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultOutputBufferConfig()
+			config.MaxRetryQueueSize = tt.limit
+			config.RetryQueueOverflow = tt.overflow
+			if err := config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
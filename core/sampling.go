@@ -0,0 +1,81 @@
+package core
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+// SampleThreshold returns the hash below which a log is kept at rate (a fraction in (0, 1])
+func SampleThreshold(rate float64) uint64 {
+	if rate >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(rate * math.MaxUint64)
+}
+
+// SampleHash mixes the seed into an FNV-1a hash of the value. The final avalanche
+// step spreads similar inputs (e.g. consecutive positions) evenly over the hash range.
+func SampleHash(seed uint64, value string) uint64 {
+	h := fnv.New64a()
+	var seedBytes [8]byte
+	binary.BigEndian.PutUint64(seedBytes[:], seed)
+	_, _ = h.Write(seedBytes[:])
+	_, _ = h.Write([]byte(value))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// SampleKeyValue returns the value of a sampling key: "message", "level",
+// "source", "source_type" or a metadata key. An empty key or a missing metadata
+// key returns false.
+func SampleKeyValue(logEntry *Log, key string) (string, bool) {
+	switch key {
+	case "":
+		return "", false
+	case "message":
+		return logEntry.Message, true
+	case "level":
+		return logEntry.Level, true
+	case "source":
+		return logEntry.Source, true
+	case "source_type":
+		return logEntry.SourceType, true
+	default:
+		value, ok := logEntry.Metadata[key]
+		return value, ok
+	}
+}
+
+// sampled reports whether the pipeline's sample_rate keeps a log. Logs with the
+// sample key are hashed without a seed, so logs sharing a key value get the same
+// decision in every pipeline and across restarts, and a log kept at one rate is
+// kept at every higher rate. Logs without the key are sampled by their position
+// among the logs the pipeline sampled. Logs matching preserve_if are always kept.
+func (p *OutputPipeline) sampled(logEntry *Log) bool {
+	if p.SampleRate <= 0 || p.SampleRate >= 1 || p.preserve.matches(logEntry) {
+		return true
+	}
+
+	value, ok := SampleKeyValue(logEntry, p.SampleKey)
+	if !ok {
+		value = strconv.FormatUint(p.sampleSeq.Add(1)-1, 10)
+	}
+	if SampleHash(0, value) < SampleThreshold(p.SampleRate) {
+		return true
+	}
+	p.sampledOut.Add(1)
+	return false
+}
+
+// SampledOutCount returns the number of logs left out by the pipeline's sample_rate
+func (p *OutputPipeline) SampledOutCount() int64 {
+	return p.sampledOut.Load()
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPipelineSampleRate(t *testing.T) {
+	engine := NewEngine()
+	full := newMockOutput()
+	sampled := newMockOutput()
+	appOnly := newMockOutput()
+
+	pipelines := []*OutputPipeline{
+		{Name: "full", Output: full},
+		{Name: "sampled", Output: sampled, SampleRate: 0.1},
+		{Name: "app", Output: appOnly, Sources: []string{"app"}, SampleRate: 0.5},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	const logs = 10000
+	for i := range logs {
+		logEntry := NewLog("info", fmt.Sprintf("log %d", i))
+		logEntry.Source = "db"
+		if i%10 == 0 {
+			logEntry.Source = "app"
+		}
+		engine.dispatchLog(logEntry)
+	}
+	engine.Stop()
+
+	if got := len(full.getLogs()); got != logs {
+		t.Errorf("Expected the unsampled pipeline to receive every log, got %d", got)
+	}
+	if got := len(sampled.getLogs()); got < 900 || got > 1100 {
+		t.Errorf("Expected about 10%% of the logs, got %d", got)
+	}
+	if got := pipelines[1].SampledOutCount(); got != int64(logs-len(sampled.getLogs())) {
+		t.Errorf("Expected every log not forwarded to be counted as sampled out, got %d", got)
+	}
+
+	// The rate applies to the logs that pass routing, not to everything read
+	if got := len(appOnly.getLogs()); got < 400 || got > 600 {
+		t.Errorf("Expected about half of the 1000 app logs, got %d", got)
+	}
+	if got := pipelines[2].SampledOutCount(); got != int64(logs/10-len(appOnly.getLogs())) {
+		t.Errorf("Expected only routed logs to be sampled, got %d sampled out", got)
+	}
+
+	drops := engine.drops.Snapshot()
+	if drops[DropReasonSampled] != pipelines[1].SampledOutCount()+pipelines[2].SampledOutCount() {
+		t.Errorf("Expected sampled drops to match the pipelines, got %v", drops)
+	}
+}
+
+func TestPipelineSampleKey(t *testing.T) {
+	narrow := &OutputPipeline{Name: "narrow", SampleRate: 0.2, SampleKey: "trace_id"}
+	wide := &OutputPipeline{Name: "wide", SampleRate: 0.6, SampleKey: "trace_id"}
+	again := &OutputPipeline{Name: "again", SampleRate: 0.2, SampleKey: "trace_id"}
+
+	kept := 0
+	for trace := range 1000 {
+		var decisions []bool
+		for span := range 5 {
+			logEntry := NewLog("info", fmt.Sprintf("span %d", span))
+			logEntry.Metadata["trace_id"] = fmt.Sprintf("trace-%d", trace)
+			decisions = append(decisions, narrow.sampled(logEntry))
+
+			// The same key gives the same decision in another pipeline, and a
+			// log kept at a lower rate is kept at every higher rate
+			if again.sampled(logEntry) != decisions[span] {
+				t.Fatalf("Expected the same decision for trace %d in every pipeline", trace)
+			}
+			if decisions[span] && !wide.sampled(logEntry) {
+				t.Fatalf("Expected trace %d kept at 0.2 to be kept at 0.6", trace)
+			}
+		}
+		for _, decision := range decisions {
+			if decision != decisions[0] {
+				t.Fatalf("Expected every log of trace %d to be sampled together, got %v", trace, decisions)
+			}
+		}
+		if decisions[0] {
+			kept++
+		}
+	}
+	if kept < 150 || kept > 250 {
+		t.Errorf("Expected about 20%% of the traces, got %d", kept)
+	}
+
+	// Logs without the key fall back to their position
+	missing := 0
+	for i := range 1000 {
+		if narrow.sampled(NewLog("info", fmt.Sprintf("no trace %d", i))) {
+			missing++
+		}
+	}
+	if missing < 150 || missing > 250 {
+		t.Errorf("Expected about 20%% of the logs without the key, got %d", missing)
+	}
+}
+
+func TestPipelineSampleValidation(t *testing.T) {
+	base := PluginDefinition{Type: "console", Config: map[string]any{"target": "stdout"}}
+
+	tests := []struct {
+		name     string
+		rate     float64
+		key      string
+		shadow   bool
+		expected string
+	}{
+		{"rate", 0.25, "", false, ""},
+		{"rate and key", 0.25, "trace_id", false, ""},
+		{"full rate", 1, "", false, ""},
+		{"negative rate", -0.1, "", false, "SampleRate"},
+		{"rate above one", 1.5, "", false, "SampleRate"},
+		{"key without rate", 0, "trace_id", false, "requires sample_rate"},
+		{"shadow", 0.5, "", true, "shadow"},
+	}
+
+	for _, tt := range tests {
+		definition := base
+		definition.SampleRate = tt.rate
+		definition.SampleKey = tt.key
+		definition.Shadow = tt.shadow
+
+		err := definition.Validate()
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
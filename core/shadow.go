@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// shadowQueueSize bounds the logs waiting for a shadow pipeline. When the queue is
+// full the shadow misses logs instead of slowing down the real pipelines.
+const shadowQueueSize = 1000
+
+// ShadowStats counts the logs seen by a shadow pipeline. They are kept apart from
+// the engine counters (logs_dropped_total, traces) so a shadow never skews them.
+type ShadowStats struct {
+	Received    int64 // Logs offered to the shadow
+	Filtered    int64 // Logs dropped by the shadow's filters
+	Written     int64 // Logs written to the shadow output
+	WriteErrors int64 // Failed writes to the shadow output
+	QueueFull   int64 // Logs missed because the shadow queue was full
+	Queued      int64 // Logs waiting in the shadow queue
+}
+
+// shadowRunner feeds a shadow pipeline from its own goroutine
+type shadowRunner struct {
+	queue chan *Log
+	done  chan struct{}
+
+	received    atomic.Int64
+	filtered    atomic.Int64
+	written     atomic.Int64
+	writeErrors atomic.Int64
+	queueFull   atomic.Int64
+}
+
+// startShadow starts the goroutine that runs the filters and output of a shadow pipeline
+func (p *OutputPipeline) startShadow() {
+	p.shadow = &shadowRunner{
+		queue: make(chan *Log, shadowQueueSize),
+		done:  make(chan struct{}),
+	}
+	go p.runShadow()
+}
+
+// offerShadow hands a copy of a log to the shadow pipeline without ever blocking
+// the engine loop
+func (p *OutputPipeline) offerShadow(logEntry *Log) {
+	if !p.Enabled() {
+		p.skipped.Add(1)
+		return
+	}
+
+	p.shadow.received.Add(1)
+	entry := logEntry.Clone()
+	entry.trace = nil // Traces describe real delivery only
+	select {
+	case p.shadow.queue <- entry:
+	default:
+		p.shadow.queueFull.Add(1)
+	}
+}
+
+// runShadow filters and writes the queued logs until the queue is closed
+func (p *OutputPipeline) runShadow() {
+	defer close(p.shadow.done)
+
+	for logEntry := range p.shadow.queue {
+		entry, passed, _ := p.applyFilters(logEntry)
+		if !passed {
+			p.shadow.filtered.Add(1)
+			continue
+		}
+
+		if err := p.writeWithTimeout(context.Background(), p.stamp(entry, true)); err != nil {
+			p.shadow.writeErrors.Add(1)
+			engineLog.Printf("Error writing to shadow output '%s': %v", p.Name, err)
+			continue
+		}
+		p.shadow.written.Add(1)
+	}
+}
+
+// stopShadow stops accepting logs and waits for the queued ones to be written
+func (p *OutputPipeline) stopShadow() {
+	if p.shadow == nil {
+		return
+	}
+	close(p.shadow.queue)
+	<-p.shadow.done
+}
+
+// ShadowStats returns the shadow counters, or nil for a regular pipeline
+func (p *OutputPipeline) ShadowStats() *ShadowStats {
+	if p.shadow == nil {
+		return nil
+	}
+	return &ShadowStats{
+		Received:    p.shadow.received.Load(),
+		Filtered:    p.shadow.filtered.Load(),
+		Written:     p.shadow.written.Load(),
+		WriteErrors: p.shadow.writeErrors.Load(),
+		QueueFull:   p.shadow.queueFull.Load(),
+		Queued:      int64(len(p.shadow.queue)),
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShadowPipelineReceivesEveryLog(t *testing.T) {
+	engine := NewEngine()
+	prod := newMockOutput()
+	shadow := newMockOutput()
+
+	pipelines := []*OutputPipeline{
+		{Name: "prod", Output: prod, Sources: []string{"app"}, Tags: []string{"billing"}},
+		{Name: "candidate", Output: shadow, Filters: []FilterPlugin{&metadataFilter{key: "shadowed", value: "true"}}, Shadow: true},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	for _, source := range []string{"app", "db"} {
+		logEntry := NewLog("info", "from "+source)
+		logEntry.Source = source
+		engine.dispatchLog(logEntry)
+	}
+	engine.Stop() // Drains the shadow queue
+
+	if got := len(shadow.getLogs()); got != 2 {
+		t.Fatalf("Expected the shadow to receive both logs, got %d", got)
+	}
+	if got := len(prod.getLogs()); got != 0 {
+		t.Errorf("Expected prod to keep rejecting untagged logs, got %d", got)
+	}
+	for _, logEntry := range shadow.getLogs() {
+		if logEntry.Metadata["shadowed"] != "true" {
+			t.Errorf("Expected the shadow filter to run, got metadata %v", logEntry.Metadata)
+		}
+	}
+
+	// Only the real pipeline's rejections count as engine drops
+	drops := engine.drops.Snapshot()
+	if drops[DropReasonSourceMismatch] != 1 || drops[DropReasonTagMismatch] != 1 || len(drops) != 2 {
+		t.Errorf("Expected drops only from prod, got %v", drops)
+	}
+
+	stats := pipelines[1].ShadowStats()
+	if stats == nil || stats.Received != 2 || stats.Written != 2 || stats.Filtered != 0 {
+		t.Errorf("Unexpected shadow stats %+v", stats)
+	}
+	if pipelines[1].Buffer != nil || pipelines[0].ShadowStats() != nil {
+		t.Error("Expected shadow pipelines to be unbuffered and regular ones to have no shadow stats")
+	}
+}
+
+func TestShadowPipelineIsolation(t *testing.T) {
+	engine := NewEngine()
+	prod := newMockOutput()
+	stuck := &blockingOutput{release: make(chan struct{})}
+
+	pipelines := []*OutputPipeline{
+		{Name: "prod", Output: prod},
+		{Name: "stuck", Output: stuck, Shadow: true},
+		{Name: "strict", Output: newMockOutput(), Filters: []FilterPlugin{newMockFilter(false)}, Shadow: true},
+	}
+	for _, pipeline := range pipelines {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	const logs = shadowQueueSize + 500
+	start := time.Now()
+	for i := range logs {
+		engine.dispatchLog(NewLog("info", fmt.Sprintf("log %d", i)))
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected a hung shadow output not to slow down dispatch, took %s", elapsed)
+	}
+	if got := len(prod.getLogs()); got != logs {
+		t.Errorf("Expected prod to receive %d logs, got %d", logs, got)
+	}
+	if stats := pipelines[1].ShadowStats(); stats.QueueFull == 0 {
+		t.Errorf("Expected the stuck shadow to miss logs, got %+v", stats)
+	}
+
+	close(stuck.release)
+	engine.Stop()
+
+	if stats := pipelines[2].ShadowStats(); stats.Filtered+stats.QueueFull != logs || stats.Filtered == 0 || stats.Written != 0 {
+		t.Errorf("Expected every log filtered by the strict shadow, got %+v", stats)
+	}
+	if drops := engine.drops.Snapshot(); len(drops) != 0 {
+		t.Errorf("Expected shadow drops to stay out of the engine counters, got %v", drops)
+	}
+}
+
+func TestShadowPipelineValidation(t *testing.T) {
+	valid := PluginDefinition{Type: "console", Config: map[string]any{"target": "stdout"}, Shadow: true}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid shadow output, got %v", err)
+	}
+
+	withSources := valid
+	withSources.Sources = []string{"app"}
+	if err := withSources.Validate(); err == nil || !strings.Contains(err.Error(), "shadow") {
+		t.Errorf("Expected sources to be rejected on a shadow output, got %v", err)
+	}
+
+	withTags := valid
+	withTags.Tags = []string{"audit"}
+	if err := withTags.Validate(); err == nil || !strings.Contains(err.Error(), "shadow") {
+		t.Errorf("Expected tags to be rejected on a shadow output, got %v", err)
+	}
+}
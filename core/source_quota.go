@@ -0,0 +1,211 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	// DropReasonSourceQuota counts logs over their source's quota
+	DropReasonSourceQuota = "source_quota"
+
+	// DefaultSourceQuotaMaxSources is how many sources get their own bucket and drop
+	// counter when max_sources is not set
+	DefaultSourceQuotaMaxSources = 10000
+
+	// sourceQuotaOther collects the drops of sources past max_sources
+	sourceQuotaOther = "_other"
+)
+
+// SourceQuotaConfig limits how many logs per second each source (input name) may
+// send, so one noisy input cannot starve the others sharing the engine. Logs over
+// the quota are dropped before they are persisted or dispatched.
+type SourceQuotaConfig struct {
+	Default    float64            `yaml:"default,omitempty"`     // Logs per second per source (0 = unlimited)
+	Burst      int                `yaml:"burst,omitempty"`       // Logs a source may send at once (default: one second of its rate)
+	Sources    map[string]float64 `yaml:"sources,omitempty"`     // Per-source rates overriding default (0 = unlimited)
+	MaxSources int                `yaml:"max_sources,omitempty"` // Sources tracked at once (default: 10000)
+}
+
+// Validate validates the SourceQuotaConfig
+func (c SourceQuotaConfig) Validate() error {
+	for source, rate := range c.Sources {
+		if source == "" {
+			return fmt.Errorf("sources: source names cannot be empty")
+		}
+		if rate < 0 {
+			return fmt.Errorf("sources: rate for %q must be no less than 0", source)
+		}
+	}
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Default, validation.Min(0.0).Error("must be no less than 0")),
+		validation.Field(&c.Burst, validation.Min(0).Error("must be no less than 0")),
+		validation.Field(&c.MaxSources, validation.Min(0).Error("must be no less than 0")),
+	)
+}
+
+// enabled reports whether any source has a quota
+func (c SourceQuotaConfig) enabled() bool {
+	if c.Default > 0 {
+		return true
+	}
+	for _, rate := range c.Sources {
+		if rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rate returns the quota of a source in logs per second (0 = unlimited)
+func (c SourceQuotaConfig) rate(source string) float64 {
+	if rate, ok := c.Sources[source]; ok {
+		return rate
+	}
+	return c.Default
+}
+
+// burst returns the bucket size for a source rate
+func (c SourceQuotaConfig) burst(rate float64) float64 {
+	if c.Burst > 0 {
+		return float64(c.Burst)
+	}
+	return max(rate, 1)
+}
+
+// maxSources returns how many sources are tracked at once
+func (c SourceQuotaConfig) maxSources() int {
+	if c.MaxSources > 0 {
+		return c.MaxSources
+	}
+	return DefaultSourceQuotaMaxSources
+}
+
+// quotaBucket is the token bucket of one source
+type quotaBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last refill
+func (b *quotaBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// sourceQuota keeps a token bucket and a drop counter per source. Buckets that
+// have refilled are equivalent to new ones, so they are dropped when max_sources
+// is reached; drop counters past max_sources are added up under "_other".
+type sourceQuota struct {
+	config  SourceQuotaConfig
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*quotaBucket
+	dropped map[string]int64
+}
+
+// newSourceQuota returns the per-source quota, or nil when no source has one
+func newSourceQuota(config SourceQuotaConfig) *sourceQuota {
+	if !config.enabled() {
+		return nil
+	}
+	return &sourceQuota{
+		config:  config,
+		now:     time.Now,
+		buckets: make(map[string]*quotaBucket),
+		dropped: make(map[string]int64),
+	}
+}
+
+// SetSourceQuotas configures the per-source log rate limits
+func (e *Engine) SetSourceQuotas(config SourceQuotaConfig) {
+	e.sourceQuota = newSourceQuota(config)
+}
+
+// allow takes a token from the source's bucket and reports whether the log is
+// within quota (always true for a nil quota)
+func (q *sourceQuota) allow(source string) bool {
+	if q == nil {
+		return true
+	}
+	rate := q.config.rate(source)
+	if rate <= 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	bucket, ok := q.buckets[source]
+	if !ok {
+		if len(q.buckets) >= q.config.maxSources() {
+			q.pruneLocked(now)
+		}
+		burst := q.config.burst(rate)
+		bucket = &quotaBucket{rate: rate, burst: burst, tokens: burst, last: now}
+		q.buckets[source] = bucket
+	}
+
+	bucket.refill(now)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true
+	}
+
+	if _, counted := q.dropped[source]; !counted && len(q.dropped) >= q.config.maxSources() {
+		source = sourceQuotaOther
+	}
+	q.dropped[source]++
+	return false
+}
+
+// pruneLocked drops the buckets that have refilled. If every tracked source is
+// still limited, arbitrary ones are dropped so the number of buckets stays bounded.
+func (q *sourceQuota) pruneLocked(now time.Time) {
+	for source, bucket := range q.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(q.buckets, source)
+		}
+	}
+	for source := range q.buckets {
+		if len(q.buckets) < q.config.maxSources() {
+			break
+		}
+		delete(q.buckets, source)
+	}
+}
+
+// droppedBySource returns the logs dropped over quota keyed by source
+func (q *sourceQuota) droppedBySource() map[string]int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	snapshot := make(map[string]int64, len(q.dropped))
+	for source, count := range q.dropped {
+		snapshot[source] = count
+	}
+	return snapshot
+}
+
+// resetDropped zeroes the per-source drop counters
+func (q *sourceQuota) resetDropped() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dropped = make(map[string]int64)
+}
+
+// overQuota drops a log its source sent over quota and counts it
+func (e *Engine) overQuota(logEntry *Log) bool {
+	if e.sourceQuota.allow(logEntry.Source) {
+		return false
+	}
+	e.drops.Inc(DropReasonSourceQuota)
+	engineLog.Printf("Log DROPPED over the quota of source '%s'", logEntry.Source)
+	return true
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestSourceQuota returns a quota driven by a fake clock
+func newTestSourceQuota(config SourceQuotaConfig) (*sourceQuota, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	q := newSourceQuota(config)
+	q.now = func() time.Time { return now }
+	return q, &now
+}
+
+func TestSourceQuota_PerSource(t *testing.T) {
+	q, now := newTestSourceQuota(SourceQuotaConfig{
+		Default: 2,
+		Sources: map[string]float64{"noisy": 1, "trusted": 0},
+	})
+
+	allowed := func(source string, n int) int {
+		count := 0
+		for range n {
+			if q.allow(source) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := allowed("noisy", 5); got != 1 {
+		t.Errorf("Expected noisy to get its burst of 1, got %d", got)
+	}
+	if got := allowed("app", 5); got != 2 {
+		t.Errorf("Expected app to get the default burst of 2, got %d", got)
+	}
+	if got := allowed("trusted", 100); got != 100 {
+		t.Errorf("Expected a zero rate to be unlimited, got %d", got)
+	}
+
+	*now = now.Add(time.Second)
+	if got := allowed("noisy", 5); got != 1 {
+		t.Errorf("Expected noisy to refill 1 token per second, got %d", got)
+	}
+
+	dropped := q.droppedBySource()
+	if dropped["noisy"] != 8 || dropped["app"] != 3 || dropped["trusted"] != 0 {
+		t.Errorf("Expected per-source drop counts noisy=8 app=3, got %v", dropped)
+	}
+
+	q.resetDropped()
+	if len(q.droppedBySource()) != 0 {
+		t.Error("Expected reset to clear the drop counters")
+	}
+}
+
+func TestSourceQuota_Bounded(t *testing.T) {
+	q, now := newTestSourceQuota(SourceQuotaConfig{Default: 1, MaxSources: 3})
+
+	// Ephemeral sources each use their only token and are then limited
+	for i := range 10 {
+		source := fmt.Sprintf("pod-%d", i)
+		q.allow(source)
+		q.allow(source)
+	}
+	if len(q.buckets) > 3 {
+		t.Errorf("Expected at most 3 buckets, got %d", len(q.buckets))
+	}
+	dropped := q.droppedBySource()
+	if len(dropped) > 4 {
+		t.Errorf("Expected at most 3 sources plus _other, got %v", dropped)
+	}
+	if dropped[sourceQuotaOther] != 7 {
+		t.Errorf("Expected the drops of 7 sources past max_sources under _other, got %v", dropped)
+	}
+
+	// Refilled buckets are pruned first
+	*now = now.Add(time.Minute)
+	q.allow("new")
+	if len(q.buckets) != 1 {
+		t.Errorf("Expected refilled buckets to be pruned, got %d buckets", len(q.buckets))
+	}
+}
+
+func TestSourceQuotaConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SourceQuotaConfig
+		wantErr bool
+	}{
+		{"empty", SourceQuotaConfig{}, false},
+		{"default", SourceQuotaConfig{Default: 100, Burst: 200}, false},
+		{"override", SourceQuotaConfig{Sources: map[string]float64{"app": 10}}, false},
+		{"negative default", SourceQuotaConfig{Default: -1}, true},
+		{"negative burst", SourceQuotaConfig{Default: 1, Burst: -1}, true},
+		{"negative source rate", SourceQuotaConfig{Sources: map[string]float64{"app": -1}}, true},
+		{"empty source", SourceQuotaConfig{Sources: map[string]float64{"": 1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if newSourceQuota(SourceQuotaConfig{Sources: map[string]float64{"app": 0}}) != nil {
+		t.Error("Expected no quota when every rate is unlimited")
+	}
+}
+
+func TestEngineSourceQuota(t *testing.T) {
+	engine := NewEngine()
+	engine.SetSourceQuotas(SourceQuotaConfig{Sources: map[string]float64{"noisy": 1}})
+	output := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "out", Output: output}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	for _, source := range []string{"noisy", "noisy", "noisy", "quiet", "quiet"} {
+		logEntry := NewLog("info", source)
+		logEntry.Source = source
+		if engine.receiveLog(logEntry) {
+			engine.dispatchLog(logEntry)
+		}
+	}
+	engine.Stop()
+
+	if got := len(output.getLogs()); got != 3 {
+		t.Errorf("Expected 1 noisy and 2 quiet logs delivered, got %d", got)
+	}
+	if got := engine.drops.Count(DropReasonSourceQuota); got != 2 {
+		t.Errorf("Expected 2 logs dropped over quota, got %d", got)
+	}
+	if got := engine.sourceQuota.droppedBySource()["noisy"]; got != 2 {
+		t.Errorf("Expected the drops to be counted for noisy, got %d", got)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// startupGracePoll is how often Start checks whether outputs became healthy
+const startupGracePoll = 100 * time.Millisecond
+
+// healthReporter is implemented by outputs that connect in the background, such
+// as resilient outputs
+type healthReporter interface {
+	IsHealthy() bool
+}
+
+// SetStartupGrace makes Start wait up to grace for outputs that connect in the
+// background to report healthy before it starts inputs (0 disables it)
+func (e *Engine) SetStartupGrace(grace time.Duration) {
+	e.startupGrace = grace
+}
+
+// waitForOutputs blocks until every enabled output that reports its health is
+// healthy, grace elapses or ctx ends. Outputs still not ready are logged and
+// receive logs anyway; their buffers retry as usual. It does not need e.mu.
+func (e *Engine) waitForOutputs(ctx context.Context, grace time.Duration) {
+	if grace <= 0 {
+		return
+	}
+
+	started := time.Now()
+	timeout := time.NewTimer(grace)
+	defer timeout.Stop()
+	ticker := time.NewTicker(startupGracePoll)
+	defer ticker.Stop()
+
+	for {
+		pending := e.pendingOutputs()
+		if len(pending) == 0 {
+			engineLog.Printf("Outputs ready after %s", time.Since(started).Round(time.Millisecond))
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			engineLog.Printf("Starting inputs after startup_grace %s with outputs not ready: %s", grace, strings.Join(pending, ", "))
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pendingOutputs returns the names of enabled outputs that report they are not healthy.
+// Shadow outputs never delay startup.
+func (e *Engine) pendingOutputs() []string {
+	var pending []string
+	for _, pipeline := range e.plugins.Load().pipelines {
+		if pipeline.Shadow || pipeline.disabled.Load() {
+			continue
+		}
+		if reporter, ok := pipeline.Output.(healthReporter); ok && !reporter.IsHealthy() {
+			pending = append(pending, pipeline.Name)
+		}
+	}
+	return pending
+}
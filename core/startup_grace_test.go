@@ -0,0 +1,114 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// warmingOutput reports healthy once readyAt has passed
+type warmingOutput struct {
+	*mockOutput
+	readyAt time.Time
+}
+
+func (w *warmingOutput) IsHealthy() bool {
+	return time.Now().After(w.readyAt)
+}
+
+// startTimeInput records when the engine started it
+type startTimeInput struct {
+	*mockInput
+	startedAt time.Time
+}
+
+func (s *startTimeInput) Start() error {
+	s.startedAt = time.Now()
+	return s.mockInput.Start()
+}
+
+func TestEngineStartupGrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		grace    time.Duration
+		warmup   time.Duration
+		minDelay time.Duration
+		maxDelay time.Duration
+	}{
+		{"disabled", 0, time.Hour, 0, 100 * time.Millisecond},
+		{"waits for outputs", time.Second, 200 * time.Millisecond, 200 * time.Millisecond, 800 * time.Millisecond},
+		{"bounded by grace", 300 * time.Millisecond, time.Hour, 300 * time.Millisecond, 800 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		engine := NewEngine()
+		engine.SetStartupGrace(tt.grace)
+
+		started := time.Now()
+		output := &warmingOutput{mockOutput: newMockOutput(), readyAt: started.Add(tt.warmup)}
+		pipelines := []*OutputPipeline{
+			{Name: "warming", Output: output},
+			// Neither a shadow nor a disabled output delays startup
+			{Name: "candidate", Output: &warmingOutput{mockOutput: newMockOutput(), readyAt: started.Add(time.Hour)}, Shadow: true},
+			{Name: "off", Output: &warmingOutput{mockOutput: newMockOutput(), readyAt: started.Add(time.Hour)}},
+		}
+		for _, pipeline := range pipelines {
+			if err := engine.AddOutputPipeline(pipeline); err != nil {
+				t.Fatalf("Failed to add output pipeline: %v", err)
+			}
+		}
+		if err := engine.SetPipelineEnabled("off", false); err != nil {
+			t.Fatalf("Failed to disable pipeline: %v", err)
+		}
+
+		input := &startTimeInput{mockInput: newMockInput(nil)}
+		engine.AddInput("app", input)
+		engine.Start()
+		engine.Stop()
+
+		delay := input.startedAt.Sub(started)
+		if delay < tt.minDelay || delay > tt.maxDelay {
+			t.Errorf("%s: expected inputs to start after %s-%s, got %s", tt.name, tt.minDelay, tt.maxDelay, delay)
+		}
+	}
+}
+
+func TestEngineReloadStartupGraceReleasesLock(t *testing.T) {
+	inputs := []PluginDefinition{{Type: "stdin", Config: map[string]any{"resilient": false}}}
+	outputs := []PluginDefinition{{Type: "console", Config: map[string]any{"resilient": false}}}
+	engine := NewEngine()
+	engine.Start()
+	defer engine.Stop()
+
+	input := &startTimeInput{mockInput: newMockInput(nil)}
+	build := func(*Config) (func(*Engine), error) {
+		return func(e *Engine) {
+			output := &warmingOutput{mockOutput: newMockOutput(), readyAt: time.Now().Add(time.Hour)}
+			_ = e.AddOutputPipeline(&OutputPipeline{Name: "warming", Output: output})
+			e.AddInput("app", input)
+		}, nil
+	}
+
+	grace := 500 * time.Millisecond
+	reloaded := make(chan error, 1)
+	started := time.Now()
+	go func() {
+		reloaded <- engine.ReloadConfigFrom(ReloadTriggerManual, &Config{Inputs: inputs, Outputs: outputs, StartupGrace: grace}, build)
+	}()
+
+	// Health checks answer while the reload waits for the output
+	time.Sleep(100 * time.Millisecond)
+	w := httptest.NewRecorder()
+	engine.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK || time.Since(started) > grace {
+		t.Errorf("Expected /health to answer during startup_grace, got %d after %s", w.Code, time.Since(started))
+	}
+
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if delay := input.startedAt.Sub(started); delay < grace {
+		t.Errorf("Expected inputs to start after startup_grace, got %s", delay)
+	}
+}
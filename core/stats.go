@@ -0,0 +1,227 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EngineStats is a point-in-time snapshot of engine counters
+type EngineStats struct {
+	Uptime             time.Duration
+	TotalLogsProcessed int64
+	TotalLogsInjected  int64
+	LogsDropped        map[string]int64 // Dropped logs by reason
+	Pipelines          []PipelineStats
+	Inputs             map[string]map[string]any // Counters reported by inputs, by input name (only inputs that report any)
+	MetadataStorage    *MetadataStorageStats     // Nil unless metadata storage is compact
+}
+
+// PipelineStats is a snapshot of a single output pipeline
+type PipelineStats struct {
+	Name           string
+	Enabled        bool
+	SkippedLogs    int64
+	WriteTimeouts  int64
+	SampledOut     int64          // Logs left out by the pipeline's sample_rate
+	DeadlineMissed int64          // Logs that missed their delivery deadline for this pipeline
+	FieldsRejected int64          // Logs missing a field the pipeline's require_fields asks for
+	Buffer         *BufferStats   // Nil when the pipeline is not buffered
+	Output         map[string]any // Counters reported by the output itself, nil when it reports none
+	Shadow         *ShadowStats   // Nil for regular pipelines
+}
+
+// OutputStatsReporter is an optional interface for outputs that report their own
+// counters (e.g. logs written) in /status and the periodic stats
+type OutputStatsReporter interface {
+	OutputStats() map[string]any
+}
+
+// InputStatsReporter is an optional interface for inputs that report their own
+// counters (e.g. open connections) in /status and the periodic stats
+type InputStatsReporter interface {
+	InputStats() map[string]any
+}
+
+// ResilienceReporter is an optional interface for plugins that report their
+// resilience state (health, retries, last error) in /status and /metrics.
+// The resilient input and output wrappers implement it.
+type ResilienceReporter interface {
+	GetStats() map[string]any
+}
+
+// resilienceStats returns the resilience state of a plugin, or nil when it is not wrapped
+func resilienceStats(plugin any) map[string]any {
+	if reporter, ok := plugin.(ResilienceReporter); ok {
+		return reporter.GetStats()
+	}
+	return nil
+}
+
+// inputStats returns the counters reported by an input, or nil
+func inputStats(input InputPlugin) map[string]any {
+	if reporter, ok := input.(InputStatsReporter); ok {
+		return reporter.InputStats()
+	}
+	return nil
+}
+
+// outputStats returns the counters reported by an output, or nil
+func outputStats(output OutputPlugin) map[string]any {
+	if reporter, ok := output.(OutputStatsReporter); ok {
+		return reporter.OutputStats()
+	}
+	return nil
+}
+
+// Stats returns a consistent snapshot of the engine counters
+func (e *Engine) Stats() EngineStats {
+	e.metricsMu.RLock()
+	defer e.metricsMu.RUnlock()
+	plugins := e.plugins.Load()
+
+	stats := EngineStats{
+		Uptime:             time.Since(e.startTime),
+		TotalLogsProcessed: e.totalLogsProcessed,
+		TotalLogsInjected:  e.totalLogsInjected,
+		LogsDropped:        e.drops.Snapshot(),
+		Pipelines:          make([]PipelineStats, 0, len(plugins.pipelines)),
+		MetadataStorage:    e.metadataStorageStatus(),
+	}
+
+	for _, pipeline := range plugins.pipelines {
+		pipelineStats := PipelineStats{
+			Name:           pipeline.Name,
+			Enabled:        pipeline.Enabled(),
+			SkippedLogs:    pipeline.SkippedCount(),
+			WriteTimeouts:  pipeline.WriteTimeoutCount(),
+			SampledOut:     pipeline.SampledOutCount(),
+			DeadlineMissed: pipeline.DeadlineMissedCount(),
+			FieldsRejected: pipeline.FieldsRejectedCount(),
+			Output:         outputStats(pipeline.Output),
+			Shadow:         pipeline.ShadowStats(),
+		}
+		if pipeline.Buffer != nil {
+			bufferStats := pipeline.Buffer.GetStats()
+			pipelineStats.Buffer = &bufferStats
+		}
+		stats.Pipelines = append(stats.Pipelines, pipelineStats)
+	}
+
+	for name, input := range plugins.inputs {
+		if counters := inputStats(input); counters != nil {
+			if stats.Inputs == nil {
+				stats.Inputs = make(map[string]map[string]any)
+			}
+			stats.Inputs[name] = counters
+		}
+	}
+
+	return stats
+}
+
+// SetStatsInterval enables periodic stats logging at the given interval (0 disables it)
+func (e *Engine) SetStatsInterval(interval time.Duration) {
+	e.statsInterval = interval
+}
+
+// SetSerializationCache enables sharing JSON encodings between the outputs a log
+// fans out to; call it before Start
+func (e *Engine) SetSerializationCache(enabled bool) {
+	e.serializationCache = enabled
+}
+
+// emitStats logs a stats summary every statsInterval until the engine stops
+func (e *Engine) emitStats(ctx context.Context, interval time.Duration) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			logStats(e.Stats())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// logStats writes a stats snapshot as key=value fields: one engine summary and
+// one line per pipeline and per input reporting stats
+func logStats(stats EngineStats) {
+	var dropped int64
+	reasons := make([]string, 0, len(stats.LogsDropped))
+	for reason, count := range stats.LogsDropped {
+		dropped += count
+		reasons = append(reasons, fmt.Sprintf("%s:%d", reason, count))
+	}
+	sort.Strings(reasons)
+
+	fields := []any{
+		"uptime", stats.Uptime.Truncate(time.Second), "processed", stats.TotalLogsProcessed,
+		"injected", stats.TotalLogsInjected, "dropped", dropped,
+		"drop_reasons", strings.Join(reasons, ","), "pipelines", len(stats.Pipelines)}
+	if interned := stats.MetadataStorage; interned != nil {
+		fields = append(fields, "interned", interned.Interned,
+			"intern_hits", interned.Hits, "intern_misses", interned.Misses)
+	}
+	statsLog.Print("engine", fields...)
+
+	for _, pipeline := range stats.Pipelines {
+		fields := []any{"pipeline", pipeline.Name, "enabled", pipeline.Enabled,
+			"skipped", pipeline.SkippedLogs, "write_timeouts", pipeline.WriteTimeouts}
+		if pipeline.SampledOut > 0 {
+			fields = append(fields, "sampled_out", pipeline.SampledOut)
+		}
+		if pipeline.DeadlineMissed > 0 {
+			fields = append(fields, "deadline_missed", pipeline.DeadlineMissed)
+		}
+		if pipeline.FieldsRejected > 0 {
+			fields = append(fields, "fields_rejected", pipeline.FieldsRejected)
+		}
+		if pipeline.Buffer != nil {
+			fields = append(fields,
+				"delivered", pipeline.Buffer.TotalDelivered, "retried", pipeline.Buffer.TotalRetried,
+				"failed", pipeline.Buffer.TotalFailed, "dlq", pipeline.Buffer.TotalDLQ,
+				"spilled", pipeline.Buffer.TotalSpilled, "stale", pipeline.Buffer.TotalStale,
+				"expired", pipeline.Buffer.TotalExpired,
+				"queued", pipeline.Buffer.CurrentQueued, "retrying", pipeline.Buffer.CurrentRetrying)
+		}
+		if shadow := pipeline.Shadow; shadow != nil {
+			fields = append(fields, "shadow", true,
+				"received", shadow.Received, "filtered", shadow.Filtered, "written", shadow.Written,
+				"write_errors", shadow.WriteErrors, "queue_full", shadow.QueueFull)
+		}
+		keys := make([]string, 0, len(pipeline.Output))
+		for key := range pipeline.Output {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields = append(fields, "output."+key, pipeline.Output[key])
+		}
+		statsLog.Print("pipeline", fields...)
+	}
+
+	names := make([]string, 0, len(stats.Inputs))
+	for name := range stats.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields := []any{"input", name}
+		keys := make([]string, 0, len(stats.Inputs[name]))
+		for key := range stats.Inputs[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields = append(fields, key, stats.Inputs[name][key])
+		}
+		statsLog.Print("input", fields...)
+	}
+}
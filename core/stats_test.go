@@ -0,0 +1,257 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a goroutine-safe writer for capturing log output
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestEngineStats(t *testing.T) {
+	engine := NewEngine()
+	engine.SetOutputBufferConfig(OutputBufferConfig{
+		Enabled:       true,
+		Dir:           t.TempDir(),
+		MaxQueueSize:  10,
+		MaxRetries:    3,
+		RetryInterval: time.Second,
+		MaxRetryDelay: time.Minute,
+		FlushInterval: time.Minute,
+	})
+
+	buffered := &OutputPipeline{Name: "buffered", Output: newMockOutput()}
+	disabled := &OutputPipeline{Name: "disabled", Output: newMockOutput()}
+	for _, pipeline := range []*OutputPipeline{buffered, disabled} {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+	defer func() {
+		_ = buffered.Buffer.Close()
+		_ = disabled.Buffer.Close()
+	}()
+
+	engine.totalLogsProcessed = 5
+	engine.Drops().Inc(DropReasonFilter)
+	disabled.SetEnabled(false)
+
+	stats := engine.Stats()
+	if stats.TotalLogsProcessed != 5 {
+		t.Errorf("Expected 5 processed logs, got %d", stats.TotalLogsProcessed)
+	}
+	if stats.LogsDropped[DropReasonFilter] != 1 {
+		t.Errorf("Expected 1 filter drop, got %d", stats.LogsDropped[DropReasonFilter])
+	}
+	if len(stats.Pipelines) != 2 {
+		t.Fatalf("Expected 2 pipelines, got %d", len(stats.Pipelines))
+	}
+	if stats.Pipelines[0].Buffer == nil {
+		t.Error("Expected buffer stats for buffered pipeline")
+	}
+	if stats.Pipelines[1].Enabled {
+		t.Errorf("Expected disabled pipeline, got %+v", stats.Pipelines[1])
+	}
+
+	unbuffered := NewEngine()
+	if err := unbuffered.AddOutputPipeline(&OutputPipeline{Name: "plain", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	if stats := unbuffered.Stats(); stats.Pipelines[0].Buffer != nil {
+		t.Error("Expected no buffer stats for unbuffered pipeline")
+	}
+}
+
+func TestEngineStatsInterval(t *testing.T) {
+	output := &syncBuffer{}
+	log.SetOutput(output)
+	defer log.SetOutput(os.Stderr)
+
+	engine := NewEngine()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "console", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	engine.SetStatsInterval(20 * time.Millisecond)
+
+	engine.Start()
+	time.Sleep(70 * time.Millisecond)
+	engine.Stop()
+
+	logged := output.String()
+	if !strings.Contains(logged, "component=stats uptime=") || !strings.Contains(logged, "processed=0") {
+		t.Errorf("Expected engine stats summary in log output, got: %s", logged)
+	}
+	if !strings.Contains(logged, "component=stats pipeline=console enabled=true") {
+		t.Errorf("Expected pipeline stats in log output, got: %s", logged)
+	}
+}
+
+func TestEngineStatsDisabledByDefault(t *testing.T) {
+	output := &syncBuffer{}
+	log.SetOutput(output)
+	defer log.SetOutput(os.Stderr)
+
+	engine := NewEngine()
+	engine.Start()
+	time.Sleep(50 * time.Millisecond)
+	engine.Stop()
+
+	if strings.Contains(output.String(), "component=stats") {
+		t.Error("Expected no stats output when stats_interval is unset")
+	}
+}
+
+// countingOutput reports its write count through OutputStatsReporter
+type countingOutput struct {
+	mockOutput
+}
+
+func (c *countingOutput) OutputStats() map[string]any {
+	return map[string]any{"logs_written": c.getCallCount()}
+}
+
+func TestEngineOutputStats(t *testing.T) {
+	engine := NewEngine()
+	counting := &countingOutput{}
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "counting", Output: counting}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "plain", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	_ = counting.Write(NewLog("info", "counted"))
+
+	stats := engine.Stats()
+	if stats.Pipelines[0].Output["logs_written"] != 1 {
+		t.Errorf("Expected logs_written=1 for the counting output, got %v", stats.Pipelines[0].Output)
+	}
+	if stats.Pipelines[1].Output != nil {
+		t.Errorf("Expected no output stats for a plain output, got %v", stats.Pipelines[1].Output)
+	}
+
+	w := httptest.NewRecorder()
+	engine.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	if !strings.Contains(w.Body.String(), `"output_stats":{"logs_written":1}`) {
+		t.Errorf("Expected output_stats in /status, got %s", w.Body.String())
+	}
+
+	output := &syncBuffer{}
+	log.SetOutput(output)
+	defer log.SetOutput(os.Stderr)
+	logStats(stats)
+	if !strings.Contains(output.String(), "pipeline=counting enabled=true skipped=0 write_timeouts=0 output.logs_written=1") {
+		t.Errorf("Expected output stats in the stats log, got: %s", output.String())
+	}
+}
+
+// connectionInput reports connection counters through InputStatsReporter
+type connectionInput struct {
+	*mockInput
+}
+
+func (c *connectionInput) InputStats() map[string]any {
+	return map[string]any{"connections": 3, "rejected_requests": 1}
+}
+
+func TestEngineInputStats(t *testing.T) {
+	engine := NewEngine()
+	engine.AddInput("http", &connectionInput{mockInput: newMockInput(nil)})
+	engine.AddInput("file", newMockInput(nil))
+
+	stats := engine.Stats()
+	if len(stats.Inputs) != 1 || stats.Inputs["http"]["connections"] != 3 {
+		t.Errorf("Expected stats for the http input only, got %v", stats.Inputs)
+	}
+
+	w := httptest.NewRecorder()
+	engine.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	if !strings.Contains(w.Body.String(), `"stats":{"http":{"connections":3,"rejected_requests":1}}`) {
+		t.Errorf("Expected input stats in /status, got %s", w.Body.String())
+	}
+
+	output := &syncBuffer{}
+	log.SetOutput(output)
+	defer log.SetOutput(os.Stderr)
+	logStats(stats)
+	if !strings.Contains(output.String(), "component=stats input=http connections=3 rejected_requests=1") {
+		t.Errorf("Expected input stats in the stats log, got: %s", output.String())
+	}
+}
+
+func TestEngineResilienceStatus(t *testing.T) {
+	engine := NewEngine()
+
+	failing := func(map[string]any) (any, error) { return nil, errors.New("connection refused") }
+	resilient := NewResilientOutputPlugin("es", "elasticsearch", failing, map[string]any{}, ResilientPluginConfig{RetryInterval: time.Millisecond, MaxRetries: 2})
+	defer func() { _ = resilient.Close() }()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "es", Output: resilient}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "plain", Output: newMockOutput()}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	engine.AddInput("file", newMockInput(nil))
+
+	// Wait for the retries to give up so the reported state is stable
+	deadline := time.Now().Add(time.Second)
+	for resilient.GetStats()["current_retries"] != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	engine.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	var status struct {
+		Inputs struct {
+			Resilience map[string]any `json:"resilience"`
+		} `json:"inputs"`
+		Outputs struct {
+			Pipelines []map[string]any `json:"pipelines"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode /status: %v", err)
+	}
+
+	health, ok := status.Outputs.Pipelines[0]["resilience"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected resilience for the resilient output, got %v", status.Outputs.Pipelines[0])
+	}
+	if health["health"] != "unhealthy" || health["current_retries"] != float64(2) || health["last_error"] != "connection refused" {
+		t.Errorf("Expected unhealthy after 2 retries with the last error, got %v", health)
+	}
+	if _, ok := status.Outputs.Pipelines[1]["resilience"]; ok {
+		t.Errorf("Expected no resilience for a plain output, got %v", status.Outputs.Pipelines[1])
+	}
+	if len(status.Inputs.Resilience) != 0 {
+		t.Errorf("Expected no resilience for a plain input, got %v", status.Inputs.Resilience)
+	}
+
+	w = httptest.NewRecorder()
+	engine.handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `"resilience":{"inputs":{},"outputs":{"es":{`) {
+		t.Errorf("Expected resilience in /metrics, got %s", w.Body.String())
+	}
+}
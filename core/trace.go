@@ -0,0 +1,111 @@
+package core
+
+import (
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DefaultTraceField is the metadata key prefix for kept stage timings
+const DefaultTraceField = "trace"
+
+// TraceConfig enables per-log stage timing for a sample of logs. Traced logs
+// record when the engine received them, when they were written to the WAL,
+// when each pipeline's filters finished and when the write returned.
+type TraceConfig struct {
+	SampleEvery int    `yaml:"sample_every,omitempty"` // Trace 1 in N logs (0 = disabled)
+	Keep        bool   `yaml:"keep,omitempty"`         // Also deliver the timings as metadata (default: only logged)
+	Field       string `yaml:"field,omitempty"`        // Metadata key prefix for kept timings (default: "trace")
+}
+
+// Validate validates the TraceConfig
+func (c TraceConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.SampleEvery, validation.Min(0).Error("must be no less than 0")),
+		validation.Field(&c.Field, validation.Length(0, 100).Error("the length must be no more than 100")),
+	)
+}
+
+// field returns the metadata key prefix for kept timings
+func (c TraceConfig) field() string {
+	if c.Field == "" {
+		return DefaultTraceField
+	}
+	return c.Field
+}
+
+// logTrace holds the engine-wide stage times of a traced log. It is not part
+// of the log's data, so outputs never see it.
+type logTrace struct {
+	received  time.Time
+	persisted time.Time // Zero without persistence
+}
+
+// SetTraceConfig configures stage timing; call it before Start
+func (e *Engine) SetTraceConfig(config TraceConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	e.trace = config
+	return nil
+}
+
+// startTrace marks one in SampleEvery logs as traced, starting at the time it was received
+func (e *Engine) startTrace(logEntry *Log) {
+	every := e.trace.SampleEvery
+	if every <= 0 || e.traceSeq.Add(1)%uint64(every) != 0 {
+		return
+	}
+	logEntry.trace = &logTrace{received: time.Now()}
+}
+
+// traceTime returns the current time for a traced log, or the zero time
+func traceTime(logEntry *Log) time.Time {
+	if logEntry.trace == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// annotateTrace adds the stage timings known before the write to a traced log
+// when trace.keep is set, copying the log unless the pipeline already owns it
+func (e *Engine) annotateTrace(logEntry *Log, owned bool, filtered time.Time) *Log {
+	trace := logEntry.trace
+	if trace == nil || !e.trace.Keep {
+		return logEntry
+	}
+	if !owned {
+		logEntry = logEntry.Clone()
+	}
+	if logEntry.Metadata == nil {
+		logEntry.Metadata = make(map[string]string)
+	}
+
+	prefix := e.trace.field()
+	logEntry.Metadata[prefix+".received"] = trace.received.Format(time.RFC3339Nano)
+	if !trace.persisted.IsZero() {
+		logEntry.Metadata[prefix+".persisted_us"] = micros(trace.persisted.Sub(trace.received))
+	}
+	logEntry.Metadata[prefix+".filtered_us"] = micros(filtered.Sub(trace.received))
+	return logEntry
+}
+
+// logTraceStages logs the stage timings of a traced log for one pipeline, relative to when it was received
+func logTraceStages(pipeline string, logEntry *Log, filtered, written time.Time) {
+	trace := logEntry.trace
+	fields := []any{"pipeline", pipeline, "source", logEntry.Source}
+	if !trace.persisted.IsZero() {
+		fields = append(fields, "persisted", trace.persisted.Sub(trace.received))
+	}
+	fields = append(fields,
+		"filtered", filtered.Sub(trace.received),
+		"written", written.Sub(trace.received),
+	)
+	traceLog.Print("stages", fields...)
+}
+
+// micros formats a duration as whole microseconds
+func micros(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10)
+}
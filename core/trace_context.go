@@ -0,0 +1,93 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+)
+
+// W3C Trace Context (https://www.w3.org/TR/trace-context/) field sizes in hex digits
+const (
+	traceIDLength     = 32
+	spanIDLength      = 16
+	traceparentLength = 2 + 1 + traceIDLength + 1 + spanIDLength + 1 + 2 // "00-<trace-id>-<parent-id>-01"
+)
+
+// ParseTraceparent parses a W3C traceparent header value
+// ("version-trace_id-parent_id-trace_flags") following the rules for receivers:
+// every field is lowercase hex, the trace and parent IDs are not all zeros and
+// version ff is invalid. A version 00 value is exactly 55 characters; a later
+// version may append fields after a dash, which are ignored. ok is false when the
+// value must be discarded.
+func ParseTraceparent(value string) (traceID, spanID string, flags uint8, ok bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < traceparentLength {
+		return "", "", 0, false
+	}
+
+	version := value[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return "", "", 0, false
+	}
+	if version == "00" && len(value) != traceparentLength {
+		return "", "", 0, false
+	}
+	if len(value) > traceparentLength && value[traceparentLength] != '-' {
+		return "", "", 0, false
+	}
+	if value[2] != '-' || value[3+traceIDLength] != '-' || value[4+traceIDLength+spanIDLength] != '-' {
+		return "", "", 0, false
+	}
+
+	traceID = value[3 : 3+traceIDLength]
+	spanID = value[4+traceIDLength : 4+traceIDLength+spanIDLength]
+	flagsHex := value[5+traceIDLength+spanIDLength : traceparentLength]
+	if !isLowerHex(traceID) || isZeroID(traceID) || !isLowerHex(spanID) || isZeroID(spanID) || !isLowerHex(flagsHex) {
+		return "", "", 0, false
+	}
+
+	parsed, err := strconv.ParseUint(flagsHex, 16, 8)
+	if err != nil {
+		return "", "", 0, false
+	}
+	return traceID, spanID, uint8(parsed), true
+}
+
+// NormalizeTraceID returns a trace ID as 32 lowercase hex digits. It accepts
+// either case and 64-bit IDs (16 digits), which are left-padded with zeros as the
+// W3C specification asks. ok is false for anything else, including all zeros.
+func NormalizeTraceID(value string) (string, bool) {
+	id := strings.ToLower(strings.TrimSpace(value))
+	if len(id) == spanIDLength {
+		id = strings.Repeat("0", traceIDLength-spanIDLength) + id
+	}
+	if len(id) != traceIDLength || !isLowerHex(id) || isZeroID(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// NormalizeSpanID returns a span ID as 16 lowercase hex digits. ok is false for
+// any other length, non-hex characters or all zeros.
+func NormalizeSpanID(value string) (string, bool) {
+	id := strings.ToLower(strings.TrimSpace(value))
+	if len(id) != spanIDLength || !isLowerHex(id) || isZeroID(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// isLowerHex reports whether s is made of lowercase hex digits only
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZeroID reports whether an ID is all zeros, which the specification reserves as invalid
+func isZeroID(id string) bool {
+	return strings.Trim(id, "0") == ""
+}
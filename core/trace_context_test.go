@@ -0,0 +1,103 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		traceID string
+		spanID  string
+		flags   uint8
+		ok      bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 1, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 0, true},
+		{"surrounding whitespace", " 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 ", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 1, true},
+		{"future version with extra field", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-09-what-the-future-will-be", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 9, true},
+		{"future version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 1, true},
+
+		{"empty", "", "", "", 0, false},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", "", "", 0, false},
+		{"future version without dash", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01extra", "", "", 0, false},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", 0, false},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", "", "", 0, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", "", 0, false},
+		{"zero parent id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "", "", 0, false},
+		{"short trace id", "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", "", "", 0, false},
+		{"wrong separator", "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", "", "", 0, false},
+		{"non-hex flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x", "", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		traceID, spanID, flags, ok := ParseTraceparent(tt.value)
+		if ok != tt.ok || traceID != tt.traceID || spanID != tt.spanID || flags != tt.flags {
+			t.Errorf("%s: expected %q %q %d %v, got %q %q %d %v", tt.name, tt.traceID, tt.spanID, tt.flags, tt.ok, traceID, spanID, flags, ok)
+		}
+	}
+}
+
+func TestNormalizeTraceAndSpanID(t *testing.T) {
+	traceIDs := []struct {
+		value    string
+		expected string
+	}{
+		{"4bf92f3577b34da6a3ce929d0e0e4736", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"4BF92F3577B34DA6A3CE929D0E0E4736", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"a3ce929d0e0e4736", "0000000000000000a3ce929d0e0e4736"},
+		{"00000000000000000000000000000000", ""},
+		{"4bf92f3577b34da6", "00000000000000004bf92f3577b34da6"},
+		{"4bf92f35-77b3-4da6-a3ce-929d0e0e4736", ""},
+		{"xyz", ""},
+	}
+	for _, tt := range traceIDs {
+		id, ok := NormalizeTraceID(tt.value)
+		if id != tt.expected || ok != (tt.expected != "") {
+			t.Errorf("trace ID %q: expected %q, got %q (%v)", tt.value, tt.expected, id, ok)
+		}
+	}
+
+	spanIDs := []struct {
+		value    string
+		expected string
+	}{
+		{"00f067aa0ba902b7", "00f067aa0ba902b7"},
+		{"00F067AA0BA902B7", "00f067aa0ba902b7"},
+		{"0000000000000000", ""},
+		{"f067aa0ba902b7", ""},
+	}
+	for _, tt := range spanIDs {
+		id, ok := NormalizeSpanID(tt.value)
+		if id != tt.expected || ok != (tt.expected != "") {
+			t.Errorf("span ID %q: expected %q, got %q (%v)", tt.value, tt.expected, id, ok)
+		}
+	}
+}
+
+func TestLogEncoderTraceContext(t *testing.T) {
+	encoder, err := NewLogEncoder(FieldStyle{}, "timestamp")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	logEntry := &Log{
+		Timestamp:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:      "error",
+		Message:    "payment failed",
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		TraceFlags: 1,
+		Metadata:   map[string]string{"service": "api"},
+	}
+
+	data, err := encoder.Marshal(logEntry)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"timestamp":"2025-01-02T03:04:05Z","level":"error","message":"payment failed","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":1,"metadata":{"service":"api"}}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineTraceSampling(t *testing.T) {
+	output := &syncBuffer{}
+	log.SetOutput(output)
+	defer log.SetOutput(os.Stderr)
+
+	engine := NewEngine()
+	if err := engine.SetTraceConfig(TraceConfig{SampleEvery: 2}); err != nil {
+		t.Fatalf("Failed to configure trace: %v", err)
+	}
+
+	var logs []*Log
+	for i := 0; i < 4; i++ {
+		logs = append(logs, NewLog("info", "traced"))
+	}
+	engine.AddInput("test-input", newMockInput(logs))
+	out := newMockOutput()
+	if err := engine.AddOutputPipeline(&OutputPipeline{Name: "out", Output: out}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	traced := 0
+	for _, line := range strings.Split(output.String(), "\n") {
+		if strings.Contains(line, "component=trace pipeline=out ") && strings.Contains(line, "written=") {
+			traced++
+		}
+	}
+	if traced != 2 {
+		t.Errorf("Expected 2 of 4 logs traced, got %d:\n%s", traced, output.String())
+	}
+
+	// Without keep, timings are only logged
+	for _, delivered := range out.getLogs() {
+		for key := range delivered.Metadata {
+			if strings.HasPrefix(key, DefaultTraceField+".") {
+				t.Errorf("Expected no trace metadata without keep, got %v", delivered.Metadata)
+			}
+		}
+	}
+}
+
+func TestEngineTraceKeep(t *testing.T) {
+	engine := NewEngine()
+	if err := engine.SetTraceConfig(TraceConfig{SampleEvery: 1, Keep: true, Field: "timing"}); err != nil {
+		t.Fatalf("Failed to configure trace: %v", err)
+	}
+
+	original := NewLog("error", "kept")
+	engine.AddInput("test-input", newMockInput([]*Log{original}))
+	first := newMockOutput()
+	second := newMockOutput()
+	for _, pipeline := range []*OutputPipeline{{Name: "first", Output: first}, {Name: "second", Output: second}} {
+		if err := engine.AddOutputPipeline(pipeline); err != nil {
+			t.Fatalf("Failed to add output pipeline: %v", err)
+		}
+	}
+
+	engine.Start()
+	time.Sleep(100 * time.Millisecond)
+	engine.Stop()
+
+	for name, output := range map[string]*mockOutput{"first": first, "second": second} {
+		logs := output.getLogs()
+		if len(logs) != 1 {
+			t.Fatalf("Expected 1 log for %s, got %d", name, len(logs))
+		}
+		if _, err := time.Parse(time.RFC3339Nano, logs[0].Metadata["timing.received"]); err != nil {
+			t.Errorf("Expected timing.received for %s, got %v", name, logs[0].Metadata)
+		}
+		if logs[0].Metadata["timing.filtered_us"] == "" {
+			t.Errorf("Expected timing.filtered_us for %s, got %v", name, logs[0].Metadata)
+		}
+		if logs[0] == original {
+			t.Errorf("Expected %s to receive a copy of the log", name)
+		}
+	}
+	if len(original.Metadata) != 0 {
+		t.Errorf("Expected the shared log not to be annotated, got %v", original.Metadata)
+	}
+}
+
+func TestTraceConfigValidation(t *testing.T) {
+	if err := NewEngine().SetTraceConfig(TraceConfig{SampleEvery: -1}); err == nil {
+		t.Error("Expected an error for a negative sample_every")
+	}
+	if err := (TraceConfig{}).Validate(); err != nil {
+		t.Errorf("Expected tracing to be disabled by default without error, got %v", err)
+	}
+}
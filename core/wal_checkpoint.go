@@ -0,0 +1,234 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Recovery checkpointing
+//
+// Without a checkpoint every start replays the whole WAL, so an engine that
+// crash-loops delivers the same logs again on every restart. The checkpoint
+// records the highest WALEntry.Sequence that recovery has handed to the engine;
+// later starts skip entries up to it and resume where the last recovery stopped.
+// Recovered logs are not written to the WAL again, so each entry is replayed by
+// at most one successful recovery.
+
+const (
+	// checkpointFile holds the recovery checkpoint inside the WAL directory
+	checkpointFile = "recovery.checkpoint"
+
+	// DefaultRecoveryCheckpointEvery is how many recovered logs are handed to the
+	// engine between checkpoint writes when recovery_checkpoint_every is not set
+	DefaultRecoveryCheckpointEvery = 1000
+
+	// walTailSize is how much of a WAL file is read to find its last sequence
+	walTailSize = 64 * 1024
+)
+
+// recoveryCheckpoint is the content of the checkpoint file
+type recoveryCheckpoint struct {
+	Sequence  uint64    `json:"seq"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// walFile is a WAL file present at startup, with the last sequence it contains
+type walFile struct {
+	path    string
+	lastSeq uint64
+}
+
+// readCheckpoint returns the sequence stored in the checkpoint file, or 0 when there
+// is none. A corrupt checkpoint is ignored, which replays the WAL rather than losing it.
+func readCheckpoint(dir string) uint64 {
+	data, err := os.ReadFile(filepath.Join(dir, checkpointFile)) // #nosec G304 - fixed name inside the WAL directory
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			persistenceLog.Printf("Error reading recovery checkpoint: %v", err)
+		}
+		return 0
+	}
+	var checkpoint recoveryCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		persistenceLog.Printf("Ignoring corrupt recovery checkpoint: %v", err)
+		return 0
+	}
+	return checkpoint.Sequence
+}
+
+// writeCheckpoint stores the checkpoint so that it survives a crash: the new content is
+// written to a temporary file and fsynced, renamed over the old checkpoint, and the
+// directory is fsynced so the rename itself is durable
+func writeCheckpoint(dir string, seq uint64) error {
+	data, err := json.Marshal(recoveryCheckpoint{Sequence: seq, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, checkpointFile)
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600) // #nosec G304 - fixed name inside the WAL directory
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+
+	// Not every platform can sync a directory; the rename is still atomic there
+	if d, err := os.Open(dir); err == nil { // #nosec G304 - the configured WAL directory
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// scanWALFiles lists the WAL files in dir with their last sequence, oldest first
+func scanWALFiles(dir string) ([]walFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]walFile, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, walFile{path: path, lastSeq: lastSequence(path)})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].lastSeq < files[j].lastSeq
+	})
+	return files, nil
+}
+
+// lastSequence returns the sequence of the last readable entry of a WAL file (0 if
+// none). Sequences only grow within a file, so only its tail is read, unless a
+// single entry is larger than the tail.
+func lastSequence(path string) uint64 {
+	file, err := os.Open(path) // #nosec G304 - WAL file listed from the configured directory
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+	offset := max(info.Size()-walTailSize, 0)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0
+	}
+	tail, err := io.ReadAll(file)
+	if err != nil {
+		return 0
+	}
+
+	lines := bytes.Split(tail, []byte("\n"))
+	if offset > 0 {
+		lines = lines[1:] // The first line may be cut
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		var entry WALEntry
+		if json.Unmarshal(lines[i], &entry) == nil && entry.Sequence > 0 {
+			return entry.Sequence
+		}
+	}
+	if offset == 0 {
+		return 0
+	}
+
+	// No complete entry in the tail: scan the whole file
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0
+	}
+	var last uint64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var entry WALEntry
+		if json.Unmarshal(line, &entry) == nil && entry.Sequence > 0 {
+			last = entry.Sequence
+		}
+		if err != nil {
+			return last
+		}
+	}
+}
+
+// markRecovered records that the recovered log with WAL sequence seq was handed to
+// the engine and writes the checkpoint every recovery_checkpoint_every logs
+func (p *Persistence) markRecovered(seq uint64) {
+	if !p.config.Enabled || seq == 0 {
+		return
+	}
+
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+
+	if seq > p.recoveredSeq {
+		p.recoveredSeq = seq
+	}
+	p.uncheckpointed++
+	if p.uncheckpointed >= p.config.recoveryCheckpointEvery() {
+		p.saveCheckpointLocked()
+	}
+}
+
+// finishRecovery writes the checkpoint for every recovered log handed to the engine
+func (p *Persistence) finishRecovery() {
+	if !p.config.Enabled {
+		return
+	}
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+	if p.uncheckpointed > 0 {
+		p.saveCheckpointLocked()
+	}
+}
+
+// saveCheckpointLocked writes the recovery progress (checkpointMu must be held)
+func (p *Persistence) saveCheckpointLocked() {
+	if err := writeCheckpoint(p.config.Dir, p.recoveredSeq); err != nil {
+		persistenceLog.Printf("Error writing recovery checkpoint: %v", err)
+		return
+	}
+	p.checkpoint.Store(p.recoveredSeq)
+	p.uncheckpointed = 0
+}
+
+// pendingRecovery reports whether a WAL file still holds entries that recovery has
+// not checkpointed, in which case retention cleanup must keep it
+func (p *Persistence) pendingRecovery(path string) bool {
+	for _, file := range p.recoveryFiles {
+		if file.path == path {
+			return file.lastSeq > p.checkpoint.Load()
+		}
+	}
+	return false
+}
+
+// recoveryCheckpointEvery returns how many recovered logs are handed to the engine between checkpoints
+func (p PersistenceConfig) recoveryCheckpointEvery() int {
+	if p.RecoveryCheckpointEvery > 0 {
+		return p.RecoveryCheckpointEvery
+	}
+	return DefaultRecoveryCheckpointEvery
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkpointTestConfig(dir string) PersistenceConfig {
+	return PersistenceConfig{
+		Enabled:                 true,
+		Dir:                     dir,
+		MaxFileSize:             1024 * 1024,
+		BufferSize:              10,
+		FlushInterval:           1,
+		RetentionHours:          1,
+		RecoveryCheckpointEvery: 2,
+	}
+}
+
+// writeWAL persists the messages with a fresh persistence handler and closes it
+func writeWAL(t *testing.T, config PersistenceConfig, messages ...string) {
+	t.Helper()
+	p, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	for _, message := range messages {
+		if err := p.Persist(NewLog("info", message)); err != nil {
+			t.Fatalf("Failed to persist log: %v", err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Failed to close persistence: %v", err)
+	}
+}
+
+// recoverMessages starts recovery and marks the first n logs as handed to the engine
+func recoverMessages(t *testing.T, p *Persistence, n int) []string {
+	t.Helper()
+	recoveryCh, err := p.Recover()
+	if err != nil {
+		t.Fatalf("Failed to start recovery: %v", err)
+	}
+	var messages []string
+	for log := range recoveryCh {
+		if len(messages) < n {
+			p.markRecovered(log.walSeq)
+		}
+		messages = append(messages, log.Message)
+	}
+	return messages
+}
+
+func TestPersistence_RecoveryCheckpoint(t *testing.T) {
+	config := checkpointTestConfig(t.TempDir())
+	writeWAL(t, config, "m1", "m2", "m3", "m4", "m5")
+
+	// First restart: crashes after handing 3 of the 5 logs to the engine
+	p2, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	if got := recoverMessages(t, p2, 3); len(got) != 5 {
+		t.Fatalf("Expected 5 recovered logs, got %v", got)
+	}
+	p2.finishRecovery()
+	if err := p2.Persist(NewLog("info", "new")); err != nil {
+		t.Fatalf("Failed to persist log: %v", err)
+	}
+	if err := p2.Close(); err != nil {
+		t.Fatalf("Failed to close persistence: %v", err)
+	}
+
+	// Second restart resumes after the checkpoint, including the log written by the first restart
+	p3, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	defer func() { _ = p3.Close() }()
+
+	expected := []string{"m4", "m5", "new"}
+	if got := recoverMessages(t, p3, 0); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v after the checkpoint, got %v", expected, got)
+	}
+}
+
+func TestPersistence_RecoveryCheckpointPeriodic(t *testing.T) {
+	config := checkpointTestConfig(t.TempDir())
+	writeWAL(t, config, "m1", "m2", "m3")
+
+	p, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	// With recovery_checkpoint_every: 2 the checkpoint is written after the second log,
+	// before recovery finishes
+	recoverMessages(t, p, 3)
+	if seq := readCheckpoint(config.Dir); seq != p.recoveryFiles[0].lastSeq-1 {
+		t.Errorf("Expected a checkpoint at sequence %d, got %d", p.recoveryFiles[0].lastSeq-1, seq)
+	}
+	p.finishRecovery()
+	if seq := readCheckpoint(config.Dir); seq != p.recoveryFiles[0].lastSeq {
+		t.Errorf("Expected a checkpoint at sequence %d, got %d", p.recoveryFiles[0].lastSeq, seq)
+	}
+}
+
+func TestCheckpointFile(t *testing.T) {
+	dir := t.TempDir()
+
+	if seq := readCheckpoint(dir); seq != 0 {
+		t.Errorf("Expected 0 without a checkpoint, got %d", seq)
+	}
+	if err := writeCheckpoint(dir, 42); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+	if seq := readCheckpoint(dir); seq != 42 {
+		t.Errorf("Expected 42, got %d", seq)
+	}
+	if _, err := os.Stat(filepath.Join(dir, checkpointFile+".tmp")); !os.IsNotExist(err) {
+		t.Error("Expected the temporary file to be renamed")
+	}
+
+	// A corrupt checkpoint replays the whole WAL instead of failing
+	if err := os.WriteFile(filepath.Join(dir, checkpointFile), []byte("{"), 0600); err != nil {
+		t.Fatalf("Failed to corrupt checkpoint: %v", err)
+	}
+	if seq := readCheckpoint(dir); seq != 0 {
+		t.Errorf("Expected 0 for a corrupt checkpoint, got %d", seq)
+	}
+}
+
+func TestLastSequence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wal-20200101-000000-1.log")
+
+	// Entries larger than the tail that is read, followed by a torn write
+	var content strings.Builder
+	for seq := uint64(1); seq <= 3; seq++ {
+		data, _ := json.Marshal(WALEntry{Sequence: seq, Log: NewLog("info", strings.Repeat("x", walTailSize))})
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+	content.WriteString(`{"seq":4,"log":{"mess`)
+	if err := os.WriteFile(path, []byte(content.String()), 0600); err != nil {
+		t.Fatalf("Failed to write WAL file: %v", err)
+	}
+
+	if seq := lastSequence(path); seq != 3 {
+		t.Errorf("Expected last sequence 3, got %d", seq)
+	}
+}
+
+func TestPersistence_CleanupKeepsPendingRecovery(t *testing.T) {
+	config := checkpointTestConfig(t.TempDir())
+	writeWAL(t, config, "m1", "m2")
+
+	old := time.Now().Add(-2 * time.Hour)
+	files, _ := filepath.Glob(filepath.Join(config.Dir, "wal-*.log"))
+	for _, file := range files {
+		if err := os.Chtimes(file, old, old); err != nil {
+			t.Fatalf("Failed to age WAL file: %v", err)
+		}
+	}
+
+	p, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	// Past retention, but not recovered yet
+	p.cleanup()
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			t.Fatalf("Expected %s to be kept until recovered, got %v", filepath.Base(file), err)
+		}
+	}
+
+	recoverMessages(t, p, 2)
+	p.finishRecovery()
+	p.cleanup()
+	for _, file := range files {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be cleaned up once checkpointed", filepath.Base(file))
+		}
+	}
+}
+
+func TestEngineRecoveredLogsNotPersistedAgain(t *testing.T) {
+	config := checkpointTestConfig(t.TempDir())
+	writeWAL(t, config, "m1", "m2")
+
+	engine := NewEngine()
+	if err := engine.SetPersistence(config); err != nil {
+		t.Fatalf("Failed to set persistence: %v", err)
+	}
+	output := &mockOutput{}
+	engine.AddOutput(output)
+	engine.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(output.getLogs()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	engine.Stop()
+
+	if got := len(output.getLogs()); got != 2 {
+		t.Fatalf("Expected 2 recovered logs delivered, got %d", got)
+	}
+
+	// The next start has nothing left to replay
+	p, err := NewPersistence(config)
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	if got := recoverMessages(t, p, 0); len(got) != 0 {
+		t.Errorf("Expected no logs to replay again, got %v", got)
+	}
+}
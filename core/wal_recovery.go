@@ -0,0 +1,217 @@
+package core
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Parallel recovery
+//
+// WAL files are decoded by up to recovery_concurrency readers at once and their
+// entries are merged by WALEntry.Sequence before being handed to the engine, so
+// recovered logs keep the order in which they were written whatever the file
+// names. Each file streams its entries in chunks of recoveryChunkSize through a
+// channel holding one chunk, so the merge keeps at most a few chunks per file in
+// memory instead of the whole WAL.
+
+const (
+	// DefaultRecoveryConcurrency is how many WAL files are decoded at once when
+	// recovery_concurrency is not set
+	DefaultRecoveryConcurrency = 4
+
+	// recoveryChunkSize is how many entries a reader decodes before handing them to the merge
+	recoveryChunkSize = 256
+)
+
+// walChunk is a batch of decoded entries from one WAL file. err is set on the
+// last chunk of a file that could not be read to the end.
+type walChunk struct {
+	entries []WALEntry
+	err     error
+}
+
+// walStream is the merge's view of one WAL file being recovered
+type walStream struct {
+	index    int // Position in the recovery file list, breaks sequence ties
+	filename string
+	chunks   <-chan walChunk
+	entries  []WALEntry // Current chunk, entries[0] is the head
+}
+
+// walMergeHeap orders streams by the sequence of their head entry
+type walMergeHeap []*walStream
+
+func (h walMergeHeap) Len() int { return len(h) }
+
+func (h walMergeHeap) Less(i, j int) bool {
+	a, b := h[i].entries[0].Sequence, h[j].entries[0].Sequence
+	if a != b {
+		return a < b
+	}
+	return h[i].index < h[j].index
+}
+
+func (h walMergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *walMergeHeap) Push(x any) { *h = append(*h, x.(*walStream)) }
+
+func (h *walMergeHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
+
+// recoverFiles replays the entries after the checkpoint from the given WAL files in
+// sequence order and returns how many logs were handed to the recovery queue
+func (p *Persistence) recoverFiles(files []string, checkpoint uint64) int {
+	slots := make(chan struct{}, min(p.config.recoveryConcurrency(), len(files)))
+	streams := make([]*walStream, 0, len(files))
+	for i, filename := range files {
+		chunks := make(chan walChunk, 1)
+		streams = append(streams, &walStream{index: i, filename: filename, chunks: chunks})
+		p.wg.Add(1)
+		go p.readWALFile(filename, checkpoint, slots, chunks)
+	}
+
+	h := make(walMergeHeap, 0, len(streams))
+	for _, s := range streams {
+		if p.nextChunk(s) {
+			h = append(h, s)
+		}
+	}
+	heap.Init(&h)
+
+	recovered := 0
+	for h.Len() > 0 {
+		s := h[0]
+		entry := s.entries[0]
+		s.entries = s.entries[1:]
+
+		select {
+		case p.recoveryQueue <- entry.Log:
+			recovered++
+		case <-p.stopCh:
+			return recovered
+		}
+
+		if len(s.entries) > 0 || p.nextChunk(s) {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return recovered
+}
+
+// nextChunk loads the next non-empty chunk of a stream and reports whether there was one
+func (p *Persistence) nextChunk(s *walStream) bool {
+	for {
+		var chunk walChunk
+		var ok bool
+		select {
+		case chunk, ok = <-s.chunks:
+		case <-p.stopCh:
+			return false
+		}
+		if !ok {
+			return false
+		}
+		if chunk.err != nil {
+			persistenceLog.Printf("Error recovering from %s: %v", s.filename, chunk.err)
+		}
+		if len(chunk.entries) > 0 {
+			s.entries = chunk.entries
+			return true
+		}
+		if chunk.err != nil {
+			return false
+		}
+	}
+}
+
+// readWALFile decodes the entries of a WAL file written after the checkpoint and
+// sends them in chunks. A slot is held only while a chunk is decoded, never while
+// sending it, so a reader waiting on the merge does not keep the other files from
+// being read.
+func (p *Persistence) readWALFile(filename string, checkpoint uint64, slots chan struct{}, chunks chan<- walChunk) {
+	defer p.wg.Done()
+	defer close(chunks)
+
+	send := func(chunk walChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-p.stopCh:
+			return false
+		}
+	}
+
+	// Validate that the file is within our configured directory
+	if err := validateFileInDirectory(filename, p.config.Dir); err != nil {
+		send(walChunk{err: fmt.Errorf("invalid WAL file path: %w", err)})
+		return
+	}
+
+	file, err := os.Open(filename) // #nosec G304 - path validated by validateFileInDirectory above
+	if err != nil {
+		send(walChunk{err: fmt.Errorf("failed to open WAL file: %w", err)})
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-p.stopCh:
+			return
+		}
+		chunk, done := decodeWALChunk(reader, checkpoint)
+		<-slots
+
+		if !send(chunk) || done {
+			return
+		}
+	}
+}
+
+// decodeWALChunk decodes up to recoveryChunkSize recoverable entries and reports
+// whether the end of the file (or a read error) was reached
+func decodeWALChunk(reader *bufio.Reader, checkpoint uint64) (walChunk, bool) {
+	chunk := walChunk{entries: make([]WALEntry, 0, recoveryChunkSize)}
+	for len(chunk.entries) < recoveryChunkSize {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				chunk.err = fmt.Errorf("error reading WAL file: %w", err)
+			}
+			return chunk, true
+		}
+
+		var entry WALEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			persistenceLog.Printf("Error unmarshaling WAL entry: %v", err)
+			continue
+		}
+
+		if entry.Log == nil || (entry.Sequence > 0 && entry.Sequence <= checkpoint) {
+			continue
+		}
+		entry.Log.walSeq = entry.Sequence
+		chunk.entries = append(chunk.entries, entry)
+	}
+	return chunk, false
+}
+
+// recoveryConcurrency returns how many WAL files are decoded at once during recovery
+func (p PersistenceConfig) recoveryConcurrency() int {
+	if p.RecoveryConcurrency > 0 {
+		return p.RecoveryConcurrency
+	}
+	return DefaultRecoveryConcurrency
+}
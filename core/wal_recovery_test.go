@@ -0,0 +1,118 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeWALFile writes entries with the given sequences to a WAL file in dir
+func writeWALFile(t *testing.T, dir, name string, seqs ...uint64) {
+	t.Helper()
+	var data []byte
+	for _, seq := range seqs {
+		line, err := json.Marshal(WALEntry{Sequence: seq, Log: NewLog("info", fmt.Sprintf("log %d", seq))})
+		if err != nil {
+			t.Fatalf("Failed to marshal entry: %v", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		t.Fatalf("Failed to write WAL file: %v", err)
+	}
+}
+
+func TestPersistence_RecoveryMergesBySequence(t *testing.T) {
+	for _, concurrency := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			dir := t.TempDir()
+
+			// File names sort in the opposite order of their sequences, and the two
+			// later files interleave across several chunks
+			var odd, even []uint64
+			for seq := uint64(101); seq <= 100+3*recoveryChunkSize; seq++ {
+				if seq%2 == 1 {
+					odd = append(odd, seq)
+				} else {
+					even = append(even, seq)
+				}
+			}
+			writeWALFile(t, dir, "wal-a.log", even...)
+			writeWALFile(t, dir, "wal-b.log", odd...)
+			writeWALFile(t, dir, "wal-c.log", 1, 2, 3)
+
+			config := checkpointTestConfig(dir)
+			config.RecoveryConcurrency = concurrency
+			p, err := NewPersistence(config)
+			if err != nil {
+				t.Fatalf("Failed to create persistence: %v", err)
+			}
+			defer func() { _ = p.Close() }()
+
+			recoveryCh, err := p.Recover()
+			if err != nil {
+				t.Fatalf("Failed to start recovery: %v", err)
+			}
+			var seqs []uint64
+			for log := range recoveryCh {
+				seqs = append(seqs, log.walSeq)
+			}
+
+			if want := 3 + 3*recoveryChunkSize; len(seqs) != want {
+				t.Fatalf("Expected %d recovered logs, got %d", want, len(seqs))
+			}
+			for i := 1; i < len(seqs); i++ {
+				if seqs[i] <= seqs[i-1] {
+					t.Fatalf("Recovered out of order at %d: %d after %d", i, seqs[i], seqs[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestPersistence_RecoveryMergeSkipsCheckpointed(t *testing.T) {
+	dir := t.TempDir()
+	writeWALFile(t, dir, "wal-a.log", 2, 4, 6)
+	writeWALFile(t, dir, "wal-b.log", 1, 3, 5)
+	if err := writeCheckpoint(dir, 3); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+
+	p, err := NewPersistence(checkpointTestConfig(dir))
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	messages := recoverMessages(t, p, 0)
+	want := []string{"log 4", "log 5", "log 6"}
+	if fmt.Sprint(messages) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+}
+
+func TestPersistence_RecoveryStopsOnClose(t *testing.T) {
+	dir := t.TempDir()
+	var seqs []uint64
+	for seq := uint64(1); seq <= 4*recoveryChunkSize; seq++ {
+		seqs = append(seqs, seq)
+	}
+	writeWALFile(t, dir, "wal-a.log", seqs...)
+
+	p, err := NewPersistence(checkpointTestConfig(dir))
+	if err != nil {
+		t.Fatalf("Failed to create persistence: %v", err)
+	}
+	recoveryCh, err := p.Recover()
+	if err != nil {
+		t.Fatalf("Failed to start recovery: %v", err)
+	}
+	<-recoveryCh
+
+	// Nobody drains the recovery queue further: Close must still stop the readers
+	if err := p.Close(); err != nil {
+		t.Fatalf("Failed to close persistence: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Middleware represents authentication middleware
@@ -71,12 +72,34 @@ func (m *Middleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 func (m *Middleware) hasEndpointPermission(key *APIKey, path, method string) bool {
 	// Define endpoint permissions
 	endpointPerms := map[string][]string{
-		"/health":  {"health"},
-		"/metrics": {"metrics", "health"}, // metrics permission includes health
-		"/status":  {"admin"},             // status requires admin permission
+		"/health":        {"health"},
+		"/metrics":       {"metrics", "health"}, // metrics permission includes health
+		"/status":        {"admin"},             // status requires admin permission
+		"/metrics/reset": {"admin"},             // resetting counters requires admin permission
+		"/inject":        {"admin"},             // injecting synthetic logs requires admin permission
+		"/ready":         {"health"},            // readiness is a health check
+		"/pause":         {"admin"},             // pausing processing requires admin permission
+		"/resume":        {"admin"},             // resuming processing requires admin permission
+		"/reloads":       {"admin"},             // the reload audit trail requires admin permission
+		"/events":        {"metrics", "admin"},  // plugin lifecycle events are monitoring data
+		"/pipelines":     {"admin"},             // adding pipelines requires admin permission
+	}
+
+	// Define permissions for endpoints addressed by path prefix
+	prefixPerms := map[string][]string{
+		"/pipelines/": {"admin"}, // pipeline management requires admin permission
+		"/inputs/":    {"admin"}, // replacing inputs requires admin permission
 	}
 
 	requiredPerms, exists := endpointPerms[path]
+	if !exists {
+		for prefix, perms := range prefixPerms {
+			if strings.HasPrefix(path, prefix) {
+				requiredPerms, exists = perms, true
+				break
+			}
+		}
+	}
 	if !exists {
 		// Unknown endpoint, deny access
 		return false
@@ -0,0 +1,143 @@
+// Package compress decompresses payloads sent by network inputs. Every decoder
+// is bounded by a maximum decompressed size, so a small compressed payload
+// cannot expand into gigabytes of memory (a "decompression bomb").
+//
+// gzip is always available. zstd is compiled in by default and can be left out
+// of the binary with the nozstd build tag.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Supported encodings, named as in the HTTP Content-Encoding header
+const (
+	Identity = "identity" // Not compressed
+	Gzip     = "gzip"
+	Zstd     = "zstd"
+)
+
+// DefaultMaxSize is the decompressed size limit used when none is configured (10MB)
+const DefaultMaxSize = 10 << 20
+
+var (
+	// ErrTooLarge is returned when a payload decompresses to more than the size limit
+	ErrTooLarge = errors.New("decompressed payload exceeds the size limit")
+
+	// ErrUnsupported is returned for unknown encodings, and for zstd in nozstd builds
+	ErrUnsupported = errors.New("unsupported content encoding")
+)
+
+// Decompress decodes data compressed with encoding ("", "identity", "gzip",
+// "x-gzip" or "zstd", case-insensitive). The result may be at most maxSize
+// bytes (DefaultMaxSize when maxSize <= 0); uncompressed data is returned as-is.
+func Decompress(encoding string, data []byte, maxSize int64) ([]byte, error) {
+	if normalize(encoding) == "" || normalize(encoding) == Identity {
+		return data, nil
+	}
+	reader, err := NewReader(encoding, bytes.NewReader(data), maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NewReader returns a reader decoding r as compressed with encoding, for
+// payloads processed as they arrive instead of in one piece. Reading past
+// maxSize decompressed bytes (DefaultMaxSize when maxSize <= 0) fails with
+// ErrTooLarge. Uncompressed data is returned as-is, without a limit.
+func NewReader(encoding string, r io.Reader, maxSize int64) (io.ReadCloser, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+	switch normalize(encoding) {
+	case "", Identity:
+		return io.NopCloser(r), nil
+	case Gzip:
+		reader, err = gzip.NewReader(r)
+	case Zstd:
+		reader, err = newZstdReader(r, maxSize)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", normalize(encoding), err)
+	}
+	return &limitedReader{reader: reader, encoding: normalize(encoding), maxSize: maxSize, remaining: maxSize}, nil
+}
+
+// limitedReader fails with ErrTooLarge once more than maxSize bytes were decoded
+type limitedReader struct {
+	reader    io.ReadCloser
+	encoding  string
+	maxSize   int64
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, fmt.Errorf("%w (%d bytes)", ErrTooLarge, l.maxSize)
+	}
+
+	// Read one byte past the limit to tell "exactly maxSize" from "too large"
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.reader.Read(p)
+	if zstdTooLarge(err) {
+		l.remaining = -1
+		return 0, fmt.Errorf("%w (%d bytes)", ErrTooLarge, l.maxSize)
+	}
+	if int64(n) > l.remaining {
+		// The bytes within the limit are still returned
+		n = int(l.remaining)
+		l.remaining = -1
+		return n, fmt.Errorf("%w (%d bytes)", ErrTooLarge, l.maxSize)
+	}
+	l.remaining -= int64(n)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("invalid %s payload: %w", l.encoding, err)
+	}
+	return n, err
+}
+
+func (l *limitedReader) Close() error {
+	return l.reader.Close()
+}
+
+// Supported reports whether an encoding can be decompressed by this build
+func Supported(encoding string) bool {
+	switch normalize(encoding) {
+	case "", Identity, Gzip:
+		return true
+	case Zstd:
+		return zstdSupported
+	default:
+		return false
+	}
+}
+
+// normalize maps an encoding name to its canonical form
+func normalize(encoding string) string {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "x-gzip" {
+		return Gzip
+	}
+	return encoding
+}
@@ -0,0 +1,103 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	payload := []byte(`{"level":"error","message":"boom"}`)
+	compressed := gzipData(t, payload)
+
+	tests := []struct {
+		name     string
+		encoding string
+		data     []byte
+		maxSize  int64
+		expected []byte
+		err      error
+	}{
+		{"identity", "", payload, 0, payload, nil},
+		{"explicit identity", "identity", payload, 0, payload, nil},
+		{"gzip", "gzip", compressed, 0, payload, nil},
+		{"x-gzip mixed case", " X-GZIP ", compressed, 0, payload, nil},
+		{"exactly the limit", "gzip", compressed, int64(len(payload)), payload, nil},
+		{"over the limit", "gzip", compressed, int64(len(payload)) - 1, nil, ErrTooLarge},
+		{"unknown encoding", "br", compressed, 0, nil, ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Decompress(tt.encoding, tt.data, tt.maxSize)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if !bytes.Equal(out, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestDecompressBomb(t *testing.T) {
+	// 64MB of zeros compresses to about 64KB
+	bomb := gzipData(t, make([]byte, 64<<20))
+
+	if _, err := Decompress(Gzip, bomb, 1<<20); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+	if _, err := Decompress(Gzip, bomb, 0); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected the default limit to stop the bomb, got %v", err)
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	if _, err := Decompress(Gzip, []byte("not gzip"), 0); err == nil {
+		t.Error("Expected an error for an invalid gzip payload")
+	}
+	if !Supported("gzip") || Supported("br") {
+		t.Error("Expected gzip to be supported and br not")
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	payload := bytes.Repeat([]byte("line\n"), 1000)
+
+	reader, err := NewReader("gzip", bytes.NewReader(gzipData(t, payload)), int64(len(payload)))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	out, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(out, payload) {
+		t.Errorf("Expected the payload back, got %d bytes and %v", len(out), err)
+	}
+
+	// Data read before the limit is reached stays usable
+	reader, _ = NewReader("gzip", bytes.NewReader(gzipData(t, payload)), 100)
+	out, err = io.ReadAll(reader)
+	if !errors.Is(err, ErrTooLarge) || len(out) > 100 {
+		t.Errorf("Expected ErrTooLarge after at most 100 bytes, got %d bytes and %v", len(out), err)
+	}
+
+	// Uncompressed data is not limited
+	reader, _ = NewReader("", bytes.NewReader(payload), 100)
+	if out, err = io.ReadAll(reader); err != nil || len(out) != len(payload) {
+		t.Errorf("Expected uncompressed data to be read whole, got %d bytes and %v", len(out), err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build nozstd
+
+package compress
+
+import (
+	"fmt"
+	"io"
+)
+
+const zstdSupported = false
+
+// newZstdReader rejects zstd payloads in builds without zstd support
+func newZstdReader(io.Reader, int64) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("%w: zstd (built with nozstd)", ErrUnsupported)
+}
+
+// zstdTooLarge is always false in builds without zstd support
+func zstdTooLarge(error) bool {
+	return false
+}
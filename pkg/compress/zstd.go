@@ -0,0 +1,31 @@
+//go:build !nozstd
+
+package compress
+
+import (
+	"errors"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const zstdSupported = true
+
+// newZstdReader returns a zstd decoder whose memory and window cannot exceed maxSize
+func newZstdReader(r io.Reader, maxSize int64) (io.ReadCloser, error) {
+	limit := uint64(maxSize) // #nosec G115 - maxSize is always positive
+	decoder, err := zstd.NewReader(r,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(limit),
+		zstd.WithDecoderMaxWindow(min(max(limit, zstd.MinWindowSize), zstd.MaxWindowSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+// zstdTooLarge reports whether a zstd error means the frame needs more memory than allowed
+func zstdTooLarge(err error) bool {
+	return errors.Is(err, zstd.ErrWindowSizeExceeded) || errors.Is(err, zstd.ErrDecoderSizeExceeded)
+}
@@ -0,0 +1,41 @@
+//go:build !nozstd
+
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func zstdData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("Failed to create encoder: %v", err)
+	}
+	defer func() { _ = encoder.Close() }()
+	return encoder.EncodeAll(data, nil)
+}
+
+func TestDecompressZstd(t *testing.T) {
+	payload := []byte("[ERROR] database unreachable\n[INFO] retrying\n")
+
+	out, err := Decompress(Zstd, zstdData(t, payload), 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(out, payload) {
+		t.Errorf("Expected %q, got %q", payload, out)
+	}
+	if !Supported(Zstd) {
+		t.Error("Expected zstd to be supported")
+	}
+
+	bomb := zstdData(t, make([]byte, 64<<20))
+	if _, err := Decompress(Zstd, bomb, 1<<20); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected the bomb to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+const (
+	// Default connection pooling values
+	DefaultMaxIdleConns        = 100 // default max idle connections across all hosts
+	DefaultMaxIdleConnsPerHost = 10  // default max idle connections per host
+	DefaultIdleConnTimeout     = 90  // default idle connection timeout in seconds
+
+	// Default dialing values
+	DefaultDialTimeout         = 30 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// Config represents connection pooling and proxy options for HTTP-based outputs
+type Config struct {
+	MaxIdleConns        int    `yaml:"max_idle_conns,omitempty"`          // Max idle connections across all hosts (default: 100)
+	MaxIdleConnsPerHost int    `yaml:"max_idle_conns_per_host,omitempty"` // Max idle connections per host (default: 10)
+	MaxConnsPerHost     int    `yaml:"max_conns_per_host,omitempty"`      // Max total connections per host (default: unlimited)
+	IdleConnTimeout     int    `yaml:"idle_conn_timeout,omitempty"`       // Idle connection timeout in seconds (default: 90)
+	Proxy               string `yaml:"proxy,omitempty"`                   // Proxy URL (default: HTTP_PROXY/HTTPS_PROXY/NO_PROXY)
+}
+
+// Validate validates the HTTP client configuration
+func (c *Config) Validate() error {
+	if c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 || c.MaxConnsPerHost < 0 {
+		return fmt.Errorf("connection limits must be non-negative")
+	}
+	if c.IdleConnTimeout < 0 {
+		return fmt.Errorf("idle_conn_timeout must be non-negative")
+	}
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("invalid proxy URL scheme %q: must be http, https or socks5", proxyURL.Scheme)
+		}
+		if proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL: missing host")
+		}
+	}
+
+	return nil
+}
+
+// applyDefaults fills in unset pooling values
+func (c *Config) applyDefaults() {
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+}
+
+// New creates an *http.Client with the given timeout on top of a pooled transport
+// of its own. Its owner calls CloseIdleConnections on the client once done with it.
+func New(config Config, timeout time.Duration, tlsCfg tlsconfig.Config) (*http.Client, error) {
+	transport, err := NewTransport(config, tlsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
+
+// NewTransport returns a new pooled *http.Transport for the given configuration.
+// Transports are not shared between outputs: certificate files are read again
+// for each one, so a client built after they were rotated uses the new ones.
+// Its owner calls CloseIdleConnections once done with it.
+func NewTransport(config Config, tlsCfg tlsconfig.Config) (*http.Transport, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := tlsCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid TLS config: %w", err)
+	}
+	config.applyDefaults()
+	return newTransport(config, tlsCfg)
+}
+
+// newTransport builds a new transport from a validated configuration with defaults applied
+func newTransport(config Config, tlsCfg tlsconfig.Config) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: DefaultKeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       time.Duration(config.IdleConnTimeout) * time.Second,
+		TLSHandshakeTimeout:   DefaultTLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if tlsCfg.Enabled {
+		clientTLS, err := tlsCfg.NewTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TLS config: %w", err)
+		}
+		transport.TLSClientConfig = clientTLS
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,158 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "empty config is valid", config: Config{}, wantErr: false},
+		{name: "http proxy is valid", config: Config{Proxy: "http://proxy:3128"}, wantErr: false},
+		{name: "socks5 proxy is valid", config: Config{Proxy: "socks5://proxy:1080"}, wantErr: false},
+		{name: "unsupported proxy scheme", config: Config{Proxy: "ftp://proxy:21"}, wantErr: true},
+		{name: "proxy without host", config: Config{Proxy: "http://"}, wantErr: true},
+		{name: "negative idle conns", config: Config{MaxIdleConns: -1}, wantErr: true},
+		{name: "negative conns per host", config: Config{MaxConnsPerHost: -1}, wantErr: true},
+		{name: "negative idle timeout", config: Config{IdleConnTimeout: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTransportDefaults(t *testing.T) {
+	transport, err := NewTransport(Config{}, tlsconfig.Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if transport.MaxIdleConns != DefaultMaxIdleConns {
+		t.Errorf("Expected MaxIdleConns %d, got %d", DefaultMaxIdleConns, transport.MaxIdleConns)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost {
+		t.Errorf("Expected MaxIdleConnsPerHost %d, got %d", DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != DefaultIdleConnTimeout*time.Second {
+		t.Errorf("Expected IdleConnTimeout %ds, got %v", DefaultIdleConnTimeout, transport.IdleConnTimeout)
+	}
+	if transport.TLSClientConfig != nil {
+		t.Error("Expected no TLS client config when TLS is disabled")
+	}
+}
+
+func TestNewTransportRereadsCertificates(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	tlsCfg := tlsconfig.Config{Enabled: true, CACert: caFile}
+
+	writeCA(t, caFile, "first")
+	first, err := NewTransport(Config{}, tlsCfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The CA is rotated in place: a transport built afterwards trusts the new one
+	writeCA(t, caFile, "second")
+	second, err := NewTransport(Config{}, tlsCfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if first == second {
+		t.Fatal("Expected each call to build a new transport")
+	}
+	if first.TLSClientConfig.RootCAs.Equal(second.TLSClientConfig.RootCAs) {
+		t.Error("Expected the rotated CA certificate to be loaded")
+	}
+}
+
+// writeCA writes a self-signed CA certificate with the given common name to path
+func writeCA(t *testing.T, path, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	transport, err := NewTransport(Config{Proxy: "http://proxy.internal:3128"}, tlsconfig.Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "hooks.slack.com"}}
+	proxyURL, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("Unexpected proxy error: %v", err)
+	}
+	if proxyURL == nil || proxyURL.Host != "proxy.internal:3128" {
+		t.Errorf("Expected configured proxy, got %v", proxyURL)
+	}
+}
+
+func TestNewTransportTLS(t *testing.T) {
+	transport, err := NewTransport(Config{}, tlsconfig.Config{Enabled: true, ServerName: "logs.example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.ServerName != "logs.example.com" {
+		t.Error("Expected TLS client config with server name")
+	}
+}
+
+func TestNew(t *testing.T) {
+	client, err := New(Config{}, 5*time.Second, tlsconfig.Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %v", client.Timeout)
+	}
+	if _, ok := client.Transport.(*http.Transport); !ok {
+		t.Errorf("Expected *http.Transport, got %T", client.Transport)
+	}
+
+	if _, err := New(Config{Proxy: "ftp://proxy"}, time.Second, tlsconfig.Config{}); err == nil {
+		t.Error("Expected error for invalid proxy")
+	}
+}
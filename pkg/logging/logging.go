@@ -0,0 +1,170 @@
+// Package logging provides the internal loggers used by the engine and plugins.
+// Every line carries the component that wrote it (and the instance name, when
+// there is one) so internal logs can be grepped and parsed consistently:
+//
+//	component=buffer name=alerts msg="Delivery failed: connection refused"
+//
+// Lines go to the standard library logger's output, as logfmt text by default
+// or as one JSON object per line for self-monitoring. Chatty components can be
+// rate limited (see SetRateLimits).
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Output formats
+const (
+	FormatText = "text" // logfmt after the standard logger prefix (default)
+	FormatJSON = "json" // One JSON object per line with time, component, name, fields and msg
+)
+
+var (
+	jsonFormat atomic.Bool
+	writeMu    sync.Mutex // Serializes JSON lines, which bypass the standard logger
+)
+
+// SetFormat selects the output format for every logger ("" means text)
+func SetFormat(format string) error {
+	switch format {
+	case "", FormatText:
+		jsonFormat.Store(false)
+	case FormatJSON:
+		jsonFormat.Store(true)
+	default:
+		return fmt.Errorf("invalid log format '%s', must be '%s' or '%s'", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// Format returns the current output format
+func Format() string {
+	if jsonFormat.Load() {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// Logger writes internal logs for one component, optionally for a named instance
+type Logger struct {
+	component string
+	name      string
+}
+
+// New creates a logger for a component, e.g. "engine" or "output.elasticsearch"
+func New(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// Named returns a logger for a named instance of the component, e.g. a pipeline or input name
+func (l *Logger) Named(name string) *Logger {
+	return &Logger{component: l.component, name: name}
+}
+
+// Printf logs a formatted message
+func (l *Logger) Printf(format string, args ...any) {
+	if allow(l.component) {
+		l.output(fmt.Sprintf(format, args...), nil)
+	}
+}
+
+// Println logs its operands separated by spaces
+func (l *Logger) Println(args ...any) {
+	if allow(l.component) {
+		l.output(strings.TrimSuffix(fmt.Sprintln(args...), "\n"), nil)
+	}
+}
+
+// Print logs a message with extra key/value fields, e.g. Print("flushed", "count", 10)
+func (l *Logger) Print(msg string, keyvals ...any) {
+	if allow(l.component) {
+		l.output(msg, keyvals)
+	}
+}
+
+// Fatalf logs a formatted message, whatever the rate limits, and exits with status 1
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.output(fmt.Sprintf(format, args...), nil)
+	os.Exit(1)
+}
+
+// output renders one line in the current format
+func (l *Logger) output(msg string, keyvals []any) {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "")
+	}
+
+	if jsonFormat.Load() {
+		l.outputJSON(msg, keyvals)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("component=")
+	b.WriteString(l.component)
+	if l.name != "" {
+		b.WriteString(" name=")
+		b.WriteString(quote(l.name))
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], quote(fmt.Sprint(keyvals[i+1])))
+	}
+	b.WriteString(" msg=")
+	b.WriteString(quote(msg))
+	_ = log.Output(3, b.String())
+}
+
+// outputJSON writes the line as a JSON object straight to the standard logger's writer
+func (l *Logger) outputJSON(msg string, keyvals []any) {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSON(&b, time.Now().UTC().Format(time.RFC3339Nano))
+	b.WriteString(`,"component":`)
+	writeJSON(&b, l.component)
+	if l.name != "" {
+		b.WriteString(`,"name":`)
+		writeJSON(&b, l.name)
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteByte(',')
+		writeJSON(&b, fmt.Sprint(keyvals[i]))
+		b.WriteByte(':')
+		writeJSON(&b, keyvals[i+1])
+	}
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, msg)
+	b.WriteString("}\n")
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	_, _ = log.Writer().Write(b.Bytes())
+}
+
+// writeJSON encodes a value, falling back to its string form when it cannot be encoded
+func writeJSON(b *bytes.Buffer, value any) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(data)
+}
+
+// quote returns a logfmt value, quoted when it is empty or contains spaces, quotes or '='
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
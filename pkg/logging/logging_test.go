@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// capture redirects the standard logger while fn runs and returns what was written
+func capture(t *testing.T, format string, fn func()) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+		_ = SetFormat(FormatText)
+	}()
+
+	if err := SetFormat(format); err != nil {
+		t.Fatalf("Failed to set format: %v", err)
+	}
+	fn()
+	return buf.String()
+}
+
+func TestLoggerText(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      func()
+		expected string
+	}{
+		{
+			"component only",
+			func() { New("engine").Printf("Metrics reset") },
+			`component=engine msg="Metrics reset"`,
+		},
+		{
+			"named instance",
+			func() { New("buffer").Named("alerts").Printf("Delivery failed: %v", errors.New("refused")) },
+			`component=buffer name=alerts msg="Delivery failed: refused"`,
+		},
+		{
+			"fields",
+			func() { New("stats").Print("pipeline", "pipeline", "alerts", "enabled", true, "note", "") },
+			`component=stats pipeline=alerts enabled=true note="" msg=pipeline`,
+		},
+		{
+			"println",
+			func() { New("main").Println("Hot reload enabled for config file:", "config.yaml") },
+			`component=main msg="Hot reload enabled for config file: config.yaml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.TrimSpace(capture(t, FormatText, tt.log))
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	output := capture(t, FormatJSON, func() {
+		New("stats").Named("alerts").Print("pipeline", "enabled", true, "skipped", 3, "error", errors.New("boom"))
+	})
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(output), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", output, err)
+	}
+
+	expected := map[string]any{
+		"component": "stats",
+		"name":      "alerts",
+		"msg":       "pipeline",
+		"enabled":   true,
+		"skipped":   float64(3),
+		"error":     "boom",
+	}
+	for key, value := range expected {
+		if line[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, line[key])
+		}
+	}
+	if _, ok := line["time"]; !ok {
+		t.Error("Expected a time field")
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	defer func() { _ = SetFormat(FormatText) }()
+
+	for _, format := range []string{"", FormatText, FormatJSON} {
+		if err := SetFormat(format); err != nil {
+			t.Errorf("Expected format %q to be accepted, got %v", format, err)
+		}
+	}
+	if Format() != FormatJSON {
+		t.Errorf("Expected format json, got %s", Format())
+	}
+	if err := SetFormat("xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
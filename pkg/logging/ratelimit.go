@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReportInterval is how often the lines suppressed by rate limits are reported
+const DefaultReportInterval = 10 * time.Second
+
+// rateLimiter allows a component up to perSecond lines in each one-second window
+type rateLimiter struct {
+	perSecond int
+
+	mu         sync.Mutex
+	window     time.Time // Start of the current window
+	count      int       // Lines written or suppressed in the current window
+	suppressed int64     // Lines suppressed since the last report
+}
+
+// allow reports whether a line may be written now, counting it as suppressed if not
+func (r *rateLimiter) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.window) >= time.Second {
+		r.window = now
+		r.count = 0
+	}
+	r.count++
+	if r.count <= r.perSecond {
+		return true
+	}
+	r.suppressed++
+	return false
+}
+
+// takeSuppressed returns the lines suppressed since the last call
+func (r *rateLimiter) takeSuppressed() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.suppressed
+	r.suppressed = 0
+	return n
+}
+
+// rateLimits holds the limiters of the rate limited components and stops their reporter
+type rateLimits struct {
+	limiters map[string]*rateLimiter
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var (
+	limits   atomic.Pointer[rateLimits]
+	limitsMu sync.Mutex // Serializes SetRateLimits
+)
+
+// SetRateLimits caps the lines each listed component writes per second, across
+// its named instances. Lines over the limit are dropped and counted; every
+// interval (DefaultReportInterval when 0), each component that dropped lines
+// logs how many. Fatalf is never limited. Replacing or removing the limits
+// (nil) reports what was suppressed so far first.
+func SetRateLimits(perSecond map[string]int, interval time.Duration) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	if previous := limits.Swap(nil); previous != nil {
+		close(previous.stop)
+		<-previous.done
+	}
+	if len(perSecond) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+
+	current := &rateLimits{
+		limiters: make(map[string]*rateLimiter, len(perSecond)),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for component, limit := range perSecond {
+		current.limiters[component] = &rateLimiter{perSecond: limit}
+	}
+	limits.Store(current)
+	go current.reportLoop(interval)
+}
+
+// allow reports whether the component may write a line now
+func allow(component string) bool {
+	current := limits.Load()
+	if current == nil {
+		return true
+	}
+	limiter, ok := current.limiters[component]
+	return !ok || limiter.allow(time.Now())
+}
+
+// reportLoop reports the suppressed lines every interval, and once more when stopped
+func (r *rateLimits) reportLoop(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.stop:
+			r.report()
+			return
+		}
+	}
+}
+
+// report logs one line for each component that suppressed lines since the last report
+func (r *rateLimits) report() {
+	components := make([]string, 0, len(r.limiters))
+	for component := range r.limiters {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	for _, component := range components {
+		limiter := r.limiters[component]
+		if n := limiter.takeSuppressed(); n > 0 {
+			New(component).output("Suppressed log lines over the rate limit",
+				[]any{"suppressed", n, "limit_per_second", limiter.perSecond})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWindow(t *testing.T) {
+	limiter := &rateLimiter{perSecond: 2}
+	start := time.Now()
+
+	allowed := 0
+	for range 5 {
+		if limiter.allow(start) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected 2 lines allowed in the first second, got %d", allowed)
+	}
+	if !limiter.allow(start.Add(time.Second)) {
+		t.Error("Expected a line to be allowed in the next second")
+	}
+	if n := limiter.takeSuppressed(); n != 3 {
+		t.Errorf("Expected 3 suppressed lines, got %d", n)
+	}
+	if n := limiter.takeSuppressed(); n != 0 {
+		t.Errorf("Expected the suppressed count to reset once taken, got %d", n)
+	}
+}
+
+func TestSetRateLimits(t *testing.T) {
+	output := capture(t, FormatText, func() {
+		SetRateLimits(map[string]int{"buffer": 2}, time.Hour)
+		defer SetRateLimits(nil, 0)
+
+		for range 10 {
+			New("buffer").Named("alerts").Printf("Delivery failed")
+			New("engine").Printf("Log passed")
+		}
+		New("buffer").Print("retry", "attempt", 1)
+
+		// Removing the limits reports the suppressed lines instead of losing them
+		SetRateLimits(nil, 0)
+		New("buffer").Printf("Unlimited again")
+	})
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	counts := map[string]int{}
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "Delivery failed"):
+			counts["buffer"]++
+		case strings.Contains(line, "Log passed"):
+			counts["engine"]++
+		}
+	}
+	if counts["buffer"] != 2 || counts["engine"] != 10 {
+		t.Errorf("Expected 2 buffer and 10 engine lines, got %v", counts)
+	}
+
+	expected := `component=buffer suppressed=9 limit_per_second=2 msg="Suppressed log lines over the rate limit"`
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected the suppressed count to be reported, got:\n%s", output)
+	}
+	if !strings.HasSuffix(lines[len(lines)-1], `msg="Unlimited again"`) {
+		t.Errorf("Expected lines to be written once the limits are removed, got %s", lines[len(lines)-1])
+	}
+}
+
+func TestSetRateLimitsReportsPeriodically(t *testing.T) {
+	output := capture(t, FormatText, func() {
+		SetRateLimits(map[string]int{"resilience": 1}, 20*time.Millisecond)
+		defer SetRateLimits(nil, 0)
+
+		for range 3 {
+			New("resilience").Printf("Retrying")
+		}
+		time.Sleep(100 * time.Millisecond)
+	})
+	if !strings.Contains(output, "component=resilience suppressed=2") {
+		t.Errorf("Expected a periodic report of the suppressed lines, got:\n%s", output)
+	}
+}
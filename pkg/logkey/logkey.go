@@ -0,0 +1,330 @@
+// Package logkey builds grouping keys from logs. Plugins that group, count or
+// route logs by a key (burst, aggregate, shard, sample) describe the key with a
+// Spec, so the same spec means the same key everywhere.
+//
+// A spec is a list of parts. Each part reads one source of the log:
+//
+//	message, level, source, source_type, trace_id, span_id  the log's own fields
+//	field.<name>                                            the same fields, spelled explicitly
+//	metadata.<key>                                          a metadata value
+//	<key>                                                   any other name is a metadata key
+//
+// and may normalize it: trim spaces, lowercase, extract a regex match or fall
+// back to a default. In YAML a part is either a plain source or a mapping:
+//
+//	group_by:
+//	  - level
+//	  - source: message
+//	    extract: 'user=(\w+)'
+//	    lowercase: true
+//
+// A spec may also be written as a single source, so `key: user_id` and
+// `key: [user_id]` are the same spec.
+package logkey
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"gopkg.in/yaml.v3"
+)
+
+// Separator joins the values of a composite key
+const Separator = "\x00"
+
+// Part is one source of a key and the normalization applied to its value
+type Part struct {
+	Source    string `yaml:"source"`              // Log field, metadata.<key>, field.<name> or a bare metadata key
+	Name      string `yaml:"name,omitempty"`      // Label of the value where a key is reported (default: the field or metadata key)
+	Trim      bool   `yaml:"trim,omitempty"`      // Remove leading and trailing spaces
+	Lowercase bool   `yaml:"lowercase,omitempty"` // Lowercase the value
+	Extract   string `yaml:"extract,omitempty"`   // Regex; the first capture group, or the whole match, replaces the value ("" without a match)
+	Default   string `yaml:"default,omitempty"`   // Value used when the source is missing or normalizes to ""
+}
+
+// UnmarshalYAML accepts a plain source as well as a mapping
+func (p *Part) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = Part{Source: value.Value}
+		return nil
+	}
+	type plain Part
+	return value.Decode((*plain)(p))
+}
+
+// MarshalYAML writes a part without normalization as its plain source
+func (p Part) MarshalYAML() (any, error) {
+	if p == (Part{Source: p.Source}) {
+		return p.Source, nil
+	}
+	type plain Part
+	return plain(p), nil
+}
+
+// Spec describes a key as an ordered list of parts
+type Spec []Part
+
+// Sources returns a spec made of plain sources
+func Sources(sources ...string) Spec {
+	spec := make(Spec, len(sources))
+	for i, source := range sources {
+		spec[i] = Part{Source: source}
+	}
+	return spec
+}
+
+// UnmarshalYAML accepts a single part as well as a sequence
+func (s *Spec) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.SequenceNode {
+		var part Part
+		if err := value.Decode(&part); err != nil {
+			return err
+		}
+		*s = Spec{part}
+		return nil
+	}
+	var parts []Part
+	if err := value.Decode(&parts); err != nil {
+		return err
+	}
+	*s = parts
+	return nil
+}
+
+// fieldKind is where a part reads its value from
+type fieldKind int
+
+const (
+	fieldMetadata fieldKind = iota
+	fieldMessage
+	fieldLevel
+	fieldSource
+	fieldSourceType
+	fieldTraceID
+	fieldSpanID
+)
+
+// logFields maps the names of the log's own fields to their kind
+var logFields = map[string]fieldKind{
+	"message":     fieldMessage,
+	"level":       fieldLevel,
+	"source":      fieldSource,
+	"source_type": fieldSourceType,
+	"trace_id":    fieldTraceID,
+	"span_id":     fieldSpanID,
+}
+
+// part is a compiled Part
+type part struct {
+	kind      fieldKind
+	key       string // Metadata key
+	name      string
+	trim      bool
+	lowercase bool
+	extract   *regexp.Regexp
+	group     int // Capture group replacing the value (0 = whole match)
+	fallback  string
+}
+
+// plain reports whether the value is used as read
+func (p *part) plain() bool {
+	return !p.trim && !p.lowercase && p.extract == nil && p.fallback == ""
+}
+
+// Key computes the key described by a spec. A Key is immutable and safe for
+// concurrent use.
+type Key struct {
+	parts []part
+}
+
+// Compile validates a spec and prepares it for computing keys
+func Compile(spec Spec) (*Key, error) {
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("key needs at least one source")
+	}
+
+	k := &Key{parts: make([]part, len(spec))}
+	for i, p := range spec {
+		compiled, err := compilePart(p)
+		if err != nil {
+			return nil, fmt.Errorf("key part %d: %w", i+1, err)
+		}
+		k.parts[i] = compiled
+	}
+	return k, nil
+}
+
+// MustCompile is like Compile but panics on an invalid spec
+func MustCompile(spec Spec) *Key {
+	k, err := Compile(spec)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// compilePart resolves the source of a part and compiles its regex
+func compilePart(p Part) (part, error) {
+	source := strings.TrimSpace(p.Source)
+	if source == "" {
+		return part{}, fmt.Errorf("source cannot be empty")
+	}
+
+	compiled := part{trim: p.Trim, lowercase: p.Lowercase, fallback: p.Default}
+	switch {
+	case strings.HasPrefix(source, "metadata."):
+		compiled.kind, compiled.key = fieldMetadata, strings.TrimPrefix(source, "metadata.")
+		if compiled.key == "" {
+			return part{}, fmt.Errorf("source %q names no metadata key", source)
+		}
+		compiled.name = compiled.key
+	case strings.HasPrefix(source, "field."):
+		name := strings.TrimPrefix(source, "field.")
+		kind, ok := logFields[name]
+		if !ok {
+			return part{}, fmt.Errorf("unknown log field %q", name)
+		}
+		compiled.kind, compiled.name = kind, name
+	default:
+		if kind, ok := logFields[source]; ok {
+			compiled.kind = kind
+		} else {
+			compiled.kind, compiled.key = fieldMetadata, source
+		}
+		compiled.name = source
+	}
+	if p.Name != "" {
+		compiled.name = p.Name
+	}
+
+	if p.Extract != "" {
+		re, err := regexp.Compile(p.Extract)
+		if err != nil {
+			return part{}, fmt.Errorf("invalid extract pattern %q: %w", p.Extract, err)
+		}
+		compiled.extract = re
+		if re.NumSubexp() > 0 {
+			compiled.group = 1
+		}
+	}
+	return compiled, nil
+}
+
+// read returns the raw value of a part and whether the log has it. The log's own
+// fields are always present; metadata keys only when set.
+func (p *part) read(logEntry *core.Log) (string, bool) {
+	switch p.kind {
+	case fieldMessage:
+		return logEntry.Message, true
+	case fieldLevel:
+		return logEntry.Level, true
+	case fieldSource:
+		return logEntry.Source, true
+	case fieldSourceType:
+		return logEntry.SourceType, true
+	case fieldTraceID:
+		return logEntry.TraceID, true
+	case fieldSpanID:
+		return logEntry.SpanID, true
+	default:
+		value, ok := logEntry.Metadata[p.key]
+		return value, ok
+	}
+}
+
+// value returns the normalized value of a part. Trimming and extracting return
+// substrings of the source, so only lowercasing a value with uppercase letters
+// copies it.
+func (p *part) value(logEntry *core.Log) (string, bool) {
+	value, ok := p.read(logEntry)
+	if p.plain() {
+		return value, ok
+	}
+	if p.trim {
+		value = strings.TrimSpace(value)
+	}
+	if p.extract != nil {
+		value = extract(p.extract, p.group, value)
+	}
+	if p.lowercase {
+		value = strings.ToLower(value)
+	}
+	if value == "" && p.fallback != "" {
+		return p.fallback, true
+	}
+	return value, ok
+}
+
+// extract returns the capture group of the first match of re, or "" without a match
+func extract(re *regexp.Regexp, group int, value string) string {
+	match := re.FindStringSubmatchIndex(value)
+	if match == nil || match[2*group] < 0 {
+		return ""
+	}
+	return value[match[2*group]:match[2*group+1]]
+}
+
+// Len returns the number of parts of the key
+func (k *Key) Len() int {
+	return len(k.parts)
+}
+
+// Names returns the label of each part, in spec order: the part's name, or
+// else its log field or metadata key
+func (k *Key) Names() []string {
+	names := make([]string, len(k.parts))
+	for i := range k.parts {
+		names[i] = k.parts[i].name
+	}
+	return names
+}
+
+// Of returns the key of a log: the value of a single part, or the part values
+// joined with Separator. A single part costs no allocation unless its
+// normalization changes the value; a composite key costs one.
+func (k *Key) Of(logEntry *core.Log) string {
+	value, _ := k.Lookup(logEntry)
+	return value
+}
+
+// Lookup returns the key of a log and whether any of its sources is present in
+// the log (a part with a default always is). Callers use it to tell a log
+// without the key apart from one whose key is empty.
+func (k *Key) Lookup(logEntry *core.Log) (string, bool) {
+	if len(k.parts) == 1 {
+		return k.parts[0].value(logEntry)
+	}
+
+	// Values stay on the stack for keys of up to 8 parts
+	var buf [8]string
+	values := buf[:0]
+	size := len(Separator) * (len(k.parts) - 1)
+	found := false
+	for i := range k.parts {
+		value, ok := k.parts[i].value(logEntry)
+		values = append(values, value)
+		size += len(value)
+		found = found || ok
+	}
+
+	var b strings.Builder
+	b.Grow(size)
+	for i, value := range values {
+		if i > 0 {
+			b.WriteString(Separator)
+		}
+		b.WriteString(value)
+	}
+	return b.String(), found
+}
+
+// Values appends the value of each part of a log to dst, in spec order
+func (k *Key) Values(dst []string, logEntry *core.Log) []string {
+	for i := range k.parts {
+		value, _ := k.parts[i].value(logEntry)
+		dst = append(dst, value)
+	}
+	return dst
+}
@@ -0,0 +1,177 @@
+package logkey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"gopkg.in/yaml.v3"
+)
+
+func testLog() *core.Log {
+	logEntry := core.NewLogWithMetadata("ERROR", "  Login failed for user=Alice  ", map[string]string{
+		"service": "API",
+		"message": "from metadata",
+		"empty":   "",
+	})
+	logEntry.Source = "app"
+	logEntry.SourceType = "http"
+	logEntry.TraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	return logEntry
+}
+
+func TestKeyOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     Spec
+		expected string
+		found    bool
+	}{
+		{"log field", Sources("level"), "ERROR", true},
+		{"explicit log field", Sources("field.source_type"), "http", true},
+		{"trace id", Sources("trace_id"), "4bf92f3577b34da6a3ce929d0e0e4736", true},
+		{"bare metadata key", Sources("service"), "API", true},
+		{"metadata key named like a field", Sources("metadata.message"), "from metadata", true},
+		{"missing metadata key", Sources("user"), "", false},
+		{"empty metadata value", Sources("empty"), "", true},
+		{"composite", Sources("source", "level", "service"), "app\x00ERROR\x00API", true},
+		{"composite with a missing part", Sources("user", "service"), "\x00API", true},
+		{"composite without any part", Sources("user", "metadata.region"), "\x00", false},
+		{"lowercase", Spec{{Source: "service", Lowercase: true}}, "api", true},
+		{"trim", Spec{{Source: "message", Trim: true}}, "Login failed for user=Alice", true},
+		{"extract group", Spec{{Source: "message", Extract: `user=(\w+)`, Lowercase: true}}, "alice", true},
+		{"extract match", Spec{{Source: "message", Extract: `[A-Z]\w+`}}, "Login", true},
+		{"extract without match", Spec{{Source: "message", Extract: `order=(\d+)`}}, "", true},
+		{"default", Spec{{Source: "user", Default: "anonymous"}}, "anonymous", true},
+		{"default after extract", Spec{{Source: "message", Extract: `order=(\d+)`, Default: "none"}}, "none", true},
+		{"optional group", Spec{{Source: "message", Extract: `user=(\d+)?`}}, "", true},
+	}
+
+	logEntry := testLog()
+	for _, tt := range tests {
+		key, err := Compile(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		value, found := key.Lookup(logEntry)
+		if value != tt.expected || found != tt.found {
+			t.Errorf("%s: expected %q (found %v), got %q (found %v)", tt.name, tt.expected, tt.found, value, found)
+		}
+		if got := key.Of(logEntry); got != value {
+			t.Errorf("%s: expected Of to match Lookup, got %q", tt.name, got)
+		}
+		if got := strings.Join(key.Values(nil, logEntry), Separator); got != tt.expected {
+			t.Errorf("%s: expected joined values %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestKeyNames(t *testing.T) {
+	key := MustCompile(Spec{
+		{Source: "level"},
+		{Source: "metadata.service"},
+		{Source: "field.source"},
+		{Source: "region"},
+		{Source: "message", Extract: `user=(\w+)`, Name: "user"},
+	})
+
+	expected := []string{"level", "service", "source", "region", "user"}
+	names := key.Names()
+	if strings.Join(names, ",") != strings.Join(expected, ",") || key.Len() != len(expected) {
+		t.Errorf("Expected names %v, got %v", expected, names)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     Spec
+		expected string
+	}{
+		{"empty spec", nil, "at least one source"},
+		{"empty source", Sources("level", " "), "key part 2: source cannot be empty"},
+		{"empty metadata key", Sources("metadata."), "names no metadata key"},
+		{"unknown field", Sources("field.host"), `unknown log field "host"`},
+		{"invalid regex", Spec{{Source: "message", Extract: "("}}, "invalid extract pattern"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestSpecYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Spec
+	}{
+		{"single source", `key: user_id`, Sources("user_id")},
+		{"list of sources", `key: [level, service]`, Sources("level", "service")},
+		{"mixed parts", "key:\n  - level\n  - source: message\n    extract: 'user=(\\w+)'\n    lowercase: true\n",
+			Spec{{Source: "level"}, {Source: "message", Extract: `user=(\w+)`, Lowercase: true}}},
+		{"single mapping", "key: {source: service, default: none}", Spec{{Source: "service", Default: "none"}}},
+	}
+
+	for _, tt := range tests {
+		var config struct {
+			Key Spec `yaml:"key"`
+		}
+		if err := yaml.Unmarshal([]byte(tt.input), &config); err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if len(config.Key) != len(tt.expected) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.expected, config.Key)
+		}
+		for i := range tt.expected {
+			if config.Key[i] != tt.expected[i] {
+				t.Errorf("%s: expected part %d %+v, got %+v", tt.name, i, tt.expected[i], config.Key[i])
+			}
+		}
+
+		// Specs survive the marshal round trip plugin configs go through
+		data, err := yaml.Marshal(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected marshal error %v", tt.name, err)
+		}
+		var again struct {
+			Key Spec `yaml:"key"`
+		}
+		if err := yaml.Unmarshal(data, &again); err != nil || len(again.Key) != len(config.Key) || again.Key[0] != config.Key[0] {
+			t.Errorf("%s: expected the spec to round trip, got %+v (%v)", tt.name, again.Key, err)
+		}
+	}
+}
+
+func TestKeyAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful under the race detector")
+	}
+	logEntry := testLog()
+	single := MustCompile(Sources("service"))
+	composite := MustCompile(Sources("level", "source", "service"))
+	extracted := MustCompile(Spec{{Source: "message", Trim: true, Extract: `user=(\w+)`}})
+
+	if allocs := testing.AllocsPerRun(100, func() { _ = single.Of(logEntry) }); allocs != 0 {
+		t.Errorf("Expected no allocation for a single part, got %v", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = composite.Of(logEntry) }); allocs != 1 {
+		t.Errorf("Expected one allocation for a composite key, got %v", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = extracted.Of(logEntry) }); allocs > 1 {
+		t.Errorf("Expected at most one allocation for an extracted key, got %v", allocs)
+	}
+}
+
+func BenchmarkKeyOf(b *testing.B) {
+	logEntry := testLog()
+	key := MustCompile(Spec{{Source: "level"}, {Source: "service", Lowercase: true}, {Source: "message", Extract: `user=(\w+)`}})
+
+	b.ReportAllocs()
+	for range b.N {
+		_ = key.Of(logEntry)
+	}
+}
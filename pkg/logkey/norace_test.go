@@ -0,0 +1,5 @@
+//go:build !race
+
+package logkey
+
+const raceEnabled = false
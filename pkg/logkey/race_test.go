@@ -0,0 +1,6 @@
+//go:build race
+
+package logkey
+
+// raceEnabled is set when the tests run with -race, which adds allocations of its own
+const raceEnabled = true
@@ -0,0 +1,111 @@
+// Package partition maps keys to partitions so that logs sharing a key always
+// land in the same place: the same child of a sharded output, the same Kafka
+// partition or the same downstream worker.
+//
+// Hashes are stable: they have no random seed and do not depend on the process,
+// platform or Go version, so a key keeps its partition across restarts.
+package partition
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of virtual nodes per node. More virtual nodes
+// spread the key space more evenly at the cost of a larger ring.
+const DefaultReplicas = 160
+
+// Hash returns the stable 64-bit hash of a key: FNV-1a followed by the
+// MurmurHash3 finalizer, which spreads similar keys (user-1, user-2, ...) evenly
+// over the hash range.
+func Hash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Ring is a consistent hash ring. Each node owns the keys between its virtual
+// nodes and the previous ones, so adding or removing a node only moves the keys
+// of that node (about 1/N of them) instead of reshuffling every key.
+// A Ring is immutable and safe for concurrent use.
+type Ring struct {
+	nodes  []string
+	points []point // Virtual nodes sorted by hash
+}
+
+// point is one virtual node on the ring
+type point struct {
+	hash uint64
+	node int // Index into nodes
+}
+
+// NewRing builds a ring over the named nodes with the given number of virtual
+// nodes per node (DefaultReplicas when 0). Keys are placed by node name, not
+// position, so reordering the nodes does not move any key.
+func NewRing(nodes []string, replicas int) (*Ring, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("at least one node is required")
+	}
+	if replicas < 0 {
+		return nil, fmt.Errorf("replicas must be non-negative")
+	}
+	if replicas == 0 {
+		replicas = DefaultReplicas
+	}
+
+	seen := make(map[string]bool, len(nodes))
+	r := &Ring{
+		nodes:  append([]string(nil), nodes...),
+		points: make([]point, 0, len(nodes)*replicas),
+	}
+	for i, node := range nodes {
+		if node == "" {
+			return nil, fmt.Errorf("node names cannot be empty")
+		}
+		if seen[node] {
+			return nil, fmt.Errorf("duplicate node %q", node)
+		}
+		seen[node] = true
+		for v := range replicas {
+			r.points = append(r.points, point{hash: Hash(node + "#" + strconv.Itoa(v)), node: i})
+		}
+	}
+
+	// Ties between virtual nodes (practically impossible) go to the smaller name, so the ring never depends on node order
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.nodes[r.points[i].node] < r.nodes[r.points[j].node]
+	})
+	return r, nil
+}
+
+// Locate returns the index (in the order given to NewRing) of the node that owns key
+func (r *Ring) Locate(key string) int {
+	h := Hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0 // Wrap around
+	}
+	return r.points[i].node
+}
+
+// Node returns the name of the node that owns key
+func (r *Ring) Node(key string) string {
+	return r.nodes[r.Locate(key)]
+}
+
+// Nodes returns the node names in the order given to NewRing
+func (r *Ring) Nodes() []string {
+	return append([]string(nil), r.nodes...)
+}
@@ -0,0 +1,91 @@
+package partition
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashStable(t *testing.T) {
+	// Golden values: a change here moves keys to other partitions after an upgrade
+	tests := map[string]uint64{
+		"":       0xefd01f60ba992926,
+		"user-1": 0x41a2fca5c68401c5,
+		"user-2": 0xa9f4ad0c2263143b,
+	}
+	for key, expected := range tests {
+		if got := Hash(key); got != expected {
+			t.Errorf("Hash(%q) = %#x, expected %#x", key, got, expected)
+		}
+	}
+}
+
+func TestNewRingValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []string
+		replicas int
+	}{
+		{name: "no nodes", nodes: nil},
+		{name: "empty name", nodes: []string{"a", ""}},
+		{name: "duplicate node", nodes: []string{"a", "b", "a"}},
+		{name: "negative replicas", nodes: []string{"a"}, replicas: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRing(tt.nodes, tt.replicas); err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestRingDistribution(t *testing.T) {
+	nodes := []string{"shard-1", "shard-2", "shard-3", "shard-4"}
+	ring, err := NewRing(nodes, 0)
+	if err != nil {
+		t.Fatalf("Failed to create ring: %v", err)
+	}
+
+	const keys = 100000
+	counts := make([]int, len(nodes))
+	for i := range keys {
+		counts[ring.Locate(fmt.Sprintf("user-%d", i))]++
+	}
+
+	expected := keys / len(nodes)
+	for i, count := range counts {
+		if count < expected*85/100 || count > expected*115/100 {
+			t.Errorf("Expected about %d keys on %s, got %d (%v)", expected, nodes[i], count, counts)
+		}
+	}
+}
+
+func TestRingConsistency(t *testing.T) {
+	before, _ := NewRing([]string{"a", "b", "c", "d"}, 0)
+	reordered, _ := NewRing([]string{"d", "c", "b", "a"}, 0)
+	grown, _ := NewRing([]string{"a", "b", "c", "d", "e"}, 0)
+
+	const keys = 20000
+	moved := 0
+	for i := range keys {
+		key := fmt.Sprintf("order-%d", i)
+		node := before.Node(key)
+		if again := before.Node(key); again != node {
+			t.Fatalf("Expected %q to stay on %s, got %s", key, node, again)
+		}
+		if other := reordered.Node(key); other != node {
+			t.Fatalf("Expected node order not to matter for %q: %s vs %s", key, node, other)
+		}
+		if newNode := grown.Node(key); newNode != node {
+			if newNode != "e" {
+				t.Fatalf("Expected %q to move only to the new node, moved from %s to %s", key, node, newNode)
+			}
+			moved++
+		}
+	}
+
+	// About 1/5 of the keys move to the new node
+	if moved < keys*15/100 || moved > keys*25/100 {
+		t.Errorf("Expected about %d keys to move, got %d", keys/5, moved)
+	}
+}
@@ -0,0 +1,329 @@
+package redisclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+const (
+	// DefaultDialTimeout is the default timeout for establishing a connection
+	DefaultDialTimeout = 10 * time.Second
+	// maxIdleConns bounds the connections kept open between commands
+	maxIdleConns = 4
+)
+
+// Config represents the connection options shared by Redis-based plugins.
+// Plugins embed it inline, so the options sit next to the plugin's own settings.
+type Config struct {
+	Addr     string           `yaml:"addr"`               // host:port
+	Username string           `yaml:"username,omitempty"` // ACL user (Redis 6+)
+	Password string           `yaml:"password,omitempty"` // AUTH password
+	DB       int              `yaml:"db,omitempty"`       // Database selected after connecting (default: 0)
+	TLS      tlsconfig.Config `yaml:"tls,omitempty"`      // TLS configuration
+}
+
+// Validate validates the connection configuration
+func (c *Config) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("redis addr is required")
+	}
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("invalid redis addr %q: %w", c.Addr, err)
+	}
+	if c.Username != "" && c.Password == "" {
+		return fmt.Errorf("redis password is required when username is set")
+	}
+	if c.DB < 0 {
+		return fmt.Errorf("redis db must be non-negative")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid TLS config: %w", err)
+	}
+	return nil
+}
+
+// Error is an error reply sent by the server (e.g. "BUSYGROUP Consumer Group name already exists").
+// The connection stays usable after an error reply.
+type Error string
+
+// Error implements the error interface
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Client sends commands over a small pool of RESP connections. A command that
+// blocks on the server (e.g. XREADGROUP BLOCK) only holds its own connection,
+// so other commands such as PING are not delayed by it.
+type Client struct {
+	config    Config
+	tlsConfig *tls.Config
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// conn is a single connection with its buffered reader
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// New creates a client. Connections are opened on first use.
+func New(config Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := &Client{config: config}
+	if config.TLS.Enabled {
+		tlsConfig, err := config.TLS.NewTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TLS config: %w", err)
+		}
+		client.tlsConfig = tlsConfig
+	}
+	return client, nil
+}
+
+// Do sends a command and returns its reply: string for simple strings, int64 for
+// integers, []byte for bulk strings, []any for arrays and nil for null replies.
+// Server errors are returned as Error. Cancelling ctx interrupts a blocked command.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, args)
+	var serverErr Error
+	if err != nil && !errors.As(err, &serverErr) {
+		// The connection state is unknown after a network error or cancellation
+		_ = cn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+// Ping checks the server is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	reply, err := c.Do(ctx, "PING")
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected PING reply: %v", reply)
+	}
+	return nil
+}
+
+// Close closes all idle connections. Commands in flight finish on their own
+// connection, which is closed instead of being returned to the pool.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, cn := range c.idle {
+		_ = cn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// get returns an idle connection or dials a new one
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("redis client is closed")
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	return c.dial(ctx)
+}
+
+// put returns a connection to the pool, or closes it if the pool is full or closed
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || len(c.idle) >= maxIdleConns {
+		_ = cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+// dial opens a connection, then authenticates and selects the database
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: DefaultDialTimeout}
+
+	var netConn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: c.tlsConfig}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", c.config.Addr)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", c.config.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", c.config.Addr, err)
+	}
+
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if c.config.Password != "" {
+		args := []string{"AUTH", c.config.Password}
+		if c.config.Username != "" {
+			args = []string{"AUTH", c.config.Username, c.config.Password}
+		}
+		if _, err := cn.do(ctx, args); err != nil {
+			_ = cn.Close()
+			return nil, fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+	if c.config.DB != 0 {
+		if _, err := cn.do(ctx, []string{"SELECT", strconv.Itoa(c.config.DB)}); err != nil {
+			_ = cn.Close()
+			return nil, fmt.Errorf("failed to select redis db %d: %w", c.config.DB, err)
+		}
+	}
+	return cn, nil
+}
+
+// do writes a command and reads its reply, honoring ctx cancellation and deadline
+func (cn *conn) do(ctx context.Context, args []string) (any, error) {
+	deadline, _ := ctx.Deadline()
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// Unblock reads and writes as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		_ = cn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := cn.Write(encodeCommand(args)); err != nil {
+		return nil, err
+	}
+	return readReply(cn.reader)
+}
+
+// encodeCommand encodes a command as a RESP array of bulk strings
+func encodeCommand(args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readReply reads a single RESP2 reply
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("invalid redis reply: empty line")
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis integer reply: %w", err)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := readReply(r)
+			var serverErr Error
+			if err != nil && !errors.As(err, &serverErr) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("invalid redis reply type %q", line[0])
+	}
+}
+
+// readLine reads a CRLF-terminated line without the terminator
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid redis reply: missing CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+// String converts a simple or bulk string reply to a string
+func String(reply any) (string, bool) {
+	switch v := reply.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,219 @@
+package redisclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer replies to each command with the handler's raw RESP reply
+type fakeServer struct {
+	listener net.Listener
+	handler  func(args []string) string
+
+	mu       sync.Mutex
+	commands [][]string
+}
+
+func newFakeServer(t *testing.T, handler func(args []string) string) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &fakeServer{listener: listener, handler: handler}
+	go server.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return server
+}
+
+func (s *fakeServer) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer func() { _ = c.Close() }()
+			reader := bufio.NewReader(c)
+			for {
+				reply, err := readReply(reader)
+				if err != nil {
+					return
+				}
+				var args []string
+				for _, arg := range reply.([]any) {
+					args = append(args, string(arg.([]byte)))
+				}
+				s.mu.Lock()
+				s.commands = append(s.commands, args)
+				s.mu.Unlock()
+				if _, err := c.Write([]byte(s.handler(args))); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (s *fakeServer) received() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+		err      string
+	}{
+		{name: "simple string", input: "+OK\r\n", expected: "OK"},
+		{name: "integer", input: ":42\r\n", expected: int64(42)},
+		{name: "bulk string", input: "$5\r\nhello\r\n", expected: []byte("hello")},
+		{name: "null bulk", input: "$-1\r\n", expected: nil},
+		{name: "null array", input: "*-1\r\n", expected: nil},
+		{name: "nested array", input: "*2\r\n$2\r\nid\r\n*2\r\n$1\r\na\r\n:1\r\n", expected: []any{[]byte("id"), []any{[]byte("a"), int64(1)}}},
+		{name: "error", input: "-NOGROUP no such group\r\n", err: "NOGROUP no such group"},
+		{name: "missing CRLF", input: "+OK\n", err: "missing CRLF"},
+		{name: "unknown type", input: "?x\r\n", err: "invalid redis reply type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(reply, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, reply)
+			}
+		})
+	}
+}
+
+func TestClientAuthSelectAndPing(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "PING":
+			return "+PONG\r\n"
+		default:
+			return "+OK\r\n"
+		}
+	})
+
+	client, err := New(Config{Addr: server.listener.Addr().String(), Username: "app", Password: "secret", DB: 2})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping failed: %v", err)
+		}
+	}
+
+	expected := [][]string{{"AUTH", "app", "secret"}, {"SELECT", "2"}, {"PING"}, {"PING"}}
+	if got := server.received(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected commands %v on one reused connection, got %v", expected, got)
+	}
+}
+
+func TestClientServerErrorKeepsConnection(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "XGROUP" {
+			return "-BUSYGROUP Consumer Group name already exists\r\n"
+		}
+		return "+PONG\r\n"
+	})
+
+	client, err := New(Config{Addr: server.listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	_, err = client.Do(context.Background(), "XGROUP", "CREATE", "logs", "g", "$")
+	var serverErr Error
+	if !errors.As(err, &serverErr) || !strings.HasPrefix(string(serverErr), "BUSYGROUP") {
+		t.Fatalf("Expected BUSYGROUP server error, got %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping after server error failed: %v", err)
+	}
+	if len(client.idle) != 1 {
+		t.Errorf("Expected the connection to be reused, got %d idle connections", len(client.idle))
+	}
+}
+
+func TestClientCancelBlockedCommand(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "XREAD" {
+			time.Sleep(time.Second) // Simulates BLOCK
+			return "*-1\r\n"
+		}
+		return "+PONG\r\n"
+	})
+
+	client, err := New(Config{Addr: server.listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Do(ctx, "XREAD", "BLOCK", "0", "STREAMS", "logs", "$")
+		done <- err
+	}()
+
+	// Other commands are not delayed by the blocked one
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping during blocked command failed: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Cancelling the context did not interrupt the blocked command")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "missing addr", config: Config{}},
+		{name: "missing port", config: Config{Addr: "localhost"}},
+		{name: "username without password", config: Config{Addr: "localhost:6379", Username: "app"}},
+		{name: "negative db", config: Config{Addr: "localhost:6379", DB: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
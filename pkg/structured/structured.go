@@ -0,0 +1,81 @@
+// Package structured reads the well-known fields of a structured log record,
+// such as a JSON object posted to the HTTP input or a decoded Kafka value, so
+// every input maps level, message, timestamp and tags the same way.
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Field returns the first of the named fields present in the record
+func Field(record map[string]any, names ...string) (any, bool) {
+	for _, name := range names {
+		if value, ok := record[name]; ok && value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns strings as-is, timestamps in RFC 3339 and the JSON encoding of
+// any other value
+func String(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// Timestamp reads a timestamp type, an RFC 3339 string or a number of Unix
+// seconds. Numbers that are not positive or do not fit in a time are rejected.
+func Timestamp(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		timestamp, err := time.Parse(time.RFC3339Nano, v)
+		return timestamp, err == nil
+	case float64:
+		if v <= 0 || v >= math.MaxInt64 || math.IsNaN(v) {
+			return time.Time{}, false
+		}
+		seconds, fraction := math.Modf(v)
+		return time.Unix(int64(seconds), int64(fraction*float64(time.Second))), true
+	case int64:
+		if v <= 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(v, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// Tags returns the tags of a "tags" field given as a string or an array of strings
+func Tags(value any) []string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []any:
+		var tags []string
+		for _, tag := range v {
+			if s, ok := tag.(string); ok && s != "" {
+				tags = append(tags, s)
+			}
+		}
+		return tags
+	}
+	return nil
+}
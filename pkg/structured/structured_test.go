@@ -0,0 +1,81 @@
+package structured
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestField(t *testing.T) {
+	record := map[string]any{"msg": "fallback", "message": nil}
+	if value, ok := Field(record, "message", "msg"); !ok || value != "fallback" {
+		t.Errorf("Expected the first non-null field, got %v (%t)", value, ok)
+	}
+	if _, ok := Field(record, "level"); ok {
+		t.Error("Expected a missing field not to be found")
+	}
+}
+
+func TestString(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{"plain", "plain"},
+		{at, "2024-05-01T12:00:00Z"},
+		{float64(42), "42"},
+		{map[string]any{"user": "ada"}, `{"user":"ada"}`},
+	}
+	for _, tt := range tests {
+		if got := String(tt.value); got != tt.expected {
+			t.Errorf("String(%v) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    any
+		expected time.Time
+		ok       bool
+	}{
+		{"time", at, at, true},
+		{"rfc3339", "2024-05-01T12:00:00Z", at, true},
+		{"seconds", float64(at.Unix()) + 0.5, at.Add(500 * time.Millisecond), true},
+		{"integer seconds", at.Unix(), at, true},
+		{"not a time", "yesterday", time.Time{}, false},
+		{"zero", float64(0), time.Time{}, false},
+		{"negative", int64(-1), time.Time{}, false},
+		{"nan", math.NaN(), time.Time{}, false},
+		{"infinite", math.Inf(1), time.Time{}, false},
+		{"too large", 1e19, time.Time{}, false},
+		{"bool", true, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Timestamp(tt.value)
+		if ok != tt.ok || !got.Equal(tt.expected) {
+			t.Errorf("%s: expected %v (%t), got %v (%t)", tt.name, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		value    any
+		expected []string
+	}{
+		{"prod", []string{"prod"}},
+		{"", nil},
+		{[]any{"prod", 1, "", "eu"}, []string{"prod", "eu"}},
+		{42, nil},
+	}
+	for _, tt := range tests {
+		if got := Tags(tt.value); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Tags(%v) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is how often a file at EOF is checked for new data and rotation
+	DefaultPollInterval = 250 * time.Millisecond
+	// maxLineSize bounds a single line; longer lines are split
+	maxLineSize = 1024 * 1024
+)
+
+// Config configures a Follow call
+type Config struct {
+	FromStart    bool          // Read the existing content first (default: start at the end)
+	PollInterval time.Duration // Check for new data and rotation at this interval (default: 250ms)
+}
+
+// Follow reads lines appended to path and calls fn with each complete line,
+// without the trailing newline, until ctx is cancelled or fn returns false.
+//
+// Rotation is detected by polling. When path is replaced by a new file
+// (rename-and-create, as Docker's json-file driver does), the rest of the old
+// file is read first and the new file is then read from its start. When the file
+// shrinks in place (copytruncate), reading restarts at offset 0. A missing file
+// is waited for.
+func Follow(ctx context.Context, path string, config Config, fn func(line []byte) bool) error {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+
+	file, err := waitOpen(ctx, path, config.PollInterval)
+	if err != nil || file == nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	if !config.FromStart {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("failed to seek to end of %s: %w", path, err)
+		}
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var partial []byte
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			partial = append(partial, line...)
+		}
+
+		switch {
+		case err == nil:
+			// Complete line: drop the newline (and a preceding CR)
+			line := partial[:len(partial)-1]
+			if n := len(line); n > 0 && line[n-1] == '\r' {
+				line = line[:n-1]
+			}
+			if !fn(line) {
+				return nil
+			}
+			partial = partial[:0]
+			continue
+		case errors.Is(err, bufio.ErrBufferFull):
+			if len(partial) >= maxLineSize {
+				if !fn(partial) {
+					return nil
+				}
+				partial = partial[:0]
+			}
+			continue
+		case !errors.Is(err, io.EOF):
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		// At EOF: wait, then check whether the file was rotated or truncated
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(config.PollInterval):
+		}
+
+		rotated, truncated := checkFile(file, path)
+		switch {
+		case rotated:
+			// Finish the old file before switching; data written to it after
+			// the rename is read by the loop above first
+			if n, _ := reader.Peek(1); len(n) > 0 {
+				continue
+			}
+			// An unterminated last line will not be completed any more
+			if len(partial) > 0 && !fn(partial) {
+				return nil
+			}
+			next, err := waitOpen(ctx, path, config.PollInterval)
+			if err != nil || next == nil {
+				return err
+			}
+			_ = file.Close()
+			file = next
+			reader.Reset(file)
+			partial = partial[:0]
+		case truncated:
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to seek to start of %s: %w", path, err)
+			}
+			reader.Reset(file)
+			partial = partial[:0]
+		}
+	}
+}
+
+// waitOpen opens path, waiting for it to exist. It returns nil without an error
+// when ctx is cancelled first.
+func waitOpen(ctx context.Context, path string, interval time.Duration) (*os.File, error) {
+	for {
+		file, err := os.Open(path) // #nosec G304 - path comes from configuration
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkFile reports whether path now names a different file than the open one,
+// or whether the open file is smaller than the current read offset
+func checkFile(file *os.File, path string) (rotated, truncated bool) {
+	current, err := file.Stat()
+	if err != nil {
+		return false, false
+	}
+	if latest, err := os.Stat(path); err == nil && !os.SameFile(current, latest) {
+		return true, false
+	}
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, false
+	}
+	return false, current.Size() < offset
+}
@@ -0,0 +1,169 @@
+package tail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// follower runs Follow in the background and collects lines
+type follower struct {
+	mu    sync.Mutex
+	lines []string
+	done  chan error
+	stop  context.CancelFunc
+}
+
+func startFollow(t *testing.T, path string, config Config) *follower {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &follower{done: make(chan error, 1), stop: cancel}
+	go func() {
+		f.done <- Follow(ctx, path, config, func(line []byte) bool {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.lines = append(f.lines, string(line))
+			return true
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-f.done
+	})
+	return f
+}
+
+func (f *follower) waitLines(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		got := append([]string(nil), f.lines...)
+		f.mu.Unlock()
+		if len(got) >= n {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.Fatalf("Expected %d lines, got %v", n, f.lines)
+	return nil
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFollowStartsAtEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old\n")
+
+	f := startFollow(t, path, Config{PollInterval: 10 * time.Millisecond})
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "new 1\nnew ")
+	appendFile(t, path, "2\r\n")
+
+	if got := f.waitLines(t, 2); !equal(got, []string{"new 1", "new 2"}) {
+		t.Errorf("Expected only lines appended after start, got %v", got)
+	}
+}
+
+func TestFollowFromStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "first\nsecond\n")
+
+	f := startFollow(t, path, Config{FromStart: true, PollInterval: 10 * time.Millisecond})
+
+	if got := f.waitLines(t, 2); !equal(got, []string{"first", "second"}) {
+		t.Errorf("Expected existing lines, got %v", got)
+	}
+}
+
+func TestFollowRenameRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "")
+
+	f := startFollow(t, path, Config{PollInterval: 10 * time.Millisecond})
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "before rotation\n")
+	f.waitLines(t, 1)
+
+	// Lines written just before the rename must not be lost
+	appendFile(t, path, "last in old file\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	appendFile(t, path, "first in new file\n")
+
+	expected := []string{"before rotation", "last in old file", "first in new file"}
+	if got := f.waitLines(t, 3); !equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestFollowTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "")
+
+	f := startFollow(t, path, Config{PollInterval: 10 * time.Millisecond})
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "a fairly long line before truncation\n")
+	f.waitLines(t, 1)
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("Failed to truncate: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "after\n")
+
+	if got := f.waitLines(t, 2); got[1] != "after" {
+		t.Errorf("Expected reading to restart after truncation, got %v", got)
+	}
+}
+
+func TestFollowWaitsForFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "later.log")
+
+	f := startFollow(t, path, Config{FromStart: true, PollInterval: 10 * time.Millisecond})
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "created\n")
+
+	if got := f.waitLines(t, 1); got[0] != "created" {
+		t.Errorf("Expected the line of the created file, got %v", got)
+	}
+
+	f.stop()
+	select {
+	case err := <-f.done:
+		if err != nil {
+			t.Errorf("Expected no error on cancel, got %v", err)
+		}
+		f.done <- err // For the cleanup
+	case <-time.After(time.Second):
+		t.Fatal("Follow did not return after cancel")
+	}
+}
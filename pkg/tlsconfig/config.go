@@ -4,10 +4,14 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
-	"log"
 	"os"
+
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
 )
 
+// logger writes TLS configuration warnings
+var logger = logging.New("tls")
+
 // Config represents TLS configuration options
 type Config struct {
 	// Enable TLS
@@ -45,7 +49,7 @@ func (c *Config) NewTLSConfig() (*tls.Config, error) {
 
 	// Security warning for InsecureSkipVerify
 	if c.InsecureSkipVerify {
-		log.Printf("WARNING: TLS InsecureSkipVerify is enabled. This disables certificate verification and should only be used in development environments!")
+		logger.Printf("WARNING: TLS InsecureSkipVerify is enabled. This disables certificate verification and should only be used in development environments!")
 	}
 
 	tlsConfig := &tls.Config{
@@ -246,7 +250,7 @@ func (c *Config) Validate() error {
 
 	// Security validation for InsecureSkipVerify
 	if c.InsecureSkipVerify {
-		log.Printf("SECURITY WARNING: TLS InsecureSkipVerify is enabled. This disables certificate verification and should NEVER be used in production!")
+		logger.Printf("SECURITY WARNING: TLS InsecureSkipVerify is enabled. This disables certificate verification and should NEVER be used in production!")
 	}
 
 	// Validate CA certificate
@@ -0,0 +1,84 @@
+// Package version holds the build information of the binary. The variables are
+// set at build time with -ldflags, for example:
+//
+//	go build -ldflags "-X github.com/mbiondo/logAnalyzer/pkg/version.Version=v1.4.0 \
+//	  -X github.com/mbiondo/logAnalyzer/pkg/version.Commit=$(git rev-parse HEAD)" ./cmd
+//
+// Unset values fall back to what the Go toolchain recorded in the binary, then
+// to "dev" or "unknown".
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time with -ldflags "-X github.com/mbiondo/logAnalyzer/pkg/version.<Name>=<value>"
+var (
+	Version   = "" // Release version, e.g. v1.4.0 (default: the module version, else "dev")
+	Commit    = "" // Git commit (default: the VCS revision recorded by go build, else "unknown")
+	BuildDate = "" // Build time, e.g. 2025-01-31T12:00:00Z (default: the VCS commit time, else "unknown")
+)
+
+// Info is the build information reported in /status and as loganalyzer_build_info
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information, filling unset values from the build
+// metadata embedded by the Go toolchain
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		settings := make(map[string]string, len(build.Settings))
+		for _, setting := range build.Settings {
+			settings[setting.Key] = setting.Value
+		}
+		if info.Commit == "" && settings["vcs.revision"] != "" {
+			info.Commit = settings["vcs.revision"]
+			if settings["vcs.modified"] == "true" {
+				info.Commit += "-dirty"
+			}
+		}
+		if info.BuildDate == "" {
+			info.BuildDate = settings["vcs.time"]
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// String returns the build information on one line, as printed by -version
+func (i Info) String() string {
+	commit, dirty := strings.CutSuffix(i.Commit, "-dirty")
+	if len(commit) == 40 {
+		commit = commit[:12]
+	}
+	if dirty {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("loganalyzer %s (commit %s, built %s, %s)", i.Version, commit, i.BuildDate, i.GoVersion)
+}
@@ -0,0 +1,37 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGetDefaults(t *testing.T) {
+	// Test binaries carry no module version or VCS information
+	info := Get()
+	if info.Version != "dev" || info.Commit != "unknown" || info.BuildDate != "unknown" {
+		t.Errorf("Expected dev/unknown defaults, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected go_version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+}
+
+func TestGetOverrides(t *testing.T) {
+	defer func(version, commit, date string) {
+		Version, Commit, BuildDate = version, commit, date
+	}(Version, Commit, BuildDate)
+	Version = "v1.4.0"
+	Commit = "0123456789abcdef0123456789abcdef01234567"
+	BuildDate = "2025-01-31T12:00:00Z"
+
+	info := Get()
+	if info.Version != Version || info.Commit != Commit || info.BuildDate != BuildDate {
+		t.Errorf("Expected the build time values, got %+v", info)
+	}
+
+	line := info.String()
+	if !strings.HasPrefix(line, "loganalyzer v1.4.0 (commit 0123456789ab, built 2025-01-31T12:00:00Z, go") {
+		t.Errorf("Unexpected version line: %s", line)
+	}
+}
@@ -0,0 +1,141 @@
+// Package workpool bounds how many long-lived per-source workers (one log stream
+// per container, one tail per file) run at once, sharing the slots round-robin
+// between the sources so that none of them is starved.
+package workpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSlice is how long a source keeps its slot while other sources wait
+const DefaultSlice = 30 * time.Second
+
+// Func works on one source until ctx is done or the source is finished. It returns
+// true when the source is finished (e.g. its container stopped) and must not be
+// scheduled again; after a preemption it returns false and is called again for the
+// same source on its next turn, so it should resume where it left off.
+type Func func(ctx context.Context, source string) (finished bool)
+
+// Pool runs a Func for a set of sources with at most Limit of them at once
+type Pool struct {
+	limit int
+	slice time.Duration
+
+	active  atomic.Int64
+	waiting atomic.Int64
+}
+
+// New creates a pool with limit concurrent workers (0 = one per source) that
+// hands a slot to the next waiting source every slice (DefaultSlice when 0)
+func New(limit int, slice time.Duration) *Pool {
+	if slice <= 0 {
+		slice = DefaultSlice
+	}
+	return &Pool{limit: limit, slice: slice}
+}
+
+// Limit returns the maximum number of concurrent workers (0 = one per source)
+func (p *Pool) Limit() int {
+	return p.limit
+}
+
+// Active returns the number of sources currently being worked on
+func (p *Pool) Active() int {
+	return int(p.active.Load())
+}
+
+// Waiting returns the number of sources queued for a slot
+func (p *Pool) Waiting() int {
+	return int(p.waiting.Load())
+}
+
+// Run calls fn for every source and blocks until all of them are finished or ctx
+// is done. When there are more sources than slots, the sources take turns in
+// FIFO order: a source gives up its slot after a slice only if another one is
+// waiting, so with no contention streams are never interrupted.
+func (p *Pool) Run(ctx context.Context, sources []string, fn Func) {
+	if len(sources) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan string, len(sources)) // Holds every source, so requeueing never blocks
+	for _, source := range sources {
+		queue <- source
+	}
+	p.waiting.Store(int64(len(sources)))
+
+	workers := len(sources)
+	if p.limit > 0 && p.limit < workers {
+		workers = p.limit
+	}
+
+	var remaining atomic.Int64
+	remaining.Store(int64(len(sources)))
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var source string
+				select {
+				case source = <-queue:
+				case <-ctx.Done():
+					return
+				}
+				p.waiting.Add(-1)
+
+				p.active.Add(1)
+				finished := p.runTurn(ctx, queue, source, fn)
+				p.active.Add(-1)
+
+				switch {
+				case finished:
+					if remaining.Add(-1) == 0 {
+						cancel() // Nothing left to schedule
+					}
+				case ctx.Err() == nil:
+					p.waiting.Add(1)
+					queue <- source
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	p.waiting.Store(0)
+}
+
+// runTurn runs fn for one turn, preempting it once its slice is over and another
+// source is waiting for a slot
+func (p *Pool) runTurn(ctx context.Context, queue chan string, source string, fn Func) bool {
+	turnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(p.slice)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if len(queue) > 0 {
+					cancel()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// A turn cut short by the slice is not finished, whatever fn reports
+	return fn(turnCtx, source) && turnCtx.Err() == nil
+}
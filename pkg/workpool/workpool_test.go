@@ -0,0 +1,135 @@
+package workpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder tracks the turns taken by each source and the peak concurrency
+type recorder struct {
+	mu      sync.Mutex
+	starts  []string
+	running int
+	peak    int
+}
+
+func (r *recorder) begin(source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.starts = append(r.starts, source)
+	r.running++
+	if r.running > r.peak {
+		r.peak = r.running
+	}
+}
+
+func (r *recorder) end() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running--
+}
+
+func (r *recorder) turns() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	turns := make(map[string]int)
+	for _, source := range r.starts {
+		turns[source]++
+	}
+	return turns
+}
+
+// streamUntilPreempted behaves like a log stream that never ends on its own
+func (r *recorder) streamUntilPreempted(ctx context.Context, source string) bool {
+	r.begin(source)
+	defer r.end()
+	<-ctx.Done()
+	return false
+}
+
+func TestPoolUnlimitedRunsEverySource(t *testing.T) {
+	pool := New(0, 10*time.Millisecond)
+	rec := &recorder{}
+	sources := []string{"a", "b", "c", "d"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	pool.Run(ctx, sources, rec.streamUntilPreempted)
+
+	if rec.peak != len(sources) {
+		t.Errorf("Expected %d concurrent workers, got %d", len(sources), rec.peak)
+	}
+	// Nobody waits, so nobody is preempted
+	for _, source := range sources {
+		if turns := rec.turns()[source]; turns != 1 {
+			t.Errorf("Expected 1 turn for %s, got %d", source, turns)
+		}
+	}
+}
+
+func TestPoolLimitedRoundRobin(t *testing.T) {
+	pool := New(2, 20*time.Millisecond)
+	rec := &recorder{}
+	var sources []string
+	for i := range 5 {
+		sources = append(sources, fmt.Sprintf("container-%d", i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if active, waiting := pool.Active(), pool.Waiting(); active != 2 || waiting != 3 {
+			t.Errorf("Expected 2 active and 3 waiting sources, got %d and %d", active, waiting)
+		}
+	}()
+	pool.Run(ctx, sources, rec.streamUntilPreempted)
+
+	if rec.peak > 2 {
+		t.Errorf("Expected at most 2 concurrent workers, got %d", rec.peak)
+	}
+
+	// Every source gets a turn before any source gets a second one
+	first := make(map[string]bool)
+	for _, source := range rec.starts[:len(sources)] {
+		first[source] = true
+	}
+	if len(first) != len(sources) {
+		t.Errorf("Expected each source once in the first round, got %v", rec.starts[:len(sources)])
+	}
+	for _, source := range sources {
+		if turns := rec.turns()[source]; turns < 2 {
+			t.Errorf("Expected %s to get several turns, got %d", source, turns)
+		}
+	}
+}
+
+func TestPoolFinishedSources(t *testing.T) {
+	pool := New(1, time.Hour)
+	rec := &recorder{}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(context.Background(), []string{"a", "b", "c"}, func(ctx context.Context, source string) bool {
+			rec.begin(source)
+			defer rec.end()
+			return true // Stream ended on its own
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return once every source finished")
+	}
+	if len(rec.starts) != 3 {
+		t.Errorf("Expected one turn per source, got %v", rec.starts)
+	}
+	if pool.Active() != 0 || pool.Waiting() != 0 {
+		t.Errorf("Expected no active or waiting sources, got %d and %d", pool.Active(), pool.Waiting())
+	}
+}
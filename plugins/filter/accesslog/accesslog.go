@@ -0,0 +1,333 @@
+package accesslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterFilterPlugin("accesslog", NewAccessLogFilterFromConfig)
+}
+
+// Predefined formats, written as nginx log_format patterns
+const (
+	FormatCommon   = "common"
+	FormatCombined = "combined"
+
+	commonPattern   = `$remote_addr $ident $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	combinedPattern = commonPattern + ` "$http_referer" "$http_user_agent"`
+)
+
+// metadataKeys renames variables whose nginx names are not obvious
+var metadataKeys = map[string]string{
+	"http_referer":    "referrer",
+	"http_user_agent": "user_agent",
+	"body_bytes_sent": "bytes",
+}
+
+// Config represents access log filter configuration
+type Config struct {
+	Format       string `yaml:"format,omitempty"`         // "common", "combined" (default) or a pattern with $variables
+	Field        string `yaml:"field,omitempty"`          // Field to parse: "message" (default) or a metadata key
+	SetLevel     bool   `yaml:"set_level,omitempty"`      // Set the level from the status: 5xx error, 4xx warn, others info
+	Timestamp    bool   `yaml:"timestamp,omitempty"`      // Use $time_local or $time_iso8601 as the log timestamp
+	OnParseError string `yaml:"on_parse_error,omitempty"` // pass_raw, tag or drop; unset keeps lines that do not match unchanged
+}
+
+// NewAccessLogFilterFromConfig creates an access log filter from configuration map
+func NewAccessLogFilterFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewAccessLogFilter(cfg)
+}
+
+// token is a literal part of a pattern or, when variable is set, a field
+type token struct {
+	literal  string
+	variable string
+	quoted   bool // The field is enclosed in double quotes and may contain spaces
+}
+
+// AccessLogFilter parses web server access log lines into metadata. Fields equal
+// to "-", the access log convention for an empty value, are left out, except
+// the body size, where "-" means zero bytes.
+type AccessLogFilter struct {
+	config Config
+	tokens []token
+}
+
+// NewAccessLogFilter creates a new access log filter
+func NewAccessLogFilter(config Config) (*AccessLogFilter, error) {
+	if config.Format == "" {
+		config.Format = FormatCombined
+	}
+	if config.Field == "" {
+		config.Field = "message"
+	}
+	if err := core.ValidateParseErrorMode(config.OnParseError); err != nil {
+		return nil, fmt.Errorf("accesslog filter: %w", err)
+	}
+
+	pattern := config.Format
+	switch config.Format {
+	case FormatCommon:
+		pattern = commonPattern
+	case FormatCombined:
+		pattern = combinedPattern
+	}
+	tokens, err := compilePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("accesslog filter: invalid format %q: %w", config.Format, err)
+	}
+
+	return &AccessLogFilter{config: config, tokens: tokens}, nil
+}
+
+// compilePattern splits a pattern into literals and $variables
+func compilePattern(pattern string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(pattern); {
+		if pattern[i] != '$' {
+			end := strings.IndexByte(pattern[i:], '$')
+			if end < 0 {
+				end = len(pattern) - i
+			}
+			tokens = append(tokens, token{literal: pattern[i : i+end]})
+			i += end
+			continue
+		}
+
+		end := i + 1
+		for end < len(pattern) && isVariableChar(pattern[end]) {
+			end++
+		}
+		if end == i+1 {
+			return nil, fmt.Errorf("'$' at offset %d is not followed by a variable name", i)
+		}
+		if len(tokens) > 0 && tokens[len(tokens)-1].variable != "" {
+			return nil, fmt.Errorf("$%s must be separated from $%s by literal text", pattern[i+1:end], tokens[len(tokens)-1].variable)
+		}
+		tokens = append(tokens, token{variable: pattern[i+1 : end]})
+		i = end
+	}
+
+	hasVariable := false
+	for i := range tokens {
+		if tokens[i].variable == "" {
+			continue
+		}
+		hasVariable = true
+		tokens[i].quoted = i > 0 && strings.HasSuffix(tokens[i-1].literal, `"`) &&
+			i+1 < len(tokens) && strings.HasPrefix(tokens[i+1].literal, `"`)
+	}
+	if !hasVariable {
+		return nil, fmt.Errorf("no $variables")
+	}
+	return tokens, nil
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Cost implements core.CostedFilter; the line is scanned once without regular expressions
+func (f *AccessLogFilter) Cost() int {
+	return core.FilterCostModerate
+}
+
+// Mutates implements core.MutatingFilter; parsed fields are added to the log metadata
+func (f *AccessLogFilter) Mutates() bool {
+	return true
+}
+
+// Process parses the configured field and adds the access log fields to metadata
+func (f *AccessLogFilter) Process(log *core.Log) bool {
+	line := log.Message
+	if f.config.Field != "message" {
+		value, ok := log.Metadata[f.config.Field]
+		if !ok {
+			return true // Field not found, pass through
+		}
+		line = value
+	}
+
+	fields, err := f.parse(line)
+	if err != nil {
+		if f.config.OnParseError == "" {
+			return true
+		}
+		return core.HandleParseError(f.config.OnParseError, log, err.Error()) != nil
+	}
+
+	if log.Metadata == nil {
+		log.Metadata = make(map[string]string, len(fields))
+	}
+	for key, value := range fields {
+		log.Metadata[key] = value
+	}
+
+	if f.config.SetLevel {
+		if status, ok := fields["status"]; ok {
+			log.Level = levelForStatus(status)
+		}
+	}
+	if f.config.Timestamp {
+		if timestamp, ok := parseTimestamp(fields); ok {
+			log.Timestamp = timestamp
+		}
+	}
+	return true
+}
+
+// parse matches a line against the pattern and returns its fields by metadata key
+func (f *AccessLogFilter) parse(line string) (map[string]string, error) {
+	fields := make(map[string]string, len(f.tokens))
+	pos := 0
+	for i, tok := range f.tokens {
+		if tok.variable == "" {
+			if !strings.HasPrefix(line[pos:], tok.literal) {
+				return nil, fmt.Errorf("expected %q at offset %d", tok.literal, pos)
+			}
+			pos += len(tok.literal)
+			continue
+		}
+
+		var value string
+		switch {
+		case tok.quoted:
+			end := closingQuote(line, pos)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted $%s", tok.variable)
+			}
+			value = unescape(line[pos:end])
+			pos = end
+		case i+1 < len(f.tokens):
+			end := strings.Index(line[pos:], f.tokens[i+1].literal)
+			if end < 0 {
+				return nil, fmt.Errorf("expected %q after $%s", f.tokens[i+1].literal, tok.variable)
+			}
+			value = line[pos : pos+end]
+			pos += end
+		default:
+			// The last field ends at the first space, so lines with extra
+			// trailing fields (e.g. combined lines read as common) still match
+			end := strings.IndexByte(line[pos:], ' ')
+			if end < 0 {
+				end = len(line) - pos
+			}
+			value = line[pos : pos+end]
+			pos += end
+		}
+
+		if err := addField(fields, tok.variable, value); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// closingQuote returns the offset of the first double quote from pos that is not
+// escaped with a backslash, or -1
+func closingQuote(line string, pos int) int {
+	for i := pos; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unescape reverses the \" and \\ escaping servers apply inside quoted fields
+func unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value)
+}
+
+// addField stores a variable under its metadata key, splitting $request into
+// method, path and protocol
+func addField(fields map[string]string, variable, value string) error {
+	key := variable
+	if renamed, ok := metadataKeys[variable]; ok {
+		key = renamed
+	}
+
+	switch key {
+	case "status":
+		if _, err := strconv.Atoi(value); err != nil || len(value) != 3 {
+			return fmt.Errorf("invalid status %q", value)
+		}
+	case "bytes":
+		if value == "-" {
+			value = "0"
+		}
+	case "request":
+		if method, path, protocol, ok := splitRequest(value); ok {
+			fields["method"] = method
+			fields["path"] = path
+			if protocol != "" {
+				fields["protocol"] = protocol
+			}
+			return nil
+		}
+	}
+
+	if value != "-" && value != "" {
+		fields[key] = value
+	}
+	return nil
+}
+
+// splitRequest splits a request line such as "GET /index.html HTTP/1.1". The
+// protocol is missing in HTTP/0.9 requests; a path with unencoded spaces is kept
+// whole. It returns false for lines that are not requests, such as "-".
+func splitRequest(request string) (method, path, protocol string, ok bool) {
+	parts := strings.Fields(request)
+	if len(parts) < 2 {
+		return "", "", "", false
+	}
+	method, parts = parts[0], parts[1:]
+	if last := parts[len(parts)-1]; len(parts) > 1 && strings.HasPrefix(last, "HTTP/") {
+		protocol, parts = last, parts[:len(parts)-1]
+	}
+	return method, strings.Join(parts, " "), protocol, true
+}
+
+// levelForStatus maps an HTTP status to a level of the vocabulary
+func levelForStatus(status string) string {
+	switch status[0] {
+	case '5':
+		return core.Levels().Normalize("error")
+	case '4':
+		return core.Levels().Normalize("warn")
+	default:
+		return core.Levels().Normalize("info")
+	}
+}
+
+// parseTimestamp reads the request time from $time_local or $time_iso8601
+func parseTimestamp(fields map[string]string) (time.Time, bool) {
+	if value, ok := fields["time_local"]; ok {
+		if timestamp, err := time.Parse("02/Jan/2006:15:04:05 -0700", value); err == nil {
+			return timestamp, true
+		}
+	}
+	if value, ok := fields["time_iso8601"]; ok {
+		if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+			return timestamp, true
+		}
+	}
+	return time.Time{}, false
+}
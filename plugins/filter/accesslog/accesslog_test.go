@@ -0,0 +1,238 @@
+package accesslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func TestAccessLogFilter_Process(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		line         string
+		expectedMeta map[string]string
+	}{
+		{
+			name:   "combined",
+			config: Config{},
+			line:   `203.0.113.7 - alice [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			expectedMeta: map[string]string{
+				"remote_addr": "203.0.113.7",
+				"remote_user": "alice",
+				"time_local":  "10/Oct/2000:13:55:36 -0700",
+				"method":      "GET",
+				"path":        "/apache_pb.gif",
+				"protocol":    "HTTP/1.0",
+				"status":      "200",
+				"bytes":       "2326",
+				"referrer":    "http://www.example.com/start.html",
+				"user_agent":  "Mozilla/4.08 [en] (Win98; I ;Nav)",
+			},
+		},
+		{
+			name:   "empty fields",
+			config: Config{},
+			line:   `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "-" 408 - "-" "-"`,
+			expectedMeta: map[string]string{
+				"remote_addr": "10.0.0.1",
+				"time_local":  "10/Oct/2000:13:55:36 -0700",
+				"status":      "408",
+				"bytes":       "0",
+			},
+		},
+		{
+			name:   "escaped quotes and spaces in quoted fields",
+			config: Config{},
+			line:   `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /search?q=a b HTTP/1.1" 404 12 "-" "curl \"custom\" \\ agent"`,
+			expectedMeta: map[string]string{
+				"remote_addr": "10.0.0.1",
+				"time_local":  "10/Oct/2000:13:55:36 -0700",
+				"method":      "GET",
+				"path":        "/search?q=a b",
+				"protocol":    "HTTP/1.1",
+				"status":      "404",
+				"bytes":       "12",
+				"user_agent":  `curl "custom" \ agent`,
+			},
+		},
+		{
+			name:   "common reads combined lines",
+			config: Config{Format: FormatCommon},
+			line:   `::1 - - [10/Oct/2000:13:55:36 -0700] "POST /login HTTP/2.0" 302 0 "-" "Mozilla/5.0"`,
+			expectedMeta: map[string]string{
+				"remote_addr": "::1",
+				"time_local":  "10/Oct/2000:13:55:36 -0700",
+				"method":      "POST",
+				"path":        "/login",
+				"protocol":    "HTTP/2.0",
+				"status":      "302",
+				"bytes":       "0",
+			},
+		},
+		{
+			name:   "request that is not a request line",
+			config: Config{Format: FormatCommon},
+			line:   `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "\x16\x03\x01" 400 157`,
+			expectedMeta: map[string]string{
+				"remote_addr": "10.0.0.1",
+				"time_local":  "10/Oct/2000:13:55:36 -0700",
+				"request":     `\x16\x03\x01`,
+				"status":      "400",
+				"bytes":       "157",
+			},
+		},
+		{
+			name:   "custom pattern",
+			config: Config{Format: `$remote_addr [$time_iso8601] "$request" $status $request_time "$http_x_forwarded_for"`},
+			line:   `10.0.0.1 [2024-05-01T12:00:00+00:00] "DELETE /items/7 HTTP/1.1" 503 0.250 "198.51.100.1, 10.0.0.2"`,
+			expectedMeta: map[string]string{
+				"remote_addr":          "10.0.0.1",
+				"time_iso8601":         "2024-05-01T12:00:00+00:00",
+				"method":               "DELETE",
+				"path":                 "/items/7",
+				"protocol":             "HTTP/1.1",
+				"status":               "503",
+				"request_time":         "0.250",
+				"http_x_forwarded_for": "198.51.100.1, 10.0.0.2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		filter, err := NewAccessLogFilter(tt.config)
+		if err != nil {
+			t.Fatalf("%s: failed to create filter: %v", tt.name, err)
+		}
+		log := core.NewLog("info", tt.line)
+		if !filter.Process(log) {
+			t.Errorf("%s: expected the log to be kept", tt.name)
+		}
+		if !reflect.DeepEqual(log.Metadata, tt.expectedMeta) {
+			t.Errorf("%s: expected metadata %v, got %v", tt.name, tt.expectedMeta, log.Metadata)
+		}
+	}
+}
+
+func TestAccessLogFilter_SetLevel(t *testing.T) {
+	filter, err := NewAccessLogFilter(Config{Format: FormatCommon, SetLevel: true})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		status   string
+		expected string
+	}{
+		{"200", "info"},
+		{"301", "info"},
+		{"404", "warn"},
+		{"500", "error"},
+	}
+
+	for _, tt := range tests {
+		// The level detected from "error" in the path is replaced
+		log := core.NewLog("error", `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /error.html HTTP/1.1" `+tt.status+` 512`)
+		filter.Process(log)
+		if log.Level != tt.expected {
+			t.Errorf("Status %s: expected level %s, got %s", tt.status, tt.expected, log.Level)
+		}
+	}
+}
+
+func TestAccessLogFilter_Timestamp(t *testing.T) {
+	filter, err := NewAccessLogFilter(Config{Format: FormatCommon, Timestamp: true})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	log := core.NewLog("info", `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 512`)
+	filter.Process(log)
+	expected := time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC)
+	if !log.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %s, got %s", expected, log.Timestamp)
+	}
+}
+
+func TestAccessLogFilter_ParseErrors(t *testing.T) {
+	lines := []string{
+		"plain application log",
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1 200 512`, // Unterminated quote
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" OK 512`, // Invalid status
+	}
+
+	tests := []struct {
+		mode     string
+		kept     bool
+		metadata bool
+	}{
+		{"", true, false},
+		{core.ParseErrorPassRaw, true, true},
+		{core.ParseErrorTag, true, true},
+		{core.ParseErrorDrop, false, false},
+	}
+
+	for _, tt := range tests {
+		filter, err := NewAccessLogFilter(Config{OnParseError: tt.mode})
+		if err != nil {
+			t.Fatalf("Failed to create filter: %v", err)
+		}
+		for _, line := range lines {
+			log := core.NewLog("info", line)
+			if kept := filter.Process(log); kept != tt.kept {
+				t.Errorf("Mode %q, line %q: expected kept %t, got %t", tt.mode, line, tt.kept, kept)
+			}
+			if (log.Metadata[core.ParseErrorKey] == "true") != tt.metadata {
+				t.Errorf("Mode %q, line %q: unexpected metadata %v", tt.mode, line, log.Metadata)
+			}
+			if log.Message != line {
+				t.Errorf("Mode %q: expected the raw line to be kept, got %q", tt.mode, log.Message)
+			}
+		}
+	}
+}
+
+func TestAccessLogFilter_MetadataField(t *testing.T) {
+	filter, err := NewAccessLogFilter(Config{Format: FormatCommon, Field: "raw"})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	log := core.NewLog("info", "request served")
+	log.Metadata["raw"] = `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 512`
+	filter.Process(log)
+	if log.Metadata["status"] != "200" || log.Metadata["path"] != "/" {
+		t.Errorf("Expected the metadata field to be parsed, got %v", log.Metadata)
+	}
+
+	// Logs without the field pass through untouched
+	other := core.NewLog("info", `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 512`)
+	filter.Process(other)
+	if len(other.Metadata) != 0 {
+		t.Errorf("Expected no metadata, got %v", other.Metadata)
+	}
+}
+
+func TestNewAccessLogFilter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"no variables", Config{Format: "just text"}},
+		{"adjacent variables", Config{Format: "$remote_addr$status"}},
+		{"dangling dollar", Config{Format: "$remote_addr $"}},
+		{"invalid on_parse_error", Config{OnParseError: "ignore"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewAccessLogFilter(tt.config); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	if _, err := NewAccessLogFilterFromConfig(map[string]any{"format": "combined", "set_level": true}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
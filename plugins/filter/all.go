@@ -1,8 +1,14 @@
 package filter
 
 import (
+	_ "github.com/mbiondo/logAnalyzer/plugins/filter/accesslog"
+	_ "github.com/mbiondo/logAnalyzer/plugins/filter/burst"
 	_ "github.com/mbiondo/logAnalyzer/plugins/filter/json"
 	_ "github.com/mbiondo/logAnalyzer/plugins/filter/level"
+	_ "github.com/mbiondo/logAnalyzer/plugins/filter/lookup"
 	_ "github.com/mbiondo/logAnalyzer/plugins/filter/rate_limit"
 	_ "github.com/mbiondo/logAnalyzer/plugins/filter/regex"
+	_ "github.com/mbiondo/logAnalyzer/plugins/filter/sample"
+	_ "github.com/mbiondo/logAnalyzer/plugins/filter/sanitize"
+	_ "github.com/mbiondo/logAnalyzer/plugins/filter/trace_context"
 )
@@ -0,0 +1,166 @@
+package burst
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterFilterPlugin("burst", NewBurstFilterFromConfig)
+}
+
+const (
+	defaultWindow    = time.Minute
+	defaultMaxGroups = 10000
+)
+
+// Config represents burst filter configuration
+type Config struct {
+	FirstN    int           `yaml:"first_n"`              // Logs passed per group and window before sampling starts
+	ThenRate  float64       `yaml:"then_rate"`            // Fraction of the remaining logs kept, in [0, 1]
+	Window    time.Duration `yaml:"window,omitempty"`     // How long a group's count lasts before it resets (default: 1m)
+	GroupBy   logkey.Spec   `yaml:"group_by,omitempty"`   // Key spec grouping the logs (default: message)
+	MaxGroups int           `yaml:"max_groups,omitempty"` // Groups tracked at once; the oldest is forgotten first (default: 10000)
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.FirstN < 0 {
+		return fmt.Errorf("first_n must be non-negative, got %d", c.FirstN)
+	}
+	if c.ThenRate < 0 || c.ThenRate > 1 {
+		return fmt.Errorf("then_rate must be between 0 and 1, got %v", c.ThenRate)
+	}
+	if c.Window < 0 {
+		return fmt.Errorf("window must be non-negative")
+	}
+	if c.MaxGroups < 0 {
+		return fmt.Errorf("max_groups must be non-negative")
+	}
+	if c.Window == 0 {
+		c.Window = defaultWindow
+	}
+	if c.MaxGroups == 0 {
+		c.MaxGroups = defaultMaxGroups
+	}
+	if len(c.GroupBy) == 0 {
+		c.GroupBy = logkey.Sources("message")
+	}
+	if _, err := logkey.Compile(c.GroupBy); err != nil {
+		return fmt.Errorf("invalid group_by: %w", err)
+	}
+	return nil
+}
+
+// NewBurstFilterFromConfig creates a burst filter from configuration map
+func NewBurstFilterFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewBurstFilter(cfg)
+}
+
+// group tracks one group_by combination within its current window
+type group struct {
+	key   string
+	start time.Time // When the current window started
+	count int       // Logs seen in the current window
+}
+
+// BurstFilter passes the first N logs of each group within a window and keeps
+// a fraction of the rest. Sampling counts the logs past N, so a rate of 0.1
+// keeps the 10th, 20th, ... extra log: decisions are deterministic and evenly
+// spread. A group's count resets once its window has passed.
+type BurstFilter struct {
+	config Config
+	key    *logkey.Key // Computes the group of a log
+	now    func() time.Time
+	groups map[string]*list.Element
+	order  *list.List // Groups by window start, oldest first
+	mu     sync.Mutex
+}
+
+// NewBurstFilter creates a new burst filter
+func NewBurstFilter(cfg Config) (*BurstFilter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &BurstFilter{
+		config: cfg,
+		key:    logkey.MustCompile(cfg.GroupBy),
+		now:    time.Now,
+		groups: make(map[string]*list.Element),
+		order:  list.New(),
+	}, nil
+}
+
+// DropReason implements core.DropReasoner so thinned-out logs are counted separately
+func (f *BurstFilter) DropReason() string {
+	return "burst"
+}
+
+// Mutates implements core.MutatingFilter; the log itself is never modified
+func (f *BurstFilter) Mutates() bool {
+	return false
+}
+
+// Preservable implements core.PreservableFilter; logs matching preserve_if are never thinned out
+func (f *BurstFilter) Preservable() bool {
+	return true
+}
+
+// Process passes the first N logs of a group per window, then samples the rest
+func (f *BurstFilter) Process(log *core.Log) bool {
+	key := f.key.Of(log)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	f.expire(now)
+
+	var g *group
+	if element, ok := f.groups[key]; ok {
+		g = element.Value.(*group)
+	} else {
+		if len(f.groups) >= f.config.MaxGroups {
+			f.remove(f.order.Front())
+		}
+		g = &group{key: key, start: now}
+		f.groups[key] = f.order.PushBack(g)
+	}
+
+	g.count++
+	if g.count <= f.config.FirstN {
+		return true
+	}
+
+	// Keep the extra log whenever the kept total (extra * rate) reaches a new whole number
+	extra := float64(g.count - f.config.FirstN)
+	return int(extra*f.config.ThenRate) > int((extra-1)*f.config.ThenRate)
+}
+
+// expire forgets groups whose window has passed, so their next log starts a new window
+func (f *BurstFilter) expire(now time.Time) {
+	for element := f.order.Front(); element != nil; element = f.order.Front() {
+		if now.Sub(element.Value.(*group).start) < f.config.Window {
+			return
+		}
+		f.remove(element)
+	}
+}
+
+// remove forgets a group
+func (f *BurstFilter) remove(element *list.Element) {
+	delete(f.groups, element.Value.(*group).key)
+	f.order.Remove(element)
+}
@@ -0,0 +1,140 @@
+package burst
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+// testFilter returns a filter whose clock is advanced by the returned function
+func testFilter(t *testing.T, cfg Config) (*BurstFilter, func(time.Duration)) {
+	t.Helper()
+
+	filter, err := NewBurstFilter(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter.now = func() time.Time { return now }
+	return filter, func(d time.Duration) { now = now.Add(d) }
+}
+
+// kept counts how many of n identical logs pass the filter
+func kept(filter *BurstFilter, n int, message string) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if filter.Process(core.NewLog("info", message)) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestBurstFilterConfigValidation(t *testing.T) {
+	invalid := []Config{
+		{FirstN: -1},
+		{FirstN: 5, ThenRate: -0.1},
+		{FirstN: 5, ThenRate: 1.5},
+		{FirstN: 5, Window: -time.Second},
+		{FirstN: 5, MaxGroups: -1},
+		{FirstN: 5, GroupBy: logkey.Sources("")},
+	}
+	for i, cfg := range invalid {
+		if _, err := NewBurstFilter(cfg); err == nil {
+			t.Errorf("Expected error for case %d", i)
+		}
+	}
+
+	filter, err := NewBurstFilterFromConfig(map[string]any{"first_n": 5, "then_rate": 0.1, "window": "30s"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := filter.(*BurstFilter).config
+	if config.Window != 30*time.Second || config.MaxGroups != defaultMaxGroups || len(config.GroupBy) != 1 || config.GroupBy[0].Source != "message" {
+		t.Errorf("Expected defaults to be applied, got %+v", config)
+	}
+}
+
+func TestBurstFilterFirstNThenSample(t *testing.T) {
+	tests := []struct {
+		name     string
+		firstN   int
+		thenRate float64
+		logs     int
+		expected int
+	}{
+		{"first n only", 5, 0, 100, 5},
+		{"then sample", 5, 0.1, 105, 15},
+		{"keep all", 5, 1, 50, 50},
+		{"sample only", 0, 0.5, 10, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, _ := testFilter(t, Config{FirstN: tt.firstN, ThenRate: tt.thenRate})
+			if got := kept(filter, tt.logs, "init"); got != tt.expected {
+				t.Errorf("Expected %d logs kept, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBurstFilterGroups(t *testing.T) {
+	filter, _ := testFilter(t, Config{FirstN: 2, GroupBy: logkey.Sources("level", "service")})
+
+	logFor := func(level, service string) *core.Log {
+		return core.NewLogWithMetadata(level, "starting", map[string]string{"service": service})
+	}
+
+	for _, logEntry := range []*core.Log{logFor("info", "api"), logFor("info", "api"), logFor("info", "db"), logFor("error", "api")} {
+		if !filter.Process(logEntry) {
+			t.Errorf("Expected the first logs of each group to pass, dropped %s/%s", logEntry.Level, logEntry.Metadata["service"])
+		}
+	}
+	if filter.Process(logFor("info", "api")) {
+		t.Error("Expected the third info/api log to be dropped")
+	}
+}
+
+func TestBurstFilterWindowReset(t *testing.T) {
+	filter, advance := testFilter(t, Config{FirstN: 3, Window: time.Minute})
+
+	if got := kept(filter, 10, "init"); got != 3 {
+		t.Errorf("Expected 3 logs kept in the first window, got %d", got)
+	}
+
+	advance(59 * time.Second)
+	if got := kept(filter, 5, "init"); got != 0 {
+		t.Errorf("Expected no logs kept before the window ends, got %d", got)
+	}
+
+	advance(time.Second)
+	if got := kept(filter, 10, "init"); got != 3 {
+		t.Errorf("Expected the count to reset after the window, got %d kept", got)
+	}
+	if len(filter.groups) != 1 || filter.order.Len() != 1 {
+		t.Errorf("Expected a single tracked group, got %d", len(filter.groups))
+	}
+}
+
+func TestBurstFilterMaxGroups(t *testing.T) {
+	filter, _ := testFilter(t, Config{FirstN: 1, MaxGroups: 10})
+
+	for i := 0; i < 100; i++ {
+		filter.Process(core.NewLog("info", fmt.Sprintf("message %d", i)))
+	}
+	if len(filter.groups) != 10 || filter.order.Len() != 10 {
+		t.Errorf("Expected state bounded to 10 groups, got %d", len(filter.groups))
+	}
+
+	// The oldest groups were forgotten, so they start over
+	if !filter.Process(core.NewLog("info", "message 0")) {
+		t.Error("Expected an evicted group to pass again")
+	}
+	if filter.Process(core.NewLog("info", "message 99")) {
+		t.Error("Expected a tracked group to be dropped")
+	}
+}
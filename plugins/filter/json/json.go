@@ -45,6 +45,11 @@ func NewJsonFilter(config Config) *JsonFilter {
 	}
 }
 
+// Mutates implements core.MutatingFilter; parsed fields are added to the log metadata
+func (f *JsonFilter) Mutates() bool {
+	return true
+}
+
 // Process parses JSON from the specified field and adds parsed fields to metadata
 func (f *JsonFilter) Process(log *core.Log) bool {
 	// Get data from specified field
@@ -1,7 +1,10 @@
 package level
 
 import (
+	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
 )
@@ -13,7 +16,8 @@ func init() {
 
 // Config represents level filter configuration
 type Config struct {
-	Levels []string `yaml:"levels"`
+	Levels   []string `yaml:"levels"`              // Explicit levels to keep (aliases are resolved)
+	MinLevel string   `yaml:"min_level,omitempty"` // Keep logs at or above this severity
 }
 
 // NewLevelFilterFromConfig creates a level filter from configuration map
@@ -23,26 +27,85 @@ func NewLevelFilterFromConfig(config map[string]any) (any, error) {
 		return nil, err
 	}
 
-	return NewLevelFilter(cfg.Levels), nil
+	filter := NewLevelFilter(cfg.Levels)
+	if cfg.MinLevel != "" {
+		levels := core.Levels()
+		if !levels.Known(cfg.MinLevel) {
+			return nil, fmt.Errorf("min_level %q is not a known level (known: %s)", cfg.MinLevel, strings.Join(levels.Levels(), ", "))
+		}
+		filter.minLevel = levels.Normalize(cfg.MinLevel)
+	}
+
+	return filter, nil
 }
 
 // LevelFilter filters logs by level
 type LevelFilter struct {
 	allowedLevels map[string]bool
+	minLevel      string
+
+	override atomic.Pointer[levelOverride] // Temporary threshold set through the API
+}
+
+// levelOverride is a temporary min_level that expires on its own
+type levelOverride struct {
+	minLevel string
+	until    time.Time
 }
 
 // NewLevelFilter creates a new level filter
 func NewLevelFilter(levels []string) *LevelFilter {
+	vocabulary := core.Levels()
 	allowed := make(map[string]bool)
 	for _, level := range levels {
-		allowed[strings.ToLower(level)] = true
+		allowed[vocabulary.Normalize(level)] = true
 	}
 	return &LevelFilter{
 		allowedLevels: allowed,
 	}
 }
 
-// Process determines if a log should be kept based on its level
+// Cost implements core.CostedFilter; level checks are a map lookup
+func (f *LevelFilter) Cost() int {
+	return core.FilterCostCheap
+}
+
+// Process determines if a log should be kept based on its level.
+// A log is kept when its level is listed or is at least min_level.
 func (f *LevelFilter) Process(log *core.Log) bool {
-	return f.allowedLevels[strings.ToLower(log.Level)]
+	levels := core.Levels()
+	if f.allowedLevels[levels.Normalize(log.Level)] {
+		return true
+	}
+	if f.minLevel != "" && levels.AtLeast(log.Level, f.minLevel) {
+		return true
+	}
+	return f.overrideAllows(log.Level)
+}
+
+// overrideAllows reports whether an unexpired override keeps the level. Expiry is
+// checked on every log, so the override reverts on time even if nobody clears it.
+func (f *LevelFilter) overrideAllows(level string) bool {
+	o := f.override.Load()
+	return o != nil && time.Now().Before(o.until) && core.Levels().AtLeast(level, o.minLevel)
+}
+
+// SetLevelOverride implements core.LevelOverrider. Until the deadline the filter
+// also keeps logs at or above minLevel; logs it already keeps are unaffected.
+func (f *LevelFilter) SetLevelOverride(minLevel string, until time.Time) {
+	f.override.Store(&levelOverride{minLevel: core.Levels().Normalize(minLevel), until: until})
+}
+
+// ClearLevelOverride implements core.LevelOverrider
+func (f *LevelFilter) ClearLevelOverride() {
+	f.override.Store(nil)
+}
+
+// LevelOverride implements core.LevelOverrider
+func (f *LevelFilter) LevelOverride() (string, time.Time, bool) {
+	o := f.override.Load()
+	if o == nil || !time.Now().Before(o.until) {
+		return "", time.Time{}, false
+	}
+	return o.minLevel, o.until, true
 }
@@ -2,6 +2,7 @@ package level
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
 )
@@ -109,3 +110,117 @@ func TestLevelFilterCaseSensitivity(t *testing.T) {
 		t.Error("Mixed case 'Error' should be allowed")
 	}
 }
+
+func TestLevelFilterAliases(t *testing.T) {
+	filter := NewLevelFilter([]string{"warning"})
+
+	if !filter.Process(core.NewLog("warn", "test")) {
+		t.Error("Expected 'warn' to match alias 'warning'")
+	}
+	if !filter.Process(core.NewLog("WARNING", "test")) {
+		t.Error("Expected 'WARNING' to be allowed")
+	}
+	if filter.Process(core.NewLog("error", "test")) {
+		t.Error("Expected 'error' to be dropped")
+	}
+}
+
+func TestLevelFilterMinLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]any
+		logLevel string
+		expected bool
+	}{
+		{"below min", map[string]any{"min_level": "warn"}, "info", false},
+		{"equal to min", map[string]any{"min_level": "warn"}, "warn", true},
+		{"above min", map[string]any{"min_level": "warn"}, "error", true},
+		{"alias above min", map[string]any{"min_level": "warning"}, "err", true},
+		{"unknown level", map[string]any{"min_level": "info"}, "verbose", false},
+		{"explicit level below min", map[string]any{"min_level": "error", "levels": []string{"debug"}}, "debug", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, err := NewLevelFilterFromConfig(tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			filter := plugin.(*LevelFilter)
+
+			if result := filter.Process(core.NewLog(tt.logLevel, "test")); result != tt.expected {
+				t.Errorf("Expected %v for level %s, got %v", tt.expected, tt.logLevel, result)
+			}
+		})
+	}
+}
+
+func TestLevelFilterUnknownMinLevel(t *testing.T) {
+	if _, err := NewLevelFilterFromConfig(map[string]any{"min_level": "verbose"}); err == nil {
+		t.Error("Expected error for unknown min_level")
+	}
+}
+
+func TestLevelFilterCustomVocabulary(t *testing.T) {
+	err := core.SetLevels(core.LevelsConfig{
+		Order:   []string{"trace", "debug", "info", "notice", "warn", "error", "fatal"},
+		Aliases: map[string]string{"crit": "fatal", "warning": "warn"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set levels: %v", err)
+	}
+	defer func() { _ = core.SetLevels(core.DefaultLevelsConfig()) }()
+
+	plugin, err := NewLevelFilterFromConfig(map[string]any{"min_level": "notice"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := plugin.(*LevelFilter)
+
+	if !filter.Process(core.NewLog("CRIT", "test")) {
+		t.Error("Expected CRIT (alias of fatal) to pass min_level notice")
+	}
+	if filter.Process(core.NewLog("info", "test")) {
+		t.Error("Expected info to be dropped by min_level notice")
+	}
+}
+
+func TestLevelFilterOverride(t *testing.T) {
+	filter := NewLevelFilter([]string{"error"})
+	info := core.NewLog("info", "test")
+
+	if filter.Process(info) {
+		t.Fatal("Expected info to be dropped without an override")
+	}
+
+	filter.SetLevelOverride("INFO", time.Now().Add(time.Minute))
+	if !filter.Process(info) {
+		t.Error("Expected info to pass while the override is active")
+	}
+	if filter.Process(core.NewLog("debug", "test")) {
+		t.Error("Expected debug to stay below the override")
+	}
+	if minLevel, _, active := filter.LevelOverride(); !active || minLevel != "info" {
+		t.Errorf("Expected active override min=info, got %q active=%v", minLevel, active)
+	}
+
+	filter.ClearLevelOverride()
+	if filter.Process(info) {
+		t.Error("Expected info to be dropped after clearing the override")
+	}
+}
+
+func TestLevelFilterOverrideExpires(t *testing.T) {
+	filter := NewLevelFilter([]string{"error"})
+	filter.SetLevelOverride("debug", time.Now().Add(-time.Second))
+
+	if filter.Process(core.NewLog("debug", "test")) {
+		t.Error("Expected an expired override to be ignored")
+	}
+	if _, _, active := filter.LevelOverride(); active {
+		t.Error("Expected an expired override to be reported inactive")
+	}
+	if !filter.Process(core.NewLog("error", "test")) {
+		t.Error("Expected the configured levels to still pass")
+	}
+}
@@ -0,0 +1,254 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+)
+
+// logger writes this filter plugin's internal logs
+var logger = logging.New("filter.lookup")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterFilterPlugin("lookup", NewLookupFilterFromConfig)
+}
+
+// Config represents lookup filter configuration
+type Config struct {
+	SourceField    string            `yaml:"source_field"`              // Field to look up: "message", "level", "source", "source_type" or a metadata key
+	TargetField    string            `yaml:"target_field"`              // Metadata key receiving the mapped value
+	Table          map[string]string `yaml:"table,omitempty"`           // Inline lookup table
+	File           string            `yaml:"file,omitempty"`            // CSV file with key,value rows
+	Header         bool              `yaml:"header,omitempty"`          // Skip the first CSV row
+	OnMiss         string            `yaml:"on_miss,omitempty"`         // "pass" (default) or "default"
+	Default        string            `yaml:"default,omitempty"`         // Value written on miss when on_miss is "default"
+	ReloadInterval int               `yaml:"reload_interval,omitempty"` // Seconds between CSV change checks (0 = never)
+}
+
+// Validate validates the lookup filter configuration
+func (c *Config) Validate() error {
+	if c.SourceField == "" {
+		return fmt.Errorf("source_field is required")
+	}
+	if c.TargetField == "" {
+		return fmt.Errorf("target_field is required")
+	}
+	if len(c.Table) == 0 && c.File == "" {
+		return fmt.Errorf("either table or file must be provided")
+	}
+	if len(c.Table) > 0 && c.File != "" {
+		return fmt.Errorf("only one of table or file can be provided")
+	}
+	if c.OnMiss != "pass" && c.OnMiss != "default" {
+		return fmt.Errorf("on_miss must be 'pass' or 'default', got '%s'", c.OnMiss)
+	}
+	if c.ReloadInterval < 0 {
+		return fmt.Errorf("reload_interval must be non-negative")
+	}
+	if c.ReloadInterval > 0 && c.File == "" {
+		return fmt.Errorf("reload_interval requires file")
+	}
+	return nil
+}
+
+// NewLookupFilterFromConfig creates a lookup filter from configuration map
+func NewLookupFilterFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewLookupFilter(cfg)
+}
+
+// LookupFilter enriches logs by mapping a field value through a lookup table
+type LookupFilter struct {
+	config Config
+	table  atomic.Pointer[map[string]string] // Swapped atomically on reload
+
+	// CSV reload state
+	reloadMu  sync.Mutex
+	nextCheck atomic.Int64 // Unix nanoseconds of the next change check
+	modTime   time.Time
+}
+
+// NewLookupFilter creates a new lookup filter
+func NewLookupFilter(config Config) (*LookupFilter, error) {
+	// Set defaults
+	if config.OnMiss == "" {
+		config.OnMiss = "pass"
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lookup filter config: %w", err)
+	}
+
+	f := &LookupFilter{config: config}
+
+	if config.File == "" {
+		table := make(map[string]string, len(config.Table))
+		for k, v := range config.Table {
+			table[k] = v
+		}
+		f.table.Store(&table)
+		return f, nil
+	}
+
+	if err := f.loadFile(); err != nil {
+		return nil, err
+	}
+	f.scheduleCheck()
+	return f, nil
+}
+
+// Mutates implements core.MutatingFilter; the mapped value is written to the log metadata
+func (f *LookupFilter) Mutates() bool {
+	return true
+}
+
+// Process writes the mapped value of the source field to the target metadata key.
+// Logs are never dropped by this filter.
+func (f *LookupFilter) Process(log *core.Log) bool {
+	f.maybeReload()
+
+	key, ok := f.sourceValue(log)
+	if !ok {
+		return true // Field not found, pass through
+	}
+
+	table := *f.table.Load()
+	value, found := table[key]
+	if !found {
+		if f.config.OnMiss != "default" {
+			return true
+		}
+		value = f.config.Default
+	}
+
+	if log.Metadata == nil {
+		log.Metadata = make(map[string]string)
+	}
+	log.Metadata[f.config.TargetField] = value
+	return true
+}
+
+// sourceValue returns the value of the configured source field
+func (f *LookupFilter) sourceValue(log *core.Log) (string, bool) {
+	switch f.config.SourceField {
+	case "message":
+		return log.Message, true
+	case "level":
+		return log.Level, true
+	case "source":
+		return log.Source, true
+	case "source_type":
+		return log.SourceType, true
+	default:
+		val, ok := log.Metadata[f.config.SourceField]
+		return val, ok
+	}
+}
+
+// maybeReload reloads the CSV file if the reload interval elapsed and the file changed.
+// Only one caller performs the check; others keep using the current table.
+func (f *LookupFilter) maybeReload() {
+	if f.config.ReloadInterval <= 0 || time.Now().UnixNano() < f.nextCheck.Load() {
+		return
+	}
+	if !f.reloadMu.TryLock() {
+		return
+	}
+	defer f.reloadMu.Unlock()
+	defer f.scheduleCheck()
+
+	info, err := os.Stat(f.config.File)
+	if err != nil {
+		logger.Printf("Error checking lookup file %s: %v", f.config.File, err)
+		return
+	}
+	if info.ModTime().Equal(f.modTime) {
+		return
+	}
+
+	if err := f.loadFile(); err != nil {
+		// Keep serving the previous table
+		logger.Printf("Error reloading lookup file %s: %v", f.config.File, err)
+		return
+	}
+	logger.Printf("Reloaded lookup file %s (%d entries)", f.config.File, len(*f.table.Load()))
+}
+
+// scheduleCheck sets the time of the next file change check
+func (f *LookupFilter) scheduleCheck() {
+	interval := time.Duration(f.config.ReloadInterval) * time.Second
+	f.nextCheck.Store(time.Now().Add(interval).UnixNano())
+}
+
+// loadFile reads the CSV file and atomically swaps in the new table
+func (f *LookupFilter) loadFile() error {
+	file, err := os.Open(f.config.File)
+	if err != nil {
+		return fmt.Errorf("failed to open lookup file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat lookup file: %w", err)
+	}
+
+	table, err := parseCSV(file, f.config.Header)
+	if err != nil {
+		return fmt.Errorf("failed to parse lookup file %s: %w", f.config.File, err)
+	}
+
+	f.table.Store(&table)
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// parseCSV parses key,value rows into a lookup table
+func parseCSV(r io.Reader, header bool) (map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	table := make(map[string]string)
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if first {
+			first = false
+			if header {
+				continue
+			}
+		}
+
+		if len(record) < 2 {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: expected key,value", line)
+		}
+		table[strings.TrimSpace(record[0])] = strings.TrimSpace(record[1])
+	}
+
+	return table, nil
+}
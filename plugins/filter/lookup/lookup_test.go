@@ -0,0 +1,207 @@
+package lookup
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func TestLookupFilter_Process(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		inputLog     *core.Log
+		expectedMeta map[string]string
+	}{
+		{
+			name: "maps metadata value",
+			config: Config{
+				SourceField: "status",
+				TargetField: "status_text",
+				Table:       map[string]string{"404": "Not Found", "500": "Internal Server Error"},
+			},
+			inputLog: &core.Log{
+				Metadata: map[string]string{"status": "404"},
+			},
+			expectedMeta: map[string]string{"status": "404", "status_text": "Not Found"},
+		},
+		{
+			name: "miss passes through unchanged",
+			config: Config{
+				SourceField: "status",
+				TargetField: "status_text",
+				Table:       map[string]string{"404": "Not Found"},
+			},
+			inputLog: &core.Log{
+				Metadata: map[string]string{"status": "418"},
+			},
+			expectedMeta: map[string]string{"status": "418"},
+		},
+		{
+			name: "miss writes default",
+			config: Config{
+				SourceField: "status",
+				TargetField: "status_text",
+				Table:       map[string]string{"404": "Not Found"},
+				OnMiss:      "default",
+				Default:     "unknown",
+			},
+			inputLog: &core.Log{
+				Metadata: map[string]string{"status": "418"},
+			},
+			expectedMeta: map[string]string{"status": "418", "status_text": "unknown"},
+		},
+		{
+			name: "missing source field passes through",
+			config: Config{
+				SourceField: "host",
+				TargetField: "team",
+				Table:       map[string]string{"web-1": "frontend"},
+				OnMiss:      "default",
+				Default:     "unknown",
+			},
+			inputLog: &core.Log{
+				Metadata: map[string]string{},
+			},
+			expectedMeta: map[string]string{},
+		},
+		{
+			name: "maps log source",
+			config: Config{
+				SourceField: "source",
+				TargetField: "team",
+				Table:       map[string]string{"web-1": "frontend"},
+			},
+			inputLog: &core.Log{
+				Source:   "web-1",
+				Metadata: map[string]string{},
+			},
+			expectedMeta: map[string]string{"team": "frontend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewLookupFilter(tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !filter.Process(tt.inputLog) {
+				t.Error("Lookup filter should never block logs")
+			}
+
+			if len(tt.inputLog.Metadata) != len(tt.expectedMeta) {
+				t.Errorf("Expected metadata %v, got %v", tt.expectedMeta, tt.inputLog.Metadata)
+			}
+			for k, v := range tt.expectedMeta {
+				if tt.inputLog.Metadata[k] != v {
+					t.Errorf("Expected metadata[%s] = %s, got %s", k, v, tt.inputLog.Metadata[k])
+				}
+			}
+		})
+	}
+}
+
+func TestLookupFilter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "missing source field", config: map[string]any{"target_field": "t", "table": map[string]any{"a": "b"}}},
+		{name: "missing target field", config: map[string]any{"source_field": "s", "table": map[string]any{"a": "b"}}},
+		{name: "missing table and file", config: map[string]any{"source_field": "s", "target_field": "t"}},
+		{name: "both table and file", config: map[string]any{"source_field": "s", "target_field": "t", "table": map[string]any{"a": "b"}, "file": "x.csv"}},
+		{name: "invalid on_miss", config: map[string]any{"source_field": "s", "target_field": "t", "table": map[string]any{"a": "b"}, "on_miss": "drop"}},
+		{name: "reload without file", config: map[string]any{"source_field": "s", "target_field": "t", "table": map[string]any{"a": "b"}, "reload_interval": 5}},
+		{name: "missing file", config: map[string]any{"source_field": "s", "target_field": "t", "file": "/nonexistent/lookup.csv"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLookupFilterFromConfig(tt.config); err == nil {
+				t.Error("Expected error for invalid config")
+			}
+		})
+	}
+}
+
+func TestLookupFilter_CSVFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams.csv")
+	content := "host,team\n# comment line\nweb-1, frontend\ndb-1,\"data, storage\"\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	filter, err := NewLookupFilter(Config{
+		SourceField: "host",
+		TargetField: "team",
+		File:        path,
+		Header:      true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	logEntry := core.NewLogWithMetadata("info", "query", map[string]string{"host": "db-1"})
+	filter.Process(logEntry)
+	if logEntry.Metadata["team"] != "data, storage" {
+		t.Errorf("Expected team 'data, storage', got '%s'", logEntry.Metadata["team"])
+	}
+
+	headerLog := core.NewLogWithMetadata("info", "x", map[string]string{"host": "host"})
+	filter.Process(headerLog)
+	if _, ok := headerLog.Metadata["team"]; ok {
+		t.Error("Header row should be skipped")
+	}
+}
+
+func TestLookupFilter_CSVReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams.csv")
+	if err := os.WriteFile(path, []byte("web-1,frontend\n"), 0600); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	filter, err := NewLookupFilter(Config{
+		SourceField:    "host",
+		TargetField:    "team",
+		File:           path,
+		ReloadInterval: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("web-1,platform\n"), 0600); err != nil {
+		t.Fatalf("Failed to rewrite CSV: %v", err)
+	}
+	// Ensure the modification time differs on filesystems with coarse timestamps
+	future := time.Now().Add(2 * time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+
+	// Force the next Process call to check the file
+	filter.nextCheck.Store(0)
+
+	// Concurrent readers while the table reloads
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filter.Process(core.NewLogWithMetadata("info", "x", map[string]string{"host": "web-1"}))
+		}()
+	}
+	wg.Wait()
+
+	logEntry := core.NewLogWithMetadata("info", "x", map[string]string{"host": "web-1"})
+	filter.Process(logEntry)
+	if logEntry.Metadata["team"] != "platform" {
+		t.Errorf("Expected reloaded team 'platform', got '%s'", logEntry.Metadata["team"])
+	}
+}
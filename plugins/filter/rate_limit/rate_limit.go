@@ -47,6 +47,21 @@ func NewRateLimitFilter(rate float64, burst int) *RateLimitFilter {
 	}
 }
 
+// DropReason implements core.DropReasoner so rate-limited logs are counted separately
+func (f *RateLimitFilter) DropReason() string {
+	return "rate_limit"
+}
+
+// Mutates implements core.MutatingFilter; the log itself is never modified
+func (f *RateLimitFilter) Mutates() bool {
+	return false
+}
+
+// Preservable implements core.PreservableFilter; logs matching preserve_if are never rate limited
+func (f *RateLimitFilter) Preservable() bool {
+	return true
+}
+
 // Process determines if a log should be kept based on rate limiting
 func (f *RateLimitFilter) Process(log *core.Log) bool {
 	f.mu.Lock()
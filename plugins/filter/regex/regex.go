@@ -1,7 +1,9 @@
 package regex
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/mbiondo/logAnalyzer/core"
 )
@@ -14,8 +16,11 @@ func init() {
 // Config represents regex filter configuration
 type Config struct {
 	Patterns []string `yaml:"patterns"`
-	Mode     string   `yaml:"mode,omitempty"`  // "include" or "exclude"
-	Field    string   `yaml:"field,omitempty"` // "message", "level", or "all"
+	Mode     string   `yaml:"mode,omitempty"`   // "include", "exclude" or "tag"
+	Field    string   `yaml:"field,omitempty"`  // "message", "level", or "all"
+	Fields   []string `yaml:"fields,omitempty"` // Fields to test: message, level, source, metadata.<key>, metadata.*
+	Match    string   `yaml:"match,omitempty"`  // How fields combine: "any" (default) or "all"
+	Tags     []string `yaml:"tags,omitempty"`   // Tags added to matching logs
 }
 
 // NewRegexFilterFromConfig creates a regex filter from configuration map
@@ -25,6 +30,10 @@ func NewRegexFilterFromConfig(config map[string]any) (any, error) {
 		return nil, err
 	}
 
+	if len(cfg.Fields) > 0 && cfg.Field != "" {
+		return nil, fmt.Errorf("regex filter: use either field or fields, not both")
+	}
+
 	// Set defaults
 	if cfg.Mode == "" {
 		cfg.Mode = "include"
@@ -33,14 +42,74 @@ func NewRegexFilterFromConfig(config map[string]any) (any, error) {
 		cfg.Field = "message"
 	}
 
-	return NewRegexFilter(cfg.Patterns, cfg.Mode, cfg.Field), nil
+	filter := NewRegexFilter(cfg.Patterns, cfg.Mode, cfg.Field)
+	filter.tags = cfg.Tags
+	if len(cfg.Fields) > 0 {
+		if err := filter.SetFields(cfg.Fields, cfg.Match); err != nil {
+			return nil, err
+		}
+	} else if cfg.Match != "" {
+		return nil, fmt.Errorf("regex filter: match requires fields")
+	}
+	return filter, nil
+}
+
+// fieldSelector identifies one log field tested by the filter
+type fieldSelector struct {
+	name string // "message", "level", "source" or "metadata"
+	key  string // Metadata key, or "*" for all metadata values
+}
+
+// parseFieldSelector parses a field name such as "message" or "metadata.url"
+func parseFieldSelector(field string) (fieldSelector, error) {
+	switch field {
+	case "message", "level", "source":
+		return fieldSelector{name: field}, nil
+	}
+	if key, ok := strings.CutPrefix(field, "metadata."); ok && key != "" {
+		return fieldSelector{name: "metadata", key: key}, nil
+	}
+	return fieldSelector{}, fmt.Errorf("regex filter: unknown field %q (use message, level, source, metadata.<key> or metadata.*)", field)
+}
+
+// values returns the values of the field in a log, or false when the field is missing.
+// Message and level are always present; source is missing when empty.
+func (s fieldSelector) values(log *core.Log) ([]string, bool) {
+	switch s.name {
+	case "message":
+		return []string{log.Message}, true
+	case "level":
+		return []string{log.Level}, true
+	case "source":
+		return []string{log.Source}, log.Source != ""
+	}
+
+	if s.key == "*" {
+		if len(log.Metadata) == 0 {
+			return nil, false
+		}
+		values := make([]string, 0, len(log.Metadata))
+		for _, value := range log.Metadata {
+			values = append(values, value)
+		}
+		return values, true
+	}
+
+	value, ok := log.Metadata[s.key]
+	if !ok {
+		return nil, false
+	}
+	return []string{value}, true
 }
 
 // RegexFilter filters logs based on regular expressions
 type RegexFilter struct {
 	patterns []*regexp.Regexp
-	mode     string // "include" or "exclude"
-	field    string // "message", "level", or "all"
+	mode     string          // "include", "exclude" or "tag"
+	field    string          // "message", "level", or "all"
+	fields   []fieldSelector // Fields to test instead of field, when set
+	matchAll bool            // Every field in fields must match (default: any field)
+	tags     []string        // Tags added to matching logs
 }
 
 // NewRegexFilter creates a new regex filter
@@ -66,31 +135,104 @@ func NewRegexFilter(patterns []string, mode string, field string) *RegexFilter {
 	}
 }
 
+// SetFields tests the patterns against several fields instead of a single one.
+// With match "any" (default) a log matches when any present field matches; with
+// "all" every field must be present and match. A missing field never matches.
+func (f *RegexFilter) SetFields(fields []string, match string) error {
+	switch match {
+	case "", "any":
+		f.matchAll = false
+	case "all":
+		f.matchAll = true
+	default:
+		return fmt.Errorf("regex filter: invalid match %q (use any or all)", match)
+	}
+
+	selectors := make([]fieldSelector, 0, len(fields))
+	for _, field := range fields {
+		selector, err := parseFieldSelector(field)
+		if err != nil {
+			return err
+		}
+		selectors = append(selectors, selector)
+	}
+	f.fields = selectors
+	return nil
+}
+
+// Cost implements core.CostedFilter; regular expressions are the most expensive predicates
+func (f *RegexFilter) Cost() int {
+	return core.FilterCostExpensive
+}
+
+// Mutates implements core.MutatingFilter; the filter changes logs when it adds tags
+func (f *RegexFilter) Mutates() bool {
+	return f.mode == "tag" || len(f.tags) > 0
+}
+
 // Process determines if a log should be kept based on regex matching
 func (f *RegexFilter) Process(log *core.Log) bool {
-	// Get the text to match against
-	var text string
-	switch f.field {
-	case "level":
-		text = log.Level
-	case "all":
-		text = log.Level + " " + log.Message
-	default: // "message"
-		text = log.Message
+	var matches bool
+	if len(f.fields) > 0 {
+		matches = f.matchFields(log)
+	} else {
+		// Get the text to match against
+		var text string
+		switch f.field {
+		case "level":
+			text = log.Level
+		case "all":
+			text = log.Level + " " + log.Message
+		default: // "message"
+			text = log.Message
+		}
+		matches = f.matchText(text)
+	}
+
+	if matches {
+		log.AddTags(f.tags...)
 	}
 
-	// Check if any pattern matches
-	matches := false
+	// Return based on mode
+	switch f.mode {
+	case "exclude":
+		return !matches
+	case "tag":
+		return true // Only tag matching logs, never drop
+	default:
+		return matches
+	}
+}
+
+// matchText reports whether any pattern matches the text
+func (f *RegexFilter) matchText(text string) bool {
 	for _, pattern := range f.patterns {
 		if pattern.MatchString(text) {
-			matches = true
-			break
+			return true
 		}
 	}
+	return false
+}
 
-	// Return based on mode
-	if f.mode == "exclude" {
-		return !matches
+// matchFields applies the patterns to the configured fields using the match mode
+func (f *RegexFilter) matchFields(log *core.Log) bool {
+	for _, selector := range f.fields {
+		matched := false
+		if values, ok := selector.values(log); ok {
+			for _, value := range values {
+				if f.matchText(value) {
+					matched = true
+					break
+				}
+			}
+		}
+
+		if matched && !f.matchAll {
+			return true
+		}
+		if !matched && f.matchAll {
+			return false
+		}
 	}
-	return matches
+	return f.matchAll
 }
@@ -236,3 +236,189 @@ func TestRegexFilterMultiplePatterns(t *testing.T) {
 		})
 	}
 }
+
+func TestRegexFilterTags(t *testing.T) {
+	plugin, err := NewRegexFilterFromConfig(map[string]any{
+		"patterns": []any{"unauthorized", "forbidden"},
+		"mode":     "tag",
+		"tags":     []any{"security", "alert", "security"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := plugin.(*RegexFilter)
+
+	matching := &core.Log{Level: "warn", Message: "unauthorized access attempt"}
+	if !filter.Process(matching) {
+		t.Error("Tag mode should never drop logs")
+	}
+	if len(matching.Tags) != 2 || !matching.HasTag("security") || !matching.HasTag("alert") {
+		t.Errorf("Expected de-duplicated tags [security alert], got %v", matching.Tags)
+	}
+
+	// Processing again must not duplicate tags
+	filter.Process(matching)
+	if len(matching.Tags) != 2 {
+		t.Errorf("Expected tags to stay de-duplicated, got %v", matching.Tags)
+	}
+
+	other := &core.Log{Level: "info", Message: "user logged in"}
+	if !filter.Process(other) {
+		t.Error("Tag mode should pass non-matching logs")
+	}
+	if len(other.Tags) != 0 {
+		t.Errorf("Expected no tags on non-matching log, got %v", other.Tags)
+	}
+}
+
+func TestRegexFilterIncludeModeWithTags(t *testing.T) {
+	filter := NewRegexFilter([]string{"error"}, "include", "message")
+	filter.tags = []string{"alert"}
+
+	logEntry := &core.Log{Level: "error", Message: "error connecting"}
+	if !filter.Process(logEntry) {
+		t.Error("Expected matching log to pass")
+	}
+	if !logEntry.HasTag("alert") {
+		t.Error("Expected matching log to be tagged")
+	}
+}
+
+func TestRegexFilterMutates(t *testing.T) {
+	if NewRegexFilter([]string{"ERROR"}, "include", "message").Mutates() {
+		t.Error("Expected include filter without tags to be a pure predicate")
+	}
+	if !NewRegexFilter([]string{"ERROR"}, "tag", "message").Mutates() {
+		t.Error("Expected tag mode filter to mutate logs")
+	}
+
+	plugin, err := NewRegexFilterFromConfig(map[string]any{
+		"patterns": []string{"ERROR"},
+		"tags":     []string{"errors"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !plugin.(*RegexFilter).Mutates() {
+		t.Error("Expected filter with tags to mutate logs")
+	}
+}
+
+func TestRegexFilterFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []string
+		match    string
+		log      *core.Log
+		expected bool
+	}{
+		{
+			name:     "any matches metadata field",
+			fields:   []string{"message", "metadata.url"},
+			log:      &core.Log{Message: "request done", Metadata: map[string]string{"url": "/admin/users"}},
+			expected: true,
+		},
+		{
+			name:     "any skips missing field",
+			fields:   []string{"metadata.url", "message"},
+			log:      &core.Log{Message: "GET /admin"},
+			expected: true,
+		},
+		{
+			name:     "any without match",
+			fields:   []string{"message", "metadata.url"},
+			log:      &core.Log{Message: "request done", Metadata: map[string]string{"url": "/home"}},
+			expected: false,
+		},
+		{
+			name:     "all requires every field",
+			fields:   []string{"message", "metadata.path"},
+			match:    "all",
+			log:      &core.Log{Message: "GET /admin", Metadata: map[string]string{"path": "/admin/users"}},
+			expected: true,
+		},
+		{
+			name:     "all fails on missing field",
+			fields:   []string{"message", "metadata.path"},
+			match:    "all",
+			log:      &core.Log{Message: "GET /admin"},
+			expected: false,
+		},
+		{
+			name:     "all fails on non-matching field",
+			fields:   []string{"message", "metadata.path"},
+			match:    "all",
+			log:      &core.Log{Message: "GET /admin", Metadata: map[string]string{"path": "/home"}},
+			expected: false,
+		},
+		{
+			name:     "wildcard matches any metadata value",
+			fields:   []string{"metadata.*"},
+			log:      &core.Log{Message: "ok", Metadata: map[string]string{"user": "bob", "target": "/admin"}},
+			expected: true,
+		},
+		{
+			name:     "wildcard without metadata is missing",
+			fields:   []string{"metadata.*"},
+			match:    "all",
+			log:      &core.Log{Message: "/admin"},
+			expected: false,
+		},
+		{
+			name:     "empty source is missing",
+			fields:   []string{"source"},
+			log:      &core.Log{Message: "/admin"},
+			expected: false,
+		},
+		{
+			name:     "source field",
+			fields:   []string{"source"},
+			log:      &core.Log{Message: "ok", Source: "admin-api"},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewRegexFilter([]string{"admin"}, "include", "message")
+			if err := filter.SetFields(tt.fields, tt.match); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := filter.Process(tt.log); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRegexFilterFieldsFromConfig(t *testing.T) {
+	plugin, err := NewRegexFilterFromConfig(map[string]any{
+		"patterns": []string{"^5\\d\\d$"},
+		"mode":     "exclude",
+		"fields":   []string{"metadata.status"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := plugin.(*RegexFilter)
+
+	if filter.Process(&core.Log{Message: "failed", Metadata: map[string]string{"status": "503"}}) {
+		t.Error("Expected log with matching status to be excluded")
+	}
+	if !filter.Process(&core.Log{Message: "no status"}) {
+		t.Error("Expected log without the field to pass in exclude mode")
+	}
+
+	invalid := []map[string]any{
+		{"patterns": []string{"x"}, "fields": []string{"url"}},
+		{"patterns": []string{"x"}, "fields": []string{"metadata."}},
+		{"patterns": []string{"x"}, "fields": []string{"message"}, "match": "most"},
+		{"patterns": []string{"x"}, "fields": []string{"message"}, "field": "level"},
+		{"patterns": []string{"x"}, "match": "all"},
+	}
+	for _, config := range invalid {
+		if _, err := NewRegexFilterFromConfig(config); err == nil {
+			t.Errorf("Expected error for config %v", config)
+		}
+	}
+}
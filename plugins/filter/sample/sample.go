@@ -0,0 +1,120 @@
+package sample
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterFilterPlugin("sample", NewSampleFilterFromConfig)
+}
+
+// Config represents sample filter configuration
+type Config struct {
+	Rate float64     `yaml:"rate"`           // Fraction of logs kept, in (0, 1]
+	Seed *int64      `yaml:"seed,omitempty"` // Fixed seed for reproducible decisions (random when unset)
+	Key  logkey.Spec `yaml:"key,omitempty"`  // Key spec hashed for the decision (default: the log's position)
+}
+
+// Validate validates the sample filter configuration
+func (c *Config) Validate() error {
+	if c.Rate <= 0 || c.Rate > 1 {
+		return fmt.Errorf("rate must be greater than 0 and at most 1, got %v", c.Rate)
+	}
+	if len(c.Key) > 0 {
+		if _, err := logkey.Compile(c.Key); err != nil {
+			return fmt.Errorf("invalid key: %w", err)
+		}
+	}
+	return nil
+}
+
+// NewSampleFilterFromConfig creates a sample filter from configuration map
+func NewSampleFilterFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewSampleFilter(cfg)
+}
+
+// SampleFilter keeps a fraction of logs. Every decision hashes the seed together
+// with the log key (or the log's position in the stream when no key is set), so
+// the same seed and the same input in the same order always give the same output.
+type SampleFilter struct {
+	config    Config
+	key       *logkey.Key // nil without a key
+	seed      uint64
+	threshold uint64        // Hashes below this value are kept
+	sequence  atomic.Uint64 // Position of the next log without a key
+}
+
+// NewSampleFilter creates a new sample filter
+func NewSampleFilter(cfg Config) (*SampleFilter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var seed uint64
+	if cfg.Seed != nil {
+		seed = uint64(*cfg.Seed) // #nosec G115 - seed bits are reinterpreted, not range-checked
+	} else {
+		var buf [8]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate sampling seed: %w", err)
+		}
+		seed = binary.BigEndian.Uint64(buf[:])
+	}
+
+	var key *logkey.Key
+	if len(cfg.Key) > 0 {
+		key = logkey.MustCompile(cfg.Key)
+	}
+
+	return &SampleFilter{
+		config:    cfg,
+		key:       key,
+		seed:      seed,
+		threshold: core.SampleThreshold(cfg.Rate),
+	}, nil
+}
+
+// DropReason implements core.DropReasoner so sampled-out logs are counted separately
+func (f *SampleFilter) DropReason() string {
+	return core.DropReasonSampled
+}
+
+// Mutates implements core.MutatingFilter; the log itself is never modified
+func (f *SampleFilter) Mutates() bool {
+	return false
+}
+
+// Preservable implements core.PreservableFilter; logs matching preserve_if are never sampled out
+func (f *SampleFilter) Preservable() bool {
+	return true
+}
+
+// Process determines if a log should be kept based on its sampling hash
+func (f *SampleFilter) Process(log *core.Log) bool {
+	if f.config.Rate >= 1 {
+		return true
+	}
+
+	var value string
+	var ok bool
+	if f.key != nil {
+		value, ok = f.key.Lookup(log)
+	}
+	if !ok {
+		value = strconv.FormatUint(f.sequence.Add(1)-1, 10)
+	}
+	return core.SampleHash(f.seed, value) < f.threshold
+}
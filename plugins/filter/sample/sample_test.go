@@ -0,0 +1,134 @@
+package sample
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+func seed(v int64) *int64 {
+	return &v
+}
+
+// decisions runs the same stream of logs through a filter and records each decision
+func decisions(filter *SampleFilter, logs []*core.Log) []bool {
+	result := make([]bool, len(logs))
+	for i, logEntry := range logs {
+		result[i] = filter.Process(logEntry)
+	}
+	return result
+}
+
+func testLogs(n int) []*core.Log {
+	logs := make([]*core.Log, n)
+	for i := range logs {
+		logs[i] = &core.Log{
+			Level:    "info",
+			Message:  fmt.Sprintf("message %d", i),
+			Metadata: map[string]string{"request_id": fmt.Sprintf("req-%d", i%50)},
+		}
+	}
+	return logs
+}
+
+func TestSampleFilterConfigValidation(t *testing.T) {
+	for _, rate := range []float64{0, -0.5, 1.5} {
+		if _, err := NewSampleFilter(Config{Rate: rate}); err == nil {
+			t.Errorf("Expected error for rate %v", rate)
+		}
+	}
+	if _, err := NewSampleFilterFromConfig(map[string]any{"rate": 0.1, "seed": 42}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSampleFilterSeedIsDeterministic(t *testing.T) {
+	logs := testLogs(1000)
+
+	first, err := NewSampleFilter(Config{Rate: 0.3, Seed: seed(42)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := NewSampleFilter(Config{Rate: 0.3, Seed: seed(42)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	other, err := NewSampleFilter(Config{Rate: 0.3, Seed: seed(7)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	a, b, c := decisions(first, logs), decisions(second, logs), decisions(other, logs)
+	kept, differs := 0, false
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expected same decision for log %d with the same seed", i)
+		}
+		if a[i] != c[i] {
+			differs = true
+		}
+		if a[i] {
+			kept++
+		}
+	}
+
+	if !differs {
+		t.Error("Expected a different seed to give different decisions")
+	}
+	if kept < 230 || kept > 370 {
+		t.Errorf("Expected about 300 of 1000 logs kept, got %d", kept)
+	}
+}
+
+func TestSampleFilterKey(t *testing.T) {
+	filter, err := NewSampleFilter(Config{Rate: 0.5, Seed: seed(1), Key: logkey.Sources("request_id")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Logs sharing a key always get the same decision, regardless of order
+	byKey := make(map[string]bool)
+	for _, logEntry := range testLogs(500) {
+		key := logEntry.Metadata["request_id"]
+		result := filter.Process(logEntry)
+		if previous, seen := byKey[key]; seen && previous != result {
+			t.Fatalf("Expected consistent decision for key %s", key)
+		}
+		byKey[key] = result
+	}
+}
+
+func TestSampleFilterRateOne(t *testing.T) {
+	filter, err := NewSampleFilter(Config{Rate: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, logEntry := range testLogs(100) {
+		if !filter.Process(logEntry) {
+			t.Fatal("Expected rate 1 to keep every log")
+		}
+	}
+}
+
+func TestSampleFilterDropReason(t *testing.T) {
+	filter, err := NewSampleFilter(Config{Rate: 0.5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filter.DropReason() != "sampled" {
+		t.Errorf("Expected drop reason sampled, got %s", filter.DropReason())
+	}
+}
+
+func TestSampleFilterPreservable(t *testing.T) {
+	filter, err := NewSampleFilter(Config{Rate: 0.5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var plugin any = filter
+	if p, ok := plugin.(core.PreservableFilter); !ok || !p.Preservable() {
+		t.Error("Expected the sample filter to let preserve_if logs through")
+	}
+}
@@ -0,0 +1,189 @@
+package sanitize
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// Newline handling modes
+const (
+	NewlinesKeep     = "keep"     // Newlines are kept so multiline logs (stack traces) stay intact
+	NewlinesCollapse = "collapse" // Newlines are treated as whitespace
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterFilterPlugin("sanitize", NewSanitizeFilterFromConfig)
+}
+
+// Config represents sanitize filter configuration. Every cleanup step is enabled
+// unless turned off.
+type Config struct {
+	StripANSI          *bool  `yaml:"strip_ansi,omitempty"`          // Remove ANSI escape sequences such as color codes
+	StripControl       *bool  `yaml:"strip_control,omitempty"`       // Remove control characters other than tab and newline
+	CollapseWhitespace *bool  `yaml:"collapse_whitespace,omitempty"` // Turn runs of whitespace into a single space
+	Trim               *bool  `yaml:"trim,omitempty"`                // Remove leading and trailing whitespace
+	Newlines           string `yaml:"newlines,omitempty"`            // "keep" (default) or "collapse"
+}
+
+// Validate validates the sanitize filter configuration
+func (c *Config) Validate() error {
+	if c.Newlines != NewlinesKeep && c.Newlines != NewlinesCollapse {
+		return fmt.Errorf("newlines must be '%s' or '%s', got '%s'", NewlinesKeep, NewlinesCollapse, c.Newlines)
+	}
+	return nil
+}
+
+// NewSanitizeFilterFromConfig creates a sanitize filter from configuration map
+func NewSanitizeFilterFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Newlines == "" {
+		cfg.Newlines = NewlinesKeep
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &SanitizeFilter{
+		stripANSI:          enabled(cfg.StripANSI),
+		stripControl:       enabled(cfg.StripControl),
+		collapseWhitespace: enabled(cfg.CollapseWhitespace),
+		trim:               enabled(cfg.Trim),
+		keepNewlines:       cfg.Newlines == NewlinesKeep,
+	}, nil
+}
+
+// enabled returns the value of an option that defaults to true
+func enabled(option *bool) bool {
+	return option == nil || *option
+}
+
+// SanitizeFilter cleans up log messages: ANSI codes, control characters and
+// inconsistent whitespace. It never drops logs.
+type SanitizeFilter struct {
+	stripANSI          bool
+	stripControl       bool
+	collapseWhitespace bool
+	trim               bool
+	keepNewlines       bool
+}
+
+// Mutates implements core.MutatingFilter; the message is rewritten in place
+func (f *SanitizeFilter) Mutates() bool {
+	return true
+}
+
+// Process sanitizes the log message and always keeps the log
+func (f *SanitizeFilter) Process(log *core.Log) bool {
+	log.Message = f.Sanitize(log.Message)
+	return true
+}
+
+// Sanitize returns the cleaned-up form of a message. Invalid UTF-8 is replaced
+// with U+FFFD, so the result is always valid UTF-8 and multi-byte characters
+// are never split.
+func (f *SanitizeFilter) Sanitize(message string) string {
+	if !utf8.ValidString(message) {
+		message = strings.ToValidUTF8(message, string(utf8.RuneError))
+	}
+	if f.stripANSI {
+		message = stripANSI(message)
+	}
+
+	var b strings.Builder
+	b.Grow(len(message))
+	pendingSpace := false // A collapsed run of whitespace waiting for the next character
+	for i, r := range message {
+		switch {
+		case r == '\r' && (f.stripControl || !f.keepNewlines) && strings.HasPrefix(message[i+1:], "\n"):
+			// CRLF is handled as its LF
+		case r == '\n' && f.keepNewlines:
+			pendingSpace = false // Whitespace before a newline is trailing whitespace
+			b.WriteRune(r)
+		case r == '\n' && !f.collapseWhitespace:
+			b.WriteByte(' ')
+		case unicode.IsSpace(r) && f.collapseWhitespace:
+			pendingSpace = true
+		case unicode.IsSpace(r):
+			b.WriteRune(r)
+		case unicode.IsControl(r) && f.stripControl:
+			// Dropped; surrounding whitespace still collapses into one space
+		default:
+			if pendingSpace {
+				b.WriteByte(' ')
+				pendingSpace = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	if pendingSpace && !f.trim {
+		b.WriteByte(' ')
+	}
+
+	message = b.String()
+	if f.trim {
+		message = strings.TrimSpace(message)
+	}
+	return message
+}
+
+// stripANSI removes ANSI escape sequences: CSI ("ESC [" ... final byte, used for
+// colors and cursor movement), OSC ("ESC ]" ... BEL or "ESC \", used for titles and
+// hyperlinks) and other escapes such as charset selection. Escape bytes are ASCII, so UTF-8 text is untouched.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != 0x1b {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			break // Lone trailing ESC
+		}
+
+		switch s[i+1] {
+		case '[': // CSI: parameter and intermediate bytes, then a final byte in 0x40-0x7E
+			j := i + 2
+			for j < len(s) && s[j] >= 0x20 && s[j] <= 0x3f {
+				j++
+			}
+			if j < len(s) && s[j] >= 0x40 && s[j] <= 0x7e {
+				i = j
+			} else {
+				i = j - 1 // Malformed: drop the introducer and parameters, keep the rest
+			}
+		case ']': // OSC: terminated by BEL or ST (ESC \)
+			j := i + 2
+			for j < len(s) && s[j] != 0x07 && !(s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\') {
+				j++
+			}
+			if j < len(s) && s[j] == 0x1b {
+				j++
+			}
+			i = j
+		default: // Other escapes: optional intermediate bytes (e.g. "ESC ( B"), then a final byte
+			j := i + 1
+			for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+				j++
+			}
+			if j < len(s) && s[j] >= 0x30 && s[j] <= 0x7e {
+				i = j
+			} else {
+				i = j - 1
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package sanitize
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func newFilter(t *testing.T, config map[string]any) *SanitizeFilter {
+	t.Helper()
+	plugin, err := NewSanitizeFilterFromConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+	return plugin.(*SanitizeFilter)
+}
+
+func TestSanitizeFilterProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]any
+		message  string
+		expected string
+	}{
+		{"ansi colors", nil, "\x1b[31;1mERROR\x1b[0m disk full", "ERROR disk full"},
+		{"osc hyperlink", nil, "see \x1b]8;;http://x\x07docs\x1b]8;;\x1b\\ now", "see docs now"},
+		{"charset escape", nil, "\x1b(Bplain", "plain"},
+		{"control characters", nil, "a\x00b\x07c\x7fd\u009be", "abcde"},
+		{"collapse whitespace", nil, "  too   many\t\tspaces  ", "too many spaces"},
+		{"keep newlines", nil, "panic: boom  \r\n\tat main.go:10\n\tat run.go:3", "panic: boom\n at main.go:10\n at run.go:3"},
+		{"collapse newlines", map[string]any{"newlines": "collapse"}, "line one\r\nline two\n", "line one line two"},
+		{"collapse newlines without collapsing spaces", map[string]any{"newlines": "collapse", "collapse_whitespace": false, "trim": false}, "a\nb  c", "a b  c"},
+		{"utf-8 is preserved", nil, "café \x1b[32m日本語\x1b[0m 🚀", "café 日本語 🚀"},
+		{"invalid utf-8 is replaced", nil, "bad \xff\xfe byte", "bad � byte"},
+		{"everything disabled", map[string]any{"strip_ansi": false, "strip_control": false, "collapse_whitespace": false, "trim": false}, " \x1b[1mx\x00  y ", " \x1b[1mx\x00  y "},
+		{"trim only", map[string]any{"strip_ansi": false, "strip_control": false, "collapse_whitespace": false}, "  a  b \n", "a  b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := newFilter(t, tt.config)
+			log := core.NewLog("info", tt.message)
+
+			if !filter.Process(log) {
+				t.Error("Expected the sanitize filter to keep every log")
+			}
+			if log.Message != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, log.Message)
+			}
+			if !utf8.ValidString(log.Message) {
+				t.Errorf("Expected valid UTF-8, got %q", log.Message)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilterMalformedEscapes(t *testing.T) {
+	filter := newFilter(t, nil)
+
+	tests := map[string]string{
+		"trailing escape\x1b":           "trailing escape",
+		"unterminated \x1b[31":          "unterminated",
+		"broken \x1b[12ñ text":          "broken ñ text",
+		"unterminated osc \x1b]0;title": "unterminated osc",
+	}
+	for message, expected := range tests {
+		if got := filter.Sanitize(message); got != expected {
+			t.Errorf("Sanitize(%q): expected %q, got %q", message, expected, got)
+		}
+	}
+}
+
+func TestNewSanitizeFilterFromConfigInvalid(t *testing.T) {
+	if _, err := NewSanitizeFilterFromConfig(map[string]any{"newlines": "escape"}); err == nil {
+		t.Error("Expected an error for an unknown newlines mode")
+	}
+}
@@ -0,0 +1,154 @@
+package trace_context
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterFilterPlugin("trace_context", NewTraceContextFilterFromConfig)
+}
+
+// Default metadata keys searched for trace context, in order
+var (
+	DefaultTraceparentFields = []string{"traceparent", "header.traceparent"}
+	DefaultTraceIDFields     = []string{"trace_id", "traceId", "trace.id"}
+	DefaultSpanIDFields      = []string{"span_id", "spanId", "span.id"}
+	DefaultTraceFlagsFields  = []string{"trace_flags", "traceFlags"}
+)
+
+// Config represents trace context filter configuration
+type Config struct {
+	TraceparentFields []string `yaml:"traceparent_fields,omitempty"` // Metadata keys holding a W3C traceparent
+	TraceIDFields     []string `yaml:"trace_id_fields,omitempty"`    // Metadata keys holding a hex trace ID
+	SpanIDFields      []string `yaml:"span_id_fields,omitempty"`     // Metadata keys holding a hex span ID
+	TraceFlagsFields  []string `yaml:"trace_flags_fields,omitempty"` // Metadata keys holding decimal trace flags
+	RemoveFields      bool     `yaml:"remove_fields,omitempty"`      // Delete the metadata keys the context was read from
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	lists := []struct {
+		name     string
+		fields   *[]string
+		defaults []string
+	}{
+		{"traceparent_fields", &c.TraceparentFields, DefaultTraceparentFields},
+		{"trace_id_fields", &c.TraceIDFields, DefaultTraceIDFields},
+		{"span_id_fields", &c.SpanIDFields, DefaultSpanIDFields},
+		{"trace_flags_fields", &c.TraceFlagsFields, DefaultTraceFlagsFields},
+	}
+	for _, list := range lists {
+		if len(*list.fields) == 0 {
+			*list.fields = list.defaults
+			continue
+		}
+		for _, field := range *list.fields {
+			if strings.TrimSpace(field) == "" {
+				return fmt.Errorf("%s cannot contain an empty field", list.name)
+			}
+		}
+	}
+	return nil
+}
+
+// NewTraceContextFilterFromConfig creates a trace context filter from configuration map
+func NewTraceContextFilterFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewTraceContextFilter(cfg)
+}
+
+// TraceContextFilter sets a log's trace ID, span ID and trace flags from its
+// metadata, so outputs can correlate logs with distributed traces. A W3C
+// traceparent wins over separate ID fields; invalid values are ignored. It never
+// drops logs, and logs that already carry a trace ID are left unchanged.
+type TraceContextFilter struct {
+	config Config
+}
+
+// NewTraceContextFilter creates a new trace context filter
+func NewTraceContextFilter(config Config) (*TraceContextFilter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &TraceContextFilter{config: config}, nil
+}
+
+// Mutates implements core.MutatingFilter; the trace context is written to the log
+func (f *TraceContextFilter) Mutates() bool {
+	return true
+}
+
+// Process extracts the trace context into the log's trace fields
+func (f *TraceContextFilter) Process(log *core.Log) bool {
+	if log.TraceID != "" || len(log.Metadata) == 0 {
+		return true
+	}
+
+	for _, field := range f.config.TraceparentFields {
+		value, ok := log.Metadata[field]
+		if !ok {
+			continue
+		}
+		if traceID, spanID, flags, ok := core.ParseTraceparent(value); ok {
+			log.TraceID, log.SpanID, log.TraceFlags = traceID, spanID, flags
+			f.remove(log, field)
+			return true
+		}
+	}
+
+	traceField, traceID := f.find(log, f.config.TraceIDFields, core.NormalizeTraceID)
+	if traceID == "" {
+		return true
+	}
+	log.TraceID = traceID
+	f.remove(log, traceField)
+
+	if spanField, spanID := f.find(log, f.config.SpanIDFields, core.NormalizeSpanID); spanID != "" {
+		log.SpanID = spanID
+		f.remove(log, spanField)
+	}
+
+	// Decimal, as in the OpenTelemetry log data model
+	for _, field := range f.config.TraceFlagsFields {
+		value, ok := log.Metadata[field]
+		if !ok {
+			continue
+		}
+		if flags, err := strconv.ParseUint(strings.TrimSpace(value), 10, 8); err == nil {
+			log.TraceFlags = uint8(flags)
+			f.remove(log, field)
+			break
+		}
+	}
+	return true
+}
+
+// find returns the first of the fields whose value normalizes, and the normalized value
+func (f *TraceContextFilter) find(log *core.Log, fields []string, normalize func(string) (string, bool)) (string, string) {
+	for _, field := range fields {
+		value, ok := log.Metadata[field]
+		if !ok {
+			continue
+		}
+		if normalized, ok := normalize(value); ok {
+			return field, normalized
+		}
+	}
+	return "", ""
+}
+
+// remove deletes a consumed metadata key when remove_fields is set
+func (f *TraceContextFilter) remove(log *core.Log, field string) {
+	if f.config.RemoveFields {
+		delete(log.Metadata, field)
+	}
+}
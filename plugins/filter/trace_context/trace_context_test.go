@@ -0,0 +1,144 @@
+package trace_context
+
+import (
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+const (
+	traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID      = "00f067aa0ba902b7"
+)
+
+func TestTraceContextFilter_Process(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		metadata     map[string]string
+		traceID      string
+		spanID       string
+		flags        uint8
+		expectedMeta map[string]string
+	}{
+		{
+			name:         "traceparent",
+			metadata:     map[string]string{"traceparent": traceparent},
+			traceID:      traceID,
+			spanID:       spanID,
+			flags:        1,
+			expectedMeta: map[string]string{"traceparent": traceparent},
+		},
+		{
+			name:         "kafka header",
+			metadata:     map[string]string{"header.traceparent": traceparent},
+			traceID:      traceID,
+			spanID:       spanID,
+			flags:        1,
+			expectedMeta: map[string]string{"header.traceparent": traceparent},
+		},
+		{
+			name:         "JSON fields",
+			metadata:     map[string]string{"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736", "span_id": spanID, "trace_flags": "1"},
+			traceID:      traceID,
+			spanID:       spanID,
+			flags:        1,
+			expectedMeta: map[string]string{"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736", "span_id": spanID, "trace_flags": "1"},
+		},
+		{
+			name:         "traceparent wins over fields",
+			metadata:     map[string]string{"traceparent": traceparent, "traceId": "a3ce929d0e0e4736"},
+			traceID:      traceID,
+			spanID:       spanID,
+			flags:        1,
+			expectedMeta: map[string]string{"traceparent": traceparent, "traceId": "a3ce929d0e0e4736"},
+		},
+		{
+			name:         "invalid traceparent falls back to fields",
+			metadata:     map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "traceId": "a3ce929d0e0e4736"},
+			traceID:      "0000000000000000a3ce929d0e0e4736",
+			expectedMeta: map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "traceId": "a3ce929d0e0e4736"},
+		},
+		{
+			name:         "span without trace is ignored",
+			metadata:     map[string]string{"span_id": spanID},
+			expectedMeta: map[string]string{"span_id": spanID},
+		},
+		{
+			name:         "invalid span and flags are ignored",
+			metadata:     map[string]string{"trace_id": traceID, "span_id": "xyz", "trace_flags": "300"},
+			traceID:      traceID,
+			expectedMeta: map[string]string{"trace_id": traceID, "span_id": "xyz", "trace_flags": "300"},
+		},
+		{
+			name:         "custom fields",
+			config:       Config{TraceIDFields: []string{"otel.trace"}, SpanIDFields: []string{"otel.span"}},
+			metadata:     map[string]string{"otel.trace": traceID, "otel.span": spanID, "trace_id": "a3ce929d0e0e4736"},
+			traceID:      traceID,
+			spanID:       spanID,
+			expectedMeta: map[string]string{"otel.trace": traceID, "otel.span": spanID, "trace_id": "a3ce929d0e0e4736"},
+		},
+		{
+			name:         "remove fields",
+			config:       Config{RemoveFields: true},
+			metadata:     map[string]string{"trace_id": traceID, "span_id": spanID, "trace_flags": "0", "service": "api"},
+			traceID:      traceID,
+			spanID:       spanID,
+			expectedMeta: map[string]string{"service": "api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewTraceContextFilter(tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			log := core.NewLogWithMetadata("info", "payment failed", tt.metadata)
+			if !filter.Process(log) {
+				t.Fatal("Expected the log to pass")
+			}
+			if log.TraceID != tt.traceID || log.SpanID != tt.spanID || log.TraceFlags != tt.flags {
+				t.Errorf("Expected %q %q %d, got %q %q %d", tt.traceID, tt.spanID, tt.flags, log.TraceID, log.SpanID, log.TraceFlags)
+			}
+			if len(log.Metadata) != len(tt.expectedMeta) {
+				t.Errorf("Expected metadata %v, got %v", tt.expectedMeta, log.Metadata)
+			}
+			for key, value := range tt.expectedMeta {
+				if log.Metadata[key] != value {
+					t.Errorf("Expected metadata %s=%s, got %s", key, value, log.Metadata[key])
+				}
+			}
+		})
+	}
+}
+
+func TestTraceContextFilter_KeepsExistingContext(t *testing.T) {
+	filter, err := NewTraceContextFilter(Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	log := core.NewLogWithMetadata("info", "retry", map[string]string{"traceparent": traceparent})
+	log.TraceID = "0af7651916cd43dd8448eb211c80319c"
+
+	filter.Process(log)
+	if log.TraceID != "0af7651916cd43dd8448eb211c80319c" || log.SpanID != "" {
+		t.Errorf("Expected the existing trace context to be kept, got %q %q", log.TraceID, log.SpanID)
+	}
+}
+
+func TestNewTraceContextFilterFromConfig(t *testing.T) {
+	plugin, err := core.CreateFilterPlugin("trace_context", map[string]any{"traceparent_fields": []any{"x-traceparent"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := plugin.(*TraceContextFilter)
+	if len(filter.config.TraceparentFields) != 1 || len(filter.config.TraceIDFields) != len(DefaultTraceIDFields) {
+		t.Errorf("Expected configured and default fields, got %+v", filter.config)
+	}
+
+	if _, err := core.CreateFilterPlugin("trace_context", map[string]any{"span_id_fields": []any{""}}); err == nil {
+		t.Error("Expected an error for an empty field")
+	}
+}
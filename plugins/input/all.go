@@ -5,4 +5,9 @@ import (
 	_ "github.com/mbiondo/logAnalyzer/plugins/input/file"
 	_ "github.com/mbiondo/logAnalyzer/plugins/input/http"
 	_ "github.com/mbiondo/logAnalyzer/plugins/input/kafka"
+	_ "github.com/mbiondo/logAnalyzer/plugins/input/multiplex"
+	_ "github.com/mbiondo/logAnalyzer/plugins/input/redis_stream"
+	_ "github.com/mbiondo/logAnalyzer/plugins/input/sqs"
+	_ "github.com/mbiondo/logAnalyzer/plugins/input/stdin"
+	_ "github.com/mbiondo/logAnalyzer/plugins/input/wineventlog"
 )
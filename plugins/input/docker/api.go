@@ -0,0 +1,231 @@
+package dockerinput
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+// DefaultDockerHost is the Docker Engine API endpoint used when no host is configured
+const DefaultDockerHost = "unix:///var/run/docker.sock"
+
+// apiClient talks to the Docker Engine API over a single pooled HTTP client,
+// shared by container listing, inspection and log streaming
+type apiClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+// containerInfo holds the fields of a container inspect response used by the input
+type containerInfo struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+		Tty    bool              `json:"Tty"`
+	} `json:"Config"`
+}
+
+// newAPIClient creates a Docker Engine API client for a unix:// or tcp:// host
+func newAPIClient(host string, tlsCfg tlsconfig.Config) (*apiClient, error) {
+	if host == "" {
+		host = DefaultDockerHost
+	}
+
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	if err := tlsCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid TLS config: %w", err)
+	}
+	tlsConfig, err := tlsCfg.NewTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TLS config: %w", err)
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	var baseURL string
+	switch hostURL.Scheme {
+	case "unix":
+		if tlsCfg.Enabled {
+			return nil, fmt.Errorf("TLS is not supported for unix socket docker hosts")
+		}
+		socketPath := hostURL.Path
+		if socketPath == "" {
+			return nil, fmt.Errorf("docker host %q has no socket path", host)
+		}
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		// The host part is ignored when dialing a unix socket
+		baseURL = "http://docker"
+	case "tcp", "http", "https":
+		if hostURL.Host == "" {
+			return nil, fmt.Errorf("docker host %q has no address", host)
+		}
+		scheme := "http"
+		if tlsCfg.Enabled || hostURL.Scheme == "https" {
+			scheme = "https"
+		}
+		baseURL = scheme + "://" + hostURL.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q (use unix:// or tcp://)", hostURL.Scheme)
+	}
+
+	// No client timeout: log streams stay open until the container stops or the input is stopped
+	return &apiClient{
+		client:  &http.Client{Transport: transport},
+		baseURL: baseURL,
+	}, nil
+}
+
+// get performs a GET request against the Docker Engine API and checks the status code
+func (c *apiClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("docker API %s returned %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// listContainers returns the IDs of running containers, optionally filtered by name
+func (c *apiClient) listContainers(ctx context.Context, nameFilter string) ([]string, error) {
+	query := url.Values{}
+	if nameFilter != "" {
+		filters, err := json.Marshal(map[string][]string{"name": {nameFilter}})
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(filters))
+	}
+
+	resp, err := c.get(ctx, "/containers/json", query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var containers []struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode container list: %w", err)
+	}
+
+	ids := make([]string, 0, len(containers))
+	for _, container := range containers {
+		ids = append(ids, container.ID)
+	}
+	return ids, nil
+}
+
+// inspectContainer returns the name, labels and TTY setting of a container
+func (c *apiClient) inspectContainer(ctx context.Context, containerID string) (*containerInfo, error) {
+	resp, err := c.get(ctx, "/containers/"+url.PathEscape(containerID)+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var info containerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode container %s: %w", containerID, err)
+	}
+	return &info, nil
+}
+
+// streamLogs follows the logs of a container from now on. Output of containers
+// without a TTY is multiplexed by the daemon and is demultiplexed here.
+func (c *apiClient) streamLogs(ctx context.Context, containerID, stream string, tty bool, since time.Time) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("follow", "1")
+	if since.IsZero() {
+		query.Set("tail", "0")
+	} else {
+		// Resume after the last delivered log; each line starts with its timestamp
+		query.Set("since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()))
+		query.Set("timestamps", "1")
+	}
+	query.Set("stdout", fmt.Sprint(stream == "stdout" || stream == "both"))
+	query.Set("stderr", fmt.Sprint(stream == "stderr" || stream == "both"))
+
+	resp, err := c.get(ctx, "/containers/"+url.PathEscape(containerID)+"/logs", query)
+	if err != nil {
+		return nil, err
+	}
+
+	if tty {
+		return resp.Body, nil
+	}
+	return &demuxReader{body: resp.Body}, nil
+}
+
+// demuxReader strips the 8-byte frame headers of a multiplexed Docker log stream
+// ([stream type, 0, 0, 0, size uint32 big-endian] followed by size bytes of payload)
+type demuxReader struct {
+	body      io.ReadCloser
+	remaining uint32
+}
+
+// Read returns payload bytes, skipping frame headers
+func (r *demuxReader) Read(p []byte) (int, error) {
+	for r.remaining == 0 {
+		var header [8]byte
+		if _, err := io.ReadFull(r.body, header[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		r.remaining = binary.BigEndian.Uint32(header[4:])
+	}
+
+	if uint32(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.body.Read(p)
+	r.remaining -= uint32(n) // #nosec G115 - n is bounded by len(p) <= remaining
+	return n, err
+}
+
+// Close closes the underlying stream
+func (r *demuxReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,290 @@
+package dockerinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+// frame builds a multiplexed Docker log frame
+func frame(streamType byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = streamType
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestNewAPIClientHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		tls         tlsconfig.Config
+		expectedURL string
+		expectError bool
+	}{
+		{name: "default socket", host: "", expectedURL: "http://docker"},
+		{name: "unix socket", host: "unix:///tmp/docker.sock", expectedURL: "http://docker"},
+		{name: "tcp", host: "tcp://10.0.0.5:2375", expectedURL: "http://10.0.0.5:2375"},
+		{name: "tcp with tls", host: "tcp://10.0.0.5:2376", tls: tlsconfig.Config{Enabled: true, InsecureSkipVerify: true}, expectedURL: "https://10.0.0.5:2376"},
+		{name: "tls on unix socket", host: "unix:///tmp/docker.sock", tls: tlsconfig.Config{Enabled: true}, expectError: true},
+		{name: "unsupported scheme", host: "ssh://user@host", expectError: true},
+		{name: "missing address", host: "tcp://", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newAPIClient(tt.host, tt.tls)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if client.baseURL != tt.expectedURL {
+				t.Errorf("Expected base URL %s, got %s", tt.expectedURL, client.baseURL)
+			}
+		})
+	}
+}
+
+func TestDemuxReader(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(frame(1, "first line\n"))
+	stream.Write(frame(2, "second "))
+	stream.Write(frame(2, "line\n"))
+
+	reader := &demuxReader{body: io.NopCloser(&stream)}
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(output) != "first line\nsecond line\n" {
+		t.Errorf("Expected demultiplexed output, got %q", string(output))
+	}
+}
+
+func TestDockerInputAPI(t *testing.T) {
+	var logsQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"Id": "abc123def456789"}})
+		case "/containers/abc123def456789/json":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Id":     "abc123def456789",
+				"Name":   "/web",
+				"Config": map[string]any{"Labels": map[string]string{"app": "web"}, "Tty": false},
+			})
+		case "/containers/abc123def456789/logs":
+			logsQuery = r.URL.RawQuery
+			_, _ = w.Write(frame(1, "[ERROR] request failed\n"))
+			_, _ = w.Write(frame(1, "all good\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	plugin, err := NewDockerInputFromConfig(map[string]any{
+		"use_api": true,
+		"host":    "tcp://" + strings.TrimPrefix(server.URL, "http://"),
+		"labels":  map[string]string{"app": "web"},
+		"stream":  "both",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create docker input: %v", err)
+	}
+	input := plugin.(*DockerInput)
+	input.SetName("docker-api")
+
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start docker input: %v", err)
+	}
+	defer func() { _ = input.Stop() }()
+
+	var received []*core.Log
+	for len(received) < 2 {
+		select {
+		case logEntry := <-logCh:
+			received = append(received, logEntry)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for logs, got %d", len(received))
+		}
+	}
+
+	if received[0].Level != "error" || received[0].Message != "[ERROR] request failed" {
+		t.Errorf("Unexpected first log: %s - %s", received[0].Level, received[0].Message)
+	}
+	if received[0].Metadata["name"] != "web" {
+		t.Errorf("Expected container name 'web', got '%s'", received[0].Metadata["name"])
+	}
+	if received[0].Metadata["container"] != "abc123def456" {
+		t.Errorf("Expected short container ID, got '%s'", received[0].Metadata["container"])
+	}
+	if received[1].Source != "docker-api" {
+		t.Errorf("Expected source 'docker-api', got '%s'", received[1].Source)
+	}
+	if !strings.Contains(logsQuery, "follow=1") || !strings.Contains(logsQuery, "stderr=true") {
+		t.Errorf("Expected follow and stderr in logs query, got %s", logsQuery)
+	}
+}
+
+func TestDockerInputAPILabelMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"Id": "abc"}})
+		case "/containers/abc/json":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Id":     "abc",
+				"Name":   "/db",
+				"Config": map[string]any{"Labels": map[string]string{"app": "db"}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	input := NewDockerInput(nil, nil, map[string]string{"app": "web"}, "stdout")
+	client, err := newAPIClient("tcp://"+strings.TrimPrefix(server.URL, "http://"), tlsconfig.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create API client: %v", err)
+	}
+	input.api = client
+
+	containers, err := input.getContainersToMonitor()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(containers) != 0 {
+		t.Errorf("Expected no containers after label filtering, got %v", containers)
+	}
+}
+
+func TestDockerInputAPIMaxStreams(t *testing.T) {
+	base := time.Now().Add(time.Second).UTC()
+	stamp := func(offset time.Duration, message string) string {
+		return base.Add(offset).Format(time.RFC3339Nano) + " " + message + "\n"
+	}
+
+	var mu sync.Mutex
+	lines := map[string][]string{
+		"aaa": {stamp(1*time.Second, "[INFO] a1")},
+		"bbb": {stamp(2*time.Second, "[INFO] b1")},
+	}
+	queries := map[string][]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"Id": "aaa"}, {"Id": "bbb"}})
+		case "/containers/aaa/json", "/containers/bbb/json":
+			id := strings.Split(r.URL.Path, "/")[2]
+			_ = json.NewEncoder(w).Encode(map[string]any{"Id": id, "Name": "/" + id, "Config": map[string]any{"Tty": true}})
+		case "/containers/aaa/logs", "/containers/bbb/logs":
+			id := strings.Split(r.URL.Path, "/")[2]
+			mu.Lock()
+			queries[id] = append(queries[id], r.URL.RawQuery)
+			current := append([]string(nil), lines[id]...)
+			if id == "aaa" {
+				// Written while aaa waits for its next turn
+				lines[id] = append(lines[id], stamp(3*time.Second, "[INFO] a2"))
+			}
+			mu.Unlock()
+
+			// The server ignores since, so the input must skip what it already delivered
+			for _, line := range current {
+				_, _ = io.WriteString(w, line)
+			}
+			w.(http.Flusher).Flush()
+			<-r.Context().Done() // Follow until the turn ends
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	plugin, err := NewDockerInputFromConfig(map[string]any{
+		"use_api":      true,
+		"host":         "tcp://" + strings.TrimPrefix(server.URL, "http://"),
+		"max_streams":  1,
+		"stream_slice": "50ms",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create docker input: %v", err)
+	}
+	input := plugin.(*DockerInput)
+
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start docker input: %v", err)
+	}
+	defer func() { _ = input.Stop() }()
+
+	received := make(map[string]int)
+	for len(received) < 3 {
+		select {
+		case logEntry := <-logCh:
+			received[logEntry.Message]++
+			if logEntry.Message == "[INFO] a1" && !logEntry.Timestamp.Equal(base.Add(time.Second)) {
+				t.Errorf("Expected the Docker timestamp on the log, got %v", logEntry.Timestamp)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for logs, got %v", received)
+		}
+	}
+
+	// Give the rotation a few more turns to deliver duplicates, if any
+	time.Sleep(200 * time.Millisecond)
+	for len(logCh) > 0 {
+		received[(<-logCh).Message]++
+	}
+	for _, message := range []string{"[INFO] a1", "[INFO] b1", "[INFO] a2"} {
+		if received[message] != 1 {
+			t.Errorf("Expected %q once, got %d (%v)", message, received[message], received)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(queries["aaa"]) < 2 {
+		t.Fatalf("Expected aaa to be streamed in several turns, got %v", queries["aaa"])
+	}
+	resumed := fmt.Sprintf("since=%d.%09d", base.Add(time.Second).Unix(), base.Add(time.Second).Nanosecond())
+	if !strings.Contains(queries["aaa"][1], resumed) || !strings.Contains(queries["aaa"][1], "timestamps=1") {
+		t.Errorf("Expected the second turn to resume after a1 (%s), got %s", resumed, queries["aaa"][1])
+	}
+	stats := input.InputStats()
+	if active, ok := stats["active_streams"].(int); !ok || active > 1 || stats["waiting_streams"] == nil {
+		t.Errorf("Expected at most 1 active stream and a waiting count, got %v", stats)
+	}
+}
+
+func TestSplitTimestamp(t *testing.T) {
+	at, rest, ok := splitTimestamp("2024-05-01T12:00:00.123456789Z [ERROR] failed")
+	if !ok || rest != "[ERROR] failed" || at.Nanosecond() != 123456789 {
+		t.Errorf("Unexpected split: %v %q %v", at, rest, ok)
+	}
+	if _, rest, ok := splitTimestamp("[ERROR] no timestamp"); ok || rest != "[ERROR] no timestamp" {
+		t.Errorf("Expected a line without timestamp to be kept, got %q %v", rest, ok)
+	}
+}
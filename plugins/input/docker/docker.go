@@ -2,19 +2,30 @@ package dockerinput
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"log"
+	"io"
 	"os/exec"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+	"github.com/mbiondo/logAnalyzer/pkg/workpool"
 )
 
+// apiRequestTimeout bounds container listing and inspection calls against the Docker Engine API
+const apiRequestTimeout = 10 * time.Second
+
 // validDockerFilterPattern is compiled once at package level to avoid recompilation
 var validDockerFilterPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.docker")
+
 func init() {
 	// Auto-register this plugin
 	core.RegisterInputPlugin("docker", NewDockerInputFromConfig)
@@ -28,7 +39,23 @@ type Config struct {
 	ContainerIDs    []string             `yaml:"container_ids,omitempty"`
 	ContainerFilter ContainerFilterValue `yaml:"container_filter,omitempty"` // Filter by name pattern (string or []string)
 	Labels          map[string]string    `yaml:"labels,omitempty"`
-	Stream          string               `yaml:"stream,omitempty"` // "stdout", "stderr", or "both"
+	Stream          string               `yaml:"stream,omitempty"`         // "stdout", "stderr", or "both"
+	OnParseError    string               `yaml:"on_parse_error,omitempty"` // Lines without a detectable level: pass_raw, tag or drop (default: default level)
+
+	// Where logs are read from: "cli" (default, docker logs -f), "api" (same as use_api)
+	// or "json-file" (tail the json-file driver's files directly)
+	Source string `yaml:"source,omitempty"`
+	LogDir string `yaml:"log_dir,omitempty"` // json-file: Docker's containers directory (default: /var/lib/docker/containers)
+
+	// Docker Engine API (instead of the docker CLI)
+	UseAPI bool             `yaml:"use_api,omitempty"` // Talk to the daemon API directly instead of shelling out to docker
+	Host   string           `yaml:"host,omitempty"`    // Daemon address: unix:///var/run/docker.sock (default) or tcp://host:2376
+	TLS    tlsconfig.Config `yaml:"tls,omitempty"`     // TLS configuration for tcp:// hosts
+
+	// Concurrency: stream at most max_streams containers at once. The others wait
+	// and take turns round-robin, resuming from the last log they delivered.
+	MaxStreams  int           `yaml:"max_streams,omitempty"`  // Containers streamed at once (0 = all)
+	StreamSlice time.Duration `yaml:"stream_slice,omitempty"` // How long a container streams while others wait for a slot (default: 30s)
 }
 
 // NewDockerInputFromConfig creates a docker input from configuration map
@@ -60,20 +87,75 @@ func NewDockerInputFromConfig(config map[string]any) (any, error) {
 		}
 	}
 
-	return NewDockerInput(cfg.ContainerIDs, containerFilters, cfg.Labels, cfg.Stream), nil
+	if err := core.ValidateParseErrorMode(cfg.OnParseError); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Source {
+	case "", "cli":
+	case "api":
+		cfg.UseAPI = true
+	case "json-file":
+		if cfg.UseAPI {
+			return nil, fmt.Errorf("use_api cannot be combined with source json-file")
+		}
+		if cfg.LogDir == "" {
+			cfg.LogDir = DefaultLogDir
+		}
+	default:
+		return nil, fmt.Errorf("invalid source '%s', must be 'cli', 'api' or 'json-file'", cfg.Source)
+	}
+	if cfg.LogDir != "" && cfg.Source != "json-file" {
+		return nil, fmt.Errorf("log_dir requires source json-file")
+	}
+	if cfg.Stream != "stdout" && cfg.Stream != "stderr" && cfg.Stream != "both" {
+		return nil, fmt.Errorf("invalid stream '%s', must be 'stdout', 'stderr' or 'both'", cfg.Stream)
+	}
+	if cfg.MaxStreams < 0 {
+		return nil, fmt.Errorf("max_streams must be non-negative")
+	}
+	if cfg.StreamSlice < 0 {
+		return nil, fmt.Errorf("stream_slice must be non-negative")
+	}
+
+	input := NewDockerInput(cfg.ContainerIDs, containerFilters, cfg.Labels, cfg.Stream)
+	input.onParseError = cfg.OnParseError
+	input.logDir = cfg.LogDir
+	input.pool = workpool.New(cfg.MaxStreams, cfg.StreamSlice)
+
+	if cfg.UseAPI {
+		client, err := newAPIClient(cfg.Host, cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		input.api = client
+	}
+
+	return input, nil
 }
 
-// DockerInput reads logs from Docker containers using docker logs command
+// DockerInput reads logs from Docker containers using the docker CLI or the Docker Engine API
 type DockerInput struct {
 	name             string // Name for this input instance
 	containerIDs     []string
 	containerFilters []string // Filter by name patterns (multiple patterns supported)
 	labels           map[string]string
 	stream           string // "stdout", "stderr", or "both"
+	onParseError     string // How lines without a detectable level are handled (see core.HandleParseError)
 	logCh            chan<- *core.Log
 	stopCh           chan struct{}
+	ctx              context.Context // Cancelled on Stop to end log streams
+	cancel           context.CancelFunc
 	wg               sync.WaitGroup
 	stopped          bool
+
+	api    *apiClient // Docker Engine API client (nil = use the docker CLI)
+	logDir string     // json-file mode: containers directory to tail log files from ("" = off)
+	names  sync.Map   // Container ID -> name cache (API and json-file modes)
+
+	pool     *workpool.Pool       // Bounds the containers streamed at once
+	resumeMu sync.Mutex           // Guards resume
+	resume   map[string]time.Time // Limited pool: time of the last log delivered per container
 }
 
 // NewDockerInput creates a new Docker input plugin
@@ -82,6 +164,8 @@ func NewDockerInput(containerIDs []string, containerFilters []string, labels map
 		stream = "stdout"
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &DockerInput{
 		name:             "docker",
 		containerIDs:     containerIDs,
@@ -89,6 +173,10 @@ func NewDockerInput(containerIDs []string, containerFilters []string, labels map
 		labels:           labels,
 		stream:           stream,
 		stopCh:           make(chan struct{}),
+		ctx:              ctx,
+		cancel:           cancel,
+		pool:             workpool.New(0, 0),
+		resume:           make(map[string]time.Time),
 	}
 }
 
@@ -99,6 +187,10 @@ func (d *DockerInput) SetName(name string) {
 
 // Start begins reading from Docker containers
 func (d *DockerInput) Start() error {
+	if d.logDir != "" {
+		return d.startJSONFile()
+	}
+
 	// Get containers to monitor
 	containers, err := d.getContainersToMonitor()
 	if err != nil {
@@ -106,18 +198,85 @@ func (d *DockerInput) Start() error {
 	}
 
 	if len(containers) == 0 {
-		log.Printf("Docker input: No containers found to monitor")
+		logger.Printf("No containers found to monitor")
+		return nil
+	}
+
+	logger.Printf("Docker input started, monitoring %d containers", len(containers))
+	d.runStreams(containers, d.monitorContainer)
+	return nil
+}
+
+// runStreams follows the containers through the pool in the background. With
+// max_streams set, each container resumes after the last log it delivered, so the
+// logs written while it waited for a slot are read on its next turn.
+func (d *DockerInput) runStreams(containers []string, follow workpool.Func) {
+	if limit := d.pool.Limit(); limit > 0 {
+		now := time.Now()
+		d.resumeMu.Lock()
+		for _, containerID := range containers {
+			d.resume[containerID] = now
+		}
+		d.resumeMu.Unlock()
+		if len(containers) > limit {
+			logger.Printf("Streaming at most %d of %d containers at a time", limit, len(containers))
+		}
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.pool.Run(d.ctx, containers, follow)
+	}()
+}
+
+// resumePoint returns the time of the last log delivered for a container, or the
+// zero time when streams are not resumed (no max_streams)
+func (d *DockerInput) resumePoint(containerID string) time.Time {
+	d.resumeMu.Lock()
+	defer d.resumeMu.Unlock()
+	return d.resume[containerID]
+}
+
+// advance records the time of the last log delivered for a container
+func (d *DockerInput) advance(containerID string, at time.Time) {
+	d.resumeMu.Lock()
+	defer d.resumeMu.Unlock()
+	if at.After(d.resume[containerID]) {
+		d.resume[containerID] = at
+	}
+}
+
+// InputStats implements core.InputStatsReporter when max_streams is set
+func (d *DockerInput) InputStats() map[string]any {
+	if d.pool.Limit() == 0 {
 		return nil
 	}
+	return map[string]any{
+		"active_streams":  d.pool.Active(),
+		"waiting_streams": d.pool.Waiting(),
+	}
+}
 
-	log.Printf("Docker input started, monitoring %d containers", len(containers))
+// startJSONFile starts tailing the json-file logs of the matching containers
+func (d *DockerInput) startJSONFile() error {
+	for _, filter := range d.containerFilters {
+		if !isValidDockerFilter(filter) {
+			return fmt.Errorf("invalid docker filter: %s", filter)
+		}
+	}
 
-	// Start monitoring each container
-	for _, container := range containers {
-		d.wg.Add(1)
-		go d.monitorContainer(container)
+	containers, err := d.discoverJSONFileContainers()
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		logger.Printf("No containers found to monitor in %s", d.logDir)
+		return nil
 	}
 
+	logger.Printf("Docker input started, tailing json-file logs of %d containers in %s", len(containers), d.logDir)
+	d.runStreams(containers, d.followJSONFile)
 	return nil
 }
 
@@ -127,9 +286,10 @@ func (d *DockerInput) Stop() error {
 		return nil
 	}
 	d.stopped = true
+	d.cancel()
 	close(d.stopCh)
 	d.wg.Wait()
-	log.Printf("Docker input stopped")
+	logger.Printf("Docker input stopped")
 	return nil
 }
 
@@ -154,18 +314,13 @@ func (d *DockerInput) getContainersToMonitor() ([]string, error) {
 			if !isValidDockerFilter(filter) {
 				return nil, fmt.Errorf("invalid docker filter: %s", filter)
 			}
-			cmd := exec.Command("docker", "ps", "--filter", "name="+filter, "--format", "{{.ID}}") // #nosec G204 - filter validated by isValidDockerFilter above
-			output, err := cmd.Output()
+			ids, err := d.listContainers(filter)
 			if err != nil {
 				return nil, err
 			}
 
-			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-			for _, line := range lines {
-				if line != "" {
-					containerID := strings.TrimSpace(line)
-					containerMap[containerID] = true
-				}
+			for _, containerID := range ids {
+				containerMap[containerID] = true
 			}
 		}
 
@@ -175,18 +330,11 @@ func (d *DockerInput) getContainersToMonitor() ([]string, error) {
 		}
 	} else {
 		// Get all running containers
-		cmd := exec.Command("docker", "ps", "--format", "{{.ID}}")
-		output, err := cmd.Output()
+		ids, err := d.listContainers("")
 		if err != nil {
 			return nil, err
 		}
-
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		for _, line := range lines {
-			if line != "" {
-				containers = append(containers, strings.TrimSpace(line))
-			}
-		}
+		containers = ids
 	}
 
 	// Filter by labels if specified
@@ -203,12 +351,54 @@ func (d *DockerInput) getContainersToMonitor() ([]string, error) {
 	return containers, nil
 }
 
+// listContainers returns the IDs of running containers, optionally filtered by name
+func (d *DockerInput) listContainers(nameFilter string) ([]string, error) {
+	if d.api != nil {
+		ctx, cancel := context.WithTimeout(d.ctx, apiRequestTimeout)
+		defer cancel()
+		return d.api.listContainers(ctx, nameFilter)
+	}
+
+	args := []string{"ps", "--format", "{{.ID}}"}
+	if nameFilter != "" {
+		args = append(args, "--filter", "name="+nameFilter)
+	}
+	cmd := exec.Command("docker", args...) // #nosec G204 - filter validated by isValidDockerFilter
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	for _, line := range lines {
+		if line != "" {
+			ids = append(ids, strings.TrimSpace(line))
+		}
+	}
+	return ids, nil
+}
+
 // containerMatchesLabels checks if a container matches the specified labels
 func (d *DockerInput) containerMatchesLabels(containerID string) bool {
+	if d.api != nil {
+		info, err := d.inspectContainer(containerID)
+		if err != nil {
+			logger.Printf("Error inspecting container %s: %v", containerID, err)
+			return false
+		}
+		for key, value := range d.labels {
+			if info.Config.Labels[key] != value {
+				return false
+			}
+		}
+		return true
+	}
+
 	cmd := exec.Command("docker", "inspect", "--format", "{{json .Config.Labels}}", containerID)
 	output, err := cmd.Output()
 	if err != nil {
-		log.Printf("Error inspecting container %s: %v", containerID, err)
+		logger.Printf("Error inspecting container %s: %v", containerID, err)
 		return false
 	}
 
@@ -224,64 +414,137 @@ func (d *DockerInput) containerMatchesLabels(containerID string) bool {
 	return true
 }
 
-// monitorContainer monitors logs from a specific container
-func (d *DockerInput) monitorContainer(containerID string) {
-	defer d.wg.Done()
+// inspectContainer inspects a container through the API and caches its name
+func (d *DockerInput) inspectContainer(containerID string) (*containerInfo, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, apiRequestTimeout)
+	defer cancel()
 
-	// Build docker logs command
-	args := []string{"logs", "-f"} // -f for follow
-
-	// Add stream filter
-	switch d.stream {
-	case "stdout":
-		args = append(args, "--tail", "0") // Start from end
-	case "stderr":
-		args = append(args, "--tail", "0")
-	case "both":
-		args = append(args, "--tail", "0")
+	info, err := d.api.inspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
 	}
+	d.names.Store(containerID, strings.TrimPrefix(info.Name, "/"))
+	return info, nil
+}
 
-	args = append(args, containerID)
+// cliLogStream is the stdout of a `docker logs -f` process
+type cliLogStream struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close kills the docker logs process and reaps it
+func (s *cliLogStream) Close() error {
+	_ = s.cmd.Process.Kill()
+	_ = s.cmd.Wait()
+	return nil
+}
 
-	cmd := exec.Command("docker", args...)
+// openLogStream follows the logs of a container from now on or, when since is
+// set, from that time on with each line prefixed by its Docker timestamp
+func (d *DockerInput) openLogStream(ctx context.Context, containerID string, since time.Time) (io.ReadCloser, error) {
+	if d.api != nil {
+		info, err := d.inspectContainer(containerID)
+		if err != nil {
+			return nil, err
+		}
+		return d.api.streamLogs(ctx, containerID, d.stream, info.Config.Tty, since)
+	}
+
+	// -f to follow, --tail 0 to start from the end
+	args := []string{"logs", "-f", "--tail", "0", containerID}
+	if !since.IsZero() {
+		args = []string{"logs", "-f", "--timestamps", "--since", since.UTC().Format(time.RFC3339Nano), containerID}
+	}
+	cmd := exec.Command("docker", args...) // #nosec G204 - container ID passed as a single argument
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		log.Printf("Error creating stdout pipe for container %s: %v", containerID, err)
-		return
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
-
 	if err := cmd.Start(); err != nil {
-		log.Printf("Error starting docker logs for container %s: %v", containerID, err)
-		return
+		return nil, err
+	}
+	return &cliLogStream{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+// monitorContainer streams the logs of a container until ctx is done (the input
+// stopped or the container's turn ended) or the stream ends. It implements
+// workpool.Func: it returns true once the container has nothing more to stream.
+func (d *DockerInput) monitorContainer(ctx context.Context, containerID string) bool {
+	since := d.resumePoint(containerID)
+	stream, err := d.openLogStream(ctx, containerID, since)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		logger.Printf("Error starting docker logs for container %s: %v", containerID, err)
+		return true
 	}
 
-	scanner := bufio.NewScanner(stdout)
-	for {
+	// Closing the stream unblocks the scanner when the input is stopped or the turn ends
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
 		select {
-		case <-d.stopCh:
-			_ = cmd.Process.Kill()
-			return
-		default:
-			if scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				if line != "" {
-					logEntry := d.ParseLogLine(line, containerID)
-					select {
-					case d.logCh <- logEntry:
-					case <-d.stopCh:
-						_ = cmd.Process.Kill()
-						return
-					}
-				}
-			} else {
-				// Command finished or error occurred
-				if err := scanner.Err(); err != nil {
-					log.Printf("Error reading logs from container %s: %v", containerID, err)
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = stream.Close()
+	}()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		var at time.Time
+		if !since.IsZero() {
+			if ts, rest, ok := splitTimestamp(line); ok {
+				if !ts.After(since) {
+					continue // Delivered in a previous turn
 				}
-				return
+				at, line = ts, rest
+			}
+		}
+		if line == "" {
+			continue
+		}
+
+		logEntry := d.ParseLogLine(line, containerID)
+		if logEntry == nil {
+			continue
+		}
+		if !at.IsZero() {
+			logEntry.Timestamp = at
+		}
+		select {
+		case d.logCh <- logEntry:
+			if !at.IsZero() {
+				d.advance(containerID, at)
 			}
+		case <-ctx.Done():
+			return false
 		}
 	}
+
+	// Stream finished or error occurred
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		logger.Printf("Error reading logs from container %s: %v", containerID, err)
+	}
+	return ctx.Err() == nil
+}
+
+// splitTimestamp splits the RFC 3339 timestamp that docker logs --timestamps
+// puts in front of each line from the rest of the line
+func splitTimestamp(line string) (time.Time, string, bool) {
+	prefix, rest, found := strings.Cut(line, " ")
+	if !found {
+		prefix, rest = line, ""
+	}
+	at, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return at, strings.TrimSpace(rest), true
 }
 
 // ParseLogLine parses a log line into a Log struct (public for testing)
@@ -292,19 +555,9 @@ func (d *DockerInput) ParseLogLine(line string, containerID string) *core.Log {
 	}
 
 	// Simple parsing - try to extract level from common patterns
-	level := "info"
+	level, parsed := core.Levels().DetectKnown(line)
 	message := line
 
-	// Look for common log level patterns
-	lowerLine := strings.ToLower(line)
-	if strings.Contains(lowerLine, "error") || strings.Contains(lowerLine, "err") {
-		level = "error"
-	} else if strings.Contains(lowerLine, "warn") || strings.Contains(lowerLine, "warning") {
-		level = "warn"
-	} else if strings.Contains(lowerLine, "debug") {
-		level = "debug"
-	}
-
 	metadata := map[string]string{
 		"source": "docker",
 	}
@@ -323,11 +576,32 @@ func (d *DockerInput) ParseLogLine(line string, containerID string) *core.Log {
 
 	logEntry := core.NewLogWithMetadata(level, message, metadata)
 	logEntry.Source = d.name // Set the source to the input name
+	if !parsed {
+		return core.HandleParseError(d.onParseError, logEntry, "no level detected")
+	}
 	return logEntry
 }
 
 // getContainerName gets the name of a container
 func (d *DockerInput) getContainerName(containerID string) string {
+	if d.logDir != "" {
+		// Read from config.v2.json when the container was discovered
+		if name, ok := d.names.Load(containerID); ok {
+			return name.(string)
+		}
+		return ""
+	}
+	if d.api != nil {
+		if name, ok := d.names.Load(containerID); ok {
+			return name.(string)
+		}
+		info, err := d.inspectContainer(containerID)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimPrefix(info.Name, "/")
+	}
+
 	cmd := exec.Command("docker", "inspect", "--format", "{{.Name}}", containerID)
 	output, err := cmd.Output()
 	if err != nil {
@@ -290,3 +290,27 @@ func TestContainerIDHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLogLineOnParseError(t *testing.T) {
+	plugin, err := NewDockerInputFromConfig(map[string]any{"on_parse_error": "tag"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := plugin.(*DockerInput)
+
+	if logEntry := input.ParseLogLine("ERROR failed", "abc123"); logEntry.HasTag(core.ParseErrorKey) {
+		t.Error("Expected line with a level not to be tagged")
+	}
+
+	logEntry := input.ParseLogLine("GET /health 200", "abc123")
+	if !logEntry.HasTag(core.ParseErrorKey) || logEntry.Metadata[core.ParseErrorKey] != "true" {
+		t.Errorf("Expected parse_error tag and metadata, got %v %v", logEntry.Tags, logEntry.Metadata)
+	}
+	if logEntry.Message != "GET /health 200" {
+		t.Errorf("Expected raw line as message, got %q", logEntry.Message)
+	}
+
+	if _, err := NewDockerInputFromConfig(map[string]any{"on_parse_error": "skip"}); err == nil {
+		t.Error("Expected error for invalid on_parse_error")
+	}
+}
@@ -0,0 +1,210 @@
+package dockerinput
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// writeContainer creates a container directory with a config.v2.json and an empty json-file log
+func writeContainer(t *testing.T, dir, id, name string, labels map[string]string) string {
+	t.Helper()
+	containerDir := filepath.Join(dir, id)
+	if err := os.MkdirAll(containerDir, 0o755); err != nil {
+		t.Fatalf("Failed to create container dir: %v", err)
+	}
+	config := map[string]any{"Name": "/" + name, "Config": map[string]any{"Labels": labels}}
+	data, _ := json.Marshal(config)
+	if err := os.WriteFile(filepath.Join(containerDir, "config.v2.json"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	logPath := filepath.Join(containerDir, id+"-json.log")
+	if err := os.WriteFile(logPath, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	return logPath
+}
+
+// appendEntries appends json-file entries to a log file
+func appendEntries(t *testing.T, path string, entries ...jsonFileEntry) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+}
+
+func TestDiscoverJSONFileContainers(t *testing.T) {
+	dir := t.TempDir()
+	writeContainer(t, dir, "aaa111", "web-1", map[string]string{"app": "web"})
+	writeContainer(t, dir, "bbb222", "db", map[string]string{"app": "db"})
+	writeContainer(t, dir, "ccc333", "web-2", map[string]string{"app": "web", "env": "dev"})
+
+	tests := []struct {
+		name     string
+		ids      []string
+		filters  []string
+		labels   map[string]string
+		expected []string
+	}{
+		{name: "all", expected: []string{"aaa111", "bbb222", "ccc333"}},
+		{name: "id prefix", ids: []string{"bbb"}, expected: []string{"bbb222"}},
+		{name: "name filter", filters: []string{"web"}, expected: []string{"aaa111", "ccc333"}},
+		{name: "labels", labels: map[string]string{"app": "web", "env": "dev"}, expected: []string{"ccc333"}},
+		{name: "no match", filters: []string{"cache"}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := NewDockerInput(tt.ids, tt.filters, tt.labels, "stdout")
+			input.logDir = dir
+			containers, err := input.discoverJSONFileContainers()
+			if err != nil {
+				t.Fatalf("Discovery failed: %v", err)
+			}
+			sort.Strings(containers)
+			if len(containers) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, containers)
+			}
+			for i := range containers {
+				if containers[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, containers)
+				}
+			}
+		})
+	}
+
+	input := NewDockerInput(nil, nil, nil, "stdout")
+	input.logDir = dir
+	if _, err := input.discoverJSONFileContainers(); err != nil {
+		t.Fatalf("Discovery failed: %v", err)
+	}
+	if name := input.getContainerName("ccc333"); name != "web-2" {
+		t.Errorf("Expected name 'web-2', got '%s'", name)
+	}
+}
+
+func TestNewDockerInputSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]any
+		wantErr bool
+		logDir  string
+		useAPI  bool
+	}{
+		{name: "default cli", config: map[string]any{}},
+		{name: "api", config: map[string]any{"source": "api"}, useAPI: true},
+		{name: "json-file default dir", config: map[string]any{"source": "json-file"}, logDir: DefaultLogDir},
+		{name: "json-file custom dir", config: map[string]any{"source": "json-file", "log_dir": "/data/containers"}, logDir: "/data/containers"},
+		{name: "json-file with use_api", config: map[string]any{"source": "json-file", "use_api": true}, wantErr: true},
+		{name: "log_dir without json-file", config: map[string]any{"log_dir": "/data"}, wantErr: true},
+		{name: "unknown source", config: map[string]any{"source": "journald"}, wantErr: true},
+		{name: "invalid stream", config: map[string]any{"source": "json-file", "stream": "stdin"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, err := NewDockerInputFromConfig(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			input := plugin.(*DockerInput)
+			if input.logDir != tt.logDir {
+				t.Errorf("Expected log dir '%s', got '%s'", tt.logDir, input.logDir)
+			}
+			if (input.api != nil) != tt.useAPI {
+				t.Errorf("Expected API client %v, got %v", tt.useAPI, input.api != nil)
+			}
+		})
+	}
+}
+
+func TestDockerInputJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	id := "abc123def456789"
+	logPath := writeContainer(t, dir, id, "web", nil)
+
+	plugin, err := NewDockerInputFromConfig(map[string]any{
+		"source":  "json-file",
+		"log_dir": dir,
+		"stream":  "stderr",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create docker input: %v", err)
+	}
+	input := plugin.(*DockerInput)
+
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start docker input: %v", err)
+	}
+	defer func() { _ = input.Stop() }()
+
+	// Let the follower open the file before writing; it starts at the end
+	time.Sleep(100 * time.Millisecond)
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	appendEntries(t, logPath,
+		jsonFileEntry{Log: "ignored stdout line\n", Stream: "stdout", Time: at},
+		jsonFileEntry{Log: "[ERROR] first ", Stream: "stderr", Time: at},
+		jsonFileEntry{Log: "half\n", Stream: "stderr", Time: at},
+	)
+
+	// Rotate the way Docker does: rename to .1 and start a new file
+	time.Sleep(100 * time.Millisecond)
+	if err := os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatalf("Failed to rotate log: %v", err)
+	}
+	appendEntries(t, logPath, jsonFileEntry{Log: "[WARN] after rotation\n", Stream: "stderr", Time: at.Add(time.Second)})
+
+	var received []*core.Log
+	for len(received) < 2 {
+		select {
+		case logEntry := <-logCh:
+			received = append(received, logEntry)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for logs, got %d", len(received))
+		}
+	}
+
+	if received[0].Message != "[ERROR] first half" || received[0].Level != "error" {
+		t.Errorf("Unexpected first log: %s - %s", received[0].Level, received[0].Message)
+	}
+	if received[0].Metadata["stream"] != "stderr" {
+		t.Errorf("Expected stream 'stderr', got '%s'", received[0].Metadata["stream"])
+	}
+	if !received[0].Timestamp.Equal(at) {
+		t.Errorf("Expected timestamp %v, got %v", at, received[0].Timestamp)
+	}
+	if received[0].Metadata["name"] != "web" {
+		t.Errorf("Expected container name 'web', got '%s'", received[0].Metadata["name"])
+	}
+	if received[1].Message != "[WARN] after rotation" {
+		t.Errorf("Expected log from the rotated file, got '%s'", received[1].Message)
+	}
+
+	select {
+	case logEntry := <-logCh:
+		t.Errorf("Unexpected extra log: %s", logEntry.Message)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
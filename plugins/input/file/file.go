@@ -2,14 +2,24 @@ package fileinput
 
 import (
 	"bufio"
-	"log"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
 )
 
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.file")
+
 func init() {
 	// Auto-register this plugin
 	core.RegisterInputPlugin("file", NewFileInputFromConfig)
@@ -17,8 +27,31 @@ func init() {
 
 // Config represents file input configuration
 type Config struct {
-	Path     string `yaml:"path"`
-	Encoding string `yaml:"encoding,omitempty"`
+	Path         string `yaml:"path"`
+	Encoding     string `yaml:"encoding,omitempty"`       // utf-8 (default), utf-16, utf-16le, utf-16be, latin1, windows-1252
+	OnParseError string `yaml:"on_parse_error,omitempty"` // Lines without a [LEVEL] prefix: pass_raw, tag or drop (default: default level)
+}
+
+// encodings maps supported encoding names to their decoders (nil = UTF-8, read as-is)
+var encodings = map[string]encoding.Encoding{
+	"utf-8":        nil,
+	"utf8":         nil,
+	"utf-16":       unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), // Endianness from the BOM, little-endian without one
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+	"cp1252":       charmap.Windows1252,
+}
+
+// resolveEncoding returns the decoder for an encoding name, or an error if it is not supported
+func resolveEncoding(name string) (encoding.Encoding, error) {
+	enc, ok := encodings[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q (supported: utf-8, utf-16, utf-16le, utf-16be, latin1, windows-1252)", name)
+	}
+	return enc, nil
 }
 
 // NewFileInputFromConfig creates a file input from configuration map
@@ -33,18 +66,29 @@ func NewFileInputFromConfig(config map[string]any) (any, error) {
 		cfg.Encoding = "utf-8"
 	}
 
-	return NewFileInput(cfg.Path), nil
+	if err := core.ValidateParseErrorMode(cfg.OnParseError); err != nil {
+		return nil, err
+	}
+
+	input, err := NewFileInputWithEncoding(cfg.Path, cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	input.onParseError = cfg.OnParseError
+	return input, nil
 }
 
 // FileInput reads logs from a file
 type FileInput struct {
-	filePath string
-	file     *os.File
-	scanner  *bufio.Scanner
-	logCh    chan<- *core.Log
-	stopCh   chan struct{}
-	wg       sync.WaitGroup
-	stopped  bool // Flag to prevent multiple stops
+	filePath     string
+	encoding     encoding.Encoding // Source encoding transcoded to UTF-8 (nil = UTF-8)
+	onParseError string            // How lines without a level prefix are handled (see core.HandleParseError)
+	file         *os.File
+	scanner      *bufio.Scanner
+	logCh        chan<- *core.Log
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+	stopped      bool // Flag to prevent multiple stops
 }
 
 // NewFileInput creates a new file input plugin
@@ -55,6 +99,28 @@ func NewFileInput(filePath string) *FileInput {
 	}
 }
 
+// NewFileInputWithEncoding creates a file input that transcodes the file from the given encoding to UTF-8
+func NewFileInputWithEncoding(filePath string, encodingName string) (*FileInput, error) {
+	enc, err := resolveEncoding(encodingName)
+	if err != nil {
+		return nil, err
+	}
+
+	input := NewFileInput(filePath)
+	input.encoding = enc
+	return input, nil
+}
+
+// newDecodingReader wraps r so it yields UTF-8. Transcoding happens before line
+// splitting because UTF-16 newlines are two bytes wide. A leading BOM is consumed
+// and, for UTF-16, selects the byte order.
+func newDecodingReader(r io.Reader, enc encoding.Encoding) io.Reader {
+	if enc == nil {
+		return r
+	}
+	return transform.NewReader(r, unicode.BOMOverride(enc.NewDecoder()))
+}
+
 // Start begins reading from the file
 func (f *FileInput) Start() error {
 	file, err := os.Open(f.filePath)
@@ -62,11 +128,11 @@ func (f *FileInput) Start() error {
 		return err
 	}
 	f.file = file
-	f.scanner = bufio.NewScanner(file)
+	f.scanner = bufio.NewScanner(newDecodingReader(file, f.encoding))
 
 	f.wg.Add(1)
 	go f.readLines()
-	log.Printf("File input started for: %s", f.filePath)
+	logger.Printf("File input started for: %s", f.filePath)
 	return nil
 }
 
@@ -82,7 +148,7 @@ func (f *FileInput) Stop() error {
 	if f.file != nil {
 		return f.file.Close()
 	}
-	log.Printf("File input stopped for: %s", f.filePath)
+	logger.Printf("File input stopped for: %s", f.filePath)
 	return nil
 }
 
@@ -103,6 +169,9 @@ func (f *FileInput) readLines() {
 			line := strings.TrimSpace(f.scanner.Text())
 			if line != "" {
 				logEntry := f.parseLogLine(line, f.filePath)
+				if logEntry == nil {
+					continue
+				}
 				select {
 				case f.logCh <- logEntry:
 				case <-f.stopCh:
@@ -113,7 +182,7 @@ func (f *FileInput) readLines() {
 	}
 
 	if err := f.scanner.Err(); err != nil {
-		log.Printf("Error reading file %s: %v", f.filePath, err)
+		logger.Printf("Error reading file %s: %v", f.filePath, err)
 	}
 }
 
@@ -130,38 +199,21 @@ func (f *FileInput) parseLogLine(line string, filePath string) *core.Log {
 		return nil
 	}
 
-	// Simple parsing - in a real implementation, you'd use regex or structured parsing
-	// For now, assume format: [LEVEL] message
-	level := "info" // default
+	// Lines may start with a [LEVEL] prefix; any level or alias from the
+	// configured vocabulary is recognized (e.g. [ERROR], [warning], [CRIT])
+	levels := core.Levels()
+	level := levels.Default()
 	message := line
-
-	// Convert to lowercase for case-insensitive matching
-	lowerLine := strings.ToLower(line)
-
-	if strings.HasPrefix(lowerLine, "[error]") || strings.HasPrefix(lowerLine, "[err]") {
-		level = "error"
-		message = strings.TrimPrefix(line, "[ERROR]")
-		message = strings.TrimPrefix(message, "[Error]")
-		message = strings.TrimPrefix(message, "[error]")
-		message = strings.TrimPrefix(message, "[ERR]")
-		message = strings.TrimPrefix(message, "[Err]")
-		message = strings.TrimPrefix(message, "[err]")
-	} else if strings.HasPrefix(lowerLine, "[warn]") || strings.HasPrefix(lowerLine, "[warning]") {
-		level = "warn"
-		message = strings.TrimPrefix(line, "[WARN]")
-		message = strings.TrimPrefix(message, "[Warning]")
-		message = strings.TrimPrefix(message, "[warning]")
-		message = strings.TrimPrefix(message, "[WARNING]")
-	} else if strings.HasPrefix(lowerLine, "[info]") {
-		level = "info"
-		message = strings.TrimPrefix(line, "[INFO]")
-		message = strings.TrimPrefix(message, "[Info]")
-		message = strings.TrimPrefix(message, "[info]")
-	} else if strings.HasPrefix(lowerLine, "[debug]") {
-		level = "debug"
-		message = strings.TrimPrefix(line, "[DEBUG]")
-		message = strings.TrimPrefix(message, "[Debug]")
-		message = strings.TrimPrefix(message, "[debug]")
+	parsed := false
+
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 1 {
+			if token := line[1:end]; levels.Known(token) {
+				level = levels.Normalize(token)
+				message = line[end+1:]
+				parsed = true
+			}
+		}
 	}
 
 	message = strings.TrimSpace(message)
@@ -171,5 +223,9 @@ func (f *FileInput) parseLogLine(line string, filePath string) *core.Log {
 		"file":   filePath,
 	}
 
-	return core.NewLogWithMetadata(level, message, metadata)
+	logEntry := core.NewLogWithMetadata(level, message, metadata)
+	if !parsed {
+		return core.HandleParseError(f.onParseError, logEntry, "no level prefix")
+	}
+	return logEntry
 }
@@ -289,3 +289,146 @@ func TestParseLogLineComplexMessages(t *testing.T) {
 		}
 	}
 }
+
+func TestFileInputEncodings(t *testing.T) {
+	// "[ERROR] Café naïve" encoded in each source encoding
+	tests := []struct {
+		name     string
+		encoding string
+		data     []byte
+	}{
+		{
+			name:     "utf-16 with little-endian BOM",
+			encoding: "utf-16",
+			data:     append([]byte{0xFF, 0xFE}, utf16Bytes("[ERROR] Café naïve\r\n", false)...),
+		},
+		{
+			name:     "utf-16 with big-endian BOM",
+			encoding: "utf-16",
+			data:     append([]byte{0xFE, 0xFF}, utf16Bytes("[ERROR] Café naïve\r\n", true)...),
+		},
+		{
+			name:     "utf-16le without BOM",
+			encoding: "utf-16le",
+			data:     utf16Bytes("[ERROR] Café naïve\n", false),
+		},
+		{
+			name:     "utf-16be with BOM",
+			encoding: "UTF-16BE",
+			data:     append([]byte{0xFE, 0xFF}, utf16Bytes("[ERROR] Café naïve\n", true)...),
+		},
+		{
+			name:     "latin1",
+			encoding: "latin1",
+			data:     []byte("[ERROR] Caf\xe9 na\xefve\n"),
+		},
+		{
+			name:     "windows-1252",
+			encoding: "windows-1252",
+			data:     []byte("[ERROR] Caf\xe9 na\xefve\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFile := filepath.Join(t.TempDir(), "legacy.log")
+			if err := os.WriteFile(tempFile, tt.data, 0644); err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			plugin, err := NewFileInputFromConfig(map[string]any{"path": tempFile, "encoding": tt.encoding})
+			if err != nil {
+				t.Fatalf("Failed to create file input: %v", err)
+			}
+			input := plugin.(*FileInput)
+			logCh := make(chan *core.Log, 1)
+			input.SetLogChannel(logCh)
+
+			if err := input.Start(); err != nil {
+				t.Fatalf("Failed to start file input: %v", err)
+			}
+			defer func() { _ = input.Stop() }()
+
+			select {
+			case logEntry := <-logCh:
+				if logEntry.Level != "error" {
+					t.Errorf("Expected level error, got %s", logEntry.Level)
+				}
+				if logEntry.Message != "Café naïve" {
+					t.Errorf("Expected message %q, got %q", "Café naïve", logEntry.Message)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for log")
+			}
+		})
+	}
+}
+
+func TestFileInputUnsupportedEncoding(t *testing.T) {
+	if _, err := NewFileInputFromConfig(map[string]any{"path": "/tmp/test.log", "encoding": "ebcdic"}); err == nil {
+		t.Error("Expected error for unsupported encoding")
+	}
+}
+
+// utf16Bytes encodes ASCII/Latin-1 text as UTF-16 without a BOM
+func utf16Bytes(s string, bigEndian bool) []byte {
+	var out []byte
+	for _, r := range s {
+		hi, lo := byte(r>>8), byte(r)
+		if bigEndian {
+			out = append(out, hi, lo)
+		} else {
+			out = append(out, lo, hi)
+		}
+	}
+	return out
+}
+
+func TestFileInputOnParseError(t *testing.T) {
+	tests := []struct {
+		mode      string
+		expectNil bool
+		expectTag bool
+	}{
+		{mode: ""},
+		{mode: "pass_raw"},
+		{mode: "tag", expectTag: true},
+		{mode: "drop", expectNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			plugin, err := NewFileInputFromConfig(map[string]any{"path": "/tmp/app.log", "on_parse_error": tt.mode})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			input := plugin.(*FileInput)
+
+			if logEntry := input.ParseLogLine("[ERROR] parsed", "/tmp/app.log"); logEntry == nil || logEntry.Metadata[core.ParseErrorKey] != "" {
+				t.Errorf("Expected prefixed line to parse normally, got %+v", logEntry)
+			}
+
+			logEntry := input.ParseLogLine("no prefix here", "/tmp/app.log")
+			if tt.expectNil {
+				if logEntry != nil {
+					t.Error("Expected line without prefix to be dropped")
+				}
+				return
+			}
+			if logEntry == nil {
+				t.Fatal("Expected a log")
+			}
+			expectMarker := tt.mode != ""
+			if (logEntry.Metadata[core.ParseErrorKey] == "true") != expectMarker {
+				t.Errorf("Expected parse_error marker %v, got %v", expectMarker, logEntry.Metadata)
+			}
+			if logEntry.HasTag(core.ParseErrorKey) != tt.expectTag {
+				t.Errorf("Expected parse_error tag %v, got %v", tt.expectTag, logEntry.Tags)
+			}
+		})
+	}
+
+	if _, err := NewFileInputFromConfig(map[string]any{"path": "/tmp/app.log", "on_parse_error": "ignore"}); err == nil {
+		t.Error("Expected error for invalid on_parse_error")
+	}
+}
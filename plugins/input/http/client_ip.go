@@ -0,0 +1,163 @@
+package httpinput
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultClientIPHeader is the header read from trusted proxies
+	DefaultClientIPHeader = "X-Forwarded-For"
+
+	// ClientIPKey is the metadata key of the resolved client IP
+	ClientIPKey = "client_ip"
+
+	// maxRateLimitedClients bounds the buckets kept by a per-client rate limiter
+	maxRateLimitedClients = 10000
+)
+
+// parseTrustedProxies parses trusted proxy IPs and CIDRs. A plain IP trusts that address only.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// trustedProxy reports whether addr is one of the trusted proxies
+func (h *HTTPInput) trustedProxy(addr netip.Addr) bool {
+	for _, prefix := range h.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP of the client that sent r. The client IP header is only read
+// when the direct peer is a trusted proxy, so other clients cannot spoof their address.
+// The header is walked from the right, where each proxy appends the address it received
+// the request from, and the first address that is not a trusted proxy is the client.
+// Entries left of it were written by the client and are ignored.
+func (h *HTTPInput) clientIP(r *http.Request) string {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !h.trustedProxy(peer) {
+		return peer.String()
+	}
+
+	// Repeated header lines form one list, in the order they were received
+	var entries []string
+	for _, value := range r.Header.Values(h.config.ClientIPHeader) {
+		entries = append(entries, strings.Split(value, ",")...)
+	}
+
+	client := peer
+	for i := len(entries) - 1; i >= 0; i-- {
+		addr, ok := parseIP(entries[i])
+		if !ok {
+			// An unparseable hop cannot be checked, so nothing left of it is believed
+			break
+		}
+		client = addr
+		if !h.trustedProxy(addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// parseIP parses an address as found in RemoteAddr or a forwarding header: an IP with an
+// optional port, brackets or quotes
+func parseIP(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// clientRateLimiter keeps a token bucket per client IP. Buckets that have refilled are
+// equivalent to new ones, so they are dropped when the limit of tracked clients is reached.
+type clientRateLimiter struct {
+	rate     float64
+	burst    int
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+// newClientRateLimiter creates a per-client rate limiter with the given rate and burst
+func newClientRateLimiter(rate float64, burst int) *clientRateLimiter {
+	return &clientRateLimiter{
+		rate:     rate,
+		burst:    burst,
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+// Allow checks if a request from client should be allowed
+func (c *clientRateLimiter) Allow(client string) bool {
+	c.mu.Lock()
+	limiter, ok := c.limiters[client]
+	if !ok {
+		if len(c.limiters) >= maxRateLimitedClients {
+			c.prune()
+		}
+		limiter = NewRateLimiter(c.rate, c.burst)
+		c.limiters[client] = limiter
+	}
+	c.mu.Unlock()
+
+	return limiter.Allow()
+}
+
+// prune drops the buckets that have refilled. If every tracked client is still limited,
+// an arbitrary one is dropped so the number of buckets stays bounded.
+func (c *clientRateLimiter) prune() {
+	now := time.Now()
+	for client, limiter := range c.limiters {
+		if limiter.full(now) {
+			delete(c.limiters, client)
+		}
+	}
+	for client := range c.limiters {
+		if len(c.limiters) < maxRateLimitedClients {
+			break
+		}
+		delete(c.limiters, client)
+	}
+}
+
+// full reports whether the bucket would be full at now
+func (r *RateLimiter) full(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokens+now.Sub(r.lastRefill).Seconds()*r.rate >= float64(r.burst)
+}
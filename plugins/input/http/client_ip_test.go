@@ -0,0 +1,156 @@
+package httpinput
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func TestHTTPInputClientIP(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     []string
+		expected   string
+	}{
+		{"untrusted peer ignores header", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted peer without header", "10.0.0.1:5000", nil, "10.0.0.1"},
+		{"single hop", "10.0.0.1:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client spoofs left entries", "10.0.0.1:5000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.1:5000", []string{"198.51.100.1, 10.1.1.1,10.2.2.2"}, "198.51.100.1"},
+		{"repeated header lines", "10.0.0.1:5000", []string{"1.1.1.1", "198.51.100.1, 10.1.1.1"}, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.1:5000", []string{"10.9.9.9, 10.1.1.1"}, "10.9.9.9"},
+		{"invalid hop stops the walk", "10.0.0.1:5000", []string{"198.51.100.1, unknown, 10.1.1.1"}, "10.1.1.1"},
+		{"garbage header", "10.0.0.1:5000", []string{"not an ip"}, "10.0.0.1"},
+		{"entries with ports", "10.0.0.1:5000", []string{`"[2001:db8::2]:443", 10.1.1.1:80`}, "2001:db8::2"},
+		{"trusted IPv6 peer", "[2001:db8::1]:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"IPv6 peer outside the trusted address", "[2001:db8::3]:5000", []string{"198.51.100.1"}, "2001:db8::3"},
+		{"IPv4-mapped peer", "[::ffff:10.0.0.1]:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/logs", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.header {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := input.clientIP(req); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	// Without trusted proxies the header is never read
+	req := httptest.NewRequest("POST", "/logs", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := NewHTTPInput("8080").clientIP(req); got != "10.0.0.1" {
+		t.Errorf("Expected the peer without trusted proxies, got %s", got)
+	}
+}
+
+func TestHTTPInputClientIPHeaderAndMetadata(t *testing.T) {
+	plugin, err := NewHTTPInputFromConfig(map[string]any{
+		"trusted_proxies":  []any{"192.0.2.0/24"},
+		"client_ip_header": "X-Real-IP",
+		"header_metadata":  map[string]any{"X-Service": "service"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := plugin.(*HTTPInput)
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	// httptest requests come from 192.0.2.1
+	req := httptest.NewRequest("POST", "/logs", bytes.NewBufferString("ERROR behind proxy"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Real-IP", "198.51.100.1")
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+	w := httptest.NewRecorder()
+	input.handleLogs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	logEntry := <-logCh
+	if logEntry.Metadata[ClientIPKey] != "198.51.100.1" {
+		t.Errorf("Expected client_ip from X-Real-IP, got %v", logEntry.Metadata)
+	}
+}
+
+func TestHTTPInputClientIPValidation(t *testing.T) {
+	tests := []struct {
+		config map[string]any
+		errMsg string
+	}{
+		{map[string]any{"trusted_proxies": []any{"10.0.0.0/33"}}, "invalid trusted proxy"},
+		{map[string]any{"trusted_proxies": []any{"proxy.internal"}}, "invalid trusted proxy"},
+		{map[string]any{"client_ip_header": "X-Real-IP"}, "requires trusted_proxies"},
+		{map[string]any{"header_metadata": map[string]any{"X-Forwarded-For": "client_ip"}}, "reserved metadata key"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewHTTPInputFromConfig(tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("Config %v: expected error containing %q, got %v", tt.config, tt.errMsg, err)
+		}
+	}
+
+	plugin, err := NewHTTPInputFromConfig(map[string]any{"trusted_proxies": []any{"10.0.0.1", "fd00::/8"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if header := plugin.(*HTTPInput).config.ClientIPHeader; header != DefaultClientIPHeader {
+		t.Errorf("Expected default header %s, got %s", DefaultClientIPHeader, header)
+	}
+}
+
+func TestHTTPInputPerClientRateLimit(t *testing.T) {
+	plugin, err := NewHTTPInputFromConfig(map[string]any{
+		"trusted_proxies": []any{"192.0.2.1"},
+		"rate_limit":      map[string]any{"enabled": true, "rate": 0.001, "burst": 1, "per_client": true},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := plugin.(*HTTPInput)
+	input.SetLogChannel(make(chan *core.Log, 10))
+
+	post := func(client string) int {
+		req := httptest.NewRequest("POST", "/logs", bytes.NewBufferString("INFO hello"))
+		req.Header.Set("X-Forwarded-For", client)
+		w := httptest.NewRecorder()
+		input.handleLogs(w, req)
+		return w.Code
+	}
+
+	// Every client behind the proxy has its own bucket
+	if code := post("198.51.100.1"); code != http.StatusOK {
+		t.Errorf("Expected first request of a client to pass, got %d", code)
+	}
+	if code := post("198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected second request of a client to be limited, got %d", code)
+	}
+	if code := post("198.51.100.2"); code != http.StatusOK {
+		t.Errorf("Expected another client to have its own bucket, got %d", code)
+	}
+}
+
+func TestClientRateLimiterBounded(t *testing.T) {
+	limiter := newClientRateLimiter(0.001, 1)
+	for i := range maxRateLimitedClients + 10 {
+		limiter.Allow(fmt.Sprintf("198.51.%d.%d", i/256, i%256))
+	}
+	if len(limiter.limiters) > maxRateLimitedClients {
+		t.Errorf("Expected at most %d buckets, got %d", maxRateLimitedClients, len(limiter.limiters))
+	}
+}
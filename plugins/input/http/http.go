@@ -4,15 +4,22 @@ import (
 	"crypto/subtle"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/compress"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
 	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 )
 
@@ -20,8 +27,21 @@ const (
 	// Default rate limiting values
 	DefaultRateLimit = 10.0 // default requests per second
 	DefaultBurst     = 20   // default burst size
+
+	// Default server tuning values
+	DefaultReadTimeout    = 30      // default request read timeout in seconds
+	DefaultWriteTimeout   = 30      // default response write timeout in seconds
+	DefaultIdleTimeout    = 120     // default keep-alive idle timeout in seconds
+	DefaultMaxHeaderBytes = 1 << 20 // default max request header size (1MB)
+
+	// Default paths
+	DefaultPath       = "/logs"   // default ingest path
+	DefaultHealthPath = "/health" // default health check path
 )
 
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.http")
+
 func init() {
 	// Auto-register this plugin
 	core.RegisterInputPlugin("http", NewHTTPInputFromConfig)
@@ -34,11 +54,70 @@ type Config struct {
 	CertFile string           `yaml:"cert_file,omitempty"` // Server certificate file (for HTTPS)
 	KeyFile  string           `yaml:"key_file,omitempty"`  // Server key file (for HTTPS)
 
+	// Paths
+	Path          string           `yaml:"path,omitempty"`           // Main ingest path (default: "/logs")
+	Endpoints     []EndpointConfig `yaml:"endpoints,omitempty"`      // Additional ingest paths with their own defaults
+	HealthPath    string           `yaml:"health_path,omitempty"`    // Health check path (default: "/health")
+	DisableHealth bool             `yaml:"disable_health,omitempty"` // Do not serve the health check path
+
 	// Authentication configuration
 	Auth AuthConfig `yaml:"auth,omitempty"`
 
 	// Rate limiting configuration
 	RateLimit RateLimitConfig `yaml:"rate_limit,omitempty"`
+
+	// Client IP resolution behind load balancers (the header is only read from trusted proxies)
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`  // Proxy IPs or CIDRs
+	ClientIPHeader string   `yaml:"client_ip_header,omitempty"` // Header with the client IP (default: X-Forwarded-For)
+
+	// Server tuning configuration
+	ReadTimeout    int   `yaml:"read_timeout,omitempty"`     // Max time to read a full request in seconds (default: 30)
+	WriteTimeout   int   `yaml:"write_timeout,omitempty"`    // Max time to write a response in seconds (default: 30)
+	IdleTimeout    int   `yaml:"idle_timeout,omitempty"`     // Keep-alive idle timeout in seconds (default: 120)
+	MaxHeaderBytes int   `yaml:"max_header_bytes,omitempty"` // Max request header size in bytes (default: 1MB)
+	MaxConnections int   `yaml:"max_connections,omitempty"`  // Concurrent ingest requests; more get 503 (default: unlimited)
+	HTTP2          *bool `yaml:"http2,omitempty"`            // Enable HTTP/2 over TLS (default: true)
+
+	// Max size of a gzip or zstd request body once decompressed (default: 10MB)
+	MaxDecompressedBytes int64 `yaml:"max_decompressed_bytes,omitempty"`
+
+	// Reject requests instead of blocking while the engine is falling behind
+	Backpressure BackpressureConfig `yaml:"backpressure,omitempty"`
+
+	// Request metadata extraction (only explicitly listed headers/params are trusted)
+	HeaderMetadata map[string]string `yaml:"header_metadata,omitempty"` // Request header -> metadata key
+	QueryMetadata  map[string]string `yaml:"query_metadata,omitempty"`  // Query parameter -> metadata key
+
+	// Invalid JSON bodies and text lines without a detectable level: pass_raw, tag or drop
+	// (default: JSON is dropped, text gets the default level)
+	OnParseError string `yaml:"on_parse_error,omitempty"`
+
+	// How JSON objects become logs: raw (the object is the message) or structured
+	// (level, message, timestamp, metadata and tags are mapped onto the log)
+	JSONMode string `yaml:"json_mode,omitempty"`
+
+	// Newline-delimited JSON bodies (Content-Type application/x-ndjson), parsed line by line
+	NDJSON NDJSONConfig `yaml:"ndjson,omitempty"`
+}
+
+// EndpointConfig is an additional ingest path. Logs posted to it get the endpoint's
+// default level and metadata. Auth and rate limiting fall back to the input's settings;
+// every path has its own rate limiter, so one busy path cannot starve another.
+type EndpointConfig struct {
+	Path      string            `yaml:"path"`
+	Level     string            `yaml:"level,omitempty"`      // Level for logs that neither carry nor name one
+	Metadata  map[string]string `yaml:"metadata,omitempty"`   // Metadata added to every log (request metadata wins)
+	Auth      *AuthConfig       `yaml:"auth,omitempty"`       // Overrides the input's auth ("auth: {}" disables it)
+	RateLimit *RateLimitConfig  `yaml:"rate_limit,omitempty"` // Overrides the input's rate limit
+}
+
+// reservedMetadataKeys are set by the input itself and cannot be overridden by request metadata
+var reservedMetadataKeys = map[string]bool{
+	"source":                 true,
+	"content_type":           true,
+	core.ParseErrorKey:       true,
+	core.ParseErrorReasonKey: true,
+	ClientIPKey:              true,
 }
 
 // AuthConfig represents authentication configuration for HTTP input
@@ -63,6 +142,37 @@ type RateLimitConfig struct {
 	Enabled bool    `yaml:"enabled,omitempty"` // Whether rate limiting is enabled
 	Rate    float64 `yaml:"rate,omitempty"`    // Requests per second
 	Burst   int     `yaml:"burst,omitempty"`   // Maximum burst size
+
+	// Keep a bucket per client IP instead of one for the whole path
+	PerClient bool `yaml:"per_client,omitempty"`
+}
+
+// BackpressureConfig makes the input reject requests while the engine's input channel
+// is filling up, so slow processing pushes back on clients instead of piling up
+// blocked request goroutines
+type BackpressureConfig struct {
+	HighWaterMark float64 `yaml:"high_water_mark,omitempty"` // Fraction of the engine input channel in use that rejects requests, in (0, 1] (0 = disabled)
+	Status        int     `yaml:"status,omitempty"`          // 503 (default) or 429
+	RetryAfter    int     `yaml:"retry_after,omitempty"`     // Retry-After seconds sent with the rejection (default: 1)
+}
+
+// Validate validates the backpressure configuration
+func (b *BackpressureConfig) Validate() error {
+	if b.HighWaterMark < 0 || b.HighWaterMark > 1 {
+		return fmt.Errorf("backpressure high_water_mark must be between 0 and 1, got %v", b.HighWaterMark)
+	}
+	if b.Status != 0 && b.Status != http.StatusServiceUnavailable && b.Status != http.StatusTooManyRequests {
+		return fmt.Errorf("backpressure status must be 503 or 429, got %d", b.Status)
+	}
+	if b.RetryAfter < 0 {
+		return fmt.Errorf("backpressure retry_after must be non-negative")
+	}
+	return nil
+}
+
+// enabled reports whether requests are rejected under engine load
+func (b *BackpressureConfig) enabled() bool {
+	return b.HighWaterMark > 0
 }
 
 // Validate validates the authentication configuration
@@ -88,6 +198,121 @@ func (a *AuthConfig) Validate() error {
 	return nil
 }
 
+// validateMetadataMapping validates a request field -> metadata key mapping
+func validateMetadataMapping(kind string, mapping map[string]string) error {
+	for field, key := range mapping {
+		if strings.TrimSpace(field) == "" {
+			return fmt.Errorf("%s_metadata contains an empty %s name", kind, kind)
+		}
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("%s_metadata for %q must map to a non-empty metadata key", kind, field)
+		}
+		if reservedMetadataKeys[key] {
+			return fmt.Errorf("%s_metadata for %q cannot override reserved metadata key %q", kind, field, key)
+		}
+	}
+	return nil
+}
+
+// Validate validates the rate limit configuration
+func (r *RateLimitConfig) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Rate < 0 {
+		return fmt.Errorf("rate limit rate must be non-negative")
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("rate limit burst must be non-negative")
+	}
+	// If rate or burst are explicitly set to 0, defaults will be applied in NewHTTPInputWithConfig
+	return nil
+}
+
+// applyPathDefaults fills in the default ingest and health paths
+func (c *Config) applyPathDefaults() {
+	if c.Path == "" {
+		c.Path = DefaultPath
+	}
+	if c.HealthPath == "" {
+		c.HealthPath = DefaultHealthPath
+	}
+}
+
+// validatePath checks that a path is a plain absolute URL path. Trailing slashes,
+// wildcards and method prefixes would change how the server mux matches it.
+func validatePath(kind, p string) error {
+	if !strings.HasPrefix(p, "/") {
+		return fmt.Errorf("%s %q must start with '/'", kind, p)
+	}
+	if strings.HasSuffix(p, "/") {
+		return fmt.Errorf("%s %q must not end with '/'", kind, p)
+	}
+	if strings.ContainsAny(p, " \t?#{}") {
+		return fmt.Errorf("%s %q must not contain whitespace, '?', '#' or wildcards", kind, p)
+	}
+	if path.Clean(p) != p {
+		return fmt.Errorf("%s %q must be a clean path without '//', '.' or '..' segments", kind, p)
+	}
+	return nil
+}
+
+// validatePaths validates the ingest and health paths and their per-endpoint settings
+func (c *Config) validatePaths() error {
+	seen := make(map[string]string)
+	register := func(kind, p string) error {
+		if err := validatePath(kind, p); err != nil {
+			return err
+		}
+		if other, ok := seen[p]; ok {
+			return fmt.Errorf("%s %q is already used by the %s", kind, p, other)
+		}
+		seen[p] = kind
+		return nil
+	}
+
+	if err := register("path", c.Path); err != nil {
+		return err
+	}
+	if !c.DisableHealth {
+		if err := register("health_path", c.HealthPath); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Endpoints {
+		endpoint := &c.Endpoints[i]
+		if err := register("endpoint path", endpoint.Path); err != nil {
+			return err
+		}
+		if endpoint.Level != "" && !core.Levels().Known(endpoint.Level) {
+			return fmt.Errorf("endpoint %s: unknown level %q", endpoint.Path, endpoint.Level)
+		}
+		for key := range endpoint.Metadata {
+			if strings.TrimSpace(key) == "" {
+				return fmt.Errorf("endpoint %s: metadata contains an empty key", endpoint.Path)
+			}
+			if reservedMetadataKeys[key] {
+				return fmt.Errorf("endpoint %s: metadata cannot override reserved key %q", endpoint.Path, key)
+			}
+		}
+		if endpoint.Auth != nil {
+			if endpoint.Auth.APIKey != "" && endpoint.Auth.APIKeyHeader == "" {
+				endpoint.Auth.APIKeyHeader = "X-API-Key"
+			}
+			if err := endpoint.Auth.Validate(); err != nil {
+				return fmt.Errorf("endpoint %s: invalid auth config: %w", endpoint.Path, err)
+			}
+		}
+		if endpoint.RateLimit != nil {
+			if err := endpoint.RateLimit.Validate(); err != nil {
+				return fmt.Errorf("endpoint %s: %w", endpoint.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
 // NewHTTPInputFromConfig creates an HTTP input from configuration map
 func NewHTTPInputFromConfig(config map[string]any) (any, error) {
 	var cfg Config
@@ -111,14 +336,47 @@ func NewHTTPInputFromConfig(config map[string]any) (any, error) {
 	}
 
 	// Validate rate limit configuration
-	if cfg.RateLimit.Enabled {
-		if cfg.RateLimit.Rate < 0 {
-			return nil, fmt.Errorf("rate limit rate must be non-negative")
-		}
-		if cfg.RateLimit.Burst < 0 {
-			return nil, fmt.Errorf("rate limit burst must be non-negative")
-		}
-		// If rate or burst are explicitly set to 0, defaults will be applied in NewHTTPInputWithConfig
+	if err := cfg.RateLimit.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate client IP resolution
+	if _, err := parseTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	if cfg.ClientIPHeader != "" && len(cfg.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("client_ip_header requires trusted_proxies")
+	}
+
+	// Validate ingest, endpoint and health paths
+	cfg.applyPathDefaults()
+	if err := cfg.validatePaths(); err != nil {
+		return nil, err
+	}
+
+	// Validate server tuning configuration
+	if cfg.ReadTimeout < 0 || cfg.WriteTimeout < 0 || cfg.IdleTimeout < 0 {
+		return nil, fmt.Errorf("server timeouts must be non-negative")
+	}
+	if cfg.MaxHeaderBytes < 0 {
+		return nil, fmt.Errorf("max_header_bytes must be non-negative")
+	}
+	if cfg.MaxConnections < 0 {
+		return nil, fmt.Errorf("max_connections must be non-negative")
+	}
+	if cfg.MaxDecompressedBytes < 0 {
+		return nil, fmt.Errorf("max_decompressed_bytes must be non-negative")
+	}
+	if err := cfg.Backpressure.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate request metadata mappings
+	if err := validateMetadataMapping("header", cfg.HeaderMetadata); err != nil {
+		return nil, err
+	}
+	if err := validateMetadataMapping("query", cfg.QueryMetadata); err != nil {
+		return nil, err
 	}
 
 	// Validate TLS config
@@ -126,6 +384,16 @@ func NewHTTPInputFromConfig(config map[string]any) (any, error) {
 		return nil, err
 	}
 
+	if err := core.ValidateParseErrorMode(cfg.OnParseError); err != nil {
+		return nil, err
+	}
+	if err := validateJSONMode(cfg.JSONMode); err != nil {
+		return nil, err
+	}
+	if err := cfg.NDJSON.Validate(); err != nil {
+		return nil, err
+	}
+
 	return NewHTTPInputWithConfig(cfg), nil
 }
 
@@ -141,8 +409,60 @@ type HTTPInput struct {
 	name      string // Name of this input instance
 	tlsConfig *tls.Config
 
-	// Rate limiter
+	// Rate limiter of the main ingest path
 	rateLimiter *RateLimiter
+
+	// Proxies whose client IP header is believed
+	trustedProxies []netip.Prefix
+
+	// Ingest paths; the first one is the main path
+	endpoints []*endpoint
+
+	// Concurrency limit of ingest requests (nil = unlimited) and connection counters
+	requestSlots   chan struct{}
+	connections    atomic.Int64 // Open client connections
+	activeRequests atomic.Int64 // Ingest requests being handled
+	rejected       atomic.Int64 // Ingest requests rejected with 503 at the limit
+	busyRejected   atomic.Int64 // Ingest requests rejected by backpressure
+}
+
+// endpoint is an ingest path with its resolved auth, rate limiter and defaults
+type endpoint struct {
+	path        string
+	level       string
+	metadata    map[string]string
+	auth        *AuthConfig
+	rateLimiter *RateLimiter // nil if rate limiting is disabled or per client
+
+	// Per-client rate limiter, nil unless rate limiting is enabled per client
+	clientLimiter *clientRateLimiter
+}
+
+// setRateLimit creates the endpoint's path or per-client rate limiter
+func (e *endpoint) setRateLimit(config RateLimitConfig) {
+	if config.Enabled && config.PerClient {
+		limiter := newRateLimiter(config)
+		e.clientLimiter = newClientRateLimiter(limiter.rate, limiter.burst)
+		return
+	}
+	e.rateLimiter = newRateLimiter(config)
+}
+
+// allow checks the endpoint's rate limit for a request from client
+func (e *endpoint) allow(client string) bool {
+	if e.clientLimiter != nil {
+		return e.clientLimiter.Allow(client)
+	}
+	// rateLimiter is nil if rate limiting is disabled, so the nil check acts as a feature flag
+	return e.rateLimiter == nil || e.rateLimiter.Allow()
+}
+
+// defaultLevel returns the level for logs that neither carry nor name one
+func (e *endpoint) defaultLevel() string {
+	if e.level != "" {
+		return core.Levels().Normalize(e.level)
+	}
+	return core.Levels().Default()
 }
 
 // RateLimiter implements token bucket rate limiting for HTTP requests.
@@ -207,15 +527,7 @@ func (r *RateLimiter) Allow() bool {
 
 // NewHTTPInput creates a new HTTP input plugin
 func NewHTTPInput(port string) *HTTPInput {
-	if port == "" {
-		port = "8080"
-	}
-
-	return &HTTPInput{
-		port:   port,
-		config: Config{Port: port},
-		stopCh: make(chan struct{}),
-	}
+	return NewHTTPInputWithConfig(Config{Port: port})
 }
 
 // NewHTTPInputWithConfig creates a new HTTP input plugin with full configuration
@@ -229,44 +541,154 @@ func NewHTTPInputWithConfig(config Config) *HTTPInput {
 		config.Auth.APIKeyHeader = "X-API-Key"
 	}
 
+	config.applyServerDefaults()
+	config.applyPathDefaults()
+	if config.Backpressure.Status == 0 {
+		config.Backpressure.Status = http.StatusServiceUnavailable
+	}
+	if config.Backpressure.RetryAfter == 0 {
+		config.Backpressure.RetryAfter = 1
+	}
+	config.NDJSON.applyDefaults()
+	if len(config.TrustedProxies) > 0 && config.ClientIPHeader == "" {
+		config.ClientIPHeader = DefaultClientIPHeader
+	}
+
 	input := &HTTPInput{
 		port:   config.Port,
 		config: config,
 		stopCh: make(chan struct{}),
 	}
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		// NewHTTPInputFromConfig rejects this; trust no proxy rather than a partial list
+		logger.Printf("Ignoring trusted_proxies: %v", err)
+	} else {
+		input.trustedProxies = trustedProxies
+	}
+	if config.MaxConnections > 0 {
+		input.requestSlots = make(chan struct{}, config.MaxConnections)
+	}
 
-	// Initialize rate limiter if enabled
-	if config.RateLimit.Enabled {
-		// Use defaults if not specified
-		if config.RateLimit.Rate <= 0 {
-			config.RateLimit.Rate = DefaultRateLimit
+	// Every path gets its own rate limiter, nil if rate limiting is disabled
+	main := &endpoint{
+		path: input.config.Path,
+		auth: &input.config.Auth,
+	}
+	main.setRateLimit(config.RateLimit)
+	input.rateLimiter = main.rateLimiter
+	input.endpoints = append(input.endpoints, main)
+	for _, cfg := range input.config.Endpoints {
+		ep := &endpoint{
+			path:     cfg.Path,
+			level:    cfg.Level,
+			metadata: cfg.Metadata,
+			auth:     &input.config.Auth,
 		}
-		if config.RateLimit.Burst <= 0 {
-			config.RateLimit.Burst = DefaultBurst
+		if cfg.Auth != nil {
+			ep.auth = cfg.Auth
 		}
-		// NewRateLimiter validates and returns nil if invalid
-		input.rateLimiter = NewRateLimiter(config.RateLimit.Rate, config.RateLimit.Burst)
-		if input.rateLimiter == nil {
-			// This shouldn't happen since we set defaults above, but safeguard
-			input.rateLimiter = NewRateLimiter(DefaultRateLimit, DefaultBurst)
+		if cfg.RateLimit != nil {
+			ep.setRateLimit(*cfg.RateLimit)
+		} else {
+			ep.setRateLimit(config.RateLimit)
 		}
+		input.endpoints = append(input.endpoints, ep)
 	}
 
 	return input
 }
 
-// Start begins the HTTP server
-func (h *HTTPInput) Start() error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/logs", h.handleLogs)
-	mux.HandleFunc("/health", h.handleHealth)
+// newRateLimiter creates the rate limiter for a path, or nil if rate limiting is disabled
+func newRateLimiter(config RateLimitConfig) *RateLimiter {
+	if !config.Enabled {
+		return nil
+	}
+	// Use defaults if not specified
+	if config.Rate <= 0 {
+		config.Rate = DefaultRateLimit
+	}
+	if config.Burst <= 0 {
+		config.Burst = DefaultBurst
+	}
+	// NewRateLimiter validates and returns nil if invalid
+	limiter := NewRateLimiter(config.Rate, config.Burst)
+	if limiter == nil {
+		// This shouldn't happen since we set defaults above, but safeguard
+		limiter = NewRateLimiter(DefaultRateLimit, DefaultBurst)
+	}
+	return limiter
+}
+
+// applyServerDefaults fills in unset server tuning values
+func (c *Config) applyServerDefaults() {
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = DefaultReadTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = DefaultWriteTimeout
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = DefaultIdleTimeout
+	}
+	if c.MaxHeaderBytes == 0 {
+		c.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+}
+
+// http2Enabled reports whether HTTP/2 should be negotiated over TLS
+func (c *Config) http2Enabled() bool {
+	return c.HTTP2 == nil || *c.HTTP2
+}
 
-	h.server = &http.Server{
+// newServer builds the http.Server with the configured timeouts and protocol settings
+func (h *HTTPInput) newServer(handler http.Handler) *http.Server {
+	server := &http.Server{
 		Addr:              ":" + h.port,
-		Handler:           mux,
+		Handler:           handler,
 		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Duration(h.config.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(h.config.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(h.config.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    h.config.MaxHeaderBytes,
 	}
 
+	// Count open connections; hijacked connections are no longer tracked by the server
+	server.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			h.connections.Add(1)
+		case http.StateClosed, http.StateHijacked:
+			h.connections.Add(-1)
+		}
+	}
+
+	// A non-nil, empty TLSNextProto map disables the automatic HTTP/2 upgrade
+	if !h.config.http2Enabled() {
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	return server
+}
+
+// newMux routes every ingest path and the health check path to their handlers
+func (h *HTTPInput) newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, ep := range h.endpoints {
+		mux.HandleFunc(ep.path, func(w http.ResponseWriter, r *http.Request) {
+			h.handleEndpoint(ep, w, r)
+		})
+	}
+	if !h.config.DisableHealth {
+		mux.HandleFunc(h.config.HealthPath, h.handleHealth)
+	}
+	return mux
+}
+
+// Start begins the HTTP server
+func (h *HTTPInput) Start() error {
+	h.server = h.newServer(h.newMux())
+
 	// Configure TLS if enabled
 	if h.config.TLS.Enabled {
 		tlsConfig, err := h.config.TLS.NewTLSConfig()
@@ -289,33 +711,49 @@ func (h *HTTPInput) Start() error {
 
 		var err error
 		if h.config.TLS.Enabled {
-			log.Printf("HTTPS input server starting on port %s (TLS enabled)", h.port)
+			logger.Printf("HTTPS input server starting on port %s (TLS enabled)", h.port)
 			// Use provided certificate files or TLS config
 			if h.config.CertFile != "" && h.config.KeyFile != "" {
 				err = h.server.ListenAndServeTLS(h.config.CertFile, h.config.KeyFile)
 			} else {
 				err = fmt.Errorf("TLS enabled but certificate files not provided: cert_file and key_file are required")
-				log.Printf("Error: %v", err)
+				logger.Printf("Error: %v", err)
 				return
 			}
 		} else {
-			log.Printf("HTTP input server starting on port %s", h.port)
+			logger.Printf("HTTP input server starting on port %s", h.port)
 			err = h.server.ListenAndServe()
 		}
 
 		if err != nil && err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+			logger.Printf("HTTP server error: %v", err)
 		}
 	}()
 
 	if h.config.TLS.Enabled {
-		log.Printf("HTTPS input started on port %s (TLS)", h.port)
+		logger.Printf("HTTPS input started on port %s (TLS)", h.port)
 	} else {
-		log.Printf("HTTP input started on port %s", h.port)
+		logger.Printf("HTTP input started on port %s", h.port)
 	}
 	return nil
 }
 
+// StartListener serves the input on an existing listener instead of its port,
+// e.g. the HTTP connections of a port shared with other protocols. Connections
+// are served as plain HTTP/1.1; TLS is up to the listener. Stop closes the listener.
+func (h *HTTPInput) StartListener(listener net.Listener) error {
+	h.server = h.newServer(h.newMux())
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Printf("HTTP server error: %v", err)
+		}
+	}()
+	return nil
+}
+
 // Stop stops the HTTP server
 func (h *HTTPInput) Stop() error {
 	if h.stopped {
@@ -327,12 +765,12 @@ func (h *HTTPInput) Stop() error {
 
 	if h.server != nil {
 		if err := h.server.Close(); err != nil {
-			log.Printf("Error closing HTTP server: %v", err)
+			logger.Printf("Error closing HTTP server: %v", err)
 		}
 	}
 
 	h.wg.Wait()
-	log.Printf("HTTP input stopped")
+	logger.Printf("HTTP input stopped")
 	return nil
 }
 
@@ -346,17 +784,37 @@ func (h *HTTPInput) SetName(name string) {
 	h.name = name
 }
 
-// handleLogs handles POST requests with log data
+// handleLogs handles POST requests to the main ingest path
 func (h *HTTPInput) handleLogs(w http.ResponseWriter, r *http.Request) {
+	h.handleEndpoint(h.endpoints[0], w, r)
+}
+
+// handleEndpoint handles POST requests with log data sent to an ingest path
+func (h *HTTPInput) handleEndpoint(ep *endpoint, w http.ResponseWriter, r *http.Request) {
+	// Enforce max_connections; the slot is released however the request ends
+	if h.requestSlots != nil {
+		select {
+		case h.requestSlots <- struct{}{}:
+			defer func() { <-h.requestSlots }()
+		default:
+			h.rejected.Add(1)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too many concurrent requests", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	h.activeRequests.Add(1)
+	defer h.activeRequests.Add(-1)
+
 	// Check authentication
-	if err := h.authenticateRequest(r); err != nil {
+	if err := authenticateRequest(ep.auth, r); err != nil {
 		http.Error(w, fmt.Sprintf("Authentication failed: %v", err), http.StatusUnauthorized)
 		return
 	}
 
 	// Check rate limit if enabled
-	// rateLimiter is nil if rate limiting is disabled, so the nil check is safe and acts as a feature flag
-	if h.rateLimiter != nil && !h.rateLimiter.Allow() {
+	client := h.clientIP(r)
+	if !ep.allow(client) {
 		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 		return
 	}
@@ -366,9 +824,30 @@ func (h *HTTPInput) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse new work before reading the body while the engine is behind
+	if h.overloaded() {
+		h.rejectBusy(w, 0)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	requestMetadata := h.extractRequestMetadata(ep, r)
+	if len(h.trustedProxies) > 0 {
+		if requestMetadata == nil {
+			requestMetadata = make(map[string]string)
+		}
+		requestMetadata[ClientIPKey] = client
+	}
+
+	// NDJSON is parsed as it arrives instead of being read whole
+	if isNDJSON(contentType) {
+		h.handleNDJSON(ep, w, r, requestMetadata)
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("Error reading request body: %v", err)
+		logger.Printf("Error reading request body: %v", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
@@ -376,61 +855,211 @@ func (h *HTTPInput) handleLogs(w http.ResponseWriter, r *http.Request) {
 		_ = r.Body.Close()
 	}()
 
-	contentType := r.Header.Get("Content-Type")
+	// Decompress gzip/zstd bodies, bounded so a small body cannot expand without limit
+	body, err = compress.Decompress(r.Header.Get("Content-Encoding"), body, h.config.MaxDecompressedBytes)
+	if err != nil {
+		h.rejectUndecodable(w, err)
+		return
+	}
 
 	// Handle different content types
+	var accepted int
 	switch {
 	case strings.Contains(contentType, "application/json"):
-		h.handleJSONLogs(body)
+		accepted, err = h.handleJSONLogs(ep, body, requestMetadata)
 	case strings.Contains(contentType, "text/plain"):
-		h.handlePlainTextLogs(body)
+		accepted, err = h.handlePlainTextLogs(ep, body, requestMetadata)
 	default:
 		// Default to plain text
-		h.handlePlainTextLogs(body)
+		accepted, err = h.handlePlainTextLogs(ep, body, requestMetadata)
+	}
+	if errors.Is(err, errEngineBusy) {
+		h.rejectBusy(w, accepted)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("OK"))
 }
 
+// rejectUndecodable answers a request whose body could not be decompressed
+func (h *HTTPInput) rejectUndecodable(w http.ResponseWriter, err error) {
+	logger.Printf("Error decompressing request body: %v", err)
+	switch {
+	case errors.Is(err, compress.ErrTooLarge):
+		http.Error(w, "Decompressed body too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, compress.ErrUnsupported):
+		http.Error(w, "Unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, "Bad request", http.StatusBadRequest)
+	}
+}
+
+// InputStats implements core.InputStatsReporter
+func (h *HTTPInput) InputStats() map[string]any {
+	return map[string]any{
+		"connections":       h.connections.Load(),
+		"active_requests":   h.activeRequests.Load(),
+		"rejected_requests": h.rejected.Load(),
+		"busy_rejected":     h.busyRejected.Load(),
+	}
+}
+
+// errEngineBusy is returned when the engine input channel has no room for a log under backpressure
+var errEngineBusy = errors.New("engine busy")
+
+// overloaded reports whether backpressure rejects requests: the share of the
+// engine input channel in use is at or above the high-water mark
+func (h *HTTPInput) overloaded() bool {
+	if !h.config.Backpressure.enabled() || cap(h.logCh) == 0 {
+		return false
+	}
+	return float64(len(h.logCh)) >= h.config.Backpressure.HighWaterMark*float64(cap(h.logCh))
+}
+
+// rejectBusy answers a request refused by backpressure. The body and the
+// X-Logs-Accepted header tell it apart from a rate limit rejection; logs before
+// accepted were already handed to the engine and should not be sent again.
+func (h *HTTPInput) rejectBusy(w http.ResponseWriter, accepted int) {
+	h.busyRejected.Add(1)
+	w.Header().Set("Retry-After", strconv.Itoa(h.config.Backpressure.RetryAfter))
+	w.Header().Set("X-Logs-Accepted", strconv.Itoa(accepted))
+	http.Error(w, fmt.Sprintf("Engine busy, %d logs accepted", accepted), h.config.Backpressure.Status)
+}
+
+// enqueue hands a log to the engine. Without backpressure it waits for room in
+// the input channel; with it, a full channel returns errEngineBusy right away.
+// It returns false without an error when the input is stopping.
+func (h *HTTPInput) enqueue(logEntry *core.Log) (bool, error) {
+	if h.config.Backpressure.enabled() {
+		select {
+		case h.logCh <- logEntry:
+			return true, nil
+		case <-h.stopCh:
+			return false, nil
+		default:
+			return false, errEngineBusy
+		}
+	}
+	select {
+	case h.logCh <- logEntry:
+		return true, nil
+	case <-h.stopCh:
+		return false, nil
+	}
+}
+
 // handleHealth provides a health check endpoint
 func (h *HTTPInput) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("OK"))
 }
 
-// handleJSONLogs processes JSON log entries
-func (h *HTTPInput) handleJSONLogs(data []byte) {
+// extractRequestMetadata collects the endpoint's metadata and the configured headers and
+// query params of a request. Fields that are not listed in the mapping are ignored.
+func (h *HTTPInput) extractRequestMetadata(ep *endpoint, r *http.Request) map[string]string {
+	if len(ep.metadata) == 0 && len(h.config.HeaderMetadata) == 0 && len(h.config.QueryMetadata) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string)
+	for key, value := range ep.metadata {
+		metadata[key] = value
+	}
+	for header, key := range h.config.HeaderMetadata {
+		if value := r.Header.Get(header); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	query := r.URL.Query()
+	for param, key := range h.config.QueryMetadata {
+		if value := query.Get(param); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	return metadata
+}
+
+// applyRequestMetadata copies request metadata into a log entry
+func applyRequestMetadata(logEntry *core.Log, requestMetadata map[string]string) {
+	for key, value := range requestMetadata {
+		logEntry.Metadata[key] = value
+	}
+}
+
+// handleJSONLogs processes JSON log entries and returns how many were handed to the
+// engine, stopping at the first one refused by backpressure
+func (h *HTTPInput) handleJSONLogs(ep *endpoint, data []byte, requestMetadata map[string]string) (int, error) {
 	// Try to parse as a single log entry
 	var logEntry map[string]any
 	if err := json.Unmarshal(data, &logEntry); err != nil {
 		// Try to parse as an array of log entries
 		var logEntries []map[string]any
 		if err := json.Unmarshal(data, &logEntries); err != nil {
-			log.Printf("Error parsing JSON logs: %v", err)
-			return
+			if h.config.OnParseError == "" {
+				logger.Printf("Error parsing JSON logs: %v", err)
+				return 0, nil
+			}
+			return h.handleInvalidJSON(ep, data, requestMetadata)
 		}
 
+		accepted := 0
 		for _, entry := range logEntries {
-			h.processJSONLogEntry(entry)
+			n, err := h.processJSONLogEntry(ep, entry, requestMetadata)
+			accepted += n
+			if err != nil {
+				return accepted, err
+			}
 		}
-		return
+		return accepted, nil
 	}
 
-	h.processJSONLogEntry(logEntry)
+	return h.processJSONLogEntry(ep, logEntry, requestMetadata)
+}
+
+// sent converts the result of enqueue to the number of logs accepted
+func sent(ok bool, err error) (int, error) {
+	if ok {
+		return 1, nil
+	}
+	return 0, err
+}
+
+// handleInvalidJSON forwards a body that is not valid JSON according to on_parse_error
+func (h *HTTPInput) handleInvalidJSON(ep *endpoint, data []byte, requestMetadata map[string]string) (int, error) {
+	metadata := map[string]string{
+		"source":       "http",
+		"content_type": "json",
+	}
+	logEntry := core.NewLogWithMetadata(ep.defaultLevel(), strings.TrimSpace(string(data)), metadata)
+	logEntry.Source = h.name
+	if logEntry = core.HandleParseError(h.config.OnParseError, logEntry, "invalid JSON"); logEntry == nil {
+		return 0, nil
+	}
+	applyRequestMetadata(logEntry, requestMetadata)
+
+	return sent(h.enqueue(logEntry))
 }
 
 // processJSONLogEntry processes a single JSON log entry
-func (h *HTTPInput) processJSONLogEntry(entry map[string]any) {
+func (h *HTTPInput) processJSONLogEntry(ep *endpoint, entry map[string]any, requestMetadata map[string]string) (int, error) {
+	if h.config.JSONMode == JSONModeStructured {
+		logEntry := h.structuredLog(ep, entry)
+		applyRequestMetadata(logEntry, requestMetadata)
+		return sent(h.enqueue(logEntry))
+	}
+
 	// For JSON logs, pass the raw JSON as the message so filters can parse it
 	jsonBytes, err := json.Marshal(entry)
 	if err != nil {
-		log.Printf("Error marshaling JSON entry: %v", err)
-		return
+		logger.Printf("Error marshaling JSON entry: %v", err)
+		return 0, nil
 	}
 
 	message := string(jsonBytes)
-	level := "info" // default level
+	level := ep.defaultLevel()
 	metadata := make(map[string]string)
 
 	metadata["source"] = "http"
@@ -438,66 +1067,60 @@ func (h *HTTPInput) processJSONLogEntry(entry map[string]any) {
 
 	// Try to extract level from the JSON for initial classification
 	if l, ok := entry["level"].(string); ok {
-		level = strings.ToLower(l)
+		level = core.Levels().Normalize(l)
 	}
 
 	logEntry := core.NewLogWithMetadata(level, message, metadata)
 	logEntry.Source = h.name // Set the source to the input name
+	applyRequestMetadata(logEntry, requestMetadata)
 
-	select {
-	case h.logCh <- logEntry:
-	case <-h.stopCh:
-		return
-	}
+	return sent(h.enqueue(logEntry))
 }
 
-// handlePlainTextLogs processes plain text log entries
-func (h *HTTPInput) handlePlainTextLogs(data []byte) {
+// handlePlainTextLogs processes plain text log entries and returns how many were
+// handed to the engine, stopping at the first one refused by backpressure
+func (h *HTTPInput) handlePlainTextLogs(ep *endpoint, data []byte, requestMetadata map[string]string) (int, error) {
 	lines := strings.Split(string(data), "\n")
 
+	accepted := 0
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 
-		logEntry := h.parseLogLine(line)
+		logEntry := h.parseLogLine(ep, line)
 		if logEntry != nil {
-			select {
-			case h.logCh <- logEntry:
-			case <-h.stopCh:
-				return
+			applyRequestMetadata(logEntry, requestMetadata)
+			ok, err := h.enqueue(logEntry)
+			if !ok {
+				return accepted, err
 			}
+			accepted++
 		}
 	}
+	return accepted, nil
 }
 
 // ParseLogLine parses a log line into a Log struct (public for testing)
 func (h *HTTPInput) ParseLogLine(line string) *core.Log {
-	return h.parseLogLine(line)
+	return h.parseLogLine(h.endpoints[0], line)
 }
 
-// parseLogLine parses a log line into a Log struct
-func (h *HTTPInput) parseLogLine(line string) *core.Log {
+// parseLogLine parses a log line posted to an endpoint into a Log struct
+func (h *HTTPInput) parseLogLine(ep *endpoint, line string) *core.Log {
 	line = strings.TrimSpace(line)
 	if line == "" {
 		return nil
 	}
 
 	// Simple parsing - try to extract level from common patterns
-	level := "info"
-	message := line
-
-	// Convert to lowercase for case-insensitive matching
-	lowerLine := strings.ToLower(line)
-
-	if strings.Contains(lowerLine, "error") || strings.Contains(lowerLine, "err") {
-		level = "error"
-	} else if strings.Contains(lowerLine, "warn") || strings.Contains(lowerLine, "warning") {
-		level = "warn"
-	} else if strings.Contains(lowerLine, "debug") {
-		level = "debug"
+	level, parsed := core.Levels().DetectKnown(line)
+	if !parsed && ep.level != "" {
+		// The endpoint defines the level of lines that do not name one
+		level, parsed = ep.defaultLevel(), true
 	}
+	message := line
 
 	metadata := map[string]string{
 		"source":       "http",
@@ -506,26 +1129,29 @@ func (h *HTTPInput) parseLogLine(line string) *core.Log {
 
 	logEntry := core.NewLogWithMetadata(level, message, metadata)
 	logEntry.Source = h.name // Set the source to the input name
+	if !parsed {
+		return core.HandleParseError(h.config.OnParseError, logEntry, "no level detected")
+	}
 	return logEntry
 }
 
-// authenticateRequest authenticates the incoming HTTP request
-func (h *HTTPInput) authenticateRequest(r *http.Request) error {
+// authenticateRequest authenticates the incoming HTTP request against an endpoint's auth
+func authenticateRequest(auth *AuthConfig, r *http.Request) error {
 	// If no authentication is configured, allow all requests
-	if h.config.Auth.Username == "" && h.config.Auth.Password == "" &&
-		h.config.Auth.BearerToken == "" && h.config.Auth.APIKey == "" {
+	if auth.Username == "" && auth.Password == "" &&
+		auth.BearerToken == "" && auth.APIKey == "" {
 		return nil
 	}
 
 	// Check Basic Authentication
-	if h.config.Auth.Username != "" && h.config.Auth.Password != "" {
+	if auth.Username != "" && auth.Password != "" {
 		username, password, ok := r.BasicAuth()
 		if !ok {
 			return fmt.Errorf("basic authentication required")
 		}
 		// Use constant-time comparison to prevent timing attacks
-		usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(h.config.Auth.Username))
-		passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(h.config.Auth.Password))
+		usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(auth.Username))
+		passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(auth.Password))
 		if usernameMatch != 1 || passwordMatch != 1 {
 			return fmt.Errorf("invalid credentials")
 		}
@@ -533,7 +1159,7 @@ func (h *HTTPInput) authenticateRequest(r *http.Request) error {
 	}
 
 	// Check Bearer Token Authentication
-	if h.config.Auth.BearerToken != "" {
+	if auth.BearerToken != "" {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			return fmt.Errorf("bearer token required")
@@ -544,15 +1170,15 @@ func (h *HTTPInput) authenticateRequest(r *http.Request) error {
 		}
 		token := strings.TrimPrefix(authHeader, bearerPrefix)
 		// Use constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.Auth.BearerToken)) != 1 {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(auth.BearerToken)) != 1 {
 			return fmt.Errorf("invalid bearer token")
 		}
 		return nil
 	}
 
 	// Check API Key Authentication
-	if h.config.Auth.APIKey != "" {
-		headerName := h.config.Auth.APIKeyHeader
+	if auth.APIKey != "" {
+		headerName := auth.APIKeyHeader
 		if headerName == "" {
 			headerName = "X-API-Key"
 		}
@@ -561,7 +1187,7 @@ func (h *HTTPInput) authenticateRequest(r *http.Request) error {
 			return fmt.Errorf("API key required in header %s", headerName)
 		}
 		// Use constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(h.config.Auth.APIKey)) != 1 {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(auth.APIKey)) != 1 {
 			return fmt.Errorf("invalid API key")
 		}
 		return nil
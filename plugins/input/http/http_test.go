@@ -2,9 +2,11 @@ package httpinput
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -202,7 +204,7 @@ func TestHandlePlainTextLogs(t *testing.T) {
 
 	data := []byte("This is an error message\nThis is a warning message\n")
 
-	input.handlePlainTextLogs(data)
+	input.handlePlainTextLogs(input.endpoints[0], data, nil)
 
 	// Wait a bit for async processing
 	time.Sleep(10 * time.Millisecond)
@@ -235,7 +237,7 @@ func TestHandleJSONLogsSingle(t *testing.T) {
 	}
 
 	data, _ := json.Marshal(logData)
-	input.handleJSONLogs(data)
+	input.handleJSONLogs(input.endpoints[0], data, nil)
 
 	// Wait a bit for async processing
 	time.Sleep(10 * time.Millisecond)
@@ -282,7 +284,7 @@ func TestHandleJSONLogsArray(t *testing.T) {
 	}
 
 	data, _ := json.Marshal(logData)
-	input.handleJSONLogs(data)
+	input.handleJSONLogs(input.endpoints[0], data, nil)
 
 	// Wait a bit for async processing
 	time.Sleep(10 * time.Millisecond)
@@ -811,3 +813,549 @@ func TestHTTPInputFromConfigWithAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPInputServerTuningDefaults(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{Port: "8080"})
+	server := input.newServer(http.NewServeMux())
+
+	if server.ReadTimeout != DefaultReadTimeout*time.Second {
+		t.Errorf("Expected read timeout %ds, got %v", DefaultReadTimeout, server.ReadTimeout)
+	}
+	if server.WriteTimeout != DefaultWriteTimeout*time.Second {
+		t.Errorf("Expected write timeout %ds, got %v", DefaultWriteTimeout, server.WriteTimeout)
+	}
+	if server.IdleTimeout != DefaultIdleTimeout*time.Second {
+		t.Errorf("Expected idle timeout %ds, got %v", DefaultIdleTimeout, server.IdleTimeout)
+	}
+	if server.MaxHeaderBytes != DefaultMaxHeaderBytes {
+		t.Errorf("Expected max header bytes %d, got %d", DefaultMaxHeaderBytes, server.MaxHeaderBytes)
+	}
+	if server.TLSNextProto != nil {
+		t.Error("Expected HTTP/2 to be enabled by default")
+	}
+}
+
+func TestHTTPInputServerTuningFromConfig(t *testing.T) {
+	plugin, err := NewHTTPInputFromConfig(map[string]any{
+		"port":             "8080",
+		"read_timeout":     5,
+		"write_timeout":    10,
+		"idle_timeout":     60,
+		"max_header_bytes": 4096,
+		"http2":            false,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create HTTP input: %v", err)
+	}
+
+	input := plugin.(*HTTPInput)
+	server := input.newServer(http.NewServeMux())
+
+	if server.ReadTimeout != 5*time.Second {
+		t.Errorf("Expected read timeout 5s, got %v", server.ReadTimeout)
+	}
+	if server.WriteTimeout != 10*time.Second {
+		t.Errorf("Expected write timeout 10s, got %v", server.WriteTimeout)
+	}
+	if server.IdleTimeout != 60*time.Second {
+		t.Errorf("Expected idle timeout 60s, got %v", server.IdleTimeout)
+	}
+	if server.MaxHeaderBytes != 4096 {
+		t.Errorf("Expected max header bytes 4096, got %d", server.MaxHeaderBytes)
+	}
+	if server.TLSNextProto == nil || len(server.TLSNextProto) != 0 {
+		t.Error("Expected HTTP/2 to be disabled via empty TLSNextProto")
+	}
+}
+
+func TestHTTPInputServerTuningValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "negative read timeout", config: map[string]any{"read_timeout": -1}},
+		{name: "negative write timeout", config: map[string]any{"write_timeout": -1}},
+		{name: "negative idle timeout", config: map[string]any{"idle_timeout": -1}},
+		{name: "negative max header bytes", config: map[string]any{"max_header_bytes": -1}},
+		{name: "negative max connections", config: map[string]any{"max_connections": -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPInputFromConfig(tt.config); err == nil {
+				t.Error("Expected error for invalid server tuning config")
+			}
+		})
+	}
+}
+
+func TestHTTPInputRequestMetadata(t *testing.T) {
+	plugin, err := NewHTTPInputFromConfig(map[string]any{
+		"header_metadata": map[string]any{"X-Service": "service"},
+		"query_metadata":  map[string]any{"env": "environment"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := plugin.(*HTTPInput)
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	body := bytes.NewBufferString("first line\nsecond line\n")
+	req := httptest.NewRequest("POST", "/logs?env=prod&ignored=1", body)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Service", "checkout")
+	req.Header.Set("X-Untrusted", "spoofed")
+	w := httptest.NewRecorder()
+
+	input.handleLogs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if len(logCh) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(logCh))
+	}
+
+	for i := 0; i < 2; i++ {
+		logEntry := <-logCh
+		if logEntry.Metadata["service"] != "checkout" {
+			t.Errorf("Expected service 'checkout', got '%s'", logEntry.Metadata["service"])
+		}
+		if logEntry.Metadata["environment"] != "prod" {
+			t.Errorf("Expected environment 'prod', got '%s'", logEntry.Metadata["environment"])
+		}
+		if _, ok := logEntry.Metadata["ignored"]; ok {
+			t.Error("Unlisted query params must not be added to metadata")
+		}
+		if len(logEntry.Metadata) != 4 {
+			t.Errorf("Expected only mapped fields plus source/content_type, got %v", logEntry.Metadata)
+		}
+	}
+}
+
+func TestHTTPInputRequestMetadataJSON(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{
+		HeaderMetadata: map[string]string{"X-Env": "environment"},
+	})
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	req := httptest.NewRequest("POST", "/logs", bytes.NewBufferString(`{"level":"error","message":"boom"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	input.handleLogs(w, req)
+
+	if len(logCh) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(logCh))
+	}
+	logEntry := <-logCh
+	if _, ok := logEntry.Metadata["environment"]; ok {
+		t.Error("Missing headers should not produce metadata")
+	}
+	if logEntry.Metadata["content_type"] != "json" {
+		t.Errorf("Expected content_type 'json', got '%s'", logEntry.Metadata["content_type"])
+	}
+}
+
+func TestHTTPInputRequestMetadataValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "empty metadata key", config: map[string]any{"header_metadata": map[string]any{"X-Service": ""}}},
+		{name: "empty header name", config: map[string]any{"header_metadata": map[string]any{" ": "service"}}},
+		{name: "reserved source key", config: map[string]any{"query_metadata": map[string]any{"src": "source"}}},
+		{name: "reserved content type key", config: map[string]any{"header_metadata": map[string]any{"X-Type": "content_type"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPInputFromConfig(tt.config); err == nil {
+				t.Error("Expected error for invalid metadata mapping")
+			}
+		})
+	}
+}
+
+func TestHandleInvalidJSONOnParseError(t *testing.T) {
+	tests := []struct {
+		mode        string
+		expectedLen int
+	}{
+		{mode: "", expectedLen: 0},
+		{mode: "pass_raw", expectedLen: 1},
+		{mode: "drop", expectedLen: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			input := NewHTTPInputWithConfig(Config{Port: "8080", OnParseError: tt.mode})
+			logCh := make(chan *core.Log, 10)
+			input.SetLogChannel(logCh)
+
+			input.handleJSONLogs(input.endpoints[0], []byte(`{"level":"error", broken`), map[string]string{"tenant": "acme"})
+
+			if len(logCh) != tt.expectedLen {
+				t.Fatalf("Expected %d logs, got %d", tt.expectedLen, len(logCh))
+			}
+			if tt.expectedLen == 0 {
+				return
+			}
+
+			logEntry := <-logCh
+			if logEntry.Message != `{"level":"error", broken` {
+				t.Errorf("Expected raw body as message, got %q", logEntry.Message)
+			}
+			if logEntry.Metadata[core.ParseErrorKey] != "true" || logEntry.Metadata[core.ParseErrorReasonKey] != "invalid JSON" {
+				t.Errorf("Expected parse_error metadata, got %v", logEntry.Metadata)
+			}
+			if logEntry.Metadata["tenant"] != "acme" {
+				t.Errorf("Expected request metadata, got %v", logEntry.Metadata)
+			}
+		})
+	}
+}
+
+func TestHTTPInputEndpoints(t *testing.T) {
+	plugin, err := NewHTTPInputFromConfig(map[string]any{
+		"path":        "/ingest",
+		"health_path": "/healthz",
+		"auth":        map[string]any{"bearer_token": "secret"},
+		"endpoints": []any{
+			map[string]any{
+				"path":     "/errors",
+				"level":    "error",
+				"metadata": map[string]any{"team": "payments"},
+			},
+			map[string]any{
+				"path":       "/public",
+				"auth":       map[string]any{},
+				"rate_limit": map[string]any{"enabled": true, "rate": 0.001, "burst": 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := plugin.(*HTTPInput)
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	mux := input.newMux()
+
+	post := func(path, contentType, body, token string) int {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post("/logs", "text/plain", "ERROR old path", "secret"); code != http.StatusNotFound {
+		t.Errorf("Expected default path to be replaced, got status %d", code)
+	}
+	if code := post("/ingest", "text/plain", "ERROR main path", "secret"); code != http.StatusOK {
+		t.Errorf("Expected main path to accept logs, got status %d", code)
+	}
+	if logEntry := <-logCh; logEntry.Level != "error" {
+		t.Errorf("Expected detected level on main path, got %s", logEntry.Level)
+	}
+
+	if code := post("/errors", "text/plain", "payment declined", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected endpoint to inherit auth, got status %d", code)
+	}
+	if code := post("/errors", "text/plain", "payment declined", "secret"); code != http.StatusOK {
+		t.Fatalf("Expected endpoint to accept logs, got status %d", code)
+	}
+	logEntry := <-logCh
+	if logEntry.Level != "error" || logEntry.Metadata["team"] != "payments" {
+		t.Errorf("Expected endpoint level and metadata, got %s %v", logEntry.Level, logEntry.Metadata)
+	}
+	if logEntry.Metadata[core.ParseErrorKey] != "" {
+		t.Errorf("Endpoint default level should not be a parse error, got %v", logEntry.Metadata)
+	}
+
+	if code := post("/errors", "application/json", `{"level":"warn","msg":"retrying"}`, "secret"); code != http.StatusOK {
+		t.Fatalf("Expected endpoint to accept JSON logs, got status %d", code)
+	}
+	if logEntry := <-logCh; logEntry.Level != "warn" {
+		t.Errorf("Expected explicit JSON level to win over endpoint level, got %s", logEntry.Level)
+	}
+
+	if code := post("/public", "text/plain", "hello", ""); code != http.StatusOK {
+		t.Errorf("Expected endpoint auth override to allow anonymous requests, got status %d", code)
+	}
+	<-logCh
+	if code := post("/public", "text/plain", "hello", ""); code != http.StatusTooManyRequests {
+		t.Errorf("Expected endpoint rate limit, got status %d", code)
+	}
+	if code := post("/ingest", "text/plain", "INFO other path", "secret"); code != http.StatusOK {
+		t.Errorf("Expected rate limit to apply per path, got status %d", code)
+	}
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected health check on custom path, got status %d", w.Code)
+	}
+}
+
+func TestHTTPInputDisableHealth(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{DisableHealth: true})
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	input.newMux().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected health check to be disabled, got status %d", w.Code)
+	}
+}
+
+func TestHTTPInputPathValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "relative path", config: map[string]any{"path": "logs"}},
+		{name: "trailing slash", config: map[string]any{"path": "/logs/"}},
+		{name: "root path", config: map[string]any{"path": "/"}},
+		{name: "wildcard", config: map[string]any{"path": "/logs/{app}"}},
+		{name: "unclean path", config: map[string]any{"path": "/a//b"}},
+		{name: "path clashes with health", config: map[string]any{"path": "/health"}},
+		{name: "duplicate endpoint", config: map[string]any{"endpoints": []any{map[string]any{"path": "/logs"}}}},
+		{name: "unknown endpoint level", config: map[string]any{"endpoints": []any{map[string]any{"path": "/e", "level": "loud"}}}},
+		{name: "reserved endpoint metadata", config: map[string]any{"endpoints": []any{map[string]any{"path": "/e", "metadata": map[string]any{"source": "x"}}}}},
+		{name: "invalid endpoint auth", config: map[string]any{"endpoints": []any{map[string]any{"path": "/e", "auth": map[string]any{"username": "u"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPInputFromConfig(tt.config); err == nil {
+				t.Error("Expected error for invalid path configuration")
+			}
+		})
+	}
+
+	// The health path is free once the health check is disabled
+	if _, err := NewHTTPInputFromConfig(map[string]any{"path": "/health", "disable_health": true}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestHTTPInputMaxConnections(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{MaxConnections: 1})
+	logCh := make(chan *core.Log) // Unbuffered: the first request blocks until its log is read
+	input.SetLogChannel(logCh)
+
+	server := httptest.NewUnstartedServer(input.newMux())
+	server.Config.ConnState = input.newServer(nil).ConnState
+	server.Start()
+	defer server.Close()
+
+	post := func() (*http.Response, error) {
+		return http.Post(server.URL+"/logs", "text/plain", bytes.NewBufferString("hello"))
+	}
+
+	first := make(chan int, 1)
+	go func() {
+		resp, err := post()
+		if err != nil {
+			first <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		first <- resp.StatusCode
+	}()
+
+	// Wait until the first request holds the only slot
+	deadline := time.Now().Add(2 * time.Second)
+	for input.InputStats()["active_requests"].(int64) != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	resp, err := post()
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 at the limit, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	stats := input.InputStats()
+	if stats["rejected_requests"].(int64) != 1 {
+		t.Errorf("Expected 1 rejected request, got %v", stats["rejected_requests"])
+	}
+	if stats["connections"].(int64) < 1 {
+		t.Errorf("Expected open connections to be counted, got %v", stats["connections"])
+	}
+
+	// Releasing the first request frees the slot for the next one
+	<-logCh
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("Expected first request to succeed, got %d", code)
+	}
+	go func() { <-logCh }()
+	resp, err = post()
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the slot to be released, got %d", resp.StatusCode)
+	}
+	if active := input.InputStats()["active_requests"].(int64); active != 0 {
+		t.Errorf("Expected no active requests, got %d", active)
+	}
+}
+
+func TestHTTPInputCompressedBody(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write([]byte("[ERROR] first\n[INFO] second\n"))
+	_ = writer.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		maxBytes int64
+		status   int
+		logs     int
+	}{
+		{"gzip", "gzip", compressed.Bytes(), 0, http.StatusOK, 2},
+		{"too large", "gzip", compressed.Bytes(), 10, http.StatusRequestEntityTooLarge, 0},
+		{"unsupported", "br", compressed.Bytes(), 0, http.StatusUnsupportedMediaType, 0},
+		{"corrupt", "gzip", []byte("not gzip"), 0, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := NewHTTPInputWithConfig(Config{MaxDecompressedBytes: tt.maxBytes})
+			logCh := make(chan *core.Log, 10)
+			input.SetLogChannel(logCh)
+
+			req := httptest.NewRequest("POST", "/logs", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/plain")
+			req.Header.Set("Content-Encoding", tt.encoding)
+			w := httptest.NewRecorder()
+			input.handleLogs(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if len(logCh) != tt.logs {
+				t.Errorf("Expected %d logs, got %d", tt.logs, len(logCh))
+			}
+		})
+	}
+}
+
+func TestHTTPInputBackpressure(t *testing.T) {
+	post := func(input *HTTPInput, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/logs", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/plain")
+		w := httptest.NewRecorder()
+		input.handleLogs(w, req)
+		return w
+	}
+
+	t.Run("above high-water mark", func(t *testing.T) {
+		input := NewHTTPInputWithConfig(Config{Backpressure: BackpressureConfig{HighWaterMark: 0.5}})
+		logCh := make(chan *core.Log, 4)
+		input.SetLogChannel(logCh)
+
+		if w := post(input, "[INFO] one\n[INFO] two"); w.Code != http.StatusOK {
+			t.Fatalf("Expected 200 below the mark, got %d", w.Code)
+		}
+		w := post(input, "[INFO] three")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 at the mark, got %d", w.Code)
+		}
+		if w.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected Retry-After 1, got %q", w.Header().Get("Retry-After"))
+		}
+		if !strings.Contains(w.Body.String(), "Engine busy") {
+			t.Errorf("Expected an engine busy body, got %q", w.Body.String())
+		}
+		if len(logCh) != 2 {
+			t.Errorf("Expected the rejected request to enqueue nothing, got %d logs", len(logCh))
+		}
+
+		<-logCh
+		if w := post(input, "[INFO] four"); w.Code != http.StatusOK {
+			t.Errorf("Expected 200 once the engine caught up, got %d", w.Code)
+		}
+		if got := input.InputStats()["busy_rejected"].(int64); got != 1 {
+			t.Errorf("Expected 1 busy rejection, got %d", got)
+		}
+	})
+
+	t.Run("full mid-request", func(t *testing.T) {
+		input := NewHTTPInputWithConfig(Config{Backpressure: BackpressureConfig{
+			HighWaterMark: 1,
+			Status:        http.StatusTooManyRequests,
+			RetryAfter:    5,
+		}})
+		logCh := make(chan *core.Log, 2)
+		input.SetLogChannel(logCh)
+
+		w := post(input, "[INFO] one\n[INFO] two\n[INFO] three")
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected the configured 429, got %d", w.Code)
+		}
+		if w.Header().Get("X-Logs-Accepted") != "2" {
+			t.Errorf("Expected 2 accepted logs, got %q", w.Header().Get("X-Logs-Accepted"))
+		}
+		if w.Header().Get("Retry-After") != "5" {
+			t.Errorf("Expected Retry-After 5, got %q", w.Header().Get("Retry-After"))
+		}
+		if len(logCh) != 2 {
+			t.Errorf("Expected the first 2 logs to be enqueued, got %d", len(logCh))
+		}
+	})
+
+	t.Run("disabled blocks", func(t *testing.T) {
+		input := NewHTTPInputWithConfig(Config{})
+		logCh := make(chan *core.Log, 1)
+		input.SetLogChannel(logCh)
+		logCh <- core.NewLog("info", "queued")
+
+		done := make(chan int, 1)
+		go func() { done <- post(input, "[INFO] waits").Code }()
+		<-logCh
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("Expected the request to wait for room, got %d", code)
+		}
+	})
+}
+
+func TestBackpressureConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BackpressureConfig
+		wantErr bool
+	}{
+		{"disabled", BackpressureConfig{}, false},
+		{"503", BackpressureConfig{HighWaterMark: 0.8, Status: 503}, false},
+		{"429", BackpressureConfig{HighWaterMark: 0.8, Status: 429, RetryAfter: 2}, false},
+		{"mark above 1", BackpressureConfig{HighWaterMark: 1.5}, true},
+		{"negative mark", BackpressureConfig{HighWaterMark: -0.1}, true},
+		{"other status", BackpressureConfig{HighWaterMark: 0.8, Status: 500}, true},
+		{"negative retry", BackpressureConfig{HighWaterMark: 0.8, RetryAfter: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package httpinput
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/compress"
+)
+
+const (
+	DefaultNDJSONMaxLineBytes = 1 << 20 // default longest NDJSON line (1MB)
+	DefaultNDJSONMaxErrors    = 100     // default rejected lines listed in a response
+)
+
+// ndjsonContentTypes are the media types of newline-delimited JSON bodies
+var ndjsonContentTypes = map[string]bool{
+	"application/x-ndjson":    true,
+	"application/ndjson":      true,
+	"application/jsonl":       true,
+	"application/x-jsonlines": true,
+}
+
+// NDJSONConfig configures bodies of newline-delimited JSON, one log per line
+type NDJSONConfig struct {
+	MaxLineBytes int `yaml:"max_line_bytes,omitempty"` // Longer lines are rejected (default: 1MB)
+	MaxErrors    int `yaml:"max_errors,omitempty"`     // Rejected lines listed in a response; the rest are only counted (default: 100)
+}
+
+// Validate validates the NDJSON configuration
+func (c *NDJSONConfig) Validate() error {
+	if c.MaxLineBytes < 0 || c.MaxErrors < 0 {
+		return fmt.Errorf("ndjson max_line_bytes and max_errors must be non-negative")
+	}
+	return nil
+}
+
+// applyDefaults sets the NDJSON defaults
+func (c *NDJSONConfig) applyDefaults() {
+	if c.MaxLineBytes == 0 {
+		c.MaxLineBytes = DefaultNDJSONMaxLineBytes
+	}
+	if c.MaxErrors == 0 {
+		c.MaxErrors = DefaultNDJSONMaxErrors
+	}
+}
+
+// NDJSONResponse is the JSON body answering an NDJSON request. Lines are
+// numbered from 1, blank lines included.
+type NDJSONResponse struct {
+	Accepted        int               `json:"accepted"`                   // Logs handed to the engine
+	Rejected        int               `json:"rejected"`                   // Lines refused, to be fixed rather than resent
+	Errors          []NDJSONLineError `json:"errors,omitempty"`           // The first max_errors rejected lines
+	ErrorsTruncated bool              `json:"errors_truncated,omitempty"` // More lines were rejected than listed
+	Error           string            `json:"error,omitempty"`            // Why the body was not read to its end
+	ResumeLine      int               `json:"resume_line,omitempty"`      // With error: first line not processed
+}
+
+// NDJSONLineError describes a rejected line
+type NDJSONLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// isNDJSON reports whether a Content-Type names newline-delimited JSON
+func isNDJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && ndjsonContentTypes[mediaType]
+}
+
+// handleNDJSON handles a newline-delimited JSON body. The body is read and
+// parsed one line at a time as it arrives, so memory stays bounded by the
+// longest line whatever the size of the batch. A malformed line is rejected on
+// its own: the other lines are forwarded and the response lists what failed.
+//
+// The response is 200 when every line was accepted, 207 when some were
+// rejected and 400 when all were. A body that cannot be read to its end (too
+// large once decompressed, corrupt, or cut off) gets 413 or 400 with the error
+// and the line to resume from; the lines before it were processed.
+func (h *HTTPInput) handleNDJSON(ep *endpoint, w http.ResponseWriter, r *http.Request, requestMetadata map[string]string) {
+	body, err := compress.NewReader(r.Header.Get("Content-Encoding"), r.Body, h.config.MaxDecompressedBytes)
+	if err != nil {
+		h.rejectUndecodable(w, err)
+		return
+	}
+	defer func() { _ = body.Close() }()
+
+	response, line, err := h.readNDJSON(ep, body, requestMetadata)
+	switch {
+	case errors.Is(err, errEngineBusy):
+		w.Header().Set("X-Logs-Resume-Line", strconv.Itoa(line))
+		h.rejectBusy(w, response.Accepted)
+		return
+	case err != nil:
+		logger.Printf("Error reading NDJSON body at line %d: %v", line, err)
+		response.Error = err.Error()
+		response.ResumeLine = line
+	}
+
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, compress.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case err != nil:
+		status = http.StatusBadRequest
+	case response.Rejected > 0 && response.Accepted > 0:
+		status = http.StatusMultiStatus
+	case response.Rejected > 0:
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Printf("Error writing NDJSON response: %v", err)
+	}
+}
+
+// readNDJSON forwards the logs of an NDJSON body line by line. It returns the
+// response so far, the number of the line it stopped at and why it stopped
+// early: errEngineBusy under backpressure, or the error reading the body.
+func (h *HTTPInput) readNDJSON(ep *endpoint, body io.Reader, requestMetadata map[string]string) (NDJSONResponse, int, error) {
+	var response NDJSONResponse
+	reject := func(line int, reason string) {
+		response.Rejected++
+		if len(response.Errors) < h.config.NDJSON.MaxErrors {
+			response.Errors = append(response.Errors, NDJSONLineError{Line: line, Error: reason})
+		} else {
+			response.ErrorsTruncated = true
+		}
+	}
+
+	reader := bufio.NewReader(body)
+	var buf []byte
+	for line := 1; ; line++ {
+		data, tooLong, err := readLine(reader, buf[:0], h.config.NDJSON.MaxLineBytes)
+		if err == io.EOF {
+			return response, line, nil
+		}
+		if err != nil {
+			return response, line, err
+		}
+		buf = data
+
+		data = bytes.TrimSpace(data)
+		switch {
+		case tooLong:
+			reject(line, fmt.Sprintf("line exceeds %d bytes", h.config.NDJSON.MaxLineBytes))
+			continue
+		case len(data) == 0:
+			continue
+		}
+
+		var entry map[string]any
+		if err := json.Unmarshal(data, &entry); err != nil || entry == nil {
+			reason := "invalid JSON"
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) || (err == nil && entry == nil) {
+				reason = "not a JSON object"
+			} else if err != nil {
+				reason = fmt.Sprintf("invalid JSON: %v", err)
+			}
+
+			// pass_raw and tag forward the line as for other invalid JSON bodies
+			if h.config.OnParseError != core.ParseErrorPassRaw && h.config.OnParseError != core.ParseErrorTag {
+				reject(line, reason)
+				continue
+			}
+			n, err := h.handleInvalidJSON(ep, data, requestMetadata)
+			response.Accepted += n
+			if err != nil {
+				return response, line, err
+			}
+			continue
+		}
+
+		n, err := h.processJSONLogEntry(ep, entry, requestMetadata)
+		response.Accepted += n
+		if err != nil {
+			return response, line, err
+		}
+	}
+}
+
+// readLine reads the next line into buf, without its line ending. A line longer
+// than maxBytes is read to its end but not kept, and reported as too long. At
+// the end of the body it returns io.EOF.
+func readLine(r *bufio.Reader, buf []byte, maxBytes int) ([]byte, bool, error) {
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(buf)+len(chunk) > maxBytes+2 { // Room for \r\n
+			tooLong = true
+			buf = buf[:0]
+		} else if !tooLong {
+			buf = append(buf, chunk...)
+		}
+
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF) && len(chunk) == 0 && len(buf) == 0 && !tooLong:
+			return nil, false, io.EOF
+		case err != nil && !errors.Is(err, io.EOF):
+			return nil, false, err
+		}
+
+		buf = bytes.TrimSuffix(bytes.TrimSuffix(buf, []byte("\n")), []byte("\r"))
+		return buf, tooLong || len(buf) > maxBytes, nil
+	}
+}
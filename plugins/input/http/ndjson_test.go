@@ -0,0 +1,173 @@
+package httpinput
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func postNDJSON(input *HTTPInput, body []byte, encoding string) (*httptest.ResponseRecorder, NDJSONResponse) {
+	req := httptest.NewRequest("POST", "/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	w := httptest.NewRecorder()
+	input.handleLogs(w, req)
+
+	var response NDJSONResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestHandleNDJSON(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{JSONMode: JSONModeStructured})
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	body := strings.Join([]string{
+		`{"level":"error","message":"first"}`,
+		`{"level":"warn","message":"broken"`,
+		``,
+		`[1,2]`,
+		`{"level":"info","message":"last"}` + "\r",
+	}, "\n")
+	w, response := postNDJSON(input, []byte(body), "")
+
+	if w.Code != http.StatusMultiStatus {
+		t.Errorf("Expected 207 for a partly accepted batch, got %d", w.Code)
+	}
+	if response.Accepted != 2 || response.Rejected != 2 || len(response.Errors) != 2 {
+		t.Fatalf("Expected 2 accepted and 2 rejected lines, got %+v", response)
+	}
+	if response.Errors[0].Line != 2 || !strings.HasPrefix(response.Errors[0].Error, "invalid JSON") {
+		t.Errorf("Expected line 2 to be invalid JSON, got %+v", response.Errors[0])
+	}
+	if response.Errors[1].Line != 4 || response.Errors[1].Error != "not a JSON object" {
+		t.Errorf("Expected line 4 not to be an object, got %+v", response.Errors[1])
+	}
+
+	if first := <-logCh; first.Message != "first" || first.Level != "error" {
+		t.Errorf("Unexpected first log: %s %s", first.Level, first.Message)
+	}
+	if last := <-logCh; last.Message != "last" {
+		t.Errorf("Unexpected last log: %s", last.Message)
+	}
+}
+
+func TestHandleNDJSONStatus(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{NDJSON: NDJSONConfig{MaxErrors: 1}})
+	input.SetLogChannel(make(chan *core.Log, 10))
+
+	w, response := postNDJSON(input, []byte("{\"message\":\"ok\"}\n{\"message\":\"ok\"}\n"), "")
+	if w.Code != http.StatusOK || response.Accepted != 2 || response.Errors != nil {
+		t.Errorf("Expected 200 with every line accepted, got %d %+v", w.Code, response)
+	}
+
+	w, response = postNDJSON(input, []byte("nope\nnope\nnope"), "")
+	if w.Code != http.StatusBadRequest || response.Rejected != 3 {
+		t.Errorf("Expected 400 with every line rejected, got %d %+v", w.Code, response)
+	}
+	if len(response.Errors) != 1 || !response.ErrorsTruncated {
+		t.Errorf("Expected the errors listed to stop at max_errors, got %+v", response)
+	}
+}
+
+func TestHandleNDJSONOnParseError(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{OnParseError: core.ParseErrorTag})
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	w, response := postNDJSON(input, []byte("{\"message\":\"ok\"}\nnot json\n"), "")
+	if w.Code != http.StatusOK || response.Accepted != 2 || response.Rejected != 0 {
+		t.Fatalf("Expected the invalid line to be forwarded, got %d %+v", w.Code, response)
+	}
+	<-logCh
+	if raw := <-logCh; raw.Message != "not json" || raw.Metadata[core.ParseErrorKey] != "true" {
+		t.Errorf("Expected the raw line tagged as a parse error, got %+v", raw)
+	}
+}
+
+func TestHandleNDJSONLimits(t *testing.T) {
+	t.Run("long line", func(t *testing.T) {
+		input := NewHTTPInputWithConfig(Config{NDJSON: NDJSONConfig{MaxLineBytes: 64}})
+		input.SetLogChannel(make(chan *core.Log, 10))
+
+		long := `{"message":"` + strings.Repeat("x", 10000) + `"}`
+		w, response := postNDJSON(input, []byte(long+"\n{\"message\":\"short\"}\n"), "")
+		if w.Code != http.StatusMultiStatus || response.Accepted != 1 {
+			t.Fatalf("Expected the short line to be accepted, got %d %+v", w.Code, response)
+		}
+		if response.Errors[0].Line != 1 || !strings.Contains(response.Errors[0].Error, "exceeds 64 bytes") {
+			t.Errorf("Expected line 1 to be too long, got %+v", response.Errors[0])
+		}
+	})
+
+	t.Run("decompressed size", func(t *testing.T) {
+		input := NewHTTPInputWithConfig(Config{MaxDecompressedBytes: 100})
+		logCh := make(chan *core.Log, 100)
+		input.SetLogChannel(logCh)
+
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		for range 20 {
+			_, _ = writer.Write([]byte("{\"message\":\"0123456789\"}\n"))
+		}
+		_ = writer.Close()
+
+		w, response := postNDJSON(input, buf.Bytes(), "gzip")
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413, got %d", w.Code)
+		}
+		if response.Accepted == 0 || response.Accepted != len(logCh) || response.ResumeLine != response.Accepted+1 {
+			t.Errorf("Expected the lines before the limit to be accepted and the next one to resume from, got %+v", response)
+		}
+	})
+}
+
+func TestHandleNDJSONBackpressure(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{Backpressure: BackpressureConfig{HighWaterMark: 1}})
+	input.SetLogChannel(make(chan *core.Log, 2))
+
+	w, _ := postNDJSON(input, []byte("bad\n{\"message\":\"a\"}\n{\"message\":\"b\"}\n{\"message\":\"c\"}\n"), "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 when the channel fills up, got %d", w.Code)
+	}
+	if w.Header().Get("X-Logs-Accepted") != "2" || w.Header().Get("X-Logs-Resume-Line") != "4" {
+		t.Errorf("Expected 2 logs accepted and line 4 to resume from, got %s and %s",
+			w.Header().Get("X-Logs-Accepted"), w.Header().Get("X-Logs-Resume-Line"))
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	reader := bufio.NewReaderSize(strings.NewReader("short\r\n"+strings.Repeat("x", 40)+"\nlast"), 16)
+
+	var lines []string
+	var tooLong []bool
+	for {
+		line, long, err := readLine(reader, nil, 20)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("readLine failed: %v", err)
+		}
+		lines = append(lines, string(line))
+		tooLong = append(tooLong, long)
+	}
+
+	if len(lines) != 3 || lines[0] != "short" || lines[2] != "last" {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+	if tooLong[0] || !tooLong[1] || tooLong[2] {
+		t.Errorf("Expected only the second line to be too long, got %v", tooLong)
+	}
+}
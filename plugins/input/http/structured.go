@@ -0,0 +1,99 @@
+package httpinput
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/structured"
+)
+
+// JSON body handling modes
+const (
+	JSONModeRaw        = "raw"        // The whole JSON object becomes the message (default)
+	JSONModeStructured = "structured" // Known fields are mapped onto the log, see structuredLog
+)
+
+// validateJSONMode checks the json_mode option
+func validateJSONMode(mode string) error {
+	switch mode {
+	case "", JSONModeRaw, JSONModeStructured:
+		return nil
+	default:
+		return fmt.Errorf("invalid json_mode '%s', must be '%s' or '%s'", mode, JSONModeRaw, JSONModeStructured)
+	}
+}
+
+// structuredLog maps a pre-structured JSON log onto a core.Log:
+//
+//   - level: the log level (a string); missing or not a string uses the endpoint's default level
+//   - message (or msg): the message; non-strings are JSON encoded, and when both are
+//     missing the message is the whole object, as in raw mode
+//   - timestamp (or time): RFC 3339 or Unix seconds; missing uses the receive time, and
+//     an unparseable value is kept as metadata.timestamp
+//   - metadata (or fields): an object whose entries become metadata
+//   - tags: a string or an array of strings
+//
+// Any other top-level field is also copied into metadata, so nothing the client sent is
+// lost. Metadata values that are not strings are JSON encoded, and the input's reserved
+// keys (source, content_type, parse error markers) cannot be overridden.
+func (h *HTTPInput) structuredLog(ep *endpoint, entry map[string]any) *core.Log {
+	level := ep.defaultLevel()
+	if l, ok := entry["level"].(string); ok && l != "" {
+		level = core.Levels().Normalize(l)
+	}
+
+	message, ok := structured.Field(entry, "message", "msg")
+	if !ok {
+		data, _ := json.Marshal(entry)
+		message = string(data)
+	}
+
+	logEntry := core.NewLogWithMetadata(level, structured.String(message), map[string]string{
+		"source":       "http",
+		"content_type": "json",
+	})
+	logEntry.Source = h.name
+
+	if value, ok := structured.Field(entry, "timestamp", "time"); ok {
+		if timestamp, ok := structured.Timestamp(value); ok {
+			logEntry.Timestamp = timestamp
+		} else {
+			setMetadata(logEntry, "timestamp", value)
+		}
+	}
+
+	logEntry.Tags = append(logEntry.Tags, structured.Tags(entry["tags"])...)
+
+	// Nested metadata wins over top-level fields of the same name
+	for key, value := range entry {
+		if !structuredFields[key] {
+			setMetadata(logEntry, key, value)
+		}
+	}
+	for _, key := range []string{"fields", "metadata"} {
+		if fields, ok := entry[key].(map[string]any); ok {
+			for name, value := range fields {
+				setMetadata(logEntry, name, value)
+			}
+		} else if value, ok := entry[key]; ok {
+			setMetadata(logEntry, key, value)
+		}
+	}
+
+	return logEntry
+}
+
+// structuredFields are the top-level fields structuredLog maps onto the log itself
+var structuredFields = map[string]bool{
+	"level": true, "message": true, "msg": true, "timestamp": true, "time": true,
+	"metadata": true, "fields": true, "tags": true,
+}
+
+// setMetadata stores a JSON value as metadata unless the key is reserved by the input
+func setMetadata(logEntry *core.Log, key string, value any) {
+	if key == "" || reservedMetadataKeys[key] {
+		return
+	}
+	logEntry.Metadata[key] = structured.String(value)
+}
@@ -0,0 +1,134 @@
+package httpinput
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func TestStructuredLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		level     string
+		message   string
+		timestamp time.Time // zero = receive time
+		metadata  map[string]string
+		tags      []string
+	}{
+		{
+			name:      "all known fields",
+			body:      `{"level":"ERROR","message":"boom","timestamp":"2023-01-01T12:00:00Z","metadata":{"service":"api","attempt":3},"tags":["alert"]}`,
+			level:     "error",
+			message:   "boom",
+			timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+			metadata:  map[string]string{"service": "api", "attempt": "3"},
+			tags:      []string{"alert"},
+		},
+		{
+			name:      "aliases and unix seconds",
+			body:      `{"msg":"hi","time":1672574400.5,"fields":{"user":"ana"},"tags":"audit"}`,
+			level:     "info",
+			message:   "hi",
+			timestamp: time.Unix(1672574400, int64(500*time.Millisecond)),
+			metadata:  map[string]string{"user": "ana"},
+			tags:      []string{"audit"},
+		},
+		{
+			name:     "unknown fields become metadata",
+			body:     `{"message":"ok","service":"web","ctx":{"id":1},"metadata":{"service":"api"}}`,
+			level:    "info",
+			message:  "ok",
+			metadata: map[string]string{"service": "api", "ctx": `{"id":1}`},
+		},
+		{
+			name:     "missing message keeps the object",
+			body:     `{"level":"warn","user":"ana"}`,
+			level:    "warn",
+			message:  `{"level":"warn","user":"ana"}`,
+			metadata: map[string]string{"user": "ana"},
+		},
+		{
+			name:     "unparseable timestamp and reserved keys",
+			body:     `{"message":"x","timestamp":"yesterday","metadata":{"source":"spoofed","content_type":"xml"}}`,
+			level:    "info",
+			message:  "x",
+			metadata: map[string]string{"timestamp": "yesterday"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := NewHTTPInputWithConfig(Config{Port: "8080", JSONMode: JSONModeStructured})
+			input.SetName("api")
+			logCh := make(chan *core.Log, 1)
+			input.SetLogChannel(logCh)
+
+			before := time.Now()
+			input.handleJSONLogs(input.endpoints[0], []byte(tt.body), nil)
+			if len(logCh) != 1 {
+				t.Fatalf("Expected 1 log, got %d", len(logCh))
+			}
+			logEntry := <-logCh
+
+			if logEntry.Level != tt.level {
+				t.Errorf("Expected level %s, got %s", tt.level, logEntry.Level)
+			}
+			if logEntry.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, logEntry.Message)
+			}
+			if tt.timestamp.IsZero() {
+				if logEntry.Timestamp.Before(before) {
+					t.Errorf("Expected the receive time, got %v", logEntry.Timestamp)
+				}
+			} else if !logEntry.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.timestamp, logEntry.Timestamp)
+			}
+			if !reflect.DeepEqual(logEntry.Tags, tt.tags) {
+				t.Errorf("Expected tags %v, got %v", tt.tags, logEntry.Tags)
+			}
+
+			expected := map[string]string{"source": "http", "content_type": "json"}
+			for key, value := range tt.metadata {
+				expected[key] = value
+			}
+			if !reflect.DeepEqual(logEntry.Metadata, expected) {
+				t.Errorf("Expected metadata %v, got %v", expected, logEntry.Metadata)
+			}
+			if logEntry.Source != "api" {
+				t.Errorf("Expected source api, got %s", logEntry.Source)
+			}
+		})
+	}
+}
+
+func TestStructuredLogRequestMetadataWins(t *testing.T) {
+	input := NewHTTPInputWithConfig(Config{Port: "8080", JSONMode: JSONModeStructured})
+	logCh := make(chan *core.Log, 2)
+	input.SetLogChannel(logCh)
+
+	input.handleJSONLogs(input.endpoints[0], []byte(`[{"message":"a","tenant":"spoofed"},{"message":"b"}]`), map[string]string{"tenant": "acme"})
+
+	if len(logCh) != 2 {
+		t.Fatalf("Expected 2 logs, got %d", len(logCh))
+	}
+	for _, expected := range []string{"a", "b"} {
+		logEntry := <-logCh
+		if logEntry.Message != expected || logEntry.Metadata["tenant"] != "acme" {
+			t.Errorf("Expected message %s with tenant acme, got %q %v", expected, logEntry.Message, logEntry.Metadata)
+		}
+	}
+}
+
+func TestHTTPInputJSONModeValidation(t *testing.T) {
+	for _, mode := range []string{"", JSONModeRaw, JSONModeStructured} {
+		if _, err := NewHTTPInputFromConfig(map[string]any{"json_mode": mode}); err != nil {
+			t.Errorf("Expected json_mode %q to be accepted, got %v", mode, err)
+		}
+	}
+	if _, err := NewHTTPInputFromConfig(map[string]any{"json_mode": "parsed"}); err == nil {
+		t.Error("Expected an error for an unknown json_mode")
+	}
+}
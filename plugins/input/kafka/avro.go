@@ -0,0 +1,362 @@
+package kafkainput
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// errTruncated is returned when an encoded value ends before its schema does
+var errTruncated = errors.New("truncated value")
+
+// maxEmptyItems bounds arrays of nulls, which take no bytes per element
+const maxEmptyItems = 1 << 16
+
+// avroSchema is a parsed Avro schema node
+type avroSchema struct {
+	kind     string // Primitive name, "record", "enum", "array", "map", "fixed" or "union"
+	name     string // Full name of named types
+	logical  string // Logical type (e.g. "timestamp-millis")
+	fields   []avroField
+	symbols  []string
+	items    *avroSchema   // Array items or map values
+	branches []*avroSchema // Union branches
+	size     int           // Fixed size
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// parseAvroSchema parses an Avro schema in its JSON form
+func parseAvroSchema(text string) (*avroSchema, error) {
+	var raw any
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+	return newAvroParser().parse(raw, "")
+}
+
+// avroParser resolves references to named types while parsing a schema
+type avroParser struct {
+	named map[string]*avroSchema
+}
+
+func newAvroParser() *avroParser {
+	return &avroParser{named: make(map[string]*avroSchema)}
+}
+
+func (p *avroParser) parse(raw any, namespace string) (*avroSchema, error) {
+	switch v := raw.(type) {
+	case string:
+		return p.parseName(v, namespace)
+	case []any:
+		union := &avroSchema{kind: "union"}
+		for _, branch := range v {
+			schema, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.branches = append(union.branches, schema)
+		}
+		return union, nil
+	case map[string]any:
+		return p.parseComplex(v, namespace)
+	default:
+		return nil, fmt.Errorf("invalid avro schema node %v", raw)
+	}
+}
+
+// parseName resolves a primitive type or a previously defined named type
+func (p *avroParser) parseName(name, namespace string) (*avroSchema, error) {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return &avroSchema{kind: name}, nil
+	}
+	if schema, ok := p.named[qualify(name, namespace)]; ok {
+		return schema, nil
+	}
+	if schema, ok := p.named[name]; ok {
+		return schema, nil
+	}
+	return nil, fmt.Errorf("unknown avro type %q", name)
+}
+
+func (p *avroParser) parseComplex(v map[string]any, namespace string) (*avroSchema, error) {
+	kind, _ := v["type"].(string)
+	logical, _ := v["logicalType"].(string)
+
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		name, _ := v["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("avro %s without a name", kind)
+		}
+		if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		schema := &avroSchema{kind: kind, name: qualify(name, namespace), logical: logical}
+		if kind == "error" {
+			schema.kind = "record"
+		}
+		if i := strings.LastIndexByte(schema.name, '.'); i >= 0 {
+			namespace = schema.name[:i]
+		}
+		// Registered before its fields so recursive records can refer to themselves
+		p.named[schema.name] = schema
+
+		switch schema.kind {
+		case "record":
+			fields, _ := v["fields"].([]any)
+			for _, rawField := range fields {
+				field, _ := rawField.(map[string]any)
+				fieldName, _ := field["name"].(string)
+				if fieldName == "" {
+					return nil, fmt.Errorf("avro record %s has a field without a name", schema.name)
+				}
+				fieldSchema, err := p.parse(field["type"], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("field %s.%s: %w", schema.name, fieldName, err)
+				}
+				schema.fields = append(schema.fields, avroField{name: fieldName, schema: fieldSchema})
+			}
+		case "enum":
+			symbols, _ := v["symbols"].([]any)
+			for _, symbol := range symbols {
+				name, _ := symbol.(string)
+				schema.symbols = append(schema.symbols, name)
+			}
+		case "fixed":
+			size, _ := v["size"].(float64)
+			if size < 0 {
+				return nil, fmt.Errorf("avro fixed %s has a negative size", schema.name)
+			}
+			schema.size = int(size)
+		}
+		return schema, nil
+
+	case "array", "map":
+		key := "items"
+		if kind == "map" {
+			key = "values"
+		}
+		items, err := p.parse(v[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{kind: kind, items: items}, nil
+
+	default:
+		// A primitive written as an object, possibly with a logical type
+		schema, err := p.parseName(kind, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if logical != "" {
+			schema = &avroSchema{kind: schema.kind, name: schema.name, logical: logical}
+		}
+		return schema, nil
+	}
+}
+
+// qualify returns the full name of a type declared in namespace
+func qualify(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// decodeAvro decodes a value in the Avro binary encoding. Records and maps become
+// map[string]any, arrays []any, bytes and fixed base64 strings, and timestamp
+// logical types time.Time.
+func decodeAvro(schema *avroSchema, data []byte) (any, error) {
+	d := &avroDecoder{data: data}
+	value, err := d.decode(schema)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%d trailing bytes after the avro value", len(d.data)-d.pos)
+	}
+	return value, nil
+}
+
+type avroDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *avroDecoder) decode(schema *avroSchema) (any, error) {
+	switch schema.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		if d.pos >= len(d.data) {
+			return nil, errTruncated
+		}
+		d.pos++
+		return d.data[d.pos-1] != 0, nil
+	case "int", "long":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		switch schema.logical {
+		case "timestamp-millis":
+			return time.UnixMilli(n).UTC(), nil
+		case "timestamp-micros":
+			return time.UnixMicro(n).UTC(), nil
+		case "date":
+			return time.Unix(n*86400, 0).UTC().Format(time.DateOnly), nil
+		}
+		return n, nil
+	case "float":
+		b, err := d.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case "double":
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "bytes":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case "fixed":
+		b, err := d.take(schema.size)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case "enum":
+		index, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(schema.symbols)) {
+			return nil, fmt.Errorf("enum %s index %d out of range", schema.name, index)
+		}
+		return schema.symbols[index], nil
+	case "union":
+		index, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(schema.branches)) {
+			return nil, fmt.Errorf("union branch %d out of range", index)
+		}
+		return d.decode(schema.branches[index])
+	case "record":
+		record := make(map[string]any, len(schema.fields))
+		for _, field := range schema.fields {
+			value, err := d.decode(field.schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field.name, err)
+			}
+			record[field.name] = value
+		}
+		return record, nil
+	case "array":
+		var items []any
+		err := d.blocks(schema.items.kind == "null", func() error {
+			value, err := d.decode(schema.items)
+			items = append(items, value)
+			return err
+		})
+		return items, err
+	case "map":
+		values := make(map[string]any)
+		err := d.blocks(false, func() error {
+			key, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			value, err := d.decode(schema.items)
+			values[string(key)] = value
+			return err
+		})
+		return values, err
+	default:
+		return nil, fmt.Errorf("unsupported avro type %q", schema.kind)
+	}
+}
+
+// long reads a zigzag varint
+func (d *avroDecoder) long() (int64, error) {
+	value, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return value, nil
+}
+
+// take reads n raw bytes
+func (d *avroDecoder) take(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errTruncated
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos], nil
+}
+
+// bytes reads a length-prefixed byte string
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n > int64(len(d.data)-d.pos) {
+		return nil, errTruncated
+	}
+	return d.take(int(n))
+}
+
+// blocks reads the blocks of an array or map, calling item for every element.
+// empty is set for arrays of nulls, whose elements take no bytes.
+func (d *avroDecoder) blocks(empty bool, item func() error) error {
+	for {
+		count, err := d.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// A negative count is followed by the block size in bytes
+			count = -count
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		// Every element but a null takes at least one byte, which bounds the count of a corrupt block
+		if count > int64(len(d.data)-d.pos) && (!empty || count > maxEmptyItems) {
+			return errTruncated
+		}
+		for range count {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package kafkainput
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+const avroLogSchema = `{
+	"type": "record", "name": "Log", "namespace": "com.example",
+	"fields": [
+		{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["DEBUG", "INFO", "ERROR"]}},
+		{"name": "message", "type": "string"},
+		{"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "user", "type": ["null", "string"]},
+		{"name": "latency", "type": "double"},
+		{"name": "labels", "type": {"type": "map", "values": "string"}},
+		{"name": "attempts", "type": {"type": "array", "items": "int"}},
+		{"name": "parent", "type": ["null", "Log"]}
+	]
+}`
+
+// avroString appends a length-prefixed Avro string
+func avroString(b []byte, s string) []byte {
+	return append(binary.AppendVarint(b, int64(len(s))), s...)
+}
+
+// avroLog encodes a com.example.Log record with an optional parent
+func avroLog(level int64, message string, parent []byte) []byte {
+	b := binary.AppendVarint(nil, level)
+	b = avroString(b, message)
+	b = binary.AppendVarint(b, 1700000000123)
+	b = binary.AppendVarint(b, 1) // user: string branch
+	b = avroString(b, "alice")
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(12.5))
+	b = binary.AppendVarint(b, 1) // labels: one block of one entry
+	b = avroString(b, "env")
+	b = avroString(b, "prod")
+	b = binary.AppendVarint(b, 0)
+	b = binary.AppendVarint(b, -2) // attempts: a block of two items with its size
+	b = binary.AppendVarint(b, 2)
+	b = binary.AppendVarint(b, 1)
+	b = binary.AppendVarint(b, 2)
+	b = binary.AppendVarint(b, 0)
+	if parent == nil {
+		return binary.AppendVarint(b, 0)
+	}
+	return append(binary.AppendVarint(b, 1), parent...)
+}
+
+func TestDecodeAvro(t *testing.T) {
+	schema, err := parseAvroSchema(avroLogSchema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	decoded, err := decodeAvro(schema, avroLog(2, "payment failed", avroLog(1, "payment started", nil)))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	record := decoded.(map[string]any)
+
+	if record["level"] != "ERROR" || record["message"] != "payment failed" || record["user"] != "alice" {
+		t.Errorf("Expected decoded fields, got %v", record)
+	}
+	if record["timestamp"] != time.UnixMilli(1700000000123).UTC() {
+		t.Errorf("Expected a timestamp, got %v", record["timestamp"])
+	}
+	if record["latency"] != 12.5 {
+		t.Errorf("Expected latency 12.5, got %v", record["latency"])
+	}
+	if labels := record["labels"].(map[string]any); labels["env"] != "prod" {
+		t.Errorf("Expected labels, got %v", labels)
+	}
+	if attempts := record["attempts"].([]any); len(attempts) != 2 || attempts[0] != int64(1) || attempts[1] != int64(2) {
+		t.Errorf("Expected attempts [1 2], got %v", attempts)
+	}
+
+	// The recursive reference resolves to the record being defined
+	parent, ok := record["parent"].(map[string]any)
+	if !ok || parent["message"] != "payment started" || parent["parent"] != nil {
+		t.Errorf("Expected the parent record, got %v", record["parent"])
+	}
+}
+
+func TestDecodeAvroInvalid(t *testing.T) {
+	schema, err := parseAvroSchema(avroLogSchema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	valid := avroLog(0, "ok", nil)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", valid[:len(valid)-3]},
+		{"trailing bytes", append(append([]byte{}, valid...), 0)},
+		{"enum out of range", avroLog(7, "ok", nil)},
+		{"string longer than the value", append(binary.AppendVarint(nil, 0), avroString(nil, "x")[:1]...)},
+	}
+
+	for _, tt := range tests {
+		if _, err := decodeAvro(schema, tt.data); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// A corrupt block count must not make the decoder loop over absent items
+	arraySchema, _ := parseAvroSchema(`{"type": "array", "items": "long"}`)
+	if _, err := decodeAvro(arraySchema, binary.AppendVarint(nil, math.MaxInt64/2)); !errors.Is(err, errTruncated) {
+		t.Errorf("Expected a truncated error for a corrupt block count, got %v", err)
+	}
+}
+
+func TestParseAvroSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"invalid JSON", `{"type":`},
+		{"unknown type", `"Missing"`},
+		{"record without name", `{"type": "record", "fields": []}`},
+		{"field with unknown type", `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "Other"}]}`},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseAvroSchema(tt.schema); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
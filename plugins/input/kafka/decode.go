@@ -0,0 +1,227 @@
+package kafkainput
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/structured"
+)
+
+// Record value formats
+const (
+	ValueFormatRaw      = "raw"      // The value is the log message (default)
+	ValueFormatJSON     = "json"     // A JSON object, with or without Schema Registry framing
+	ValueFormatAvro     = "avro"     // Avro binary with Schema Registry framing
+	ValueFormatProtobuf = "protobuf" // Protobuf with Schema Registry framing
+)
+
+// Schema Registry wire format: a zero magic byte and a 4-byte big-endian schema ID
+// precede the encoded value
+const (
+	registryMagicByte   = 0
+	registryHeaderBytes = 5
+)
+
+// validateValueFormat checks the value_format option
+func validateValueFormat(format, registryURL string) error {
+	switch format {
+	case "", ValueFormatRaw, ValueFormatJSON:
+		return nil
+	case ValueFormatAvro, ValueFormatProtobuf:
+		if registryURL == "" {
+			return fmt.Errorf("value_format '%s' requires schema_registry_url", format)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid value_format '%s', must be 'raw', 'json', 'avro' or 'protobuf'", format)
+	}
+}
+
+// defaultRegistryTimeout bounds a schema fetch when schema_registry_timeout is unset
+const defaultRegistryTimeout = 10 * time.Second
+
+// newValueDecoder validates the decoding options and returns the decoder, or nil
+// for raw values
+func newValueDecoder(cfg Config) (*valueDecoder, error) {
+	if err := validateValueFormat(cfg.ValueFormat, cfg.SchemaRegistryURL); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateParseErrorMode(cfg.OnParseError); err != nil {
+		return nil, err
+	}
+	if cfg.SchemaRegistryTimeout < 0 {
+		return nil, fmt.Errorf("schema_registry_timeout must be non-negative")
+	}
+	if err := cfg.SchemaRegistryTLS.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.ValueFormat == "" || cfg.ValueFormat == ValueFormatRaw {
+		return nil, nil
+	}
+
+	decoder := &valueDecoder{format: cfg.ValueFormat, onParseError: cfg.OnParseError}
+	if cfg.SchemaRegistryURL != "" {
+		timeout := defaultRegistryTimeout
+		if cfg.SchemaRegistryTimeout > 0 {
+			timeout = time.Duration(cfg.SchemaRegistryTimeout) * time.Second
+		}
+		registry, err := newSchemaRegistry(cfg.SchemaRegistryURL, cfg.SchemaRegistryUsername,
+			cfg.SchemaRegistryPassword, timeout, cfg.SchemaRegistryTLS)
+		if err != nil {
+			return nil, err
+		}
+		decoder.registry = registry
+	}
+	return decoder, nil
+}
+
+// valueDecoder turns encoded record values into structured logs
+type valueDecoder struct {
+	format       string
+	registry     *schemaRegistry // Nil for JSON without a registry
+	onParseError string
+}
+
+// apply decodes the log's message, the record value, and maps the decoded fields
+// onto the log. A value that cannot be decoded, including one whose schema cannot
+// be fetched, is handled by on_parse_error and keeps the undecoded value as its
+// message. It returns nil when the record must be dropped.
+func (d *valueDecoder) apply(ctx context.Context, logEntry *core.Log) *core.Log {
+	value := []byte(logEntry.Message)
+	record, schemaID, err := d.decode(ctx, value)
+	if schemaID >= 0 {
+		logEntry.Metadata["schema_id"] = strconv.Itoa(schemaID)
+	}
+	if err != nil {
+		if d.format != ValueFormatJSON {
+			// Binary values are kept base64 encoded so outputs can carry them
+			logEntry.Message = base64.StdEncoding.EncodeToString(value)
+		}
+		logEntry.Metadata["value_format"] = d.format
+		return core.HandleParseError(d.onParseError, logEntry, err.Error())
+	}
+
+	applyRecord(logEntry, record)
+	return logEntry
+}
+
+// decode decodes a record value; the schema ID is -1 when the value is not framed
+func (d *valueDecoder) decode(ctx context.Context, value []byte) (map[string]any, int, error) {
+	schemaID := -1
+	if len(value) >= registryHeaderBytes && value[0] == registryMagicByte {
+		schemaID = int(binary.BigEndian.Uint32(value[1:registryHeaderBytes]))
+		value = value[registryHeaderBytes:]
+	} else if d.format != ValueFormatJSON {
+		return nil, schemaID, fmt.Errorf("value is not in the schema registry wire format")
+	}
+
+	if d.format == ValueFormatJSON {
+		var record map[string]any
+		if err := json.Unmarshal(value, &record); err != nil {
+			return nil, schemaID, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return record, schemaID, nil
+	}
+
+	schema, err := d.registry.schema(ctx, schemaID)
+	if err != nil {
+		return nil, schemaID, err
+	}
+
+	var decoded any
+	switch {
+	case d.format == ValueFormatAvro && schema.avro != nil:
+		decoded, err = decodeAvro(schema.avro, value)
+	case d.format == ValueFormatProtobuf && schema.proto != nil:
+		decoded, err = decodeProtobuf(schema.proto, value)
+	default:
+		return nil, schemaID, fmt.Errorf("schema %d is %s, not %s", schemaID, schema.schemaType, d.format)
+	}
+	if err != nil {
+		return nil, schemaID, fmt.Errorf("invalid %s value: %w", d.format, err)
+	}
+
+	record, ok := decoded.(map[string]any)
+	if !ok {
+		// A schema that is not a record, e.g. a plain string
+		record = map[string]any{"message": decoded}
+	}
+	return record, schemaID, nil
+}
+
+// decodeProtobuf reads the message indexes that follow the schema ID and decodes
+// the selected message. A single 0 stands for the first message.
+func decodeProtobuf(file *protoFile, value []byte) (any, error) {
+	count, n := binary.Varint(value)
+	if n <= 0 || count < 0 || count > int64(len(value)) {
+		return nil, fmt.Errorf("invalid message indexes")
+	}
+	value = value[n:]
+
+	indexes := make([]int, 0, count)
+	for range count {
+		index, n := binary.Varint(value)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid message indexes")
+		}
+		indexes = append(indexes, int(index))
+		value = value[n:]
+	}
+
+	message, err := file.messageAt(indexes)
+	if err != nil {
+		return nil, err
+	}
+	return message.decode(value)
+}
+
+// decodedFields are the fields applyRecord maps onto the log itself
+var decodedFields = map[string]bool{
+	"level": true, "message": true, "msg": true, "timestamp": true, "time": true, "tags": true,
+}
+
+// applyRecord maps a decoded record onto a log, like the HTTP input's structured
+// JSON mode:
+//
+//   - level: the log level, replacing the level header
+//   - message (or msg): the message; when both are missing the message is the
+//     whole record as JSON
+//   - timestamp (or time): a timestamp type, RFC 3339 or Unix seconds
+//   - tags: a string or an array of strings
+//
+// Every other field becomes metadata, JSON encoded unless it is a string. The
+// record's topic, partition, offset, key and headers are never overridden.
+func applyRecord(logEntry *core.Log, record map[string]any) {
+	if level, ok := record["level"].(string); ok && level != "" {
+		logEntry.Level = core.Levels().Normalize(level)
+	}
+
+	if message, ok := structured.Field(record, "message", "msg"); ok {
+		logEntry.Message = structured.String(message)
+	} else {
+		logEntry.Message = structured.String(record)
+	}
+
+	if value, ok := structured.Field(record, "timestamp", "time"); ok {
+		if timestamp, ok := structured.Timestamp(value); ok {
+			logEntry.Timestamp = timestamp
+		}
+	}
+
+	logEntry.Tags = append(logEntry.Tags, structured.Tags(record["tags"])...)
+
+	for key, value := range record {
+		if decodedFields[key] || value == nil {
+			continue
+		}
+		if _, taken := logEntry.Metadata[key]; !taken {
+			logEntry.Metadata[key] = structured.String(value)
+		}
+	}
+}
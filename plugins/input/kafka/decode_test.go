@@ -0,0 +1,219 @@
+package kafkainput
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/segmentio/kafka-go"
+)
+
+// newTestRegistry serves avroLogSchema as schema 1 and protoLogSchema as schema 2
+func newTestRegistry(t *testing.T, fetches *atomic.Int64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		var response map[string]string
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			response = map[string]string{"schema": avroLogSchema}
+		case "/schemas/ids/2":
+			response = map[string]string{"schema": protoLogSchema, "schemaType": "PROTOBUF"}
+		default:
+			http.Error(w, `{"error_code":40403,"message":"Schema not found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// framed prefixes a value with the schema registry header
+func framed(schemaID uint32, value []byte) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte{registryMagicByte}, schemaID), value...)
+}
+
+func testDecoder(t *testing.T, config map[string]any) *valueDecoder {
+	plugin, err := NewKafkaInputFromConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create kafka input: %v", err)
+	}
+	input := plugin.(*KafkaInput)
+	_ = input.reader.Close()
+	return input.decoder
+}
+
+func decodeMessage(t *testing.T, decoder *valueDecoder, value []byte) *core.Log {
+	msg := kafka.Message{Topic: "logs", Partition: 1, Offset: 7, Value: value}
+	logEntry, err := buildLogFromMessage(msg, "kafka", 0)
+	if err != nil {
+		t.Fatalf("Failed to build log: %v", err)
+	}
+	return decoder.apply(context.Background(), logEntry)
+}
+
+func TestValueDecoderAvro(t *testing.T) {
+	var fetches atomic.Int64
+	registry := newTestRegistry(t, &fetches)
+	decoder := testDecoder(t, map[string]any{
+		"brokers": []string{"localhost:9092"}, "topic": "logs",
+		"value_format": "avro", "schema_registry_url": registry.URL,
+	})
+
+	for range 3 {
+		logEntry := decodeMessage(t, decoder, framed(1, avroLog(2, "payment failed", nil)))
+		if logEntry.Level != "error" || logEntry.Message != "payment failed" {
+			t.Fatalf("Expected the decoded level and message, got %s %q", logEntry.Level, logEntry.Message)
+		}
+		if !logEntry.Timestamp.Equal(time.UnixMilli(1700000000123)) {
+			t.Errorf("Expected the record timestamp, got %v", logEntry.Timestamp)
+		}
+		metadata := logEntry.Metadata
+		if metadata["schema_id"] != "1" || metadata["user"] != "alice" || metadata["latency"] != "12.5" ||
+			metadata["labels"] != `{"env":"prod"}` || metadata["topic"] != "logs" {
+			t.Errorf("Expected decoded metadata, got %v", metadata)
+		}
+	}
+
+	// Schemas are cached by ID
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 registry fetch, got %d", got)
+	}
+}
+
+func TestValueDecoderProtobuf(t *testing.T) {
+	var fetches atomic.Int64
+	registry := newTestRegistry(t, &fetches)
+	decoder := testDecoder(t, map[string]any{
+		"brokers": []string{"localhost:9092"}, "topic": "logs",
+		"value_format": "protobuf", "schema_registry_url": registry.URL,
+	})
+
+	// Message indexes [1] select Log
+	value := append([]byte{2, 2}, protoLogMessage()...)
+	logEntry := decodeMessage(t, decoder, framed(2, value))
+	if logEntry.Message != "payment failed" || logEntry.Metadata["service"] != "billing" || logEntry.Metadata["schema_id"] != "2" {
+		t.Errorf("Expected the decoded record, got %q %v", logEntry.Message, logEntry.Metadata)
+	}
+
+	// An Avro schema ID is not a protobuf schema
+	logEntry = decodeMessage(t, decoder, framed(1, value))
+	if logEntry == nil || logEntry.Metadata["value_format"] != "protobuf" || logEntry.Metadata[core.ParseErrorKey] != "" {
+		t.Errorf("Expected the undecoded record to be forwarded as-is without on_parse_error, got %v", logEntry)
+	}
+}
+
+func TestValueDecoderUnavailableSchema(t *testing.T) {
+	var fetches atomic.Int64
+	registry := newTestRegistry(t, &fetches)
+
+	tests := []struct {
+		mode    string
+		dropped bool
+		tagged  bool
+	}{
+		{"", false, false},
+		{core.ParseErrorPassRaw, false, false},
+		{core.ParseErrorTag, false, true},
+		{core.ParseErrorDrop, true, false},
+	}
+
+	for _, tt := range tests {
+		fetches.Store(0)
+		decoder := testDecoder(t, map[string]any{
+			"brokers": []string{"localhost:9092"}, "topic": "logs",
+			"value_format": "avro", "schema_registry_url": registry.URL, "on_parse_error": tt.mode,
+		})
+
+		for range 2 {
+			logEntry := decodeMessage(t, decoder, framed(42, []byte{1, 2, 3}))
+			if tt.dropped {
+				if logEntry != nil {
+					t.Errorf("%q: expected the record to be dropped", tt.mode)
+				}
+				continue
+			}
+			if logEntry == nil {
+				t.Fatalf("%q: expected the record to be forwarded", tt.mode)
+			}
+			if logEntry.Message != "AAAAACoBAgM=" || logEntry.Metadata["schema_id"] != "42" {
+				t.Errorf("%q: expected the base64 value and schema ID, got %q %v", tt.mode, logEntry.Message, logEntry.Metadata)
+			}
+			if tt.mode != "" && !strings.Contains(logEntry.Metadata[core.ParseErrorReasonKey], "schema 42 unavailable") {
+				t.Errorf("%q: expected a parse error reason, got %v", tt.mode, logEntry.Metadata)
+			}
+			if logEntry.HasTag(core.ParseErrorKey) != tt.tagged {
+				t.Errorf("%q: expected tagged=%v, got %v", tt.mode, tt.tagged, logEntry.Tags)
+			}
+		}
+
+		// The failure is remembered rather than fetched for every record
+		if got := fetches.Load(); got != 1 {
+			t.Errorf("%q: expected 1 registry fetch, got %d", tt.mode, got)
+		}
+	}
+}
+
+func TestValueDecoderJSON(t *testing.T) {
+	decoder := testDecoder(t, map[string]any{
+		"brokers": []string{"localhost:9092"}, "topic": "logs",
+		"value_format": "json", "on_parse_error": "tag",
+	})
+
+	value := []byte(`{"level":"WARN","msg":"disk low","tags":["infra"],"free":0.5,"topic":"ignored"}`)
+	for _, v := range [][]byte{value, framed(3, value)} {
+		logEntry := decodeMessage(t, decoder, v)
+		if logEntry.Level != "warn" || logEntry.Message != "disk low" || !logEntry.HasTag("infra") {
+			t.Errorf("Expected the decoded record, got %s %q %v", logEntry.Level, logEntry.Message, logEntry.Tags)
+		}
+		// Record fields never override the Kafka metadata
+		if logEntry.Metadata["free"] != "0.5" || logEntry.Metadata["topic"] != "logs" {
+			t.Errorf("Expected decoded metadata, got %v", logEntry.Metadata)
+		}
+	}
+
+	logEntry := decodeMessage(t, decoder, []byte("not json"))
+	if logEntry.Message != "not json" || !logEntry.HasTag(core.ParseErrorKey) {
+		t.Errorf("Expected the raw value tagged as a parse error, got %q %v", logEntry.Message, logEntry.Tags)
+	}
+}
+
+func TestValueDecoderConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]any
+		expected string
+	}{
+		{"raw", map[string]any{}, ""},
+		{"json without registry", map[string]any{"value_format": "json"}, ""},
+		{"avro without registry", map[string]any{"value_format": "avro"}, "requires schema_registry_url"},
+		{"protobuf without registry", map[string]any{"value_format": "protobuf"}, "requires schema_registry_url"},
+		{"unknown format", map[string]any{"value_format": "thrift"}, "invalid value_format"},
+		{"invalid on_parse_error", map[string]any{"value_format": "json", "on_parse_error": "skip"}, "invalid on_parse_error"},
+		{"negative timeout", map[string]any{"value_format": "json", "schema_registry_timeout": -1}, "schema_registry_timeout"},
+	}
+
+	for _, tt := range tests {
+		config := map[string]any{"brokers": []string{"localhost:9092"}, "topic": "logs"}
+		for key, value := range tt.config {
+			config[key] = value
+		}
+		plugin, err := NewKafkaInputFromConfig(config)
+		if err == nil {
+			_ = plugin.(*KafkaInput).reader.Close()
+		}
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -4,22 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/compress"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
 	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/sasl/plain"
 )
 
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.kafka")
+
 func init() {
 	core.RegisterInputPlugin("kafka", NewKafkaInputFromConfig)
 }
 
+// DefaultMaxDeliveryAttempts is how often a record is sent to sync outputs that
+// keep failing before it is skipped, when max_delivery_attempts is unset
+const DefaultMaxDeliveryAttempts = 10
+
 // Config represents Kafka input configuration values supplied via YAML.
 type Config struct {
 	Brokers     []string         `yaml:"brokers"`
@@ -32,6 +41,22 @@ type Config struct {
 	Username    string           `yaml:"username,omitempty"`
 	Password    string           `yaml:"password,omitempty"`
 	TLS         tlsconfig.Config `yaml:"tls,omitempty"` // TLS configuration
+
+	// Max size of a record flagged with a content-encoding header once decompressed (default: 10MB)
+	MaxDecompressedBytes int64 `yaml:"max_decompressed_bytes,omitempty"`
+
+	// With an ack_mode: sync output, attempts to deliver a record before it is
+	// skipped and committed (default: 10, -1 = retry until delivered)
+	MaxDeliveryAttempts int `yaml:"max_delivery_attempts,omitempty"`
+
+	// Record value decoding
+	ValueFormat            string           `yaml:"value_format,omitempty"`             // raw (default), json, avro or protobuf
+	SchemaRegistryURL      string           `yaml:"schema_registry_url,omitempty"`      // Confluent Schema Registry, required for avro and protobuf
+	SchemaRegistryUsername string           `yaml:"schema_registry_username,omitempty"` // Basic auth
+	SchemaRegistryPassword string           `yaml:"schema_registry_password,omitempty"`
+	SchemaRegistryTimeout  int              `yaml:"schema_registry_timeout,omitempty"` // Seconds (default: 10)
+	SchemaRegistryTLS      tlsconfig.Config `yaml:"schema_registry_tls,omitempty"`
+	OnParseError           string           `yaml:"on_parse_error,omitempty"` // Values that cannot be decoded: pass_raw, tag or drop (default: forward as-is)
 }
 
 // NewKafkaInputFromConfig builds a Kafka input plugin from generic configuration.
@@ -47,12 +72,30 @@ func NewKafkaInputFromConfig(config map[string]any) (any, error) {
 	if cfg.Topic == "" {
 		return nil, fmt.Errorf("kafka input requires a topic")
 	}
+	if cfg.MaxDecompressedBytes < 0 {
+		return nil, fmt.Errorf("max_decompressed_bytes must be non-negative")
+	}
+	if cfg.MaxDeliveryAttempts < -1 {
+		return nil, fmt.Errorf("max_delivery_attempts must be -1 (unlimited) or more")
+	}
+	maxAttempts := cfg.MaxDeliveryAttempts
+	switch maxAttempts {
+	case 0:
+		maxAttempts = DefaultMaxDeliveryAttempts
+	case -1:
+		maxAttempts = 0
+	}
 
 	// Validate TLS config
 	if err := cfg.TLS.Validate(); err != nil {
 		return nil, err
 	}
 
+	decoder, err := newValueDecoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	startOffset, err := parseStartOffset(cfg.StartOffset)
 	if err != nil {
 		return nil, err
@@ -113,6 +156,10 @@ func NewKafkaInputFromConfig(config map[string]any) (any, error) {
 		topic:   cfg.Topic,
 		groupID: cfg.GroupID,
 		reader:  reader,
+
+		maxDecompressed: cfg.MaxDecompressedBytes,
+		maxAttempts:     maxAttempts,
+		decoder:         decoder,
 	}, nil
 }
 
@@ -125,6 +172,12 @@ type KafkaInput struct {
 	topic   string
 	groupID string
 
+	maxDecompressed int64         // Decompressed size limit of content-encoded records
+	maxAttempts     int           // Delivery attempts before a record is skipped (0 = unlimited)
+	decoder         *valueDecoder // Nil for raw values
+	ackRequired     func() bool   // Whether a pipeline has ack_mode: sync (nil = never)
+	undelivered     atomic.Int64  // Records skipped after maxAttempts failed deliveries
+
 	ctx     context.Context
 	cancel  context.CancelFunc
 	wg      sync.WaitGroup
@@ -141,6 +194,12 @@ func (k *KafkaInput) SetLogChannel(ch chan<- *core.Log) {
 	k.logCh = ch
 }
 
+// SetAckRequired sets the engine's check for pipelines with ack_mode: sync. While
+// one exists, a record is committed only once every sync pipeline delivered it.
+func (k *KafkaInput) SetAckRequired(required func() bool) {
+	k.ackRequired = required
+}
+
 // Start launches the background goroutine that reads from Kafka.
 func (k *KafkaInput) Start() error {
 	if k.reader == nil {
@@ -155,7 +214,7 @@ func (k *KafkaInput) Start() error {
 	k.wg.Add(1)
 	go k.consumeLoop()
 
-	log.Printf("Kafka input started (topic=%s, brokers=%v, group=%s)", k.topic, k.brokers, k.groupID)
+	logger.Printf("Kafka input started (topic=%s, brokers=%v, group=%s)", k.topic, k.brokers, k.groupID)
 	return nil
 }
 
@@ -174,15 +233,25 @@ func (k *KafkaInput) Stop() error {
 
 	if k.reader != nil {
 		if err := k.reader.Close(); err != nil {
-			log.Printf("Kafka input close error: %v", err)
+			logger.Printf("Kafka input close error: %v", err)
 		}
 	}
+	if k.decoder != nil && k.decoder.registry != nil {
+		k.decoder.registry.client.CloseIdleConnections()
+	}
 
-	log.Printf("Kafka input stopped")
+	logger.Printf("Kafka input stopped")
 	k.ctx = nil
 	return nil
 }
 
+// InputStats implements core.InputStatsReporter
+func (k *KafkaInput) InputStats() map[string]any {
+	return map[string]any{
+		"undelivered": k.undelivered.Load(),
+	}
+}
+
 // CheckHealth implements HealthChecker interface
 func (k *KafkaInput) CheckHealth(ctx context.Context) error {
 	if k.reader == nil {
@@ -211,16 +280,19 @@ func (k *KafkaInput) consumeLoop() {
 				return
 			}
 
-			log.Printf("Kafka input fetch error: %v", err)
+			logger.Printf("Kafka input fetch error: %v", err)
 			time.Sleep(time.Second)
 			continue
 		}
 
-		logEntry := buildLogFromMessage(msg, k.name)
-
-		select {
-		case k.logCh <- logEntry:
-		case <-k.ctx.Done():
+		logEntry, err := buildLogFromMessage(msg, k.name, k.maxDecompressed)
+		if err != nil {
+			// Skip (and commit) the record so one bad payload cannot block the partition
+			logger.Printf("Dropping record %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		} else if k.decoder != nil {
+			logEntry = k.decoder.apply(k.ctx, logEntry)
+		}
+		if logEntry != nil && !k.forward(logEntry) {
 			return
 		}
 
@@ -229,14 +301,70 @@ func (k *KafkaInput) consumeLoop() {
 				if k.ctx.Err() != nil {
 					return
 				}
-				log.Printf("Kafka input commit error: %v", err)
+				logger.Printf("Kafka input commit error: %v", err)
 			}
 		}
 	}
 }
 
-func buildLogFromMessage(msg kafka.Message, source string) *core.Log {
+// forward sends a log to the engine and returns false once the input is stopped.
+// With a sync pipeline it waits for the log to be delivered and, as the record
+// cannot be committed without committing the ones before it, resends a copy of it
+// until it is, stalling the partition meanwhile. A resend only goes to the sync
+// pipelines that did not deliver it (see core.Ack.Retry). After maxAttempts the
+// record is given up on, counted as undelivered, so the partition moves on.
+func (k *KafkaInput) forward(logEntry *core.Log) bool {
+	var ack *core.Ack
+	for attempt := 1; ; attempt++ {
+		entry := logEntry
+		if k.ackRequired != nil && k.ackRequired() {
+			// The engine's filters may change what they are given, so each attempt gets a fresh copy
+			entry = logEntry.Clone()
+			if ack == nil {
+				ack = core.NewAck()
+			} else {
+				ack = ack.Retry()
+			}
+			entry.SetAck(ack)
+		} else {
+			ack = nil
+		}
+
+		select {
+		case k.logCh <- entry:
+		case <-k.ctx.Done():
+			return false
+		}
+
+		err := ack.Wait(k.ctx)
+		if err == nil {
+			return true
+		}
+		if k.ctx.Err() != nil {
+			return false
+		}
+		if k.maxAttempts > 0 && attempt >= k.maxAttempts {
+			k.undelivered.Add(1)
+			logger.Printf("Skipping record %s@%s not delivered after %d attempts: %v",
+				logEntry.Metadata["partition"], logEntry.Metadata["offset"], attempt, err)
+			return true
+		}
+
+		logger.Printf("Record %s@%s not delivered (attempt %d), resending: %v",
+			logEntry.Metadata["partition"], logEntry.Metadata["offset"], attempt, err)
+		select {
+		case <-time.After(min(time.Duration(attempt)*time.Second, 30*time.Second)):
+		case <-k.ctx.Done():
+			return false
+		}
+	}
+}
+
+// buildLogFromMessage converts a record into a log. Records with a content-encoding
+// header (gzip or zstd) are decompressed, up to maxDecompressed bytes.
+func buildLogFromMessage(msg kafka.Message, source string, maxDecompressed int64) (*core.Log, error) {
 	level := "info"
+	value := msg.Value
 	metadata := map[string]string{
 		"source":    "kafka",
 		"topic":     msg.Topic,
@@ -252,12 +380,19 @@ func buildLogFromMessage(msg kafka.Message, source string) *core.Log {
 		if strings.EqualFold(header.Key, "level") {
 			level = strings.ToLower(string(header.Value))
 		}
+		if strings.EqualFold(header.Key, "content-encoding") {
+			decompressed, err := compress.Decompress(string(header.Value), msg.Value, maxDecompressed)
+			if err != nil {
+				return nil, err
+			}
+			value = decompressed
+		}
 		metadata["header."+strings.ToLower(header.Key)] = string(header.Value)
 	}
 
-	logEntry := core.NewLogWithMetadata(level, string(msg.Value), metadata)
+	logEntry := core.NewLogWithMetadata(level, string(value), metadata)
 	logEntry.Source = source
-	return logEntry
+	return logEntry, nil
 }
 
 func parseStartOffset(raw string) (int64, error) {
@@ -1,8 +1,16 @@
 package kafkainput
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/compress"
 	"github.com/segmentio/kafka-go"
 )
 
@@ -50,7 +58,10 @@ func TestBuildLogFromMessage(t *testing.T) {
 		Value: []byte("service failed"),
 	}
 
-	entry := buildLogFromMessage(msg, "kafka-input-1")
+	entry, err := buildLogFromMessage(msg, "kafka-input-1", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
 	if entry.Level != "error" {
 		t.Fatalf("expected level 'error', got %s", entry.Level)
@@ -82,6 +93,36 @@ func TestBuildLogFromMessage(t *testing.T) {
 	}
 }
 
+func TestBuildLogFromCompressedMessage(t *testing.T) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, _ = writer.Write([]byte("payment declined"))
+	_ = writer.Close()
+
+	msg := kafka.Message{
+		Topic:   "logs",
+		Headers: []kafka.Header{{Key: "Content-Encoding", Value: []byte("gzip")}},
+		Value:   buf.Bytes(),
+	}
+
+	entry, err := buildLogFromMessage(msg, "kafka-input-1", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.Message != "payment declined" {
+		t.Errorf("expected decompressed message, got %q", entry.Message)
+	}
+
+	if _, err := buildLogFromMessage(msg, "kafka-input-1", 4); !errors.Is(err, compress.ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge above max_decompressed_bytes, got %v", err)
+	}
+
+	msg.Headers[0].Value = []byte("br")
+	if _, err := buildLogFromMessage(msg, "kafka-input-1", 0); !errors.Is(err, compress.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for an unknown encoding, got %v", err)
+	}
+}
+
 func TestNewKafkaInputFromConfigValidation(t *testing.T) {
 	_, err := NewKafkaInputFromConfig(map[string]any{
 		"topic": "logs",
@@ -127,3 +168,115 @@ func TestNewKafkaInputFromConfigSuccess(t *testing.T) {
 		t.Fatal("expected reader to be initialized")
 	}
 }
+
+// flakyOutput fails its first writes, as many as failures
+type flakyOutput struct {
+	mu       sync.Mutex
+	failures int
+	writes   []*core.Log
+}
+
+func (o *flakyOutput) Write(logEntry *core.Log) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failures > 0 {
+		o.failures--
+		return errors.New("backend unavailable")
+	}
+	o.writes = append(o.writes, logEntry)
+	return nil
+}
+
+func (o *flakyOutput) Close() error { return nil }
+
+func TestForwardResendsUntilDelivered(t *testing.T) {
+	engine := core.NewEngine()
+	output := &flakyOutput{failures: 1}
+	if err := engine.AddOutputPipeline(&core.OutputPipeline{Name: "durable", Output: output, AckMode: core.AckModeSync}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	console := &flakyOutput{}
+	if err := engine.AddOutputPipeline(&core.OutputPipeline{Name: "console", Output: console}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	engine.Start()
+	defer engine.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	input := &KafkaInput{logCh: engine.InputChannel(), ctx: ctx, ackRequired: func() bool { return true }}
+
+	if !input.forward(core.NewLog("info", "order placed")) {
+		t.Fatal("expected the record to be delivered")
+	}
+	output.mu.Lock()
+	defer output.mu.Unlock()
+	if len(output.writes) != 1 || output.writes[0].Message != "order placed" {
+		t.Errorf("expected the record to be resent once after the failed write, got %d writes", len(output.writes))
+	}
+
+	// The resend went only to the sync pipeline that failed
+	engine.Stop()
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	if len(console.writes) != 1 {
+		t.Errorf("expected the async output to get the record once, got %d writes", len(console.writes))
+	}
+}
+
+func TestForwardGivesUpAfterMaxAttempts(t *testing.T) {
+	engine := core.NewEngine()
+	output := &flakyOutput{failures: 100}
+	if err := engine.AddOutputPipeline(&core.OutputPipeline{Name: "durable", Output: output, AckMode: core.AckModeSync}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	engine.Start()
+	defer engine.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	input := &KafkaInput{logCh: engine.InputChannel(), ctx: ctx, ackRequired: func() bool { return true }, maxAttempts: 2}
+
+	if !input.forward(core.NewLog("info", "poison")) {
+		t.Fatal("expected the record to be skipped rather than the input to stop")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("expected forward to give up before the input was stopped")
+	}
+	output.mu.Lock()
+	defer output.mu.Unlock()
+	if output.failures != 98 {
+		t.Errorf("expected 2 delivery attempts, got %d", 100-output.failures)
+	}
+	if stats := input.InputStats(); stats["undelivered"] != int64(1) {
+		t.Errorf("expected the skipped record to be counted, got %v", stats)
+	}
+}
+
+func TestMaxDeliveryAttemptsConfig(t *testing.T) {
+	tests := []struct {
+		value    int
+		expected int
+	}{
+		{0, DefaultMaxDeliveryAttempts},
+		{3, 3},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		plugin, err := NewKafkaInputFromConfig(map[string]any{
+			"brokers":               []string{"localhost:9092"},
+			"topic":                 "logs",
+			"max_delivery_attempts": tt.value,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := plugin.(*KafkaInput).maxAttempts; got != tt.expected {
+			t.Errorf("max_delivery_attempts %d: expected %d attempts, got %d", tt.value, tt.expected, got)
+		}
+	}
+
+	if _, err := NewKafkaInputFromConfig(map[string]any{"brokers": []string{"localhost:9092"}, "topic": "logs", "max_delivery_attempts": -2}); err == nil {
+		t.Error("expected an error for max_delivery_attempts below -1")
+	}
+}
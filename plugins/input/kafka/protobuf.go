@@ -0,0 +1,593 @@
+package kafkainput
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoFile is the part of a .proto schema needed to decode messages: message and
+// enum declarations. Services, options and extensions are skipped.
+type protoFile struct {
+	pkg      string
+	messages []*protoMessage // Top-level messages in declaration order, as message indexes refer to them
+	types    map[string]*protoMessage
+	enums    map[string]*protoEnum
+}
+
+type protoMessage struct {
+	name     string // Full name
+	fields   map[protowire.Number]*protoField
+	nested   []*protoMessage // In declaration order
+	mapEntry bool            // Synthesized key/value message of a map field
+}
+
+type protoField struct {
+	name     string
+	number   protowire.Number
+	typeName string // Scalar type or the type name as written
+	repeated bool
+	scope    string // Full name of the declaring message, for resolving typeName
+
+	message *protoMessage // Resolved message type
+	enum    *protoEnum    // Resolved enum type
+}
+
+type protoEnum struct {
+	name   string
+	values map[int64]string
+}
+
+// protoScalars maps scalar types to their wire type
+var protoScalars = map[string]protowire.Type{
+	"double": protowire.Fixed64Type, "float": protowire.Fixed32Type,
+	"int32": protowire.VarintType, "int64": protowire.VarintType,
+	"uint32": protowire.VarintType, "uint64": protowire.VarintType,
+	"sint32": protowire.VarintType, "sint64": protowire.VarintType,
+	"fixed32": protowire.Fixed32Type, "fixed64": protowire.Fixed64Type,
+	"sfixed32": protowire.Fixed32Type, "sfixed64": protowire.Fixed64Type,
+	"bool": protowire.VarintType, "string": protowire.BytesType, "bytes": protowire.BytesType,
+}
+
+// parseProtoSchema parses the message and enum declarations of a .proto file
+func parseProtoSchema(text string) (*protoFile, error) {
+	tokens, err := tokenizeProto(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{
+		tokens: tokens,
+		file:   &protoFile{types: make(map[string]*protoMessage), enums: make(map[string]*protoEnum)},
+	}
+	if err := p.parseFile(); err != nil {
+		return nil, fmt.Errorf("invalid protobuf schema: %w", err)
+	}
+	p.file.resolve()
+	return p.file, nil
+}
+
+// tokenizeProto splits a .proto file into identifiers, numbers, strings and symbols
+func tokenizeProto(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(text[i:], "//"):
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			i += end
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(text) && text[end] != c {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, text[i:end+1])
+			i = end + 1
+		case isProtoWordChar(c):
+			end := i
+			for end < len(text) && isProtoWordChar(text[end]) {
+				end++
+			}
+			tokens = append(tokens, text[i:end])
+			i = end
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isProtoWordChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+type protoParser struct {
+	tokens []string
+	pos    int
+	file   *protoFile
+}
+
+func (p *protoParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *protoParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *protoParser) expect(token string) error {
+	if got := p.next(); got != token {
+		return fmt.Errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+// skipStatement skips to the end of a statement, including any nested braces
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unexpected end of schema")
+}
+
+func (p *protoParser) parseFile() error {
+	for p.pos < len(p.tokens) {
+		switch token := p.next(); token {
+		case "package":
+			p.file.pkg = p.next()
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "message":
+			message, err := p.parseMessage(p.file.pkg)
+			if err != nil {
+				return err
+			}
+			p.file.messages = append(p.file.messages, message)
+		case "enum":
+			if err := p.parseEnum(p.file.pkg); err != nil {
+				return err
+			}
+		case ";":
+		default:
+			// syntax, edition, import, option, service and extend carry nothing to decode
+			p.pos--
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseMessage parses a message declaration after the "message" keyword
+func (p *protoParser) parseMessage(scope string) (*protoMessage, error) {
+	message := &protoMessage{name: qualify(p.next(), scope), fields: make(map[protowire.Number]*protoField)}
+	p.file.types[message.name] = message
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseMessageBody(message, false); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// parseMessageBody parses fields and nested declarations up to the closing brace
+func (p *protoParser) parseMessageBody(message *protoMessage, oneof bool) error {
+	for {
+		switch token := p.next(); token {
+		case "}":
+			return nil
+		case "":
+			return fmt.Errorf("message %s is not closed", message.name)
+		case ";":
+		case "message":
+			if oneof {
+				return fmt.Errorf("unexpected message in oneof")
+			}
+			nested, err := p.parseMessage(message.name)
+			if err != nil {
+				return err
+			}
+			message.nested = append(message.nested, nested)
+		case "enum":
+			if err := p.parseEnum(message.name); err != nil {
+				return err
+			}
+		case "oneof":
+			p.next() // Name
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseMessageBody(message, true); err != nil {
+				return err
+			}
+		case "option", "reserved", "extensions", "extend":
+			p.pos--
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			p.pos--
+			if err := p.parseField(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseField parses a field, including map fields, up to its semicolon
+func (p *protoParser) parseField(message *protoMessage) error {
+	field := &protoField{scope: message.name}
+	switch p.peek() {
+	case "repeated":
+		field.repeated = true
+		p.next()
+	case "optional", "required":
+		p.next()
+	}
+
+	field.typeName = p.next()
+	if field.typeName == "group" {
+		return fmt.Errorf("groups are not supported (message %s)", message.name)
+	}
+	if field.typeName == "map" && p.peek() == "<" {
+		p.next()
+		key := p.next()
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		value := p.next()
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+
+		// A map is a repeated message of key (1) and value (2) fields
+		entry := &protoMessage{name: message.name + ".<map>", mapEntry: true, fields: map[protowire.Number]*protoField{
+			1: {name: "key", number: 1, typeName: key, scope: message.name},
+			2: {name: "value", number: 2, typeName: value, scope: message.name},
+		}}
+		field.typeName = ""
+		field.message = entry
+		field.repeated = true
+	}
+
+	field.name = p.next()
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	number, err := strconv.ParseInt(p.next(), 0, 32)
+	if err != nil || number <= 0 {
+		return fmt.Errorf("invalid number for field %s.%s", message.name, field.name)
+	}
+	field.number = protowire.Number(number)
+	if err := p.skipStatement(); err != nil { // Field options
+		return err
+	}
+	message.fields[field.number] = field
+	return nil
+}
+
+// parseEnum parses an enum declaration after the "enum" keyword
+func (p *protoParser) parseEnum(scope string) error {
+	enum := &protoEnum{name: qualify(p.next(), scope), values: make(map[int64]string)}
+	p.file.enums[enum.name] = enum
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch token := p.next(); token {
+		case "}":
+			return nil
+		case "":
+			return fmt.Errorf("enum %s is not closed", enum.name)
+		case ";":
+		case "option", "reserved":
+			p.pos--
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			number, err := strconv.ParseInt(p.next(), 0, 32)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s.%s", enum.name, token)
+			}
+			if _, ok := enum.values[number]; !ok { // The first name wins for aliases
+				enum.values[number] = token
+			}
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// resolve links message and enum fields to their types
+func (f *protoFile) resolve() {
+	var resolveMessage func(message *protoMessage)
+	resolveMessage = func(message *protoMessage) {
+		for _, field := range message.fields {
+			if field.message != nil && field.message.mapEntry {
+				resolveMessage(field.message)
+				continue
+			}
+			if _, scalar := protoScalars[field.typeName]; scalar || field.typeName == "" {
+				continue
+			}
+			field.message, field.enum = f.lookup(field.typeName, field.scope)
+		}
+		for _, nested := range message.nested {
+			resolveMessage(nested)
+		}
+	}
+	for _, message := range f.messages {
+		resolveMessage(message)
+	}
+}
+
+// lookup finds a type by name following protobuf scoping: the innermost scope first
+func (f *protoFile) lookup(name, scope string) (*protoMessage, *protoEnum) {
+	if strings.HasPrefix(name, ".") {
+		return f.types[name[1:]], f.enums[name[1:]]
+	}
+	for {
+		candidate := qualify(name, scope)
+		if message, ok := f.types[candidate]; ok {
+			return message, nil
+		}
+		if enum, ok := f.enums[candidate]; ok {
+			return nil, enum
+		}
+		if scope == "" {
+			return nil, nil
+		}
+		if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+// messageAt returns the message selected by Confluent message indexes: the first
+// index selects a top-level message, each further one a message nested in it
+func (f *protoFile) messageAt(indexes []int) (*protoMessage, error) {
+	if len(indexes) == 0 {
+		indexes = []int{0}
+	}
+	candidates := f.messages
+	var message *protoMessage
+	for _, index := range indexes {
+		if index < 0 || index >= len(candidates) {
+			return nil, fmt.Errorf("message index %v not in schema", indexes)
+		}
+		message = candidates[index]
+		candidates = message.nested
+	}
+	return message, nil
+}
+
+// decode decodes a message in the protobuf wire format. Fields missing from the
+// schema are skipped, enums become their names, bytes base64 strings and
+// google.protobuf.Timestamp a time.Time.
+func (m *protoMessage) decode(data []byte) (map[string]any, error) {
+	record := make(map[string]any, len(m.fields))
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		field := m.fields[number]
+		if field == nil {
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		values, n, err := field.decodeValues(wireType, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+		data = data[n:]
+
+		switch {
+		case field.message != nil && field.message.mapEntry:
+			entries, _ := record[field.name].(map[string]any)
+			if entries == nil {
+				entries = make(map[string]any)
+				record[field.name] = entries
+			}
+			for _, value := range values {
+				entry, _ := value.(map[string]any)
+				entries[fmt.Sprint(entry["key"])] = entry["value"]
+			}
+		case field.repeated:
+			list, _ := record[field.name].([]any)
+			record[field.name] = append(list, values...)
+		default:
+			record[field.name] = values[len(values)-1]
+		}
+	}
+	return record, nil
+}
+
+// decodeValues decodes one field occurrence; packed repeated scalars yield several values
+func (f *protoField) decodeValues(wireType protowire.Type, data []byte) ([]any, int, error) {
+	scalarType, scalar := protoScalars[f.typeName]
+	if f.enum != nil {
+		scalarType, scalar = protowire.VarintType, true
+	}
+
+	if wireType == protowire.BytesType && scalar && scalarType != protowire.BytesType {
+		// Packed repeated scalars
+		packed, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		var values []any
+		for len(packed) > 0 {
+			value, m, err := f.decodeValue(scalarType, packed)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			packed = packed[m:]
+		}
+		return values, n, nil
+	}
+
+	value, n, err := f.decodeValue(wireType, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return []any{value}, n, nil
+}
+
+// decodeValue decodes a single value of the field's type
+func (f *protoField) decodeValue(wireType protowire.Type, data []byte) (any, int, error) {
+	switch wireType {
+	case protowire.VarintType:
+		v, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		if f.enum != nil {
+			if name, ok := f.enum.values[int64(int32(v))]; ok { // #nosec G115 - enums are int32 on the wire
+				return name, n, nil
+			}
+			return int64(int32(v)), n, nil // #nosec G115 - unknown enum values keep their number
+		}
+		switch f.typeName {
+		case "int32":
+			return int64(int32(v)), n, nil // #nosec G115 - int32 values are sign-extended varints
+		case "int64":
+			return int64(v), n, nil // #nosec G115 - two's complement reinterpretation
+		case "sint32", "sint64":
+			return protowire.DecodeZigZag(v), n, nil
+		case "bool":
+			return v != 0, n, nil
+		default:
+			return v, n, nil
+		}
+
+	case protowire.Fixed32Type:
+		v, n := protowire.ConsumeFixed32(data)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		switch f.typeName {
+		case "float":
+			return float64(math.Float32frombits(v)), n, nil
+		case "sfixed32":
+			return int64(int32(v)), n, nil // #nosec G115 - two's complement reinterpretation
+		default:
+			return uint64(v), n, nil
+		}
+
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(data)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		switch f.typeName {
+		case "double":
+			return math.Float64frombits(v), n, nil
+		case "sfixed64":
+			return int64(v), n, nil // #nosec G115 - two's complement reinterpretation
+		default:
+			return v, n, nil
+		}
+
+	case protowire.BytesType:
+		b, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		switch {
+		case f.typeName == "string":
+			return string(b), n, nil
+		case f.message != nil:
+			record, err := f.message.decode(b)
+			if err != nil {
+				return nil, 0, err
+			}
+			if f.message.name == "google.protobuf.Timestamp" {
+				return timestampValue(record), n, nil
+			}
+			return record, n, nil
+		case strings.TrimPrefix(f.typeName, ".") == "google.protobuf.Timestamp":
+			// Imported from the well-known types, which the schema does not include
+			timestamp := &protoMessage{fields: map[protowire.Number]*protoField{
+				1: {name: "seconds", number: 1, typeName: "int64"},
+				2: {name: "nanos", number: 2, typeName: "int32"},
+			}}
+			record, err := timestamp.decode(b)
+			if err != nil {
+				return nil, 0, err
+			}
+			return timestampValue(record), n, nil
+		default:
+			// bytes, or a message type the schema imports from elsewhere
+			return base64.StdEncoding.EncodeToString(b), n, nil
+		}
+
+	default:
+		return nil, 0, fmt.Errorf("unsupported wire type %d", wireType)
+	}
+}
+
+// timestampValue converts a decoded google.protobuf.Timestamp
+func timestampValue(record map[string]any) time.Time {
+	seconds, _ := record["seconds"].(int64)
+	nanos, _ := record["nanos"].(int64)
+	return time.Unix(seconds, nanos).UTC()
+}
@@ -0,0 +1,176 @@
+package kafkainput
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const protoLogSchema = `
+syntax = "proto3";
+package example.v1;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example/v1";
+
+enum Level {
+  LEVEL_UNSPECIFIED = 0;
+  LEVEL_INFO = 1;
+  LEVEL_ERROR = 2;
+}
+
+message Event {
+  string id = 1;
+}
+
+message Log {
+  Level level = 1;
+  string message = 2 [json_name = "msg"];
+  google.protobuf.Timestamp timestamp = 3;
+  repeated int32 codes = 4;
+  map<string, string> labels = 5;
+  Request request = 6;
+  oneof actor {
+    string user = 7;
+    string service = 8;
+  }
+  reserved 9, 10;
+
+  message Request {
+    string method = 1;
+    bytes body = 2;
+  }
+}
+
+service Logs {
+  rpc Send (Log) returns (Event);
+}
+`
+
+func protoLogMessage() []byte {
+	var timestamp []byte
+	timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, 1700000000)
+	timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, 5000)
+
+	var codes []byte
+	codes = protowire.AppendVarint(codes, 3)
+	codes = protowire.AppendVarint(codes, 7)
+
+	var label []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, "env")
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, "prod")
+
+	var request []byte
+	request = protowire.AppendTag(request, 1, protowire.BytesType)
+	request = protowire.AppendString(request, "POST")
+	request = protowire.AppendTag(request, 2, protowire.BytesType)
+	request = protowire.AppendBytes(request, []byte{0xff, 0x00})
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, 2)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, "payment failed")
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, timestamp)
+	b = protowire.AppendTag(b, 4, protowire.BytesType) // Packed
+	b = protowire.AppendBytes(b, codes)
+	b = protowire.AppendTag(b, 4, protowire.VarintType) // Unpacked occurrence of the same field
+	b = protowire.AppendVarint(b, 9)
+	b = protowire.AppendTag(b, 5, protowire.BytesType)
+	b = protowire.AppendBytes(b, label)
+	b = protowire.AppendTag(b, 6, protowire.BytesType)
+	b = protowire.AppendBytes(b, request)
+	b = protowire.AppendTag(b, 8, protowire.BytesType)
+	b = protowire.AppendString(b, "billing")
+	b = protowire.AppendTag(b, 99, protowire.Fixed32Type) // Unknown field
+	b = protowire.AppendFixed32(b, 1)
+	return b
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	file, err := parseProtoSchema(protoLogSchema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	// Message indexes [1]: the second top-level message
+	value := append(protowire.AppendVarint(nil, protowire.EncodeZigZag(1)), byte(protowire.EncodeZigZag(1)))
+	decoded, err := decodeProtobuf(file, append(value, protoLogMessage()...))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	record := decoded.(map[string]any)
+
+	if record["level"] != "LEVEL_ERROR" || record["message"] != "payment failed" || record["service"] != "billing" {
+		t.Errorf("Expected decoded fields, got %v", record)
+	}
+	if record["timestamp"] != time.Unix(1700000000, 5000).UTC() {
+		t.Errorf("Expected a timestamp, got %v", record["timestamp"])
+	}
+	if codes := record["codes"].([]any); len(codes) != 3 || codes[0] != int64(3) || codes[2] != int64(9) {
+		t.Errorf("Expected codes [3 7 9], got %v", codes)
+	}
+	if labels := record["labels"].(map[string]any); labels["env"] != "prod" {
+		t.Errorf("Expected labels, got %v", labels)
+	}
+	request := record["request"].(map[string]any)
+	if request["method"] != "POST" || request["body"] != "/wA=" {
+		t.Errorf("Expected the nested request, got %v", request)
+	}
+}
+
+func TestProtoMessageIndexes(t *testing.T) {
+	file, err := parseProtoSchema(protoLogSchema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		indexes  []int
+		expected string
+	}{
+		{nil, "example.v1.Event"},
+		{[]int{0}, "example.v1.Event"},
+		{[]int{1}, "example.v1.Log"},
+		{[]int{1, 0}, "example.v1.Log.Request"},
+		{[]int{2}, ""},
+	}
+
+	for _, tt := range tests {
+		message, err := file.messageAt(tt.indexes)
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("%v: expected an error", tt.indexes)
+			}
+			continue
+		}
+		if err != nil || message.name != tt.expected {
+			t.Errorf("%v: expected %s, got %v (%v)", tt.indexes, tt.expected, message, err)
+		}
+	}
+}
+
+func TestParseProtoSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"unterminated message", `message Log { string id = 1;`},
+		{"missing field number", `message Log { string id; }`},
+		{"group", `message Log { repeated group Item = 1 { string id = 2; } }`},
+		{"unterminated string", `syntax = "proto3`},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseProtoSchema(tt.schema); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package kafkainput
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/httpclient"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+// Schema types reported by a Confluent Schema Registry
+const (
+	schemaTypeAvro     = "AVRO" // Also used when the registry omits schemaType
+	schemaTypeProtobuf = "PROTOBUF"
+	schemaTypeJSON     = "JSON"
+)
+
+// schemaRetryInterval is how long a schema that could not be fetched is not asked for again
+const schemaRetryInterval = 30 * time.Second
+
+// maxSchemaBytes bounds a registry response
+const maxSchemaBytes = 4 * 1024 * 1024
+
+// registrySchema is a parsed schema, cached by ID
+type registrySchema struct {
+	schemaType string
+	avro       *avroSchema
+	proto      *protoFile
+}
+
+// registryFailure remembers a failed fetch so records with an unavailable schema
+// do not each wait for the registry
+type registryFailure struct {
+	err   error
+	until time.Time
+}
+
+// schemaRegistry fetches schemas by ID from a Confluent-compatible Schema
+// Registry. Schemas are immutable once registered, so they are cached forever.
+type schemaRegistry struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+
+	mu       sync.Mutex
+	schemas  map[int]*registrySchema
+	failures map[int]registryFailure
+}
+
+// newSchemaRegistry creates a registry client
+func newSchemaRegistry(url, username, password string, timeout time.Duration, tls tlsconfig.Config) (*schemaRegistry, error) {
+	client, err := httpclient.New(httpclient.Config{}, timeout, tls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema registry client: %w", err)
+	}
+
+	return &schemaRegistry{
+		url:      strings.TrimRight(url, "/"),
+		username: username,
+		password: password,
+		client:   client,
+		schemas:  make(map[int]*registrySchema),
+		failures: make(map[int]registryFailure),
+	}, nil
+}
+
+// schema returns the schema with the given ID, fetching and parsing it on first use
+func (r *schemaRegistry) schema(ctx context.Context, id int) (*registrySchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if schema, ok := r.schemas[id]; ok {
+		return schema, nil
+	}
+	if failure, ok := r.failures[id]; ok && time.Now().Before(failure.until) {
+		return nil, failure.err
+	}
+
+	schema, err := r.fetch(ctx, id)
+	if err != nil && ctx.Err() != nil {
+		// Stopping; not a registry failure
+		return nil, err
+	}
+	if err != nil {
+		err = fmt.Errorf("schema %d unavailable: %w", id, err)
+		r.failures[id] = registryFailure{err: err, until: time.Now().Add(schemaRetryInterval)}
+		logger.Printf("Failed to load schema from %s, retrying in %s: %v", r.url, schemaRetryInterval, err)
+		return nil, err
+	}
+	delete(r.failures, id)
+	r.schemas[id] = schema
+	logger.Printf("Loaded %s schema %d from %s", schema.schemaType, id, r.url)
+	return schema, nil
+}
+
+// fetch requests and parses a schema
+func (r *schemaRegistry) fetch(ctx context.Context, id int) (*registrySchema, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", r.url, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSchemaBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned %s", resp.Status)
+	}
+
+	var response struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid registry response: %w", err)
+	}
+
+	schema := &registrySchema{schemaType: strings.ToUpper(response.SchemaType)}
+	switch schema.schemaType {
+	case "", schemaTypeAvro:
+		schema.schemaType = schemaTypeAvro
+		schema.avro, err = parseAvroSchema(response.Schema)
+	case schemaTypeProtobuf:
+		schema.proto, err = parseProtoSchema(response.Schema)
+	case schemaTypeJSON:
+		// JSON records are decoded without their schema
+	default:
+		err = fmt.Errorf("unsupported schema type %q", response.SchemaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
@@ -0,0 +1,130 @@
+package multiplexinput
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Connection protocols told apart by detect
+const (
+	protocolHTTP = "http"
+	protocolRaw  = "raw"
+)
+
+// httpMethods are the request methods that mark a connection as HTTP. CONNECT and
+// the HTTP/2 preface (PRI) are not served, so such connections are read as raw lines.
+var httpMethods = [][]byte{
+	[]byte("GET"), []byte("HEAD"), []byte("POST"), []byte("PUT"),
+	[]byte("DELETE"), []byte("OPTIONS"), []byte("PATCH"), []byte("TRACE"),
+}
+
+// maxRequestPrefix is the longest prefix detect needs: "OPTIONS /"
+const maxRequestPrefix = len("OPTIONS") + 2
+
+// classify decides the protocol from the first bytes of a connection. An HTTP
+// request starts with a method, a space and a request target beginning with '/'
+// or '*'. It returns "" while prefix could still become such a request line.
+func classify(prefix []byte) string {
+	for _, method := range httpMethods {
+		n := min(len(prefix), len(method))
+		if !bytes.Equal(prefix[:n], method[:n]) {
+			continue
+		}
+		switch {
+		case len(prefix) <= len(method):
+			return ""
+		case prefix[len(method)] != ' ':
+			continue
+		case len(prefix) == len(method)+1:
+			return ""
+		case prefix[len(method)+1] == '/' || prefix[len(method)+1] == '*':
+			return protocolHTTP
+		}
+	}
+	return protocolRaw
+}
+
+// detect peeks at the first bytes of a connection, without consuming them, until
+// they identify an HTTP request line or rule it out. A read error, including the
+// detection deadline passing, leaves the decision to the bytes seen so far, so a
+// client that sends something ambiguous and waits is read as raw lines.
+func detect(reader *bufio.Reader) (string, error) {
+	for n := 1; n <= maxRequestPrefix; n++ {
+		prefix, err := reader.Peek(n)
+		if err != nil {
+			if len(prefix) == 0 {
+				return "", err
+			}
+			return protocolRaw, nil
+		}
+		if protocol := classify(prefix); protocol != "" {
+			return protocol, nil
+		}
+		// Decide on what is already buffered before waiting for more
+		if buffered := reader.Buffered(); buffered > n {
+			n = min(buffered, maxRequestPrefix) - 1
+		}
+	}
+	return protocolRaw, nil
+}
+
+// peekedConn is a connection whose first bytes were peeked; reads go through the
+// buffered reader so the handler sees the whole stream
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read reads the peeked bytes first, then from the connection
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// connListener hands accepted connections detected as HTTP to an http.Server
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// newConnListener creates a listener reporting addr as its address
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// errListenerClosed is returned by Accept once the listener is closed
+var errListenerClosed = errors.New("listener closed")
+
+// Accept waits for the next HTTP connection
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+// deliver hands a connection to the HTTP server, closing it if the listener is closed
+func (l *connListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+// Close stops Accept; the shared port itself is closed by the input
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr returns the address of the shared port
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
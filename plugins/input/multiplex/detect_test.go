@@ -0,0 +1,91 @@
+package multiplexinput
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"POST /logs HTTP/1.1", protocolHTTP},
+		{"GET /", protocolHTTP},
+		{"OPTIONS *", protocolHTTP},
+		{"PO", ""},
+		{"POST", ""},
+		{"POST ", ""},
+		{"POSTGRES ready", protocolRaw},
+		{"POST failed", protocolRaw},
+		{"GET", ""},
+		{"GETTING /", protocolRaw},
+		{"ERROR disk full", protocolRaw},
+		{"PRI * HTTP/2.0", protocolRaw},
+		{"CONNECT host:443", protocolRaw},
+		{"<34>Oct 11 22:14:15 host app: message", protocolRaw},
+		{"post /logs", protocolRaw},
+	}
+
+	for _, tt := range tests {
+		if got := classify([]byte(tt.prefix)); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.prefix, tt.expected, got)
+		}
+	}
+}
+
+func TestDetectDoesNotConsume(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"POST /logs HTTP/1.1\r\nHost: localhost\r\n\r\n", protocolHTTP},
+		{"INFO started\nERROR failed\n", protocolRaw},
+		{"PUT", protocolRaw}, // The client closed on an ambiguous prefix
+		{"x", protocolRaw},
+	}
+
+	for _, tt := range tests {
+		reader := bufio.NewReader(strings.NewReader(tt.input))
+		protocol, err := detect(reader)
+		if err != nil || protocol != tt.expected {
+			t.Errorf("%q: expected %q, got %q (%v)", tt.input, tt.expected, protocol, err)
+			continue
+		}
+		// The handler still reads the whole stream
+		if rest, _ := io.ReadAll(reader); string(rest) != tt.input {
+			t.Errorf("%q: expected the peeked bytes to be kept, got %q", tt.input, rest)
+		}
+	}
+
+	if _, err := detect(bufio.NewReader(strings.NewReader(""))); err == nil {
+		t.Error("Expected an error for a connection closed without data")
+	}
+}
+
+func TestDetectAmbiguousFallsBackToRaw(t *testing.T) {
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	defer func() { _ = server.Close() }()
+
+	// The client sends a prefix of a request line and then waits
+	go func() { _, _ = client.Write([]byte("GET")) }()
+
+	_ = server.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	reader := bufio.NewReader(server)
+	protocol, err := detect(reader)
+	if err != nil || protocol != protocolRaw {
+		t.Fatalf("Expected raw after the detection deadline, got %q (%v)", protocol, err)
+	}
+
+	_ = server.SetReadDeadline(time.Time{})
+	go func() { _, _ = client.Write([]byte(" ready\n")) }()
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "GET ready\n" {
+		t.Errorf("Expected the whole line after the fallback, got %q (%v)", line, err)
+	}
+}
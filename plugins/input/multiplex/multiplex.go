@@ -0,0 +1,358 @@
+package multiplexinput
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+	httpinput "github.com/mbiondo/logAnalyzer/plugins/input/http"
+)
+
+const (
+	DefaultMaxLineBytes  = 64 * 1024 // default max raw line size
+	DefaultDetectTimeout = 5         // default wait for the first bytes of a connection in seconds
+)
+
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.multiplex")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterInputPlugin("multiplex", NewMultiplexInputFromConfig)
+}
+
+// Config represents multiplex input configuration. The input accepts HTTP
+// requests and raw newline-delimited logs on the same port; every HTTP input
+// option (path, endpoints, auth, rate_limit, json_mode, ...) applies to the HTTP
+// connections and is validated like an http input's.
+type Config struct {
+	Port     string           `yaml:"port,omitempty"`
+	TLS      tlsconfig.Config `yaml:"tls,omitempty"`       // TLS for both protocols, terminated before detection
+	CertFile string           `yaml:"cert_file,omitempty"` // Server certificate file (for TLS)
+	KeyFile  string           `yaml:"key_file,omitempty"`  // Server key file (for TLS)
+
+	// Client certificate authentication applies to every connection
+	Auth httpinput.AuthConfig `yaml:"auth,omitempty"`
+
+	// Raw connections
+	MaxLineBytes  int    `yaml:"max_line_bytes,omitempty"` // Longer lines close the connection (default: 64KB)
+	DetectTimeout int    `yaml:"detect_timeout,omitempty"` // Seconds to wait for the bytes that tell the protocols apart (default: 5)
+	OnParseError  string `yaml:"on_parse_error,omitempty"` // Raw lines without a detectable level: pass_raw, tag or drop (shared with HTTP)
+}
+
+// NewMultiplexInputFromConfig creates a multiplex input from configuration map
+func NewMultiplexInputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	// The HTTP side validates the shared and HTTP options
+	plugin, err := httpinput.NewHTTPInputFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+	if cfg.TLS.Enabled && (cfg.CertFile == "" || cfg.KeyFile == "") {
+		return nil, fmt.Errorf("TLS enabled but certificate files not provided: cert_file and key_file are required")
+	}
+	if cfg.MaxLineBytes < 0 {
+		return nil, fmt.Errorf("max_line_bytes must be non-negative")
+	}
+	if cfg.DetectTimeout < 0 {
+		return nil, fmt.Errorf("detect_timeout must be non-negative")
+	}
+
+	return NewMultiplexInput(cfg, plugin.(*httpinput.HTTPInput)), nil
+}
+
+// MultiplexInput accepts HTTP requests and raw line logs on a single port,
+// telling them apart by the first bytes of each connection
+type MultiplexInput struct {
+	config Config
+	http   *httpinput.HTTPInput
+	name   string
+	logCh  chan<- *core.Log
+
+	listener     net.Listener
+	httpListener *connListener
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+	stopped      bool // Flag to prevent multiple stops
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{} // Connections being detected or read as raw lines, closed on Stop
+
+	httpConns atomic.Int64 // Connections detected as HTTP
+	rawConns  atomic.Int64 // Connections detected as raw
+	rawOpen   atomic.Int64 // Raw connections still open
+	rawLines  atomic.Int64 // Raw lines received
+}
+
+// NewMultiplexInput creates a multiplex input serving HTTP connections with httpInput
+func NewMultiplexInput(cfg Config, httpInput *httpinput.HTTPInput) *MultiplexInput {
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+	if cfg.MaxLineBytes == 0 {
+		cfg.MaxLineBytes = DefaultMaxLineBytes
+	}
+	if cfg.DetectTimeout == 0 {
+		cfg.DetectTimeout = DefaultDetectTimeout
+	}
+
+	return &MultiplexInput{
+		config: cfg,
+		http:   httpInput,
+		name:   "multiplex",
+		stopCh: make(chan struct{}),
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// SetName sets the name for this input instance
+func (m *MultiplexInput) SetName(name string) {
+	m.name = name
+	m.http.SetName(name)
+}
+
+// SetLogChannel sets the channel to send logs to
+func (m *MultiplexInput) SetLogChannel(ch chan<- *core.Log) {
+	m.logCh = ch
+	m.http.SetLogChannel(ch)
+}
+
+// tlsConfig builds the server TLS configuration. Only HTTP/1.1 is negotiated,
+// since HTTP connections are detected by their request line.
+func (m *MultiplexInput) tlsConfig() (*tls.Config, error) {
+	tlsConfig, err := m.config.TLS.NewTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(m.config.CertFile, m.config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	tlsConfig.NextProtos = []string{"http/1.1"}
+	if m.config.Auth.ClientCertRequired {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// Start listens on the port and starts serving both protocols
+func (m *MultiplexInput) Start() error {
+	listener, err := net.Listen("tcp", ":"+m.config.Port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", m.config.Port, err)
+	}
+	if m.config.TLS.Enabled {
+		tlsConfig, err := m.tlsConfig()
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	m.listener = listener
+
+	m.httpListener = newConnListener(listener.Addr())
+	if err := m.http.StartListener(m.httpListener); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	m.wg.Add(1)
+	go m.acceptLoop()
+
+	logger.Named(m.name).Printf("Multiplex input started on port %s (HTTP and raw lines, TLS: %v)", m.config.Port, m.config.TLS.Enabled)
+	return nil
+}
+
+// Addr returns the address the input listens on, or nil before Start
+func (m *MultiplexInput) Addr() net.Addr {
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Addr()
+}
+
+// acceptLoop accepts connections until the listener is closed
+func (m *MultiplexInput) acceptLoop() {
+	defer m.wg.Done()
+
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			select {
+			case <-m.stopCh:
+			default:
+				logger.Named(m.name).Printf("Error accepting connection: %v", err)
+			}
+			return
+		}
+
+		m.wg.Add(1)
+		go m.handleConn(conn)
+	}
+}
+
+// handleConn detects the protocol of a connection and hands it to its handler
+func (m *MultiplexInput) handleConn(conn net.Conn) {
+	defer m.wg.Done()
+	if !m.track(conn) {
+		_ = conn.Close()
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Duration(m.config.DetectTimeout) * time.Second))
+	protocol, err := detect(reader)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		// Closed before sending anything, or a failed TLS handshake
+		m.untrack(conn, true)
+		return
+	}
+
+	if protocol == protocolHTTP {
+		// The HTTP server owns the connection from here on
+		m.untrack(conn, false)
+		m.httpConns.Add(1)
+		m.httpListener.deliver(&peekedConn{Conn: conn, reader: reader})
+		return
+	}
+
+	m.rawConns.Add(1)
+	m.rawOpen.Add(1)
+	defer m.rawOpen.Add(-1)
+	defer m.untrack(conn, true)
+	m.readLines(conn, reader)
+}
+
+// readLines forwards each line of a raw connection as a log until the client
+// closes it, a line exceeds max_line_bytes or the input stops
+func (m *MultiplexInput) readLines(conn net.Conn, reader *bufio.Reader) {
+	clientIP := ""
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		clientIP = addr.IP.String()
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, min(m.config.MaxLineBytes, 64*1024)), m.config.MaxLineBytes)
+	for scanner.Scan() {
+		logEntry := m.parseLine(scanner.Text(), clientIP)
+		if logEntry == nil {
+			continue
+		}
+		m.rawLines.Add(1)
+		select {
+		case m.logCh <- logEntry:
+		case <-m.stopCh:
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Named(m.name).Printf("Closing raw connection from %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// track registers a connection so Stop can close it, or reports false once the input is stopping
+func (m *MultiplexInput) track(conn net.Conn) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return false
+	}
+	m.conns[conn] = struct{}{}
+	return true
+}
+
+// untrack forgets a connection, closing it unless it was handed over
+func (m *MultiplexInput) untrack(conn net.Conn, closeConn bool) {
+	m.mu.Lock()
+	delete(m.conns, conn)
+	m.mu.Unlock()
+	if closeConn {
+		_ = conn.Close()
+	}
+}
+
+// ParseLogLine parses a raw line into a Log struct (public for testing)
+func (m *MultiplexInput) ParseLogLine(line string) *core.Log {
+	return m.parseLine(line, "")
+}
+
+// parseLine parses a raw line, detecting its level like the HTTP input's text lines
+func (m *MultiplexInput) parseLine(line, clientIP string) *core.Log {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	level, parsed := core.Levels().DetectKnown(line)
+	metadata := map[string]string{
+		"source":       "tcp",
+		"content_type": "text",
+	}
+	if clientIP != "" {
+		metadata[httpinput.ClientIPKey] = clientIP
+	}
+
+	logEntry := core.NewLogWithMetadata(level, line, metadata)
+	logEntry.Source = m.name // Set the source to the input name
+	if !parsed {
+		return core.HandleParseError(m.config.OnParseError, logEntry, "no level detected")
+	}
+	return logEntry
+}
+
+// InputStats implements core.InputStatsReporter
+func (m *MultiplexInput) InputStats() map[string]any {
+	stats := m.http.InputStats()
+	stats["raw_connections"] = m.rawOpen.Load()
+	stats["http_connections_total"] = m.httpConns.Load()
+	stats["raw_connections_total"] = m.rawConns.Load()
+	stats["raw_lines"] = m.rawLines.Load()
+	return stats
+}
+
+// Stop closes the port, the HTTP server and every raw connection
+func (m *MultiplexInput) Stop() error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return nil // Already stopped
+	}
+	m.stopped = true
+	for conn := range m.conns {
+		_ = conn.Close()
+	}
+	m.mu.Unlock()
+
+	close(m.stopCh)
+	if m.listener != nil {
+		_ = m.listener.Close()
+	}
+	if err := m.http.Stop(); err != nil {
+		logger.Named(m.name).Printf("Error stopping HTTP server: %v", err)
+	}
+
+	m.wg.Wait()
+	logger.Named(m.name).Printf("Multiplex input stopped")
+	return nil
+}
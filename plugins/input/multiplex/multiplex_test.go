@@ -0,0 +1,148 @@
+package multiplexinput
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func startInput(t *testing.T, config map[string]any) (*MultiplexInput, chan *core.Log) {
+	config["port"] = "0"
+	plugin, err := NewMultiplexInputFromConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create multiplex input: %v", err)
+	}
+	input := plugin.(*MultiplexInput)
+	input.SetName("shared")
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	t.Cleanup(func() { _ = input.Stop() })
+	return input, logCh
+}
+
+func receive(t *testing.T, logCh chan *core.Log) *core.Log {
+	select {
+	case logEntry := <-logCh:
+		return logEntry
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for a log")
+		return nil
+	}
+}
+
+func TestMultiplexInputSharedPort(t *testing.T) {
+	input, logCh := startInput(t, map[string]any{"detect_timeout": 1})
+	addr := fmt.Sprintf("127.0.0.1:%d", input.Addr().(*net.TCPAddr).Port)
+
+	// Raw lines
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := fmt.Fprint(conn, "ERROR disk full\nWARN retrying\n"); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	for _, expected := range []string{"error", "warn"} {
+		logEntry := receive(t, logCh)
+		if logEntry.Level != expected || logEntry.Source != "shared" || logEntry.Metadata["source"] != "tcp" || logEntry.Metadata["client_ip"] != "127.0.0.1" {
+			t.Errorf("Expected a raw %s log, got %s %v", expected, logEntry.Level, logEntry.Metadata)
+		}
+	}
+
+	// HTTP on the same port
+	resp, err := http.Post("http://"+addr+"/logs", "text/plain", strings.NewReader("ERROR from http"))
+	if err != nil {
+		t.Fatalf("Failed to post: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if logEntry := receive(t, logCh); logEntry.Message != "ERROR from http" || logEntry.Metadata["source"] != "http" {
+		t.Errorf("Expected the posted log, got %q %v", logEntry.Message, logEntry.Metadata)
+	}
+
+	// A raw line that starts like a request line is read as raw once detection gives up
+	ambiguous, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = ambiguous.Close() }()
+	_, _ = fmt.Fprint(ambiguous, "POST")
+	time.Sleep(1200 * time.Millisecond)
+	_, _ = fmt.Fprint(ambiguous, "ed ERROR job failed\n")
+	if logEntry := receive(t, logCh); logEntry.Message != "POSTed ERROR job failed" {
+		t.Errorf("Expected the ambiguous line as a raw log, got %q", logEntry.Message)
+	}
+
+	stats := input.InputStats()
+	if stats["raw_connections_total"] != int64(2) || stats["http_connections_total"] != int64(1) || stats["raw_lines"] != int64(3) {
+		t.Errorf("Expected 2 raw and 1 HTTP connections, got %v", stats)
+	}
+}
+
+func TestMultiplexInputStopClosesRawConnections(t *testing.T) {
+	plugin, err := NewMultiplexInputFromConfig(map[string]any{"port": "0"})
+	if err != nil {
+		t.Fatalf("Failed to create multiplex input: %v", err)
+	}
+	input := plugin.(*MultiplexInput)
+	input.SetLogChannel(make(chan *core.Log, 10))
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", input.Addr().(*net.TCPAddr).Port))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = fmt.Fprint(conn, "INFO connected\n")
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		_ = input.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected Stop to close open raw connections")
+	}
+}
+
+func TestMultiplexInputConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]any
+		expected string
+	}{
+		{"defaults", map[string]any{}, ""},
+		{"http options", map[string]any{"path": "/ingest", "json_mode": "structured"}, ""},
+		{"invalid http option", map[string]any{"path": "ingest"}, "must start with '/'"},
+		{"invalid on_parse_error", map[string]any{"on_parse_error": "skip"}, "on_parse_error"},
+		{"negative max_line_bytes", map[string]any{"max_line_bytes": -1}, "max_line_bytes"},
+		{"negative detect_timeout", map[string]any{"detect_timeout": -1}, "detect_timeout"},
+		{"tls without certificates", map[string]any{"tls": map[string]any{"enabled": true}}, "cert_file and key_file"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewMultiplexInputFromConfig(tt.config)
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
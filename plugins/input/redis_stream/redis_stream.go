@@ -0,0 +1,495 @@
+package redisstreaminput
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/redisclient"
+)
+
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.redis_stream")
+
+func init() {
+	core.RegisterInputPlugin("redis_stream", NewRedisStreamInputFromConfig)
+}
+
+const (
+	defaultCount         = 100
+	defaultBlockTimeout  = 5  // seconds
+	defaultClaimMinIdle  = 60 // seconds
+	defaultClaimInterval = 30 // seconds
+	metadataFieldPrefix  = "metadata."
+)
+
+// Config represents Redis Streams input configuration values supplied via YAML.
+type Config struct {
+	redisclient.Config `yaml:",inline"`
+
+	Stream        string `yaml:"stream"`
+	Group         string `yaml:"group,omitempty"`          // Consumer group; without one, entries are read with XREAD and never acknowledged
+	Consumer      string `yaml:"consumer,omitempty"`       // Consumer name within the group (default: hostname)
+	StartID       string `yaml:"start_id,omitempty"`       // Where a new group or plain reader starts: "$" (new entries, default) or "0" (whole stream)
+	Count         int    `yaml:"count,omitempty"`          // Entries per read (default: 100)
+	BlockTimeout  int    `yaml:"block_timeout,omitempty"`  // Seconds a read waits for new entries (default: 5)
+	ClaimMinIdle  int    `yaml:"claim_min_idle,omitempty"` // Seconds another consumer's pending entry must be idle before it is reclaimed (default: 60)
+	ClaimInterval int    `yaml:"claim_interval,omitempty"` // Seconds between reclaim passes (default: 30)
+	MessageField  string `yaml:"message_field,omitempty"`  // Field used as the log message (default: "message")
+}
+
+// NewRedisStreamInputFromConfig builds a Redis Streams input plugin from generic configuration.
+func NewRedisStreamInputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewRedisStreamInput(cfg)
+}
+
+// NewRedisStreamInput creates a Redis Streams input, applying defaults and validating the configuration
+func NewRedisStreamInput(cfg Config) (*RedisStreamInput, error) {
+	if cfg.Stream == "" {
+		return nil, fmt.Errorf("redis_stream input requires a stream")
+	}
+	if cfg.Consumer != "" && cfg.Group == "" {
+		return nil, fmt.Errorf("consumer requires a group")
+	}
+	if cfg.Count < 0 || cfg.BlockTimeout < 0 || cfg.ClaimMinIdle < 0 || cfg.ClaimInterval < 0 {
+		return nil, fmt.Errorf("count, block_timeout, claim_min_idle and claim_interval must be non-negative")
+	}
+
+	if cfg.StartID == "" {
+		cfg.StartID = "$"
+	}
+	if cfg.Count == 0 {
+		cfg.Count = defaultCount
+	}
+	if cfg.BlockTimeout == 0 {
+		cfg.BlockTimeout = defaultBlockTimeout
+	}
+	if cfg.ClaimMinIdle == 0 {
+		cfg.ClaimMinIdle = defaultClaimMinIdle
+	}
+	if cfg.ClaimInterval == 0 {
+		cfg.ClaimInterval = defaultClaimInterval
+	}
+	if cfg.MessageField == "" {
+		cfg.MessageField = "message"
+	}
+	if cfg.Group != "" && cfg.Consumer == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("consumer is required when the hostname is unavailable: %w", err)
+		}
+		cfg.Consumer = hostname
+	}
+
+	client, err := redisclient.New(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStreamInput{
+		config: cfg,
+		client: client,
+		lastID: cfg.StartID,
+	}, nil
+}
+
+// RedisStreamInput reads entries from a Redis stream and forwards them to the engine.
+// With a consumer group, entries are acknowledged only after the engine has accepted
+// them, and entries left pending by crashed consumers are reclaimed with XAUTOCLAIM.
+type RedisStreamInput struct {
+	name   string
+	config Config
+	client *redisclient.Client
+	logCh  chan<- *core.Log
+	lastID string // Last entry read without a consumer group
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// entry is a stream entry. Fields is nil for entries deleted while pending.
+type entry struct {
+	ID     string
+	Fields map[string]string
+}
+
+// SetName assigns a logical name to this plugin instance.
+func (r *RedisStreamInput) SetName(name string) {
+	r.name = name
+}
+
+// SetLogChannel stores the channel used to send logs to the engine.
+func (r *RedisStreamInput) SetLogChannel(ch chan<- *core.Log) {
+	r.logCh = ch
+}
+
+// Start launches the background goroutine that reads the stream.
+func (r *RedisStreamInput) Start() error {
+	if r.ctx != nil {
+		return fmt.Errorf("redis_stream input already started")
+	}
+
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.wg.Add(1)
+	go r.readLoop()
+
+	logger.Printf("Redis stream input started (addr=%s, stream=%s, group=%s, consumer=%s)",
+		r.config.Addr, r.config.Stream, r.config.Group, r.config.Consumer)
+	return nil
+}
+
+// Stop cancels reading, waits for the goroutine to finish and closes the connections.
+// Entries read but not yet accepted stay pending and are read again on restart.
+func (r *RedisStreamInput) Stop() error {
+	if r.stopped {
+		return nil
+	}
+	r.stopped = true
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+
+	if err := r.client.Close(); err != nil {
+		logger.Printf("Redis stream input close error: %v", err)
+	}
+
+	logger.Printf("Redis stream input stopped")
+	r.ctx = nil
+	return nil
+}
+
+// CheckHealth implements HealthChecker interface
+func (r *RedisStreamInput) CheckHealth(ctx context.Context) error {
+	if err := r.client.Ping(ctx); err != nil {
+		return fmt.Errorf("redis unreachable: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStreamInput) readLoop() {
+	defer r.wg.Done()
+
+	var lastClaim time.Time
+	if r.config.Group != "" {
+		for !r.ensureGroup() {
+			if !r.sleep(time.Second) {
+				return
+			}
+		}
+		// Entries this consumer read before a restart but never acknowledged
+		if !r.readPending() {
+			return
+		}
+	}
+
+	for {
+		if r.config.Group != "" && time.Since(lastClaim) >= time.Duration(r.config.ClaimInterval)*time.Second {
+			if !r.reclaim() {
+				return
+			}
+			lastClaim = time.Now()
+		}
+
+		entries, err := r.read()
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return
+			}
+			logger.Printf("Redis stream input read error: %v", err)
+			if !r.sleep(time.Second) {
+				return
+			}
+			continue
+		}
+
+		if !r.deliver(entries) {
+			return
+		}
+	}
+}
+
+// ensureGroup creates the consumer group (and the stream) if it does not exist
+func (r *RedisStreamInput) ensureGroup() bool {
+	_, err := r.client.Do(r.ctx, "XGROUP", "CREATE", r.config.Stream, r.config.Group, r.config.StartID, "MKSTREAM")
+	var serverErr redisclient.Error
+	if err == nil || (errors.As(err, &serverErr) && strings.HasPrefix(string(serverErr), "BUSYGROUP")) {
+		return true
+	}
+	if r.ctx.Err() == nil {
+		logger.Printf("Redis stream input failed to create group %s: %v", r.config.Group, err)
+	}
+	return false
+}
+
+// readPending delivers the entries pending for this consumer, oldest first
+func (r *RedisStreamInput) readPending() bool {
+	after := "0"
+	for {
+		reply, err := r.client.Do(r.ctx, "XREADGROUP", "GROUP", r.config.Group, r.config.Consumer,
+			"COUNT", strconv.Itoa(r.config.Count), "STREAMS", r.config.Stream, after)
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return false
+			}
+			logger.Printf("Redis stream input failed to read pending entries: %v", err)
+			return true
+		}
+
+		entries, err := parseReadReply(reply)
+		if err != nil {
+			logger.Printf("Redis stream input failed to parse pending entries: %v", err)
+			return true
+		}
+		if len(entries) == 0 {
+			return true
+		}
+		if !r.deliver(entries) {
+			return false
+		}
+		after = entries[len(entries)-1].ID
+	}
+}
+
+// reclaim takes over entries that other consumers read but did not acknowledge
+// within claim_min_idle and delivers them
+func (r *RedisStreamInput) reclaim() bool {
+	minIdle := strconv.Itoa(r.config.ClaimMinIdle * 1000)
+	cursor := "0-0"
+	for {
+		reply, err := r.client.Do(r.ctx, "XAUTOCLAIM", r.config.Stream, r.config.Group, r.config.Consumer,
+			minIdle, cursor, "COUNT", strconv.Itoa(r.config.Count))
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return false
+			}
+			logger.Printf("Redis stream input failed to reclaim pending entries: %v", err)
+			return true
+		}
+
+		next, entries, err := parseAutoClaimReply(reply)
+		if err != nil {
+			logger.Printf("Redis stream input failed to parse reclaimed entries: %v", err)
+			return true
+		}
+		if len(entries) > 0 {
+			logger.Printf("Redis stream input reclaimed %d pending entries", len(entries))
+		}
+		if !r.deliver(entries) {
+			return false
+		}
+		if next == "0-0" || next == "" {
+			return true
+		}
+		cursor = next
+	}
+}
+
+// read blocks until new entries arrive or the block timeout expires
+func (r *RedisStreamInput) read() ([]entry, error) {
+	count := strconv.Itoa(r.config.Count)
+	block := strconv.Itoa(r.config.BlockTimeout * 1000)
+
+	if r.config.Group != "" {
+		reply, err := r.client.Do(r.ctx, "XREADGROUP", "GROUP", r.config.Group, r.config.Consumer,
+			"COUNT", count, "BLOCK", block, "STREAMS", r.config.Stream, ">")
+		if err != nil {
+			return nil, err
+		}
+		return parseReadReply(reply)
+	}
+
+	reply, err := r.client.Do(r.ctx, "XREAD", "COUNT", count, "BLOCK", block, "STREAMS", r.config.Stream, r.lastID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseReadReply(reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		r.lastID = entries[len(entries)-1].ID
+	}
+	return entries, nil
+}
+
+// deliver forwards entries to the engine in order and acknowledges the accepted ones.
+// It returns false when the input stopped before every entry was accepted.
+func (r *RedisStreamInput) deliver(entries []entry) bool {
+	accepted := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Fields == nil {
+			// Deleted from the stream while pending: nothing to deliver
+			accepted = append(accepted, e.ID)
+			continue
+		}
+		select {
+		case r.logCh <- buildLogFromEntry(r.config.Stream, e, r.config.MessageField, r.name):
+			accepted = append(accepted, e.ID)
+		case <-r.ctx.Done():
+			// The rest stays pending
+			r.ack(accepted)
+			return false
+		}
+	}
+
+	r.ack(accepted)
+	return true
+}
+
+// ack acknowledges entries in a single XACK. It runs even after Stop so accepted
+// entries are not delivered twice.
+func (r *RedisStreamInput) ack(ids []string) {
+	if r.config.Group == "" || len(ids) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	args := append([]string{"XACK", r.config.Stream, r.config.Group}, ids...)
+	if _, err := r.client.Do(ctx, args...); err != nil {
+		logger.Printf("Redis stream input ack error: %v (%d entries stay pending)", err, len(ids))
+	}
+}
+
+// sleep waits for d and reports whether the input is still running
+func (r *RedisStreamInput) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
+
+// parseReadReply parses an XREAD/XREADGROUP reply for a single stream
+func parseReadReply(reply any) ([]entry, error) {
+	if reply == nil {
+		return nil, nil // Block timeout
+	}
+	streams, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply type %T", reply)
+	}
+
+	var entries []entry
+	for _, s := range streams {
+		stream, ok := s.([]any)
+		if !ok || len(stream) != 2 {
+			return nil, fmt.Errorf("unexpected stream reply")
+		}
+		parsed, err := parseEntries(stream[1])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, parsed...)
+	}
+	return entries, nil
+}
+
+// parseAutoClaimReply parses an XAUTOCLAIM reply into the next cursor and the claimed entries
+func parseAutoClaimReply(reply any) (string, []entry, error) {
+	items, ok := reply.([]any)
+	if !ok || len(items) < 2 {
+		return "", nil, fmt.Errorf("unexpected XAUTOCLAIM reply")
+	}
+	next, _ := redisclient.String(items[0])
+	entries, err := parseEntries(items[1])
+	return next, entries, err
+}
+
+// parseEntries parses a list of [id, [field, value, ...]] entries
+func parseEntries(reply any) ([]entry, error) {
+	if reply == nil {
+		return nil, nil
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected entries reply type %T", reply)
+	}
+
+	entries := make([]entry, 0, len(items))
+	for _, item := range items {
+		pair, ok := item.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("unexpected entry reply")
+		}
+		id, ok := redisclient.String(pair[0])
+		if !ok {
+			return nil, fmt.Errorf("unexpected entry id type %T", pair[0])
+		}
+
+		e := entry{ID: id}
+		if values, ok := pair[1].([]any); ok {
+			e.Fields = make(map[string]string, len(values)/2)
+			for i := 0; i+1 < len(values); i += 2 {
+				field, _ := redisclient.String(values[i])
+				value, _ := redisclient.String(values[i+1])
+				e.Fields[field] = value
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// buildLogFromEntry converts a stream entry into a log. The fields written by the
+// redis_stream output (timestamp, level, message, tags and metadata.*) are mapped
+// back onto the log; other fields are kept as "field.<name>" metadata.
+func buildLogFromEntry(stream string, e entry, messageField, source string) *core.Log {
+	metadata := make(map[string]string, len(e.Fields)+4)
+
+	message, ok := e.Fields[messageField]
+	if !ok {
+		// No message field: keep the whole entry as JSON so filters can parse it
+		data, _ := json.Marshal(e.Fields)
+		message = string(data)
+		metadata["content_type"] = "json"
+	}
+
+	for field, value := range e.Fields {
+		switch {
+		case field == messageField, field == "level", field == "timestamp", field == "tags":
+		case strings.HasPrefix(field, metadataFieldPrefix):
+			metadata[strings.TrimPrefix(field, metadataFieldPrefix)] = value
+		default:
+			metadata["field."+field] = value
+		}
+	}
+	// Input-owned keys win over metadata carried in the entry
+	metadata["source"] = "redis_stream"
+	metadata["stream"] = stream
+	metadata["entry_id"] = e.ID
+
+	level := core.DetectLevel(message)
+	if l := e.Fields["level"]; l != "" {
+		level = core.Levels().Normalize(l)
+	}
+
+	logEntry := core.NewLogWithMetadata(level, message, metadata)
+	logEntry.Source = source
+	if ts, err := time.Parse(time.RFC3339Nano, e.Fields["timestamp"]); err == nil {
+		logEntry.Timestamp = ts
+	}
+	if tags := e.Fields["tags"]; tags != "" {
+		logEntry.AddTags(strings.Split(tags, ",")...)
+	}
+	return logEntry
+}
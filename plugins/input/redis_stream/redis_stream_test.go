@@ -0,0 +1,405 @@
+package redisstreaminput
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/redisclient"
+)
+
+// fakeStream is a minimal Redis server holding one stream and one consumer group
+type fakeStream struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	ids     []string
+	fields  map[string][]string
+	next    int               // Index of the next entry delivered with ">"
+	pending map[string]string // Entry ID -> consumer
+	acked   []string
+}
+
+func newFakeStream(t *testing.T) *fakeStream {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeStream{
+		listener: listener,
+		fields:   make(map[string][]string),
+		pending:  make(map[string]string),
+	}
+	go f.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return f
+}
+
+func (f *fakeStream) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeStream) add(id string, fields ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ids = append(f.ids, id)
+	f.fields[id] = fields
+}
+
+// deliverTo marks the entries up to id as read by consumer without acknowledging them
+func (f *fakeStream) deliverTo(consumer, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending[id] = consumer
+	for i, entryID := range f.ids {
+		if entryID == id && i >= f.next {
+			f.next = i + 1
+		}
+	}
+}
+
+func (f *fakeStream) ackedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acked...)
+}
+
+func (f *fakeStream) pendingCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pending)
+}
+
+func (f *fakeStream) serve() {
+	for {
+		c, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer func() { _ = c.Close() }()
+			reader := bufio.NewReader(c)
+			for {
+				args, err := readCommand(reader)
+				if err != nil {
+					return
+				}
+				if _, err := io.WriteString(c, f.handle(args)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (f *fakeStream) handle(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch args[0] {
+	case "PING":
+		return "+PONG\r\n"
+	case "XGROUP":
+		return "-BUSYGROUP Consumer Group name already exists\r\n"
+	case "XREADGROUP":
+		consumer, after := args[3], args[len(args)-1]
+		var ids []string
+		if after == ">" {
+			for ; f.next < len(f.ids); f.next++ {
+				id := f.ids[f.next]
+				f.pending[id] = consumer
+				ids = append(ids, id)
+			}
+			if len(ids) == 0 {
+				f.mu.Unlock()
+				time.Sleep(10 * time.Millisecond) // Short BLOCK
+				f.mu.Lock()
+				return "*-1\r\n"
+			}
+		} else {
+			for _, id := range f.ids {
+				if f.pending[id] == consumer && compareIDs(id, after) > 0 {
+					ids = append(ids, id)
+				}
+			}
+		}
+		return "*1\r\n*2\r\n" + bulk(args[len(args)-2]) + f.encodeEntries(ids)
+	case "XAUTOCLAIM":
+		consumer := args[3]
+		var ids []string
+		for _, id := range f.ids {
+			if owner, ok := f.pending[id]; ok && owner != consumer {
+				f.pending[id] = consumer
+				ids = append(ids, id)
+			}
+		}
+		return "*3\r\n" + bulk("0-0") + f.encodeEntries(ids) + "*0\r\n"
+	case "XACK":
+		n := 0
+		for _, id := range args[3:] {
+			if _, ok := f.pending[id]; ok {
+				delete(f.pending, id)
+				f.acked = append(f.acked, id)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (f *fakeStream) encodeEntries(ids []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(ids))
+	for _, id := range ids {
+		fields := f.fields[id]
+		fmt.Fprintf(&b, "*2\r\n%s*%d\r\n", bulk(id), len(fields))
+		for _, field := range fields {
+			b.WriteString(bulk(field))
+		}
+	}
+	return b.String()
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func compareIDs(a, b string) int {
+	ai, _ := strconv.Atoi(strings.SplitN(a, "-", 2)[0])
+	bi, _ := strconv.Atoi(strings.SplitN(b, "-", 2)[0])
+	return ai - bi
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func receiveMessages(t *testing.T, logCh <-chan *core.Log, n int) []string {
+	t.Helper()
+	var messages []string
+	for len(messages) < n {
+		select {
+		case logEntry := <-logCh:
+			messages = append(messages, logEntry.Message)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %d logs, got %v", n, messages)
+		}
+	}
+	return messages
+}
+
+func TestRedisStreamInputReclaimsPendingEntries(t *testing.T) {
+	server := newFakeStream(t)
+	server.add("1-0", "message", "own pending")
+	server.add("2-0", "message", "stranded")
+	server.add("3-0", "message", "new")
+	server.deliverTo("worker-1", "1-0") // Read before this consumer restarted
+	server.deliverTo("crashed", "2-0")  // Read by a consumer that never came back
+
+	input, err := NewRedisStreamInput(Config{
+		Config:   redisclient.Config{Addr: server.addr()},
+		Stream:   "logs",
+		Group:    "analyzer",
+		Consumer: "worker-1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create input: %v", err)
+	}
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start input: %v", err)
+	}
+	defer func() { _ = input.Stop() }()
+
+	messages := receiveMessages(t, logCh, 3)
+	expected := []string{"own pending", "stranded", "new"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %v, got %v", expected, messages)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.pendingCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := server.pendingCount(); n != 0 {
+		t.Errorf("Expected all entries acknowledged, %d still pending", n)
+	}
+}
+
+func TestRedisStreamInputAcksOnlyAcceptedEntries(t *testing.T) {
+	server := newFakeStream(t)
+	server.add("1-0", "message", "first")
+	server.add("2-0", "message", "second")
+
+	input, err := NewRedisStreamInput(Config{
+		Config:   redisclient.Config{Addr: server.addr()},
+		Stream:   "logs",
+		Group:    "analyzer",
+		Consumer: "worker-1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create input: %v", err)
+	}
+	logCh := make(chan *core.Log) // Unbuffered: nothing is accepted until read
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start input: %v", err)
+	}
+
+	receiveMessages(t, logCh, 1)
+	if err := input.Stop(); err != nil {
+		t.Fatalf("Failed to stop input: %v", err)
+	}
+
+	if acked := server.ackedIDs(); !reflect.DeepEqual(acked, []string{"1-0"}) {
+		t.Errorf("Expected only 1-0 acknowledged, got %v", acked)
+	}
+	if n := server.pendingCount(); n != 1 {
+		t.Errorf("Expected the unaccepted entry to stay pending, got %d pending", n)
+	}
+}
+
+func TestRedisStreamInputCheckHealth(t *testing.T) {
+	server := newFakeStream(t)
+	input, err := NewRedisStreamInput(Config{Config: redisclient.Config{Addr: server.addr()}, Stream: "logs"})
+	if err != nil {
+		t.Fatalf("Failed to create input: %v", err)
+	}
+	if err := input.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected healthy input, got %v", err)
+	}
+
+	_ = server.listener.Close()
+	input.client = mustClient(t, server.addr())
+	if err := input.CheckHealth(context.Background()); err == nil {
+		t.Error("Expected health check to fail when redis is unreachable")
+	}
+}
+
+func mustClient(t *testing.T, addr string) *redisclient.Client {
+	t.Helper()
+	client, err := redisclient.New(redisclient.Config{Addr: addr})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestBuildLogFromEntry(t *testing.T) {
+	e := entry{ID: "5-0", Fields: map[string]string{
+		"message":          "payment failed",
+		"level":            "ERROR",
+		"timestamp":        "2024-03-01T10:00:00.5Z",
+		"tags":             "billing,critical",
+		"metadata.service": "billing",
+		"metadata.source":  "spoofed",
+		"region":           "eu-west-1",
+	}}
+
+	logEntry := buildLogFromEntry("logs", e, "message", "redis-main")
+
+	if logEntry.Message != "payment failed" || logEntry.Level != "error" {
+		t.Errorf("Expected error 'payment failed', got %s %q", logEntry.Level, logEntry.Message)
+	}
+	if logEntry.Source != "redis-main" {
+		t.Errorf("Expected source redis-main, got %s", logEntry.Source)
+	}
+	if expected := time.Date(2024, 3, 1, 10, 0, 0, 500000000, time.UTC); !logEntry.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %v, got %v", expected, logEntry.Timestamp)
+	}
+	if !reflect.DeepEqual(logEntry.Tags, []string{"billing", "critical"}) {
+		t.Errorf("Expected tags [billing critical], got %v", logEntry.Tags)
+	}
+
+	expectedMetadata := map[string]string{
+		"service":      "billing",
+		"source":       "redis_stream",
+		"stream":       "logs",
+		"entry_id":     "5-0",
+		"field.region": "eu-west-1",
+	}
+	for key, value := range expectedMetadata {
+		if logEntry.Metadata[key] != value {
+			t.Errorf("Expected metadata %s=%s, got %q", key, value, logEntry.Metadata[key])
+		}
+	}
+}
+
+func TestBuildLogFromEntryWithoutMessageField(t *testing.T) {
+	e := entry{ID: "6-0", Fields: map[string]string{"event": "login", "user": "alice"}}
+
+	logEntry := buildLogFromEntry("logs", e, "message", "")
+
+	if logEntry.Message != `{"event":"login","user":"alice"}` {
+		t.Errorf("Expected entry as JSON, got %q", logEntry.Message)
+	}
+	if logEntry.Metadata["content_type"] != "json" {
+		t.Errorf("Expected content_type json, got %q", logEntry.Metadata["content_type"])
+	}
+}
+
+func TestNewRedisStreamInputValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "missing addr", config: map[string]any{"stream": "logs"}},
+		{name: "missing stream", config: map[string]any{"addr": "localhost:6379"}},
+		{name: "consumer without group", config: map[string]any{"addr": "localhost:6379", "stream": "logs", "consumer": "c1"}},
+		{name: "negative count", config: map[string]any{"addr": "localhost:6379", "stream": "logs", "count": -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRedisStreamInputFromConfig(tt.config); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+
+	plugin, err := NewRedisStreamInputFromConfig(map[string]any{"addr": "localhost:6379", "stream": "logs", "group": "analyzer"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := plugin.(*RedisStreamInput)
+	if input.config.Consumer == "" || input.config.StartID != "$" || input.config.Count != defaultCount {
+		t.Errorf("Expected defaults applied, got %+v", input.config)
+	}
+}
@@ -0,0 +1,229 @@
+package sqsinput
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sqsClient calls the SQS API using the AWS JSON 1.0 protocol with Signature Version 4
+type sqsClient struct {
+	client      *http.Client
+	endpoint    string
+	region      string
+	credentials credentials
+	now         func() time.Time
+}
+
+// credentials are the static AWS credentials used to sign requests
+type credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// message is a message returned by ReceiveMessage
+type message struct {
+	MessageID         string                      `json:"MessageId"`
+	ReceiptHandle     string                      `json:"ReceiptHandle"`
+	Body              string                      `json:"Body"`
+	Attributes        map[string]string           `json:"Attributes"`
+	MessageAttributes map[string]messageAttribute `json:"MessageAttributes"`
+}
+
+// messageAttribute is a user-defined message attribute
+type messageAttribute struct {
+	DataType    string `json:"DataType"`
+	StringValue string `json:"StringValue"`
+}
+
+// apiError is an error response from the SQS API
+type apiError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// receiveMessage long-polls the queue for up to maxMessages messages
+func (c *sqsClient) receiveMessage(ctx context.Context, queueURL string, maxMessages, waitTimeSeconds, visibilityTimeout int) ([]message, error) {
+	request := map[string]any{
+		"QueueUrl":                    queueURL,
+		"MaxNumberOfMessages":         maxMessages,
+		"WaitTimeSeconds":             waitTimeSeconds,
+		"MessageAttributeNames":       []string{"All"},
+		"MessageSystemAttributeNames": []string{"All"},
+	}
+	if visibilityTimeout > 0 {
+		request["VisibilityTimeout"] = visibilityTimeout
+	}
+
+	var response struct {
+		Messages []message `json:"Messages"`
+	}
+	if err := c.call(ctx, "ReceiveMessage", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Messages, nil
+}
+
+// deleteMessageBatch deletes up to 10 messages in one request and returns the
+// receipt handles that could not be deleted
+func (c *sqsClient) deleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]string, error) {
+	type entry struct {
+		ID            string `json:"Id"`
+		ReceiptHandle string `json:"ReceiptHandle"`
+	}
+	entries := make([]entry, len(receiptHandles))
+	for i, handle := range receiptHandles {
+		entries[i] = entry{ID: fmt.Sprintf("m%d", i), ReceiptHandle: handle}
+	}
+
+	var response struct {
+		Failed []struct {
+			ID      string `json:"Id"`
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Failed"`
+	}
+	request := map[string]any{"QueueUrl": queueURL, "Entries": entries}
+	if err := c.call(ctx, "DeleteMessageBatch", request, &response); err != nil {
+		return receiptHandles, err
+	}
+
+	failed := make([]string, 0, len(response.Failed))
+	for _, failure := range response.Failed {
+		for _, e := range entries {
+			if e.ID == failure.ID {
+				failed = append(failed, e.ReceiptHandle)
+			}
+		}
+	}
+	return failed, nil
+}
+
+// call sends a signed SQS API request and decodes the response
+func (c *sqsClient) call(ctx context.Context, action string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", action, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+	c.sign(req, body)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", action, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Type != "" {
+			errType := apiErr.Type[strings.LastIndex(apiErr.Type, "#")+1:]
+			return fmt.Errorf("sqs %s failed: %s: %s", action, errType, apiErr.Message)
+		}
+		return fmt.Errorf("sqs %s failed: %s", action, resp.Status)
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", action, err)
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to a request
+func (c *sqsClient) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.credentials.SessionToken)
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + c.region + "/sqs/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(c.credentials.SecretAccessKey, date, c.region, "sqs"), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// signingKey derives the Signature Version 4 signing key
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// regionFromQueueURL extracts the region from a queue URL such as
+// https://sqs.us-east-1.amazonaws.com/123456789012/logs
+func regionFromQueueURL(queueURL string) string {
+	parsed, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(parsed.Hostname(), ".")
+	if len(parts) >= 4 && parts[0] == "sqs" {
+		return parts[1]
+	}
+	return ""
+}
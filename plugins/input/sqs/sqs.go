@@ -0,0 +1,380 @@
+package sqsinput
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/httpclient"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.sqs")
+
+func init() {
+	core.RegisterInputPlugin("sqs", NewSQSInputFromConfig)
+}
+
+const (
+	maxMessagesLimit = 10 // SQS returns at most 10 messages per receive
+	maxWaitTime      = 20 // SQS long polling is capped at 20 seconds
+)
+
+// Config represents SQS input configuration values supplied via YAML.
+type Config struct {
+	QueueURL          string           `yaml:"queue_url"`
+	Region            string           `yaml:"region,omitempty"`             // Default: derived from queue_url, then AWS_REGION
+	MaxMessages       int              `yaml:"max_messages,omitempty"`       // Messages per receive, 1-10 (default: 10)
+	WaitTimeSeconds   *int             `yaml:"wait_time_seconds,omitempty"`  // Long polling wait, 0-20 (default: 20)
+	VisibilityTimeout int              `yaml:"visibility_timeout,omitempty"` // Seconds received messages stay hidden (default: queue setting)
+	Endpoint          string           `yaml:"endpoint,omitempty"`           // Endpoint override (e.g. LocalStack)
+	AccessKeyID       string           `yaml:"access_key_id,omitempty"`      // Default: AWS_ACCESS_KEY_ID
+	SecretAccessKey   string           `yaml:"secret_access_key,omitempty"`  // Default: AWS_SECRET_ACCESS_KEY
+	SessionToken      string           `yaml:"session_token,omitempty"`      // Default: AWS_SESSION_TOKEN
+	TLS               tlsconfig.Config `yaml:"tls,omitempty"`                // TLS configuration
+}
+
+// NewSQSInputFromConfig builds an SQS input plugin from generic configuration.
+func NewSQSInputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewSQSInput(cfg)
+}
+
+// NewSQSInput creates an SQS input, applying defaults and validating the configuration
+func NewSQSInput(cfg Config) (*SQSInput, error) {
+	if cfg.QueueURL == "" {
+		return nil, fmt.Errorf("sqs input requires a queue_url")
+	}
+
+	if cfg.MaxMessages == 0 {
+		cfg.MaxMessages = maxMessagesLimit
+	}
+	if cfg.MaxMessages < 1 || cfg.MaxMessages > maxMessagesLimit {
+		return nil, fmt.Errorf("max_messages must be between 1 and %d", maxMessagesLimit)
+	}
+
+	waitTime := maxWaitTime
+	if cfg.WaitTimeSeconds != nil {
+		waitTime = *cfg.WaitTimeSeconds
+	}
+	if waitTime < 0 || waitTime > maxWaitTime {
+		return nil, fmt.Errorf("wait_time_seconds must be between 0 and %d", maxWaitTime)
+	}
+	if cfg.VisibilityTimeout < 0 || cfg.VisibilityTimeout > 43200 {
+		return nil, fmt.Errorf("visibility_timeout must be between 0 and 43200")
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = regionFromQueueURL(cfg.QueueURL)
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("sqs input requires a region")
+	}
+
+	creds := credentials{
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		SessionToken:    cfg.SessionToken,
+	}
+	if creds.AccessKeyID == "" && creds.SecretAccessKey == "" {
+		creds = credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("sqs input requires access_key_id and secret_access_key (or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sqs.%s.amazonaws.com/", cfg.Region)
+	}
+
+	if err := cfg.TLS.Validate(); err != nil {
+		return nil, err
+	}
+	// The request timeout must outlast a long poll
+	client, err := httpclient.New(httpclient.Config{}, time.Duration(waitTime+10)*time.Second, cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQSInput{
+		config:   cfg,
+		waitTime: waitTime,
+		client: &sqsClient{
+			client:      client,
+			endpoint:    endpoint,
+			region:      cfg.Region,
+			credentials: creds,
+			now:         time.Now,
+		},
+	}, nil
+}
+
+// SQSInput polls an SQS queue and forwards each message to the engine.
+// Messages are deleted only after the engine has accepted them (at-least-once),
+// or with a pipeline in ack_mode sync, once that pipeline delivered them.
+type SQSInput struct {
+	name        string
+	config      Config
+	waitTime    int
+	client      *sqsClient
+	logCh       chan<- *core.Log
+	ackRequired func() bool // Whether a pipeline has ack_mode: sync (nil = never)
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// SetName assigns a logical name to this plugin instance.
+func (s *SQSInput) SetName(name string) {
+	s.name = name
+}
+
+// SetLogChannel stores the channel used to send logs to the engine.
+func (s *SQSInput) SetLogChannel(ch chan<- *core.Log) {
+	s.logCh = ch
+}
+
+// SetAckRequired sets the engine's check for pipelines with ack_mode: sync. While
+// one exists, a message is deleted only once every sync pipeline delivered it.
+func (s *SQSInput) SetAckRequired(required func() bool) {
+	s.ackRequired = required
+}
+
+// Start launches the background goroutine that polls the queue.
+func (s *SQSInput) Start() error {
+	if s.ctx != nil {
+		return fmt.Errorf("sqs input already started")
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.wg.Add(1)
+	go s.pollLoop()
+
+	logger.Named(s.name).Printf("SQS input started (queue=%s, region=%s)", s.config.QueueURL, s.config.Region)
+	return nil
+}
+
+// Stop cancels polling and waits for the goroutine to finish. Messages received
+// but not yet accepted are left in the queue and reappear after their visibility timeout.
+func (s *SQSInput) Stop() error {
+	if s.stopped {
+		return nil
+	}
+	s.stopped = true
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.client.client.CloseIdleConnections()
+
+	logger.Named(s.name).Printf("SQS input stopped")
+	s.ctx = nil
+	return nil
+}
+
+// CheckHealth implements HealthChecker interface
+func (s *SQSInput) CheckHealth(ctx context.Context) error {
+	if s.ctx != nil && s.ctx.Err() != nil {
+		return fmt.Errorf("sqs input stopped: %w", s.ctx.Err())
+	}
+	return nil
+}
+
+func (s *SQSInput) pollLoop() {
+	defer s.wg.Done()
+
+	for {
+		messages, err := s.client.receiveMessage(s.ctx, s.config.QueueURL, s.config.MaxMessages, s.waitTime, s.config.VisibilityTimeout)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			logger.Named(s.name).Printf("SQS input receive error: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-s.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		if len(messages) == 0 && s.waitTime == 0 {
+			// Short polling an empty queue: back off instead of spinning
+			select {
+			case <-time.After(time.Second):
+			case <-s.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		accepted := s.forward(messages)
+		s.delete(accepted)
+
+		if s.ctx.Err() != nil {
+			// Stopped while forwarding: the rest stays in the queue
+			return
+		}
+	}
+}
+
+// forward sends messages to the engine in order and returns the receipt handles
+// of the messages it accepted. With a sync pipeline the whole batch is sent
+// before waiting for its deliveries; messages that were not delivered stay in
+// the queue and reappear after their visibility timeout.
+func (s *SQSInput) forward(messages []message) []string {
+	var acks []*core.Ack
+	if s.ackRequired != nil && s.ackRequired() {
+		acks = make([]*core.Ack, 0, len(messages))
+	}
+
+	sent := 0
+	for _, msg := range messages {
+		logEntry := buildLogFromMessage(msg, s.name)
+		if acks != nil {
+			ack := core.NewAck()
+			logEntry.SetAck(ack)
+			acks = append(acks, ack)
+		}
+		select {
+		case s.logCh <- logEntry:
+			sent++
+		case <-s.ctx.Done():
+			return s.delivered(messages[:sent], acks)
+		}
+	}
+	return s.delivered(messages[:sent], acks)
+}
+
+// delivered returns the receipt handles of the sent messages whose Ack
+// succeeded, or of all of them when they carry none
+func (s *SQSInput) delivered(messages []message, acks []*core.Ack) []string {
+	handles := make([]string, 0, len(messages))
+	for i, msg := range messages {
+		if acks != nil {
+			if err := acks[i].Wait(s.ctx); err != nil {
+				logger.Named(s.name).Printf("SQS message %s not delivered, leaving it in the queue: %v", msg.MessageID, err)
+				continue
+			}
+		}
+		handles = append(handles, msg.ReceiptHandle)
+	}
+	return handles
+}
+
+// delete removes accepted messages from the queue in a single batch request. It
+// runs even after Stop so accepted messages are not delivered twice.
+func (s *SQSInput) delete(receiptHandles []string) {
+	if len(receiptHandles) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	failed, err := s.client.deleteMessageBatch(ctx, s.config.QueueURL, receiptHandles)
+	if err != nil {
+		logger.Named(s.name).Printf("SQS input delete error: %v (%d messages will be redelivered)", err, len(failed))
+		return
+	}
+	if len(failed) > 0 {
+		logger.Named(s.name).Printf("SQS input failed to delete %d messages, they will be redelivered", len(failed))
+	}
+}
+
+// buildLogFromMessage converts an SQS message into a log. JSON bodies are kept as
+// the message (so filters such as json can parse them) and their level field is
+// used; SNS notifications are unwrapped first.
+func buildLogFromMessage(msg message, source string) *core.Log {
+	body := unwrapSNS(msg.Body)
+
+	metadata := map[string]string{
+		"source":     "sqs",
+		"message_id": msg.MessageID,
+	}
+	for name, value := range msg.Attributes {
+		switch name {
+		case "SentTimestamp", "ApproximateReceiveCount", "MessageGroupId":
+			metadata[toSnakeCase(name)] = value
+		}
+	}
+	for name, attribute := range msg.MessageAttributes {
+		if attribute.StringValue != "" {
+			metadata["attribute."+name] = attribute.StringValue
+		}
+	}
+
+	level := ""
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(body), &entry); err == nil {
+		metadata["content_type"] = "json"
+		if l, ok := entry["level"].(string); ok {
+			level = core.Levels().Normalize(l)
+		}
+	}
+	if level == "" {
+		if attribute, ok := msg.MessageAttributes["level"]; ok && attribute.StringValue != "" {
+			level = core.Levels().Normalize(attribute.StringValue)
+		} else {
+			level = core.DetectLevel(body)
+		}
+	}
+
+	logEntry := core.NewLogWithMetadata(level, body, metadata)
+	logEntry.Source = source
+	return logEntry
+}
+
+// unwrapSNS returns the inner message of an SNS notification delivered to SQS,
+// or the body unchanged
+func unwrapSNS(body string) string {
+	var notification struct {
+		Type     string `json:"Type"`
+		TopicArn string `json:"TopicArn"`
+		Message  string `json:"Message"`
+	}
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return body
+	}
+	if notification.Type == "Notification" && notification.TopicArn != "" {
+		return notification.Message
+	}
+	return body
+}
+
+// toSnakeCase converts an attribute name such as "SentTimestamp" to "sent_timestamp"
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
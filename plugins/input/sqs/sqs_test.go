@@ -0,0 +1,271 @@
+package sqsinput
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// fakeSQS serves ReceiveMessage and DeleteMessageBatch from an in-memory queue
+type fakeSQS struct {
+	mu       sync.Mutex
+	messages []message
+	deleted  []string
+	targets  []string
+	authOK   bool
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := r.Header.Get("X-Amz-Target")
+	f.targets = append(f.targets, target)
+	f.authOK = strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") &&
+		strings.Contains(r.Header.Get("Authorization"), "/us-east-1/sqs/aws4_request")
+
+	var request map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&request)
+
+	switch target {
+	case "AmazonSQS.ReceiveMessage":
+		max := int(request["MaxNumberOfMessages"].(float64))
+		if max > len(f.messages) {
+			max = len(f.messages)
+		}
+		batch := f.messages[:max]
+		f.messages = f.messages[max:]
+		_ = json.NewEncoder(w).Encode(map[string]any{"Messages": batch})
+	case "AmazonSQS.DeleteMessageBatch":
+		for _, entry := range request["Entries"].([]any) {
+			f.deleted = append(f.deleted, entry.(map[string]any)["ReceiptHandle"].(string))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Successful": []any{}})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type":"com.amazonaws.sqs#InvalidAction","message":"unknown"}`))
+	}
+}
+
+func (f *fakeSQS) deletedHandles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleted...)
+}
+
+func newTestInput(t *testing.T, server *httptest.Server, maxMessages int) *SQSInput {
+	t.Helper()
+	wait := 0
+	input, err := NewSQSInput(Config{
+		QueueURL:        "https://sqs.us-east-1.amazonaws.com/123456789012/logs",
+		MaxMessages:     maxMessages,
+		WaitTimeSeconds: &wait,
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create input: %v", err)
+	}
+	input.SetName("queue")
+	return input
+}
+
+func TestSQSInputDeliversAndDeletes(t *testing.T) {
+	fake := &fakeSQS{messages: []message{
+		{MessageID: "1", ReceiptHandle: "h1", Body: `{"level":"ERROR","msg":"boom"}`},
+		{MessageID: "2", ReceiptHandle: "h2", Body: "WARN disk almost full"},
+		{MessageID: "3", ReceiptHandle: "h3", Body: "plain message"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	input := newTestInput(t, server, 10)
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer func() { _ = input.Stop() }()
+
+	var logs []*core.Log
+	for len(logs) < 3 {
+		select {
+		case logEntry := <-logCh:
+			logs = append(logs, logEntry)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out, got %d logs", len(logs))
+		}
+	}
+
+	if logs[0].Level != "error" || logs[0].Metadata["content_type"] != "json" {
+		t.Errorf("Expected JSON error log, got level=%s metadata=%v", logs[0].Level, logs[0].Metadata)
+	}
+	if logs[1].Level != "warn" {
+		t.Errorf("Expected detected warn level, got %s", logs[1].Level)
+	}
+	if logs[2].Source != "queue" || logs[2].Metadata["message_id"] != "3" {
+		t.Errorf("Expected source and message_id metadata, got %s %v", logs[2].Source, logs[2].Metadata)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.deletedHandles()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if deleted := fake.deletedHandles(); len(deleted) != 3 {
+		t.Errorf("Expected 3 deleted messages, got %v", deleted)
+	}
+
+	fake.mu.Lock()
+	authOK := fake.authOK
+	fake.mu.Unlock()
+	if !authOK {
+		t.Error("Expected requests to be signed with SigV4 for us-east-1")
+	}
+}
+
+func TestSQSInputDoesNotDeleteUnacceptedMessages(t *testing.T) {
+	fake := &fakeSQS{messages: []message{
+		{MessageID: "1", ReceiptHandle: "h1", Body: "first"},
+		{MessageID: "2", ReceiptHandle: "h2", Body: "second"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	input := newTestInput(t, server, 10)
+	logCh := make(chan *core.Log) // Unbuffered: the second message blocks
+	input.SetLogChannel(logCh)
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	select {
+	case <-logCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the first message")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := input.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+
+	deleted := fake.deletedHandles()
+	if len(deleted) != 1 || deleted[0] != "h1" {
+		t.Errorf("Expected only the accepted message to be deleted, got %v", deleted)
+	}
+}
+
+func TestNewSQSInputValidation(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_REGION", "")
+
+	wait := 30
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"missing queue", Config{AccessKeyID: "a", SecretAccessKey: "b", Region: "us-east-1"}},
+		{"too many messages", Config{QueueURL: "https://sqs.us-east-1.amazonaws.com/1/q", MaxMessages: 11, AccessKeyID: "a", SecretAccessKey: "b"}},
+		{"wait too long", Config{QueueURL: "https://sqs.us-east-1.amazonaws.com/1/q", WaitTimeSeconds: &wait, AccessKeyID: "a", SecretAccessKey: "b"}},
+		{"no region", Config{QueueURL: "http://localhost:4566/1/q", AccessKeyID: "a", SecretAccessKey: "b"}},
+		{"no credentials", Config{QueueURL: "https://sqs.us-east-1.amazonaws.com/1/q"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSQSInput(tt.config); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	input, err := NewSQSInput(Config{QueueURL: "https://sqs.eu-west-1.amazonaws.com/1/q"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if input.config.Region != "eu-west-1" || input.client.credentials.AccessKeyID != "env-key" {
+		t.Errorf("Expected region from URL and env credentials, got %s %s", input.config.Region, input.client.credentials.AccessKeyID)
+	}
+	if input.client.endpoint != "https://sqs.eu-west-1.amazonaws.com/" {
+		t.Errorf("Expected default endpoint, got %s", input.client.endpoint)
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(key) != expected {
+		t.Errorf("Expected signing key %s, got %x", expected, key)
+	}
+}
+
+func TestBuildLogFromSNSMessage(t *testing.T) {
+	body := `{"Type":"Notification","TopicArn":"arn:aws:sns:us-east-1:1:logs","Message":"ERROR payment failed"}`
+	logEntry := buildLogFromMessage(message{
+		MessageID:         "1",
+		Body:              body,
+		MessageAttributes: map[string]messageAttribute{"service": {DataType: "String", StringValue: "billing"}},
+	}, "queue")
+
+	if logEntry.Message != "ERROR payment failed" {
+		t.Errorf("Expected unwrapped SNS message, got %q", logEntry.Message)
+	}
+	if logEntry.Level != "error" {
+		t.Errorf("Expected error level, got %s", logEntry.Level)
+	}
+	if logEntry.Metadata["attribute.service"] != "billing" {
+		t.Errorf("Expected message attribute in metadata, got %v", logEntry.Metadata)
+	}
+}
+
+// rejectingOutput fails the logs whose message is reject
+type rejectingOutput struct {
+	reject string
+}
+
+func (o *rejectingOutput) Write(logEntry *core.Log) error {
+	if logEntry.Message == o.reject {
+		return errors.New("rejected by backend")
+	}
+	return nil
+}
+
+func (o *rejectingOutput) Close() error { return nil }
+
+func TestSQSInputAckModeSync(t *testing.T) {
+	fake := &fakeSQS{messages: []message{
+		{MessageID: "1", ReceiptHandle: "h1", Body: "delivered"},
+		{MessageID: "2", ReceiptHandle: "h2", Body: "rejected"},
+		{MessageID: "3", ReceiptHandle: "h3", Body: "also delivered"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	engine := core.NewEngine()
+	if err := engine.AddOutputPipeline(&core.OutputPipeline{Name: "durable", Output: &rejectingOutput{reject: "rejected"}, AckMode: core.AckModeSync}); err != nil {
+		t.Fatalf("Failed to add output pipeline: %v", err)
+	}
+	engine.AddInput("queue", newTestInput(t, server, 10))
+	engine.Start()
+	defer engine.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.deletedHandles()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if deleted := fake.deletedHandles(); len(deleted) != 2 || deleted[0] != "h1" || deleted[1] != "h3" {
+		t.Errorf("Expected only the delivered messages to be deleted, got %v", deleted)
+	}
+}
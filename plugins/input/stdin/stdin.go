@@ -0,0 +1,206 @@
+package stdininput
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+)
+
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.stdin")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterInputPlugin("stdin", NewStdinInputFromConfig)
+}
+
+// Config represents stdin input configuration
+type Config struct {
+	StopOnEOF bool `yaml:"stop_on_eof,omitempty"` // Stop the engine once stdin is exhausted
+}
+
+// NewStdinInputFromConfig creates a stdin input from configuration map
+func NewStdinInputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewStdinInput(cfg), nil
+}
+
+// StdinInput reads logs line by line from standard input
+type StdinInput struct {
+	name      string
+	reader    io.Reader
+	stopOnEOF bool
+	logCh     chan<- *core.Log
+	shutdown  func()
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	stopped   bool // Flag to prevent multiple stops
+}
+
+// NewStdinInput creates a new stdin input plugin
+func NewStdinInput(cfg Config) *StdinInput {
+	return &StdinInput{
+		name:      "stdin",
+		reader:    os.Stdin,
+		stopOnEOF: cfg.StopOnEOF,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// SetName sets the name for this input instance
+func (s *StdinInput) SetName(name string) {
+	s.name = name
+}
+
+// SetLogChannel sets the channel to send logs to
+func (s *StdinInput) SetLogChannel(ch chan<- *core.Log) {
+	s.logCh = ch
+}
+
+// SetShutdownFunc sets the hook used to stop the engine when stdin reaches EOF
+func (s *StdinInput) SetShutdownFunc(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdown = fn
+}
+
+// Start begins reading from stdin
+func (s *StdinInput) Start() error {
+	if isTerminal(s.reader) {
+		// Nothing is piped in; reading would block on the user's terminal
+		logger.Named(s.name).Printf("Stdin is a terminal, no logs will be read")
+		return nil
+	}
+
+	// Reads from stdin cannot be interrupted, so the scanner runs in its own
+	// goroutine and hands lines over; Stop only waits for the forwarder.
+	lines := make(chan string)
+	go s.scanLines(lines)
+
+	s.wg.Add(1)
+	go s.forwardLines(lines)
+	logger.Named(s.name).Printf("Stdin input started (stop_on_eof: %v)", s.stopOnEOF)
+	return nil
+}
+
+// Stop stops reading from stdin
+func (s *StdinInput) Stop() error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil // Already stopped
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	close(s.stopCh)
+	s.wg.Wait()
+	logger.Named(s.name).Printf("Stdin input stopped")
+	return nil
+}
+
+// scanLines reads lines from the reader until EOF and closes lines afterwards
+func (s *StdinInput) scanLines(lines chan<- string) {
+	defer close(lines)
+
+	scanner := bufio.NewScanner(s.reader)
+	for scanner.Scan() {
+		select {
+		case lines <- scanner.Text():
+		case <-s.stopCh:
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Named(s.name).Printf("Error reading stdin: %v", err)
+	}
+}
+
+// forwardLines converts scanned lines to logs and requests shutdown on EOF if configured
+func (s *StdinInput) forwardLines(lines <-chan string) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case line, ok := <-lines:
+			if !ok {
+				s.handleEOF()
+				return
+			}
+
+			logEntry := s.parseLogLine(line)
+			if logEntry == nil {
+				continue
+			}
+			select {
+			case s.logCh <- logEntry:
+			case <-s.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// handleEOF stops the engine when stop_on_eof is enabled
+func (s *StdinInput) handleEOF() {
+	logger.Named(s.name).Printf("Stdin input reached EOF")
+	if !s.stopOnEOF {
+		return
+	}
+
+	s.mu.Lock()
+	shutdown := s.shutdown
+	s.mu.Unlock()
+
+	if shutdown != nil {
+		shutdown()
+	}
+}
+
+// ParseLogLine parses a log line into a Log struct (public for testing)
+func (s *StdinInput) ParseLogLine(line string) *core.Log {
+	return s.parseLogLine(line)
+}
+
+// parseLogLine parses a log line into a Log struct
+func (s *StdinInput) parseLogLine(line string) *core.Log {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	metadata := map[string]string{
+		"source": "stdin",
+	}
+
+	logEntry := core.NewLogWithMetadata(core.DetectLevel(line), line, metadata)
+	logEntry.Source = s.name // Set the source to the input name
+	return logEntry
+}
+
+// isTerminal reports whether the reader is an interactive terminal
+func isTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,128 @@
+package stdininput
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func TestNewStdinInputFromConfig(t *testing.T) {
+	plugin, err := NewStdinInputFromConfig(map[string]any{"stop_on_eof": true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	input, ok := plugin.(*StdinInput)
+	if !ok {
+		t.Fatalf("Expected *StdinInput, got %T", plugin)
+	}
+	if !input.stopOnEOF {
+		t.Error("Expected stopOnEOF to be true")
+	}
+	if input.name != "stdin" {
+		t.Errorf("Expected default name 'stdin', got %s", input.name)
+	}
+}
+
+func TestStdinInputParseLogLine(t *testing.T) {
+	input := NewStdinInput(Config{})
+	input.SetName("batch")
+
+	tests := []struct {
+		line          string
+		expectedLevel string
+	}{
+		{"Database connection failed with error", "error"},
+		{"WARNING: disk almost full", "warn"},
+		{"debug: cache miss", "debug"},
+		{"User logged in", "info"},
+	}
+
+	for _, tt := range tests {
+		logEntry := input.ParseLogLine(tt.line)
+		if logEntry == nil {
+			t.Fatalf("Expected log for line %q", tt.line)
+		}
+		if logEntry.Level != tt.expectedLevel {
+			t.Errorf("Line %q: expected level %s, got %s", tt.line, tt.expectedLevel, logEntry.Level)
+		}
+		if logEntry.Message != tt.line {
+			t.Errorf("Expected message %q, got %q", tt.line, logEntry.Message)
+		}
+		if logEntry.Source != "batch" {
+			t.Errorf("Expected source 'batch', got %s", logEntry.Source)
+		}
+		if logEntry.Metadata["source"] != "stdin" {
+			t.Errorf("Expected metadata source 'stdin', got %s", logEntry.Metadata["source"])
+		}
+	}
+
+	if input.ParseLogLine("   ") != nil {
+		t.Error("Expected nil for blank line")
+	}
+}
+
+func TestStdinInputReadsLinesAndStopsOnEOF(t *testing.T) {
+	input := NewStdinInput(Config{StopOnEOF: true})
+	input.reader = strings.NewReader("first line\n\nsecond error line\n")
+
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	shutdownCh := make(chan struct{})
+	input.SetShutdownFunc(func() { close(shutdownCh) })
+
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	defer input.Stop()
+
+	select {
+	case <-shutdownCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected shutdown to be requested on EOF")
+	}
+
+	if len(logCh) != 2 {
+		t.Fatalf("Expected 2 logs, got %d", len(logCh))
+	}
+	if first := <-logCh; first.Message != "first line" {
+		t.Errorf("Expected 'first line', got %q", first.Message)
+	}
+	if second := <-logCh; second.Level != "error" {
+		t.Errorf("Expected error level, got %s", second.Level)
+	}
+}
+
+func TestStdinInputNoShutdownWithoutStopOnEOF(t *testing.T) {
+	input := NewStdinInput(Config{})
+	input.reader = strings.NewReader("only line\n")
+
+	logCh := make(chan *core.Log, 10)
+	input.SetLogChannel(logCh)
+
+	shutdownCalled := make(chan struct{}, 1)
+	input.SetShutdownFunc(func() { shutdownCalled <- struct{}{} })
+
+	if err := input.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	select {
+	case <-logCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a log entry")
+	}
+
+	if err := input.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+
+	select {
+	case <-shutdownCalled:
+		t.Error("Shutdown should not be requested without stop_on_eof")
+	default:
+	}
+}
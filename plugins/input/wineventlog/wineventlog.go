@@ -0,0 +1,442 @@
+// Package wineventlog reads the Windows Event Log. The subscription itself uses
+// the Windows Event Log API (wineventlog_windows.go); on other platforms the
+// plugin is registered but cannot be created, so configurations still validate.
+package wineventlog
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+)
+
+// logger writes this input plugin's internal logs
+var logger = logging.New("input.wineventlog")
+
+func init() {
+	core.RegisterInputPlugin("wineventlog", NewWinEventLogInputFromConfig)
+}
+
+// defaultChannels are read when no channels are configured. Security is left out
+// because reading it requires administrator rights.
+var defaultChannels = []string{"Application", "System"}
+
+// Config represents Windows Event Log input configuration values supplied via YAML.
+type Config struct {
+	Channels     []string `yaml:"channels,omitempty"`      // Channels to subscribe to (default: Application, System)
+	Query        string   `yaml:"query,omitempty"`         // XPath filter applied to every channel, e.g. "*[System[Level<=3]]" (default: all events)
+	BookmarkPath string   `yaml:"bookmark_path,omitempty"` // File recording the last event read, to resume there after a restart (default: new events only)
+}
+
+// subscription reads events from the Event Log, see subscribe
+type subscription interface {
+	// Next blocks until events are available or ctx is done and returns the XML of the next batch
+	Next(ctx context.Context) ([]string, error)
+	// Ack moves the bookmark past the first n events of the last batch
+	Ack(n int) error
+	// Bookmark returns the bookmark XML, to pass to subscribe after a restart
+	Bookmark() (string, error)
+	Close() error
+}
+
+// subscribeFunc opens a subscription to a structured query, starting after the
+// bookmark XML when it is not empty and at new events otherwise
+type subscribeFunc func(query, bookmark string) (subscription, error)
+
+// NewWinEventLogInputFromConfig builds a Windows Event Log input plugin from generic configuration.
+func NewWinEventLogInputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewWinEventLogInput(cfg)
+}
+
+// NewWinEventLogInput creates a Windows Event Log input, applying defaults and
+// validating the configuration. It fails on platforms other than Windows.
+func NewWinEventLogInput(cfg Config) (*WinEventLogInput, error) {
+	if !supported {
+		return nil, fmt.Errorf("wineventlog input is only supported on Windows")
+	}
+	return newWinEventLogInput(cfg, subscribe)
+}
+
+// newWinEventLogInput creates the input with the given way to open subscriptions
+func newWinEventLogInput(cfg Config, open subscribeFunc) (*WinEventLogInput, error) {
+	if len(cfg.Channels) == 0 {
+		cfg.Channels = defaultChannels
+	}
+	for _, channel := range cfg.Channels {
+		if strings.TrimSpace(channel) == "" {
+			return nil, fmt.Errorf("wineventlog channels cannot be blank")
+		}
+	}
+
+	return &WinEventLogInput{
+		config: cfg,
+		query:  buildQuery(cfg.Channels, cfg.Query),
+		open:   open,
+	}, nil
+}
+
+// WinEventLogInput subscribes to Event Log channels and forwards each event to the engine.
+// With a bookmark_path, the bookmark only moves past events the engine has accepted.
+type WinEventLogInput struct {
+	name   string
+	config Config
+	query  string
+	open   subscribeFunc
+	logCh  chan<- *core.Log
+
+	sub          subscription
+	lastBookmark string // Last bookmark written to bookmark_path
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// SetName assigns a logical name to this plugin instance.
+func (w *WinEventLogInput) SetName(name string) {
+	w.name = name
+}
+
+// SetLogChannel stores the channel used to send logs to the engine.
+func (w *WinEventLogInput) SetLogChannel(ch chan<- *core.Log) {
+	w.logCh = ch
+}
+
+// Start subscribes to the channels, after the saved bookmark if there is one, and
+// launches the background goroutine that reads events.
+func (w *WinEventLogInput) Start() error {
+	if w.ctx != nil {
+		return fmt.Errorf("wineventlog input already started")
+	}
+
+	bookmark, err := w.loadBookmark()
+	if err != nil {
+		logger.Named(w.name).Printf("Ignoring bookmark: %v", err)
+	}
+	sub, err := w.open(w.query, bookmark)
+	if err != nil && bookmark != "" {
+		logger.Named(w.name).Printf("Failed to resume from bookmark, reading new events only: %v", err)
+		bookmark = ""
+		sub, err = w.open(w.query, "")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to event log: %w", err)
+	}
+	w.sub = sub
+	w.lastBookmark = bookmark
+
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.wg.Add(1)
+	go w.readLoop()
+
+	logger.Named(w.name).Printf("Windows Event Log input started (channels=%s, resumed=%v)", strings.Join(w.config.Channels, ","), bookmark != "")
+	return nil
+}
+
+// Stop cancels reading and waits for the goroutine to finish. The bookmark already
+// covers every event the engine accepted.
+func (w *WinEventLogInput) Stop() error {
+	if w.stopped {
+		return nil
+	}
+	w.stopped = true
+
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+
+	logger.Named(w.name).Printf("Windows Event Log input stopped")
+	w.ctx = nil
+	return nil
+}
+
+// CheckHealth implements HealthChecker interface
+func (w *WinEventLogInput) CheckHealth(ctx context.Context) error {
+	if w.ctx != nil && w.ctx.Err() != nil {
+		return fmt.Errorf("wineventlog input stopped: %w", w.ctx.Err())
+	}
+	return nil
+}
+
+func (w *WinEventLogInput) readLoop() {
+	defer w.wg.Done()
+	defer func() {
+		if err := w.sub.Close(); err != nil {
+			logger.Named(w.name).Printf("Error closing subscription: %v", err)
+		}
+	}()
+
+	for {
+		events, err := w.sub.Next(w.ctx)
+		if err != nil {
+			if w.ctx.Err() != nil {
+				return
+			}
+			logger.Named(w.name).Printf("Windows Event Log read error: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-w.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		accepted := w.forward(events)
+		if accepted > 0 {
+			if err := w.sub.Ack(accepted); err != nil {
+				logger.Named(w.name).Printf("Error updating bookmark: %v", err)
+			} else {
+				w.saveBookmark()
+			}
+		}
+
+		if accepted < len(events) {
+			// Stopped while forwarding: the rest is read again after a restart
+			return
+		}
+	}
+}
+
+// forward sends events to the engine in order and returns how many it handled.
+// Events that cannot be parsed are logged and skipped, so they count as handled.
+func (w *WinEventLogInput) forward(events []string) int {
+	for i, data := range events {
+		if data == "" {
+			continue // Not rendered, already logged by the subscription
+		}
+		logEntry, err := buildLogFromEvent(data, w.name)
+		if err != nil {
+			logger.Named(w.name).Printf("Skipping event that cannot be parsed: %v", err)
+			continue
+		}
+		select {
+		case w.logCh <- logEntry:
+		case <-w.ctx.Done():
+			return i
+		}
+	}
+	return len(events)
+}
+
+// loadBookmark reads the bookmark saved by a previous run, if any
+func (w *WinEventLogInput) loadBookmark() (string, error) {
+	if w.config.BookmarkPath == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(w.config.BookmarkPath) // #nosec G304 - path comes from configuration
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveBookmark writes the current bookmark to bookmark_path when it changed. The
+// file is replaced atomically, so a crash never leaves a truncated bookmark.
+func (w *WinEventLogInput) saveBookmark() {
+	if w.config.BookmarkPath == "" {
+		return
+	}
+	bookmark, err := w.sub.Bookmark()
+	if err != nil {
+		logger.Named(w.name).Printf("Error rendering bookmark: %v", err)
+		return
+	}
+	if bookmark == w.lastBookmark {
+		return
+	}
+
+	tmp := w.config.BookmarkPath + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(w.config.BookmarkPath), 0750); err == nil {
+		err = os.WriteFile(tmp, []byte(bookmark), 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.config.BookmarkPath)
+	}
+	if err != nil {
+		logger.Named(w.name).Printf("Error saving bookmark: %v", err)
+		return
+	}
+	w.lastBookmark = bookmark
+}
+
+// buildQuery returns a structured query selecting the XPath filter (all events
+// when empty) from every channel, so one subscription and one bookmark cover them all
+func buildQuery(channels []string, xpath string) string {
+	if strings.TrimSpace(xpath) == "" {
+		xpath = "*"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<QueryList><Query Id="0">`)
+	for _, channel := range channels {
+		b.WriteString(`<Select Path="`)
+		_ = xml.EscapeText(&b, []byte(channel))
+		b.WriteString(`">`)
+		_ = xml.EscapeText(&b, []byte(xpath))
+		b.WriteString(`</Select>`)
+	}
+	b.WriteString(`</Query></QueryList>`)
+	return b.String()
+}
+
+// event is the part of an event's XML rendering that is mapped to a log
+type event struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID     string `xml:"EventID"`
+		Level       string `xml:"Level"`
+		Task        string `xml:"Task"`
+		Opcode      string `xml:"Opcode"`
+		Keywords    string `xml:"Keywords"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID string `xml:"EventRecordID"`
+		Execution     struct {
+			ProcessID string `xml:"ProcessID,attr"`
+			ThreadID  string `xml:"ThreadID,attr"`
+		} `xml:"Execution"`
+		Channel  string `xml:"Channel"`
+		Computer string `xml:"Computer"`
+		Security struct {
+			UserID string `xml:"UserID,attr"`
+		} `xml:"Security"`
+	} `xml:"System"`
+	EventData struct {
+		Data []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Data"`
+	} `xml:"EventData"`
+	RenderingInfo struct {
+		Message string `xml:"Message"`
+		Task    string `xml:"Task"`
+		Opcode  string `xml:"Opcode"`
+	} `xml:"RenderingInfo"`
+}
+
+// keywordAuditFailure is the keyword bit of failed audits, which the Security
+// channel logs at level 0 like successful ones
+const keywordAuditFailure = 0x10000000000000
+
+// buildLogFromEvent converts an event's XML into a log. The message is the
+// formatted event message when the provider has one, and the event data otherwise.
+func buildLogFromEvent(data, source string) (*core.Log, error) {
+	var e event
+	if err := xml.Unmarshal([]byte(data), &e); err != nil {
+		return nil, err
+	}
+	system := e.System
+
+	metadata := map[string]string{"source": "wineventlog"}
+	fields := map[string]string{
+		"channel":    system.Channel,
+		"provider":   system.Provider.Name,
+		"event_id":   strings.TrimSpace(system.EventID),
+		"record_id":  strings.TrimSpace(system.EventRecordID),
+		"computer":   system.Computer,
+		"task":       firstNonEmpty(e.RenderingInfo.Task, system.Task),
+		"opcode":     firstNonEmpty(e.RenderingInfo.Opcode, system.Opcode),
+		"keywords":   system.Keywords,
+		"user_sid":   system.Security.UserID,
+		"process_id": system.Execution.ProcessID,
+		"thread_id":  system.Execution.ThreadID,
+	}
+	for key, value := range fields {
+		if value = strings.TrimSpace(value); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	var values []string
+	for i, d := range e.EventData.Data {
+		name := d.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		value := strings.TrimSpace(d.Value)
+		if value == "" {
+			continue
+		}
+		metadata["data."+name] = value
+		values = append(values, name+"="+value)
+	}
+
+	message := strings.TrimSpace(e.RenderingInfo.Message)
+	if message == "" {
+		message = fmt.Sprintf("%s event %s", system.Provider.Name, metadata["event_id"])
+		if len(values) > 0 {
+			message += ": " + strings.Join(values, ", ")
+		}
+	}
+
+	logEntry := core.NewLogWithMetadata(mapLevel(system.Level, system.Keywords), message, metadata)
+	if created, err := time.Parse(time.RFC3339Nano, system.TimeCreated.SystemTime); err == nil {
+		logEntry.Timestamp = created
+	}
+	logEntry.Source = source
+	return logEntry, nil
+}
+
+// mapLevel maps an event level (and, for level 0, the audit keywords of the
+// Security channel) to a level of the vocabulary. The Windows level names are
+// tried first, so "critical" or "verbose" can be added to levels or aliased.
+func mapLevel(level, keywords string) string {
+	vocabulary := core.Levels()
+
+	var candidates []string
+	switch strings.TrimSpace(level) {
+	case "1":
+		candidates = []string{"critical", "error"}
+	case "2":
+		candidates = []string{"error"}
+	case "3":
+		candidates = []string{"warning", "warn"}
+	case "5":
+		candidates = []string{"verbose", "debug"}
+	default: // 0 (LogAlways) and 4 (Information)
+		mask, _ := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(keywords), "0x"), 16, 64)
+		if mask&keywordAuditFailure != 0 {
+			candidates = []string{"warning", "warn"}
+		} else {
+			candidates = []string{"information", "info"}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if vocabulary.Known(candidate) {
+			return vocabulary.Normalize(candidate)
+		}
+	}
+	return vocabulary.Default()
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package wineventlog
+
+import "fmt"
+
+const supported = false
+
+// subscribe is not available outside Windows
+func subscribe(string, string) (subscription, error) {
+	return nil, fmt.Errorf("the Windows Event Log is not available on this platform")
+}
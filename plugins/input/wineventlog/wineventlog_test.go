@@ -0,0 +1,301 @@
+package wineventlog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// eventXML renders an event the way EvtFormatMessage does, with a RenderingInfo
+// element when message is not empty
+func eventXML(record int, level, keywords, message string) string {
+	rendering := ""
+	if message != "" {
+		rendering = fmt.Sprintf(`<RenderingInfo Culture="en-US"><Message>%s</Message><Level>Error</Level><Task>Logon</Task></RenderingInfo>`, message)
+	}
+	return fmt.Sprintf(`<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">
+  <System>
+    <Provider Name="Service Control Manager" Guid="{555908d1-a6d7-4695-8e1e-26931d2012f4}"/>
+    <EventID Qualifiers="49152">7000</EventID>
+    <Level>%s</Level>
+    <Task>0</Task>
+    <Keywords>%s</Keywords>
+    <TimeCreated SystemTime="2024-05-01T12:00:00.1234567Z"/>
+    <EventRecordID>%d</EventRecordID>
+    <Execution ProcessID="812" ThreadID="4120"/>
+    <Channel>System</Channel>
+    <Computer>web-01.example.com</Computer>
+    <Security UserID="S-1-5-18"/>
+  </System>
+  <EventData>
+    <Data Name="param1">Print Spooler</Data>
+    <Data Name="param2">%%%%1053</Data>
+    <Data></Data>
+  </EventData>
+  %s
+</Event>`, level, keywords, record, rendering)
+}
+
+func TestBuildLogFromEvent(t *testing.T) {
+	logEntry, err := buildLogFromEvent(eventXML(42, "2", "0x8080000000000000", "The Print Spooler service failed to start."), "windows")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if logEntry.Level != "error" || logEntry.Message != "The Print Spooler service failed to start." || logEntry.Source != "windows" {
+		t.Errorf("Unexpected log %s %q from %s", logEntry.Level, logEntry.Message, logEntry.Source)
+	}
+	if expected := time.Date(2024, 5, 1, 12, 0, 0, 123456700, time.UTC); !logEntry.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %v, got %v", expected, logEntry.Timestamp)
+	}
+
+	expected := map[string]string{
+		"source":      "wineventlog",
+		"channel":     "System",
+		"provider":    "Service Control Manager",
+		"event_id":    "7000",
+		"record_id":   "42",
+		"computer":    "web-01.example.com",
+		"task":        "Logon",
+		"opcode":      "",
+		"keywords":    "0x8080000000000000",
+		"user_sid":    "S-1-5-18",
+		"process_id":  "812",
+		"thread_id":   "4120",
+		"data.param1": "Print Spooler",
+		"data.param2": "%%1053",
+	}
+	for key, value := range expected {
+		if got, ok := logEntry.Metadata[key]; got != value || ok != (value != "") {
+			t.Errorf("Expected metadata %s=%q, got %q", key, value, got)
+		}
+	}
+
+	// Without a registered provider there is no formatted message
+	logEntry, err = buildLogFromEvent(eventXML(43, "4", "0x8080000000000000", ""), "windows")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "Service Control Manager event 7000: param1=Print Spooler, param2=%%1053"; logEntry.Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, logEntry.Message)
+	}
+	if logEntry.Metadata["task"] != "0" {
+		t.Errorf("Expected the numeric task without rendering info, got %q", logEntry.Metadata["task"])
+	}
+
+	if _, err := buildLogFromEvent("<Event><System>", "windows"); err == nil {
+		t.Error("Expected an error for truncated XML")
+	}
+}
+
+func TestMapLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		keywords string
+		expected string
+	}{
+		{"1", "", "error"}, // Critical, not in the default vocabulary
+		{"2", "", "error"},
+		{"3", "", "warn"},
+		{"4", "", "info"},
+		{"5", "", "debug"},
+		{"0", "0x8010000000000000", "warn"}, // Audit failure
+		{"0", "0x8020000000000000", "info"}, // Audit success
+		{"", "", "info"},
+	}
+
+	for _, tt := range tests {
+		if got := mapLevel(tt.level, tt.keywords); got != tt.expected {
+			t.Errorf("Level %q keywords %q: expected %s, got %s", tt.level, tt.keywords, tt.expected, got)
+		}
+	}
+
+	// Levels added to the vocabulary take precedence over the built-in mapping
+	defer func() { _ = core.SetLevels(core.LevelsConfig{}) }()
+	if err := core.SetLevels(core.LevelsConfig{Order: []string{"debug", "info", "warn", "error", "critical"}}); err != nil {
+		t.Fatalf("Failed to set levels: %v", err)
+	}
+	if got := mapLevel("1", ""); got != "critical" {
+		t.Errorf("Expected critical, got %s", got)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	query := buildQuery([]string{"System", "Microsoft-Windows-PowerShell/Operational"}, "*[System[Level<=3]]")
+	expected := `<QueryList><Query Id="0">` +
+		`<Select Path="System">*[System[Level&lt;=3]]</Select>` +
+		`<Select Path="Microsoft-Windows-PowerShell/Operational">*[System[Level&lt;=3]]</Select>` +
+		`</Query></QueryList>`
+	if query != expected {
+		t.Errorf("Expected %s, got %s", expected, query)
+	}
+
+	if query := buildQuery([]string{"Application"}, ""); !strings.Contains(query, `<Select Path="Application">*</Select>`) {
+		t.Errorf("Expected every event to be selected, got %s", query)
+	}
+}
+
+// fakeEventLog is an event log shared by the subscriptions of successive runs
+type fakeEventLog struct {
+	mu        sync.Mutex
+	events    []string // Record i+1 at index i
+	bookmarks []string // Bookmark passed to each subscribe
+}
+
+func (f *fakeEventLog) write(count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for range count {
+		f.events = append(f.events, eventXML(len(f.events)+1, "4", "", fmt.Sprintf("event %d", len(f.events)+1)))
+	}
+}
+
+func (f *fakeEventLog) subscribe(query, bookmark string) (subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bookmarks = append(f.bookmarks, bookmark)
+
+	s := &fakeSubscription{log: f, next: len(f.events)} // New events only
+	if bookmark != "" {
+		if _, err := fmt.Sscanf(bookmark, `<BookmarkList><Bookmark RecordId="%d"/></BookmarkList>`, &s.next); err != nil {
+			return nil, err
+		}
+	}
+	s.acked = s.next
+	return s, nil
+}
+
+// fakeSubscription reads a fakeEventLog; its bookmark is the last acknowledged record
+type fakeSubscription struct {
+	log        *fakeEventLog
+	next       int // Index of the next event to read
+	batchStart int
+	acked      int
+}
+
+func (s *fakeSubscription) Next(ctx context.Context) ([]string, error) {
+	for {
+		s.log.mu.Lock()
+		events := s.log.events[s.next:]
+		s.log.mu.Unlock()
+		if len(events) > 0 {
+			s.batchStart = s.next
+			s.next += len(events)
+			return events, nil
+		}
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *fakeSubscription) Ack(n int) error {
+	s.acked = s.batchStart + n
+	return nil
+}
+
+func (s *fakeSubscription) Bookmark() (string, error) {
+	return fmt.Sprintf(`<BookmarkList><Bookmark RecordId="%d"/></BookmarkList>`, s.acked), nil
+}
+
+func (s *fakeSubscription) Close() error {
+	return nil
+}
+
+// receive reads count logs from ch
+func receive(t *testing.T, ch <-chan *core.Log, count int) []string {
+	t.Helper()
+	var messages []string
+	for range count {
+		select {
+		case logEntry := <-ch:
+			messages = append(messages, logEntry.Message)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for logs, got %v", messages)
+		}
+	}
+	return messages
+}
+
+func TestWinEventLogInputResumesFromBookmark(t *testing.T) {
+	eventLog := &fakeEventLog{}
+	eventLog.write(2) // Written before the first start, never read
+	bookmarkPath := filepath.Join(t.TempDir(), "state", "bookmark.xml")
+
+	run := func(expected []string) {
+		input, err := newWinEventLogInput(Config{Channels: []string{"System"}, BookmarkPath: bookmarkPath}, eventLog.subscribe)
+		if err != nil {
+			t.Fatalf("Failed to create input: %v", err)
+		}
+		ch := make(chan *core.Log, 10)
+		input.SetLogChannel(ch)
+		if err := input.Start(); err != nil {
+			t.Fatalf("Failed to start input: %v", err)
+		}
+		eventLog.write(2)
+		if got := receive(t, ch, len(expected)); strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+		if err := input.Stop(); err != nil {
+			t.Fatalf("Failed to stop input: %v", err)
+		}
+	}
+
+	run([]string{"event 3", "event 4"})
+	eventLog.write(1) // Written while stopped
+	run([]string{"event 5", "event 6", "event 7"})
+
+	if len(eventLog.bookmarks) != 2 || eventLog.bookmarks[0] != "" || !strings.Contains(eventLog.bookmarks[1], `RecordId="4"`) {
+		t.Errorf("Expected the second run to resume after record 4, got bookmarks %q", eventLog.bookmarks)
+	}
+	data, err := os.ReadFile(bookmarkPath)
+	if err != nil || !strings.Contains(string(data), `RecordId="7"`) {
+		t.Errorf("Expected the bookmark file to point at record 7, got %q (%v)", data, err)
+	}
+}
+
+func TestWinEventLogInputInvalidBookmark(t *testing.T) {
+	eventLog := &fakeEventLog{}
+	bookmarkPath := filepath.Join(t.TempDir(), "bookmark.xml")
+	if err := os.WriteFile(bookmarkPath, []byte("not a bookmark"), 0600); err != nil {
+		t.Fatalf("Failed to write bookmark: %v", err)
+	}
+
+	input, err := newWinEventLogInput(Config{BookmarkPath: bookmarkPath}, eventLog.subscribe)
+	if err != nil {
+		t.Fatalf("Failed to create input: %v", err)
+	}
+	input.SetLogChannel(make(chan *core.Log, 10))
+	if err := input.Start(); err != nil {
+		t.Fatalf("Expected the input to start without the bookmark, got %v", err)
+	}
+	defer func() { _ = input.Stop() }()
+
+	if len(eventLog.bookmarks) != 2 || eventLog.bookmarks[1] != "" {
+		t.Errorf("Expected a retry without the bookmark, got %q", eventLog.bookmarks)
+	}
+	if !strings.Contains(input.query, `<Select Path="Application">*</Select><Select Path="System">*</Select>`) {
+		t.Errorf("Expected the default channels, got %s", input.query)
+	}
+}
+
+func TestNewWinEventLogInputConfig(t *testing.T) {
+	if _, err := newWinEventLogInput(Config{Channels: []string{"System", " "}}, nil); err == nil {
+		t.Error("Expected blank channels to be rejected")
+	}
+
+	_, err := NewWinEventLogInputFromConfig(map[string]any{"channels": []any{"System"}})
+	if runtime.GOOS != "windows" && (err == nil || !strings.Contains(err.Error(), "only supported on Windows")) {
+		t.Errorf("Expected the input to be unavailable on %s, got %v", runtime.GOOS, err)
+	}
+}
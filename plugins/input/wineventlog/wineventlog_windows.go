@@ -0,0 +1,280 @@
+//go:build windows
+
+package wineventlog
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const supported = true
+
+var (
+	wevtapi = windows.NewLazySystemDLL("wevtapi.dll")
+
+	procEvtSubscribe             = wevtapi.NewProc("EvtSubscribe")
+	procEvtNext                  = wevtapi.NewProc("EvtNext")
+	procEvtRender                = wevtapi.NewProc("EvtRender")
+	procEvtFormatMessage         = wevtapi.NewProc("EvtFormatMessage")
+	procEvtOpenPublisherMetadata = wevtapi.NewProc("EvtOpenPublisherMetadata")
+	procEvtCreateBookmark        = wevtapi.NewProc("EvtCreateBookmark")
+	procEvtUpdateBookmark        = wevtapi.NewProc("EvtUpdateBookmark")
+	procEvtClose                 = wevtapi.NewProc("EvtClose")
+)
+
+// Event Log API flags, see winevt.h
+const (
+	evtSubscribeToFutureEvents     = 1
+	evtSubscribeStartAfterBookmark = 3
+	evtRenderEventXML              = 1
+	evtRenderBookmark              = 2
+	evtFormatMessageXML            = 9
+)
+
+const (
+	batchSize    = 100 // Events read per EvtNext call
+	waitInterval = 500 // Milliseconds between checks for Stop while waiting for events
+)
+
+// evtHandle is an Event Log API handle, released with EvtClose
+type evtHandle uintptr
+
+func (h evtHandle) close() {
+	if h != 0 {
+		_, _, _ = procEvtClose.Call(uintptr(h))
+	}
+}
+
+// winSubscription is a pull subscription: the system sets signal when events
+// arrive and Next reads them with EvtNext
+type winSubscription struct {
+	handle     evtHandle
+	signal     windows.Handle
+	bookmark   evtHandle
+	batch      []evtHandle          // Events returned by the last Next, released by the next one
+	publishers map[string]evtHandle // Publisher metadata by provider, 0 when it cannot be opened
+}
+
+// subscribe opens a pull subscription to a structured query, after the bookmark
+// XML when it is not empty and at new events otherwise
+func subscribe(query, bookmark string) (subscription, error) {
+	s := &winSubscription{publishers: make(map[string]evtHandle)}
+
+	var err error
+	if s.bookmark, err = createBookmark(bookmark); err != nil {
+		return nil, err
+	}
+
+	s.signal, err = windows.CreateEvent(nil, 1, 1, nil)
+	if err != nil {
+		s.bookmark.close()
+		return nil, fmt.Errorf("failed to create signal event: %w", err)
+	}
+
+	queryPtr, err := windows.UTF16PtrFromString(query)
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	flags := uintptr(evtSubscribeToFutureEvents)
+	bookmarkHandle := uintptr(0)
+	if bookmark != "" {
+		flags = evtSubscribeStartAfterBookmark
+		bookmarkHandle = uintptr(s.bookmark)
+	}
+
+	handle, _, err := procEvtSubscribe.Call(0, uintptr(s.signal), 0, uintptr(unsafe.Pointer(queryPtr)), bookmarkHandle, 0, 0, flags)
+	if handle == 0 {
+		_ = s.Close()
+		return nil, fmt.Errorf("EvtSubscribe failed: %w", err)
+	}
+	s.handle = evtHandle(handle)
+	return s, nil
+}
+
+// createBookmark creates a bookmark from its XML, or an empty one when data is empty
+func createBookmark(data string) (evtHandle, error) {
+	var xmlPtr *uint16
+	if data != "" {
+		var err error
+		if xmlPtr, err = windows.UTF16PtrFromString(data); err != nil {
+			return 0, err
+		}
+	}
+	handle, _, err := procEvtCreateBookmark.Call(uintptr(unsafe.Pointer(xmlPtr)))
+	if handle == 0 {
+		return 0, fmt.Errorf("EvtCreateBookmark failed: %w", err)
+	}
+	return evtHandle(handle), nil
+}
+
+// Next implements subscription. The signal is reset before reading, so events
+// that arrive after EvtNext reports none set it again and are not missed.
+func (s *winSubscription) Next(ctx context.Context) ([]string, error) {
+	s.releaseBatch()
+
+	for {
+		if err := windows.ResetEvent(s.signal); err != nil {
+			return nil, fmt.Errorf("failed to reset signal event: %w", err)
+		}
+
+		handles := make([]evtHandle, batchSize)
+		var returned uint32
+		ok, _, err := procEvtNext.Call(uintptr(s.handle), batchSize, uintptr(unsafe.Pointer(&handles[0])), 0, 0, uintptr(unsafe.Pointer(&returned)))
+		if ok != 0 {
+			s.batch = handles[:returned]
+			return s.renderBatch()
+		}
+		if !errors.Is(err, windows.ERROR_NO_MORE_ITEMS) && !errors.Is(err, windows.ERROR_TIMEOUT) {
+			return nil, fmt.Errorf("EvtNext failed: %w", err)
+		}
+
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until the signal is set or ctx is done
+func (s *winSubscription) wait(ctx context.Context) error {
+	for {
+		event, err := windows.WaitForSingleObject(s.signal, waitInterval)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch event {
+		case windows.WAIT_OBJECT_0:
+			return nil
+		case uint32(windows.WAIT_TIMEOUT):
+		default:
+			return fmt.Errorf("failed to wait for events: %w", err)
+		}
+	}
+}
+
+// renderBatch renders the XML of every event in the batch. An event that cannot
+// be rendered is left empty, so it is skipped but still acknowledged.
+func (s *winSubscription) renderBatch() ([]string, error) {
+	events := make([]string, 0, len(s.batch))
+	for _, handle := range s.batch {
+		data, err := s.renderEvent(handle)
+		if err != nil {
+			logger.Printf("Skipping event that cannot be rendered: %v", err)
+		}
+		events = append(events, data)
+	}
+	return events, nil
+}
+
+// renderEvent returns the event's XML, with its formatted message when the
+// provider's metadata is available
+func (s *winSubscription) renderEvent(handle evtHandle) (string, error) {
+	data, err := render(handle, evtRenderEventXML)
+	if err != nil {
+		return "", err
+	}
+
+	var e event
+	if err := xml.Unmarshal([]byte(data), &e); err != nil || e.System.Provider.Name == "" {
+		return data, nil
+	}
+	provider := e.System.Provider.Name
+	publisher, ok := s.publishers[provider]
+	if !ok {
+		publisher = openPublisher(provider)
+		s.publishers[provider] = publisher
+	}
+	if publisher == 0 {
+		return data, nil
+	}
+	if formatted, err := formatMessage(publisher, handle); err == nil {
+		return formatted, nil
+	}
+	return data, nil
+}
+
+// Ack implements subscription
+func (s *winSubscription) Ack(n int) error {
+	for _, handle := range s.batch[:n] {
+		if ok, _, err := procEvtUpdateBookmark.Call(uintptr(s.bookmark), uintptr(handle)); ok == 0 {
+			return fmt.Errorf("EvtUpdateBookmark failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Bookmark implements subscription
+func (s *winSubscription) Bookmark() (string, error) {
+	return render(s.bookmark, evtRenderBookmark)
+}
+
+// Close implements subscription
+func (s *winSubscription) Close() error {
+	s.releaseBatch()
+	s.handle.close()
+	s.bookmark.close()
+	for _, publisher := range s.publishers {
+		publisher.close()
+	}
+	if s.signal != 0 {
+		return windows.CloseHandle(s.signal)
+	}
+	return nil
+}
+
+// releaseBatch closes the event handles of the last batch
+func (s *winSubscription) releaseBatch() {
+	for _, handle := range s.batch {
+		handle.close()
+	}
+	s.batch = nil
+}
+
+// render renders an event or bookmark as XML
+func render(handle evtHandle, flags uintptr) (string, error) {
+	var used, count uint32
+	ok, _, err := procEvtRender.Call(0, uintptr(handle), flags, 0, 0, uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&count)))
+	if ok == 0 && !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+		return "", fmt.Errorf("EvtRender failed: %w", err)
+	}
+
+	buffer := make([]uint16, used/2+1)
+	ok, _, err = procEvtRender.Call(0, uintptr(handle), flags, uintptr(len(buffer)*2), uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&count)))
+	if ok == 0 {
+		return "", fmt.Errorf("EvtRender failed: %w", err)
+	}
+	return windows.UTF16ToString(buffer), nil
+}
+
+// openPublisher opens the metadata of a provider, used to format its messages.
+// It returns 0 when the provider is not registered on this host.
+func openPublisher(provider string) evtHandle {
+	providerPtr, err := windows.UTF16PtrFromString(provider)
+	if err != nil {
+		return 0
+	}
+	handle, _, _ := procEvtOpenPublisherMetadata.Call(0, uintptr(unsafe.Pointer(providerPtr)), 0, 0, 0)
+	return evtHandle(handle)
+}
+
+// formatMessage renders the event's XML with a RenderingInfo element holding the
+// formatted message and the names of its level, task and opcode
+func formatMessage(publisher, handle evtHandle) (string, error) {
+	var used uint32
+	ok, _, err := procEvtFormatMessage.Call(uintptr(publisher), uintptr(handle), 0, 0, 0, evtFormatMessageXML, 0, 0, uintptr(unsafe.Pointer(&used)))
+	if ok == 0 && !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+		return "", fmt.Errorf("EvtFormatMessage failed: %w", err)
+	}
+
+	buffer := make([]uint16, used+1) // EvtFormatMessage counts characters, not bytes
+	ok, _, err = procEvtFormatMessage.Call(uintptr(publisher), uintptr(handle), 0, 0, 0, evtFormatMessageXML, uintptr(len(buffer)), uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&used)))
+	if ok == 0 {
+		return "", fmt.Errorf("EvtFormatMessage failed: %w", err)
+	}
+	return windows.UTF16ToString(buffer), nil
+}
@@ -0,0 +1,434 @@
+package aggregate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.aggregate")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("aggregate", NewAggregateOutputFromConfig)
+}
+
+const (
+	defaultWindow      = time.Minute
+	defaultMaxGroups   = 1000
+	defaultMaxDistinct = 1000
+
+	// overflowValue replaces every group_by value of logs that arrive once max_groups is reached
+	overflowValue = "_overflow"
+)
+
+// reservedKeys are summary metadata keys set by the plugin itself
+var reservedKeys = map[string]bool{"window_start": true, "window_end": true, "partial": true, "overflow": true}
+
+// Config represents aggregate output configuration
+type Config struct {
+	Window      time.Duration  `yaml:"window,omitempty"`       // Rollup period, aligned to the clock (default: 1m)
+	GroupBy     logkey.Spec    `yaml:"group_by,omitempty"`     // Key spec grouping the logs; each part's name labels its value
+	Metrics     []MetricConfig `yaml:"metrics,omitempty"`      // Values computed per group (default: count)
+	MaxGroups   int            `yaml:"max_groups,omitempty"`   // Groups per window; later combinations share one overflow group (default: 1000)
+	MaxDistinct int            `yaml:"max_distinct,omitempty"` // Distinct values tracked per metric and group (default: 1000)
+	Output      ChildConfig    `yaml:"output"`                 // Output that receives the summaries
+}
+
+// MetricConfig describes one value computed per group
+type MetricConfig struct {
+	Type  string `yaml:"type"`            // "count" or "distinct"
+	Field string `yaml:"field,omitempty"` // distinct: key source whose distinct values are counted
+	Name  string `yaml:"name,omitempty"`  // Summary metadata key (default: "count" or "distinct_<field>")
+}
+
+// ChildConfig is the output the summaries are written to
+type ChildConfig struct {
+	Type   string         `yaml:"type"`
+	Config map[string]any `yaml:"config,omitempty"`
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.Window < 0 {
+		return fmt.Errorf("window must be non-negative")
+	}
+	if c.MaxGroups < 0 || c.MaxDistinct < 0 {
+		return fmt.Errorf("max_groups and max_distinct must be non-negative")
+	}
+	if c.Output.Type == "" {
+		return fmt.Errorf("output.type is required")
+	}
+
+	if c.Window == 0 {
+		c.Window = defaultWindow
+	}
+	if c.MaxGroups == 0 {
+		c.MaxGroups = defaultMaxGroups
+	}
+	if c.MaxDistinct == 0 {
+		c.MaxDistinct = defaultMaxDistinct
+	}
+	if len(c.Metrics) == 0 {
+		c.Metrics = []MetricConfig{{Type: "count"}}
+	}
+
+	used := make(map[string]bool)
+	if len(c.GroupBy) > 0 {
+		key, err := logkey.Compile(c.GroupBy)
+		if err != nil {
+			return fmt.Errorf("invalid group_by: %w", err)
+		}
+		for _, name := range key.Names() {
+			if used[name] || reservedKeys[name] {
+				return fmt.Errorf("group_by field %q is duplicated or reserved", name)
+			}
+			used[name] = true
+		}
+	}
+	for i := range c.Metrics {
+		metric := &c.Metrics[i]
+		switch metric.Type {
+		case "count":
+			if metric.Name == "" {
+				metric.Name = "count"
+			}
+		case "distinct":
+			if metric.Field == "" {
+				return fmt.Errorf("distinct metric requires a field")
+			}
+			key, err := logkey.Compile(logkey.Sources(metric.Field))
+			if err != nil {
+				return fmt.Errorf("invalid distinct field: %w", err)
+			}
+			if metric.Name == "" {
+				metric.Name = "distinct_" + key.Names()[0]
+			}
+		default:
+			return fmt.Errorf("invalid metric type '%s', must be 'count' or 'distinct'", metric.Type)
+		}
+		if used[metric.Name] || reservedKeys[metric.Name] {
+			return fmt.Errorf("metric name %q is duplicated or reserved", metric.Name)
+		}
+		used[metric.Name] = true
+	}
+	return nil
+}
+
+// NewAggregateOutputFromConfig creates an aggregate output from configuration map
+func NewAggregateOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewAggregateOutput(cfg)
+}
+
+// AggregateOutput rolls logs up into one summary per group and window and writes
+// the summaries to a child output when the window closes. Windows are aligned to
+// the clock (a 1m window covers 12:00:00-12:01:00) and logs are assigned to the
+// window they arrive in, whatever their timestamp.
+type AggregateOutput struct {
+	config     Config
+	child      core.OutputPlugin
+	groupKey   *logkey.Key   // Computes the group of a log (nil without group_by: a single group)
+	labels     []string      // Summary metadata key of each group_by value
+	metricKeys []*logkey.Key // Per metric: the field a distinct metric counts (nil for count metrics)
+
+	mu          sync.Mutex
+	windowStart time.Time
+	groups      map[string]*group
+	overflow    *group // Logs of combinations beyond max_groups (nil until needed)
+	closed      bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	// Counters reported through OutputStats
+	summaries    int64
+	overflowLogs int64
+	childErrors  int64
+}
+
+// group accumulates the logs of one group_by combination
+type group struct {
+	values   []string              // group_by values, in config order
+	count    int64                 // Logs in the group
+	level    string                // Most severe level seen
+	distinct []map[string]struct{} // Distinct values per metric (nil for count metrics)
+	capped   []bool                // Per metric: values beyond max_distinct were ignored
+	overflow bool                  // Logs folded in after max_groups was reached
+}
+
+// NewAggregateOutput creates a new aggregate output and its child output
+func NewAggregateOutput(config Config) (*AggregateOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	child, err := core.CreateOutputPlugin(config.Output.Type, config.Output.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s child output: %w", config.Output.Type, err)
+	}
+
+	a := &AggregateOutput{
+		config:      config,
+		child:       child,
+		metricKeys:  make([]*logkey.Key, len(config.Metrics)),
+		windowStart: time.Now().Truncate(config.Window),
+		groups:      make(map[string]*group),
+		stopCh:      make(chan struct{}),
+	}
+	if len(config.GroupBy) > 0 {
+		a.groupKey = logkey.MustCompile(config.GroupBy)
+		a.labels = a.groupKey.Names()
+	}
+	for i, metric := range config.Metrics {
+		if metric.Type == "distinct" {
+			a.metricKeys[i] = logkey.MustCompile(logkey.Sources(metric.Field))
+		}
+	}
+
+	a.wg.Add(1)
+	go a.run()
+
+	return a, nil
+}
+
+// Write adds a log to its group in the current window
+func (a *AggregateOutput) Write(logEntry *core.Log) error {
+	var key string
+	if a.groupKey != nil {
+		key = a.groupKey.Of(logEntry)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return fmt.Errorf("aggregate output is closed")
+	}
+
+	g, ok := a.groups[key]
+	switch {
+	case ok:
+	case len(a.groups) < a.config.MaxGroups:
+		g = a.newGroup(a.groupValues(logEntry))
+		a.groups[key] = g
+	default:
+		// Bound memory: fold new combinations into a single overflow group
+		a.overflowLogs++
+		if a.overflow == nil {
+			values := make([]string, len(a.labels))
+			for i := range values {
+				values[i] = overflowValue
+			}
+			a.overflow = a.newGroup(values)
+			a.overflow.overflow = true
+		}
+		g = a.overflow
+	}
+
+	g.count++
+	if rank, ok := core.Levels().Severity(logEntry.Level); ok {
+		if current, known := core.Levels().Severity(g.level); !known || rank > current {
+			g.level = core.Levels().Normalize(logEntry.Level)
+		}
+	}
+	for i, metricKey := range a.metricKeys {
+		if g.distinct[i] == nil {
+			continue
+		}
+		value := metricKey.Of(logEntry)
+		if _, seen := g.distinct[i][value]; seen {
+			continue
+		}
+		if len(g.distinct[i]) >= a.config.MaxDistinct {
+			g.capped[i] = true
+			continue
+		}
+		g.distinct[i][value] = struct{}{}
+	}
+	return nil
+}
+
+// newGroup creates an empty group with a distinct set per distinct metric
+func (a *AggregateOutput) newGroup(values []string) *group {
+	g := &group{
+		values:   values,
+		distinct: make([]map[string]struct{}, len(a.config.Metrics)),
+		capped:   make([]bool, len(a.config.Metrics)),
+	}
+	for i, metric := range a.config.Metrics {
+		if metric.Type == "distinct" {
+			g.distinct[i] = make(map[string]struct{})
+		}
+	}
+	return g
+}
+
+// run closes each window at its aligned end until the output is closed
+func (a *AggregateOutput) run() {
+	defer a.wg.Done()
+
+	for {
+		a.mu.Lock()
+		end := a.windowStart.Add(a.config.Window)
+		a.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(end))
+		select {
+		case <-timer.C:
+			a.closeWindow(end, false)
+		case <-a.stopCh:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// closeWindow starts a new window and emits the summaries of the previous one
+func (a *AggregateOutput) closeWindow(end time.Time, partial bool) {
+	a.mu.Lock()
+	start := a.windowStart
+	groups := a.groups
+	overflow := a.overflow
+	a.groups = make(map[string]*group)
+	a.overflow = nil
+	a.windowStart = time.Now().Truncate(a.config.Window)
+	a.mu.Unlock()
+
+	a.emit(groups, overflow, start, end, partial)
+}
+
+// emit writes one summary per group to the child output, in group order, then the overflow group
+func (a *AggregateOutput) emit(groups map[string]*group, overflow *group, start, end time.Time, partial bool) {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ordered := make([]*group, 0, len(groups)+1)
+	for _, key := range keys {
+		ordered = append(ordered, groups[key])
+	}
+	if overflow != nil {
+		ordered = append(ordered, overflow)
+	}
+
+	for _, g := range ordered {
+		summary := a.summarize(g, start, end, partial)
+		err := a.child.Write(summary)
+
+		a.mu.Lock()
+		if err != nil {
+			a.childErrors++
+		} else {
+			a.summaries++
+		}
+		a.mu.Unlock()
+
+		if err != nil {
+			logger.Printf("Failed to write summary to %s output: %v", a.config.Output.Type, err)
+		}
+	}
+}
+
+// summarize builds the summary log of a group
+func (a *AggregateOutput) summarize(g *group, start, end time.Time, partial bool) *core.Log {
+	metadata := map[string]string{
+		"window_start": start.UTC().Format(time.RFC3339Nano),
+		"window_end":   end.UTC().Format(time.RFC3339Nano),
+	}
+	labels := make([]string, len(a.labels))
+	for i, label := range a.labels {
+		metadata[label] = g.values[i]
+		labels[i] = label + "=" + g.values[i]
+	}
+	for i, metric := range a.config.Metrics {
+		switch metric.Type {
+		case "count":
+			metadata[metric.Name] = strconv.FormatInt(g.count, 10)
+		case "distinct":
+			metadata[metric.Name] = strconv.Itoa(len(g.distinct[i]))
+			if g.capped[i] {
+				metadata[metric.Name+"_capped"] = "true"
+			}
+		}
+	}
+	if partial {
+		metadata["partial"] = "true"
+	}
+	if g.overflow {
+		metadata["overflow"] = "true"
+	}
+
+	level := g.level
+	if level == "" {
+		level = core.Levels().Default()
+	}
+
+	message := fmt.Sprintf("%d logs in %s", g.count, end.Sub(start).Round(time.Millisecond))
+	if len(labels) > 0 {
+		message += " (" + strings.Join(labels, ", ") + ")"
+	}
+
+	summary := core.NewLogWithMetadata(level, message, metadata)
+	summary.Timestamp = end
+	summary.SourceType = "aggregate"
+	summary.AddTags("aggregate")
+	return summary
+}
+
+// groupValues returns the group_by values of a log, in config order
+func (a *AggregateOutput) groupValues(logEntry *core.Log) []string {
+	if a.groupKey == nil {
+		return nil
+	}
+	return a.groupKey.Values(make([]string, 0, a.groupKey.Len()), logEntry)
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (a *AggregateOutput) OutputStats() map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	active := len(a.groups)
+	if a.overflow != nil {
+		active++
+	}
+	return map[string]any{
+		"active_groups":     active,
+		"summaries_emitted": a.summaries,
+		"overflow_logs":     a.overflowLogs,
+		"child_errors":      a.childErrors,
+	}
+}
+
+// Close emits the current window as a partial summary and closes the child output
+func (a *AggregateOutput) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	close(a.stopCh)
+	a.wg.Wait()
+
+	// No window is in progress on the ticker any more, so this is the last emit
+	a.closeWindow(time.Now(), true)
+	return a.child.Close()
+}
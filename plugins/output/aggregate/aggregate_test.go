@@ -0,0 +1,241 @@
+package aggregate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+// captureOutput records the summaries written by the aggregate output
+type captureOutput struct {
+	mu     sync.Mutex
+	logs   []*core.Log
+	closed bool
+}
+
+var captured = &captureOutput{}
+
+func init() {
+	core.RegisterOutputPlugin("aggregate_test_capture", func(map[string]any) (any, error) {
+		captured.mu.Lock()
+		defer captured.mu.Unlock()
+		captured.logs = nil
+		captured.closed = false
+		return captured, nil
+	})
+}
+
+func (c *captureOutput) Write(log *core.Log) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logs = append(c.logs, log)
+	return nil
+}
+
+func (c *captureOutput) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *captureOutput) summaries() []*core.Log {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*core.Log(nil), c.logs...)
+}
+
+func newTestLog(level, service, user string) *core.Log {
+	return core.NewLogWithMetadata(level, "request", map[string]string{"service": service, "user": user})
+}
+
+func TestAggregateOutputGroupsAndFlushesOnClose(t *testing.T) {
+	output, err := NewAggregateOutput(Config{
+		Window:  time.Hour,
+		GroupBy: logkey.Sources("service"),
+		Metrics: []MetricConfig{{Type: "count"}, {Type: "distinct", Field: "user", Name: "users"}},
+		Output:  ChildConfig{Type: "aggregate_test_capture"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+
+	for _, logEntry := range []*core.Log{
+		newTestLog("info", "api", "alice"),
+		newTestLog("error", "api", "bob"),
+		newTestLog("warn", "api", "alice"),
+		newTestLog("info", "billing", "carol"),
+	} {
+		if err := output.Write(logEntry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if len(captured.summaries()) != 0 {
+		t.Fatal("Expected no summaries before the window closes")
+	}
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !captured.closed {
+		t.Error("Expected the child output to be closed")
+	}
+
+	summaries := captured.summaries()
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summaries, got %d", len(summaries))
+	}
+	expected := []struct {
+		service, level, count, users string
+	}{
+		{"api", "error", "3", "2"},
+		{"billing", "info", "1", "1"},
+	}
+	for i, want := range expected {
+		summary := summaries[i]
+		if summary.Metadata["service"] != want.service || summary.Level != want.level ||
+			summary.Metadata["count"] != want.count || summary.Metadata["users"] != want.users {
+			t.Errorf("Summary %d: expected %+v, got level=%s metadata=%v", i, want, summary.Level, summary.Metadata)
+		}
+		if summary.Metadata["partial"] != "true" {
+			t.Errorf("Summary %d: expected partial=true on close, got %v", i, summary.Metadata)
+		}
+	}
+
+	if err := output.Write(newTestLog("info", "api", "alice")); err == nil {
+		t.Error("Expected error writing after close")
+	}
+}
+
+func TestAggregateOutputWindowAlignment(t *testing.T) {
+	window := 100 * time.Millisecond
+	output, err := NewAggregateOutput(Config{
+		Window: window,
+		Output: ChildConfig{Type: "aggregate_test_capture"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	if err := output.Write(newTestLog("info", "api", "alice")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(captured.summaries()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	summaries := captured.summaries()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary after the window closed, got %d", len(summaries))
+	}
+
+	start, err := time.Parse(time.RFC3339Nano, summaries[0].Metadata["window_start"])
+	if err != nil {
+		t.Fatalf("Invalid window_start: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339Nano, summaries[0].Metadata["window_end"])
+	if err != nil {
+		t.Fatalf("Invalid window_end: %v", err)
+	}
+	if !start.Equal(start.Truncate(window)) || end.Sub(start) != window {
+		t.Errorf("Expected a window aligned to %s, got %s - %s", window, start, end)
+	}
+	if summaries[0].Metadata["partial"] != "" || summaries[0].Metadata["count"] != "1" {
+		t.Errorf("Expected a complete window with count 1, got %v", summaries[0].Metadata)
+	}
+}
+
+func TestAggregateOutputBoundsCardinality(t *testing.T) {
+	output, err := NewAggregateOutput(Config{
+		Window:      time.Hour,
+		GroupBy:     logkey.Sources("service"),
+		Metrics:     []MetricConfig{{Type: "count"}, {Type: "distinct", Field: "user"}},
+		MaxGroups:   2,
+		MaxDistinct: 2,
+		Output:      ChildConfig{Type: "aggregate_test_capture"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+
+	for _, logEntry := range []*core.Log{
+		newTestLog("info", "a", "u1"),
+		newTestLog("info", "a", "u2"),
+		newTestLog("info", "a", "u3"),
+		newTestLog("info", "b", "u1"),
+		newTestLog("info", "c", "u1"),
+		newTestLog("info", "d", "u1"),
+	} {
+		if err := output.Write(logEntry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	stats := output.OutputStats()
+	if stats["active_groups"] != 3 || stats["overflow_logs"] != int64(2) {
+		t.Errorf("Expected 3 active groups and 2 overflow logs, got %v", stats)
+	}
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	summaries := captured.summaries()
+	if len(summaries) != 3 {
+		t.Fatalf("Expected 2 groups plus the overflow group, got %d", len(summaries))
+	}
+	if summaries[0].Metadata["distinct_user"] != "2" || summaries[0].Metadata["distinct_user_capped"] != "true" {
+		t.Errorf("Expected distinct users capped at 2, got %v", summaries[0].Metadata)
+	}
+	overflow := summaries[2]
+	if overflow.Metadata["overflow"] != "true" || overflow.Metadata["service"] != overflowValue || overflow.Metadata["count"] != "2" {
+		t.Errorf("Expected overflow group with 2 logs, got %v", overflow.Metadata)
+	}
+}
+
+func TestAggregateOutputValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "missing child output", config: map[string]any{"window": "1m"}},
+		{name: "unknown child output", config: map[string]any{"output": map[string]any{"type": "nope"}}},
+		{name: "negative window", config: map[string]any{"window": "-1m", "output": map[string]any{"type": "null"}}},
+		{name: "unknown metric", config: map[string]any{"metrics": []any{map[string]any{"type": "sum"}}, "output": map[string]any{"type": "null"}}},
+		{name: "distinct without field", config: map[string]any{"metrics": []any{map[string]any{"type": "distinct"}}, "output": map[string]any{"type": "null"}}},
+		{name: "metric shadows group field", config: map[string]any{"group_by": []any{"count"}, "output": map[string]any{"type": "null"}}},
+		{name: "reserved group field", config: map[string]any{"group_by": []any{"window_start"}, "output": map[string]any{"type": "null"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAggregateOutputFromConfig(tt.config); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestAggregateOutputFromConfig(t *testing.T) {
+	plugin, err := NewAggregateOutputFromConfig(map[string]any{
+		"window":   "30s",
+		"group_by": []any{"level", "service"},
+		"output":   map[string]any{"type": "aggregate_test_capture"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := plugin.(*AggregateOutput)
+	defer func() { _ = output.Close() }()
+
+	if output.config.Window != 30*time.Second {
+		t.Errorf("Expected 30s window, got %s", output.config.Window)
+	}
+	if output.config.MaxGroups != defaultMaxGroups || len(output.config.Metrics) != 1 || output.config.Metrics[0].Name != "count" {
+		t.Errorf("Expected defaults applied, got %+v", output.config)
+	}
+}
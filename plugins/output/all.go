@@ -1,9 +1,18 @@
 package output
 
 import (
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/aggregate"
 	_ "github.com/mbiondo/logAnalyzer/plugins/output/console"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/datadog"
 	_ "github.com/mbiondo/logAnalyzer/plugins/output/elasticsearch"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/email"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/fallback"
 	_ "github.com/mbiondo/logAnalyzer/plugins/output/file"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/grpc"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/null"
 	_ "github.com/mbiondo/logAnalyzer/plugins/output/prometheus"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/redis_stream"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/shard"
 	_ "github.com/mbiondo/logAnalyzer/plugins/output/slack"
+	_ "github.com/mbiondo/logAnalyzer/plugins/output/syslog"
 )
@@ -17,7 +17,9 @@ func init() {
 // Config represents console output configuration
 type Config struct {
 	Target string `yaml:"target,omitempty"` // "stdout" or "stderr"
-	Format string `yaml:"format,omitempty"` // "text" or "json"
+	Format string `yaml:"format,omitempty"` // "text", "json", or "raw" (alias: "passthrough")
+
+	core.FieldStyle `yaml:",inline"` // Metadata rendering for the json format
 }
 
 // NewConsoleOutputFromConfig creates a console output from configuration map
@@ -34,6 +36,7 @@ func NewConsoleOutputFromConfig(config map[string]any) (any, error) {
 type ConsoleOutput struct {
 	config     Config
 	writer     io.Writer
+	encoder    *core.LogEncoder
 	closeMutex sync.Mutex
 	closed     bool
 }
@@ -59,15 +62,26 @@ func NewConsoleOutput(config Config) (*ConsoleOutput, error) {
 		return nil, fmt.Errorf("invalid target '%s', must be 'stdout' or 'stderr'", config.Target)
 	}
 
+	// "passthrough" is an alias for "raw"
+	if config.Format == "passthrough" {
+		config.Format = "raw"
+	}
+
 	// Validate format
-	if config.Format != "text" && config.Format != "json" {
-		return nil, fmt.Errorf("invalid format '%s', must be 'text' or 'json'", config.Format)
+	if config.Format != "text" && config.Format != "json" && config.Format != "raw" {
+		return nil, fmt.Errorf("invalid format '%s', must be 'text', 'json' or 'raw'", config.Format)
+	}
+
+	encoder, err := core.NewLogEncoder(config.FieldStyle, "timestamp")
+	if err != nil {
+		return nil, err
 	}
 
 	return &ConsoleOutput{
-		config: config,
-		writer: writer,
-		closed: false,
+		config:  config,
+		writer:  writer,
+		encoder: encoder,
+		closed:  false,
 	}, nil
 }
 
@@ -88,17 +102,20 @@ func (c *ConsoleOutput) Write(log *core.Log) error {
 	var output string
 	switch c.config.Format {
 	case "json":
-		// Simple JSON format
-		output = fmt.Sprintf(`{"timestamp":"%s","level":"%s","message":"%s"}`+"\n",
-			log.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
-			log.Level,
-			log.Message)
+		data, err := c.encoder.Marshal(log)
+		if err != nil {
+			return err
+		}
+		output = string(data) + "\n"
 	case "text":
 		// Simple text format
 		output = fmt.Sprintf("[%s] %s: %s\n",
 			log.Timestamp.Format("2006-01-02 15:04:05"),
 			log.Level,
 			log.Message)
+	case "raw":
+		// Verbatim message, no timestamp, level or metadata
+		output = log.Message + "\n"
 	}
 
 	_, err := c.writer.Write([]byte(output))
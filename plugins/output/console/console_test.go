@@ -36,6 +36,13 @@ func TestNewConsoleOutput(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "passthrough format",
+			config: Config{
+				Format: "passthrough",
+			},
+			expectError: false,
+		},
 		{
 			name: "invalid target",
 			config: Config{
@@ -119,17 +126,31 @@ func TestConsoleOutputWrite(t *testing.T) {
 			},
 			expected: `{"timestamp":"2023-01-01T12:00:00Z","level":"info","message":"json test"}` + "\n",
 		},
+		{
+			name: "raw format",
+			config: Config{
+				Format: "raw",
+			},
+			log: &core.Log{
+				Timestamp: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+				Level:     "warn",
+				Message:   `127.0.0.1 - - "GET / HTTP/1.1" 200`,
+				Metadata:  map[string]string{"source": "http"},
+			},
+			expected: `127.0.0.1 - - "GET / HTTP/1.1" 200` + "\n",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			output := &ConsoleOutput{
-				config: tt.config,
-				writer: &buf,
+			output, err := NewConsoleOutput(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
 			}
+			output.writer = &buf
 
-			err := output.Write(tt.log)
+			err = output.Write(tt.log)
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 				return
@@ -0,0 +1,430 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/httpclient"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.datadog")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("datadog", NewDatadogOutputFromConfig)
+}
+
+// Datadog logs intake limits
+const (
+	MaxBatchSize    = 1000            // Entries per request
+	MaxPayloadBytes = 5 * 1000 * 1000 // Uncompressed bytes per request
+	MaxEntryBytes   = 1000 * 1000     // Bytes per entry; longer messages are truncated
+)
+
+const (
+	DefaultBatchSize      = 100   // default entries per request
+	DefaultFlushInterval  = 5     // default seconds between flushes of a partial batch
+	DefaultTimeout        = 30    // default request timeout in seconds
+	DefaultMaxPendingLogs = 10000 // default logs kept while the intake is failing
+	DefaultSource         = "loganalyzer"
+)
+
+// sites maps the site names to their Datadog domain
+var sites = map[string]string{
+	"us":  "datadoghq.com",
+	"us1": "datadoghq.com",
+	"us3": "us3.datadoghq.com",
+	"us5": "us5.datadoghq.com",
+	"eu":  "datadoghq.eu",
+	"ap1": "ap1.datadoghq.com",
+	"gov": "ddog-gov.com",
+}
+
+// Config represents Datadog output configuration
+type Config struct {
+	APIKey        string   `yaml:"api_key"`                  // Required: Datadog API key
+	Site          string   `yaml:"site,omitempty"`           // us (default), us3, us5, eu, ap1 or gov
+	Service       string   `yaml:"service,omitempty"`        // Service of logs without a service metadata value
+	Source        string   `yaml:"source,omitempty"`         // ddsource of every log (default: loganalyzer)
+	Tags          []string `yaml:"tags,omitempty"`           // Tags added to every log ("env:prod", "team:payments")
+	BatchSize     int      `yaml:"batch_size,omitempty"`     // Entries per request, at most 1000 (default: 100)
+	FlushInterval int      `yaml:"flush_interval,omitempty"` // Seconds before a partial batch is sent (default: 5)
+	MaxPending    int      `yaml:"max_pending,omitempty"`    // Logs kept while the intake is failing; the oldest are dropped first (default: 10000)
+	Timeout       int      `yaml:"timeout,omitempty"`        // Request timeout in seconds (default: 30)
+	IntakeURL     string   `yaml:"intake_url,omitempty"`     // Overrides the site's logs intake URL (e.g. a proxy)
+	APIURL        string   `yaml:"api_url,omitempty"`        // Overrides the site's API URL used by the health check
+
+	TLS  tlsconfig.Config  `yaml:"tls,omitempty"`  // TLS configuration
+	HTTP httpclient.Config `yaml:"http,omitempty"` // Connection pooling and proxy settings
+
+	core.LevelFlush `yaml:",inline"` // Early flush of batches holding important logs
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.APIKey == "" {
+		return fmt.Errorf("api_key is required")
+	}
+	if c.BatchSize < 0 || c.BatchSize > MaxBatchSize {
+		return fmt.Errorf("batch_size must be between 1 and %d, got %d", MaxBatchSize, c.BatchSize)
+	}
+	if c.FlushInterval < 0 || c.MaxPending < 0 || c.Timeout < 0 {
+		return fmt.Errorf("flush_interval, max_pending and timeout must be non-negative")
+	}
+	if c.Site == "" {
+		c.Site = "us"
+	}
+	domain, ok := sites[c.Site]
+	if !ok {
+		return fmt.Errorf("invalid site '%s', must be one of us, us3, us5, eu, ap1 or gov", c.Site)
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid TLS config: %w", err)
+	}
+	if err := c.LevelFlush.Validate(); err != nil {
+		return err
+	}
+	for _, tag := range c.Tags {
+		if formatTag(tag) == "" {
+			return fmt.Errorf("invalid tag %q: tags must contain a letter", tag)
+		}
+	}
+
+	if c.BatchSize == 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	if c.MaxPending == 0 {
+		c.MaxPending = DefaultMaxPendingLogs
+	}
+	if c.MaxPending < c.BatchSize {
+		return fmt.Errorf("max_pending (%d) must be at least batch_size (%d)", c.MaxPending, c.BatchSize)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Source == "" {
+		c.Source = DefaultSource
+	}
+	if c.IntakeURL == "" {
+		c.IntakeURL = "https://http-intake.logs." + domain + "/api/v2/logs"
+	}
+	if c.APIURL == "" {
+		c.APIURL = "https://api." + domain
+	}
+	return nil
+}
+
+// NewDatadogOutputFromConfig creates a Datadog output from configuration map
+func NewDatadogOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewDatadogOutput(cfg)
+}
+
+// DatadogOutput sends logs to the Datadog logs intake in batches. A batch is
+// sent once it reaches batch_size, after flush_interval, or early when a log at
+// flush_on_level is added. Requests are split so each stays within the intake's
+// entry and payload limits.
+type DatadogOutput struct {
+	config   Config
+	client   *http.Client
+	hostname string
+	tags     string // Formatted config tags, joined
+
+	levelFlush *core.LevelFlusher
+
+	mu      sync.Mutex // Serializes batching and sending
+	pending [][]byte   // Encoded entries not sent yet, oldest first
+	closed  bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	// Counters reported through OutputStats, guarded by mu
+	sent      int64 // Logs accepted by the intake
+	requests  int64 // Requests sent
+	failures  int64 // Requests that failed or were refused
+	rejected  int64 // Logs refused by the intake as invalid, not retried
+	dropped   int64 // Logs discarded over max_pending or at shutdown
+	truncated int64 // Logs whose message was cut to fit the entry limit
+}
+
+// NewDatadogOutput creates a new Datadog output plugin
+func NewDatadogOutput(config Config) (*DatadogOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Create HTTP client on its own pooled transport
+	client, err := httpclient.New(config.HTTP, time.Duration(config.Timeout)*time.Second, config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	d := &DatadogOutput{
+		config:   config,
+		client:   client,
+		hostname: hostname,
+		tags:     joinTags(nil, config.Tags),
+		stopCh:   make(chan struct{}),
+	}
+	d.levelFlush = core.NewLevelFlusher(config.LevelFlush, func() {
+		if err := d.flush(); err != nil {
+			logger.Printf("Flush failed, %d logs stay pending: %v", d.pendingCount(), err)
+		}
+	})
+
+	d.wg.Add(1)
+	go d.periodicFlush()
+
+	return d, nil
+}
+
+// Write adds a log to the batch and sends the batch once it is full, or when the
+// log triggers flush_on_level. When that request fails, the log is handed back
+// with the error, so the output buffer retries it, while the rest of the batch
+// stays pending for the next flush.
+func (d *DatadogOutput) Write(logEntry *core.Log) error {
+	entry, truncated, err := d.encode(logEntry)
+	if err != nil {
+		return fmt.Errorf("failed to encode log: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return fmt.Errorf("datadog output is closed")
+	}
+	if truncated {
+		d.truncated++
+	}
+
+	d.pending = append(d.pending, entry)
+	if len(d.pending) < d.config.BatchSize && !d.levelFlush.Added(logEntry) {
+		return nil
+	}
+
+	if err := d.sendPending(); err != nil {
+		// Unless its own request was refused, the log just written is still the newest pending entry
+		if n := len(d.pending); n > 0 && &d.pending[n-1][0] == &entry[0] {
+			d.pending = d.pending[:n-1]
+		}
+		return err
+	}
+	return nil
+}
+
+// sendPending sends the pending entries in requests within the intake limits,
+// oldest first, and stops at the first failure. Entries of a failed request
+// that may succeed later stay pending.
+func (d *DatadogOutput) sendPending() error {
+	for len(d.pending) > 0 {
+		n := chunkSize(d.pending, d.config.BatchSize)
+		retry, err := d.send(d.pending[:n])
+		if err != nil {
+			d.failures++
+			if !retry {
+				d.rejected += int64(n)
+				d.pending = d.pending[n:]
+			}
+			d.trimPending()
+			return err
+		}
+		d.sent += int64(n)
+		d.pending = d.pending[n:]
+	}
+	d.pending = nil
+	return nil
+}
+
+// chunkSize returns how many of the first entries fit in one request
+func chunkSize(entries [][]byte, batchSize int) int {
+	size := 2 // Brackets of the JSON array
+	for i, entry := range entries {
+		if i == batchSize || (i > 0 && size+1+len(entry) > MaxPayloadBytes) {
+			return i
+		}
+		size += len(entry) + 1
+	}
+	return len(entries)
+}
+
+// trimPending drops the oldest entries over max_pending
+func (d *DatadogOutput) trimPending() {
+	if over := len(d.pending) - d.config.MaxPending; over > 0 {
+		d.dropped += int64(over)
+		d.pending = d.pending[over:]
+		logger.Printf("Dropped %d logs over max_pending (%d) while the intake is failing", over, d.config.MaxPending)
+	}
+}
+
+// send posts entries as one JSON array. retry reports whether a failed request
+// may succeed later: transport errors, 408, 429 and 5xx responses.
+func (d *DatadogOutput) send(entries [][]byte) (retry bool, err error) {
+	body := make([]byte, 0, chunkBytes(entries))
+	body = append(body, '[')
+	for i, entry := range entries {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, entry...)
+	}
+	body = append(body, ']')
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.config.Timeout)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.IntakeURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", d.config.APIKey)
+
+	d.requests++
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send logs to Datadog: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retry := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("datadog intake returned status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return false, nil
+}
+
+// chunkBytes returns the size of entries as a JSON array
+func chunkBytes(entries [][]byte) int {
+	size := 1 + len(entries)
+	for _, entry := range entries {
+		size += len(entry)
+	}
+	return size
+}
+
+// flush sends every pending entry
+func (d *DatadogOutput) flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.sendPending()
+}
+
+// periodicFlush sends partial batches every flush_interval
+func (d *DatadogOutput) periodicFlush() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(d.config.FlushInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.flush(); err != nil {
+				logger.Printf("Flush failed, %d logs stay pending: %v", d.pendingCount(), err)
+			}
+		case <-d.stopCh:
+			return
+		}
+	}
+}
+
+// pendingCount returns the number of entries not sent yet
+func (d *DatadogOutput) pendingCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
+
+// CheckHealth implements HealthChecker interface by validating the API key
+func (d *DatadogOutput) CheckHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.config.APIURL+"/api/v1/validate", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+	req.Header.Set("DD-API-KEY", d.config.APIKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("datadog API key validation returned status %d", resp.StatusCode)
+	}
+	var result struct {
+		Valid bool `json:"valid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || !result.Valid {
+		return fmt.Errorf("datadog API key is not valid")
+	}
+	return nil
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (d *DatadogOutput) OutputStats() map[string]any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return map[string]any{
+		"sent":            d.sent,
+		"pending":         len(d.pending),
+		"requests":        d.requests,
+		"failed_requests": d.failures,
+		"rejected":        d.rejected,
+		"dropped":         d.dropped,
+		"truncated":       d.truncated,
+	}
+}
+
+// Close sends the pending logs and stops the periodic flush
+func (d *DatadogOutput) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	d.levelFlush.Stop()
+	close(d.stopCh)
+	d.wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.sendPending()
+	if len(d.pending) > 0 {
+		d.dropped += int64(len(d.pending))
+		logger.Printf("Dropped %d logs that could not be sent at shutdown: %v", len(d.pending), err)
+		d.pending = nil
+	}
+	d.client.CloseIdleConnections()
+	return err
+}
@@ -0,0 +1,324 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// intake records the batches posted to a fake Datadog intake
+type intake struct {
+	mu      sync.Mutex
+	batches [][]map[string]any
+	sizes   []int
+	status  int // Response status (default: 202)
+}
+
+func newIntake(t *testing.T) (*intake, *httptest.Server) {
+	in := &intake{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/validate":
+			if r.Header.Get("DD-API-KEY") != "test-key" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["Forbidden"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"valid":true}`))
+		case "/api/v2/logs":
+			body, _ := io.ReadAll(r.Body)
+			in.mu.Lock()
+			defer in.mu.Unlock()
+			if in.status != 0 {
+				w.WriteHeader(in.status)
+				return
+			}
+			var batch []map[string]any
+			if err := json.Unmarshal(body, &batch); err != nil || r.Header.Get("DD-API-KEY") != "test-key" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			in.batches = append(in.batches, batch)
+			in.sizes = append(in.sizes, len(body))
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return in, server
+}
+
+func (in *intake) setStatus(status int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.status = status
+}
+
+func (in *intake) entries() []map[string]any {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	var entries []map[string]any
+	for _, batch := range in.batches {
+		entries = append(entries, batch...)
+	}
+	return entries
+}
+
+func testOutput(t *testing.T, server *httptest.Server, config Config) *DatadogOutput {
+	config.APIKey = "test-key"
+	config.IntakeURL = server.URL + "/api/v2/logs"
+	config.APIURL = server.URL
+	if config.FlushInterval == 0 {
+		config.FlushInterval = 3600
+	}
+	output, err := NewDatadogOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create datadog output: %v", err)
+	}
+	t.Cleanup(func() { _ = output.Close() })
+	return output
+}
+
+func TestDatadogOutputEntries(t *testing.T) {
+	in, server := newIntake(t)
+	output := testOutput(t, server, Config{Service: "checkout", Source: "go", Tags: []string{"env:prod", "Team:Payments"}, BatchSize: 2})
+
+	first := core.NewLogWithMetadata("error", "payment failed", map[string]string{"host": "web-1", "order": "42", "source": "tcp"})
+	first.Source = "app-logs"
+	first.Tags = []string{"alert", "env:prod"}
+	first.TraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	first.SpanID = "00f067aa0ba902b7"
+	second := core.NewLogWithMetadata("info", "ok", map[string]string{"service": "billing"})
+
+	for _, logEntry := range []*core.Log{first, second} {
+		if err := output.Write(logEntry); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	entries := in.entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected one batch of 2 entries, got %v", entries)
+	}
+	entry := entries[0]
+	expected := map[string]any{
+		"message":       "payment failed",
+		"status":        "error",
+		"ddsource":      "go",
+		"service":       "checkout",
+		"hostname":      "web-1",
+		"ddtags":        "env:prod,team:payments,alert",
+		"order":         "42",
+		"input":         "app-logs",
+		"otel.trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"dd.trace_id":   "11803532876627986230",
+		"dd.span_id":    "67667974448284343",
+		"timestamp":     float64(first.Timestamp.UnixMilli()),
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["source"]; ok {
+		t.Errorf("Expected reserved metadata to be left out, got %v", entry)
+	}
+	if entries[1]["service"] != "billing" || entries[1]["ddtags"] != "env:prod,team:payments" {
+		t.Errorf("Expected the log's service and the config tags, got %v", entries[1])
+	}
+}
+
+func TestDatadogOutputFlush(t *testing.T) {
+	in, server := newIntake(t)
+	output := testOutput(t, server, Config{BatchSize: 10, FlushInterval: 1})
+
+	_ = output.Write(core.NewLog("info", "partial batch"))
+	if len(in.entries()) != 0 {
+		t.Fatal("Expected the batch to wait for batch_size or the flush interval")
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(in.entries()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(in.entries()) != 1 {
+		t.Fatalf("Expected the periodic flush to send the partial batch")
+	}
+
+	_ = output.Write(core.NewLog("info", "sent on close"))
+	if err := output.Close(); err != nil {
+		t.Fatalf("Unexpected close error: %v", err)
+	}
+	if len(in.entries()) != 2 {
+		t.Errorf("Expected Close to send the pending logs, got %d entries", len(in.entries()))
+	}
+	if err := output.Write(core.NewLog("info", "late")); err == nil {
+		t.Error("Expected an error writing to a closed output")
+	}
+}
+
+func TestDatadogOutputFlushOnLevel(t *testing.T) {
+	in, server := newIntake(t)
+	output := testOutput(t, server, Config{BatchSize: 10, LevelFlush: core.LevelFlush{FlushOnLevel: "error"}})
+
+	_ = output.Write(core.NewLog("info", "routine"))
+	if len(in.entries()) != 0 {
+		t.Fatal("Expected a routine log to wait for the batch")
+	}
+	if err := output.Write(core.NewLog("error", "payment failed")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(in.entries()) != 2 {
+		t.Errorf("Expected the error to send the batch at once, got %d entries", len(in.entries()))
+	}
+}
+
+func TestDatadogOutputRetry(t *testing.T) {
+	in, server := newIntake(t)
+	output := testOutput(t, server, Config{BatchSize: 2})
+
+	in.setStatus(http.StatusServiceUnavailable)
+	_ = output.Write(core.NewLog("info", "first"))
+	err := output.Write(core.NewLog("info", "second"))
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Fatalf("Expected the status error, got %v", err)
+	}
+	// The failed log is left to the caller's retry; the earlier one stays pending
+	if stats := output.OutputStats(); stats["pending"] != 1 || stats["failed_requests"] != int64(1) {
+		t.Errorf("Expected 1 pending log and 1 failed request, got %v", stats)
+	}
+
+	in.setStatus(0)
+	if err := output.Write(core.NewLog("info", "second")); err != nil {
+		t.Fatalf("Unexpected error after recovery: %v", err)
+	}
+	entries := in.entries()
+	if len(entries) != 2 || entries[0]["message"] != "first" || entries[1]["message"] != "second" {
+		t.Errorf("Expected both logs once, in order, got %v", entries)
+	}
+
+	// Invalid requests are not kept for another attempt
+	in.setStatus(http.StatusBadRequest)
+	_ = output.Write(core.NewLog("info", "third"))
+	if err := output.Write(core.NewLog("info", "fourth")); err == nil {
+		t.Fatal("Expected the status error")
+	}
+	if stats := output.OutputStats(); stats["pending"] != 0 || stats["rejected"] != int64(2) {
+		t.Errorf("Expected the rejected batch to be discarded, got %v", stats)
+	}
+}
+
+func TestDatadogOutputPayloadLimits(t *testing.T) {
+	in, server := newIntake(t)
+	output := testOutput(t, server, Config{BatchSize: MaxBatchSize})
+
+	// 12 entries of ~600KB need 2 requests under the 5MB limit
+	message := strings.Repeat("x", 600*1000)
+	for range 12 {
+		if err := output.Write(core.NewLog("info", message)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := output.flush(); err != nil {
+		t.Fatalf("Unexpected flush error: %v", err)
+	}
+	in.mu.Lock()
+	sizes := append([]int(nil), in.sizes...)
+	in.mu.Unlock()
+	if len(sizes) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(sizes))
+	}
+	for _, size := range sizes {
+		if size > MaxPayloadBytes {
+			t.Errorf("Expected payloads within %d bytes, got %d", MaxPayloadBytes, size)
+		}
+	}
+
+	// A message over the entry limit is cut to fit
+	huge := core.NewLog("info", strings.Repeat("é\"", MaxEntryBytes/2))
+	entry, truncated, err := output.encode(huge)
+	if err != nil || !truncated || len(entry) > MaxEntryBytes {
+		t.Errorf("Expected a truncated entry within %d bytes, got %d bytes (truncated=%v, %v)", MaxEntryBytes, len(entry), truncated, err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(entry, &decoded); err != nil || !strings.HasPrefix(huge.Message, decoded["message"].(string)) {
+		t.Errorf("Expected a valid entry with a prefix of the message, got %v", err)
+	}
+}
+
+func TestFormatTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"env:prod", "env:prod"},
+		{"Team:Payments", "team:payments"},
+		{" region:us east ", "region:us_east"},
+		{"42:answer", "answer"},
+		{"path:/var/log", "path:/var/log"},
+		{"trailing:", "trailing"},
+		{"a,b", "a_b"},
+		{"123", ""},
+		{"tag:" + strings.Repeat("v", 300), "tag:" + strings.Repeat("v", maxTagLength-4)},
+	}
+
+	for _, tt := range tests {
+		if got := formatTag(tt.tag); got != tt.expected {
+			t.Errorf("formatTag(%q): expected %q, got %q", tt.tag, tt.expected, got)
+		}
+	}
+}
+
+func TestDatadogOutputCheckHealth(t *testing.T) {
+	_, server := newIntake(t)
+	output := testOutput(t, server, Config{})
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected a valid key, got %v", err)
+	}
+
+	output.config.APIKey = "wrong"
+	if err := output.CheckHealth(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected the validation status, got %v", err)
+	}
+}
+
+func TestDatadogConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"defaults", Config{APIKey: "k"}, ""},
+		{"eu site", Config{APIKey: "k", Site: "eu"}, ""},
+		{"missing key", Config{}, "api_key is required"},
+		{"unknown site", Config{APIKey: "k", Site: "mars"}, "invalid site"},
+		{"batch too large", Config{APIKey: "k", BatchSize: 1001}, "batch_size"},
+		{"pending below batch", Config{APIKey: "k", BatchSize: 500, MaxPending: 100}, "max_pending"},
+		{"tag without letters", Config{APIKey: "k", Tags: []string{"42"}}, "invalid tag"},
+	}
+
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if tt.expected == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+
+	config := Config{APIKey: "k", Site: "eu"}
+	_ = config.Validate()
+	if config.IntakeURL != "https://http-intake.logs.datadoghq.eu/api/v2/logs" || config.APIURL != "https://api.datadoghq.eu" {
+		t.Errorf("Expected the EU endpoints, got %s and %s", config.IntakeURL, config.APIURL)
+	}
+}
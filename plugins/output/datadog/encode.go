@@ -0,0 +1,163 @@
+package datadog
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// maxTagLength is the longest tag Datadog keeps
+const maxTagLength = 200
+
+// reservedAttributes are entry fields set by the output or that Datadog reads
+// as one of them (host, source); metadata with these keys is not copied into
+// the attributes
+var reservedAttributes = map[string]bool{
+	"message": true, "status": true, "hostname": true, "host": true, "service": true,
+	"ddsource": true, "source": true, "ddtags": true, "timestamp": true,
+}
+
+// encode builds the intake entry of a log: the reserved fields Datadog reads
+// (message, status, hostname, service, ddsource, ddtags, timestamp) plus the
+// metadata, the input name and the trace context as attributes. Messages over
+// MaxEntryBytes are cut so the entry fits, and truncated reports it.
+func (d *DatadogOutput) encode(logEntry *core.Log) (entry []byte, truncated bool, err error) {
+	attributes := make(map[string]any, len(logEntry.Metadata)+12)
+	for key, value := range logEntry.Metadata {
+		if !reservedAttributes[key] {
+			attributes[key] = value
+		}
+	}
+	if logEntry.Source != "" {
+		attributes["input"] = logEntry.Source
+	}
+	// Datadog correlates logs with traces by the low 64 bits of the IDs, in decimal
+	if logEntry.TraceID != "" {
+		attributes["otel.trace_id"] = logEntry.TraceID
+		attributes["dd.trace_id"] = lowDecimal(logEntry.TraceID)
+	}
+	if logEntry.SpanID != "" {
+		attributes["otel.span_id"] = logEntry.SpanID
+		attributes["dd.span_id"] = lowDecimal(logEntry.SpanID)
+	}
+
+	attributes["status"] = logEntry.Level
+	attributes["ddsource"] = d.config.Source
+	attributes["hostname"] = firstNonEmpty(logEntry.Metadata["hostname"], logEntry.Metadata["host"], d.hostname)
+	if service := firstNonEmpty(logEntry.Metadata["service"], d.config.Service); service != "" {
+		attributes["service"] = service
+	}
+	tags := d.tags
+	if len(logEntry.Tags) > 0 {
+		tags = joinTags([]string{d.tags}, logEntry.Tags)
+	}
+	if tags != "" {
+		attributes["ddtags"] = tags
+	}
+	if !logEntry.Timestamp.IsZero() {
+		attributes["timestamp"] = logEntry.Timestamp.UnixMilli()
+	}
+
+	attributes["message"] = logEntry.Message
+	entry, err = json.Marshal(attributes)
+	if err != nil || len(entry) <= MaxEntryBytes {
+		return entry, false, err
+	}
+
+	// Cut the message by what the entry is over, plus room for escaped characters
+	over := len(entry) - MaxEntryBytes
+	message := logEntry.Message
+	for len(entry) > MaxEntryBytes && message != "" {
+		message = truncateUTF8(message, max(len(message)-over, 0))
+		attributes["message"] = message
+		if entry, err = json.Marshal(attributes); err != nil {
+			return nil, false, err
+		}
+		over = max(len(entry)-MaxEntryBytes, 1024)
+	}
+	return entry, true, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// lowDecimal returns the low 64 bits of a hex ID in decimal
+func lowDecimal(id string) string {
+	value, err := strconv.ParseUint(id[max(len(id)-16, 0):], 16, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// joinTags formats tags and appends them to already formatted, comma-separated
+// lists, skipping empty and duplicate tags
+func joinTags(formatted []string, tags []string) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, list := range formatted {
+		for _, tag := range strings.Split(list, ",") {
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				out = append(out, tag)
+			}
+		}
+	}
+	for _, tag := range tags {
+		if tag = formatTag(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// formatTag applies Datadog's tag rules: tags are lowercase, start with a
+// letter, hold only letters, digits, '_', '-', ':', '.' and '/' (anything else
+// becomes '_'), do not end with ':' and are at most 200 characters. It returns
+// "" for a tag without any letter.
+func formatTag(tag string) string {
+	tag = strings.TrimLeftFunc(strings.TrimSpace(tag), func(r rune) bool { return !unicode.IsLetter(r) })
+	if tag == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(tag))
+	count := 0
+	for _, r := range strings.ToLower(tag) {
+		if count == maxTagLength {
+			break
+		}
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), strings.ContainsRune("_-:./", r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+		count++
+	}
+	return strings.TrimRight(b.String(), ":")
+}
@@ -5,20 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/httpclient"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
 	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 
-	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 )
 
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.elasticsearch")
+
 func init() {
 	// Auto-register this plugin
 	core.RegisterOutputPlugin("elasticsearch", NewElasticsearchOutputFromConfig)
@@ -26,22 +31,42 @@ func init() {
 
 // Config represents Elasticsearch output configuration
 type Config struct {
-	Addresses []string         `yaml:"addresses"`            // Elasticsearch addresses
-	Username  string           `yaml:"username,omitempty"`   // Basic auth username
-	Password  string           `yaml:"password,omitempty"`   // Basic auth password
-	APIKey    string           `yaml:"api_key,omitempty"`    // API key authentication
-	Index     string           `yaml:"index"`                // Index name (supports date templates)
-	Timeout   int              `yaml:"timeout,omitempty"`    // Request timeout in seconds
-	BatchSize int              `yaml:"batch_size,omitempty"` // Batch size for bulk operations
-	TLS       tlsconfig.Config `yaml:"tls,omitempty"`        // TLS configuration
+	Addresses []string          `yaml:"addresses"`            // Elasticsearch addresses
+	Username  string            `yaml:"username,omitempty"`   // Basic auth username
+	Password  string            `yaml:"password,omitempty"`   // Basic auth password
+	APIKey    string            `yaml:"api_key,omitempty"`    // API key authentication
+	Index     string            `yaml:"index"`                // Index name (supports date templates)
+	Timeout   int               `yaml:"timeout,omitempty"`    // Request timeout in seconds
+	BatchSize int               `yaml:"batch_size,omitempty"` // Batch size for bulk operations
+	TLS       tlsconfig.Config  `yaml:"tls,omitempty"`        // TLS configuration
+	HTTP      httpclient.Config `yaml:"http,omitempty"`       // Connection pooling and proxy settings
+
+	MaxPendingLogs int    `yaml:"max_pending_logs,omitempty"` // Logs kept in memory while Elasticsearch is unreachable (default: 10000)
+	MaxBatchMemory int    `yaml:"max_batch_memory,omitempty"` // Approximate bytes of pending logs kept in memory (default: 64MB)
+	SpillPath      string `yaml:"spill_path,omitempty"`       // File the oldest logs over the cap are moved to and re-sent from after a restart (default: drop them)
+
+	RebuildAfter    int `yaml:"rebuild_after,omitempty"`    // Consecutive connection failures before the client is rebuilt (default: 3, -1 = never)
+	RebuildInterval int `yaml:"rebuild_interval,omitempty"` // Minimum seconds between rebuilds, doubled while they don't help (default: 60)
+
+	TimestampField  string `yaml:"timestamp_field,omitempty"`  // Metadata key holding the event time for @timestamp and the index date (default: log timestamp)
+	TimestampLayout string `yaml:"timestamp_layout,omitempty"` // "rfc3339" (default), "unix", "unix_ms" or a Go time layout
+
+	core.FieldStyle `yaml:",inline"` // Metadata rendering in documents
+	core.LevelFlush `yaml:",inline"` // Early flush of batches holding important logs
 }
 
 // ElasticsearchOutput sends logs to Elasticsearch
 type ElasticsearchOutput struct {
 	config     Config
-	client     *elasticsearch.Client
+	client     atomic.Pointer[esClient] // Replaced when the client is rebuilt
+	reconnect  reconnectState
+	encoder    *core.LogEncoder
 	batch      []core.Log
 	batchMutex sync.Mutex
+	pending    pendingStats // Guarded by batchMutex
+	failing    atomic.Bool  // The last bulk request failed; Write leaves retries to the periodic flush
+	levelFlush *core.LevelFlusher
+	spillMutex sync.Mutex
 	closeMutex sync.Mutex
 	closed     bool
 	ctx        context.Context
@@ -73,37 +98,55 @@ func NewElasticsearchOutput(config Config) (*ElasticsearchOutput, error) {
 	if config.BatchSize == 0 {
 		config.BatchSize = 100
 	}
+	if config.MaxPendingLogs < 0 || config.MaxBatchMemory < 0 {
+		return nil, fmt.Errorf("max_pending_logs and max_batch_memory must not be negative")
+	}
+	if config.MaxPendingLogs == 0 {
+		config.MaxPendingLogs = DefaultMaxPendingLogs
+	}
+	if config.MaxBatchMemory == 0 {
+		config.MaxBatchMemory = DefaultMaxBatchMemory
+	}
+	if config.MaxPendingLogs < config.BatchSize {
+		return nil, fmt.Errorf("max_pending_logs (%d) must be at least batch_size (%d)", config.MaxPendingLogs, config.BatchSize)
+	}
+	if config.RebuildAfter < -1 || config.RebuildInterval < 0 {
+		return nil, fmt.Errorf("rebuild_after must be -1 or more and rebuild_interval must not be negative")
+	}
+	if config.RebuildAfter == 0 {
+		config.RebuildAfter = DefaultRebuildAfter
+	}
+	if config.RebuildInterval == 0 {
+		config.RebuildInterval = DefaultRebuildInterval
+	}
+	if config.TimestampLayout != "" && config.TimestampField == "" {
+		return nil, fmt.Errorf("timestamp_layout requires timestamp_field")
+	}
+	if config.TimestampField != "" && config.TimestampLayout == "" {
+		config.TimestampLayout = "rfc3339"
+	}
 
 	// Validate TLS config
 	if err := config.TLS.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid TLS config: %w", err)
 	}
-
-	// Configure Elasticsearch client
-	esCfg := elasticsearch.Config{
-		Addresses: config.Addresses,
-		Username:  config.Username,
-		Password:  config.Password,
-		APIKey:    config.APIKey,
+	if err := config.LevelFlush.Validate(); err != nil {
+		return nil, err
 	}
 
-	// Configure TLS transport if enabled
-	if config.TLS.Enabled {
-		tlsConfig, err := config.TLS.NewTLSConfig()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create TLS config: %w", err)
-		}
-
-		esCfg.Transport = &http.Transport{
-			TLSClientConfig: tlsConfig,
-		}
-
-		log.Printf("[ELASTICSEARCH] TLS enabled (InsecureSkipVerify=%v)", tlsConfig.InsecureSkipVerify)
+	encoder, err := core.NewLogEncoder(config.FieldStyle, "@timestamp")
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := elasticsearch.NewClient(esCfg)
+	// Configure Elasticsearch client on its own pooled transport (handles TLS and proxy settings)
+	client, err := newClient(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Elasticsearch client: %w", err)
+		return nil, err
+	}
+
+	if config.TLS.Enabled {
+		logger.Printf("TLS enabled (InsecureSkipVerify=%v)", client.transport.TLSClientConfig.InsecureSkipVerify)
 	}
 
 	// Test connection (non-blocking - just log if fails)
@@ -114,7 +157,7 @@ func NewElasticsearchOutput(config Config) (*ElasticsearchOutput, error) {
 		res, err := client.Info(client.Info.WithContext(ctx))
 
 		if err != nil {
-			log.Printf("[ELASTICSEARCH] Initial connection test failed: %v (will retry in background)", err)
+			logger.Printf("Initial connection test failed: %v (will retry in background)", err)
 			// Don't fail initialization - resilience layer will handle reconnection
 		} else {
 			defer func() {
@@ -122,10 +165,10 @@ func NewElasticsearchOutput(config Config) (*ElasticsearchOutput, error) {
 			}()
 
 			if res.IsError() {
-				log.Printf("[ELASTICSEARCH] Initial connection returned error: %s (will retry in background)", res.String())
+				logger.Printf("Initial connection returned error: %s (will retry in background)", res.String())
 				// Don't fail initialization - resilience layer will handle reconnection
 			} else {
-				log.Printf("[ELASTICSEARCH] Successfully connected to Elasticsearch")
+				logger.Printf("Successfully connected to Elasticsearch")
 			}
 		}
 	}
@@ -133,13 +176,22 @@ func NewElasticsearchOutput(config Config) (*ElasticsearchOutput, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	output := &ElasticsearchOutput{
-		config: config,
-		client: client,
-		batch:  make([]core.Log, 0, config.BatchSize),
-		closed: false,
-		ctx:    ctx,
-		cancel: cancel,
+		config:  config,
+		encoder: encoder,
+		batch:   make([]core.Log, 0, config.BatchSize),
+		closed:  false,
+		ctx:     ctx,
+		cancel:  cancel,
 	}
+	output.client.Store(client)
+	output.levelFlush = core.NewLevelFlusher(config.LevelFlush, func() {
+		if !output.failing.Load() {
+			output.flushNow()
+		}
+	})
+
+	// Re-send logs spilled by a previous run
+	output.recoverSpill()
 
 	// Start background flusher
 	go output.periodicFlush()
@@ -156,28 +208,34 @@ func (e *ElasticsearchOutput) Write(logEntry *core.Log) error {
 	}
 	e.closeMutex.Unlock()
 
-	e.batchMutex.Lock()
-	e.batch = append(e.batch, *logEntry)
-	currentSize := len(e.batch)
-	shouldFlush := currentSize >= e.config.BatchSize
-	e.batchMutex.Unlock()
-
-	log.Printf("[ELASTICSEARCH] Received log (batch size: %d/%d): %s - %s", currentSize, e.config.BatchSize, logEntry.Level, logEntry.Message)
+	currentSize := e.enqueue([]core.Log{*logEntry}, false)
+	logger.Printf("Received log (batch size: %d/%d): %s - %s", currentSize, e.config.BatchSize, logEntry.Level, logEntry.Message)
 
-	if shouldFlush {
-		log.Printf("[ELASTICSEARCH] Batch full, flushing...")
-		return e.flush()
+	// While Elasticsearch is failing, the periodic flush retries; the failed logs stay pending
+	if currentSize >= e.config.BatchSize && !e.failing.Load() {
+		logger.Printf("Batch full, flushing...")
+		e.flushNow()
+	} else if e.levelFlush.Added(logEntry) && !e.failing.Load() {
+		logger.Printf("Flushing batch for %s log...", logEntry.Level)
+		e.flushNow()
 	}
 
 	return nil
 }
 
+// flushNow flushes the batch outside the periodic flush and logs a failure
+func (e *ElasticsearchOutput) flushNow() {
+	if err := e.flush(); err != nil {
+		logger.Printf("Flush failed, %d logs stay pending: %v", e.pendingCount(), err)
+	}
+}
+
 // flush sends batched logs to Elasticsearch
 func (e *ElasticsearchOutput) flush() error {
 	e.batchMutex.Lock()
 	if len(e.batch) == 0 {
 		e.batchMutex.Unlock()
-		log.Printf("[ELASTICSEARCH] Flush called but batch is empty")
+		logger.Printf("Flush called but batch is empty")
 		return nil
 	}
 
@@ -185,17 +243,43 @@ func (e *ElasticsearchOutput) flush() error {
 	batch := e.batch
 	batchSize := len(batch)
 	e.batch = make([]core.Log, 0, e.config.BatchSize)
+	e.pending.bytes = 0
 	e.batchMutex.Unlock()
 
-	log.Printf("[ELASTICSEARCH] Flushing %d logs to Elasticsearch", batchSize)
+	logger.Printf("Flushing %d logs to Elasticsearch", batchSize)
+
+	// Requests that did not reach Elasticsearch, or that it rejected as a whole,
+	// put the batch back so it is retried by the next flush
+	requeue, err := e.send(batch)
+	if requeue {
+		e.failing.Store(true)
+		e.enqueue(batch, true)
+	} else {
+		e.failing.Store(false)
+	}
+	return err
+}
+
+// send indexes a batch with a bulk request. requeue reports whether the batch
+// was not accepted and should be retried.
+func (e *ElasticsearchOutput) send(batch []core.Log) (requeue bool, err error) {
+	batchSize := len(batch)
 
 	// Build bulk request
 	var buf bytes.Buffer
+	unparsed := 0
 
 	for i, logEntry := range batch {
+		// Index at the event time when configured; the batch holds copies, so this is local
+		if eventTime, ok := e.eventTime(&logEntry); ok {
+			logEntry.Timestamp = eventTime
+		} else if e.config.TimestampField != "" {
+			unparsed++
+		}
+
 		// Index directive
 		indexName := e.resolveIndexName(logEntry.Timestamp)
-		log.Printf("[ELASTICSEARCH] Log %d/%d -> Index: %s", i+1, batchSize, indexName)
+		logger.Printf("Log %d/%d -> Index: %s", i+1, batchSize, indexName)
 		meta := map[string]any{
 			"index": map[string]any{
 				"_index": indexName,
@@ -206,64 +290,66 @@ func (e *ElasticsearchOutput) flush() error {
 		buf.WriteByte('\n')
 
 		// Document
-		doc := map[string]any{
-			"@timestamp": logEntry.Timestamp.Format(time.RFC3339),
-			"level":      logEntry.Level,
-			"message":    logEntry.Message,
-		}
-
-		// Add metadata fields if present
-		if len(logEntry.Metadata) > 0 {
-			doc["metadata"] = logEntry.Metadata
+		docBytes, err := e.encoder.Marshal(&logEntry)
+		if err != nil {
+			logger.Printf("Skipping log that cannot be encoded: %v", err)
+			continue
 		}
-		docBytes, _ := json.Marshal(doc)
 		buf.Write(docBytes)
 		buf.WriteByte('\n')
 	}
 
+	if unparsed > 0 {
+		logger.Printf("%d/%d logs had a missing or invalid %s, indexed at the log timestamp",
+			unparsed, batchSize, e.config.TimestampField)
+	}
+
 	// Send bulk request
-	ctx, cancel := context.WithTimeout(e.ctx, time.Duration(e.config.Timeout)*time.Second)
+	// Not tied to e.ctx: the final flush in Close runs after the background tasks are cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.config.Timeout)*time.Second)
 	defer cancel()
 
 	req := esapi.BulkRequest{
 		Body: bytes.NewReader(buf.Bytes()),
 	}
 
-	log.Printf("[ELASTICSEARCH] Sending bulk request...")
-	res, err := req.Do(ctx, e.client)
+	logger.Printf("Sending bulk request...")
+	res, err := req.Do(ctx, e.client.Load())
 	if err != nil {
-		log.Printf("[ELASTICSEARCH] Bulk request failed: %v", err)
-		return fmt.Errorf("bulk request failed: %w", err)
+		logger.Printf("Bulk request failed: %v", err)
+		e.connectionFailed()
+		return true, fmt.Errorf("bulk request failed: %w", err)
 	}
 	defer func() {
 		_ = res.Body.Close()
 	}()
+	e.connected()
 
 	if res.IsError() {
-		log.Printf("[ELASTICSEARCH] Response error status: %s", res.Status())
+		logger.Printf("Response error status: %s", res.Status())
 		var errResp map[string]any
 		if err := json.NewDecoder(res.Body).Decode(&errResp); err == nil {
-			log.Printf("[ELASTICSEARCH] Error response: %v", errResp)
-			return fmt.Errorf("elasticsearch error: %v", errResp)
+			logger.Printf("Error response: %v", errResp)
+			return true, fmt.Errorf("elasticsearch error: %v", errResp)
 		}
-		return fmt.Errorf("elasticsearch returned status: %s", res.Status())
+		return true, fmt.Errorf("elasticsearch returned status: %s", res.Status())
 	}
 
 	// Check for partial failures
 	var bulkResp map[string]any
 	if err := json.NewDecoder(res.Body).Decode(&bulkResp); err != nil {
-		log.Printf("[ELASTICSEARCH] Failed to parse response: %v", err)
-		return fmt.Errorf("failed to parse response: %w", err)
+		logger.Printf("Failed to parse response: %v", err)
+		return false, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if bulkResp["errors"] == true {
 		// Log partial failures but don't fail completely
-		log.Printf("[ELASTICSEARCH] Bulk request had partial failures")
+		logger.Printf("Bulk request had partial failures")
 	} else {
-		log.Printf("[ELASTICSEARCH] Successfully indexed %d logs", batchSize)
+		logger.Printf("Successfully indexed %d logs", batchSize)
 	}
 
-	return nil
+	return false, nil
 }
 
 // periodicFlush flushes logs every 5 seconds
@@ -304,15 +390,49 @@ func (e *ElasticsearchOutput) resolveIndexName(t time.Time) string {
 	return indexName
 }
 
+// eventTime parses the configured timestamp field of a log. It returns false when
+// no field is configured or the value is missing or malformed.
+func (e *ElasticsearchOutput) eventTime(logEntry *core.Log) (time.Time, bool) {
+	if e.config.TimestampField == "" {
+		return time.Time{}, false
+	}
+	value := strings.TrimSpace(logEntry.Metadata[e.config.TimestampField])
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	switch e.config.TimestampLayout {
+	case "rfc3339":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return t, err == nil
+	case "unix", "unix_ms":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if e.config.TimestampLayout == "unix_ms" {
+			return time.UnixMilli(int64(n)), true
+		}
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	default:
+		t, err := time.Parse(e.config.TimestampLayout, value)
+		return t, err == nil
+	}
+}
+
 // CheckHealth implements HealthChecker interface
 func (e *ElasticsearchOutput) CheckHealth(ctx context.Context) error {
-	res, err := e.client.Info(e.client.Info.WithContext(ctx))
+	client := e.client.Load()
+	res, err := client.Info(client.Info.WithContext(ctx))
 	if err != nil {
+		e.connectionFailed()
 		return fmt.Errorf("health check failed: %w", err)
 	}
 	defer func() {
 		_ = res.Body.Close()
 	}()
+	e.connected()
 
 	if res.IsError() {
 		return fmt.Errorf("elasticsearch health check error: %s", res.String())
@@ -333,7 +453,13 @@ func (e *ElasticsearchOutput) Close() error {
 
 	// Cancel background tasks
 	e.cancel()
+	e.levelFlush.Stop()
 
-	// Flush remaining logs
-	return e.flush()
+	// Flush remaining logs; whatever cannot be delivered is spilled for the next run
+	err := e.flush()
+	if err != nil {
+		e.spill(e.takePending(), "undelivered at shutdown")
+	}
+	e.client.Load().transport.CloseIdleConnections()
+	return err
 }
@@ -1,8 +1,17 @@
 package elasticsearch
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -212,3 +221,381 @@ func TestConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestEventTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string
+		value    string
+		expected time.Time
+		ok       bool
+	}{
+		{name: "rfc3339", layout: "rfc3339", value: "2024-01-15T12:30:00Z", expected: time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC), ok: true},
+		{name: "rfc3339 with offset", layout: "rfc3339", value: "2024-01-15T23:30:00.5-03:00", expected: time.Date(2024, 1, 16, 2, 30, 0, 500000000, time.UTC), ok: true},
+		{name: "unix seconds", layout: "unix", value: "1705321800.25", expected: time.Date(2024, 1, 15, 12, 30, 0, 250000000, time.UTC), ok: true},
+		{name: "unix milliseconds", layout: "unix_ms", value: "1705321800250", expected: time.Date(2024, 1, 15, 12, 30, 0, 250000000, time.UTC), ok: true},
+		{name: "custom layout", layout: "02/Jan/2006:15:04:05 -0700", value: "15/Jan/2024:12:30:00 +0000", expected: time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC), ok: true},
+		{name: "malformed", layout: "rfc3339", value: "yesterday"},
+		{name: "malformed unix", layout: "unix", value: "soon"},
+		{name: "missing", layout: "rfc3339", value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &ElasticsearchOutput{config: Config{TimestampField: "event_time", TimestampLayout: tt.layout}}
+			logEntry := core.NewLogWithMetadata("info", "test", map[string]string{"event_time": tt.value})
+
+			eventTime, ok := output.eventTime(logEntry)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%t, got %t", tt.ok, ok)
+			}
+			if ok && !eventTime.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, eventTime)
+			}
+		})
+	}
+}
+
+func TestFlushUsesEventTime(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	output, err := NewElasticsearchOutput(Config{
+		Addresses:      []string{server.URL},
+		Index:          "logs-{yyyy.MM.dd}",
+		TimestampField: "event_time",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	ingested := time.Date(2024, 3, 2, 0, 5, 0, 0, time.UTC)
+	valid := core.NewLogWithMetadata("info", "late event", map[string]string{"event_time": "2024-03-01T23:59:00Z"})
+	valid.Timestamp = ingested
+	malformed := core.NewLogWithMetadata("info", "bad time", map[string]string{"event_time": "not a time"})
+	malformed.Timestamp = ingested
+	for _, logEntry := range []*core.Log{valid, malformed} {
+		if err := output.Write(logEntry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := output.flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 bulk lines, got %d: %s", len(lines), body)
+	}
+	expected := []string{
+		`"_index":"logs-2024.03.01"`, `"@timestamp":"2024-03-01T23:59:00Z"`,
+		`"_index":"logs-2024.03.02"`, `"@timestamp":"2024-03-02T00:05:00Z"`,
+	}
+	for i, want := range expected {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Expected line %d to contain %s, got %s", i, want, lines[i])
+		}
+	}
+	if valid.Timestamp != ingested {
+		t.Error("Expected the written log to keep its own timestamp")
+	}
+}
+
+func TestTimestampLayoutRequiresField(t *testing.T) {
+	if _, err := NewElasticsearchOutput(Config{Index: "logs", TimestampLayout: "unix"}); err == nil {
+		t.Error("Expected error for timestamp_layout without timestamp_field")
+	}
+}
+
+// newBulkServer returns a fake Elasticsearch that fails bulk requests while failing is set
+func newBulkServer(t *testing.T, failing *atomic.Bool, indexed *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"unavailable"}`))
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		indexed.Add(int64(strings.Count(string(data), "\n") / 2))
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFlushOnLevel(t *testing.T) {
+	var failing atomic.Bool
+	var indexed atomic.Int64
+	server := newBulkServer(t, &failing, &indexed)
+
+	output, err := NewElasticsearchOutput(Config{
+		Addresses:  []string{server.URL},
+		Index:      "logs",
+		BatchSize:  10,
+		LevelFlush: core.LevelFlush{FlushOnLevel: "error"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	_ = output.Write(core.NewLog("info", "routine"))
+	if indexed.Load() != 0 {
+		t.Fatal("Expected a routine log to wait for the batch")
+	}
+	_ = output.Write(core.NewLog("error", "payment failed"))
+	if indexed.Load() != 2 {
+		t.Errorf("Expected the error to flush the batch at once, got %d logs indexed", indexed.Load())
+	}
+}
+
+func TestPendingCapSpillsAndRecovers(t *testing.T) {
+	var failing atomic.Bool
+	var indexed atomic.Int64
+	failing.Store(true)
+	server := newBulkServer(t, &failing, &indexed)
+	spillPath := filepath.Join(t.TempDir(), "es-spill.jsonl")
+
+	config := Config{
+		Addresses:      []string{server.URL},
+		Index:          "logs",
+		BatchSize:      2,
+		MaxPendingLogs: 3,
+		SpillPath:      spillPath,
+	}
+	output, err := NewElasticsearchOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := output.Write(core.NewLog("error", fmt.Sprintf("log %d", i))); err != nil {
+			t.Fatalf("Expected Write to keep failed logs pending, got %v", err)
+		}
+	}
+
+	stats := output.OutputStats()
+	if stats["pending"] != 3 || stats["spilled"] != int64(2) || stats["dropped"] != int64(0) {
+		t.Errorf("Expected 3 pending and 2 spilled logs, got %v", stats)
+	}
+
+	// Shutting down while Elasticsearch is still down spills the rest
+	if err := output.Close(); err == nil {
+		t.Error("Expected the final flush to fail")
+	}
+	logs, err := readSpill(spillPath)
+	if err != nil {
+		t.Fatalf("Failed to read spill file: %v", err)
+	}
+	if len(logs) != 5 || logs[0].Message != "log 0" {
+		t.Fatalf("Expected all 5 logs in the spill file oldest first, got %d", len(logs))
+	}
+
+	// The next run re-sends the spilled logs
+	failing.Store(false)
+	recovered, err := NewElasticsearchOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	if got := recovered.OutputStats()["recovered"]; got != int64(5) {
+		t.Errorf("Expected 5 recovered logs, got %v", got)
+	}
+	if err := recovered.Close(); err != nil {
+		t.Fatalf("Expected the recovered logs to be indexed, got %v", err)
+	}
+	// 3 fit the cap, the 2 oldest were spilled again and are recovered by the following run
+	if indexed.Load() != 3 {
+		t.Errorf("Expected 3 logs indexed, got %d", indexed.Load())
+	}
+	if logs, _ := readSpill(spillPath); len(logs) != 2 {
+		t.Errorf("Expected 2 logs left in the spill file, got %d", len(logs))
+	}
+}
+
+func TestPendingCapDropsWithoutSpillPath(t *testing.T) {
+	var failing atomic.Bool
+	var indexed atomic.Int64
+	failing.Store(true)
+	server := newBulkServer(t, &failing, &indexed)
+
+	output, err := NewElasticsearchOutput(Config{
+		Addresses:      []string{server.URL},
+		Index:          "logs",
+		BatchSize:      1,
+		MaxBatchMemory: 3 * logOverhead,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	for i := 0; i < 4; i++ {
+		_ = output.Write(core.NewLog("info", "x"))
+	}
+
+	stats := output.OutputStats()
+	if stats["pending"] != 2 || stats["dropped"] != int64(2) {
+		t.Errorf("Expected the memory cap to keep 2 logs and drop 2, got %v", stats)
+	}
+	if bytes := stats["pending_bytes"].(int); bytes > 3*logOverhead {
+		t.Errorf("Expected pending bytes within the cap, got %d", bytes)
+	}
+
+	// Once Elasticsearch is back, pending logs are delivered
+	failing.Store(false)
+	if err := output.flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if indexed.Load() != 2 {
+		t.Errorf("Expected 2 logs indexed, got %d", indexed.Load())
+	}
+}
+
+func TestPendingCapValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"negative max_pending_logs", Config{Index: "logs", MaxPendingLogs: -1}},
+		{"negative max_batch_memory", Config{Index: "logs", MaxBatchMemory: -1}},
+		{"cap below batch size", Config{Index: "logs", BatchSize: 100, MaxPendingLogs: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewElasticsearchOutput(tt.config); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestClientRebuildAfterConnectionFailures(t *testing.T) {
+	// A closed server refuses connections, like an endpoint whose address moved
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	output, err := NewElasticsearchOutput(Config{Addresses: []string{server.URL}, Index: "logs", RebuildAfter: 2, RebuildInterval: 1})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	checkHealth := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := output.CheckHealth(ctx); err == nil {
+			t.Fatal("Expected the health check to fail")
+		}
+	}
+
+	original := output.client.Load()
+	checkHealth()
+	if output.client.Load() != original {
+		t.Error("Expected a single failure not to rebuild the client")
+	}
+
+	checkHealth()
+	if output.client.Load() == original || output.reconnect.rebuilds.Load() != 1 {
+		t.Errorf("Expected the client to be rebuilt after 2 failures, got %d rebuilds", output.reconnect.rebuilds.Load())
+	}
+
+	// The rebuilt client resolves addresses again on a transport of its own
+	if output.client.Load().transport == original.transport {
+		t.Error("Expected the rebuilt client to use a new transport")
+	}
+
+	// Further failures wait for rebuild_interval
+	rebuilt := output.client.Load()
+	checkHealth()
+	if output.client.Load() != rebuilt {
+		t.Error("Expected no rebuild before rebuild_interval has passed")
+	}
+
+	// Once it has passed, the client is rebuilt again and the next wait doubles
+	output.reconnect.mu.Lock()
+	output.reconnect.lastRebuild = time.Now().Add(-2 * time.Second)
+	output.reconnect.mu.Unlock()
+	checkHealth()
+	if output.reconnect.rebuilds.Load() != 2 || output.reconnect.interval != 2*time.Second {
+		t.Errorf("Expected a second rebuild with a 2s interval, got %d rebuilds and %s", output.reconnect.rebuilds.Load(), output.reconnect.interval)
+	}
+
+	// Reaching Elasticsearch resets the failure count and the backoff
+	output.connected()
+	if output.reconnect.failures.Load() != 0 || output.reconnect.interval != 0 {
+		t.Errorf("Expected the failure count and interval to reset, got %d and %s", output.reconnect.failures.Load(), output.reconnect.interval)
+	}
+	if output.OutputStats()["rebuilds"] != int64(2) {
+		t.Errorf("Expected rebuilds in the output stats, got %v", output.OutputStats())
+	}
+}
+
+func TestClientRebuildDisabled(t *testing.T) {
+	output, err := NewElasticsearchOutput(Config{Index: "logs", RebuildAfter: -1})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	original := output.client.Load()
+	for i := 0; i < 10; i++ {
+		output.connectionFailed()
+	}
+	if output.client.Load() != original {
+		t.Error("Expected rebuild_after -1 to never rebuild the client")
+	}
+
+	for _, config := range []Config{{Index: "logs", RebuildAfter: -2}, {Index: "logs", RebuildInterval: -1}} {
+		if _, err := NewElasticsearchOutput(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestCloseClosesIdleConnections(t *testing.T) {
+	var closed atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	output, err := NewElasticsearchOutput(Config{Addresses: []string{server.URL}, Index: "logs", BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	if err := output.Write(core.NewLog("info", "kept alive")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if closed.Load() != 0 {
+		t.Fatal("Expected the connection to stay open for reuse")
+	}
+
+	_ = output.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for closed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if closed.Load() == 0 {
+		t.Error("Expected Close to close the idle connection")
+	}
+}
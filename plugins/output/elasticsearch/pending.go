@@ -0,0 +1,213 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// Defaults for the pending-log cap
+const (
+	DefaultMaxPendingLogs = 10000
+	DefaultMaxBatchMemory = 64 * 1024 * 1024
+)
+
+// logOverhead approximates the memory a pending log uses besides its strings
+const logOverhead = 128
+
+// pendingStats counts what happened to logs that could not be indexed right away
+type pendingStats struct {
+	bytes     int   // Approximate memory held by the pending batch
+	spilled   int64 // Logs moved to the spill file
+	dropped   int64 // Logs discarded because there is no spill file or it could not be written
+	recovered int64 // Logs read back from the spill file on startup
+}
+
+// logSize approximates the memory held by a log
+func logSize(logEntry *core.Log) int {
+	size := logOverhead + len(logEntry.Level) + len(logEntry.Message) + len(logEntry.Source) + len(logEntry.SourceType) +
+		len(logEntry.TraceID) + len(logEntry.SpanID)
+	for key, value := range logEntry.Metadata {
+		size += len(key) + len(value)
+	}
+	for _, tag := range logEntry.Tags {
+		size += len(tag)
+	}
+	return size
+}
+
+// enqueue adds logs to the pending batch (in front when a failed batch is put
+// back), moves the oldest logs over the cap to the spill file and returns the
+// number of pending logs
+func (e *ElasticsearchOutput) enqueue(logs []core.Log, front bool) int {
+	e.batchMutex.Lock()
+	if front {
+		e.batch = append(logs, e.batch...)
+	} else {
+		e.batch = append(e.batch, logs...)
+	}
+	for i := range logs {
+		e.pending.bytes += logSize(&logs[i])
+	}
+
+	// Trim the oldest logs until both limits hold
+	trim := 0
+	for trim < len(e.batch) && (len(e.batch)-trim > e.config.MaxPendingLogs || e.pending.bytes > e.config.MaxBatchMemory) {
+		e.pending.bytes -= logSize(&e.batch[trim])
+		trim++
+	}
+	var overflow []core.Log
+	if trim > 0 {
+		overflow = e.batch[:trim]
+		e.batch = append(make([]core.Log, 0, max(len(e.batch)-trim, e.config.BatchSize)), e.batch[trim:]...)
+	}
+	pending := len(e.batch)
+	e.batchMutex.Unlock()
+
+	e.spill(overflow, "over the pending cap")
+	return pending
+}
+
+// takePending removes and returns every pending log
+func (e *ElasticsearchOutput) takePending() []core.Log {
+	e.batchMutex.Lock()
+	defer e.batchMutex.Unlock()
+
+	batch := e.batch
+	e.batch = make([]core.Log, 0, e.config.BatchSize)
+	e.pending.bytes = 0
+	return batch
+}
+
+// pendingCount returns the number of pending logs
+func (e *ElasticsearchOutput) pendingCount() int {
+	e.batchMutex.Lock()
+	defer e.batchMutex.Unlock()
+	return len(e.batch)
+}
+
+// spill appends logs to the spill file, or drops and counts them when there is none
+func (e *ElasticsearchOutput) spill(logs []core.Log, reason string) {
+	if len(logs) == 0 {
+		return
+	}
+
+	err := errors.New("no spill_path configured")
+	if e.config.SpillPath != "" {
+		err = e.appendSpill(logs)
+	}
+
+	e.batchMutex.Lock()
+	if err != nil {
+		e.pending.dropped += int64(len(logs))
+	} else {
+		e.pending.spilled += int64(len(logs))
+	}
+	e.batchMutex.Unlock()
+
+	if err != nil {
+		logger.Printf("Dropped %d logs %s: %v", len(logs), reason, err)
+		return
+	}
+	logger.Printf("Spilled %d logs %s to %s", len(logs), reason, e.config.SpillPath)
+}
+
+// appendSpill writes logs to the spill file as JSON lines
+func (e *ElasticsearchOutput) appendSpill(logs []core.Log) error {
+	e.spillMutex.Lock()
+	defer e.spillMutex.Unlock()
+
+	file, err := os.OpenFile(e.config.SpillPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 - path comes from configuration
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for i := range logs {
+		if err := encoder.Encode(&logs[i]); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	return file.Close()
+}
+
+// recoverSpill moves logs spilled by a previous run back into the pending batch.
+// The file is removed once read; logs that still do not fit are spilled again.
+func (e *ElasticsearchOutput) recoverSpill() {
+	if e.config.SpillPath == "" {
+		return
+	}
+
+	e.spillMutex.Lock()
+	logs, err := readSpill(e.config.SpillPath)
+	if err == nil && len(logs) > 0 {
+		err = os.Remove(e.config.SpillPath)
+	}
+	e.spillMutex.Unlock()
+
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Printf("Error recovering spill file %s: %v", e.config.SpillPath, err)
+		}
+		return
+	}
+	if len(logs) == 0 {
+		return
+	}
+
+	e.batchMutex.Lock()
+	e.pending.recovered += int64(len(logs))
+	e.batchMutex.Unlock()
+
+	logger.Printf("Recovered %d spilled logs from %s", len(logs), e.config.SpillPath)
+	e.enqueue(logs, true)
+}
+
+// readSpill reads the logs of a spill file, skipping malformed lines
+func readSpill(path string) ([]core.Log, error) {
+	file, err := os.Open(path) // #nosec G304 - path comes from configuration
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var logs []core.Log
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var logEntry core.Log
+		if err := json.Unmarshal(scanner.Bytes(), &logEntry); err != nil {
+			logger.Printf("Skipping malformed entry in spill file %s: %v", path, err)
+			continue
+		}
+		logs = append(logs, logEntry)
+	}
+	return logs, scanner.Err()
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (e *ElasticsearchOutput) OutputStats() map[string]any {
+	e.batchMutex.Lock()
+	defer e.batchMutex.Unlock()
+
+	return map[string]any{
+		"pending":       len(e.batch),
+		"pending_bytes": e.pending.bytes,
+		"spilled":       e.pending.spilled,
+		"dropped":       e.pending.dropped,
+		"recovered":     e.pending.recovered,
+		"rebuilds":      e.reconnect.rebuilds.Load(),
+	}
+}
@@ -0,0 +1,109 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/pkg/httpclient"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Defaults for rebuilding the client after sustained connection failures
+const (
+	DefaultRebuildAfter    = 3
+	DefaultRebuildInterval = 60 // Seconds
+	maxRebuildInterval     = 10 * time.Minute
+)
+
+// esClient is a client together with its transport. Each client owns its
+// transport, so once replaced its idle connections (to addresses that may be
+// gone) can be closed.
+type esClient struct {
+	*elasticsearch.Client
+	transport *http.Transport
+}
+
+// reconnectState tracks consecutive connection failures and when the client may next be rebuilt
+type reconnectState struct {
+	failures    atomic.Int64 // Consecutive requests that did not reach Elasticsearch
+	rebuilds    atomic.Int64 // Clients rebuilt since startup
+	mu          sync.Mutex   // Serializes rebuilds and guards the fields below
+	lastRebuild time.Time
+	interval    time.Duration // Minimum time before the next rebuild; doubles while rebuilds don't help
+}
+
+// newClient creates a client on a new transport, so addresses are resolved
+// again and no connection of a previous client is reused
+func newClient(config Config) (*esClient, error) {
+	transport, err := httpclient.NewTransport(config.HTTP, config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP transport: %w", err)
+	}
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: config.Addresses,
+		Username:  config.Username,
+		Password:  config.Password,
+		APIKey:    config.APIKey,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Elasticsearch client: %w", err)
+	}
+	return &esClient{Client: client, transport: transport}, nil
+}
+
+// connected records that a request reached Elasticsearch (even if it returned an error status)
+func (e *ElasticsearchOutput) connected() {
+	if e.reconnect.failures.Swap(0) == 0 {
+		return
+	}
+	e.reconnect.mu.Lock()
+	e.reconnect.interval = 0
+	e.reconnect.mu.Unlock()
+}
+
+// connectionFailed records a request that did not reach Elasticsearch and
+// rebuilds the client once rebuild_after consecutive requests have failed. A
+// rebuild that does not help doubles the wait before the next one.
+func (e *ElasticsearchOutput) connectionFailed() {
+	failures := e.reconnect.failures.Add(1)
+	if e.config.RebuildAfter < 0 || failures < int64(e.config.RebuildAfter) {
+		return
+	}
+	if e.ctx.Err() != nil {
+		// Closing: the final flush must not leave a new client behind
+		return
+	}
+
+	e.reconnect.mu.Lock()
+	defer e.reconnect.mu.Unlock()
+
+	if e.reconnect.interval == 0 {
+		e.reconnect.interval = time.Duration(e.config.RebuildInterval) * time.Second
+	}
+	if !e.reconnect.lastRebuild.IsZero() && time.Since(e.reconnect.lastRebuild) < e.reconnect.interval {
+		return
+	}
+
+	client, err := newClient(e.config)
+	if err != nil {
+		logger.Printf("Failed to rebuild client after %d connection failures: %v", failures, err)
+		return
+	}
+
+	// Requests in flight keep the client they loaded; new requests use the new one
+	old := e.client.Swap(client)
+	old.transport.CloseIdleConnections()
+
+	if !e.reconnect.lastRebuild.IsZero() {
+		e.reconnect.interval = min(e.reconnect.interval*2, maxRebuildInterval)
+	}
+	e.reconnect.lastRebuild = time.Now()
+	e.reconnect.rebuilds.Add(1)
+	logger.Printf("Rebuilt client after %d consecutive connection failures (next rebuild no sooner than %s)", failures, e.reconnect.interval)
+}
@@ -0,0 +1,592 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.email")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("email", NewEmailOutputFromConfig)
+}
+
+// TLS modes
+const (
+	TLSModeStartTLS = "starttls" // Plain connection upgraded with STARTTLS (default)
+	TLSModeImplicit = "tls"      // TLS from the first byte (SMTPS, usually port 465)
+	TLSModeNone     = "none"     // No encryption; only for local relays
+)
+
+// Email is expensive and easy to flood, so the defaults are conservative
+const (
+	defaultPort             = 587
+	defaultWindow           = 5 * time.Minute
+	defaultMaxEmailsPerHour = 6
+	defaultMaxEntries       = 50
+	defaultTimeout          = 30
+
+	defaultSubjectTemplate = `[LogAnalyzer] {{.Count}} {{.Level}} log(s)`
+	defaultBodyTemplate    = `{{.Count}} log(s) between {{.Start.Format "2006-01-02 15:04:05 MST"}} and {{.End.Format "2006-01-02 15:04:05 MST"}}.
+{{range .Entries}}
+[{{.Level}}] {{.Message}}
+  {{.Count}}x{{with .Source}}, source: {{.}}{{end}}, first: {{.First.Format "15:04:05"}}, last: {{.Last.Format "15:04:05"}}
+{{end}}{{if .Omitted}}
+{{.Omitted}} more log(s) not shown.
+{{end}}`
+)
+
+// Config represents email output configuration
+type Config struct {
+	SMTPHost         string        `yaml:"smtp_host"`                     // Required: SMTP server host
+	Port             int           `yaml:"port,omitempty"`                // SMTP server port (default: 587)
+	From             string        `yaml:"from"`                          // Required: sender address
+	To               []string      `yaml:"to"`                            // Required: recipient addresses
+	Auth             AuthConfig    `yaml:"auth,omitempty"`                // Optional: SMTP PLAIN authentication
+	TLS              TLSConfig     `yaml:"tls,omitempty"`                 // Connection security
+	SubjectTemplate  string        `yaml:"subject_template,omitempty"`    // Go template for the subject, see Digest
+	BodyTemplate     string        `yaml:"body_template,omitempty"`       // Go template for the plain text body, see Digest
+	Window           time.Duration `yaml:"window,omitempty"`              // Logs are collected this long and sent as one digest (default: 5m)
+	MaxEmailsPerHour int           `yaml:"max_emails_per_hour,omitempty"` // Digests sent per rolling hour (default: 6)
+	MaxEntries       int           `yaml:"max_entries,omitempty"`         // Distinct messages listed per digest (default: 50)
+	Timeout          int           `yaml:"timeout,omitempty"`             // Seconds per SMTP conversation (default: 30)
+}
+
+// AuthConfig holds SMTP credentials
+type AuthConfig struct {
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// TLSConfig selects how the connection is secured. Certificate options are the
+// shared TLS options; enabled is implied by the mode.
+type TLSConfig struct {
+	Mode             string `yaml:"mode,omitempty"` // starttls (default), tls or none
+	tlsconfig.Config `yaml:",inline"`
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.SMTPHost == "" {
+		return fmt.Errorf("smtp_host is required")
+	}
+	if c.From == "" {
+		return fmt.Errorf("from is required")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("at least one to address is required")
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid to address %q: %w", to, err)
+		}
+	}
+	if (c.Auth.Username == "") != (c.Auth.Password == "") {
+		return fmt.Errorf("auth requires both username and password")
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if c.Window < 0 || c.MaxEmailsPerHour < 0 || c.MaxEntries < 0 || c.Timeout < 0 {
+		return fmt.Errorf("window, max_emails_per_hour, max_entries and timeout must be non-negative")
+	}
+
+	switch c.TLS.Mode {
+	case "":
+		c.TLS.Mode = TLSModeStartTLS
+	case TLSModeStartTLS, TLSModeImplicit, TLSModeNone:
+	default:
+		return fmt.Errorf("invalid tls mode '%s', must be '%s', '%s' or '%s'", c.TLS.Mode, TLSModeStartTLS, TLSModeImplicit, TLSModeNone)
+	}
+	c.TLS.Enabled = c.TLS.Mode != TLSModeNone
+	if err := c.TLS.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid TLS config: %w", err)
+	}
+
+	if c.Port == 0 {
+		c.Port = defaultPort
+	}
+	if c.Window == 0 {
+		c.Window = defaultWindow
+	}
+	if c.MaxEmailsPerHour == 0 {
+		c.MaxEmailsPerHour = defaultMaxEmailsPerHour
+	}
+	if c.MaxEntries == 0 {
+		c.MaxEntries = defaultMaxEntries
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.SubjectTemplate == "" {
+		c.SubjectTemplate = defaultSubjectTemplate
+	}
+	if c.BodyTemplate == "" {
+		c.BodyTemplate = defaultBodyTemplate
+	}
+	return nil
+}
+
+// Digest is the data passed to subject_template and body_template
+type Digest struct {
+	Level   string    // Most severe level in the digest
+	Count   int       // Logs in the digest, including omitted ones
+	Omitted int       // Logs not listed because max_entries was reached
+	Start   time.Time // Arrival of the first log
+	End     time.Time // Arrival of the last log
+	Entries []*Entry  // Distinct messages, most frequent first
+}
+
+// Entry groups the logs of a digest that share level, source and message
+type Entry struct {
+	Level    string
+	Source   string
+	Message  string
+	Count    int
+	First    time.Time         // Timestamp of the first log
+	Last     time.Time         // Timestamp of the last log
+	Metadata map[string]string // Metadata of the first log
+}
+
+// NewEmailOutputFromConfig creates an email output from configuration map
+func NewEmailOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewEmailOutput(cfg)
+}
+
+// EmailOutput sends logs as email digests. Write only collects the log; every
+// window the collected logs are grouped by level, source and message and sent as
+// one email, as long as the hourly limit allows. Logs that cannot be sent yet stay
+// pending for the next window.
+type EmailOutput struct {
+	config    Config
+	subject   *template.Template
+	body      *template.Template
+	tlsConfig *tls.Config
+	send      func(subject, body string) error // sendMail, replaced in tests
+
+	mu      sync.Mutex
+	pending *Digest
+	index   map[string]*Entry // pending.Entries by group key
+	sent    []time.Time       // Send times within the last hour
+	closed  bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	// Counters reported through OutputStats
+	emailsSent int64
+	sendErrors int64
+	throttled  int64
+}
+
+// NewEmailOutput creates a new email output. Templates are parsed and rendered
+// against a sample digest, so template errors fail here instead of at send time.
+func NewEmailOutput(config Config) (*EmailOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	subject, err := parseTemplate("subject_template", config.SubjectTemplate)
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseTemplate("body_template", config.BodyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := config.TLS.NewTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TLS config: %w", err)
+	}
+	if tlsConfig != nil && tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = config.SMTPHost
+	}
+
+	e := &EmailOutput{
+		config:    config,
+		subject:   subject,
+		body:      body,
+		tlsConfig: tlsConfig,
+		stopCh:    make(chan struct{}),
+	}
+	e.send = e.sendMail
+
+	e.wg.Add(1)
+	go e.run()
+
+	return e, nil
+}
+
+// parseTemplate parses a template and checks it renders a sample digest
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	now := time.Now()
+	sample := &Digest{
+		Level: "error",
+		Count: 2,
+		Start: now,
+		End:   now,
+		Entries: []*Entry{{
+			Level: "error", Source: "sample", Message: "sample message", Count: 2,
+			First: now, Last: now, Metadata: map[string]string{},
+		}},
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Write adds a log to the pending digest
+func (e *EmailOutput) Write(logEntry *core.Log) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return fmt.Errorf("email output is closed")
+	}
+	e.addLocked(logEntry)
+	return nil
+}
+
+// addLocked groups a log into the pending digest (mu must be held)
+func (e *EmailOutput) addLocked(logEntry *core.Log) {
+	now := time.Now()
+	if e.pending == nil {
+		e.pending = &Digest{Start: now}
+		e.index = make(map[string]*Entry)
+	}
+	d := e.pending
+	d.Count++
+	d.End = now
+	if d.Level == "" || core.Levels().AtLeast(logEntry.Level, d.Level) {
+		d.Level = core.Levels().Normalize(logEntry.Level)
+	}
+
+	key := groupKey(logEntry.Level, logEntry.Source, logEntry.Message)
+	if entry, ok := e.index[key]; ok {
+		entry.Count++
+		if logEntry.Timestamp.After(entry.Last) {
+			entry.Last = logEntry.Timestamp
+		}
+		return
+	}
+	if len(d.Entries) >= e.config.MaxEntries {
+		d.Omitted++
+		return
+	}
+
+	metadata := make(map[string]string, len(logEntry.Metadata))
+	for k, v := range logEntry.Metadata {
+		metadata[k] = v
+	}
+	entry := &Entry{
+		Level:    logEntry.Level,
+		Source:   logEntry.Source,
+		Message:  logEntry.Message,
+		Count:    1,
+		First:    logEntry.Timestamp,
+		Last:     logEntry.Timestamp,
+		Metadata: metadata,
+	}
+	d.Entries = append(d.Entries, entry)
+	e.index[key] = entry
+}
+
+// run sends the pending digest every window until the output is closed
+func (e *EmailOutput) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flush(time.Now())
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// flush sends the pending digest unless the hourly limit is reached. A digest that
+// is throttled or fails to send stays pending and is merged with the next window.
+func (e *EmailOutput) flush(now time.Time) {
+	e.mu.Lock()
+	if e.pending == nil {
+		e.mu.Unlock()
+		return
+	}
+	e.sent = trimSent(e.sent, now)
+	if len(e.sent) >= e.config.MaxEmailsPerHour {
+		e.throttled++
+		count := e.pending.Count
+		e.mu.Unlock()
+		logger.Printf("Hourly limit of %d emails reached, keeping %d log(s) for the next window", e.config.MaxEmailsPerHour, count)
+		return
+	}
+
+	digest := e.pending
+	e.pending = nil
+	e.index = nil
+	e.sent = append(e.sent, now)
+	e.mu.Unlock()
+
+	err := e.sendDigest(digest)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		e.emailsSent++
+		return
+	}
+	e.sendErrors++
+	logger.Printf("Failed to send email digest of %d log(s): %v", digest.Count, err)
+	e.requeueLocked(digest)
+}
+
+// requeueLocked merges an unsent digest back into the pending one (mu must be held)
+func (e *EmailOutput) requeueLocked(digest *Digest) {
+	newer := e.pending
+	e.pending = digest
+	e.index = make(map[string]*Entry, len(digest.Entries))
+	for _, entry := range digest.Entries {
+		e.index[groupKey(entry.Level, entry.Source, entry.Message)] = entry
+	}
+	if newer == nil {
+		return
+	}
+
+	digest.Count += newer.Count
+	digest.Omitted += newer.Omitted
+	digest.End = newer.End
+	if core.Levels().AtLeast(newer.Level, digest.Level) {
+		digest.Level = newer.Level
+	}
+	for _, entry := range newer.Entries {
+		key := groupKey(entry.Level, entry.Source, entry.Message)
+		switch existing, ok := e.index[key]; {
+		case ok:
+			existing.Count += entry.Count
+			existing.Last = entry.Last
+		case len(digest.Entries) < e.config.MaxEntries:
+			digest.Entries = append(digest.Entries, entry)
+			e.index[key] = entry
+		default:
+			digest.Omitted += entry.Count
+		}
+	}
+}
+
+// groupKey identifies the digest entry of a log
+func groupKey(level, source, message string) string {
+	return level + "\x00" + source + "\x00" + message
+}
+
+// trimSent drops send times older than an hour
+func trimSent(sent []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(sent) && now.Sub(sent[i]) >= time.Hour {
+		i++
+	}
+	return sent[i:]
+}
+
+// sendDigest renders the templates and sends the email
+func (e *EmailOutput) sendDigest(digest *Digest) error {
+	sort.SliceStable(digest.Entries, func(i, j int) bool {
+		return digest.Entries[i].Count > digest.Entries[j].Count
+	})
+
+	var subject, body bytes.Buffer
+	if err := e.subject.Execute(&subject, digest); err != nil {
+		return fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := e.body.Execute(&body, digest); err != nil {
+		return fmt.Errorf("failed to render body: %w", err)
+	}
+	return e.send(subject.String(), body.String())
+}
+
+// sendMail delivers one email over SMTP
+func (e *EmailOutput) sendMail(subject, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.config.Timeout)*time.Second)
+	defer cancel()
+
+	client, err := e.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if e.config.Auth.Username != "" {
+		auth := smtp.PlainAuth("", e.config.Auth.Username, e.config.Auth.Password, e.config.SMTPHost)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(e.config.From)
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, to := range e.config.To {
+		addr, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(addr.Address); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", addr.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(e.buildMessage(subject, body)); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected email: %w", err)
+	}
+	return client.Quit()
+}
+
+// connect opens an SMTP session, secured according to the TLS mode
+func (e *EmailOutput) connect(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.config.SMTPHost, strconv.Itoa(e.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if e.config.TLS.Mode == TLSModeImplicit {
+		conn = tls.Client(conn, e.tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, e.config.SMTPHost)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("SMTP handshake with %s failed: %w", addr, err)
+	}
+	if e.config.TLS.Mode == TLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(e.tlsConfig); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("STARTTLS with %s failed: %w", addr, err)
+		}
+	}
+	return client, nil
+}
+
+// buildMessage formats the email headers and a quoted-printable UTF-8 body
+func (e *EmailOutput) buildMessage(subject, body string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&msg)
+	_, _ = w.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	_ = w.Close()
+	return msg.Bytes()
+}
+
+// CheckHealth implements HealthChecker interface by opening an SMTP session
+func (e *EmailOutput) CheckHealth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.config.Timeout)*time.Second)
+	defer cancel()
+
+	client, err := e.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	if err := client.Noop(); err != nil {
+		return fmt.Errorf("SMTP server unhealthy: %w", err)
+	}
+	return client.Quit()
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (e *EmailOutput) OutputStats() map[string]any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pending := 0
+	if e.pending != nil {
+		pending = e.pending.Count
+	}
+	return map[string]any{
+		"pending_logs":      pending,
+		"emails_sent":       e.emailsSent,
+		"send_errors":       e.sendErrors,
+		"throttled_flushes": e.throttled,
+	}
+}
+
+// Close sends the pending digest, if the hourly limit allows, and stops the output
+func (e *EmailOutput) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.stopCh)
+	e.wg.Wait()
+	e.flush(time.Now())
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending != nil {
+		logger.Printf("Discarding %d unsent log(s) on close", e.pending.Count)
+		e.pending = nil
+		e.index = nil
+	}
+	return nil
+}
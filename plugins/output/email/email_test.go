@@ -0,0 +1,367 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func testConfig() Config {
+	return Config{
+		SMTPHost: "localhost",
+		From:     "LogAnalyzer <alerts@example.com>",
+		To:       []string{"oncall@example.com"},
+		Window:   time.Hour, // Tests flush explicitly
+		TLS:      TLSConfig{Mode: TLSModeNone},
+	}
+}
+
+type sentEmail struct {
+	subject string
+	body    string
+}
+
+// newTestOutput creates an output whose emails are recorded instead of sent
+func newTestOutput(t *testing.T, config Config, sendErr ...error) (*EmailOutput, *[]sentEmail) {
+	t.Helper()
+	output, err := NewEmailOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create email output: %v", err)
+	}
+	t.Cleanup(func() { _ = output.Close() })
+
+	var mu sync.Mutex
+	sent := &[]sentEmail{}
+	output.send = func(subject, body string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(sendErr) > 0 {
+			err := sendErr[0]
+			sendErr = sendErr[1:]
+			if err != nil {
+				return err
+			}
+		}
+		*sent = append(*sent, sentEmail{subject, body})
+		return nil
+	}
+	return output, sent
+}
+
+func TestNewEmailOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(*Config)
+		expectError bool
+	}{
+		{name: "valid config", modify: func(c *Config) {}},
+		{name: "missing smtp_host", modify: func(c *Config) { c.SMTPHost = "" }, expectError: true},
+		{name: "missing from", modify: func(c *Config) { c.From = "" }, expectError: true},
+		{name: "missing to", modify: func(c *Config) { c.To = nil }, expectError: true},
+		{name: "invalid to", modify: func(c *Config) { c.To = []string{"not an address"} }, expectError: true},
+		{name: "username without password", modify: func(c *Config) { c.Auth.Username = "user" }, expectError: true},
+		{name: "invalid tls mode", modify: func(c *Config) { c.TLS.Mode = "ssl" }, expectError: true},
+		{name: "template syntax error", modify: func(c *Config) { c.SubjectTemplate = "{{.Count" }, expectError: true},
+		{name: "template unknown field", modify: func(c *Config) { c.BodyTemplate = "{{.Hostname}}" }, expectError: true},
+		{name: "template unknown metadata key", modify: func(c *Config) { c.BodyTemplate = "{{range .Entries}}{{.Metadata.service}}{{end}}" }, expectError: true},
+		{name: "template optional metadata key", modify: func(c *Config) {
+			c.BodyTemplate = `{{range .Entries}}{{index .Metadata "service"}}{{end}}`
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			tt.modify(&config)
+			output, err := NewEmailOutput(config)
+			if tt.expectError {
+				if err == nil {
+					_ = output.Close()
+					t.Error("Expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_ = output.Close()
+		})
+	}
+}
+
+func TestEmailOutputDefaults(t *testing.T) {
+	config := testConfig()
+	config.Window = 0
+	config.TLS.Mode = ""
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Port != 587 || config.Window != 5*time.Minute || config.MaxEmailsPerHour != 6 ||
+		config.MaxEntries != 50 || config.TLS.Mode != TLSModeStartTLS {
+		t.Errorf("Unexpected defaults: %+v", config)
+	}
+}
+
+func TestEmailOutputDigest(t *testing.T) {
+	output, sent := newTestOutput(t, testConfig())
+
+	for range 3 {
+		_ = output.Write(core.NewLog("error", "database down"))
+	}
+	_ = output.Write(core.NewLog("warn", "slow query"))
+	output.flush(time.Now())
+
+	if len(*sent) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(*sent))
+	}
+	email := (*sent)[0]
+	if email.subject != "[LogAnalyzer] 4 error log(s)" {
+		t.Errorf("Unexpected subject %q", email.subject)
+	}
+	if !strings.Contains(email.body, "[error] database down\n  3x") || !strings.Contains(email.body, "[warn] slow query\n  1x") {
+		t.Errorf("Expected grouped entries in body, got:\n%s", email.body)
+	}
+	if strings.Index(email.body, "database down") > strings.Index(email.body, "slow query") {
+		t.Error("Expected the most frequent entry first")
+	}
+
+	// Nothing pending, nothing sent
+	output.flush(time.Now())
+	if len(*sent) != 1 {
+		t.Errorf("Expected no email for an empty window, got %d", len(*sent))
+	}
+}
+
+func TestEmailOutputMaxEntries(t *testing.T) {
+	config := testConfig()
+	config.MaxEntries = 2
+	output, sent := newTestOutput(t, config)
+
+	for _, message := range []string{"a", "b", "c", "a", "d"} {
+		_ = output.Write(core.NewLog("error", message))
+	}
+	output.flush(time.Now())
+
+	if len(*sent) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(*sent))
+	}
+	if !strings.Contains((*sent)[0].body, "2 more log(s) not shown") {
+		t.Errorf("Expected omitted logs to be reported, got:\n%s", (*sent)[0].body)
+	}
+}
+
+func TestEmailOutputThrottle(t *testing.T) {
+	config := testConfig()
+	config.MaxEmailsPerHour = 1
+	output, sent := newTestOutput(t, config)
+
+	now := time.Now()
+	_ = output.Write(core.NewLog("error", "first"))
+	output.flush(now)
+	_ = output.Write(core.NewLog("error", "second"))
+	output.flush(now.Add(5 * time.Minute))
+	_ = output.Write(core.NewLog("error", "third"))
+	output.flush(now.Add(10 * time.Minute))
+
+	if len(*sent) != 1 {
+		t.Fatalf("Expected 1 email within the hour, got %d", len(*sent))
+	}
+	stats := output.OutputStats()
+	if stats["pending_logs"] != 2 || stats["throttled_flushes"] != int64(2) {
+		t.Errorf("Expected 2 pending logs and 2 throttled flushes, got %v", stats)
+	}
+
+	// Once the hour has passed the held logs go out together
+	output.flush(now.Add(time.Hour))
+	if len(*sent) != 2 {
+		t.Fatalf("Expected 2 emails, got %d", len(*sent))
+	}
+	if (*sent)[1].subject != "[LogAnalyzer] 2 error log(s)" {
+		t.Errorf("Unexpected subject %q", (*sent)[1].subject)
+	}
+}
+
+func TestEmailOutputRequeueOnError(t *testing.T) {
+	output, sent := newTestOutput(t, testConfig(), errors.New("connection refused"))
+
+	_ = output.Write(core.NewLog("error", "database down"))
+	output.flush(time.Now())
+	if len(*sent) != 0 {
+		t.Fatalf("Expected the send to fail, got %d emails", len(*sent))
+	}
+
+	_ = output.Write(core.NewLog("error", "database down"))
+	_ = output.Write(core.NewLog("info", "recovered"))
+	output.flush(time.Now())
+
+	if len(*sent) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(*sent))
+	}
+	if (*sent)[0].subject != "[LogAnalyzer] 3 error log(s)" || !strings.Contains((*sent)[0].body, "database down\n  2x") {
+		t.Errorf("Expected the failed digest merged into the next one, got %q:\n%s", (*sent)[0].subject, (*sent)[0].body)
+	}
+	if stats := output.OutputStats(); stats["send_errors"] != int64(1) || stats["emails_sent"] != int64(1) {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+}
+
+func TestEmailOutputWriteAfterClose(t *testing.T) {
+	output, _ := newTestOutput(t, testConfig())
+	_ = output.Close()
+	if err := output.Write(core.NewLog("error", "late")); err == nil {
+		t.Error("Expected an error writing to a closed output")
+	}
+}
+
+// fakeSMTPServer accepts SMTP sessions and records the DATA of each message
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch command {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+func TestEmailOutputSMTP(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	config := testConfig()
+	config.Port = server.listener.Addr().(*net.TCPAddr).Port
+
+	output, err := NewEmailOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create email output: %v", err)
+	}
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Fatalf("Expected a healthy SMTP server, got %v", err)
+	}
+
+	_ = output.Write(core.NewLog("error", "disk full on /var"))
+	if err := output.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("Expected the pending digest to be sent on close, got %d messages", len(messages))
+	}
+	for _, expected := range []string{
+		"From: LogAnalyzer <alerts@example.com>\r\n",
+		"To: oncall@example.com\r\n",
+		"Subject: [LogAnalyzer] 1 error log(s)\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"[error] disk full on /var",
+	} {
+		if !strings.Contains(messages[0], expected) {
+			t.Errorf("Expected message to contain %q, got:\n%s", expected, messages[0])
+		}
+	}
+}
+
+func TestEmailOutputStartTLSRequired(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	config := testConfig()
+	config.Port = server.listener.Addr().(*net.TCPAddr).Port
+	config.TLS.Mode = TLSModeStartTLS
+
+	output, err := NewEmailOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create email output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	err = output.CheckHealth(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Expected a STARTTLS error, got %v", err)
+	}
+}
+
+func TestEmailOutputHealthUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	config := testConfig()
+	config.Port = port
+	output, err := NewEmailOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create email output: %v", err)
+	}
+	defer func() { _ = output.Close() }()
+
+	if err := output.CheckHealth(context.Background()); err == nil || !strings.Contains(err.Error(), strconv.Itoa(port)) {
+		t.Errorf("Expected a connection error, got %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+)
+
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.fallback")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("fallback", NewFallbackOutputFromConfig)
+}
+
+const (
+	defaultFailureThreshold = 1
+	defaultRetryInterval    = 30 * time.Second
+
+	// maxHealthTimeout bounds each primary health check
+	maxHealthTimeout = 10 * time.Second
+)
+
+// Config represents fallback output configuration
+type Config struct {
+	Primary          ChildConfig   `yaml:"primary"`                     // Output that normally receives the logs
+	Secondary        ChildConfig   `yaml:"secondary"`                   // Output used while the primary is unavailable
+	FailureThreshold int           `yaml:"failure_threshold,omitempty"` // Consecutive primary errors before switching (default: 1)
+	RetryInterval    time.Duration `yaml:"retry_interval,omitempty"`    // How often an unavailable primary is retried (default: 30s)
+}
+
+// ChildConfig is one of the wrapped outputs
+type ChildConfig struct {
+	Type   string         `yaml:"type"`
+	Config map[string]any `yaml:"config,omitempty"`
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.Primary.Type == "" || c.Secondary.Type == "" {
+		return fmt.Errorf("primary.type and secondary.type are required")
+	}
+	if c.FailureThreshold < 0 || c.RetryInterval < 0 {
+		return fmt.Errorf("failure_threshold and retry_interval must be non-negative")
+	}
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = defaultFailureThreshold
+	}
+	if c.RetryInterval == 0 {
+		c.RetryInterval = defaultRetryInterval
+	}
+	return nil
+}
+
+// NewFallbackOutputFromConfig creates a fallback output from configuration map
+func NewFallbackOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewFallbackOutput(cfg)
+}
+
+// FallbackOutput writes to a primary output and switches to a secondary output
+// while the primary is unavailable. Unlike buffering or the DLQ, logs are not held
+// for a later retry: they go to the secondary immediately.
+//
+// The primary becomes unavailable after failure_threshold consecutive write errors,
+// or when its health check fails. While it is unavailable it is retried every
+// retry_interval: with its health check when it has one, otherwise by sending it
+// the next log.
+type FallbackOutput struct {
+	config    Config
+	primary   core.OutputPlugin
+	secondary core.OutputPlugin
+	checker   core.HealthChecker // The primary's health check (nil if it has none)
+
+	mu          sync.Mutex
+	available   bool      // Whether logs go to the primary
+	failures    int       // Consecutive primary write errors
+	lastAttempt time.Time // Last retry of an unavailable primary without a health check
+	closed      bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	// Counters reported through OutputStats
+	primaryWrites  int64
+	fallbackWrites int64
+	primaryErrors  int64
+	failedWrites   int64
+	switches       int64
+}
+
+// NewFallbackOutput creates a new fallback output and both child outputs
+func NewFallbackOutput(config Config) (*FallbackOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	primary, err := core.CreateOutputPlugin(config.Primary.Type, config.Primary.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s primary output: %w", config.Primary.Type, err)
+	}
+	secondary, err := core.CreateOutputPlugin(config.Secondary.Type, config.Secondary.Config)
+	if err != nil {
+		_ = primary.Close()
+		return nil, fmt.Errorf("failed to create %s secondary output: %w", config.Secondary.Type, err)
+	}
+
+	f := &FallbackOutput{
+		config:    config,
+		primary:   primary,
+		secondary: secondary,
+		available: true,
+		stopCh:    make(chan struct{}),
+	}
+	if checker, ok := primary.(core.HealthChecker); ok {
+		f.checker = checker
+		f.wg.Add(1)
+		go f.healthLoop()
+	}
+
+	return f, nil
+}
+
+// Write sends the log to the primary output, or to the secondary when the primary
+// is unavailable or the write fails. An error is returned only if the log could not
+// be written anywhere.
+func (f *FallbackOutput) Write(logEntry *core.Log) error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return fmt.Errorf("fallback output is closed")
+	}
+	usePrimary := f.usePrimaryLocked(time.Now())
+	f.mu.Unlock()
+
+	var primaryErr error
+	if usePrimary {
+		primaryErr = f.primary.Write(logEntry)
+		f.recordPrimaryWrite(primaryErr)
+		if primaryErr == nil {
+			return nil
+		}
+	}
+
+	if err := f.secondary.Write(logEntry); err != nil {
+		f.mu.Lock()
+		f.failedWrites++
+		f.mu.Unlock()
+		if primaryErr != nil {
+			return fmt.Errorf("primary %s output failed: %v; secondary %s output failed: %w", f.config.Primary.Type, primaryErr, f.config.Secondary.Type, err)
+		}
+		return fmt.Errorf("primary %s output unavailable; secondary %s output failed: %w", f.config.Primary.Type, f.config.Secondary.Type, err)
+	}
+
+	f.mu.Lock()
+	f.fallbackWrites++
+	f.mu.Unlock()
+	return nil
+}
+
+// usePrimaryLocked decides whether a log goes to the primary (mu must be held). An
+// unavailable primary without a health check gets one log per retry_interval.
+func (f *FallbackOutput) usePrimaryLocked(now time.Time) bool {
+	if f.available {
+		return true
+	}
+	if f.checker != nil || now.Sub(f.lastAttempt) < f.config.RetryInterval {
+		return false
+	}
+	f.lastAttempt = now
+	return true
+}
+
+// recordPrimaryWrite updates the primary's availability after a write
+func (f *FallbackOutput) recordPrimaryWrite(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.primaryWrites++
+		f.failures = 0
+		f.setAvailableLocked(true, nil)
+		return
+	}
+
+	f.primaryErrors++
+	f.failures++
+	if f.failures >= f.config.FailureThreshold {
+		f.setAvailableLocked(false, err)
+	}
+}
+
+// setAvailableLocked switches between the primary and secondary output (mu must be held)
+func (f *FallbackOutput) setAvailableLocked(available bool, reason error) {
+	if f.available == available {
+		return
+	}
+	f.available = available
+	f.switches++
+	if available {
+		logger.Printf("Primary %s output recovered, switching back from %s", f.config.Primary.Type, f.config.Secondary.Type)
+		return
+	}
+	f.lastAttempt = time.Now()
+	logger.Printf("Primary %s output unavailable (%v), writing to %s", f.config.Primary.Type, reason, f.config.Secondary.Type)
+}
+
+// healthLoop checks the primary's health every retry_interval until the output is closed
+func (f *FallbackOutput) healthLoop() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.config.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.checkPrimary()
+		case <-f.stopCh:
+			return
+		}
+	}
+}
+
+// checkPrimary runs the primary's health check and updates its availability
+func (f *FallbackOutput) checkPrimary() {
+	ctx, cancel := context.WithTimeout(context.Background(), min(f.config.RetryInterval, maxHealthTimeout))
+	defer cancel()
+	err := f.checker.CheckHealth(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		f.setAvailableLocked(false, fmt.Errorf("health check failed: %w", err))
+		return
+	}
+	f.failures = 0
+	f.setAvailableLocked(true, nil)
+}
+
+// CheckHealth implements HealthChecker interface. The output is healthy while the
+// primary is available, and otherwise as healthy as the secondary.
+func (f *FallbackOutput) CheckHealth(ctx context.Context) error {
+	f.mu.Lock()
+	available := f.available
+	f.mu.Unlock()
+
+	if available {
+		return nil
+	}
+	if checker, ok := f.secondary.(core.HealthChecker); ok {
+		if err := checker.CheckHealth(ctx); err != nil {
+			return fmt.Errorf("primary unavailable and secondary unhealthy: %w", err)
+		}
+	}
+	return nil
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (f *FallbackOutput) OutputStats() map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return map[string]any{
+		"primary_available": f.available,
+		"primary_writes":    f.primaryWrites,
+		"fallback_writes":   f.fallbackWrites,
+		"primary_errors":    f.primaryErrors,
+		"failed_writes":     f.failedWrites,
+		"switches":          f.switches,
+	}
+}
+
+// Close stops the health checks and closes both outputs
+func (f *FallbackOutput) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.mu.Unlock()
+
+	close(f.stopCh)
+	f.wg.Wait()
+
+	return errors.Join(f.primary.Close(), f.secondary.Close())
+}
@@ -0,0 +1,243 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+// testOutput records writes and fails them on demand
+type testOutput struct {
+	mu        sync.Mutex
+	logs      []*core.Log
+	failWrite bool
+	closed    bool
+}
+
+// checkedOutput is a testOutput with a health check
+type checkedOutput struct {
+	testOutput
+	healthErr error
+}
+
+var (
+	testOutputsMu sync.Mutex
+	testOutputs   = map[string]core.OutputPlugin{}
+)
+
+func init() {
+	core.RegisterOutputPlugin("fallback_test", func(config map[string]any) (any, error) {
+		testOutputsMu.Lock()
+		defer testOutputsMu.Unlock()
+		id, _ := config["id"].(string)
+		output, ok := testOutputs[id]
+		if !ok {
+			return nil, errors.New("unknown test output")
+		}
+		return output, nil
+	})
+}
+
+func (o *testOutput) Write(log *core.Log) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failWrite {
+		return errors.New("write failed")
+	}
+	o.logs = append(o.logs, log)
+	return nil
+}
+
+func (o *testOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	return nil
+}
+
+func (o *testOutput) setFail(fail bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.failWrite = fail
+}
+
+func (o *testOutput) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.logs)
+}
+
+func (o *checkedOutput) CheckHealth(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.healthErr
+}
+
+func (o *checkedOutput) setHealth(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.healthErr = err
+}
+
+// newTestFallback registers the children under unique ids and creates the fallback output
+func newTestFallback(t *testing.T, primary, secondary core.OutputPlugin, config Config) *FallbackOutput {
+	t.Helper()
+	testOutputsMu.Lock()
+	testOutputs[t.Name()+"/primary"] = primary
+	testOutputs[t.Name()+"/secondary"] = secondary
+	testOutputsMu.Unlock()
+
+	config.Primary = ChildConfig{Type: "fallback_test", Config: map[string]any{"id": t.Name() + "/primary"}}
+	config.Secondary = ChildConfig{Type: "fallback_test", Config: map[string]any{"id": t.Name() + "/secondary"}}
+	output, err := NewFallbackOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create fallback output: %v", err)
+	}
+	t.Cleanup(func() { _ = output.Close() })
+	return output
+}
+
+func TestNewFallbackOutputValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "missing primary", config: Config{Secondary: ChildConfig{Type: "null"}}},
+		{name: "missing secondary", config: Config{Primary: ChildConfig{Type: "null"}}},
+		{name: "negative threshold", config: Config{Primary: ChildConfig{Type: "null"}, Secondary: ChildConfig{Type: "null"}, FailureThreshold: -1}},
+		{name: "unknown child type", config: Config{Primary: ChildConfig{Type: "nope"}, Secondary: ChildConfig{Type: "null"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFallbackOutput(tt.config); err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestFallbackOutputSwitchesAfterThreshold(t *testing.T) {
+	primary, secondary := &testOutput{}, &testOutput{}
+	output := newTestFallback(t, primary, secondary, Config{FailureThreshold: 2, RetryInterval: time.Hour})
+
+	if err := output.Write(core.NewLog("info", "ok")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	primary.setFail(true)
+	for range 3 {
+		if err := output.Write(core.NewLog("info", "failover")); err != nil {
+			t.Fatalf("Expected the secondary to take the log, got %v", err)
+		}
+	}
+
+	// Both failed writes were attempted on the primary, the third went straight to the secondary
+	stats := output.OutputStats()
+	expected := map[string]any{
+		"primary_available": false,
+		"primary_writes":    int64(1),
+		"fallback_writes":   int64(3),
+		"primary_errors":    int64(2),
+		"failed_writes":     int64(0),
+		"switches":          int64(1),
+	}
+	for key, value := range expected {
+		if stats[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, stats[key])
+		}
+	}
+	if primary.count() != 1 || secondary.count() != 3 {
+		t.Errorf("Expected 1 primary and 3 secondary logs, got %d and %d", primary.count(), secondary.count())
+	}
+}
+
+func TestFallbackOutputRetriesPrimaryWithoutHealthCheck(t *testing.T) {
+	primary, secondary := &testOutput{}, &testOutput{}
+	output := newTestFallback(t, primary, secondary, Config{RetryInterval: time.Hour})
+
+	primary.setFail(true)
+	_ = output.Write(core.NewLog("info", "a"))
+	primary.setFail(false)
+	_ = output.Write(core.NewLog("info", "b"))
+	if primary.count() != 0 {
+		t.Fatal("Expected the primary not to be retried before retry_interval")
+	}
+
+	// Once the interval has passed the next log probes the primary
+	output.mu.Lock()
+	output.lastAttempt = time.Now().Add(-time.Hour)
+	output.mu.Unlock()
+	_ = output.Write(core.NewLog("info", "c"))
+	_ = output.Write(core.NewLog("info", "d"))
+
+	if primary.count() != 2 || secondary.count() != 2 {
+		t.Errorf("Expected 2 logs on each output, got %d and %d", primary.count(), secondary.count())
+	}
+	if stats := output.OutputStats(); stats["primary_available"] != true || stats["switches"] != int64(2) {
+		t.Errorf("Expected the primary to be available again, got %v", stats)
+	}
+}
+
+func TestFallbackOutputHealthAware(t *testing.T) {
+	primary, secondary := &checkedOutput{}, &testOutput{}
+	output := newTestFallback(t, primary, secondary, Config{RetryInterval: time.Hour})
+
+	primary.setHealth(errors.New("cluster red"))
+	output.checkPrimary()
+	_ = output.Write(core.NewLog("info", "a"))
+	if primary.count() != 0 || secondary.count() != 1 {
+		t.Fatalf("Expected an unhealthy primary to be skipped, got %d and %d", primary.count(), secondary.count())
+	}
+
+	// With a health check, logs are not used to probe the primary
+	output.mu.Lock()
+	output.lastAttempt = time.Now().Add(-time.Hour)
+	output.mu.Unlock()
+	_ = output.Write(core.NewLog("info", "b"))
+	if primary.count() != 0 {
+		t.Fatal("Expected the primary to wait for a passing health check")
+	}
+
+	primary.setHealth(nil)
+	output.checkPrimary()
+	_ = output.Write(core.NewLog("info", "c"))
+	if primary.count() != 1 || secondary.count() != 2 {
+		t.Errorf("Expected the primary back after a passing health check, got %d and %d", primary.count(), secondary.count())
+	}
+}
+
+func TestFallbackOutputBothFail(t *testing.T) {
+	primary, secondary := &testOutput{}, &testOutput{}
+	output := newTestFallback(t, primary, secondary, Config{})
+
+	primary.setFail(true)
+	secondary.setFail(true)
+	if err := output.Write(core.NewLog("info", "lost")); err == nil {
+		t.Fatal("Expected an error when both outputs fail")
+	}
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected no health error without a secondary health check, got %v", err)
+	}
+	if stats := output.OutputStats(); stats["failed_writes"] != int64(1) {
+		t.Errorf("Expected 1 failed write, got %v", stats["failed_writes"])
+	}
+}
+
+func TestFallbackOutputClose(t *testing.T) {
+	primary, secondary := &checkedOutput{}, &testOutput{}
+	output := newTestFallback(t, primary, secondary, Config{})
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !primary.closed || !secondary.closed {
+		t.Error("Expected both outputs to be closed")
+	}
+	if err := output.Write(core.NewLog("info", "late")); err == nil {
+		t.Error("Expected an error writing to a closed output")
+	}
+}
@@ -17,6 +17,9 @@ func init() {
 // Config represents file output configuration
 type Config struct {
 	FilePath string `yaml:"file_path"`
+	Format   string `yaml:"format,omitempty"` // "text" (default), "json", or "raw" (alias: "passthrough")
+
+	core.FieldStyle `yaml:",inline"` // Metadata rendering for the json format
 }
 
 // NewFileOutputFromConfig creates a file output from configuration map
@@ -32,6 +35,8 @@ func NewFileOutputFromConfig(config map[string]any) (any, error) {
 // FileOutput represents a file output plugin
 type FileOutput struct {
 	filePath string
+	format   string
+	encoder  *core.LogEncoder
 	file     *os.File
 	writer   *bufio.Writer
 	mu       sync.Mutex
@@ -43,6 +48,24 @@ func NewFileOutput(config Config) (*FileOutput, error) {
 		return nil, fmt.Errorf("file path cannot be empty")
 	}
 
+	// Set defaults
+	switch config.Format {
+	case "":
+		config.Format = "text"
+	case "passthrough":
+		config.Format = "raw"
+	}
+
+	// Validate format
+	if config.Format != "text" && config.Format != "json" && config.Format != "raw" {
+		return nil, fmt.Errorf("invalid format '%s', must be 'text', 'json' or 'raw'", config.Format)
+	}
+
+	encoder, err := core.NewLogEncoder(config.FieldStyle, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
 	file, err := os.OpenFile(config.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s: %w", config.FilePath, err)
@@ -52,6 +75,8 @@ func NewFileOutput(config Config) (*FileOutput, error) {
 
 	return &FileOutput{
 		filePath: config.FilePath,
+		format:   config.Format,
+		encoder:  encoder,
 		file:     file,
 		writer:   writer,
 	}, nil
@@ -63,7 +88,20 @@ func (f *FileOutput) Write(log *core.Log) error {
 	defer f.mu.Unlock()
 
 	// Format log entry
-	line := fmt.Sprintf("[%s] %s: %s\n", log.Timestamp.Format("2006-01-02 15:04:05"), log.Level, log.Message)
+	var line string
+	switch f.format {
+	case "raw":
+		// Verbatim message, no timestamp, level or metadata
+		line = log.Message + "\n"
+	case "json":
+		data, err := f.encoder.Marshal(log)
+		if err != nil {
+			return fmt.Errorf("failed to encode log: %w", err)
+		}
+		line = string(data) + "\n"
+	default:
+		line = fmt.Sprintf("[%s] %s: %s\n", log.Timestamp.Format("2006-01-02 15:04:05"), log.Level, log.Message)
+	}
 
 	// Write to file
 	if _, err := f.writer.WriteString(line); err != nil {
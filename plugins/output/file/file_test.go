@@ -199,3 +199,86 @@ func TestFileOutputConcurrency(t *testing.T) {
 		t.Errorf("Expected 10 lines, got %d", len(lines))
 	}
 }
+
+func TestFileOutputRawFormat(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "test.log")
+
+	output, err := NewFileOutput(Config{FilePath: filePath, Format: "passthrough"})
+	if err != nil {
+		t.Fatalf("NewFileOutput failed: %v", err)
+	}
+
+	testLog := core.Log{
+		Timestamp: time.Now(),
+		Level:     "error",
+		Message:   `{"level":"error","msg":"disk full"}`,
+		Metadata:  map[string]string{"source": "http"},
+	}
+	if err := output.Write(&testLog); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	expected := `{"level":"error","msg":"disk full"}` + "\n"
+	if string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(content))
+	}
+}
+
+func TestFileOutputInvalidFormat(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "test.log")
+
+	if _, err := NewFileOutput(Config{FilePath: filePath, Format: "xml"}); err == nil {
+		t.Error("Expected error for invalid format, got nil")
+	}
+}
+
+func TestFileOutputJSONFlatFormat(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "test.log")
+
+	output, err := NewFileOutput(Config{
+		FilePath:   filePath,
+		Format:     "json",
+		FieldStyle: core.FieldStyle{Style: "flat", Prefix: "meta"},
+	})
+	if err != nil {
+		t.Fatalf("NewFileOutput failed: %v", err)
+	}
+
+	testLog := core.Log{
+		Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:     "warn",
+		Message:   "slow request",
+		Metadata:  map[string]string{"service": "api"},
+	}
+	if err := output.Write(&testLog); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	expected := `{"timestamp":"2025-01-02T03:04:05Z","level":"warn","message":"slow request","meta_service":"api"}` + "\n"
+	if string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(content))
+	}
+
+	if _, err := NewFileOutput(Config{FilePath: filePath, Format: "json", FieldStyle: core.FieldStyle{Style: "tree"}}); err == nil {
+		t.Error("Expected error for invalid field_style, got nil")
+	}
+}
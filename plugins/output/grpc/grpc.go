@@ -0,0 +1,468 @@
+package grpcoutput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.grpc")
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("grpc", NewGRPCOutputFromConfig)
+}
+
+const (
+	DefaultMethod         = "/loganalyzer.v1.LogSink/Push" // Push of logsink.proto
+	DefaultBatchSize      = 100                            // default logs per LogBatch message
+	DefaultMaxBatchBytes  = 4000000                        // default bytes per message, under gRPC's default 4MB receive limit
+	DefaultFlushInterval  = 1                              // default seconds between flushes of a partial batch
+	DefaultSendTimeout    = 10                             // default seconds a batch may wait for the server to read it
+	DefaultMaxPendingLogs = 10000                          // default logs kept while the stream is down
+	DefaultTimeout        = 10                             // default seconds to connect or close a stream
+)
+
+// pushStream describes the client stream of LogSink.Push
+var pushStream = grpc.StreamDesc{StreamName: "Push", ClientStreams: true}
+
+// Config represents gRPC output configuration
+type Config struct {
+	Endpoint      string            `yaml:"endpoint"`                  // Required: host:port of the LogSink service
+	Method        string            `yaml:"method,omitempty"`          // Full method name (default: /loganalyzer.v1.LogSink/Push)
+	Headers       map[string]string `yaml:"headers,omitempty"`         // Metadata sent with every stream (e.g. authorization)
+	BatchSize     int               `yaml:"batch_size,omitempty"`      // Logs per LogBatch message (default: 100)
+	MaxBatchBytes int               `yaml:"max_batch_bytes,omitempty"` // Bytes per message; larger batches are split (default: 4000000)
+	FlushInterval int               `yaml:"flush_interval,omitempty"`  // Seconds before a partial batch is sent (default: 1)
+	SendTimeout   int               `yaml:"send_timeout,omitempty"`    // Seconds a batch may wait for the server to read it before the stream is reset (default: 10)
+	MaxPending    int               `yaml:"max_pending,omitempty"`     // Logs kept while the stream is down; the oldest are dropped first (default: 10000)
+	Timeout       int               `yaml:"timeout,omitempty"`         // Seconds to connect for the health check or close a stream (default: 10)
+
+	TLS tlsconfig.Config `yaml:"tls,omitempty"` // TLS configuration (default: plaintext)
+
+	core.LevelFlush `yaml:",inline"` // Early flush of batches holding important logs
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("endpoint is required")
+	}
+	if c.Method != "" && (!strings.HasPrefix(c.Method, "/") || strings.Count(c.Method, "/") != 2) {
+		return fmt.Errorf("invalid method '%s', must be /package.Service/Method", c.Method)
+	}
+	for key := range c.Headers {
+		if key == "" || strings.HasPrefix(strings.ToLower(key), "grpc-") {
+			return fmt.Errorf("invalid header %q: header names must be set and not start with grpc-", key)
+		}
+	}
+	if c.BatchSize < 0 || c.MaxBatchBytes < 0 || c.FlushInterval < 0 || c.SendTimeout < 0 || c.MaxPending < 0 || c.Timeout < 0 {
+		return fmt.Errorf("batch_size, max_batch_bytes, flush_interval, send_timeout, max_pending and timeout must be non-negative")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid TLS config: %w", err)
+	}
+	if err := c.LevelFlush.Validate(); err != nil {
+		return err
+	}
+
+	if c.Method == "" {
+		c.Method = DefaultMethod
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.MaxBatchBytes == 0 {
+		c.MaxBatchBytes = DefaultMaxBatchBytes
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	if c.SendTimeout == 0 {
+		c.SendTimeout = DefaultSendTimeout
+	}
+	if c.MaxPending == 0 {
+		c.MaxPending = DefaultMaxPendingLogs
+	}
+	if c.MaxPending < c.BatchSize {
+		return fmt.Errorf("max_pending (%d) must be at least batch_size (%d)", c.MaxPending, c.BatchSize)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	return nil
+}
+
+// NewGRPCOutputFromConfig creates a gRPC output from configuration map
+func NewGRPCOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewGRPCOutput(cfg)
+}
+
+// GRPCOutput pushes logs to a LogSink service (see logsink.proto) on a
+// long-lived client stream, one LogBatch message per batch. A batch is sent once
+// it reaches batch_size, after flush_interval, or early when a log at
+// flush_on_level is added. When a send fails, the stream is dropped and the
+// next send opens a new one; the failed batch stays pending.
+//
+// Sends block while the server's HTTP/2 flow control window is full, so a slow
+// server slows the output down instead of being overrun. A server that stops
+// reading altogether makes the send time out after send_timeout, which resets
+// the stream.
+type GRPCOutput struct {
+	config     Config
+	conn       *grpc.ClientConn
+	headers    metadata.MD
+	levelFlush *core.LevelFlusher
+
+	mu           sync.Mutex         // Serializes batching and sending
+	pending      [][]byte           // Encoded LogEntry messages not sent yet, oldest first
+	stream       grpc.ClientStream  // Open stream, nil until the next send opens one
+	cancelStream context.CancelFunc // Cancels stream
+	closed       bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	// Counters reported through OutputStats, guarded by mu
+	sent     int64 // Logs handed to a stream
+	accepted int64 // Logs the server reported as accepted when its streams ended
+	batches  int64 // Messages sent
+	streams  int64 // Streams opened
+	failures int64 // Sends that failed
+	rejected int64 // Logs refused by the server as invalid, not retried
+	dropped  int64 // Logs discarded over max_pending or at shutdown
+}
+
+// NewGRPCOutput creates a new gRPC output plugin. The connection is made in the
+// background, so an unreachable endpoint does not fail the creation.
+func NewGRPCOutput(config Config) (*GRPCOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if config.TLS.Enabled {
+		tlsConfig, err := config.TLS.NewTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TLS config: %w", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(config.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	o := &GRPCOutput{
+		config:  config,
+		conn:    conn,
+		headers: metadata.New(config.Headers),
+		stopCh:  make(chan struct{}),
+	}
+	o.levelFlush = core.NewLevelFlusher(config.LevelFlush, func() {
+		if err := o.flush(); err != nil {
+			logger.Printf("Flush failed, %d logs stay pending: %v", o.pendingCount(), err)
+		}
+	})
+
+	o.wg.Add(1)
+	go o.periodicFlush()
+
+	return o, nil
+}
+
+// Write adds a log to the batch and sends the batch once it is full, or when the
+// log triggers flush_on_level. When that send fails, the log is handed back with
+// the error, so the output buffer retries it, while the rest of the batch stays
+// pending for the next flush.
+func (o *GRPCOutput) Write(logEntry *core.Log) error {
+	entry := encodeLog(logEntry)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return fmt.Errorf("grpc output is closed")
+	}
+
+	o.pending = append(o.pending, entry)
+	if len(o.pending) < o.config.BatchSize && !o.levelFlush.Added(logEntry) {
+		return nil
+	}
+
+	if err := o.sendPending(); err != nil {
+		// Unless its own batch was refused, the log just written is still the newest pending entry
+		if n := len(o.pending); n > 0 && len(entry) > 0 && &o.pending[n-1][0] == &entry[0] {
+			o.pending = o.pending[:n-1]
+		}
+		return err
+	}
+	return nil
+}
+
+// sendPending sends the pending entries in batches, oldest first, and stops at
+// the first failure. Batches the server refused as invalid are discarded; the
+// others stay pending.
+func (o *GRPCOutput) sendPending() error {
+	for len(o.pending) > 0 {
+		n := chunkSize(o.pending, o.config.BatchSize, o.config.MaxBatchBytes)
+		if err := o.send(o.pending[:n]); err != nil {
+			o.failures++
+			o.resetStream()
+			if rejected(err) {
+				o.rejected += int64(n)
+				o.pending = o.pending[n:]
+			}
+			o.trimPending()
+			return err
+		}
+		o.sent += int64(n)
+		o.batches++
+		o.pending = o.pending[n:]
+	}
+	o.pending = nil
+	return nil
+}
+
+// send sends entries as one LogBatch message, opening a stream if needed. A
+// stream the server ended cleanly (e.g. a maximum stream age) is replaced once.
+func (o *GRPCOutput) send(entries [][]byte) error {
+	message := encodeBatch(entries)
+	for attempt := 1; ; attempt++ {
+		if o.stream == nil {
+			if err := o.openStream(); err != nil {
+				return err
+			}
+		}
+
+		// SendMsg blocks while the server's flow control window is full; cancel the
+		// stream if the server does not read the batch in time
+		timer := time.AfterFunc(time.Duration(o.config.SendTimeout)*time.Second, o.cancelStream)
+		err := o.stream.SendMsg(message)
+		if !timer.Stop() {
+			return fmt.Errorf("server did not read the batch within %ds", o.config.SendTimeout)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to send batch: %w", err)
+		}
+
+		// The server ended the stream; its status tells why
+		if err := o.finishStream(); err != nil {
+			return fmt.Errorf("server ended the stream: %w", err)
+		}
+		if attempt > 1 {
+			return fmt.Errorf("server ended the new stream without reading the batch")
+		}
+	}
+}
+
+// openStream opens a Push stream carrying the configured headers
+func (o *GRPCOutput) openStream() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if len(o.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, o.headers)
+	}
+	stream, err := o.conn.NewStream(ctx, &pushStream, o.config.Method, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open stream to %s: %w", o.config.Endpoint, err)
+	}
+	o.stream = stream
+	o.cancelStream = cancel
+	o.streams++
+	return nil
+}
+
+// finishStream closes the sending side of the stream, waits up to timeout for
+// the server's response and counts the logs it accepted. The stream is gone afterwards.
+func (o *GRPCOutput) finishStream() error {
+	if o.stream == nil {
+		return nil
+	}
+	stream, cancel := o.stream, o.cancelStream
+	o.stream, o.cancelStream = nil, nil
+	defer cancel()
+
+	timer := time.AfterFunc(time.Duration(o.config.Timeout)*time.Second, cancel)
+	defer timer.Stop()
+
+	_ = stream.CloseSend()
+	var response []byte
+	if err := stream.RecvMsg(&response); err != nil {
+		return err
+	}
+	accepted, err := decodeAccepted(response)
+	if err != nil {
+		return err
+	}
+	o.accepted += int64(accepted)
+	return nil
+}
+
+// resetStream cancels the stream after a failure; the next send opens a new one
+func (o *GRPCOutput) resetStream() {
+	if o.stream == nil {
+		return
+	}
+	o.cancelStream()
+	o.stream, o.cancelStream = nil, nil
+}
+
+// rejected reports whether the server refused a batch as invalid, so sending it again cannot succeed
+func rejected(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return true
+	}
+	return false
+}
+
+// chunkSize returns how many of the first entries fit in one message
+func chunkSize(entries [][]byte, batchSize, maxBytes int) int {
+	size := 0
+	for i, entry := range entries {
+		entrySize := len(entry) + 6 // Tag and length prefix
+		if i == batchSize || (i > 0 && size+entrySize > maxBytes) {
+			return i
+		}
+		size += entrySize
+	}
+	return len(entries)
+}
+
+// trimPending drops the oldest entries over max_pending
+func (o *GRPCOutput) trimPending() {
+	if over := len(o.pending) - o.config.MaxPending; over > 0 {
+		o.dropped += int64(over)
+		o.pending = o.pending[over:]
+		logger.Printf("Dropped %d logs over max_pending (%d) while the stream is down", over, o.config.MaxPending)
+	}
+}
+
+// flush sends every pending entry
+func (o *GRPCOutput) flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.sendPending()
+}
+
+// periodicFlush sends partial batches every flush_interval
+func (o *GRPCOutput) periodicFlush() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(o.config.FlushInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.flush(); err != nil {
+				logger.Printf("Flush failed, %d logs stay pending: %v", o.pendingCount(), err)
+			}
+		case <-o.stopCh:
+			return
+		}
+	}
+}
+
+// pendingCount returns the number of entries not sent yet
+func (o *GRPCOutput) pendingCount() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// CheckHealth implements HealthChecker interface by waiting for the connection
+// to the endpoint to be ready
+func (o *GRPCOutput) CheckHealth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(o.config.Timeout)*time.Second)
+	defer cancel()
+
+	o.conn.Connect()
+	for {
+		state := o.conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if state == connectivity.Shutdown || !o.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("grpc endpoint %s is not reachable (connection %s)", o.config.Endpoint, state)
+		}
+	}
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (o *GRPCOutput) OutputStats() map[string]any {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return map[string]any{
+		"sent":         o.sent,
+		"accepted":     o.accepted,
+		"pending":      len(o.pending),
+		"batches":      o.batches,
+		"streams":      o.streams,
+		"failed_sends": o.failures,
+		"rejected":     o.rejected,
+		"dropped":      o.dropped,
+	}
+}
+
+// Close sends the pending logs, ends the stream and closes the connection
+func (o *GRPCOutput) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+
+	o.levelFlush.Stop()
+	close(o.stopCh)
+	o.wg.Wait()
+
+	o.mu.Lock()
+	err := o.sendPending()
+	if len(o.pending) > 0 {
+		o.dropped += int64(len(o.pending))
+		logger.Printf("Dropped %d logs that could not be sent at shutdown: %v", len(o.pending), err)
+		o.pending = nil
+	}
+	if finishErr := o.finishStream(); finishErr != nil {
+		logger.Printf("Error ending stream: %v", finishErr)
+		if err == nil {
+			err = finishErr
+		}
+	}
+	o.mu.Unlock()
+
+	if closeErr := o.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
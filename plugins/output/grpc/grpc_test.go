@@ -0,0 +1,326 @@
+package grpcoutput
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// received is a LogEntry decoded by the fake sink
+type received struct {
+	level    string
+	message  string
+	metadata map[string]string
+	tags     []string
+	stream   int
+}
+
+// sink is a fake LogSink service
+type sink struct {
+	mu            sync.Mutex
+	entries       []received
+	streams       int
+	headers       metadata.MD
+	method        string
+	failStream    int           // Stream ended with Unavailable after its first batch (0: none)
+	stalled       bool          // Streams never read their messages
+	firstStreamCh chan struct{} // Closed when a failing stream ended
+}
+
+func newSink(t *testing.T, configure func(*sink), opts ...grpc.ServerOption) (*sink, string) {
+	s := &sink{firstStreamCh: make(chan struct{})}
+	if configure != nil {
+		configure(s)
+	}
+
+	opts = append(opts, grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(s.handle))
+	server := grpc.NewServer(opts...)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return s, listener.Addr().String()
+}
+
+func (s *sink) handle(_ any, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	headers, _ := metadata.FromIncomingContext(stream.Context())
+
+	s.mu.Lock()
+	s.streams++
+	number := s.streams
+	s.method = method
+	s.headers = headers
+	stalled := s.stalled
+	s.mu.Unlock()
+
+	if stalled {
+		<-stream.Context().Done()
+		return stream.Context().Err()
+	}
+
+	accepted := 0
+	for {
+		var message []byte
+		if err := stream.RecvMsg(&message); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		entries := decodeTestBatch(message, number)
+		s.mu.Lock()
+		s.entries = append(s.entries, entries...)
+		s.mu.Unlock()
+		accepted += len(entries)
+
+		if number == s.failStream {
+			close(s.firstStreamCh)
+			return status.Error(codes.Unavailable, "sink restarting")
+		}
+	}
+	response := protowire.AppendTag(nil, fieldAccepted, protowire.VarintType)
+	response = protowire.AppendVarint(response, uint64(accepted))
+	return stream.SendMsg(response)
+}
+
+func (s *sink) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.entries...)
+}
+
+// decodeTestBatch decodes the LogEntry messages of a LogBatch message
+func decodeTestBatch(b []byte, stream int) []received {
+	var entries []received
+	for len(b) > 0 {
+		_, _, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		value, n := protowire.ConsumeBytes(b)
+		b = b[n:]
+
+		entry := received{metadata: map[string]string{}, stream: stream}
+		for len(value) > 0 {
+			number, wireType, n := protowire.ConsumeTag(value)
+			value = value[n:]
+			if wireType != protowire.BytesType {
+				n = protowire.ConsumeFieldValue(number, wireType, value)
+				value = value[n:]
+				continue
+			}
+			field, n := protowire.ConsumeBytes(value)
+			value = value[n:]
+			switch number {
+			case fieldLevel:
+				entry.level = string(field)
+			case fieldMessage:
+				entry.message = string(field)
+			case fieldTags:
+				entry.tags = append(entry.tags, string(field))
+			case fieldMetadata:
+				_, _, n := protowire.ConsumeTag(field)
+				key, m := protowire.ConsumeString(field[n:])
+				_, _, k := protowire.ConsumeTag(field[n+m:])
+				val, _ := protowire.ConsumeString(field[n+m+k:])
+				entry.metadata[key] = val
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func testOutput(t *testing.T, endpoint string, config Config) *GRPCOutput {
+	config.Endpoint = endpoint
+	if config.FlushInterval == 0 {
+		config.FlushInterval = 3600 // Flushed explicitly by the tests
+	}
+	output, err := NewGRPCOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create grpc output: %v", err)
+	}
+	t.Cleanup(func() { _ = output.Close() })
+	return output
+}
+
+func TestGRPCOutputStream(t *testing.T) {
+	s, endpoint := newSink(t, nil)
+	output := testOutput(t, endpoint, Config{
+		BatchSize: 2,
+		Headers:   map[string]string{"Authorization": "Bearer token"},
+	})
+
+	for _, message := range []string{"one", "two", "three"} {
+		log := core.NewLogWithMetadata("info", message, map[string]string{"host": "web-1"})
+		log.Tags = []string{"prod"}
+		if err := output.Write(log); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.received()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(s.received()); got != 2 {
+		t.Fatalf("Expected a full batch of 2 logs to be sent, got %d", got)
+	}
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	entries := s.received()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 logs after close, got %d", len(entries))
+	}
+	entry := entries[2]
+	if entry.message != "three" || entry.level != "info" || entry.metadata["host"] != "web-1" || len(entry.tags) != 1 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	s.mu.Lock()
+	if s.method != DefaultMethod || s.streams != 1 {
+		t.Errorf("Expected one %s stream, got %d streams of %s", DefaultMethod, s.streams, s.method)
+	}
+	if got := s.headers.Get("authorization"); len(got) != 1 || got[0] != "Bearer token" {
+		t.Errorf("Expected the authorization header, got %v", got)
+	}
+	s.mu.Unlock()
+
+	stats := output.OutputStats()
+	if stats["sent"] != int64(3) || stats["accepted"] != int64(3) || stats["batches"] != int64(2) {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+}
+
+func TestGRPCOutputReopensStream(t *testing.T) {
+	s, endpoint := newSink(t, func(s *sink) { s.failStream = 1 })
+	output := testOutput(t, endpoint, Config{BatchSize: 1})
+
+	if err := output.Write(core.NewLog("info", "before")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	<-s.firstStreamCh
+
+	// Logs are written until one reaches a new stream; writes on the failed stream return errors
+	for i := 0; i < 100; i++ {
+		entries := s.received()
+		if entries[len(entries)-1].stream == 2 {
+			break
+		}
+		_ = output.Write(core.NewLog("info", "after"))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entries := s.received()
+	last := entries[len(entries)-1]
+	if last.stream != 2 || last.message != "after" {
+		t.Fatalf("Expected a log on a second stream, got %+v", last)
+	}
+	stats := output.OutputStats()
+	if stats["streams"] != int64(2) || stats["failed_sends"].(int64) < 1 {
+		t.Errorf("Expected a failed send and a second stream, got %v", stats)
+	}
+}
+
+func TestGRPCOutputSendTimeout(t *testing.T) {
+	// A fixed flow control window makes a server that does not read block the client
+	_, endpoint := newSink(t, func(s *sink) { s.stalled = true },
+		grpc.InitialWindowSize(64*1024), grpc.InitialConnWindowSize(64*1024))
+	output := testOutput(t, endpoint, Config{BatchSize: 1, SendTimeout: 1})
+
+	large := strings.Repeat("x", 32*1024)
+	var err error
+	for i := 0; i < 20 && err == nil; i++ {
+		err = output.Write(core.NewLog("info", large))
+	}
+	if err == nil || !strings.Contains(err.Error(), "did not read the batch") {
+		t.Fatalf("Expected the send to time out, got %v", err)
+	}
+	if stats := output.OutputStats(); stats["failed_sends"] != int64(1) {
+		t.Errorf("Expected one failed send, got %v", stats)
+	}
+}
+
+func TestGRPCOutputCheckHealth(t *testing.T) {
+	_, endpoint := newSink(t, nil)
+	output := testOutput(t, endpoint, Config{})
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected a healthy connection, got %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	unreachable := listener.Addr().String()
+	_ = listener.Close()
+
+	output = testOutput(t, unreachable, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := output.CheckHealth(ctx); err == nil {
+		t.Error("Expected an unreachable endpoint to be unhealthy")
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	entries := [][]byte{make([]byte, 10), make([]byte, 10), make([]byte, 10)}
+	if n := chunkSize(entries, 2, 1000); n != 2 {
+		t.Errorf("Expected batch_size to cap the chunk at 2, got %d", n)
+	}
+	if n := chunkSize(entries, 10, 20); n != 1 {
+		t.Errorf("Expected max_batch_bytes to cap the chunk at 1, got %d", n)
+	}
+	if n := chunkSize(entries, 10, 1); n != 1 {
+		t.Errorf("Expected an oversized entry to be sent alone, got %d", n)
+	}
+}
+
+func TestGRPCConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"missing endpoint", Config{}, "endpoint is required"},
+		{"invalid method", Config{Endpoint: "sink:4317", Method: "Push"}, "invalid method"},
+		{"reserved header", Config{Endpoint: "sink:4317", Headers: map[string]string{"grpc-timeout": "1S"}}, "invalid header"},
+		{"negative batch size", Config{Endpoint: "sink:4317", BatchSize: -1}, "non-negative"},
+		{"pending under batch", Config{Endpoint: "sink:4317", BatchSize: 100, MaxPending: 10}, "max_pending"},
+		{"unknown flush level", Config{Endpoint: "sink:4317", LevelFlush: core.LevelFlush{FlushOnLevel: "loud"}}, "flush_on_level"},
+		{"valid", Config{Endpoint: "sink:4317"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if tt.config.Method != DefaultMethod || tt.config.BatchSize != DefaultBatchSize || tt.config.SendTimeout != DefaultSendTimeout {
+					t.Errorf("Expected defaults to be applied, got %+v", tt.config)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package grpcoutput
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Messages of logsink.proto are encoded by hand with protowire, like the
+// protobuf decoding of the Kafka input, so no generated code is needed.
+
+// Field numbers of loganalyzer.v1.LogEntry
+const (
+	fieldTimestamp  protowire.Number = 1
+	fieldLevel      protowire.Number = 2
+	fieldMessage    protowire.Number = 3
+	fieldMetadata   protowire.Number = 4
+	fieldSource     protowire.Number = 5
+	fieldSourceType protowire.Number = 6
+	fieldTags       protowire.Number = 7
+	fieldTraceID    protowire.Number = 8
+	fieldSpanID     protowire.Number = 9
+	fieldTraceFlags protowire.Number = 10
+)
+
+// fieldLogs is the repeated LogEntry field of LogBatch, fieldAccepted the field of PushResponse
+const (
+	fieldLogs     protowire.Number = 1
+	fieldAccepted protowire.Number = 1
+)
+
+// encodeLog encodes a log as a LogEntry message
+func encodeLog(logEntry *core.Log) []byte {
+	var b []byte
+	if !logEntry.Timestamp.IsZero() {
+		b = protowire.AppendTag(b, fieldTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(logEntry.Timestamp.UnixNano()))
+	}
+	b = appendString(b, fieldLevel, logEntry.Level)
+	b = appendString(b, fieldMessage, logEntry.Message)
+
+	keys := make([]string, 0, len(logEntry.Metadata))
+	for key := range logEntry.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, key)
+		entry = appendString(entry, 2, logEntry.Metadata[key])
+		b = protowire.AppendTag(b, fieldMetadata, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	b = appendString(b, fieldSource, logEntry.Source)
+	b = appendString(b, fieldSourceType, logEntry.SourceType)
+	for _, tag := range logEntry.Tags {
+		b = protowire.AppendTag(b, fieldTags, protowire.BytesType)
+		b = protowire.AppendString(b, tag)
+	}
+	b = appendString(b, fieldTraceID, logEntry.TraceID)
+	b = appendString(b, fieldSpanID, logEntry.SpanID)
+	if logEntry.TraceFlags != 0 {
+		b = protowire.AppendTag(b, fieldTraceFlags, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(logEntry.TraceFlags))
+	}
+	return b
+}
+
+// appendString appends a string field, leaving out empty strings as proto3 does
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// encodeBatch encodes encoded LogEntry messages as a LogBatch message
+func encodeBatch(entries [][]byte) []byte {
+	size := 0
+	for _, entry := range entries {
+		size += protowire.SizeTag(fieldLogs) + protowire.SizeBytes(len(entry))
+	}
+	b := make([]byte, 0, size)
+	for _, entry := range entries {
+		b = protowire.AppendTag(b, fieldLogs, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b
+}
+
+// decodeAccepted returns the accepted field of a PushResponse message
+func decodeAccepted(b []byte) (uint64, error) {
+	var accepted uint64
+	for len(b) > 0 {
+		number, wireType, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, fmt.Errorf("invalid push response: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if number == fieldAccepted && wireType == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, fmt.Errorf("invalid push response: %w", protowire.ParseError(n))
+			}
+			accepted = value
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(number, wireType, b)
+		if n < 0 {
+			return 0, fmt.Errorf("invalid push response: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return accepted, nil
+}
+
+// rawCodec passes already encoded messages to gRPC. It is named "proto", so
+// requests carry the usual application/grpc+proto content type and services
+// built from logsink.proto with generated code can receive them.
+type rawCodec struct{}
+
+// Marshal returns the encoded message
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	switch message := v.(type) {
+	case []byte:
+		return message, nil
+	case *[]byte:
+		return *message, nil
+	}
+	return nil, fmt.Errorf("rawCodec cannot marshal %T", v)
+}
+
+// Unmarshal copies the encoded message
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	message, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec cannot unmarshal into %T", v)
+	}
+	*message = append((*message)[:0], data...)
+	return nil
+}
+
+// Name returns the content subtype
+func (rawCodec) Name() string {
+	return "proto"
+}
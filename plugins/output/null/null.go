@@ -0,0 +1,66 @@
+package null
+
+import (
+	"sync/atomic"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("null", NewNullOutputFromConfig)
+}
+
+// Config represents null output configuration
+type Config struct {
+	Count bool `yaml:"count,omitempty"` // Count discarded logs and report them in stats (default: false)
+}
+
+// NewNullOutputFromConfig creates a null output from configuration map
+func NewNullOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewNullOutput(cfg), nil
+}
+
+// NullOutput discards every log. It is used to benchmark the engine or a set of
+// filters without output cost, and to drop a routed subset of logs on purpose.
+// Writes are lock-free so it never becomes the bottleneck.
+type NullOutput struct {
+	config  Config
+	written atomic.Int64
+}
+
+// NewNullOutput creates a new null output plugin
+func NewNullOutput(config Config) *NullOutput {
+	return &NullOutput{config: config}
+}
+
+// Write discards a log entry, counting it when enabled
+func (n *NullOutput) Write(log *core.Log) error {
+	if n.config.Count {
+		n.written.Add(1)
+	}
+	return nil
+}
+
+// Written returns the number of logs discarded so far (always 0 unless counting is enabled)
+func (n *NullOutput) Written() int64 {
+	return n.written.Load()
+}
+
+// OutputStats implements core.OutputStatsReporter when counting is enabled
+func (n *NullOutput) OutputStats() map[string]any {
+	if !n.config.Count {
+		return nil
+	}
+	return map[string]any{"logs_written": n.Written()}
+}
+
+// Close is a no-op: there is nothing to release
+func (n *NullOutput) Close() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package null
+
+import (
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+)
+
+func TestNullOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]any
+		expected int64
+		stats    bool
+	}{
+		{name: "discard only", config: map[string]any{}, expected: 0},
+		{name: "counting", config: map[string]any{"count": true}, expected: 3, stats: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, err := core.CreateOutputPlugin("null", tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create null output: %v", err)
+			}
+			output, ok := plugin.(*NullOutput)
+			if !ok {
+				t.Fatalf("Expected *NullOutput, got %T", plugin)
+			}
+
+			for i := 0; i < 3; i++ {
+				if err := output.Write(core.NewLog("info", "discarded")); err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+			}
+			if output.Written() != tt.expected {
+				t.Errorf("Expected %d written logs, got %d", tt.expected, output.Written())
+			}
+
+			stats := output.OutputStats()
+			if tt.stats && stats["logs_written"] != tt.expected {
+				t.Errorf("Expected logs_written=%d in stats, got %v", tt.expected, stats)
+			}
+			if !tt.stats && stats != nil {
+				t.Errorf("Expected no stats without counting, got %v", stats)
+			}
+
+			if err := output.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if err := output.Write(core.NewLog("info", "after close")); err != nil {
+				t.Errorf("Expected writes after close to be discarded, got %v", err)
+			}
+		})
+	}
+}
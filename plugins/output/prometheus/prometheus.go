@@ -2,18 +2,21 @@ package prometheusoutput
 
 import (
 	"fmt"
-	"log"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logging"
+	"github.com/mbiondo/logAnalyzer/pkg/version"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// logger writes this output plugin's internal logs
+var logger = logging.New("output.prometheus")
+
 func init() {
 	// Auto-register this plugin
 	core.RegisterOutputPlugin("prometheus", NewPrometheusOutputFromConfig)
@@ -39,13 +42,51 @@ func NewPrometheusOutputFromConfig(config map[string]any) (any, error) {
 	return NewPrometheusOutputWithPort(cfg.Port), nil
 }
 
+// logsTotal counts logs by level. It is registered once and shared by every
+// prometheus output, so counters keep increasing across config reloads.
+var (
+	logsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loganalyzer_logs_total",
+			Help: "Total number of logs processed by level",
+		},
+		[]string{"level"},
+	)
+	registerOnce sync.Once
+)
+
+// newBuildInfo returns the loganalyzer_build_info gauge, always 1, whose labels
+// tell which build of LogAnalyzer is running
+func newBuildInfo() prometheus.Collector {
+	build := version.Get()
+	info := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "loganalyzer_build_info",
+			Help: "Build information of LogAnalyzer, always 1",
+		},
+		[]string{"version", "commit", "go_version"},
+	)
+	info.WithLabelValues(build.Version, build.Commit, build.GoVersion).Set(1)
+	return info
+}
+
+// metricsServer is the HTTP server of one port, shared by the outputs using it
+type metricsServer struct {
+	server *http.Server
+	refs   int
+}
+
+var (
+	serversMu sync.Mutex
+	servers   = make(map[int]*metricsServer)
+)
+
 // PrometheusOutput sends logs to Prometheus metrics
 type PrometheusOutput struct {
-	logsTotal     *prometheus.CounterVec
-	httpServer    *http.Server
-	mutex         sync.RWMutex
-	port          int
-	serverStarted bool
+	logsTotal *prometheus.CounterVec
+	mutex     sync.Mutex
+	port      int
+	closed    bool
 }
 
 // NewPrometheusOutput creates a new Prometheus output plugin
@@ -53,66 +94,79 @@ func NewPrometheusOutput() *PrometheusOutput {
 	return NewPrometheusOutputWithPort(9091)
 }
 
-// NewPrometheusOutputWithPort creates a new Prometheus output plugin with custom port
+// NewPrometheusOutputWithPort creates a new Prometheus output plugin with custom port.
+// Outputs on the same port share one metrics server, which keeps running until the
+// last of them is closed; a reload can therefore create the new output before the
+// old one is closed without the two competing for the port.
 func NewPrometheusOutputWithPort(port int) *PrometheusOutput {
-	// Create Prometheus metrics
-	logsTotal := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "loganalyzer_logs_total",
-			Help: "Total number of logs processed by level",
-		},
-		[]string{"level"},
-	)
-
-	// Register metrics
-	prometheus.MustRegister(logsTotal)
+	registerOnce.Do(func() {
+		prometheus.MustRegister(logsTotal, newBuildInfo())
+	})
 
 	p := &PrometheusOutput{
 		logsTotal: logsTotal,
 		port:      port,
 	}
-
-	// Start HTTP server for metrics
-	go p.startMetricsServer()
-
+	acquireMetricsServer(port)
 	return p
 }
 
-// startMetricsServer starts the HTTP server for Prometheus metrics
-func (p *PrometheusOutput) startMetricsServer() {
-	p.mutex.Lock()
-	if p.serverStarted {
-		p.mutex.Unlock()
+// acquireMetricsServer starts the metrics server of a port or adds a reference to it
+func acquireMetricsServer(port int) {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+
+	if ms, ok := servers[port]; ok {
+		ms.refs++
 		return
 	}
-	p.serverStarted = true
-	addr := fmt.Sprintf(":%d", p.port)
-	p.mutex.Unlock()
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
+	ms := &metricsServer{
+		server: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		refs: 1,
+	}
+	servers[port] = ms
+
+	go func() {
+		logger.Printf("Starting Prometheus metrics server on %s", ms.server.Addr)
+		if err := ms.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Printf("Prometheus metrics server error: %v", err)
+		}
+	}()
+}
+
+// releaseMetricsServer drops a reference to the metrics server of a port and
+// shuts it down when no output uses it any more
+func releaseMetricsServer(port int) {
+	serversMu.Lock()
+	defer serversMu.Unlock()
 
-	p.httpServer = &http.Server{
-		Addr:              addr,
-		Handler:           mux,
-		ReadHeaderTimeout: 10 * time.Second,
+	ms, ok := servers[port]
+	if !ok {
+		return
+	}
+	ms.refs--
+	if ms.refs > 0 {
+		return
 	}
+	delete(servers, port)
 
-	log.Printf("Starting Prometheus metrics server on %s", addr)
-	if err := p.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Printf("Prometheus metrics server error: %v", err)
+	logger.Println("Shutting down Prometheus metrics server")
+	if err := ms.server.Close(); err != nil {
+		logger.Printf("Error closing Prometheus server: %v", err)
 	}
 }
 
 // Write processes a log entry and updates metrics
 func (p *PrometheusOutput) Write(logEntry *core.Log) error {
-	level := strings.ToLower(logEntry.Level)
-
-	// Normalize level names
-	switch level {
-	case "warn", "warning":
-		level = "warn"
-	}
+	// Normalize aliases (e.g. warning -> warn) so each level has a single label
+	level := core.Levels().Normalize(logEntry.Level)
 
 	// Increment counter for this level
 	p.logsTotal.WithLabelValues(level).Inc()
@@ -120,17 +174,17 @@ func (p *PrometheusOutput) Write(logEntry *core.Log) error {
 	return nil
 }
 
-// Close cleans up resources
+// Close releases the metrics server
 func (p *PrometheusOutput) Close() error {
-	log.Println("Prometheus output closed")
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 
-	// Shutdown HTTP server
-	if p.httpServer != nil {
-		log.Println("Shutting down Prometheus metrics server")
-		if err := p.httpServer.Close(); err != nil {
-			log.Printf("Error closing Prometheus server: %v", err)
-		}
+	if p.closed {
+		return nil
 	}
+	p.closed = true
+	logger.Println("Prometheus output closed")
 
+	releaseMetricsServer(p.port)
 	return nil
 }
@@ -1,7 +1,14 @@
 package prometheusoutput
 
 import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
 )
@@ -111,3 +118,81 @@ func TestPrometheusOutputCaseInsensitiveLevels(t *testing.T) {
 		}
 	}
 }
+
+func TestPrometheusOutputSharesServerAcrossReload(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	url := fmt.Sprintf("http://127.0.0.1:%d/metrics", port)
+
+	scrape := func() error {
+		var err error
+		for i := 0; i < 20; i++ {
+			var resp *http.Response
+			if resp, err = http.Get(url); err == nil {
+				_ = resp.Body.Close()
+				return nil
+			}
+			time.Sleep(25 * time.Millisecond)
+		}
+		return err
+	}
+
+	// The new output is created before the old one is closed, like a reload
+	oldOutput := NewPrometheusOutputWithPort(port)
+	newOutput := NewPrometheusOutputWithPort(port)
+	if err := scrape(); err != nil {
+		t.Fatalf("Expected metrics server to be up: %v", err)
+	}
+
+	if err := oldOutput.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := newOutput.Write(core.NewLog("error", "after reload")); err != nil {
+		t.Errorf("Write failed: %v", err)
+	}
+	if resp, err := http.Get(url); err != nil {
+		t.Errorf("Expected metrics server to keep running for the new output: %v", err)
+	} else {
+		_ = resp.Body.Close()
+	}
+
+	if err := newOutput.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if resp, err := http.Get(url); err == nil {
+		_ = resp.Body.Close()
+		t.Error("Expected metrics server to stop after the last output closed")
+	}
+}
+
+func TestPrometheusOutputBuildInfo(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	output := NewPrometheusOutputWithPort(port)
+	defer func() { _ = output.Close() }()
+
+	var body []byte
+	for i := 0; i < 20; i++ {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			break
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+
+	expected := fmt.Sprintf(`loganalyzer_build_info{commit="unknown",go_version="%s",version="dev"} 1`, runtime.Version())
+	if !strings.Contains(string(body), expected) {
+		t.Errorf("Expected %s in the metrics, got:\n%s", expected, body)
+	}
+}
@@ -0,0 +1,151 @@
+package redisstreamoutput
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/redisclient"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("redis_stream", NewRedisStreamOutputFromConfig)
+}
+
+// Config represents Redis Streams output configuration
+type Config struct {
+	redisclient.Config `yaml:",inline"`
+
+	Stream          string `yaml:"stream"`                     // Required: stream to append to
+	MaxLen          int64  `yaml:"maxlen,omitempty"`           // Trim the stream to about this many entries (default: no trimming)
+	ApproximateTrim *bool  `yaml:"approximate_trim,omitempty"` // Trim with "MAXLEN ~", which is much cheaper (default: true)
+	Timeout         int    `yaml:"timeout,omitempty"`          // Seconds a single XADD may take (default: 5)
+}
+
+// NewRedisStreamOutputFromConfig creates a Redis Streams output from configuration map
+func NewRedisStreamOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewRedisStreamOutput(cfg)
+}
+
+// RedisStreamOutput appends each log to a Redis stream with XADD. The entry holds
+// timestamp, level, message, source, source_type, tags and one "metadata.<key>"
+// field per metadata key, which the redis_stream input maps back onto the log.
+type RedisStreamOutput struct {
+	config     Config
+	client     *redisclient.Client
+	closeMutex sync.Mutex
+	closed     bool
+}
+
+// NewRedisStreamOutput creates a new Redis Streams output plugin
+func NewRedisStreamOutput(config Config) (*RedisStreamOutput, error) {
+	if config.Stream == "" {
+		return nil, fmt.Errorf("stream is required")
+	}
+	if config.MaxLen < 0 {
+		return nil, fmt.Errorf("maxlen must be non-negative")
+	}
+	if config.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must be non-negative")
+	}
+
+	// Set defaults
+	if config.Timeout == 0 {
+		config.Timeout = 5
+	}
+
+	client, err := redisclient.New(config.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStreamOutput{
+		config: config,
+		client: client,
+	}, nil
+}
+
+// Write appends a log entry to the stream
+func (r *RedisStreamOutput) Write(log *core.Log) error {
+	r.closeMutex.Lock()
+	closed := r.closed
+	r.closeMutex.Unlock()
+	if closed {
+		return fmt.Errorf("redis_stream output is closed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.config.Timeout)*time.Second)
+	defer cancel()
+
+	if _, err := r.client.Do(ctx, r.xaddArgs(log)...); err != nil {
+		return fmt.Errorf("failed to add log to stream %s: %w", r.config.Stream, err)
+	}
+	return nil
+}
+
+// xaddArgs builds the XADD command for a log
+func (r *RedisStreamOutput) xaddArgs(log *core.Log) []string {
+	args := []string{"XADD", r.config.Stream}
+	if r.config.MaxLen > 0 {
+		args = append(args, "MAXLEN")
+		if r.config.ApproximateTrim == nil || *r.config.ApproximateTrim {
+			args = append(args, "~")
+		}
+		args = append(args, strconv.FormatInt(r.config.MaxLen, 10))
+	}
+	args = append(args, "*",
+		"timestamp", log.Timestamp.Format(time.RFC3339Nano),
+		"level", log.Level,
+		"message", log.Message,
+	)
+	if log.Source != "" {
+		args = append(args, "source", log.Source)
+	}
+	if log.SourceType != "" {
+		args = append(args, "source_type", log.SourceType)
+	}
+	if len(log.Tags) > 0 {
+		args = append(args, "tags", strings.Join(log.Tags, ","))
+	}
+
+	keys := make([]string, 0, len(log.Metadata))
+	for key := range log.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "metadata."+key, log.Metadata[key])
+	}
+	return args
+}
+
+// CheckHealth implements HealthChecker interface
+func (r *RedisStreamOutput) CheckHealth(ctx context.Context) error {
+	if err := r.client.Ping(ctx); err != nil {
+		return fmt.Errorf("redis unreachable: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis connections
+func (r *RedisStreamOutput) Close() error {
+	r.closeMutex.Lock()
+	defer r.closeMutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.client.Close()
+}
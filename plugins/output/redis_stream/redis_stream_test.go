@@ -0,0 +1,166 @@
+package redisstreamoutput
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/redisclient"
+)
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisStreamOutputWrite(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	var mu sync.Mutex
+	var commands [][]string
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = c.Close() }()
+		reader := bufio.NewReader(c)
+		for {
+			args, err := readCommand(reader)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			commands = append(commands, args)
+			mu.Unlock()
+			_, _ = io.WriteString(c, "$3\r\n1-0\r\n")
+		}
+	}()
+
+	output, err := NewRedisStreamOutput(Config{
+		Config: redisclient.Config{Addr: listener.Addr().String()},
+		Stream: "logs",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+
+	if err := output.Write(core.NewLog("info", "hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	mu.Lock()
+	if len(commands) != 1 || commands[0][0] != "XADD" || commands[0][1] != "logs" {
+		t.Errorf("Expected one XADD to logs, got %v", commands)
+	}
+	mu.Unlock()
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := output.Write(core.NewLog("info", "after close")); err == nil {
+		t.Error("Expected error writing after close")
+	}
+}
+
+func TestRedisStreamOutputXaddArgs(t *testing.T) {
+	exact := false
+	logEntry := core.NewLogWithMetadata("error", "disk full", map[string]string{"service": "db", "host": "a"})
+	logEntry.Timestamp = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	logEntry.Source = "app"
+	logEntry.AddTags("storage", "critical")
+
+	fields := []string{
+		"timestamp", "2024-03-01T10:00:00Z",
+		"level", "error",
+		"message", "disk full",
+		"source", "app",
+		"tags", "storage,critical",
+		"metadata.host", "a",
+		"metadata.service", "db",
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{
+			name:     "no trimming",
+			config:   Config{Stream: "logs"},
+			expected: append([]string{"XADD", "logs", "*"}, fields...),
+		},
+		{
+			name:     "approximate trimming",
+			config:   Config{Stream: "logs", MaxLen: 1000},
+			expected: append([]string{"XADD", "logs", "MAXLEN", "~", "1000", "*"}, fields...),
+		},
+		{
+			name:     "exact trimming",
+			config:   Config{Stream: "logs", MaxLen: 1000, ApproximateTrim: &exact},
+			expected: append([]string{"XADD", "logs", "MAXLEN", "1000", "*"}, fields...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &RedisStreamOutput{config: tt.config}
+			if got := output.xaddArgs(logEntry); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewRedisStreamOutputValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{name: "missing addr", config: map[string]any{"stream": "logs"}},
+		{name: "missing stream", config: map[string]any{"addr": "localhost:6379"}},
+		{name: "negative maxlen", config: map[string]any{"addr": "localhost:6379", "stream": "logs", "maxlen": -1}},
+		{name: "negative timeout", config: map[string]any{"addr": "localhost:6379", "stream": "logs", "timeout": -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRedisStreamOutputFromConfig(tt.config); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
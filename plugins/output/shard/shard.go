@@ -0,0 +1,194 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+	"github.com/mbiondo/logAnalyzer/pkg/partition"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("shard", NewShardOutputFromConfig)
+}
+
+// Config represents shard output configuration
+type Config struct {
+	KeyField     logkey.Spec   `yaml:"key_field"`               // Key spec hashed to pick the shard
+	VirtualNodes int           `yaml:"virtual_nodes,omitempty"` // Virtual nodes per shard on the hash ring (default: 160)
+	Outputs      []ChildConfig `yaml:"outputs"`                 // Shards, at least one
+}
+
+// ChildConfig is one shard. Keys are assigned by name, so a shard keeps its keys
+// when other shards are added, removed or reordered.
+type ChildConfig struct {
+	Name   string         `yaml:"name,omitempty"` // Shard name (default: "shard-<n>", 1-based)
+	Type   string         `yaml:"type"`
+	Config map[string]any `yaml:"config,omitempty"`
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if len(c.KeyField) == 0 {
+		return fmt.Errorf("key_field is required")
+	}
+	if _, err := logkey.Compile(c.KeyField); err != nil {
+		return fmt.Errorf("invalid key_field: %w", err)
+	}
+	if c.VirtualNodes < 0 {
+		return fmt.Errorf("virtual_nodes must be non-negative")
+	}
+	if len(c.Outputs) == 0 {
+		return fmt.Errorf("at least one output is required")
+	}
+	for i := range c.Outputs {
+		child := &c.Outputs[i]
+		if child.Type == "" {
+			return fmt.Errorf("outputs[%d].type is required", i)
+		}
+		if child.Name == "" {
+			child.Name = fmt.Sprintf("shard-%d", i+1)
+		}
+	}
+	return nil
+}
+
+// NewShardOutputFromConfig creates a shard output from configuration map
+func NewShardOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewShardOutput(cfg)
+}
+
+// ShardOutput distributes logs across child outputs by consistent hashing of a key
+// field, so logs with the same key always reach the same child. Logs without the
+// key are spread round-robin.
+type ShardOutput struct {
+	config   Config
+	key      *logkey.Key
+	ring     *partition.Ring
+	children []core.OutputPlugin
+
+	next    atomic.Uint64  // Round-robin position for logs without a key
+	writes  []atomic.Int64 // Logs written per shard
+	errors  []atomic.Int64 // Failed writes per shard
+	keyless atomic.Int64   // Logs without a key
+
+	closeMu sync.Mutex
+	closed  bool
+}
+
+// NewShardOutput creates a new shard output and its child outputs
+func NewShardOutput(config Config) (*ShardOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(config.Outputs))
+	for i, child := range config.Outputs {
+		names[i] = child.Name
+	}
+	ring, err := partition.NewRing(names, config.VirtualNodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shards: %w", err)
+	}
+
+	s := &ShardOutput{
+		config: config,
+		key:    logkey.MustCompile(config.KeyField),
+		ring:   ring,
+		writes: make([]atomic.Int64, len(config.Outputs)),
+		errors: make([]atomic.Int64, len(config.Outputs)),
+	}
+	for _, child := range config.Outputs {
+		output, err := core.CreateOutputPlugin(child.Type, child.Config)
+		if err != nil {
+			_ = s.closeChildren()
+			return nil, fmt.Errorf("failed to create %s output for shard %s: %w", child.Type, child.Name, err)
+		}
+		s.children = append(s.children, output)
+	}
+
+	return s, nil
+}
+
+// Write sends the log to the shard that owns its key
+func (s *ShardOutput) Write(logEntry *core.Log) error {
+	s.closeMu.Lock()
+	closed := s.closed
+	s.closeMu.Unlock()
+	if closed {
+		return fmt.Errorf("shard output is closed")
+	}
+
+	i := s.shardFor(logEntry)
+	if err := s.children[i].Write(logEntry); err != nil {
+		s.errors[i].Add(1)
+		return fmt.Errorf("shard %s: %w", s.config.Outputs[i].Name, err)
+	}
+	s.writes[i].Add(1)
+	return nil
+}
+
+// shardFor returns the index of the shard a log belongs to
+func (s *ShardOutput) shardFor(logEntry *core.Log) int {
+	key, ok := s.key.Lookup(logEntry)
+	if !ok || key == "" {
+		s.keyless.Add(1)
+		return int((s.next.Add(1) - 1) % uint64(len(s.children))) // #nosec G115 - the result is below the number of shards
+	}
+	return s.ring.Locate(key)
+}
+
+// CheckHealth implements HealthChecker interface. Every shard must be healthy,
+// since the logs of an unhealthy shard cannot go anywhere else.
+func (s *ShardOutput) CheckHealth(ctx context.Context) error {
+	var errs []error
+	for i, child := range s.children {
+		if checker, ok := child.(core.HealthChecker); ok {
+			if err := checker.CheckHealth(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shard %s: %w", s.config.Outputs[i].Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// OutputStats implements core.OutputStatsReporter
+func (s *ShardOutput) OutputStats() map[string]any {
+	stats := map[string]any{"keyless": s.keyless.Load()}
+	for i, child := range s.config.Outputs {
+		stats["writes."+child.Name] = s.writes[i].Load()
+		stats["errors."+child.Name] = s.errors[i].Load()
+	}
+	return stats
+}
+
+// Close closes every shard
+func (s *ShardOutput) Close() error {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.closeChildren()
+}
+
+// closeChildren closes the child outputs created so far
+func (s *ShardOutput) closeChildren() error {
+	var errs []error
+	for _, child := range s.children {
+		errs = append(errs, child.Close())
+	}
+	return errors.Join(errs...)
+}
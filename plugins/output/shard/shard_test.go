@@ -0,0 +1,163 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/logkey"
+)
+
+// captureOutput records the logs written to one shard
+type captureOutput struct {
+	mu     sync.Mutex
+	logs   []*core.Log
+	fail   bool
+	closed bool
+}
+
+func init() {
+	core.RegisterOutputPlugin("shard_test_capture", func(map[string]any) (any, error) {
+		return &captureOutput{}, nil
+	})
+}
+
+func (c *captureOutput) Write(log *core.Log) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return errors.New("write failed")
+	}
+	c.logs = append(c.logs, log)
+	return nil
+}
+
+func (c *captureOutput) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *captureOutput) users() map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	users := make(map[string]bool)
+	for _, log := range c.logs {
+		users[log.Metadata["user"]] = true
+	}
+	return users
+}
+
+func newTestShards(t *testing.T, shards int) (*ShardOutput, []*captureOutput) {
+	t.Helper()
+	config := Config{KeyField: logkey.Sources("user")}
+	for range shards {
+		config.Outputs = append(config.Outputs, ChildConfig{Type: "shard_test_capture"})
+	}
+	output, err := NewShardOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create shard output: %v", err)
+	}
+	t.Cleanup(func() { _ = output.Close() })
+
+	children := make([]*captureOutput, shards)
+	for i, child := range output.children {
+		children[i] = child.(*captureOutput)
+	}
+	return output, children
+}
+
+func TestNewShardOutputValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "missing key_field", config: Config{Outputs: []ChildConfig{{Type: "shard_test_capture"}}}},
+		{name: "no outputs", config: Config{KeyField: logkey.Sources("user")}},
+		{name: "missing child type", config: Config{KeyField: logkey.Sources("user"), Outputs: []ChildConfig{{Name: "a"}}}},
+		{name: "duplicate shard names", config: Config{KeyField: logkey.Sources("user"), Outputs: []ChildConfig{
+			{Name: "a", Type: "shard_test_capture"}, {Name: "a", Type: "shard_test_capture"},
+		}}},
+		{name: "unknown child type", config: Config{KeyField: logkey.Sources("user"), Outputs: []ChildConfig{{Type: "nope"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewShardOutput(tt.config); err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestShardOutputKeepsKeysTogether(t *testing.T) {
+	output, children := newTestShards(t, 3)
+
+	for round := range 3 {
+		for user := range 30 {
+			logEntry := core.NewLogWithMetadata("info", fmt.Sprintf("event %d", round), map[string]string{"user": fmt.Sprintf("user-%d", user)})
+			if err := output.Write(logEntry); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+	}
+
+	seen := make(map[string]int)
+	for i, child := range children {
+		users := child.users()
+		if len(users) == 0 {
+			t.Errorf("Expected shard %d to receive some users", i)
+		}
+		for user := range users {
+			if previous, ok := seen[user]; ok {
+				t.Errorf("Expected %s on one shard, found on %d and %d", user, previous, i)
+			}
+			seen[user] = i
+		}
+	}
+	if len(seen) != 30 {
+		t.Errorf("Expected 30 users, got %d", len(seen))
+	}
+}
+
+func TestShardOutputKeylessRoundRobin(t *testing.T) {
+	output, children := newTestShards(t, 2)
+
+	for range 4 {
+		_ = output.Write(core.NewLog("info", "no user"))
+	}
+	for i, child := range children {
+		if len(child.logs) != 2 {
+			t.Errorf("Expected 2 keyless logs on shard %d, got %d", i, len(child.logs))
+		}
+	}
+	if stats := output.OutputStats(); stats["keyless"] != int64(4) || stats["writes.shard-1"] != int64(2) {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+}
+
+func TestShardOutputWriteError(t *testing.T) {
+	output, children := newTestShards(t, 1)
+	children[0].fail = true
+
+	err := output.Write(core.NewLogWithMetadata("info", "x", map[string]string{"user": "ana"}))
+	if err == nil {
+		t.Fatal("Expected the shard error to be returned")
+	}
+	if stats := output.OutputStats(); stats["errors.shard-1"] != int64(1) {
+		t.Errorf("Expected 1 error, got %v", stats)
+	}
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !children[0].closed {
+		t.Error("Expected the shard to be closed")
+	}
+	if err := output.Write(core.NewLog("info", "late")); err == nil {
+		t.Error("Expected an error writing to a closed output")
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/httpclient"
 	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
 )
 
@@ -19,13 +21,14 @@ func init() {
 
 // Config represents slack output configuration
 type Config struct {
-	WebhookURL string           `yaml:"webhook_url"`          // Required: Slack webhook URL
-	Username   string           `yaml:"username,omitempty"`   // Optional: Username to post as
-	Channel    string           `yaml:"channel,omitempty"`    // Optional: Channel to post to
-	IconEmoji  string           `yaml:"icon_emoji,omitempty"` // Optional: Emoji icon
-	IconURL    string           `yaml:"icon_url,omitempty"`   // Optional: URL icon
-	Timeout    int              `yaml:"timeout,omitempty"`    // Optional: HTTP timeout in seconds
-	TLS        tlsconfig.Config `yaml:"tls,omitempty"`        // TLS configuration
+	WebhookURL string            `yaml:"webhook_url"`          // Required: Slack webhook URL
+	Username   string            `yaml:"username,omitempty"`   // Optional: Username to post as
+	Channel    string            `yaml:"channel,omitempty"`    // Optional: Channel to post to
+	IconEmoji  string            `yaml:"icon_emoji,omitempty"` // Optional: Emoji icon
+	IconURL    string            `yaml:"icon_url,omitempty"`   // Optional: URL icon
+	Timeout    int               `yaml:"timeout,omitempty"`    // Optional: HTTP timeout in seconds
+	TLS        tlsconfig.Config  `yaml:"tls,omitempty"`        // TLS configuration
+	HTTP       httpclient.Config `yaml:"http,omitempty"`       // Connection pooling and proxy settings
 }
 
 // NewSlackOutputFromConfig creates a slack output from configuration map
@@ -91,21 +94,10 @@ func NewSlackOutput(config Config) (*SlackOutput, error) {
 		return nil, fmt.Errorf("invalid TLS config: %w", err)
 	}
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: time.Duration(config.Timeout) * time.Second,
-	}
-
-	// Configure TLS transport if enabled
-	if config.TLS.Enabled {
-		tlsConfig, err := config.TLS.NewTLSConfig()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create TLS config: %w", err)
-		}
-
-		client.Transport = &http.Transport{
-			TLSClientConfig: tlsConfig,
-		}
+	// Create HTTP client on its own pooled transport
+	client, err := httpclient.New(config.HTTP, time.Duration(config.Timeout)*time.Second, config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
 	}
 
 	return &SlackOutput{
@@ -193,6 +185,17 @@ func (s *SlackOutput) createSlackMessage(log *core.Log) SlackMessage {
 		},
 	}
 
+	// Add source fields if present
+	if log.Source != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Source", Value: log.Source, Short: true})
+	}
+	if log.SourceType != "" {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Source Type", Value: log.SourceType, Short: true})
+	}
+	if len(log.Tags) > 0 {
+		attachment.Fields = append(attachment.Fields, SlackField{Title: "Tags", Value: strings.Join(log.Tags, ", "), Short: true})
+	}
+
 	message := SlackMessage{
 		Attachments: []SlackAttachment{attachment},
 	}
@@ -214,23 +217,22 @@ func (s *SlackOutput) createSlackMessage(log *core.Log) SlackMessage {
 	return message
 }
 
-// getColorForLevel returns a color string based on log level
+// getColorForLevel returns a color string based on log level severity
 func (s *SlackOutput) getColorForLevel(level string) string {
-	switch level {
-	case "error", "ERROR", "err", "ERR":
+	levels := core.Levels()
+	switch {
+	case levels.AtLeast(level, "error"):
 		return "danger" // red
-	case "warn", "warning", "WARN", "WARNING":
+	case levels.AtLeast(level, "warn"):
 		return "warning" // yellow/orange
-	case "info", "INFO":
+	case levels.AtLeast(level, "info"):
 		return "good" // green
-	case "debug", "DEBUG":
-		return "#808080" // gray
 	default:
-		return "#808080" // gray for unknown levels
+		return "#808080" // gray for debug and unknown levels
 	}
 }
 
-// Close closes the Slack output (no-op for HTTP client)
+// Close closes the Slack output and its idle connections
 func (s *SlackOutput) Close() error {
 	s.closeMutex.Lock()
 	defer s.closeMutex.Unlock()
@@ -240,6 +242,6 @@ func (s *SlackOutput) Close() error {
 	}
 
 	s.closed = true
-	// No actual closing needed for HTTP client
+	s.client.CloseIdleConnections()
 	return nil
 }
@@ -0,0 +1,503 @@
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+func init() {
+	// Auto-register this plugin
+	core.RegisterOutputPlugin("syslog", NewSyslogOutputFromConfig)
+}
+
+// Transport protocols
+const (
+	ProtocolUDP = "udp" // One datagram per message (default)
+	ProtocolTCP = "tcp" // Octet-counted frames on a plain connection (RFC 6587)
+	ProtocolTLS = "tls" // Octet-counted frames on a TLS connection (RFC 5425)
+)
+
+// Message formats
+const (
+	FormatRFC5424 = "rfc5424" // Structured syslog (default)
+	FormatRFC3164 = "rfc3164" // BSD syslog
+)
+
+const (
+	defaultPort     = 514
+	defaultTLSPort  = 6514
+	defaultFacility = "user"
+	defaultAppName  = "logAnalyzer"
+	defaultSDID     = "logAnalyzer@32473" // 32473 is the private enterprise number reserved for examples (RFC 5612)
+	defaultTimeout  = 5
+)
+
+// Facility codes by name (RFC 5424, section 6.2.1)
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11, "ntp": 12, "security": 13, "console": 14, "solaris-cron": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Severity codes by level name. Other levels of the level vocabulary are placed
+// by rank (see Severity); levels unknown to both are sent as info.
+var severities = map[string]int{
+	"emerg": 0, "emergency": 0, "panic": 0,
+	"alert":    1,
+	"critical": 2, "crit": 2, "fatal": 2,
+	"error": 3, "err": 3,
+	"warn": 4, "warning": 4,
+	"notice": 5,
+	"info":   6,
+	"debug":  7, "trace": 7,
+}
+
+// severityInfo is the severity of levels without a mapping
+const severityInfo = 6
+
+// Config represents syslog output configuration
+type Config struct {
+	Protocol string           `yaml:"protocol,omitempty"` // udp (default), tcp or tls
+	Host     string           `yaml:"host"`               // Required: syslog server host
+	Port     int              `yaml:"port,omitempty"`     // Server port (default: 514, or 6514 for tls)
+	Facility string           `yaml:"facility,omitempty"` // Facility name (e.g. local0) or code 0-23 (default: user)
+	AppName  string           `yaml:"app_name,omitempty"` // APP-NAME, or the tag for rfc3164 (default: logAnalyzer)
+	Hostname string           `yaml:"hostname,omitempty"` // HOSTNAME field (default: this machine's hostname)
+	Format   string           `yaml:"format,omitempty"`   // rfc5424 (default) or rfc3164
+	SDID     string           `yaml:"sd_id,omitempty"`    // SD-ID of the rfc5424 structured data (default: logAnalyzer@32473)
+	TLS      tlsconfig.Config `yaml:"tls,omitempty"`      // Certificate options for the tls protocol
+	Timeout  int              `yaml:"timeout,omitempty"`  // Seconds to connect or write a message (default: 5)
+}
+
+// Validate validates the configuration and applies defaults
+func (c *Config) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must be non-negative")
+	}
+
+	switch c.Protocol {
+	case "":
+		c.Protocol = ProtocolUDP
+		if c.TLS.Enabled {
+			c.Protocol = ProtocolTLS
+		}
+	case ProtocolUDP, ProtocolTCP:
+		if c.TLS.Enabled {
+			return fmt.Errorf("tls requires protocol '%s'", ProtocolTLS)
+		}
+	case ProtocolTLS:
+	default:
+		return fmt.Errorf("invalid protocol '%s', must be '%s', '%s' or '%s'", c.Protocol, ProtocolUDP, ProtocolTCP, ProtocolTLS)
+	}
+	if c.Protocol == ProtocolTLS {
+		c.TLS.Enabled = true
+		if err := c.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid TLS config: %w", err)
+		}
+	}
+
+	switch c.Format {
+	case "":
+		c.Format = FormatRFC5424
+	case FormatRFC5424, FormatRFC3164:
+	default:
+		return fmt.Errorf("invalid format '%s', must be '%s' or '%s'", c.Format, FormatRFC5424, FormatRFC3164)
+	}
+
+	if c.Facility == "" {
+		c.Facility = defaultFacility
+	}
+	if _, err := parseFacility(c.Facility); err != nil {
+		return err
+	}
+
+	if c.SDID == "" {
+		c.SDID = defaultSDID
+	}
+	if !validSDName(c.SDID) {
+		return fmt.Errorf("invalid sd_id '%s': must be 1-32 printable ASCII characters without '=', ']', '\"' or spaces", c.SDID)
+	}
+
+	if c.Port == 0 {
+		c.Port = defaultPort
+		if c.Protocol == ProtocolTLS {
+			c.Port = defaultTLSPort
+		}
+	}
+	if c.AppName == "" {
+		c.AppName = defaultAppName
+	}
+	if c.Hostname == "" {
+		c.Hostname, _ = os.Hostname()
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+// parseFacility resolves a facility name or numeric code
+func parseFacility(facility string) (int, error) {
+	if code, ok := facilities[strings.ToLower(facility)]; ok {
+		return code, nil
+	}
+	if code, err := strconv.Atoi(facility); err == nil && code >= 0 && code <= 23 {
+		return code, nil
+	}
+	return 0, fmt.Errorf("invalid facility '%s', must be a facility name such as 'user' or 'local0', or a code between 0 and 23", facility)
+}
+
+// Severity returns the syslog severity of a log level. Aliases of the level
+// vocabulary (see core.Levels) are resolved first, so a level keeps the
+// severity of its syslog name. Other levels in the vocabulary's order get one
+// by rank, between the nearest levels that have a syslog name.
+func Severity(level string) int {
+	vocabulary := core.Levels()
+	name := vocabulary.Normalize(level)
+	if severity, ok := severities[name]; ok {
+		return severity
+	}
+	rank, ok := vocabulary.Severity(name)
+	if !ok {
+		return severityInfo
+	}
+	return rankSeverity(vocabulary.Levels(), rank)
+}
+
+// rankSeverity places the level at rank in order (least to most severe): one
+// step more severe than the nearest named level below when that leaves it less
+// severe than the named level above, otherwise as severe as the level below.
+// Without named levels, the order is spread from debug to error.
+func rankSeverity(order []string, rank int) int {
+	below, above := -1, -1
+	for i := rank - 1; i >= 0 && below < 0; i-- {
+		if severity, ok := severities[order[i]]; ok {
+			below = severity
+		}
+	}
+	for i := rank + 1; i < len(order) && above < 0; i++ {
+		if severity, ok := severities[order[i]]; ok {
+			above = severity
+		}
+	}
+
+	switch {
+	case below >= 0 && above >= 0:
+		if below-1 > above {
+			return below - 1
+		}
+		return below
+	case below >= 0:
+		return max(below-1, 0)
+	case above >= 0:
+		return min(above+1, 7)
+	case len(order) == 1:
+		return severityInfo
+	default:
+		last := len(order) - 1
+		return 7 - (rank*4+last/2)/last
+	}
+}
+
+// Priority returns the PRI value of a message: facility * 8 + severity
+func Priority(facility int, level string) int {
+	return facility*8 + Severity(level)
+}
+
+// NewSyslogOutputFromConfig creates a syslog output from configuration map
+func NewSyslogOutputFromConfig(config map[string]any) (any, error) {
+	var cfg Config
+	if err := core.GetPluginConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewSyslogOutput(cfg)
+}
+
+// SyslogOutput forwards each log to a remote syslog server. Over UDP every message
+// is one datagram; over TCP and TLS messages are framed with octet counting
+// ("<length> <message>", RFC 6587) so multi-line messages arrive intact. The
+// connection is opened on the first write and reopened once if a write fails.
+type SyslogOutput struct {
+	config    Config
+	facility  int
+	pid       string
+	addr      string
+	tlsConfig *tls.Config
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewSyslogOutput creates a new syslog output plugin
+func NewSyslogOutput(config Config) (*SyslogOutput, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	facility, _ := parseFacility(config.Facility)
+	tlsConfig, err := config.TLS.NewTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TLS config: %w", err)
+	}
+
+	return &SyslogOutput{
+		config:    config,
+		facility:  facility,
+		pid:       strconv.Itoa(os.Getpid()),
+		addr:      net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// Write sends a log entry to the syslog server
+func (s *SyslogOutput) Write(log *core.Log) error {
+	message := s.format(log)
+	if s.config.Protocol != ProtocolUDP {
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("syslog output is closed")
+	}
+
+	// A connection the server closed usually only fails on the next write, so
+	// a failed write is retried once on a new connection
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(context.Background()); err != nil {
+				return err
+			}
+		}
+		if err = s.send(message); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return fmt.Errorf("failed to send log to syslog server %s: %w", s.addr, err)
+}
+
+// send writes one message on the current connection
+func (s *SyslogOutput) send(message string) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout())); err != nil {
+		return err
+	}
+	_, err := s.conn.Write([]byte(message))
+	return err
+}
+
+// dial connects to the syslog server
+func (s *SyslogOutput) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout()}
+
+	var conn net.Conn
+	var err error
+	switch s.config.Protocol {
+	case ProtocolTLS:
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", s.addr)
+	case ProtocolTCP:
+		conn, err = dialer.DialContext(ctx, "tcp", s.addr)
+	default:
+		conn, err = dialer.DialContext(ctx, "udp", s.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog server %s: %w", s.addr, err)
+	}
+	return conn, nil
+}
+
+func (s *SyslogOutput) timeout() time.Duration {
+	return time.Duration(s.config.Timeout) * time.Second
+}
+
+// format renders a log in the configured message format
+func (s *SyslogOutput) format(log *core.Log) string {
+	if s.config.Format == FormatRFC3164 {
+		return s.formatRFC3164(log)
+	}
+	return s.formatRFC5424(log)
+}
+
+// formatRFC5424 renders "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG".
+// Level, source, source type, tags, the trace context and metadata go into one
+// structured data element; MSGID is not used.
+func (s *SyslogOutput) formatRFC5424(log *core.Log) string {
+	timestamp := log.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s - ",
+		Priority(s.facility, log.Level),
+		timestamp.Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(s.config.Hostname, 255),
+		headerField(s.config.AppName, 48),
+		headerField(s.pid, 128),
+	)
+
+	b.WriteString("[" + s.config.SDID)
+	writeParam(&b, "level", log.Level)
+	if log.Source != "" {
+		writeParam(&b, "source", log.Source)
+	}
+	if log.SourceType != "" {
+		writeParam(&b, "source_type", log.SourceType)
+	}
+	if len(log.Tags) > 0 {
+		writeParam(&b, "tags", strings.Join(log.Tags, ","))
+	}
+	if log.TraceID != "" {
+		writeParam(&b, "trace_id", log.TraceID)
+		if log.SpanID != "" {
+			writeParam(&b, "span_id", log.SpanID)
+		}
+	}
+	keys := make([]string, 0, len(log.Metadata))
+	for key := range log.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeParam(&b, sdName(key), log.Metadata[key])
+	}
+	b.WriteString("]")
+
+	if log.Message != "" {
+		b.WriteString(" " + log.Message)
+	}
+	return b.String()
+}
+
+// formatRFC3164 renders "<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG". The format
+// has no room for structured data, so only the message is sent.
+func (s *SyslogOutput) formatRFC3164(log *core.Log) string {
+	timestamp := log.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return fmt.Sprintf("<%d>%s %s %s[%s]: %s",
+		Priority(s.facility, log.Level),
+		timestamp.Format(time.Stamp),
+		headerField(s.config.Hostname, 255),
+		tag(s.config.AppName),
+		s.pid,
+		log.Message,
+	)
+}
+
+// headerField returns a header field limited to printable ASCII and maxLen
+// characters, or the NILVALUE "-" if nothing is left
+func headerField(value string, maxLen int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(field) > maxLen {
+		field = field[:maxLen]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
+
+// tag returns the rfc3164 TAG: up to 32 alphanumeric characters
+func tag(appName string) string {
+	var b strings.Builder
+	for _, r := range appName {
+		if b.Len() == 32 || !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			break
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return defaultAppName
+	}
+	return b.String()
+}
+
+// validSDName reports whether name is a valid SD-ID or PARAM-NAME
+func validSDName(name string) bool {
+	return name != "" && sdName(name) == name
+}
+
+// sdName makes a metadata key a valid PARAM-NAME: up to 32 printable ASCII
+// characters other than '=', ']', '"' and space, which are replaced with '_'
+func sdName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// paramEscaper escapes the characters PARAM-VALUE requires to be escaped
+var paramEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// writeParam appends ` name="value"` to a structured data element
+func writeParam(b *strings.Builder, name, value string) {
+	b.WriteString(" " + name + `="`)
+	b.WriteString(paramEscaper.Replace(value))
+	b.WriteString(`"`)
+}
+
+// CheckHealth implements HealthChecker interface. For tcp and tls it opens a
+// separate connection to the server; udp is connectionless and always healthy.
+func (s *SyslogOutput) CheckHealth(ctx context.Context) error {
+	if s.config.Protocol == ProtocolUDP {
+		return nil
+	}
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Close closes the connection to the syslog server
+func (s *SyslogOutput) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
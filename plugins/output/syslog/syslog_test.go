@@ -0,0 +1,388 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbiondo/logAnalyzer/core"
+	"github.com/mbiondo/logAnalyzer/pkg/tlsconfig"
+)
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		facility string
+		level    string
+		expected int
+	}{
+		{"kern", "critical", 2},
+		{"user", "error", 11},
+		{"user", "warn", 12},
+		{"daemon", "info", 30},
+		{"local0", "debug", 135},
+		{"local7", "WARNING", 188},
+		{"4", "notice", 37},
+		{"user", "custom", 14}, // Unknown levels are sent as info
+	}
+
+	for _, tt := range tests {
+		facility, err := parseFacility(tt.facility)
+		if err != nil {
+			t.Fatalf("Unexpected error for facility %s: %v", tt.facility, err)
+		}
+		if got := Priority(facility, tt.level); got != tt.expected {
+			t.Errorf("%s.%s: expected PRI %d, got %d", tt.facility, tt.level, tt.expected, got)
+		}
+	}
+}
+
+func TestSeverityCustomLevels(t *testing.T) {
+	defer func() { _ = core.SetLevels(core.LevelsConfig{}) }()
+
+	err := core.SetLevels(core.LevelsConfig{
+		Order:   []string{"trace", "debug", "info", "audit", "warn", "error", "page"},
+		Aliases: map[string]string{"CRIT": "error", "5": "page", "warning": "warn"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set levels: %v", err)
+	}
+
+	tests := map[string]int{
+		"audit":   5, // Between info and warn
+		"page":    2, // Above error
+		"CRIT":    3, // Alias of error
+		"5":       2, // Alias of page
+		"warning": 4,
+		"fatal":   2, // Syslog name outside the vocabulary
+		"custom":  6, // Unknown
+	}
+	for level, expected := range tests {
+		if got := Severity(level); got != expected {
+			t.Errorf("%s: expected severity %d, got %d", level, expected, got)
+		}
+	}
+
+	// Without a syslog name in the order, levels are spread from debug to error
+	if err := core.SetLevels(core.LevelsConfig{Order: []string{"low", "mid", "high"}, Default: "low"}); err != nil {
+		t.Fatalf("Failed to set levels: %v", err)
+	}
+	for level, expected := range map[string]int{"low": 7, "mid": 5, "high": 3} {
+		if got := Severity(level); got != expected {
+			t.Errorf("%s: expected severity %d, got %d", level, expected, got)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+		port        int
+		protocol    string
+	}{
+		{"defaults", Config{Host: "logs"}, false, 514, ProtocolUDP},
+		{"tls default port", Config{Host: "logs", Protocol: "tls"}, false, 6514, ProtocolTLS},
+		{"tls enabled implies protocol", Config{Host: "logs", TLS: tlsconfig.Config{Enabled: true}}, false, 6514, ProtocolTLS},
+		{"explicit port", Config{Host: "logs", Protocol: "tcp", Port: 1514}, false, 1514, ProtocolTCP},
+		{"missing host", Config{}, true, 0, ""},
+		{"invalid protocol", Config{Host: "logs", Protocol: "http"}, true, 0, ""},
+		{"tls with udp", Config{Host: "logs", Protocol: "udp", TLS: tlsconfig.Config{Enabled: true}}, true, 0, ""},
+		{"invalid format", Config{Host: "logs", Format: "cef"}, true, 0, ""},
+		{"invalid facility name", Config{Host: "logs", Facility: "local8"}, true, 0, ""},
+		{"invalid facility code", Config{Host: "logs", Facility: "24"}, true, 0, ""},
+		{"invalid sd_id", Config{Host: "logs", SDID: "my app"}, true, 0, ""},
+		{"invalid port", Config{Host: "logs", Port: 70000}, true, 0, ""},
+	}
+
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if (err != nil) != tt.expectError {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectError, err)
+			continue
+		}
+		if err == nil && (tt.config.Port != tt.port || tt.config.Protocol != tt.protocol) {
+			t.Errorf("%s: expected %s port %d, got %s port %d", tt.name, tt.protocol, tt.port, tt.config.Protocol, tt.config.Port)
+		}
+	}
+}
+
+// newTestOutput creates an output with fixed header fields
+func newTestOutput(t *testing.T, config Config) *SyslogOutput {
+	t.Helper()
+	if config.Hostname == "" {
+		config.Hostname = "web-1"
+	}
+	if config.AppName == "" {
+		config.AppName = "myapp"
+	}
+	output, err := NewSyslogOutput(config)
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	output.pid = "42"
+	return output
+}
+
+func TestFormatRFC5424(t *testing.T) {
+	output := newTestOutput(t, Config{Host: "logs", Facility: "local0"})
+
+	log := &core.Log{
+		Timestamp:  time.Date(2024, 5, 1, 12, 30, 45, 123456789, time.UTC),
+		Level:      "error",
+		Message:    "payment failed\nretrying",
+		Source:     "api",
+		SourceType: "http",
+		Tags:       []string{"payments", "eu"},
+		Metadata:   map[string]string{"trace id": "a]b", "path": `C:\tmp "x"`},
+	}
+
+	expected := `<131>1 2024-05-01T12:30:45.123456Z web-1 myapp 42 - ` +
+		`[logAnalyzer@32473 level="error" source="api" source_type="http" tags="payments,eu" path="C:\\tmp \"x\"" trace_id="a\]b"] ` +
+		"payment failed\nretrying"
+	if got := output.format(log); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// Empty header fields use the NILVALUE
+	output.config.Hostname = ""
+	minimal := output.format(&core.Log{Timestamp: log.Timestamp, Level: "info"})
+	if minimal != `<134>1 2024-05-01T12:30:45.123456Z - myapp 42 - [logAnalyzer@32473 level="info"]` {
+		t.Errorf("Unexpected minimal message %q", minimal)
+	}
+
+	// The trace context follows the tags
+	traced := output.format(&core.Log{Timestamp: log.Timestamp, Level: "info", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	if traced != `<134>1 2024-05-01T12:30:45.123456Z - myapp 42 - [logAnalyzer@32473 level="info" trace_id="4bf92f3577b34da6a3ce929d0e0e4736" span_id="00f067aa0ba902b7"]` {
+		t.Errorf("Unexpected traced message %q", traced)
+	}
+}
+
+func TestFormatRFC3164(t *testing.T) {
+	output := newTestOutput(t, Config{Host: "logs", Format: FormatRFC3164, AppName: "my-app"})
+
+	log := &core.Log{
+		Timestamp: time.Date(2024, 5, 1, 9, 5, 3, 0, time.UTC),
+		Level:     "warn",
+		Message:   "disk almost full",
+		Metadata:  map[string]string{"disk": "/var"},
+	}
+
+	// The tag stops at the first non-alphanumeric character
+	expected := "<12>May  1 09:05:03 web-1 my[42]: disk almost full"
+	if got := output.format(log); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// readFrame reads one octet-counted frame
+func readFrame(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return "", err
+	}
+	return string(frame), nil
+}
+
+// acceptFrames serves one connection at a time and sends every frame it reads
+func acceptFrames(listener net.Listener, frames chan<- string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer func() { _ = conn.Close() }()
+			reader := bufio.NewReader(conn)
+			for {
+				frame, err := readFrame(reader)
+				if err != nil {
+					return
+				}
+				frames <- frame
+			}
+		}()
+	}
+}
+
+func receive(t *testing.T, frames <-chan string) string {
+	t.Helper()
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return ""
+	}
+}
+
+func listenerPort(t *testing.T, addr net.Addr) int {
+	t.Helper()
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		t.Fatalf("Invalid address %s: %v", addr, err)
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+func TestSyslogOutputTCPOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+	frames := make(chan string, 10)
+	go acceptFrames(listener, frames)
+
+	output := newTestOutput(t, Config{Host: "127.0.0.1", Port: listenerPort(t, listener.Addr()), Protocol: ProtocolTCP})
+	defer func() { _ = output.Close() }()
+
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected a healthy server, got %v", err)
+	}
+
+	// Multi-line and multi-byte messages stay one frame
+	messages := []string{"first line\nsecond line", "héllo wörld"}
+	for _, message := range messages {
+		if err := output.Write(core.NewLog("info", message)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	for _, message := range messages {
+		if frame := receive(t, frames); !strings.HasPrefix(frame, "<14>1 ") || !strings.HasSuffix(frame, "] "+message) {
+			t.Errorf("Expected a frame ending in %q, got %q", message, frame)
+		}
+	}
+
+	// A connection closed by the server is replaced on the next write
+	_ = output.conn.Close()
+	if err := output.Write(core.NewLog("error", "after reconnect")); err != nil {
+		t.Fatalf("Failed to write after the connection closed: %v", err)
+	}
+	if frame := receive(t, frames); !strings.HasPrefix(frame, "<11>1 ") || !strings.HasSuffix(frame, "after reconnect") {
+		t.Errorf("Unexpected frame %q", frame)
+	}
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if err := output.Write(core.NewLog("info", "closed")); err == nil {
+		t.Error("Expected an error writing to a closed output")
+	}
+}
+
+func TestSyslogOutputUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	output := newTestOutput(t, Config{Host: "127.0.0.1", Port: listenerPort(t, conn.LocalAddr()), Facility: "local3", Format: FormatRFC3164})
+	defer func() { _ = output.Close() }()
+
+	if err := output.Write(core.NewLog("debug", "cache miss")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	// Datagrams are not octet-counted
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read datagram: %v", err)
+	}
+	if got := string(buf[:n]); !strings.HasPrefix(got, "<159>") || !strings.HasSuffix(got, " web-1 myapp[42]: cache miss") {
+		t.Errorf("Unexpected datagram %q", got)
+	}
+}
+
+func TestSyslogOutputTLS(t *testing.T) {
+	// The test server's certificate is valid for example.com
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: server.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+	frames := make(chan string, 10)
+	go acceptFrames(listener, frames)
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	output := newTestOutput(t, Config{
+		Host:     "127.0.0.1",
+		Port:     listenerPort(t, listener.Addr()),
+		Protocol: ProtocolTLS,
+		TLS:      tlsconfig.Config{CACertData: string(caCert), ServerName: "example.com"},
+	})
+	defer func() { _ = output.Close() }()
+
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected a healthy server, got %v", err)
+	}
+	if err := output.Write(core.NewLog("warn", "over tls")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if frame := receive(t, frames); !strings.HasPrefix(frame, "<12>1 ") || !strings.HasSuffix(frame, "] over tls") {
+		t.Errorf("Unexpected frame %q", frame)
+	}
+}
+
+func TestSyslogOutputCheckHealthUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listenerPort(t, listener.Addr())
+	_ = listener.Close()
+
+	output := newTestOutput(t, Config{Host: "127.0.0.1", Port: port, Protocol: ProtocolTCP, Timeout: 1})
+	if err := output.CheckHealth(context.Background()); err == nil {
+		t.Error("Expected an error for an unreachable server")
+	}
+	if err := output.Write(core.NewLog("info", "lost")); err == nil {
+		t.Error("Expected an error writing to an unreachable server")
+	}
+
+	// Nothing to check over udp
+	output = newTestOutput(t, Config{Host: "127.0.0.1", Port: port})
+	if err := output.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected udp to be healthy, got %v", err)
+	}
+}
+
+func TestNewSyslogOutputFromConfig(t *testing.T) {
+	plugin, err := NewSyslogOutputFromConfig(map[string]any{
+		"host":     "logs.example.com",
+		"protocol": "tcp",
+		"facility": "local4",
+		"app_name": "checkout",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	output := plugin.(*SyslogOutput)
+	if output.facility != 20 || output.addr != "logs.example.com:514" || output.config.Format != FormatRFC5424 {
+		t.Errorf("Unexpected output %+v", output.config)
+	}
+}